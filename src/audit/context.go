@@ -0,0 +1,190 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
+)
+
+// RedactedValue replaces the credentials in the recorded changes
+const RedactedValue = "[REDACTED]"
+
+// Change is the value of a field before and after a request
+type Change struct {
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// RequestAudit holds what the handler of a request knows about the change it
+// made, the audit middleware completes it with the caller, the resource type
+// of the route and the outcome
+type RequestAudit struct {
+	ResourceId string
+	Actor      string
+	ActorType  string
+	Changes    map[string]Change
+	lock       sync.Mutex
+}
+
+// NewRequestContext adds an empty request audit to the context
+func NewRequestContext(ctx context.Context) (context.Context, *RequestAudit) {
+	requestAudit := &RequestAudit{}
+	return context.WithValue(ctx, constants.AUDIT_CONTEXT_KEY, requestAudit), requestAudit
+}
+
+// FromContext returns the request audit of the context or nil if the request
+// is not audited
+func FromContext(ctx context.Context) *RequestAudit {
+	if ctx == nil {
+		return nil
+	}
+	requestAudit, _ := ctx.Value(constants.AUDIT_CONTEXT_KEY).(*RequestAudit)
+	return requestAudit
+}
+
+// SetResourceId sets the id of the resource changed by the request when the
+// route does not have it, for example on creation
+func SetResourceId(ctx context.Context, resourceId string) {
+	requestAudit := FromContext(ctx)
+	if requestAudit == nil {
+		return
+	}
+
+	requestAudit.lock.Lock()
+	defer requestAudit.lock.Unlock()
+	requestAudit.ResourceId = resourceId
+}
+
+// SetActor sets who made the request when it is not authorized, for example
+// the user signing in, the authorization context takes precedence over it
+func SetActor(ctx context.Context, actorType string, actor string) {
+	requestAudit := FromContext(ctx)
+	if requestAudit == nil {
+		return
+	}
+
+	requestAudit.lock.Lock()
+	defer requestAudit.lock.Unlock()
+	requestAudit.ActorType = actorType
+	requestAudit.Actor = actor
+}
+
+// RecordChange records the fields that changed between the resource before and
+// after the request, before is nil for created resources and after is nil for
+// deleted ones
+func RecordChange(ctx context.Context, before interface{}, after interface{}) {
+	requestAudit := FromContext(ctx)
+	if requestAudit == nil {
+		return
+	}
+
+	changes := Diff(before, after)
+	requestAudit.lock.Lock()
+	defer requestAudit.lock.Unlock()
+	if requestAudit.Changes == nil {
+		requestAudit.Changes = make(map[string]Change)
+	}
+	for field, change := range changes {
+		requestAudit.Changes[field] = change
+	}
+}
+
+// Diff returns the top level fields that differ between the json of before and
+// after, credentials are redacted
+func Diff(before interface{}, after interface{}) map[string]Change {
+	beforeFields := toFields(before)
+	afterFields := toFields(after)
+
+	names := make([]string, 0)
+	for name := range beforeFields {
+		names = append(names, name)
+	}
+	for name := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	result := make(map[string]Change)
+	for _, name := range names {
+		beforeValue := beforeFields[name]
+		afterValue := afterFields[name]
+		if !data.Diff(beforeValue, afterValue) {
+			continue
+		}
+
+		result[name] = Change{
+			Before: redact(name, beforeValue),
+			After:  redact(name, afterValue),
+		}
+	}
+
+	return result
+}
+
+func toFields(value interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+	if value == nil {
+		return result
+	}
+
+	content, err := json.Marshal(value)
+	if err != nil || string(content) == "null" {
+		return result
+	}
+	if err := json.Unmarshal(content, &result); err != nil {
+		var single interface{}
+		_ = json.Unmarshal(content, &single)
+		return map[string]interface{}{"value": single}
+	}
+
+	return result
+}
+
+// IsSensitiveField returns true if the field holds a credential that cannot be
+// written to the audit log
+func IsSensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	if name == "key" || name == "connection" {
+		return true
+	}
+	for _, sensitive := range []string{"password", "secret", "token", "api_key", "apikey", "private_key"} {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func redact(name string, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	if IsSensitiveField(name) {
+		return RedactedValue
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(typed))
+		for key, item := range typed {
+			result[key] = redact(key, item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(typed))
+		for i, item := range typed {
+			result[i] = redact("", item)
+		}
+		return result
+	}
+
+	return value
+}
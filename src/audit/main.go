@@ -0,0 +1,322 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/google/uuid"
+)
+
+// TimestampFormat keeps the timestamps the same length so they can be
+// compared as strings by the list filters
+const TimestampFormat = "2006-01-02T15:04:05.000Z07:00"
+
+const (
+	ActorTypeUser      = "user"
+	ActorTypeApiKey    = "api_key"
+	ActorTypeAnonymous = "anonymous"
+
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionLogin   = "login"
+	ActionRefresh = "refresh"
+
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied"
+)
+
+// Entry is a change made through the API
+type Entry struct {
+	ID           string            `json:"id"`
+	Timestamp    string            `json:"timestamp"`
+	Actor        string            `json:"actor"`
+	ActorType    string            `json:"actor_type"`
+	SourceIp     string            `json:"source_ip,omitempty"`
+	RequestId    string            `json:"request_id,omitempty"`
+	Method       string            `json:"method,omitempty"`
+	Path         string            `json:"path,omitempty"`
+	ResourceType string            `json:"resource_type"`
+	ResourceId   string            `json:"resource_id,omitempty"`
+	Action       string            `json:"action"`
+	Outcome      string            `json:"outcome"`
+	StatusCode   int               `json:"status_code,omitempty"`
+	Changes      map[string]Change `json:"changes,omitempty"`
+}
+
+// Query selects the entries of the audit log, empty fields match everything
+// and From and To are inclusive
+type Query struct {
+	Actor        string
+	ResourceType string
+	ResourceId   string
+	Action       string
+	Outcome      string
+	RequestId    string
+	From         time.Time
+	To           time.Time
+}
+
+// AuditLog appends the entries to a JSON lines file, the entries are never
+// changed once written and only the retention removes them
+type AuditLog struct {
+	ctx       basecontext.ApiContext
+	path      string
+	retention time.Duration
+	syslog    *SyslogWriter
+	lock      sync.Mutex
+}
+
+var globalAuditLog *AuditLog
+
+// New creates the audit log from the configuration, it returns nil when the
+// audit log is disabled
+func New(ctx basecontext.ApiContext) *AuditLog {
+	cfg := config.Get()
+	if !cfg.IsAuditLogEnabled() {
+		ctx.LogInfof("[Audit] Audit log is disabled")
+		globalAuditLog = nil
+		return nil
+	}
+
+	path, err := cfg.AuditLogPath()
+	if err != nil {
+		ctx.LogErrorf("[Audit] Error getting the audit log path: %v", err)
+		globalAuditLog = nil
+		return nil
+	}
+
+	auditLog := NewAuditLog(ctx, path, cfg.AuditLogRetention())
+	if address := cfg.AuditLogSyslogAddress(); address != "" {
+		writer, err := NewSyslogWriter(address)
+		if err != nil {
+			ctx.LogErrorf("[Audit] Error configuring the syslog forwarding: %v", err)
+		} else {
+			auditLog.syslog = writer
+		}
+	}
+
+	globalAuditLog = auditLog
+	return globalAuditLog
+}
+
+func NewAuditLog(ctx basecontext.ApiContext, path string, retention time.Duration) *AuditLog {
+	return &AuditLog{
+		ctx:       ctx,
+		path:      path,
+		retention: retention,
+	}
+}
+
+// Get returns the audit log of the service or nil if it is not enabled
+func Get() *AuditLog {
+	return globalAuditLog
+}
+
+func (l *AuditLog) Path() string {
+	return l.path
+}
+
+// Record appends the entry to the log and forwards it to syslog if configured
+func (l *AuditLog) Record(entry Entry) error {
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	if entry.Timestamp == "" {
+		entry.Timestamp = time.Now().UTC().Format(TimestampFormat)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.lock.Lock()
+	err = l.append(line)
+	l.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if l.syslog != nil {
+		if err := l.syslog.Write(entry); err != nil {
+			l.ctx.LogErrorf("[Audit] Error forwarding the audit entry %v to syslog: %v", entry.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (l *AuditLog) append(line []byte) error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o750); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Clean(l.path), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// Query returns the entries matching the query, oldest first
+func (l *AuditLog) Query(query Query) ([]Entry, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	result := make([]Entry, 0)
+	err := l.read(func(entry Entry, line []byte) {
+		if query.Matches(entry) {
+			result = append(result, entry)
+		}
+	})
+
+	return result, err
+}
+
+func (l *AuditLog) read(fn func(entry Entry, line []byte)) error {
+	file, err := os.Open(filepath.Clean(l.path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := []byte(strings.TrimSpace(scanner.Text()))
+		if len(line) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			l.ctx.LogErrorf("[Audit] Skipping invalid audit entry at line %d: %v", lineNumber, err)
+			continue
+		}
+		fn(entry, line)
+	}
+
+	return scanner.Err()
+}
+
+// Prune removes the entries older than the retention, it returns the number
+// of entries removed
+func (l *AuditLog) Prune(now time.Time) (int, error) {
+	if l.retention <= 0 {
+		return 0, nil
+	}
+	cutoff := now.Add(-l.retention).UTC().Format(TimestampFormat)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	kept := make([]byte, 0)
+	removed := 0
+	err := l.read(func(entry Entry, line []byte) {
+		if entry.Timestamp < cutoff {
+			removed++
+			return
+		}
+		kept = append(kept, line...)
+		kept = append(kept, '\n')
+	})
+	if err != nil {
+		return 0, err
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	tempFile := l.path + ".prune"
+	if err := os.WriteFile(tempFile, kept, 0o600); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tempFile, l.path); err != nil {
+		_ = os.Remove(tempFile)
+		return 0, err
+	}
+
+	return removed, nil
+}
+
+// StartRetentionLoop prunes the audit log every hour while the service runs
+func (l *AuditLog) StartRetentionLoop() {
+	if l.retention <= 0 {
+		return
+	}
+
+	l.ctx.LogInfof("[Audit] Keeping the audit log entries for %v", l.retention)
+	for {
+		if removed, err := l.Prune(time.Now()); err != nil {
+			l.ctx.LogErrorf("[Audit] Error pruning the audit log: %v", err)
+		} else if removed > 0 {
+			l.ctx.LogInfof("[Audit] Removed %d audit entries older than %v", removed, l.retention)
+		}
+		time.Sleep(time.Hour)
+	}
+}
+
+// Matches returns true if the entry matches all the fields set in the query
+func (q Query) Matches(entry Entry) bool {
+	if q.Actor != "" && !strings.EqualFold(q.Actor, entry.Actor) {
+		return false
+	}
+	if q.ResourceType != "" && !strings.EqualFold(q.ResourceType, entry.ResourceType) {
+		return false
+	}
+	if q.ResourceId != "" && q.ResourceId != entry.ResourceId {
+		return false
+	}
+	if q.Action != "" && !strings.EqualFold(q.Action, entry.Action) {
+		return false
+	}
+	if q.Outcome != "" && !strings.EqualFold(q.Outcome, entry.Outcome) {
+		return false
+	}
+	if q.RequestId != "" && q.RequestId != entry.RequestId {
+		return false
+	}
+	if !q.From.IsZero() && entry.Timestamp < q.From.UTC().Format(TimestampFormat) {
+		return false
+	}
+	if !q.To.IsZero() && entry.Timestamp > q.To.UTC().Format(TimestampFormat) {
+		return false
+	}
+
+	return true
+}
+
+// ParseTime reads the from and to values of a query, either RFC 3339 or a
+// date
+func ParseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if result, err := time.Parse(time.RFC3339, value); err == nil {
+		return result, nil
+	}
+	if result, err := time.Parse("2006-01-02", value); err == nil {
+		return result, nil
+	}
+
+	return time.Time{}, errors.NewWithCodef(400, "invalid time %v, use RFC 3339 or YYYY-MM-DD", value)
+}
@@ -0,0 +1,159 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAuditLog(t *testing.T, retention time.Duration) *AuditLog {
+	ctx := basecontext.NewBaseContext()
+	ctx.DisableLog()
+
+	return NewAuditLog(ctx, filepath.Join(t.TempDir(), "audit.jsonl"), retention)
+}
+
+func TestRecordAndQuery(t *testing.T) {
+	auditLog := newTestAuditLog(t, 0)
+
+	require.NoError(t, auditLog.Record(Entry{Actor: "alice", ActorType: ActorTypeUser, ResourceType: "auth/users", ResourceId: "1", Action: ActionCreate, Outcome: OutcomeSuccess}))
+	require.NoError(t, auditLog.Record(Entry{Actor: "bob", ActorType: ActorTypeUser, ResourceType: "auth/users", ResourceId: "1", Action: ActionDelete, Outcome: OutcomeDenied}))
+	require.NoError(t, auditLog.Record(Entry{Actor: "ci", ActorType: ActorTypeApiKey, ResourceType: "catalog", ResourceId: "2", Action: ActionUpdate, Outcome: OutcomeSuccess}))
+
+	entries, err := auditLog.Query(Query{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "alice", entries[0].Actor)
+	assert.NotEmpty(t, entries[0].ID)
+	assert.NotEmpty(t, entries[0].Timestamp)
+
+	entries, err = auditLog.Query(Query{ResourceType: "auth/users", Outcome: OutcomeSuccess})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "alice", entries[0].Actor)
+
+	entries, err = auditLog.Query(Query{Actor: "CI"})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "2", entries[0].ResourceId)
+
+	entries, err = auditLog.Query(Query{From: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	info, err := os.Stat(auditLog.Path())
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestQuery_SkipsInvalidLines(t *testing.T) {
+	auditLog := newTestAuditLog(t, 0)
+	require.NoError(t, auditLog.Record(Entry{Actor: "alice", Action: ActionCreate, Outcome: OutcomeSuccess}))
+
+	file, err := os.OpenFile(auditLog.Path(), os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = file.WriteString("not json\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	entries, err := auditLog.Query(Query{})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestPrune(t *testing.T) {
+	auditLog := newTestAuditLog(t, 24*time.Hour)
+	now := time.Now().UTC()
+
+	require.NoError(t, auditLog.Record(Entry{ID: "old", Timestamp: now.Add(-48 * time.Hour).Format(TimestampFormat), Action: ActionCreate}))
+	require.NoError(t, auditLog.Record(Entry{ID: "new", Timestamp: now.Add(-time.Hour).Format(TimestampFormat), Action: ActionCreate}))
+
+	removed, err := auditLog.Prune(now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	entries, err := auditLog.Query(Query{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "new", entries[0].ID)
+
+	removed, err = auditLog.Prune(now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, removed)
+}
+
+func TestPrune_KeepForever(t *testing.T) {
+	auditLog := newTestAuditLog(t, 0)
+	require.NoError(t, auditLog.Record(Entry{Timestamp: "2000-01-01T00:00:00.000Z", Action: ActionCreate}))
+
+	removed, err := auditLog.Prune(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 0, removed)
+}
+
+func TestParseTime(t *testing.T) {
+	value, err := ParseTime("")
+	require.NoError(t, err)
+	assert.True(t, value.IsZero())
+
+	value, err = ParseTime("2024-05-01")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), value)
+
+	value, err = ParseTime("2024-05-01T10:00:00+02:00")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC), value.UTC())
+
+	_, err = ParseTime("yesterday")
+	assert.ErrorContains(t, err, "invalid time")
+}
+
+func TestDiff(t *testing.T) {
+	type user struct {
+		Name     string            `json:"name"`
+		Email    string            `json:"email"`
+		Password string            `json:"password,omitempty"`
+		Meta     map[string]string `json:"meta,omitempty"`
+	}
+
+	changes := Diff(
+		user{Name: "alice", Email: "alice@example.com", Password: "old"},
+		user{Name: "alice", Email: "alice@example.org", Password: "new", Meta: map[string]string{"secret_key": "value", "bucket": "demo"}},
+	)
+
+	assert.NotContains(t, changes, "name")
+	assert.Equal(t, Change{Before: "alice@example.com", After: "alice@example.org"}, changes["email"])
+	assert.Equal(t, Change{Before: RedactedValue, After: RedactedValue}, changes["password"])
+	assert.Equal(t, map[string]interface{}{"secret_key": RedactedValue, "bucket": "demo"}, changes["meta"].After)
+	assert.Nil(t, changes["meta"].Before)
+}
+
+func TestDiff_CreateAndDelete(t *testing.T) {
+	created := Diff(nil, map[string]interface{}{"id": "1", "token": "abc"})
+	assert.Equal(t, Change{After: "1"}, created["id"])
+	assert.Equal(t, Change{After: RedactedValue}, created["token"])
+
+	deleted := Diff(map[string]interface{}{"id": "1"}, nil)
+	assert.Equal(t, Change{Before: "1"}, deleted["id"])
+
+	assert.Empty(t, Diff(nil, nil))
+}
+
+func TestRecordChange(t *testing.T) {
+	ctx, requestAudit := NewRequestContext(context.Background())
+	SetResourceId(ctx, "42")
+	RecordChange(ctx, map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "b"})
+
+	assert.Equal(t, "42", requestAudit.ResourceId)
+	assert.Equal(t, Change{Before: "a", After: "b"}, requestAudit.Changes["name"])
+
+	// not audited requests are ignored
+	SetResourceId(context.Background(), "1")
+	assert.Nil(t, FromContext(context.Background()))
+}
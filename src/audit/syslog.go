@@ -0,0 +1,136 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+const (
+	// syslogFacility is LOG_AUTHPRIV, used for security and authorization messages
+	syslogFacility       = 10
+	syslogSeverityNotice = 5
+	syslogSeverityWarn   = 4
+	syslogDialTimeout    = 5 * time.Second
+)
+
+// SyslogWriter forwards the audit entries to a syslog server using RFC 5424
+// messages, the standard library syslog package is not available on Windows
+type SyslogWriter struct {
+	network  string
+	address  string
+	hostname string
+	conn     net.Conn
+	lock     sync.Mutex
+}
+
+// NewSyslogWriter creates a writer for udp://host:port or tcp://host:port,
+// addresses without a scheme use udp
+func NewSyslogWriter(address string) (*SyslogWriter, error) {
+	network := "udp"
+	if index := strings.Index(address, "://"); index >= 0 {
+		network = strings.ToLower(address[:index])
+		address = address[index+3:]
+	}
+	if network != "udp" && network != "tcp" {
+		return nil, errors.Newf("invalid syslog network %v, use udp or tcp", network)
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, errors.NewFromErrorf(err, "invalid syslog address %v", address)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	return &SyslogWriter{
+		network:  network,
+		address:  address,
+		hostname: hostname,
+	}, nil
+}
+
+// Write sends the entry, the connection is opened again if it was closed
+func (w *SyslogWriter) Write(entry Entry) error {
+	message, err := FormatSyslog(entry, w.hostname)
+	if err != nil {
+		return err
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	// tcp messages use the octet counting framing of RFC 6587
+	if w.network == "tcp" {
+		message = fmt.Sprintf("%d %s", len(message), message)
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.conn == nil {
+			conn, err := net.DialTimeout(w.network, w.address, syslogDialTimeout)
+			if err != nil {
+				return err
+			}
+			w.conn = conn
+		}
+
+		_ = w.conn.SetWriteDeadline(time.Now().Add(syslogDialTimeout))
+		if _, err = w.conn.Write([]byte(message)); err == nil {
+			return nil
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+
+	return err
+}
+
+func (w *SyslogWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// FormatSyslog returns the entry as a RFC 5424 message with the json of the
+// entry as the message body
+func FormatSyslog(entry Entry, hostname string) (string, error) {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+
+	severity := syslogSeverityNotice
+	if entry.Outcome != OutcomeSuccess {
+		severity = syslogSeverityWarn
+	}
+	if hostname == "" {
+		hostname = "-"
+	}
+	msgId := entry.Action
+	if msgId == "" {
+		msgId = "-"
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s - %s",
+		syslogFacility*8+severity,
+		entry.Timestamp,
+		hostname,
+		constants.ExecutableName,
+		os.Getpid(),
+		msgId,
+		string(body)), nil
+}
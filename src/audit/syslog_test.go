@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatSyslog(t *testing.T) {
+	entry := Entry{
+		ID:        "1",
+		Timestamp: "2024-05-01T10:00:00.000Z",
+		Actor:     "alice",
+		Action:    ActionDelete,
+		Outcome:   OutcomeSuccess,
+	}
+
+	message, err := FormatSyslog(entry, "host")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(message, "<85>1 2024-05-01T10:00:00.000Z host "+constants.ExecutableName+" "))
+	assert.Contains(t, message, " delete - {")
+	assert.Contains(t, message, `"actor":"alice"`)
+
+	entry.Outcome = OutcomeDenied
+	message, err = FormatSyslog(entry, "")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(message, "<84>1 2024-05-01T10:00:00.000Z - "))
+}
+
+func TestNewSyslogWriter_InvalidAddress(t *testing.T) {
+	_, err := NewSyslogWriter("http://localhost:514")
+	assert.ErrorContains(t, err, "invalid syslog network")
+
+	_, err = NewSyslogWriter("localhost")
+	assert.ErrorContains(t, err, "invalid syslog address")
+}
+
+func TestSyslogWriter_Udp(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	writer, err := NewSyslogWriter("udp://" + listener.LocalAddr().String())
+	require.NoError(t, err)
+	defer writer.Close()
+
+	require.NoError(t, writer.Write(Entry{ID: "1", Timestamp: "2024-05-01T10:00:00.000Z", Action: ActionCreate, Outcome: OutcomeSuccess}))
+
+	buffer := make([]byte, 4096)
+	_ = listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := listener.ReadFrom(buffer)
+	require.NoError(t, err)
+	assert.Contains(t, string(buffer[:n]), `"id":"1"`)
+}
+
+func TestRecord_ForwardsToSyslog(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	auditLog := newTestAuditLog(t, 0)
+	auditLog.syslog, err = NewSyslogWriter(listener.LocalAddr().String())
+	require.NoError(t, err)
+
+	require.NoError(t, auditLog.Record(Entry{Actor: "alice", Action: ActionUpdate, Outcome: OutcomeFailure}))
+
+	buffer := make([]byte, 4096)
+	_ = listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := listener.ReadFrom(buffer)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(buffer[:n]), "<84>1 "))
+}
@@ -3,22 +3,37 @@ package basecontext
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/Parallels/prl-devops-service/constants"
 	"github.com/Parallels/prl-devops-service/models"
 )
 
 type AuthorizationContext struct {
-	RequestId          string
-	Issuer             string
-	Scope              string
-	Audiences          []string
-	BaseUrl            string
-	IsAuthorized       bool
-	IsMicroService     bool
-	IsSuperUser        bool
-	AuthorizedBy       string
-	ApiKeyName         string
+	RequestId      string
+	Issuer         string
+	Scope          string
+	Audiences      []string
+	BaseUrl        string
+	IsAuthorized   bool
+	IsMicroService bool
+	IsSuperUser    bool
+	AuthorizedBy   string
+	ApiKeyName     string
+	// IsScopedApiKey is set when the claims of the request are limited by the
+	// scope of the api key, they cannot be overridden by the X-Claims header
+	IsScopedApiKey bool
+	// ScopedCatalogs and ScopedHostTags are the catalogs and host tags the api
+	// key of the request is restricted to, empty when it is not restricted
+	ScopedCatalogs []string
+	ScopedHostTags []string
+	// ApiKeyRateLimit is the requests per minute of the scope of the api key
+	ApiKeyRateLimit int
+	// SessionId and TokenId identify the bearer token of the request, they
+	// are empty for api keys and tokens issued by an identity provider
+	SessionId          string
+	TokenId            string
+	TokenExpiresAt     time.Time
 	User               *models.ApiUser
 	AuthorizationError *models.OAuthErrorResponse
 	// InjectedClaims/InjectedRoles are set from X-Claims/X-Roles headers on
@@ -161,6 +176,7 @@ func CloneAuthorizationContext() *AuthorizationContext {
 		IsMicroService:     baseAuthorizationCtx.IsMicroService,
 		IsSuperUser:        baseAuthorizationCtx.IsSuperUser,
 		ApiKeyName:         baseAuthorizationCtx.ApiKeyName,
+		ApiKeyRateLimit:    baseAuthorizationCtx.ApiKeyRateLimit,
 		AuthorizationError: baseAuthorizationCtx.AuthorizationError,
 	}
 
@@ -169,6 +185,14 @@ func CloneAuthorizationContext() *AuthorizationContext {
 		newContext.InjectedClaims = make([]string, len(baseAuthorizationCtx.InjectedClaims))
 		copy(newContext.InjectedClaims, baseAuthorizationCtx.InjectedClaims)
 	}
+	if len(baseAuthorizationCtx.ScopedCatalogs) > 0 {
+		newContext.ScopedCatalogs = make([]string, len(baseAuthorizationCtx.ScopedCatalogs))
+		copy(newContext.ScopedCatalogs, baseAuthorizationCtx.ScopedCatalogs)
+	}
+	if len(baseAuthorizationCtx.ScopedHostTags) > 0 {
+		newContext.ScopedHostTags = make([]string, len(baseAuthorizationCtx.ScopedHostTags))
+		copy(newContext.ScopedHostTags, baseAuthorizationCtx.ScopedHostTags)
+	}
 	if len(baseAuthorizationCtx.InjectedRoles) > 0 {
 		newContext.InjectedRoles = make([]string, len(baseAuthorizationCtx.InjectedRoles))
 		copy(newContext.InjectedRoles, baseAuthorizationCtx.InjectedRoles)
@@ -0,0 +1,221 @@
+package catalog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/catalog/cacheservice"
+	"github.com/Parallels/prl-devops-service/catalog/models"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/helpers"
+	"github.com/Parallels/prl-devops-service/jobs"
+	api_models "github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+	"github.com/Parallels/prl-devops-service/serviceprovider/apiclient"
+	"github.com/Parallels/prl-devops-service/serviceprovider/system"
+	"github.com/Parallels/prl-devops-service/version"
+
+	"github.com/cjlapao/common-go/helper/http_helper"
+)
+
+// GetCacheStatus resolves the manifest for a cache request and checks if it is
+// already in the local cache. Channels are resolved to the version they point
+// to and the request is updated so a later AsyncCache caches the same version.
+func (s *CatalogManifestService) GetCacheStatus(r *models.CacheCatalogManifestRequest) (*models.CacheCatalogManifestResponse, error) {
+	cacheService, manifest, err := s.newCacheServiceForRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CacheCatalogManifestResponse{
+		ID:           manifest.ID,
+		CatalogId:    manifest.CatalogId,
+		Version:      manifest.Version,
+		Architecture: manifest.Architecture,
+		Cached:       cacheService.IsCached(),
+	}, nil
+}
+
+// AsyncCache caches a catalog manifest in the background without pulling a
+// machine, the download only starts inside the configured transfer windows.
+func (s *CatalogManifestService) AsyncCache(jobId string, r *models.CacheCatalogManifestRequest) {
+	if s.ctx == nil {
+		s.ctx = basecontext.NewRootBaseContext()
+	}
+
+	jobManager := jobs.Get(s.ctx)
+	if jobManager == nil {
+		s.ns.NotifyErrorf("Job Manager is not available")
+		return
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			s.ns.NotifyErrorf("AsyncCache panic recovered for job %v: %v", jobId, rec)
+			_ = jobManager.MarkJobError(jobId, fmt.Errorf("internal error: %v", rec))
+		}
+	}()
+
+	r.JobId = jobId
+	s.ns.InitJob(jobId)
+	cacheService, manifest, err := s.newCacheServiceForRequest(r)
+	if err != nil {
+		_ = jobManager.MarkJobError(jobId, err)
+		return
+	}
+
+	if !cacheService.IsCached() {
+		if err := cacheService.Cache(); err != nil {
+			_ = jobManager.MarkJobError(jobId, errors.Newf("Error caching manifest %v: %v", manifest.Name, err))
+			return
+		}
+	}
+	s.recordCacheSource(manifest, r.Connection)
+
+	_ = jobManager.MarkJobCompleteWithRecord(jobId, "Catalog Manifest Cached", manifest.ID, manifest.Name, "catalog_cache", "")
+}
+
+func (s *CatalogManifestService) newCacheServiceForRequest(r *models.CacheCatalogManifestRequest) (*cacheservice.CacheService, *models.VirtualMachineCatalogManifest, error) {
+	if !config.Get().IsCatalogCachingEnable() {
+		return nil, nil, errors.NewWithCode("catalog caching is disabled", 400)
+	}
+
+	if r.Channel != "" {
+		channelVersion, err := s.getChannelVersion(r.CatalogId, r.Channel, r.Connection)
+		if err != nil {
+			return nil, nil, err
+		}
+		r.Version = channelVersion
+		r.Channel = ""
+	}
+
+	manifest, err := s.getManifest(r.JobId, r.CatalogId, r.Version, r.Connection)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if manifest.PackFile == "" || manifest.MetadataFile == "" || manifest.Path == "" {
+		return nil, nil, errors.Newf("Manifest %v is not correctly generated", manifest.Name)
+	}
+
+	rs, err := s.GetProviderFromConnection(manifest.Provider.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	rs.SetJobId(r.JobId)
+	rs.SetTransferRateLimit(r.MaxTransferRateKb * 1024)
+
+	cacheService, err := cacheservice.NewCacheService(s.ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cacheRequest := cacheservice.NewCacheRequest(s.ctx, manifest, rs, r.JobId)
+	cacheRequest.Background = true
+	if err := cacheService.WithRequest(cacheRequest); err != nil {
+		return nil, nil, err
+	}
+
+	return cacheService, manifest, nil
+}
+
+// channelCandidate is a manifest version tagged with the requested channel
+type channelCandidate struct {
+	version   string
+	updatedAt string
+}
+
+// latestChannelVersion picks the newest version of the candidates, versions
+// that are equal or cannot be compared fall back to the last update
+func latestChannelVersion(candidates []channelCandidate) (string, bool) {
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if compare := version.CompareStrings(candidates[i].version, candidates[j].version); compare != 0 {
+			return compare > 0
+		}
+		return candidates[i].updatedAt > candidates[j].updatedAt
+	})
+
+	return candidates[0].version, true
+}
+
+func channelCandidateUpdatedAt(createdAt string, updatedAt string) string {
+	if updatedAt != "" {
+		return updatedAt
+	}
+
+	return createdAt
+}
+
+// getChannelVersion returns the newest version of the catalog manifest tagged
+// with the channel for this host architecture.
+func (s *CatalogManifestService) getChannelVersion(catalogId string, channel string, connection string) (string, error) {
+	arch, err := system.Get().GetArchitecture(s.ctx)
+	if err != nil {
+		return "", errors.New("unable to determine architecture")
+	}
+
+	provider := models.CatalogManifestProvider{}
+	if err := provider.Parse(connection); err != nil {
+		return "", err
+	}
+
+	candidates := make([]channelCandidate, 0)
+	if provider.IsRemote() {
+		apiClient := apiclient.NewHttpClient(s.ctx)
+		apiClient.SetAuthorization(GetAuthenticator(&provider))
+
+		var manifests []api_models.CatalogManifest
+		path := http_helper.JoinUrl(constants.DEFAULT_API_PREFIX, "catalog", helpers.NormalizeStringUpper(catalogId))
+		if _, err := apiClient.Get(fmt.Sprintf("%s%s", provider.GetUrl(), path), &manifests); err != nil {
+			return "", errors.Newf("Error getting catalog manifest %v versions: %v", catalogId, err)
+		}
+
+		for _, manifest := range manifests {
+			if !strings.EqualFold(manifest.Architecture, arch) {
+				continue
+			}
+			for _, tag := range manifest.Tags {
+				if strings.EqualFold(tag, channel) {
+					candidates = append(candidates, channelCandidate{
+						version:   manifest.Version,
+						updatedAt: channelCandidateUpdatedAt(manifest.CreatedAt, manifest.UpdatedAt),
+					})
+					break
+				}
+			}
+		}
+	} else {
+		db := serviceprovider.Get().JsonDatabase
+		if db == nil {
+			return "", errors.New("local catalog is disabled")
+		}
+
+		manifests, err := db.GetCatalogManifestsByCatalogId(s.ctx, catalogId)
+		if err != nil {
+			return "", err
+		}
+
+		for _, manifest := range manifests {
+			if strings.EqualFold(manifest.Architecture, arch) && manifest.HasTag(channel) {
+				candidates = append(candidates, channelCandidate{
+					version:   manifest.Version,
+					updatedAt: channelCandidateUpdatedAt(manifest.CreatedAt, manifest.UpdatedAt),
+				})
+			}
+		}
+	}
+
+	if latest, ok := latestChannelVersion(candidates); ok {
+		return latest, nil
+	}
+
+	return "", errors.NewWithCodef(404, "No version of catalog manifest %v found in channel %v for architecture %v", catalogId, channel, arch)
+}
@@ -0,0 +1,221 @@
+package catalog
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/catalog/cacheservice"
+	"github.com/Parallels/prl-devops-service/catalog/models"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/constants"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/helpers"
+	"github.com/Parallels/prl-devops-service/jobs"
+	api_models "github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/secrets"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+)
+
+var (
+	ErrCacheScrubRunning = errors.NewWithCode("a cache scrub is already running", 409)
+
+	cacheScrubRunLock    sync.Mutex
+	lastCacheScrubReport *models.CacheScrubReport
+	lastCacheScrubLock   sync.RWMutex
+)
+
+// IsCacheScrubRunning returns true if a cache scrub is verifying the cache
+func IsCacheScrubRunning() bool {
+	if !cacheScrubRunLock.TryLock() {
+		return true
+	}
+	cacheScrubRunLock.Unlock()
+	return false
+}
+
+// GetLastCacheScrubReport returns the report of the last cache scrub, nil if
+// the cache was not scrubbed since the service started.
+func GetLastCacheScrubReport() *models.CacheScrubReport {
+	lastCacheScrubLock.RLock()
+	defer lastCacheScrubLock.RUnlock()
+
+	return lastCacheScrubReport
+}
+
+// StartCacheScrubLoop verifies the catalog cache on the configured interval
+func StartCacheScrubLoop(ctx basecontext.ApiContext) {
+	interval := config.Get().CatalogCacheScrubInterval()
+	if interval <= 0 || !config.Get().IsCatalogCachingEnable() {
+		return
+	}
+
+	ctx.LogInfof("Starting catalog cache scrub every %v", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := NewManifestService(ctx).ScrubCache(""); err != nil {
+			ctx.LogErrorf("Error scrubbing catalog cache: %v", err)
+		}
+	}
+}
+
+// AsyncScrubCache runs a cache scrub as a job
+func (s *CatalogManifestService) AsyncScrubCache(jobId string) {
+	jobManager := jobs.Get(s.ctx)
+	if jobManager == nil {
+		s.ns.NotifyErrorf("Job Manager is not available")
+		return
+	}
+
+	s.ns.InitJob(jobId)
+	report, err := s.ScrubCache(jobId)
+	if err != nil {
+		_ = jobManager.MarkJobError(jobId, err)
+		return
+	}
+
+	_ = jobManager.MarkJobCompleteWithRecord(jobId, "Catalog Cache Scrubbed", jobId, "cache_scrub", "catalog_cache_scrub", "")
+	s.ctx.LogInfof("Catalog cache scrub checked %v items, %v corrupted, %v repaired", report.Checked, report.Corrupted, report.Repaired)
+}
+
+// ScrubCache verifies the checksums of every cached item, quarantines the
+// corrupted ones and downloads them again from where they were cached from.
+func (s *CatalogManifestService) ScrubCache(jobId string) (*models.CacheScrubReport, error) {
+	if !config.Get().IsCatalogCachingEnable() {
+		return nil, errors.NewWithCode("catalog caching is disabled", 400)
+	}
+
+	// The scrub lock only covers the verification, repairs can wait for a
+	// transfer window and must not block the next scrub in the meantime
+	report, err := s.scrubCacheItems(jobId)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, item := range report.Items {
+		if item.Status != models.CacheScrubStatusCorrupted {
+			continue
+		}
+
+		s.ns.NotifyJobMessage(jobId, "Downloading corrupted cache item %v/%v again", item.CatalogId, item.Version)
+		err := s.repairCacheItem(jobId, item)
+		report.SetRepairResult(i, err)
+
+		event := api_models.CacheItemRepairEvent{
+			CatalogId:    item.CatalogId,
+			Version:      item.Version,
+			Architecture: item.Architecture,
+		}
+		eventType := "CACHE_ITEM_REPAIRED"
+		if err != nil {
+			s.ctx.LogErrorf("Error repairing cache item %v/%v: %v", item.CatalogId, item.Version, err)
+			event.Error = err.Error()
+			eventType = "CACHE_ITEM_REPAIR_FAILED"
+		}
+		broadcastCacheEvent(eventType, event)
+	}
+
+	report.CompletedAt = helpers.GetUtcCurrentDateTime()
+	lastCacheScrubLock.Lock()
+	lastCacheScrubReport = report
+	lastCacheScrubLock.Unlock()
+
+	broadcastCacheEvent("CACHE_SCRUB_COMPLETED", api_models.CacheScrubCompletedEvent{
+		JobId:        jobId,
+		Checked:      report.Checked,
+		Healthy:      report.Healthy,
+		Skipped:      report.Skipped,
+		Corrupted:    report.Corrupted,
+		Repaired:     report.Repaired,
+		RepairFailed: report.RepairFailed,
+	})
+
+	return report, nil
+}
+
+func (s *CatalogManifestService) scrubCacheItems(jobId string) (*models.CacheScrubReport, error) {
+	if !cacheScrubRunLock.TryLock() {
+		return nil, ErrCacheScrubRunning
+	}
+	defer cacheScrubRunLock.Unlock()
+
+	cacheService, err := cacheservice.NewCacheService(s.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return cacheService.Scrub(jobId)
+}
+
+func (s *CatalogManifestService) repairCacheItem(jobId string, item models.CacheScrubItemResult) error {
+	db := serviceprovider.Get().JsonDatabase
+	if db == nil {
+		return errors.New("database is not available")
+	}
+
+	source, err := db.GetCatalogCacheSource(s.ctx, item.CatalogId, item.Version, item.Architecture)
+	if err != nil {
+		return errors.Newf("no source recorded for cache item %v/%v", item.CatalogId, item.Version)
+	}
+
+	connection, err := secrets.Resolve(s.ctx, source.ConnectionRef)
+	if err != nil {
+		return errors.NewFromErrorf(err, "error resolving the source connection of cache item %v/%v", item.CatalogId, item.Version)
+	}
+
+	request := &models.CacheCatalogManifestRequest{
+		CatalogId:  item.CatalogId,
+		Version:    item.Version,
+		Connection: connection,
+		JobId:      jobId,
+	}
+	cacheService, _, err := s.newCacheServiceForRequest(request)
+	if err != nil {
+		return err
+	}
+	if cacheService.IsCached() {
+		return nil
+	}
+
+	return cacheService.Cache()
+}
+
+// recordCacheSource remembers where a manifest was cached from so it can be
+// downloaded again if the cached copy gets corrupted. The connection holds the
+// provider credentials, so it is kept in the secret store and the database only
+// keeps the reference to it.
+func (s *CatalogManifestService) recordCacheSource(manifest *models.VirtualMachineCatalogManifest, connection string) {
+	provider := serviceprovider.Get()
+	if provider == nil || provider.JsonDatabase == nil {
+		return
+	}
+
+	store, err := secrets.GetStore(s.ctx)
+	if err != nil {
+		s.ctx.LogWarnf("Cache source for %v/%v was not recorded, corrupted copies will not be repaired: %v", manifest.CatalogId, manifest.Version, err)
+		return
+	}
+	secretName := cacheservice.CacheSourceSecretName(manifest.CatalogId, manifest.Version, manifest.Architecture)
+	if err := store.Set(s.ctx, secretName, connection); err != nil {
+		s.ctx.LogErrorf("Error storing cache source for %v/%v: %v", manifest.CatalogId, manifest.Version, err)
+		return
+	}
+
+	if _, err := provider.JsonDatabase.SetCatalogCacheSource(s.ctx, data_models.CatalogCacheSource{
+		CatalogId:     manifest.CatalogId,
+		Version:       manifest.Version,
+		Architecture:  manifest.Architecture,
+		ConnectionRef: secrets.SecretScheme + secretName,
+	}); err != nil {
+		s.ctx.LogErrorf("Error recording cache source for %v/%v: %v", manifest.CatalogId, manifest.Version, err)
+	}
+}
+
+func broadcastCacheEvent(eventType string, body interface{}) {
+	if emitter := serviceprovider.GetEventEmitter(); emitter != nil && emitter.IsRunning() {
+		msg := api_models.NewEventMessage(constants.EventTypeCatalogCache, eventType, body)
+		go func() { _ = emitter.Broadcast(msg) }()
+	}
+}
@@ -0,0 +1,37 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatestChannelVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []channelCandidate
+		expected   string
+	}{
+		{"newest version", []channelCandidate{
+			{version: "1.9.0", updatedAt: "2024-06-01T10:00:00Z"},
+			{version: "1.10.0", updatedAt: "2024-05-01T10:00:00Z"},
+			{version: "1.2.0", updatedAt: "2024-07-01T10:00:00Z"},
+		}, "1.10.0"},
+		{"last updated when versions cannot be compared", []channelCandidate{
+			{version: "monterey", updatedAt: "2024-05-01T10:00:00Z"},
+			{version: "sonoma", updatedAt: "2024-06-01T10:00:00Z"},
+			{version: "ventura", updatedAt: "2024-04-01T10:00:00Z"},
+		}, "sonoma"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			latest, ok := latestChannelVersion(tt.candidates)
+			assert.True(t, ok)
+			assert.Equal(t, tt.expected, latest)
+		})
+	}
+
+	_, ok := latestChannelVersion(nil)
+	assert.False(t, ok)
+}
@@ -0,0 +1,137 @@
+package cacheservice
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/catalog/models"
+)
+
+const (
+	EvictionPolicyLRU          = "lru"
+	EvictionPolicyLFU          = "lfu"
+	EvictionPolicySizeWeighted = "size"
+)
+
+// EvictionPolicy decides the order in which cache items are removed when the
+// cache needs space, the first items in the sorted list are removed first.
+type EvictionPolicy interface {
+	Name() string
+	Sort(items []models.VirtualMachineCatalogManifest)
+}
+
+// GetEvictionPolicy returns the eviction policy by name, unknown or empty
+// names fall back to least recently used.
+func GetEvictionPolicy(name string) EvictionPolicy {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case EvictionPolicyLFU:
+		return lfuEvictionPolicy{}
+	case EvictionPolicySizeWeighted, "size_weighted", "size-weighted":
+		return sizeWeightedEvictionPolicy{}
+	default:
+		return lruEvictionPolicy{}
+	}
+}
+
+func cacheLastUsed(item models.VirtualMachineCatalogManifest) time.Time {
+	lastUsed, err := time.Parse(time.RFC3339, item.CacheLastUsed)
+	if err != nil {
+		return time.Unix(0, 0)
+	}
+
+	return lastUsed
+}
+
+func cacheLastUsedDay(item models.VirtualMachineCatalogManifest) time.Time {
+	lastUsed := cacheLastUsed(item)
+	return time.Date(lastUsed.Year(), lastUsed.Month(), lastUsed.Day(), 0, 0, 0, 0, lastUsed.Location())
+}
+
+// lruEvictionPolicy removes the items that have not been used for the longest
+// time first. Like the cache always did, the last use is compared by day so
+// items used on the same day are ordered by the least used item.
+type lruEvictionPolicy struct{}
+
+func (p lruEvictionPolicy) Name() string {
+	return EvictionPolicyLRU
+}
+
+func (p lruEvictionPolicy) Sort(items []models.VirtualMachineCatalogManifest) {
+	sort.SliceStable(items, func(i, j int) bool {
+		thisDate := cacheLastUsedDay(items[i])
+		thatDate := cacheLastUsedDay(items[j])
+		if thisDate.Equal(thatDate) {
+			return items[i].CacheUsedCount < items[j].CacheUsedCount
+		}
+
+		return thisDate.Before(thatDate)
+	})
+}
+
+// lfuEvictionPolicy removes the items with the lowest use count first, ties
+// are broken by the least recently used item.
+type lfuEvictionPolicy struct{}
+
+func (p lfuEvictionPolicy) Name() string {
+	return EvictionPolicyLFU
+}
+
+func (p lfuEvictionPolicy) Sort(items []models.VirtualMachineCatalogManifest) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].CacheUsedCount == items[j].CacheUsedCount {
+			return cacheLastUsed(items[i]).Before(cacheLastUsed(items[j]))
+		}
+
+		return items[i].CacheUsedCount < items[j].CacheUsedCount
+	})
+}
+
+// sizeWeightedEvictionPolicy removes the items that take the most space for
+// the amount of times they were used first, so a single big and rarely used
+// item goes before several small ones that are used often.
+type sizeWeightedEvictionPolicy struct{}
+
+func (p sizeWeightedEvictionPolicy) Name() string {
+	return EvictionPolicySizeWeighted
+}
+
+func (p sizeWeightedEvictionPolicy) Sort(items []models.VirtualMachineCatalogManifest) {
+	score := func(item models.VirtualMachineCatalogManifest) float64 {
+		return float64(item.CacheSize) / float64(item.CacheUsedCount+1)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		thisScore := score(items[i])
+		thatScore := score(items[j])
+		if thisScore == thatScore {
+			return cacheLastUsed(items[i]).Before(cacheLastUsed(items[j]))
+		}
+
+		return thisScore > thatScore
+	})
+}
+
+// selectEvictionCandidates returns the items to remove to free the space
+// needed, in the policy order. Pinned items are never selected, the space that
+// could not be freed is returned so the caller can decide what to do.
+func selectEvictionCandidates(items []models.VirtualMachineCatalogManifest, spaceNeeded int64, policy EvictionPolicy) ([]models.VirtualMachineCatalogManifest, int64) {
+	candidates := make([]models.VirtualMachineCatalogManifest, 0)
+	for _, item := range items {
+		if !item.CachePinned {
+			candidates = append(candidates, item)
+		}
+	}
+	policy.Sort(candidates)
+
+	result := make([]models.VirtualMachineCatalogManifest, 0)
+	for _, item := range candidates {
+		if spaceNeeded <= 0 {
+			break
+		}
+		result = append(result, item)
+		spaceNeeded -= item.CacheSize
+	}
+
+	return result, spaceNeeded
+}
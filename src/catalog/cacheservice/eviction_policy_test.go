@@ -0,0 +1,96 @@
+package cacheservice
+
+import (
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/catalog/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCacheItems() []models.VirtualMachineCatalogManifest {
+	return []models.VirtualMachineCatalogManifest{
+		{CatalogId: "old-big", CacheLastUsed: "2024-01-01T10:00:00Z", CacheUsedCount: 10, CacheSize: 1000},
+		{CatalogId: "recent-small", CacheLastUsed: "2024-03-01T10:00:00Z", CacheUsedCount: 1, CacheSize: 100},
+		{CatalogId: "middle", CacheLastUsed: "2024-02-01T10:00:00Z", CacheUsedCount: 5, CacheSize: 2000},
+	}
+}
+
+func catalogIds(items []models.VirtualMachineCatalogManifest) []string {
+	result := make([]string, 0)
+	for _, item := range items {
+		result = append(result, item.CatalogId)
+	}
+	return result
+}
+
+func TestGetEvictionPolicy(t *testing.T) {
+	assert.Equal(t, EvictionPolicyLRU, GetEvictionPolicy("").Name())
+	assert.Equal(t, EvictionPolicyLRU, GetEvictionPolicy("unknown").Name())
+	assert.Equal(t, EvictionPolicyLFU, GetEvictionPolicy("LFU").Name())
+	assert.Equal(t, EvictionPolicySizeWeighted, GetEvictionPolicy("size").Name())
+	assert.Equal(t, EvictionPolicySizeWeighted, GetEvictionPolicy("size-weighted").Name())
+}
+
+func TestEvictionPolicySort(t *testing.T) {
+	tests := []struct {
+		policy   string
+		expected []string
+	}{
+		{EvictionPolicyLRU, []string{"old-big", "middle", "recent-small"}},
+		{EvictionPolicyLFU, []string{"recent-small", "middle", "old-big"}},
+		{EvictionPolicySizeWeighted, []string{"middle", "old-big", "recent-small"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			items := testCacheItems()
+			GetEvictionPolicy(tt.policy).Sort(items)
+			assert.Equal(t, tt.expected, catalogIds(items))
+		})
+	}
+}
+
+func TestEvictionPolicySort_InvalidLastUsedIsOldest(t *testing.T) {
+	items := testCacheItems()
+	items = append(items, models.VirtualMachineCatalogManifest{CatalogId: "never-used", CacheSize: 10})
+
+	GetEvictionPolicy(EvictionPolicyLRU).Sort(items)
+
+	assert.Equal(t, "never-used", items[0].CatalogId)
+}
+
+func TestEvictionPolicySort_LRUComparesByDay(t *testing.T) {
+	items := []models.VirtualMachineCatalogManifest{
+		{CatalogId: "morning-often", CacheLastUsed: "2024-01-01T08:00:00Z", CacheUsedCount: 10},
+		{CatalogId: "evening-once", CacheLastUsed: "2024-01-01T20:00:00Z", CacheUsedCount: 1},
+	}
+
+	GetEvictionPolicy(EvictionPolicyLRU).Sort(items)
+
+	assert.Equal(t, []string{"evening-once", "morning-often"}, catalogIds(items))
+}
+
+func TestSelectEvictionCandidates(t *testing.T) {
+	items, remaining := selectEvictionCandidates(testCacheItems(), 1500, GetEvictionPolicy(EvictionPolicyLRU))
+
+	assert.Equal(t, []string{"old-big", "middle"}, catalogIds(items))
+	assert.Equal(t, int64(-1500), remaining)
+}
+
+func TestSelectEvictionCandidates_NoSpaceNeeded(t *testing.T) {
+	items, remaining := selectEvictionCandidates(testCacheItems(), 0, GetEvictionPolicy(EvictionPolicyLRU))
+
+	assert.Empty(t, items)
+	assert.Equal(t, int64(0), remaining)
+}
+
+func TestSelectEvictionCandidates_SkipsPinnedItems(t *testing.T) {
+	cacheItems := testCacheItems()
+	cacheItems[0].CachePinned = true
+	cacheItems[2].CachePinned = true
+
+	items, remaining := selectEvictionCandidates(cacheItems, 1500, GetEvictionPolicy(EvictionPolicyLRU))
+
+	assert.Equal(t, []string{"recent-small"}, catalogIds(items))
+	assert.Equal(t, int64(1400), remaining)
+}
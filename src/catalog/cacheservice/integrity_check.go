@@ -3,8 +3,11 @@ package cacheservice
 import (
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/Parallels/prl-devops-service/catalog/models"
 	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/helpers"
 	"github.com/cjlapao/common-go/helper"
 )
 
@@ -23,28 +26,26 @@ func (cs *CacheService) checkCacheItemIntegrity(cachePath string) error {
 		return errors.NewWithCode("Cache is not completed", 400)
 	}
 
-	switch metadata.Type {
-	case "pvm":
-		for _, file := range requiredFileList["pvm"] {
-			filePath := filepath.Join(cachePath, file)
-			if !helper.FileExists(filePath) {
-				return errors.NewWithCodef(400, "Cache is not completed, missing file %v ", file)
-			}
-		}
-	case "macvm":
-		for _, file := range requiredFileList["macvm"] {
-			filePath := filepath.Join(cachePath, file)
-			if !helper.FileExists(filePath) {
-				return errors.NewWithCodef(400, "Cache is not completed, missing file %v ", file)
-			}
-		}
-	default:
+	return checkCacheItemFiles(metadata.Type, cachePath)
+}
+
+// checkCacheItemFiles checks that an unpacked machine has the files parallels
+// desktop needs to register it and at least one disk.
+func checkCacheItemFiles(machineType string, cachePath string) error {
+	requiredFiles, ok := requiredFileList[machineType]
+	if !ok {
 		return errors.NewWithCode("Invalid cache type", 400)
 	}
+	for _, file := range requiredFiles {
+		filePath := filepath.Join(cachePath, file)
+		if !helper.FileExists(filePath) {
+			return errors.NewWithCodef(400, "Cache is not completed, missing file %v ", file)
+		}
+	}
 
 	// Now checking if we have at least one disk file
 	foundHDD := false
-	err = filepath.Walk(cachePath, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(cachePath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -64,3 +65,59 @@ func (cs *CacheService) checkCacheItemIntegrity(cachePath string) error {
 
 	return nil
 }
+
+// verifyCacheItem recomputes the checksums of a cached item against its
+// manifest. Packed items are checked against the compressed checksum and
+// unpacked machines against the virtual machine contents list, files without
+// a checksum in the manifest are checked by size.
+func verifyCacheItem(item models.VirtualMachineCatalogManifest) error {
+	cachePath := filepath.Join(item.CacheLocalFullPath, item.CacheFileName)
+	if !helper.FileExists(cachePath) {
+		return errors.NewWithCodef(400, "Cache file %v is missing", item.CacheFileName)
+	}
+
+	if item.CacheType == models.CatalogCacheTypeFile.String() {
+		if item.CompressedChecksum == "" {
+			return nil
+		}
+		checksum, err := helpers.GetFileMD5Checksum(cachePath)
+		if err != nil {
+			return errors.NewWithCodef(500, "Error calculating checksum for %v: %v", item.CacheFileName, err)
+		}
+		if !strings.EqualFold(checksum, item.CompressedChecksum) {
+			return errors.NewWithCodef(400, "Checksum mismatch for %v, expected %v got %v", item.CacheFileName, item.CompressedChecksum, checksum)
+		}
+		return nil
+	}
+
+	if err := checkCacheItemFiles(item.Type, cachePath); err != nil {
+		return err
+	}
+
+	for _, content := range item.VirtualMachineContents {
+		if content.IsDir {
+			continue
+		}
+
+		contentPath := filepath.Join(cachePath, content.Path, content.Name)
+		info, err := os.Stat(contentPath)
+		if err != nil {
+			return errors.NewWithCodef(400, "Missing file %v", filepath.Join(content.Path, content.Name))
+		}
+		if content.Size > 0 && info.Size() != content.Size {
+			return errors.NewWithCodef(400, "Size mismatch for %v, expected %v got %v", filepath.Join(content.Path, content.Name), content.Size, info.Size())
+		}
+		if content.Checksum == "" {
+			continue
+		}
+		checksum, err := helpers.GetFileMD5Checksum(contentPath)
+		if err != nil {
+			return errors.NewWithCodef(500, "Error calculating checksum for %v: %v", contentPath, err)
+		}
+		if !strings.EqualFold(checksum, content.Checksum) {
+			return errors.NewWithCodef(400, "Checksum mismatch for %v, expected %v got %v", filepath.Join(content.Path, content.Name), content.Checksum, checksum)
+		}
+	}
+
+	return nil
+}
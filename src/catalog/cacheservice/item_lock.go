@@ -0,0 +1,77 @@
+package cacheservice
+
+import (
+	"strings"
+	"sync"
+)
+
+// cacheItemLock is a reader/writer lock for a single cache item, pulls take a
+// read lock while they copy from the cache and the scrub takes the write lock
+// before it moves an item to the quarantine.
+type cacheItemLock struct {
+	mu   sync.RWMutex
+	refs int
+}
+
+var (
+	cacheItemLocks     = make(map[string]*cacheItemLock)
+	cacheItemLocksLock sync.Mutex
+)
+
+func cacheItemLockKey(catalogId string, version string, architecture string) string {
+	return strings.ToLower(catalogId) + "/" + strings.ToLower(version) + "/" + strings.ToLower(architecture)
+}
+
+func acquireCacheItemLock(key string) *cacheItemLock {
+	cacheItemLocksLock.Lock()
+	defer cacheItemLocksLock.Unlock()
+
+	lock, ok := cacheItemLocks[key]
+	if !ok {
+		lock = &cacheItemLock{}
+		cacheItemLocks[key] = lock
+	}
+	lock.refs++
+	return lock
+}
+
+// releaseCacheItemLock drops the reference and removes the lock once nobody
+// holds or waits on it, so the map does not grow with every item ever cached.
+func releaseCacheItemLock(key string, lock *cacheItemLock) {
+	cacheItemLocksLock.Lock()
+	defer cacheItemLocksLock.Unlock()
+
+	lock.refs--
+	if lock.refs <= 0 {
+		delete(cacheItemLocks, key)
+	}
+}
+
+// RLockCacheItem blocks until no scrub is quarantining the item and returns the
+// function to release the lock.
+func RLockCacheItem(catalogId string, version string, architecture string) func() {
+	key := cacheItemLockKey(catalogId, version, architecture)
+	lock := acquireCacheItemLock(key)
+	lock.mu.RLock()
+
+	return func() {
+		lock.mu.RUnlock()
+		releaseCacheItemLock(key, lock)
+	}
+}
+
+// TryLockCacheItem takes the write lock of the item if no pull is reading it,
+// the returned function releases the lock and is nil if the item is in use.
+func TryLockCacheItem(catalogId string, version string, architecture string) func() {
+	key := cacheItemLockKey(catalogId, version, architecture)
+	lock := acquireCacheItemLock(key)
+	if !lock.mu.TryLock() {
+		releaseCacheItemLock(key, lock)
+		return nil
+	}
+
+	return func() {
+		lock.mu.Unlock()
+		releaseCacheItemLock(key, lock)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/Parallels/prl-devops-service/catalog/common"
 	"github.com/Parallels/prl-devops-service/catalog/interfaces"
 	"github.com/Parallels/prl-devops-service/catalog/models"
+	"github.com/Parallels/prl-devops-service/catalog/transferwindow"
 	"github.com/Parallels/prl-devops-service/compressor"
 	"github.com/Parallels/prl-devops-service/config"
 	"github.com/Parallels/prl-devops-service/constants"
@@ -38,6 +39,9 @@ type CacheRequest struct {
 	Manifest             *models.VirtualMachineCatalogManifest
 	RemoteStorageService interfaces.RemoteStorageService
 	JobId                string
+	// Background transfers, like cache warmups, only run inside the
+	// configured transfer windows
+	Background bool
 }
 
 func NewCacheRequest(ctx basecontext.ApiContext, catalogManifest *models.VirtualMachineCatalogManifest, rss interfaces.RemoteStorageService, jobId string) CacheRequest {
@@ -71,6 +75,7 @@ type CacheService struct {
 	cacheData           *models.CacheResponse
 	cleanupservice      *cleanupservice.CleanupService
 	JobId               string
+	background          bool
 }
 
 func NewCacheService(ctx basecontext.ApiContext) (*CacheService, error) {
@@ -111,6 +116,7 @@ func (cs *CacheService) WithRequest(r CacheRequest) error {
 	cs.packFilename = r.Manifest.PackFile
 	cs.metadataFilename = r.Manifest.MetadataFile
 	cs.JobId = r.JobId
+	cs.background = r.Background
 	// getting the checksum of the file from the remote storage provider
 	if checksum, err := r.RemoteStorageService.FileChecksum(cs.baseCtx, r.Manifest.Path, r.Manifest.PackFile); err != nil {
 		err := errors.NewWithCode("Error getting checksum for file", 500)
@@ -162,6 +168,18 @@ func (cs *CacheService) getCacheTotalSize() (int64, error) {
 }
 
 func (cs *CacheService) checkNeedCleanup() (*CleanupRequirements, error) {
+	manifestUsedSize := cs.manifest.Size * 2
+	// if we can stream then we do no need the double size request
+	if cs.rss.CanStream() && cs.cfg.IsRemoteProviderStreamEnabled() {
+		manifestUsedSize = cs.manifest.Size
+	}
+
+	return cs.checkNeedCleanupForSize(manifestUsedSize)
+}
+
+// checkNeedCleanupForSize checks if the cache needs to free space to fit a new
+// item of the given size in megabytes.
+func (cs *CacheService) checkNeedCleanupForSize(manifestUsedSize int64) (*CleanupRequirements, error) {
 	r := CleanupRequirements{
 		NeedsCleaning: false,
 		Reason:        "",
@@ -175,12 +193,8 @@ func (cs *CacheService) checkNeedCleanup() (*CleanupRequirements, error) {
 	if err != nil {
 		return nil, errors.NewFromErrorWithCode(err, 500)
 	}
-
-	manifestUsedSize := cs.manifest.Size * 2
-	// if we can stream then we do no need the double size request
-	if cs.rss.CanStream() && cs.cfg.IsRemoteProviderStreamEnabled() {
-		manifestUsedSize = cs.manifest.Size
-	}
+	r.FreeDiskSpace = freeDiskSpace
+	r.CatalogTotalSize = cacheTotalSize
 
 	// First lets check if we passed the setup thresholds in the system
 	if cs.keepFreeDiskSpace > 0 {
@@ -671,6 +685,8 @@ func (cs *CacheService) RemoveCacheItem(catalogId string, version string) error
 					}
 				}
 
+				cs.removeCacheSource(cache)
+
 				// Emit cache item removed event
 				if emitter := serviceprovider.GetEventEmitter(); emitter != nil && emitter.IsRunning() {
 					msg := global_models.NewEventMessage(constants.EventTypeCatalogCache, "CACHE_ITEM_REMOVED", global_models.CacheItemRemovedEvent{
@@ -798,18 +814,12 @@ func (cs *CacheService) Clean() error {
 		return err
 	}
 
-	// We will sort the cache items by ranking so we can delete the least used ones first
-	cacheItems.SortManifestsByRanking()
-
-	// we will now calculate the amount of items we need to delete based on the space needed
-	itemToRemove := []models.VirtualMachineCatalogManifest{}
-	for _, item := range cacheItems.Manifests {
-		if cleanupRequirement.SpaceNeeded <= 0 {
-			break
-		}
-		itemToRemove = append(itemToRemove, item)
-		cleanupRequirement.SpaceNeeded -= item.CacheSize
-	}
+	// We will sort the cache items using the eviction policy so we delete the
+	// least valuable ones first, pinned items are never removed
+	policy := GetEvictionPolicy(cs.cfg.CatalogCacheEvictionPolicy())
+	cs.baseCtx.LogDebugf("Using %v eviction policy", policy.Name())
+	itemToRemove, spaceNeeded := selectEvictionCandidates(cacheItems.Manifests, cleanupRequirement.SpaceNeeded, policy)
+	cleanupRequirement.SpaceNeeded = spaceNeeded
 
 	// If we would clear all the cache items, and we still need space, we
 	if cleanupRequirement.SpaceNeeded > 0 {
@@ -830,6 +840,96 @@ func (cs *CacheService) Clean() error {
 	return nil
 }
 
+// PreviewCleanup returns the cache items the next cleanup would remove to fit
+// a new item of the given size in megabytes, nothing is removed.
+func (cs *CacheService) PreviewCleanup(size int64) (*models.CacheCleanupPreview, error) {
+	if size < 0 {
+		return nil, errors.NewWithCode("Size cannot be negative", 400)
+	}
+
+	policy := GetEvictionPolicy(cs.cfg.CatalogCacheEvictionPolicy())
+	response := models.CacheCleanupPreview{
+		Policy: policy.Name(),
+		Items:  make([]models.VirtualMachineCatalogManifest, 0),
+	}
+
+	cleanupRequirement, err := cs.checkNeedCleanupForSize(size)
+	if err != nil {
+		return nil, err
+	}
+	response.FreeDiskSpace = cleanupRequirement.FreeDiskSpace
+	response.CacheTotalSize = cleanupRequirement.CatalogTotalSize
+
+	cacheItems, err := cs.GetAllCacheItems()
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range cacheItems.Manifests {
+		if item.CachePinned {
+			response.PinnedSize += item.CacheSize
+		}
+	}
+
+	if !cleanupRequirement.NeedsCleaning || cleanupRequirement.SpaceNeeded <= 0 {
+		response.EnoughSpace = true
+		return &response, nil
+	}
+
+	response.NeedsCleaning = true
+	response.Reason = cleanupRequirement.Reason
+	response.SpaceNeeded = cleanupRequirement.SpaceNeeded
+	if cleanupRequirement.IsFatal {
+		response.Reason = fmt.Sprintf("%v, cleaning the cache would not free enough space", cleanupRequirement.Reason)
+		return &response, nil
+	}
+
+	items, spaceNeeded := selectEvictionCandidates(cacheItems.Manifests, cleanupRequirement.SpaceNeeded, policy)
+	response.Items = items
+	for _, item := range items {
+		response.SpaceFreed += item.CacheSize
+	}
+	response.EnoughSpace = spaceNeeded <= 0
+
+	return &response, nil
+}
+
+// SetCacheItemPinned pins or unpins a cached version, pinned items are never
+// removed by the cleanup to make space for new items.
+func (cs *CacheService) SetCacheItemPinned(catalogId string, version string, pinned bool) (*models.VirtualMachineCatalogManifest, error) {
+	if catalogId == "" {
+		return nil, errors.NewWithCode("Catalog ID is empty", 400)
+	}
+	if version == "" {
+		return nil, errors.NewWithCode("Version is empty", 400)
+	}
+
+	cacheItems, err := cs.GetAllCacheItems()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cache := range cacheItems.Manifests {
+		if !strings.EqualFold(cache.CatalogId, catalogId) || !strings.EqualFold(cache.Version, version) {
+			continue
+		}
+
+		metadataFullPath := filepath.Join(cache.CacheLocalFullPath, cache.CacheMetadataName)
+		metadata, err := cs.loadCacheManifest(metadataFullPath)
+		if err != nil {
+			return nil, err
+		}
+
+		metadata.CachePinned = pinned
+		if err := cs.saveCacheManifest(*metadata, metadataFullPath); err != nil {
+			return nil, err
+		}
+
+		return metadata, nil
+	}
+
+	return nil, errors.NewWithCodef(404, "Cache not found for catalog %s and version %s", catalogId, version)
+}
+
 func (cs *CacheService) Cache() error {
 	// Setting the initial progress to 1 for the download step
 	cs.ns.UpdateStepMessagef(cs.JobId, constants.ActionDownloader, "Starting to download the pack file")
@@ -852,6 +952,17 @@ func (cs *CacheService) Cache() error {
 		return errors.NewFromErrorWithCodef(err, 500, "Error cleaning cache")
 	}
 
+	if cs.background {
+		windows := transferwindow.Get(cs.baseCtx)
+		if !windows.IsOpen(time.Now()) {
+			cs.notify(fmt.Sprintf("Waiting for the transfer window to open at %v to download %v", windows.NextOpening(time.Now()).Format(time.RFC3339), cs.manifest.Name))
+		}
+		if err := windows.WaitForWindow(cs.baseCtx.Context(), cs.JobId, cs.manifest.Name); err != nil {
+			cs.cleanupservice.Clean(cs.baseCtx)
+			return errors.NewFromErrorWithCodef(err, 500, "Error waiting for transfer window")
+		}
+	}
+
 	cs.notify("Downloading catalog pack file")
 
 	// Checking if the cached file is compressed or not and if we can stream the file and decompress on the fly
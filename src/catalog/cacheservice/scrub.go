@@ -0,0 +1,177 @@
+package cacheservice
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/catalog/models"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/helpers"
+	global_models "github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/secrets"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+)
+
+const (
+	quarantineFolderSuffix = "_quarantine"
+	quarantineRetention    = 7 * 24 * time.Hour
+)
+
+// quarantineFolder lives next to the cache folder so quarantined items do not
+// count towards the cache size and are not picked up as cache items.
+func (cs *CacheService) quarantineFolder() string {
+	return filepath.Clean(cs.cacheFolder) + quarantineFolderSuffix
+}
+
+// Scrub verifies every completed item in the cache, corrupted items are moved
+// to the quarantine folder so they are no longer used by pulls.
+func (cs *CacheService) Scrub(jobId string) (*models.CacheScrubReport, error) {
+	report := models.CacheScrubReport{
+		JobId:     jobId,
+		StartedAt: helpers.GetUtcCurrentDateTime(),
+		Items:     make([]models.CacheScrubItemResult, 0),
+	}
+
+	cs.purgeQuarantine()
+	cacheItems, err := cs.GetAllCacheItems()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, item := range cacheItems.Manifests {
+		result := models.CacheScrubItemResult{
+			CatalogId:    item.CatalogId,
+			Version:      item.Version,
+			Architecture: item.Architecture,
+			Status:       models.CacheScrubStatusHealthy,
+		}
+
+		// Items that are still being downloaded are not verified
+		if !item.CacheCompleted {
+			result.Status = models.CacheScrubStatusSkipped
+			result.Reason = "Cache item is not completed"
+			report.Add(result)
+			continue
+		}
+
+		cs.ns.NotifyJobMessage(jobId, "Verifying cache item %v/%v (%v of %v)", item.CatalogId, item.Version, i+1, len(cacheItems.Manifests))
+		if err := verifyCacheItem(item); err != nil {
+			// A pull copying from the item keeps it until the next scrub, moving
+			// the files from under it would fail the pull half way through
+			unlock := TryLockCacheItem(item.CatalogId, item.Version, item.Architecture)
+			if unlock == nil {
+				result.Status = models.CacheScrubStatusSkipped
+				result.Reason = fmt.Sprintf("%v, the item is in use and will be quarantined on the next scrub", err.Error())
+				cs.baseCtx.LogWarnf("Cache item %v/%v is corrupted but in use: %v", item.CatalogId, item.Version, err)
+				report.Add(result)
+				continue
+			}
+
+			result.Status = models.CacheScrubStatusCorrupted
+			result.Reason = err.Error()
+			cs.baseCtx.LogWarnf("Cache item %v/%v is corrupted: %v", item.CatalogId, item.Version, err)
+
+			quarantinePath, qErr := cs.quarantineCacheItem(item)
+			unlock()
+			if qErr != nil {
+				cs.baseCtx.LogErrorf("Error quarantining cache item %v/%v: %v", item.CatalogId, item.Version, qErr)
+				result.Reason = fmt.Sprintf("%v, quarantine failed: %v", result.Reason, qErr)
+			}
+			result.QuarantinePath = quarantinePath
+
+			if emitter := serviceprovider.GetEventEmitter(); emitter != nil && emitter.IsRunning() {
+				msg := global_models.NewEventMessage(constants.EventTypeCatalogCache, "CACHE_ITEM_CORRUPTED", global_models.CacheItemCorruptedEvent{
+					CatalogId:      item.CatalogId,
+					Version:        item.Version,
+					Architecture:   item.Architecture,
+					Reason:         result.Reason,
+					QuarantinePath: quarantinePath,
+				})
+				go func() { _ = emitter.Broadcast(msg) }()
+			}
+		}
+
+		report.Add(result)
+	}
+
+	return &report, nil
+}
+
+// CacheSourceSecretName is the name of the secret holding the connection a
+// cache item was downloaded with
+func CacheSourceSecretName(catalogId string, version string, architecture string) string {
+	return "catalog-cache-source-" + helpers.NormalizeString(catalogId+"-"+version+"-"+architecture)
+}
+
+// removeCacheSource forgets where an item was cached from together with the
+// secret that holds its connection.
+func (cs *CacheService) removeCacheSource(item models.VirtualMachineCatalogManifest) {
+	provider := serviceprovider.Get()
+	if provider == nil || provider.JsonDatabase == nil {
+		return
+	}
+
+	source, err := provider.JsonDatabase.GetCatalogCacheSource(cs.baseCtx, item.CatalogId, item.Version, item.Architecture)
+	if err != nil {
+		return
+	}
+	_ = provider.JsonDatabase.DeleteCatalogCacheSource(cs.baseCtx, item.CatalogId, item.Version, item.Architecture)
+
+	scheme, name, err := secrets.ParseReference(source.ConnectionRef)
+	if err != nil || scheme != secrets.SecretScheme {
+		return
+	}
+	if store, err := secrets.GetStore(cs.baseCtx); err == nil {
+		if err := store.Delete(cs.baseCtx, name); err != nil {
+			cs.baseCtx.LogWarnf("Error removing the cache source secret %v: %v", name, err)
+		}
+	}
+}
+
+// quarantineCacheItem moves the cached pack and metadata of an item to its own
+// folder in the quarantine so it can be inspected later.
+func (cs *CacheService) quarantineCacheItem(item models.VirtualMachineCatalogManifest) (string, error) {
+	destination := filepath.Join(cs.quarantineFolder(), fmt.Sprintf("%v-%v", item.CacheFileName, time.Now().Unix()))
+	if err := helpers.CreateDirIfNotExist(destination); err != nil {
+		return "", err
+	}
+
+	for _, name := range []string{item.CacheFileName, item.CacheMetadataName} {
+		if name == "" {
+			continue
+		}
+		source := filepath.Join(item.CacheLocalFullPath, name)
+		if _, err := os.Stat(source); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.Rename(source, filepath.Join(destination, name)); err != nil {
+			return destination, err
+		}
+	}
+
+	return destination, nil
+}
+
+// purgeQuarantine removes quarantined items older than the retention period.
+func (cs *CacheService) purgeQuarantine() {
+	entries, err := os.ReadDir(cs.quarantineFolder())
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if time.Since(info.ModTime()) < quarantineRetention {
+			continue
+		}
+		path := filepath.Join(cs.quarantineFolder(), entry.Name())
+		if err := os.RemoveAll(path); err != nil {
+			cs.baseCtx.LogErrorf("Error removing quarantined cache item %v: %v", path, err)
+		}
+	}
+}
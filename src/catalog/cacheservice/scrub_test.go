@@ -0,0 +1,162 @@
+package cacheservice
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/catalog/cleanupservice"
+	"github.com/Parallels/prl-devops-service/catalog/models"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/helpers"
+	"github.com/Parallels/prl-devops-service/jobs/tracker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestScrubCacheService(t *testing.T) *CacheService {
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+
+	cacheFolder := filepath.Join(t.TempDir(), "cache")
+	require.NoError(t, os.MkdirAll(cacheFolder, 0o755))
+
+	return &CacheService{
+		baseCtx:        ctx,
+		cfg:            config.Get(),
+		cacheFolder:    cacheFolder,
+		ns:             tracker.GetProgressService(),
+		cleanupservice: cleanupservice.NewCleanupService(),
+	}
+}
+
+// writeTestCacheItem creates an unpacked pvm cache item and returns the
+// path of its machine folder.
+func writeTestCacheItem(t *testing.T, cacheFolder string, name string, completed bool) string {
+	machineFolder := filepath.Join(cacheFolder, name+".pvm")
+	require.NoError(t, os.MkdirAll(machineFolder, 0o755))
+	files := map[string]string{
+		"config.pvs": "<config/>",
+		"NVRAM.dat":  "nvram",
+		"disk.hdd":   "disk",
+	}
+
+	manifest := models.VirtualMachineCatalogManifest{
+		CatalogId:      "TEST",
+		Version:        name,
+		Type:           "pvm",
+		CacheCompleted: completed,
+	}
+	for fileName, content := range files {
+		filePath := filepath.Join(machineFolder, fileName)
+		require.NoError(t, os.WriteFile(filePath, []byte(content), 0o600))
+		checksum, err := helpers.GetFileMD5Checksum(filePath)
+		require.NoError(t, err)
+		manifest.VirtualMachineContents = append(manifest.VirtualMachineContents, models.VirtualMachineManifestContentItem{
+			Name:     fileName,
+			Path:     "/",
+			Size:     int64(len(content)),
+			Checksum: checksum,
+		})
+	}
+
+	content, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(cacheFolder, name+metadataExtension), content, 0o600))
+
+	return machineFolder
+}
+
+func TestScrub_HealthyItem(t *testing.T) {
+	cs := newTestScrubCacheService(t)
+	writeTestCacheItem(t, cs.cacheFolder, "healthy", true)
+
+	report, err := cs.Scrub("")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, report.Checked)
+	assert.Equal(t, 1, report.Healthy)
+	assert.Equal(t, 0, report.Corrupted)
+}
+
+func TestScrub_SkipsIncompleteItems(t *testing.T) {
+	cs := newTestScrubCacheService(t)
+	writeTestCacheItem(t, cs.cacheFolder, "downloading", false)
+
+	report, err := cs.Scrub("")
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, report.Checked)
+	assert.Equal(t, 1, report.Skipped)
+}
+
+func TestScrub_QuarantinesCorruptedItems(t *testing.T) {
+	cs := newTestScrubCacheService(t)
+	machineFolder := writeTestCacheItem(t, cs.cacheFolder, "corrupted", true)
+	require.NoError(t, os.WriteFile(filepath.Join(machineFolder, "config.pvs"), []byte("<changed/>"), 0o600))
+
+	report, err := cs.Scrub("")
+	require.NoError(t, err)
+
+	require.Len(t, report.Items, 1)
+	assert.Equal(t, 1, report.Corrupted)
+	assert.Equal(t, models.CacheScrubStatusCorrupted, report.Items[0].Status)
+	assert.Contains(t, report.Items[0].Reason, "config.pvs")
+	assert.NoDirExists(t, machineFolder)
+	assert.NoFileExists(t, filepath.Join(cs.cacheFolder, "corrupted"+metadataExtension))
+	assert.DirExists(t, filepath.Join(report.Items[0].QuarantinePath, "corrupted.pvm"))
+	assert.FileExists(t, filepath.Join(report.Items[0].QuarantinePath, "corrupted"+metadataExtension))
+}
+
+func TestScrub_DoesNotQuarantineItemsInUse(t *testing.T) {
+	cs := newTestScrubCacheService(t)
+	machineFolder := writeTestCacheItem(t, cs.cacheFolder, "in-use", true)
+	require.NoError(t, os.WriteFile(filepath.Join(machineFolder, "config.pvs"), []byte("<changed/>"), 0o600))
+
+	unlock := RLockCacheItem("TEST", "in-use", "")
+	report, err := cs.Scrub("")
+	unlock()
+	require.NoError(t, err)
+
+	require.Len(t, report.Items, 1)
+	assert.Equal(t, models.CacheScrubStatusSkipped, report.Items[0].Status)
+	assert.Contains(t, report.Items[0].Reason, "in use")
+	assert.DirExists(t, machineFolder)
+
+	report, err = cs.Scrub("")
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Corrupted)
+	assert.NoDirExists(t, machineFolder)
+}
+
+func TestVerifyCacheItem_MissingFile(t *testing.T) {
+	cs := newTestScrubCacheService(t)
+	machineFolder := writeTestCacheItem(t, cs.cacheFolder, "missing", true)
+	require.NoError(t, os.Remove(filepath.Join(machineFolder, "disk.hdd")))
+	items, err := cs.GetAllCacheItems()
+	require.NoError(t, err)
+	require.Len(t, items.Manifests, 1)
+
+	assert.Error(t, verifyCacheItem(items.Manifests[0]))
+}
+
+func TestVerifyCacheItem_PackedFileChecksum(t *testing.T) {
+	folder := t.TempDir()
+	packFile := filepath.Join(folder, "item.pvm")
+	require.NoError(t, os.WriteFile(packFile, []byte("pack"), 0o600))
+	checksum, err := helpers.GetFileMD5Checksum(packFile)
+	require.NoError(t, err)
+
+	item := models.VirtualMachineCatalogManifest{
+		CacheLocalFullPath: folder,
+		CacheFileName:      "item.pvm",
+		CacheType:          models.CatalogCacheTypeFile.String(),
+		CompressedChecksum: checksum,
+	}
+	assert.NoError(t, verifyCacheItem(item))
+
+	item.CompressedChecksum = "invalid"
+	assert.Error(t, verifyCacheItem(item))
+}
@@ -351,6 +351,19 @@ func (s *ChunkManagerService) downloadChunk(
 	for {
 		n, err := reader.Read(buf)
 		if n > 0 {
+			// Throttling the chunk workers, the limiter is shared by all of them
+			if waitErr := request.RateLimiter.WaitContext(ctx, n); waitErr != nil {
+				mu.Lock()
+				st.chunkInfos[chunkIndex].err = waitErr
+				st.activeWorkers--
+				mu.Unlock()
+				cond.Broadcast()
+				setGlobalError(waitErr)
+				tmpFile.Close()
+				_ = os.Remove(tmpFile.Name())
+				return
+			}
+
 			if _, writeErr := tmpFile.Write(buf[:n]); writeErr != nil {
 				mu.Lock()
 				st.chunkInfos[chunkIndex].err = writeErr
@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/Parallels/prl-devops-service/jobs/tracker"
+	"github.com/Parallels/prl-devops-service/writers"
 )
 
 // DownloadRequest contains all the parameters needed for a download operation
@@ -26,6 +27,8 @@ type DownloadRequest struct {
 	JobId string
 	// Action for progress notifications (e.g. constants.ActionDownloadingPackFile)
 	Action string
+	// Optional bandwidth limiter shared by all the chunk workers, nil means no limit
+	RateLimiter *writers.RateLimiter
 }
 
 // chunkInfo tracks the state of an individual chunk during download
@@ -86,8 +86,13 @@ func (s *CatalogManifestService) Delete(catalogId string, version string, archit
 	}
 
 	for _, cleanItem := range cleanItems {
+		connection, resolveErr := s.resolveConnection(cleanItem.Provider.String())
+		if resolveErr != nil {
+			return resolveErr
+		}
+
 		for _, rs := range s.remoteServices {
-			check, checkErr := rs.Check(s.ctx, cleanItem.Provider.String())
+			check, checkErr := rs.Check(s.ctx, connection)
 			if checkErr != nil {
 				s.ns.NotifyErrorf("Error checking remote service %v: %v", rs.Name(), checkErr)
 				return checkErr
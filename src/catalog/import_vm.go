@@ -56,8 +56,14 @@ func (s *CatalogManifestService) ImportVm(r *models.ImportVmRequest) *models.Imp
 		return response
 	}
 
+	connection, resolveErr := s.resolveConnection(provider.String())
+	if resolveErr != nil {
+		response.AddError(resolveErr)
+		return response
+	}
+
 	for _, rs := range s.remoteServices {
-		check, checkErr := rs.Check(s.ctx, provider.String())
+		check, checkErr := rs.Check(s.ctx, connection)
 		if checkErr != nil {
 			s.ns.NotifyErrorf("Error checking remote service %v: %v", rs.Name(), checkErr)
 			response.AddError(checkErr)
@@ -10,6 +10,7 @@ type RemoteStorageService interface {
 	CanStream() bool
 	SetJobId(jobId string)
 	SetCurrentAction(action string)
+	SetTransferRateLimit(bytesPerSecond int64)
 	GetProviderRootPath(ctx basecontext.ApiContext) string
 	FileChecksum(ctx basecontext.ApiContext, path string, fileName string) (string, error)
 	FileSize(ctx basecontext.ApiContext, path string, fileName string) (int64, error)
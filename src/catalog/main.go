@@ -21,10 +21,12 @@ import (
 	"github.com/Parallels/prl-devops-service/catalog/providers/local"
 	"github.com/Parallels/prl-devops-service/catalog/providers/minio"
 	"github.com/Parallels/prl-devops-service/compressor"
+	"github.com/Parallels/prl-devops-service/config"
 	"github.com/Parallels/prl-devops-service/constants"
 	"github.com/Parallels/prl-devops-service/errors"
 	"github.com/Parallels/prl-devops-service/helpers"
 	"github.com/Parallels/prl-devops-service/jobs/tracker"
+	"github.com/Parallels/prl-devops-service/secrets"
 
 	"github.com/cjlapao/common-go/helper"
 )
@@ -90,8 +92,13 @@ func (s *CatalogManifestService) GetProviders() []interfaces.RemoteStorageServic
 }
 
 func (s *CatalogManifestService) GetProviderFromConnection(connectionString string) (interfaces.RemoteStorageService, error) {
+	connection, err := s.resolveConnection(connectionString)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, rs := range s.remoteServices {
-		check, checkErr := rs.Check(s.ctx, connectionString)
+		check, checkErr := rs.Check(s.ctx, connection)
 		if checkErr != nil {
 			s.ns.NotifyErrorf("Error checking remote service %v: %v", rs.Name(), checkErr)
 			return nil, checkErr
@@ -107,6 +114,18 @@ func (s *CatalogManifestService) GetProviderFromConnection(connectionString stri
 	return nil, errors.NewWithCode("remote storage service was not found", 404)
 }
 
+// resolveConnection resolves the secret references of a connection string just
+// before it is checked, the manifests keep the connection with the references
+func (s *CatalogManifestService) resolveConnection(connection string) (string, error) {
+	resolved, err := secrets.ResolveConnectionString(s.ctx, connection)
+	if err != nil {
+		s.ns.NotifyErrorf("Error resolving the secrets of the provider connection: %v", err)
+		return "", err
+	}
+
+	return resolved, nil
+}
+
 func (s *CatalogManifestService) AddRemoteService(service interfaces.RemoteStorageService) {
 	exists := false
 	for _, remoteService := range s.remoteServices {
@@ -250,7 +269,6 @@ func (s *CatalogManifestService) getManifestFiles(path string, relativePath stri
 	}
 
 	for _, file := range files {
-		// fullPath := filepath.Join(path, file.Name())
 		if file.IsDir() {
 			result = append(result, models.VirtualMachineManifestContentItem{
 				IsDir: true,
@@ -276,11 +294,14 @@ func (s *CatalogManifestService) getManifestFiles(path string, relativePath stri
 		manifestFile.Size = fileInfo.Size()
 		manifestFile.CreatedAt = fileInfo.ModTime().Format(time.RFC3339Nano)
 		manifestFile.UpdatedAt = fileInfo.ModTime().Format(time.RFC3339Nano)
-		// checksum, err := helpers.GetFileMD5Checksum(fullPath)
-		// if err != nil {
-		// 	return nil, err
-		// }
-		// manifestFile.Checksum = checksum
+		// the checksum is used by the cache scrub to verify unpacked machines
+		if config.Get().CatalogPushFileChecksums() {
+			checksum, err := helpers.GetFileMD5Checksum(filepath.Join(path, file.Name()))
+			if err != nil {
+				return nil, err
+			}
+			manifestFile.Checksum = checksum
+		}
 		result = append(result, manifestFile)
 	}
 
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+var ErrCacheMissingCatalogId = errors.NewWithCode("missing catalog id", 400)
+
+// CacheCatalogManifestRequest asks a host to cache a catalog manifest without
+// pulling a machine from it. The version can be pinned or follow a channel,
+// which is a tag on the catalog manifest, like latest.
+type CacheCatalogManifestRequest struct {
+	CatalogId         string `json:"catalog_id"`
+	Version           string `json:"version,omitempty"`
+	Channel           string `json:"channel,omitempty"`
+	Connection        string `json:"connection,omitempty"`
+	MaxTransferRateKb int64  `json:"max_transfer_rate_kb,omitempty"`
+	JobId             string `json:"-"`
+}
+
+func (r *CacheCatalogManifestRequest) Validate() error {
+	if r.CatalogId == "" {
+		return ErrCacheMissingCatalogId
+	}
+	if r.Version == "" && r.Channel == "" {
+		r.Version = constants.LATEST_TAG
+	}
+	if r.Connection == "" && !config.Get().IsCatalog() {
+		return ErrMissingConnection
+	}
+
+	return nil
+}
+
+type CacheCatalogManifestResponse struct {
+	ID           string `json:"id"`
+	CatalogId    string `json:"catalog_id"`
+	Version      string `json:"version"`
+	Architecture string `json:"architecture,omitempty"`
+	Cached       bool   `json:"cached"`
+	JobId        string `json:"job_id,omitempty"`
+}
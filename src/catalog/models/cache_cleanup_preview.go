@@ -0,0 +1,16 @@
+package models
+
+// CacheCleanupPreview lists the cache items the next cleanup would remove,
+// all sizes are in megabytes.
+type CacheCleanupPreview struct {
+	Policy         string                          `json:"policy"`
+	NeedsCleaning  bool                            `json:"needs_cleaning"`
+	Reason         string                          `json:"reason,omitempty"`
+	FreeDiskSpace  int64                           `json:"free_disk_space"`
+	CacheTotalSize int64                           `json:"cache_total_size"`
+	PinnedSize     int64                           `json:"pinned_size"`
+	SpaceNeeded    int64                           `json:"space_needed"`
+	SpaceFreed     int64                           `json:"space_freed"`
+	EnoughSpace    bool                            `json:"enough_space"`
+	Items          []VirtualMachineCatalogManifest `json:"items"`
+}
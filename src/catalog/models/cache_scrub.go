@@ -0,0 +1,66 @@
+package models
+
+type CacheScrubStatus string
+
+const (
+	CacheScrubStatusHealthy      CacheScrubStatus = "healthy"
+	CacheScrubStatusSkipped      CacheScrubStatus = "skipped"
+	CacheScrubStatusCorrupted    CacheScrubStatus = "corrupted"
+	CacheScrubStatusRepaired     CacheScrubStatus = "repaired"
+	CacheScrubStatusRepairFailed CacheScrubStatus = "repair_failed"
+)
+
+type CacheScrubItemResult struct {
+	CatalogId      string           `json:"catalog_id"`
+	Version        string           `json:"version"`
+	Architecture   string           `json:"architecture,omitempty"`
+	Status         CacheScrubStatus `json:"status"`
+	Reason         string           `json:"reason,omitempty"`
+	QuarantinePath string           `json:"quarantine_path,omitempty"`
+}
+
+// CacheScrubReport is the result of verifying every item in the catalog cache
+type CacheScrubReport struct {
+	JobId        string                 `json:"job_id,omitempty"`
+	StartedAt    string                 `json:"started_at"`
+	CompletedAt  string                 `json:"completed_at,omitempty"`
+	Checked      int                    `json:"checked"`
+	Healthy      int                    `json:"healthy"`
+	Skipped      int                    `json:"skipped"`
+	Corrupted    int                    `json:"corrupted"`
+	Repaired     int                    `json:"repaired"`
+	RepairFailed int                    `json:"repair_failed"`
+	Items        []CacheScrubItemResult `json:"items"`
+}
+
+func (r *CacheScrubReport) Add(item CacheScrubItemResult) {
+	switch item.Status {
+	case CacheScrubStatusHealthy:
+		r.Healthy++
+	case CacheScrubStatusSkipped:
+		r.Skipped++
+	case CacheScrubStatusCorrupted:
+		r.Corrupted++
+	}
+	if item.Status != CacheScrubStatusSkipped {
+		r.Checked++
+	}
+	r.Items = append(r.Items, item)
+}
+
+// SetRepairResult updates a corrupted item after trying to download it again
+func (r *CacheScrubReport) SetRepairResult(index int, err error) {
+	if index < 0 || index >= len(r.Items) {
+		return
+	}
+
+	if err != nil {
+		r.Items[index].Status = CacheScrubStatusRepairFailed
+		r.Items[index].Reason = r.Items[index].Reason + ", repair failed: " + err.Error()
+		r.RepairFailed++
+		return
+	}
+
+	r.Items[index].Status = CacheScrubStatusRepaired
+	r.Repaired++
+}
@@ -25,6 +25,7 @@ type PullCatalogManifestRequest struct {
 	Connection         string            `json:"connection,omitempty"`
 	ProviderMetadata   map[string]string `json:"provider_metadata,omitempty"`
 	StartAfterPull     bool              `json:"start_after_pull,omitempty"`
+	MaxTransferRateKb  int64             `json:"max_transfer_rate_kb,omitempty"`
 	JobId              string            `json:"job_id,omitempty"`
 	LocalMachineFolder string            `json:"-"`
 	FromPdf            bool              `json:"-"`
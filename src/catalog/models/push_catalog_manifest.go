@@ -34,6 +34,7 @@ type PushCatalogManifestRequest struct {
 	Tags                    []string               `json:"tags,omitempty"`
 	MinimumSpecRequirements MinimumSpecRequirement `json:"minimum_requirements,omitempty"`
 	PackSize                int64                  `json:"pack_size,omitempty"`
+	MaxTransferRateKb       int64                  `json:"max_transfer_rate_kb,omitempty"`
 	JobId                   string                 `json:"-"`
 }
 
@@ -57,6 +57,7 @@ type VirtualMachineCatalogManifest struct {
 	CacheType               string                              `json:"cache_type,omitempty"`
 	CacheSize               int64                               `json:"cache_size,omitempty"`
 	CacheCompleted          bool                                `json:"cache_completed,omitempty"`
+	CachePinned             bool                                `json:"cache_pinned,omitempty"`
 	CleanupRequest          *cleanupservice.CleanupService      `json:"-"`
 	Errors                  []error                             `json:"-"`
 }
@@ -43,6 +43,7 @@ type ArtifactoryProvider struct {
 	Repo          ArtifactoryRepo
 	JobId         string
 	currentAction string
+	rateLimiter   *writers.RateLimiter
 }
 
 func NewArtifactoryProvider() *ArtifactoryProvider {
@@ -77,6 +78,20 @@ func (s *ArtifactoryProvider) SetCurrentAction(action string) {
 	s.currentAction = action
 }
 
+// SetTransferRateLimit sets the bandwidth limit for the transfers of the current
+// request, it is always capped by the global limit.
+func (s *ArtifactoryProvider) SetTransferRateLimit(bytesPerSecond int64) {
+	s.rateLimiter = writers.NewTransferRateLimiter(bytesPerSecond)
+}
+
+func (s *ArtifactoryProvider) transferRateLimiter() *writers.RateLimiter {
+	if s.rateLimiter == nil {
+		return writers.GlobalRateLimiter()
+	}
+
+	return s.rateLimiter
+}
+
 func (s *ArtifactoryProvider) GetProviderRootPath(ctx basecontext.ApiContext) string {
 	return "/"
 }
@@ -182,6 +197,7 @@ func (s *ArtifactoryProvider) PushFile(ctx basecontext.ApiContext, rootLocalPath
 	pr.SetJobId(s.JobId)
 	pr.SetCorrelationId(s.JobId)
 	pr.SetPrefix("Uploading")
+	pr.SetRateLimiter(s.transferRateLimiter())
 
 	client := http.DefaultClient
 	request, err := http.NewRequestWithContext(ctx.Context(), http.MethodPut, uploadURL, pr)
@@ -245,6 +261,7 @@ func (s *ArtifactoryProvider) PullFile(ctx basecontext.ApiContext, path string,
 	progressReporter.SetCurrentAction(action)
 	progressReporter.SetPrefix("Pulling")
 	progressReporter.SetFilename(filename)
+	progressReporter.SetRateLimiter(s.transferRateLimiter())
 	err = downloadSrv.DownloadFile(url, headers, destinationFilePath, progressReporter)
 	if err != nil {
 		return err
@@ -287,6 +304,7 @@ func (s *ArtifactoryProvider) PullFileAndDecompress(ctx basecontext.ApiContext,
 	progressReporter.SetCurrentAction(action)
 	progressReporter.SetPrefix("Pulling")
 	progressReporter.SetFilename(filename)
+	progressReporter.SetRateLimiter(s.transferRateLimiter())
 	err = downloadSrv.DownloadFile(url, headers, destinationFilePath, progressReporter)
 	if err != nil {
 		return err
@@ -334,6 +352,7 @@ func (s *ArtifactoryProvider) PullFileToMemory(ctx basecontext.ApiContext, path
 	progressReporter.SetCurrentAction(action)
 	progressReporter.SetPrefix("Pulling")
 	progressReporter.SetFilename(filename)
+	progressReporter.SetRateLimiter(s.transferRateLimiter())
 	data, err := downloadSrv.DownloadFileToBytes(url, headers, progressReporter)
 	if err != nil {
 		return nil, err
@@ -42,6 +42,7 @@ type AwsS3BucketProvider struct {
 	ctx           basecontext.ApiContext
 	JobId         string
 	currentAction string
+	rateLimiter   *writers.RateLimiter
 }
 
 func NewAwsS3Provider() *AwsS3BucketProvider {
@@ -80,6 +81,20 @@ func (s *AwsS3BucketProvider) SetCurrentAction(action string) {
 	s.currentAction = action
 }
 
+// SetTransferRateLimit sets the bandwidth limit for the transfers of the current
+// request, it is always capped by the global limit.
+func (s *AwsS3BucketProvider) SetTransferRateLimit(bytesPerSecond int64) {
+	s.rateLimiter = writers.NewTransferRateLimiter(bytesPerSecond)
+}
+
+func (s *AwsS3BucketProvider) transferRateLimiter() *writers.RateLimiter {
+	if s.rateLimiter == nil {
+		return writers.GlobalRateLimiter()
+	}
+
+	return s.rateLimiter
+}
+
 func (s *AwsS3BucketProvider) Check(ctx basecontext.ApiContext, connection string) (bool, error) {
 	parts := strings.Split(connection, ";")
 	provider := ""
@@ -169,6 +184,7 @@ func (s *AwsS3BucketProvider) PushFile(ctx basecontext.ApiContext, rootLocalPath
 	cr.SetJobId(s.JobId)
 	cr.SetCorrelationId(s.JobId)
 	cr.SetPrefix("Uploading")
+	cr.SetRateLimiter(s.transferRateLimiter())
 	cid := cr.CorrelationId()
 
 	_, err = uploader.Upload(&s3manager.UploadInput{
@@ -223,6 +239,7 @@ func (s *AwsS3BucketProvider) PullFile(ctx basecontext.ApiContext, path string,
 	cw := writers.NewProgressWriter(f, fileSize, constants.ActionDownloader)
 	cw.SetFilename("")
 	cw.SetPrefix(fmt.Sprintf("Pulling %s", filename))
+	cw.SetRateLimiter(s.transferRateLimiter())
 	cid := cw.CorrelationId()
 	// Write the contents of S3 Object to the file
 	_, err = downloader.Download(cw, &s3.GetObjectInput{
@@ -565,6 +582,7 @@ func (s *AwsS3BucketProvider) pullFileAndDecompressChunck(ctx basecontext.ApiCon
 		MessagePrefix:       fmt.Sprintf("Pulling %s", filename),
 		CorrelationID:       helpers.GenerateId(),
 		Action:              constants.ActionDownloader,
+		RateLimiter:         s.transferRateLimiter(),
 	}
 
 	// Execute the download and decompress operation
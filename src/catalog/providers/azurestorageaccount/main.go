@@ -15,6 +15,7 @@ import (
 	"github.com/Parallels/prl-devops-service/constants"
 	"github.com/Parallels/prl-devops-service/helpers"
 	"github.com/Parallels/prl-devops-service/jobs/tracker"
+	"github.com/Parallels/prl-devops-service/writers"
 
 	"github.com/Azure/azure-storage-blob-go/azblob"
 )
@@ -31,6 +32,7 @@ type AzureStorageAccountProvider struct {
 	StorageAccount AzureStorageAccount
 	JobId          string
 	currentAction  string
+	rateLimiter    *writers.RateLimiter
 }
 
 func NewAzureStorageAccountProvider() *AzureStorageAccountProvider {
@@ -66,6 +68,20 @@ func (s *AzureStorageAccountProvider) SetCurrentAction(action string) {
 	s.currentAction = action
 }
 
+// SetTransferRateLimit sets the bandwidth limit for the transfers of the current
+// request, it is always capped by the global limit.
+func (s *AzureStorageAccountProvider) SetTransferRateLimit(bytesPerSecond int64) {
+	s.rateLimiter = writers.NewTransferRateLimiter(bytesPerSecond)
+}
+
+func (s *AzureStorageAccountProvider) transferRateLimiter() *writers.RateLimiter {
+	if s.rateLimiter == nil {
+		return writers.GlobalRateLimiter()
+	}
+
+	return s.rateLimiter
+}
+
 func (s *AzureStorageAccountProvider) Check(ctx basecontext.ApiContext, connection string) (bool, error) {
 	parts := strings.Split(connection, ";")
 	provider := ""
@@ -139,10 +155,13 @@ func (s *AzureStorageAccountProvider) PushFile(ctx basecontext.ApiContext, rootL
 	}
 	ns := tracker.GetProgressService()
 	startTime := time.Now()
+	// The azure sdk does not expose the reader, so we throttle in the progress callback
+	throttle := s.transferRateLimiter().ProgressThrottle()
 	_, err = azblob.UploadFileToBlockBlob(ctx.Context(), file, blobUrl, azblob.UploadToBlockBlobOptions{
 		BlockSize:   4 * 1024 * 1024,
 		Parallelism: 16,
 		Progress: func(bytesTransferred int64) {
+			throttle(bytesTransferred)
 			if ns != nil && s.JobId != "" && fileSize > 0 {
 				pct := float64(bytesTransferred) / float64(fileSize) * 100.0
 				if pct > 100 {
@@ -205,7 +224,9 @@ func (s *AzureStorageAccountProvider) PullFile(ctx basecontext.ApiContext, path
 		return nil
 	}
 
-	err = azblob.DownloadBlobToFile(downloadContext, blobUrl.BlobURL, 0, azblob.CountToEnd, file, azblob.DownloadFromBlobOptions{})
+	err = azblob.DownloadBlobToFile(downloadContext, blobUrl.BlobURL, 0, azblob.CountToEnd, file, azblob.DownloadFromBlobOptions{
+		Progress: s.transferRateLimiter().ProgressThrottle(),
+	})
 
 	return err
 }
@@ -272,7 +293,9 @@ func (s *AzureStorageAccountProvider) PullFileAndDecompress(ctx basecontext.ApiC
 	ctx.LogDebugf("Downloading blob to temporary file: %s", tempDownloadPath)
 
 	// Download the blob to the temporary file
-	err = azblob.DownloadBlobToFile(downloadContext, blob.BlobURL, 0, azblob.CountToEnd, tempDownloadFile, azblob.DownloadFromBlobOptions{})
+	err = azblob.DownloadBlobToFile(downloadContext, blob.BlobURL, 0, azblob.CountToEnd, tempDownloadFile, azblob.DownloadFromBlobOptions{
+		Progress: s.transferRateLimiter().ProgressThrottle(),
+	})
 	if err != nil {
 		return fmt.Errorf("failed to download blob to temporary file: %w", err)
 	}
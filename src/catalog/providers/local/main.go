@@ -28,6 +28,7 @@ type LocalProvider struct {
 	Config        LocalProviderConfig
 	JobId         string
 	currentAction string
+	rateLimiter   *writers.RateLimiter
 }
 
 func NewLocalProvider() *LocalProvider {
@@ -67,6 +68,20 @@ func (s *LocalProvider) SetCurrentAction(action string) {
 	s.currentAction = action
 }
 
+// SetTransferRateLimit sets the bandwidth limit for the transfers of the current
+// request, it is always capped by the global limit.
+func (s *LocalProvider) SetTransferRateLimit(bytesPerSecond int64) {
+	s.rateLimiter = writers.NewTransferRateLimiter(bytesPerSecond)
+}
+
+func (s *LocalProvider) transferRateLimiter() *writers.RateLimiter {
+	if s.rateLimiter == nil {
+		return writers.GlobalRateLimiter()
+	}
+
+	return s.rateLimiter
+}
+
 func (s *LocalProvider) Check(ctx basecontext.ApiContext, connection string) (bool, error) {
 	parts := strings.Split(connection, ";")
 	provider := ""
@@ -137,6 +152,7 @@ func (s *LocalProvider) PushFile(ctx basecontext.ApiContext, localRoot, path, fi
 	pr.SetJobId(s.JobId)
 	pr.SetCorrelationId(s.JobId)
 	pr.SetPrefix("Uploading")
+	pr.SetRateLimiter(s.transferRateLimiter())
 	_, err = io.Copy(destFile, pr)
 	return err
 }
@@ -160,7 +176,7 @@ func (s *LocalProvider) PullFile(ctx basecontext.ApiContext, path, filename, des
 	}
 	defer destFile.Close()
 
-	_, err = io.Copy(destFile, srcFile)
+	_, err = io.Copy(destFile, writers.NewThrottledReader(srcFile, s.transferRateLimiter()))
 	return err
 }
 
@@ -190,7 +206,7 @@ func (s *LocalProvider) PullFileAndDecompress(ctx basecontext.ApiContext, path,
 	}
 	defer tmpFile.Close()
 
-	if _, err = io.Copy(tmpFile, srcFile); err != nil {
+	if _, err = io.Copy(tmpFile, writers.NewThrottledReader(srcFile, s.transferRateLimiter())); err != nil {
 		return fmt.Errorf("failed to copy to temporary file: %w", err)
 	}
 	tmpFile.Close()
@@ -44,6 +44,7 @@ type MinioBucketProvider struct {
 	Bucket        MinioBucket
 	JobId         string
 	currentAction string
+	rateLimiter   *writers.RateLimiter
 }
 
 func NewMinioProvider() *MinioBucketProvider {
@@ -83,6 +84,20 @@ func (s *MinioBucketProvider) SetCurrentAction(action string) {
 	s.currentAction = action
 }
 
+// SetTransferRateLimit sets the bandwidth limit for the transfers of the current
+// request, it is always capped by the global limit.
+func (s *MinioBucketProvider) SetTransferRateLimit(bytesPerSecond int64) {
+	s.rateLimiter = writers.NewTransferRateLimiter(bytesPerSecond)
+}
+
+func (s *MinioBucketProvider) transferRateLimiter() *writers.RateLimiter {
+	if s.rateLimiter == nil {
+		return writers.GlobalRateLimiter()
+	}
+
+	return s.rateLimiter
+}
+
 func (s *MinioBucketProvider) Check(ctx basecontext.ApiContext, connection string) (bool, error) {
 	parts := strings.Split(connection, ";")
 	provider := ""
@@ -175,6 +190,7 @@ func (s *MinioBucketProvider) PushFile(ctx basecontext.ApiContext, rootLocalPath
 	cr.SetJobId(s.JobId)
 	cr.SetCorrelationId(s.JobId)
 	cr.SetPrefix("Uploading")
+	cr.SetRateLimiter(s.transferRateLimiter())
 	cid := cr.CorrelationId()
 
 	_, err = uploader.Upload(&s3manager.UploadInput{
@@ -229,6 +245,7 @@ func (s *MinioBucketProvider) PullFile(ctx basecontext.ApiContext, path string,
 	cw := writers.NewProgressWriter(f, fileSize, constants.ActionDownloader)
 	cw.SetFilename("")
 	cw.SetPrefix(fmt.Sprintf("Pulling %s", filename))
+	cw.SetRateLimiter(s.transferRateLimiter())
 	cid := cw.CorrelationId()
 	// Write the contents of S3 Object to the file
 	_, err = downloader.Download(cw, &s3.GetObjectInput{
@@ -588,6 +605,7 @@ func (s *MinioBucketProvider) pullFileAndDecompressChunk(ctx basecontext.ApiCont
 		CorrelationID:       helpers.GenerateId(),
 		JobId:               s.JobId,
 		Action:              constants.ActionDownloader,
+		RateLimiter:         s.transferRateLimiter(),
 	}
 
 	// Execute the download and decompress operation
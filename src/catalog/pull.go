@@ -136,7 +136,6 @@ func (s *CatalogManifestService) Pull(r *models.PullCatalogManifestRequest) *mod
 
 	foundProvider := false
 	response.MachineName = r.MachineName
-	apiClient := apiclient.NewHttpClient(s.ctx)
 	serviceProvider := serviceprovider.Get()
 	parallelsDesktopSvc := serviceProvider.ParallelsDesktopService
 	db := serviceProvider.JsonDatabase
@@ -166,130 +165,13 @@ func (s *CatalogManifestService) Pull(r *models.PullCatalogManifestRequest) *mod
 	// Just for test, setting everything to take way more time
 	time.Sleep(2 * time.Second)
 
-	var manifest *models.VirtualMachineCatalogManifest
-	provider := models.CatalogManifestProvider{}
 	cfg := config.Get()
-
-	if err := provider.Parse(r.Connection); err != nil {
+	manifest, err := s.getManifest(r.JobId, r.CatalogId, r.Version, r.Connection)
+	if err != nil {
 		response.AddError(err)
 		return response
 	}
 
-	// getting the provider metadata from the database
-	if provider.IsRemote() {
-		s.ns.NotifyJobMessage(r.JobId, "Checking remote catalog...")
-		manifest = &models.VirtualMachineCatalogManifest{}
-		manifest.Provider = &provider
-		apiClient.SetAuthorization(GetAuthenticator(manifest.Provider))
-		srvCtl := system.Get()
-		arch, err := srvCtl.GetArchitecture(s.ctx)
-		if err != nil {
-			response.AddError(errors.New("unable to determine architecture"))
-			return response
-		}
-
-		var catalogManifest api_models.CatalogManifest
-		path := http_helper.JoinUrl(constants.DEFAULT_API_PREFIX, "catalog", helpers.NormalizeStringUpper(r.CatalogId), helpers.NormalizeString(r.Version), arch, "download")
-		getUrl := fmt.Sprintf("%s%s", manifest.Provider.GetUrl(), path)
-		if clientResponse, err := apiClient.Get(getUrl, &catalogManifest); err != nil {
-			if clientResponse != nil && clientResponse.ApiError != nil {
-				if clientResponse.StatusCode == 401 || clientResponse.StatusCode == 403 || clientResponse.StatusCode == 400 {
-					s.ns.NotifyJobMessage(r.JobId, "Error getting catalog manifest %s: %s", path, clientResponse.ApiError.Message)
-					response.AddError(errors.New(clientResponse.ApiError.Message))
-					return response
-				}
-			}
-			if clientResponse.StatusCode == 401 || clientResponse.StatusCode == 403 {
-				s.ns.NotifyJobMessage(r.JobId, "Error getting catalog manifest %s: Unauthorized access", path)
-				response.AddError(errors.New("Unauthorized access to the catalog manifest"))
-				return response
-			}
-			if clientResponse.StatusCode == 400 {
-				s.ns.NotifyJobMessage(r.JobId, "Error getting catalog manifest %s: Bad request", path)
-				response.AddError(errors.New("Bad request to the catalog manifest"))
-				return response
-			}
-			s.ns.NotifyJobMessage(r.JobId, "Error getting catalog manifest %s: %s", path, err)
-			response.AddError(errors.Newf("Could not find a catalog manifest %s version %s for architecture %s", r.CatalogId, r.Version, arch))
-			return response
-		}
-		m := mappers.ApiCatalogManifestToCatalogManifest(catalogManifest)
-		if manifest.Provider != nil {
-			if manifest.Provider.Host != "" {
-				m.Provider.Host = manifest.Provider.Host
-			}
-			if manifest.Provider.Port != "" {
-				m.Provider.Port = manifest.Provider.Port
-			}
-			if manifest.Provider.Username != "" {
-				m.Provider.Username = manifest.Provider.Username
-			}
-			if manifest.Provider.Password != "" {
-				m.Provider.Password = manifest.Provider.Password
-			}
-			if manifest.Provider.ApiKey != "" {
-				m.Provider.ApiKey = manifest.Provider.ApiKey
-			}
-			if len(manifest.Provider.Meta) > 0 {
-				for key, value := range manifest.Provider.Meta {
-					m.Provider.Meta[key] = value
-				}
-			}
-		}
-
-		manifest = &m
-		s.ns.NotifyJobMessage(r.JobId, "Manifest %s version %s for architecture %s has been downloaded", r.CatalogId, r.Version, arch)
-		s.ns.NotifyDebugf("Remote Manifest: %v", manifest)
-	} else {
-		if disableLocalCatalog {
-			response.AddError(errors.New("local catalog is disabled"))
-			return response
-		}
-		s.ns.NotifyJobMessage(r.JobId, "Checking if the manifest exists in the local catalog")
-		dto, err := db.GetCatalogManifestByName(s.ctx, r.CatalogId)
-		if err != nil {
-			manifestErr := errors.Newf("Error getting catalog manifest %v: %v", r.CatalogId, err)
-			s.ns.NotifyJobMessage(r.JobId, "Error getting catalog manifest %s: %s", r.CatalogId, err)
-			response.AddError(manifestErr)
-			return response
-		}
-		m := mappers.DtoCatalogManifestToBase(*dto)
-		manifest = &m
-		s.ns.NotifyJobMessage(r.JobId, "Manifest %s version %s for architecture %s has been downloaded", r.CatalogId, r.Version, m.Architecture)
-		s.ns.NotifyDebugf("Local Manifest: %v", manifest)
-	}
-
-	// Checking if we have read all of the manifest correctly
-	if manifest.CatalogId == "" {
-		manifestErr := errors.Newf("manifest %v not found in the catalog", r.CatalogId)
-		s.ns.NotifyJobMessage(r.JobId, "Error getting catalog manifest %s: %s", r.CatalogId, manifestErr)
-		response.AddError(manifestErr)
-		return response
-	}
-
-	if manifest.Provider == nil {
-		manifestErr := errors.Newf("Manifest %v does not contain a valid provider", r.CatalogId)
-		s.ns.NotifyJobMessage(r.JobId, "Error getting catalog manifest %s: %s", r.CatalogId, manifestErr)
-		response.AddError(manifestErr)
-		return response
-	}
-
-	// Checking for tainted or revoked manifests
-	if manifest.Tainted {
-		manifestErr := errors.Newf("manifest %v is tainted", r.CatalogId)
-		s.ns.NotifyJobMessage(r.JobId, "Error getting catalog manifest %s: %s", r.CatalogId, manifestErr)
-		response.AddError(manifestErr)
-		return response
-	}
-
-	// Check if the manifest is revoked
-	if manifest.Revoked {
-		manifestErr := errors.Newf("manifest %v is revoked", r.CatalogId)
-		s.ns.NotifyJobMessage(r.JobId, "Error getting catalog manifest %s: %s", r.CatalogId, manifestErr)
-		response.AddError(manifestErr)
-		return response
-	}
-
 	// Check if the path for the machine exists
 	if !helper.FileExists(r.Path) {
 		manifestErr := errors.Newf("path %v does not exist", r.Path)
@@ -305,8 +187,14 @@ func (s *CatalogManifestService) Pull(r *models.PullCatalogManifestRequest) *mod
 
 	s.ns.NotifyJobMessage(r.JobId, "Finding the remote storage provider for %s", r.MachineName)
 
+	connection, resolveErr := s.resolveConnection(manifest.Provider.String())
+	if resolveErr != nil {
+		response.AddError(resolveErr)
+		return response
+	}
+
 	for _, rs := range s.remoteServices {
-		check, checkErr := rs.Check(s.ctx, manifest.Provider.String())
+		check, checkErr := rs.Check(s.ctx, connection)
 		if checkErr != nil {
 			s.ns.NotifyErrorf("Error checking remote service %v: %v", rs.Name(), checkErr)
 			response.AddError(checkErr)
@@ -319,6 +207,7 @@ func (s *CatalogManifestService) Pull(r *models.PullCatalogManifestRequest) *mod
 
 		s.ns.NotifyJobMessage(r.JobId, "Found remote service %v", rs.Name())
 		rs.SetJobId(r.JobId)
+		rs.SetTransferRateLimit(r.MaxTransferRateKb * 1024)
 		foundProvider = true
 		r.LocalMachineFolder = fmt.Sprintf("%s.%s", filepath.Join(r.Path, r.MachineName), manifest.Type)
 
@@ -451,6 +340,126 @@ func (s *CatalogManifestService) Pull(r *models.PullCatalogManifestRequest) *mod
 	return response
 }
 
+// getManifest resolves the catalog manifest for the given catalog id and
+// version, either from the remote catalog in the connection or from the local
+// catalog, and checks that it can be used.
+func (s *CatalogManifestService) getManifest(jobId string, catalogId string, version string, connection string) (*models.VirtualMachineCatalogManifest, error) {
+	apiClient := apiclient.NewHttpClient(s.ctx)
+	db := serviceprovider.Get().JsonDatabase
+	var manifest *models.VirtualMachineCatalogManifest
+	provider := models.CatalogManifestProvider{}
+
+	if err := provider.Parse(connection); err != nil {
+		return nil, err
+	}
+
+	// getting the provider metadata from the database
+	if provider.IsRemote() {
+		s.ns.NotifyJobMessage(jobId, "Checking remote catalog...")
+		manifest = &models.VirtualMachineCatalogManifest{}
+		manifest.Provider = &provider
+		apiClient.SetAuthorization(GetAuthenticator(manifest.Provider))
+		srvCtl := system.Get()
+		arch, err := srvCtl.GetArchitecture(s.ctx)
+		if err != nil {
+			return nil, errors.New("unable to determine architecture")
+		}
+
+		var catalogManifest api_models.CatalogManifest
+		path := http_helper.JoinUrl(constants.DEFAULT_API_PREFIX, "catalog", helpers.NormalizeStringUpper(catalogId), helpers.NormalizeString(version), arch, "download")
+		getUrl := fmt.Sprintf("%s%s", manifest.Provider.GetUrl(), path)
+		if clientResponse, err := apiClient.Get(getUrl, &catalogManifest); err != nil {
+			if clientResponse != nil && clientResponse.ApiError != nil {
+				if clientResponse.StatusCode == 401 || clientResponse.StatusCode == 403 || clientResponse.StatusCode == 400 {
+					s.ns.NotifyJobMessage(jobId, "Error getting catalog manifest %s: %s", path, clientResponse.ApiError.Message)
+					return nil, errors.New(clientResponse.ApiError.Message)
+				}
+			}
+			if clientResponse.StatusCode == 401 || clientResponse.StatusCode == 403 {
+				s.ns.NotifyJobMessage(jobId, "Error getting catalog manifest %s: Unauthorized access", path)
+				return nil, errors.New("Unauthorized access to the catalog manifest")
+			}
+			if clientResponse.StatusCode == 400 {
+				s.ns.NotifyJobMessage(jobId, "Error getting catalog manifest %s: Bad request", path)
+				return nil, errors.New("Bad request to the catalog manifest")
+			}
+			s.ns.NotifyJobMessage(jobId, "Error getting catalog manifest %s: %s", path, err)
+			return nil, errors.Newf("Could not find a catalog manifest %s version %s for architecture %s", catalogId, version, arch)
+		}
+		m := mappers.ApiCatalogManifestToCatalogManifest(catalogManifest)
+		if manifest.Provider != nil {
+			if manifest.Provider.Host != "" {
+				m.Provider.Host = manifest.Provider.Host
+			}
+			if manifest.Provider.Port != "" {
+				m.Provider.Port = manifest.Provider.Port
+			}
+			if manifest.Provider.Username != "" {
+				m.Provider.Username = manifest.Provider.Username
+			}
+			if manifest.Provider.Password != "" {
+				m.Provider.Password = manifest.Provider.Password
+			}
+			if manifest.Provider.ApiKey != "" {
+				m.Provider.ApiKey = manifest.Provider.ApiKey
+			}
+			if len(manifest.Provider.Meta) > 0 {
+				for key, value := range manifest.Provider.Meta {
+					m.Provider.Meta[key] = value
+				}
+			}
+		}
+
+		manifest = &m
+		s.ns.NotifyJobMessage(jobId, "Manifest %s version %s for architecture %s has been downloaded", catalogId, version, arch)
+		s.ns.NotifyDebugf("Remote Manifest: %v", manifest)
+	} else {
+		if db == nil {
+			return nil, errors.New("local catalog is disabled")
+		}
+		s.ns.NotifyJobMessage(jobId, "Checking if the manifest exists in the local catalog")
+		dto, err := db.GetCatalogManifestByName(s.ctx, catalogId)
+		if err != nil {
+			manifestErr := errors.Newf("Error getting catalog manifest %v: %v", catalogId, err)
+			s.ns.NotifyJobMessage(jobId, "Error getting catalog manifest %s: %s", catalogId, err)
+			return nil, manifestErr
+		}
+		m := mappers.DtoCatalogManifestToBase(*dto)
+		manifest = &m
+		s.ns.NotifyJobMessage(jobId, "Manifest %s version %s for architecture %s has been downloaded", catalogId, version, m.Architecture)
+		s.ns.NotifyDebugf("Local Manifest: %v", manifest)
+	}
+
+	// Checking if we have read all of the manifest correctly
+	if manifest.CatalogId == "" {
+		manifestErr := errors.Newf("manifest %v not found in the catalog", catalogId)
+		s.ns.NotifyJobMessage(jobId, "Error getting catalog manifest %s: %s", catalogId, manifestErr)
+		return nil, manifestErr
+	}
+
+	if manifest.Provider == nil {
+		manifestErr := errors.Newf("Manifest %v does not contain a valid provider", catalogId)
+		s.ns.NotifyJobMessage(jobId, "Error getting catalog manifest %s: %s", catalogId, manifestErr)
+		return nil, manifestErr
+	}
+
+	// Checking for tainted or revoked manifests
+	if manifest.Tainted {
+		manifestErr := errors.Newf("manifest %v is tainted", catalogId)
+		s.ns.NotifyJobMessage(jobId, "Error getting catalog manifest %s: %s", catalogId, manifestErr)
+		return nil, manifestErr
+	}
+
+	// Check if the manifest is revoked
+	if manifest.Revoked {
+		manifestErr := errors.Newf("manifest %v is revoked", catalogId)
+		s.ns.NotifyJobMessage(jobId, "Error getting catalog manifest %s: %s", catalogId, manifestErr)
+		return nil, manifestErr
+	}
+
+	return manifest, nil
+}
+
 func (s *CatalogManifestService) registerMachineWithParallelsDesktop(r *models.PullCatalogManifestRequest, response *models.PullCatalogManifestResponse) {
 	s.ns.StartStepf(r.JobId, constants.ActionPullRegisterVm, "Registering machine %v", r.MachineName)
 
@@ -653,6 +662,7 @@ func (s *CatalogManifestService) pullFromCache(r *models.PullCatalogManifestRequ
 			return err
 		}
 	}
+	s.recordCacheSource(manifest, r.Connection)
 
 	// if it is cached we need to skip the download and decompress steps
 	if cacheService.IsCached() {
@@ -660,7 +670,10 @@ func (s *CatalogManifestService) pullFromCache(r *models.PullCatalogManifestRequ
 		s.ns.SkipStep(r.JobId, constants.ActionDownloader, "Skipping download step")
 	}
 
-	// We now need to copy the cached folder to the local machine folder
+	// We now need to copy the cached folder to the local machine folder, the
+	// item is locked so a cache scrub does not quarantine it while we copy
+	unlock := cacheservice.RLockCacheItem(manifest.CatalogId, manifest.Version, manifest.Architecture)
+	defer unlock()
 	cacheResponse, err := cacheService.Get()
 	if err != nil {
 		s.ns.FailStepf(r.JobId, constants.ActionPullCacheStage, "Error getting cache response: %v", err)
@@ -75,8 +75,14 @@ func (s *CatalogManifestService) Push(r *models.PushCatalogManifestRequest) *mod
 	manifest := models.NewVirtualMachineCatalogManifest()
 	var err error
 
+	connection, resolveErr := s.resolveConnection(r.Connection)
+	if resolveErr != nil {
+		manifest.AddError(resolveErr)
+		return manifest
+	}
+
 	for _, rs := range s.remoteServices {
-		check, checkErr := rs.Check(s.ctx, r.Connection)
+		check, checkErr := rs.Check(s.ctx, connection)
 		if checkErr != nil {
 			s.ns.NotifyErrorf("Error checking remote service %v: %v", rs.Name(), checkErr)
 			manifest.AddError(checkErr)
@@ -91,6 +97,7 @@ func (s *CatalogManifestService) Push(r *models.PushCatalogManifestRequest) *mod
 			s.ns.NotifyDebugf("Setting job id for remote service %v", rs.Name())
 			rs.SetJobId(r.JobId)
 		}
+		rs.SetTransferRateLimit(r.MaxTransferRateKb * 1024)
 
 		// Register the job workflow now that we found the proper provider
 		s.ns.RegisterJobWorkflow(r.JobId, []tracker.JobStep{
@@ -25,8 +25,13 @@ func (s *CatalogManifestService) PushMetadata(r *models.VirtualMachineCatalogMan
 		return manifest
 	}
 	connection := r.Provider.String()
+	resolvedConnection, resolveErr := s.resolveConnection(connection)
+	if resolveErr != nil {
+		manifest.AddError(resolveErr)
+		return manifest
+	}
 	for _, rs := range s.remoteServices {
-		check, checkErr := rs.Check(s.ctx, connection)
+		check, checkErr := rs.Check(s.ctx, resolvedConnection)
 		if checkErr != nil {
 			s.ns.NotifyErrorf("Error checking remote service %v: %v", rs.Name(), checkErr)
 			manifest.AddError(checkErr)
@@ -0,0 +1,348 @@
+package transferwindow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/helpers"
+)
+
+const minutesInDay = 24 * 60
+
+var (
+	globalTransferWindowService *TransferWindowService
+	globalLock                  sync.Mutex
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Window is a daily time range in which background transfers are allowed.
+// When End is before Start the window crosses midnight and finishes on the
+// next day.
+type Window struct {
+	Days  []time.Weekday
+	Start int
+	End   int
+}
+
+// QueuedTransfer is a background transfer waiting for a window to open.
+type QueuedTransfer struct {
+	ID       string    `json:"id"`
+	JobId    string    `json:"job_id,omitempty"`
+	Name     string    `json:"name"`
+	QueuedAt time.Time `json:"queued_at"`
+	OpensAt  time.Time `json:"opens_at"`
+}
+
+type TransferWindowService struct {
+	windows []Window
+	queue   map[string]QueuedTransfer
+	mu      sync.Mutex
+}
+
+// Get returns the global service configured with CATALOG_TRANSFER_WINDOWS, an
+// invalid configuration is logged and treated as always open.
+func Get(ctx basecontext.ApiContext) *TransferWindowService {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	if globalTransferWindowService != nil {
+		return globalTransferWindowService
+	}
+
+	windows, err := Parse(config.Get().CatalogTransferWindows())
+	if err != nil {
+		ctx.LogErrorf("[TransferWindow] Invalid transfer windows configuration, transfers will not be restricted: %v", err)
+		windows = nil
+	}
+
+	globalTransferWindowService = New(windows)
+	return globalTransferWindowService
+}
+
+func New(windows []Window) *TransferWindowService {
+	return &TransferWindowService{
+		windows: windows,
+		queue:   make(map[string]QueuedTransfer),
+	}
+}
+
+// Parse reads a windows specification, entries are separated by ";" and each
+// entry has optional days followed by a time range, for example
+// "mon-fri 19:00-07:00; sat,sun 00:00-24:00" or "22:00-06:00".
+func Parse(spec string) ([]Window, error) {
+	result := make([]Window, 0)
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return result, nil
+	}
+
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		fields := strings.Fields(entry)
+		var window Window
+		var timeRange string
+		switch len(fields) {
+		case 1:
+			timeRange = fields[0]
+		case 2:
+			days, err := parseDays(fields[0])
+			if err != nil {
+				return nil, err
+			}
+			window.Days = days
+			timeRange = fields[1]
+		default:
+			return nil, errors.Newf("invalid transfer window %q", entry)
+		}
+
+		rangeParts := strings.Split(timeRange, "-")
+		if len(rangeParts) != 2 {
+			return nil, errors.Newf("invalid time range %q in transfer window %q", timeRange, entry)
+		}
+		start, err := parseClock(rangeParts[0])
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(rangeParts[1])
+		if err != nil {
+			return nil, err
+		}
+		if start == end {
+			return nil, errors.Newf("transfer window %q has the same start and end time", entry)
+		}
+
+		window.Start = start
+		window.End = end
+		result = append(result, window)
+	}
+
+	return result, nil
+}
+
+func parseDays(value string) ([]time.Weekday, error) {
+	days := make([]time.Weekday, 0)
+	for _, part := range strings.Split(strings.ToLower(value), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		if strings.Contains(part, "-") {
+			bounds := strings.Split(part, "-")
+			if len(bounds) != 2 {
+				return nil, errors.Newf("invalid day range %q", part)
+			}
+			from, okFrom := weekdays[bounds[0]]
+			to, okTo := weekdays[bounds[1]]
+			if !okFrom || !okTo {
+				return nil, errors.Newf("invalid day range %q", part)
+			}
+			for day := from; ; day = (day + 1) % 7 {
+				days = append(days, day)
+				if day == to {
+					break
+				}
+			}
+			continue
+		}
+
+		day, ok := weekdays[part]
+		if !ok {
+			return nil, errors.Newf("invalid day %q", part)
+		}
+		days = append(days, day)
+	}
+
+	return days, nil
+}
+
+func parseClock(value string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 2 {
+		return 0, errors.Newf("invalid time %q, expected HH:MM", value)
+	}
+
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, errors.Newf("invalid time %q, expected HH:MM", value)
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, errors.Newf("invalid time %q, expected HH:MM", value)
+	}
+
+	total := hours*60 + minutes
+	if hours < 0 || minutes < 0 || minutes > 59 || total > minutesInDay {
+		return 0, errors.Newf("invalid time %q, expected HH:MM", value)
+	}
+
+	return total, nil
+}
+
+func (w Window) appliesTo(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Contains checks if the given time is inside the window.
+func (w Window) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if w.Start < w.End {
+		return w.appliesTo(t.Weekday()) && minute >= w.Start && minute < w.End
+	}
+
+	// The window crosses midnight, so the early part belongs to the previous day
+	if minute >= w.Start && w.appliesTo(t.Weekday()) {
+		return true
+	}
+
+	previousDay := (t.Weekday() + 6) % 7
+	return minute < w.End && w.appliesTo(previousDay)
+}
+
+func (w Window) String() string {
+	days := make([]string, 0, len(w.Days))
+	for _, day := range w.Days {
+		days = append(days, strings.ToLower(day.String()[:3]))
+	}
+
+	timeRange := fmt.Sprintf("%02d:%02d-%02d:%02d", w.Start/60, w.Start%60, w.End/60, w.End%60)
+	if len(days) == 0 {
+		return timeRange
+	}
+
+	return fmt.Sprintf("%s %s", strings.Join(days, ","), timeRange)
+}
+
+func (s *TransferWindowService) Windows() []Window {
+	return s.windows
+}
+
+// IsRestricted returns true if there are windows configured.
+func (s *TransferWindowService) IsRestricted() bool {
+	return len(s.windows) > 0
+}
+
+// IsOpen checks if background transfers are allowed at the given time.
+func (s *TransferWindowService) IsOpen(t time.Time) bool {
+	if !s.IsRestricted() {
+		return true
+	}
+
+	for _, window := range s.windows {
+		if window.Contains(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NextOpening returns the next time a window opens, or the given time if a
+// window is already open.
+func (s *TransferWindowService) NextOpening(t time.Time) time.Time {
+	if s.IsOpen(t) {
+		return t
+	}
+
+	// Windows have minute precision, so walking a week minute by minute is
+	// enough to find the next opening
+	next := t.Truncate(time.Minute)
+	for i := 0; i <= 7*minutesInDay; i++ {
+		next = next.Add(time.Minute)
+		if s.IsOpen(next) {
+			return next
+		}
+	}
+
+	return t
+}
+
+// WaitForWindow blocks until a transfer window is open or the context is done,
+// the transfer is listed as queued while it waits.
+func (s *TransferWindowService) WaitForWindow(ctx context.Context, jobId string, name string) error {
+	now := time.Now()
+	if s.IsOpen(now) {
+		return nil
+	}
+
+	queued := QueuedTransfer{
+		ID:       helpers.GenerateId(),
+		JobId:    jobId,
+		Name:     name,
+		QueuedAt: now,
+		OpensAt:  s.NextOpening(now),
+	}
+
+	s.mu.Lock()
+	s.queue[queued.ID] = queued
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.queue, queued.ID)
+		s.mu.Unlock()
+	}()
+
+	for {
+		wait := time.Until(s.NextOpening(time.Now()))
+		if wait <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+			if s.IsOpen(time.Now()) {
+				return nil
+			}
+		}
+	}
+}
+
+// QueuedTransfers returns the transfers waiting for a window, oldest first.
+func (s *TransferWindowService) QueuedTransfers() []QueuedTransfer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]QueuedTransfer, 0, len(s.queue))
+	for _, item := range s.queue {
+		result = append(result, item)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].QueuedAt.Before(result[j].QueuedAt)
+	})
+
+	return result
+}
@@ -0,0 +1,100 @@
+package transferwindow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 2026-10-19 is a Monday
+func at(day int, hour int, minute int) time.Time {
+	return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParse_Empty(t *testing.T) {
+	windows, err := Parse("")
+	require.NoError(t, err)
+	assert.Empty(t, windows)
+	assert.True(t, New(windows).IsOpen(at(19, 12, 0)))
+}
+
+func TestParse_DaysAndRanges(t *testing.T) {
+	windows, err := Parse("mon-fri 19:00-07:00; sat,sun 00:00-24:00")
+	require.NoError(t, err)
+	require.Len(t, windows, 2)
+
+	assert.Equal(t, []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, windows[0].Days)
+	assert.Equal(t, 19*60, windows[0].Start)
+	assert.Equal(t, 7*60, windows[0].End)
+	assert.Equal(t, []time.Weekday{time.Saturday, time.Sunday}, windows[1].Days)
+	assert.Equal(t, "mon,tue,wed,thu,fri 19:00-07:00", windows[0].String())
+}
+
+func TestParse_WrappingDayRange(t *testing.T) {
+	windows, err := Parse("fri-mon 10:00-12:00")
+	require.NoError(t, err)
+	assert.Equal(t, []time.Weekday{time.Friday, time.Saturday, time.Sunday, time.Monday}, windows[0].Days)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	specs := []string{
+		"funday 10:00-12:00",
+		"mon 10:00",
+		"mon 25:00-26:00",
+		"mon 10:00-10:00",
+		"mon 10:61-11:00",
+		"mon tue 10:00-11:00",
+	}
+
+	for _, spec := range specs {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestIsOpen_CrossingMidnight(t *testing.T) {
+	windows, err := Parse("mon-fri 19:00-07:00")
+	require.NoError(t, err)
+	svc := New(windows)
+
+	assert.False(t, svc.IsOpen(at(19, 12, 0)), "monday noon")
+	assert.True(t, svc.IsOpen(at(19, 19, 0)), "monday evening")
+	assert.True(t, svc.IsOpen(at(20, 6, 59)), "tuesday early morning")
+	assert.False(t, svc.IsOpen(at(20, 7, 0)), "tuesday morning")
+	assert.True(t, svc.IsOpen(at(24, 3, 0)), "saturday early morning belongs to friday")
+	assert.False(t, svc.IsOpen(at(24, 20, 0)), "saturday evening")
+	assert.False(t, svc.IsOpen(at(19, 3, 0)), "monday early morning belongs to sunday")
+}
+
+func TestNextOpening(t *testing.T) {
+	windows, err := Parse("sat,sun 00:00-24:00")
+	require.NoError(t, err)
+	svc := New(windows)
+
+	assert.Equal(t, at(24, 0, 0), svc.NextOpening(at(19, 12, 30)))
+	assert.Equal(t, at(25, 10, 0), svc.NextOpening(at(25, 10, 0)))
+}
+
+func TestWaitForWindow_QueuesUntilCancelled(t *testing.T) {
+	now := time.Now()
+	closed := Window{Start: (now.Hour()*60 + now.Minute() + 120) % minutesInDay, End: (now.Hour()*60 + now.Minute() + 180) % minutesInDay}
+	svc := New([]Window{closed})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- svc.WaitForWindow(ctx, "job-1", "warmup")
+	}()
+
+	assert.Eventually(t, func() bool {
+		return len(svc.QueuedTransfers()) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "job-1", svc.QueuedTransfers()[0].JobId)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Empty(t, svc.QueuedTransfers())
+}
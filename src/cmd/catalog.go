@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/Parallels/prl-devops-service/basecontext"
 	"github.com/Parallels/prl-devops-service/constants"
@@ -24,7 +25,7 @@ func processCatalog(ctx basecontext.ApiContext, cmd string, operation string, fi
 	_ = os.Setenv(constants.SOURCE_ENV_VAR, "catalog")
 	processTelemetry(cmd)
 
-	if operation != "list" {
+	if operation != "list" && operation != "schema" {
 		if filePath == "" {
 			ctx.LogInfof("The filePath is empty")
 			filePath = helper.GetFlagValue(constants.FILE_FLAG, "")
@@ -51,6 +52,14 @@ func processCatalog(ctx basecontext.ApiContext, cmd string, operation string, fi
 	case "pull":
 		fmt.Println("Starting pull, this can take a while...")
 		processCatalogPullCmd(ctx, filePath)
+	case "convert":
+		processCatalogConvertCmd(ctx, filePath)
+	case "schema":
+		processCatalogSchemaCmd()
+	case "validate":
+		processCatalogValidateCmd(ctx, filePath)
+	case "plan":
+		processCatalogPlanCmd(ctx, filePath)
 	case "delete":
 		fmt.Println("Not implemented yet")
 	case "import":
@@ -73,13 +82,50 @@ func processCatalogHelp() {
 	fmt.Println("  pull <catalog>\t\t\tPull a catalog from the server")
 	fmt.Println("  delete <catalog>\t\t\tDelete a catalog from the server")
 	fmt.Println("  import <catalog> <file>\t\tImport a catalog from a file")
+	fmt.Println("  convert <file> --format=<format>\tConvert a pd file to the pdfile, yaml or json format")
+	fmt.Println("  schema\t\t\t\tPrint the JSON Schema of the yaml and json pd files")
+	fmt.Println("  validate <file>\t\t\tLint a pd file without running it")
+	fmt.Println("  plan <file>\t\t\t\tPrint what running a pd file would do without transferring data")
+}
+
+// isPdFilePath returns true if the path has one of the pd file extensions
+func isPdFilePath(path string) bool {
+	lowerPath := strings.ToLower(path)
+	for _, extension := range []string{".pdfile", ".yaml", ".yml", ".json"} {
+		if strings.HasSuffix(lowerPath, extension) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func catalogInitPdFile(ctx basecontext.ApiContext, cmd string, filepath string) *pdfile.PDFileService {
+	pdFile := catalogLoadPdFile(ctx, cmd, filepath)
+	svc := pdfile.NewPDFileService(ctx, pdFile)
+
+	validationDiag := svc.Validate()
+	if validationDiag.HasErrors() {
+		ctx.EnableLog()
+		ctx.ToggleLogTimestamps(false)
+		ctx.LogErrorf("There was errors validating the pd file:")
+		for _, err := range validationDiag.Errors() {
+			ctx.LogErrorf("  - %v", err)
+		}
+
+		os.Exit(1)
+	}
+
+	return svc
+}
+
+// catalogLoadPdFile loads the pd file and applies the flags and the defaults
+// without validating it
+func catalogLoadPdFile(ctx basecontext.ApiContext, cmd string, filepath string) *models.PDFile {
 	var pdFile *models.PDFile
 	var diag *diagnostics.PDFileDiagnostics
 	if filepath != "" {
-		pdFile, diag = pdfile.Load(ctx, filepath)
+		pdFile, diag = pdfile.LoadWithArgs(ctx, filepath, catalogGetArgs())
 		if diag.HasErrors() {
 			ctx.EnableLog()
 			ctx.ToggleLogTimestamps(false)
@@ -89,50 +135,58 @@ func catalogInitPdFile(ctx basecontext.ApiContext, cmd string, filepath string)
 			}
 			os.Exit(1)
 		}
-		catalogGetFlags(pdFile)
 	} else {
 		pdFile = models.NewPdFile()
-		catalogGetFlags(pdFile)
-	}
-	if pdFile.Destination == "" {
-		pdService := parallelsdesktop.New(ctx)
-		if pdService != nil {
-			info, err := pdService.GetInfo()
-			if err != nil {
-				ctx.LogErrorf("Error getting info from parallels desktop: %v", err)
-			}
-			if err == nil && info != nil {
-				pdFile.Destination = info.VMHome
-			}
-		}
 	}
+	pdFile.TargetStage = helper.GetFlagValue(constants.PD_FILE_STAGE_FLAG, "")
 
-	if cmd != "" {
-		pdFile.Command = cmd
+	// the flags and defaults apply to every stage of a multi-stage file
+	pdFiles := []*models.PDFile{pdFile}
+	for _, stage := range pdFile.Stages {
+		pdFiles = append(pdFiles, &stage.PDFile)
 	}
+	for _, item := range pdFiles {
+		catalogGetFlags(item)
+		if item.Destination == "" {
+			pdService := parallelsdesktop.New(ctx)
+			if pdService != nil {
+				info, err := pdService.GetInfo()
+				if err != nil {
+					ctx.LogErrorf("Error getting info from parallels desktop: %v", err)
+				}
+				if err == nil && info != nil {
+					item.Destination = info.VMHome
+				}
+			}
+		}
 
-	if pdFile.Owner == "" {
-		user, _ := system.Get().GetCurrentUser(ctx)
-		if user != "" {
-			pdFile.Owner = user
+		if cmd != "" && len(pdFile.Stages) == 0 {
+			item.Command = cmd
+		}
+
+		if item.Owner == "" {
+			user, _ := system.Get().GetCurrentUser(ctx)
+			if user != "" {
+				item.Owner = user
+			}
 		}
 	}
 
-	svc := pdfile.NewPDFileService(ctx, pdFile)
+	return pdFile
+}
 
-	validationDiag := svc.Validate()
-	if validationDiag.HasErrors() {
-		ctx.EnableLog()
-		ctx.ToggleLogTimestamps(false)
-		ctx.LogErrorf("There was errors validating the pd file:")
-		for _, err := range validationDiag.Errors() {
-			ctx.LogErrorf("  - %v", err)
+// catalogGetArgs returns the --arg=NAME=VALUE flags used to override the ARG
+// declarations of a pd file
+func catalogGetArgs() map[string]string {
+	args := make(map[string]string)
+	for _, arg := range helper.GetFlagArrayValue(constants.PD_FILE_ARG_FLAG) {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) != "" {
+			args[strings.TrimSpace(parts[0])] = parts[1]
 		}
-
-		os.Exit(1)
 	}
 
-	return svc
+	return args
 }
 
 func catalogGetFlags(pdFile *models.PDFile) {
@@ -210,6 +264,80 @@ func processCatalogRunCmd(ctx basecontext.ApiContext, filepath string) {
 	ctx.LogInfof("%v", out)
 }
 
+func processCatalogConvertCmd(ctx basecontext.ApiContext, filepath string) {
+	pdFile, diag := pdfile.LoadWithArgs(ctx, filepath, catalogGetArgs())
+	if diag.HasErrors() {
+		for _, err := range diag.Errors() {
+			fmt.Println(err)
+		}
+		os.Exit(1)
+	}
+
+	format := helper.GetFlagValue(constants.PD_FILE_FORMAT_FLAG, "")
+	if format == "" {
+		// converting to the other format when none is set
+		format = pdfile.FormatYaml
+		if pdfile.GetFormat(filepath) != pdfile.FormatPDFile {
+			format = pdfile.FormatPDFile
+		}
+	}
+
+	out, err := pdfile.Format(pdFile, format)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Print(out)
+}
+
+func processCatalogValidateCmd(ctx basecontext.ApiContext, filepath string) {
+	pdFile := catalogLoadPdFile(ctx, "", filepath)
+	diag := pdfile.NewPDFileService(ctx, pdFile).Lint()
+
+	for _, warning := range diag.Warnings() {
+		fmt.Printf("warning: %v\n", warning)
+	}
+	for _, err := range diag.Errors() {
+		fmt.Printf("error: %v\n", err)
+	}
+	if diag.HasErrors() {
+		fmt.Printf("%v has %v errors\n", filepath, len(diag.Errors()))
+		os.Exit(1)
+	}
+
+	fmt.Printf("%v is valid\n", filepath)
+}
+
+func processCatalogPlanCmd(ctx basecontext.ApiContext, filepath string) {
+	svc := catalogInitPdFile(ctx, "", filepath)
+	ctx.DisableLog()
+
+	steps, diag := svc.Plan(ctx)
+	for _, step := range steps {
+		fmt.Println(step.String())
+	}
+	for _, warning := range diag.Warnings() {
+		fmt.Printf("warning: %v\n", warning)
+	}
+	for _, err := range diag.Errors() {
+		fmt.Printf("error: %v\n", err)
+	}
+	if diag.HasErrors() {
+		os.Exit(1)
+	}
+}
+
+func processCatalogSchemaCmd() {
+	out, err := pdfile.SchemaJSON()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Println(out)
+}
+
 func processCatalogListCmd(ctx basecontext.ApiContext, filepath string) {
 	svc := catalogInitPdFile(ctx, "list", filepath)
 
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
+	"github.com/Parallels/prl-devops-service/security/envelope"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+	"github.com/cjlapao/common-go/helper"
+)
+
+func processDatabase(ctx basecontext.ApiContext, cmd string, operation string) {
+	if helper.GetFlagSwitch(constants.HELP_FLAG, false) || operation == "help" || operation == "" {
+		processHelp(constants.DATABASE_COMMAND)
+		os.Exit(0)
+	}
+	_ = os.Setenv(constants.SOURCE_ENV_VAR, constants.DATABASE_COMMAND)
+
+	processTelemetry(cmd)
+	ctx.ToggleLogTimestamps(false)
+	filename, err := getDatabaseFilename(ctx)
+	if err != nil {
+		ctx.LogErrorf("Error finding the database file: %v", err.Error())
+		os.Exit(1)
+	}
+
+	switch operation {
+	case "audit":
+		processDatabaseAudit(ctx, filename)
+	case "rotate-key":
+		processDatabaseRotateKey(ctx, filename)
+	default:
+		ctx.LogErrorf("Invalid operation %v, allowed operations are audit and rotate-key", operation)
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
+
+func processDatabaseAudit(ctx basecontext.ApiContext, filename string) {
+	records, err := data.AuditDatabaseCredentials(ctx, filename)
+	if err != nil {
+		ctx.LogErrorf("Error auditing the database: %v", err.Error())
+		os.Exit(1)
+	}
+
+	plaintext := 0
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "COLLECTION\tRECORD\tFIELD\tSTATE\tKEY")
+	for _, record := range records {
+		if record.State == data.CredentialStatePlaintext {
+			plaintext++
+		}
+		fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\n", record.Collection, record.RecordId, record.Field, record.State, record.KeyId)
+	}
+	_ = writer.Flush()
+
+	fmt.Printf("\n%d credentials found, %d stored in plain text\n", len(records), plaintext)
+	if plaintext > 0 {
+		os.Exit(2)
+	}
+}
+
+func processDatabaseRotateKey(ctx basecontext.ApiContext, filename string) {
+	newKeyFile := helper.GetFlagValue(constants.NEW_KEY_FILE_FLAG, "")
+	if newKeyFile == "" {
+		ctx.LogErrorf("The --%v flag is required", constants.NEW_KEY_FILE_FLAG)
+		os.Exit(1)
+	}
+
+	var currentKey *envelope.MasterKey
+	if value := config.Get().DatabaseMasterKey(); value != "" {
+		key, err := envelope.ParseMasterKey(value)
+		if err != nil {
+			ctx.LogErrorf("Error reading the current master key: %v", err.Error())
+			os.Exit(1)
+		}
+		currentKey = key
+	}
+
+	if !helper.FileExists(newKeyFile) {
+		value, err := envelope.GenerateMasterKey()
+		if err != nil {
+			ctx.LogErrorf("Error generating the new master key: %v", err.Error())
+			os.Exit(1)
+		}
+		if err := os.WriteFile(filepath.Clean(newKeyFile), []byte(value+"\n"), 0o600); err != nil {
+			ctx.LogErrorf("Error writing the new master key: %v", err.Error())
+			os.Exit(1)
+		}
+		ctx.LogInfof("Generated a new master key in %v", newKeyFile)
+	}
+	newKey, err := envelope.LoadMasterKeyFile(newKeyFile)
+	if err != nil {
+		ctx.LogErrorf("Error reading the new master key: %v", err.Error())
+		os.Exit(1)
+	}
+
+	rotated, err := data.RotateDatabaseMasterKey(ctx, filename, currentKey, newKey)
+	if err != nil {
+		ctx.LogErrorf("Error rotating the master key: %v", err.Error())
+		os.Exit(1)
+	}
+
+	ctx.LogInfof("%d credentials of %v are now encrypted with the master key %v", rotated, filename, newKey.ID())
+	ctx.LogInfof("Set %v=%v before starting the service", constants.DATABASE_MASTER_KEY_FILE_ENV_VAR, newKeyFile)
+}
+
+// getDatabaseFilename returns the database file of the --file flag or the one
+// the service uses for the current user
+func getDatabaseFilename(ctx basecontext.ApiContext) (string, error) {
+	if filename := helper.GetFlagValue(constants.FILE_FLAG, ""); filename != "" {
+		return filename, nil
+	}
+
+	return serviceprovider.GetDatabaseFilename(ctx)
+}
+
+func processDatabaseHelp() {
+	fmt.Println("Audits the credentials stored in the database and rotates the master key that encrypts them at rest.")
+	fmt.Println("Stop the service before rotating the key, then start it with the new key.")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Printf("  %v %v audit [--%v=<database file>]\n", constants.ExecutableName, constants.DATABASE_COMMAND, constants.FILE_FLAG)
+	fmt.Printf("  %v %v rotate-key --%v=<path> [--%v=<database file>]\n", constants.ExecutableName, constants.DATABASE_COMMAND, constants.NEW_KEY_FILE_FLAG, constants.FILE_FLAG)
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Printf("  %s\t\t database file, defaults to the one used by the service\n", constants.FILE_FLAG)
+	fmt.Printf("  %s\t file with the new base64 master key, generated when it does not exist\n", constants.NEW_KEY_FILE_FLAG)
+	fmt.Println()
+	fmt.Println("Example:")
+	fmt.Printf("  %v %v rotate-key --%v=/etc/parallels-devops/master.key\n", constants.ExecutableName, constants.DATABASE_COMMAND, constants.NEW_KEY_FILE_FLAG)
+	fmt.Println()
+}
@@ -25,6 +25,10 @@ func processHelp(command string) {
 		processReverseProxyHelp()
 	case constants.INSTALL_SERVICE_COMMAND:
 		processInstallHelp()
+	case constants.SECRETS_COMMAND:
+		processSecretsHelp()
+	case constants.DATABASE_COMMAND:
+		processDatabaseHelp()
 	case constants.START_COMMAND,
 		constants.STOP_COMMAND,
 		constants.CLONE_COMMAND,
@@ -49,6 +53,8 @@ func processDefaultHelp() {
 	fmt.Printf("  %s\t\t Starts the Reverse Proxy Service\n", constants.REVERSE_PROXY_COMMAND)
 	fmt.Printf("  %s\t\t Prints the API Catalog\n", constants.CATALOG_COMMAND)
 	fmt.Printf("  %s\t\t Generates a new Security Key\n", constants.GENERATE_SECURITY_KEY_COMMAND)
+	fmt.Printf("  %s\t\t Manages the secrets of the Secret Store\n", constants.SECRETS_COMMAND)
+	fmt.Printf("  %s\t\t Audits and rotates the Database encryption keys\n", constants.DATABASE_COMMAND)
 	fmt.Printf("  %s\t\t Installs the API Service\n", constants.INSTALL_SERVICE_COMMAND)
 	fmt.Printf("  %s\t\t Uninstalls the API Service\n", constants.UNINSTALL_SERVICE_COMMAND)
 	fmt.Printf("  %s\t Updates the Root Password\n", constants.UPDATE_ROOT_PASSWORD_COMMAND)
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"os"
-	"strings"
 
 	"github.com/Parallels/prl-devops-service/basecontext"
 	"github.com/Parallels/prl-devops-service/config"
@@ -41,19 +40,19 @@ func Process() {
 	case constants.CATALOG_COMMAND:
 		subcommand := helper.GetCommandAt(1)
 		filepath := helper.GetCommandAt(2)
-		if !strings.HasSuffix(filepath, ".pdfile") {
+		if !isPdFilePath(filepath) {
 			filepath = ""
 		}
 		processCatalog(ctx, command, subcommand, filepath)
 	case constants.CATALOG_PUSH_COMMAND:
 		filepath := helper.GetCommandAt(1)
-		if !strings.HasSuffix(filepath, ".pdfile") {
+		if !isPdFilePath(filepath) {
 			filepath = ""
 		}
 		processCatalog(ctx, command, "push", filepath)
 	case constants.CATALOG_PULL_COMMAND:
 		filepath := helper.GetCommandAt(1)
-		if !strings.HasSuffix(filepath, ".pdfile") {
+		if !isPdFilePath(filepath) {
 			filepath = ""
 		}
 		processCatalog(ctx, command, "pull", filepath)
@@ -71,6 +70,10 @@ func Process() {
 		processInitOrchestratorClient(ctx, command)
 	case constants.REGISTER_WITH_ORCHESTRATOR_COMMAND:
 		processRegisterWithOrchestrator(ctx, command)
+	case constants.SECRETS_COMMAND:
+		processSecrets(ctx, command, helper.GetCommandAt(1), helper.GetCommandAt(2))
+	case constants.DATABASE_COMMAND:
+		processDatabase(ctx, command, helper.GetCommandAt(1))
 	default:
 		if helper.GetFlagSwitch("help", false) {
 			processHelp("")
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/secrets"
+	"github.com/cjlapao/common-go/helper"
+)
+
+func processSecrets(ctx basecontext.ApiContext, cmd string, operation string, name string) {
+	if helper.GetFlagSwitch(constants.HELP_FLAG, false) || operation == "help" || operation == "" {
+		processHelp(constants.SECRETS_COMMAND)
+		os.Exit(0)
+	}
+	_ = os.Setenv(constants.SOURCE_ENV_VAR, constants.SECRETS_COMMAND)
+
+	processTelemetry(cmd)
+	ctx.ToggleLogTimestamps(false)
+	store, err := secrets.GetStore(ctx)
+	if err != nil {
+		ctx.LogErrorf("Error opening the secret store: %v", err.Error())
+		os.Exit(1)
+	}
+
+	if operation != "list" && name == "" {
+		ctx.LogErrorf("The secret name is required")
+		os.Exit(1)
+	}
+
+	switch operation {
+	case "list":
+		names, err := store.List(ctx)
+		if err != nil {
+			ctx.LogErrorf("Error listing the secrets: %v", err.Error())
+			os.Exit(1)
+		}
+		for _, item := range names {
+			fmt.Println(item)
+		}
+	case "get":
+		value, err := store.Get(ctx, name)
+		if err != nil {
+			ctx.LogErrorf("Error reading the secret %v: %v", name, err.Error())
+			os.Exit(1)
+		}
+		fmt.Println(value)
+	case "set":
+		value := helper.GetFlagValue(constants.SECRET_VALUE_FLAG, "")
+		if value == "" {
+			// reading the value from stdin keeps it out of the shell history
+			reader := bufio.NewReader(os.Stdin)
+			line, err := reader.ReadString('\n')
+			if err != nil && line == "" {
+				ctx.LogErrorf("The secret value is required, use --%v or pipe it to the command", constants.SECRET_VALUE_FLAG)
+				os.Exit(1)
+			}
+			value = strings.TrimRight(line, "\r\n")
+		}
+		if err := store.Set(ctx, name, value); err != nil {
+			ctx.LogErrorf("Error saving the secret %v: %v", name, err.Error())
+			os.Exit(1)
+		}
+		ctx.LogInfof("Secret %v saved in the %v store, use it as secret://%v", name, store.Name(), name)
+	case "delete":
+		if err := store.Delete(ctx, name); err != nil {
+			ctx.LogErrorf("Error deleting the secret %v: %v", name, err.Error())
+			os.Exit(1)
+		}
+		ctx.LogInfof("Secret %v deleted", name)
+	default:
+		ctx.LogErrorf("Invalid operation %v, allowed operations are list, get, set and delete", operation)
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
+
+func processSecretsHelp() {
+	fmt.Println("Manages the secrets of the secret store used to resolve secret:// references in connection strings, catalog managers and pd files.")
+	fmt.Println("The store is chosen with SECRETS_STORE, local (default) or vault.")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Printf("  %v %v list\n", constants.ExecutableName, constants.SECRETS_COMMAND)
+	fmt.Printf("  %v %v get <name>\n", constants.ExecutableName, constants.SECRETS_COMMAND)
+	fmt.Printf("  %v %v set <name> [--%v=<value>]\n", constants.ExecutableName, constants.SECRETS_COMMAND, constants.SECRET_VALUE_FLAG)
+	fmt.Printf("  %v %v delete <name>\n", constants.ExecutableName, constants.SECRETS_COMMAND)
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Printf("  %s\t\t value of the secret, read from the standard input when not set\n", constants.SECRET_VALUE_FLAG)
+	fmt.Println()
+	fmt.Println("Example:")
+	fmt.Printf("  echo $AWS_SECRET_KEY | %v %v set aws-secret-key\n", constants.ExecutableName, constants.SECRETS_COMMAND)
+	fmt.Println()
+}
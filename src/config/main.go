@@ -598,6 +598,27 @@ func (c *Config) DatabaseFolder() string {
 	return c.GetKey(constants.DATABASE_FOLDER_ENV_VAR)
 }
 
+// DatabaseMasterKey returns the base64 master key that wraps the keys used to
+// encrypt the credentials stored in the database, read from DATABASE_MASTER_KEY
+// or from the file in DATABASE_MASTER_KEY_FILE
+func (c *Config) DatabaseMasterKey() string {
+	if key := c.GetKey(constants.DATABASE_MASTER_KEY_ENV_VAR); key != "" {
+		return key
+	}
+
+	path := c.GetKey(constants.DATABASE_MASTER_KEY_FILE_ENV_VAR)
+	if path == "" {
+		return ""
+	}
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		common.Logger.Error("Error reading the database master key file %v: %v", path, err.Error())
+		return ""
+	}
+
+	return strings.TrimSpace(string(content))
+}
+
 func (c *Config) Localhost() string {
 	schema := "http"
 	host := "localhost"
@@ -724,6 +745,170 @@ func (c *Config) CatalogCompressRatio() int {
 	return returnValue
 }
 
+// CatalogMaxTransferRate returns the service wide bandwidth limit for catalog
+// transfers in bytes per second, the value is configured in KB per second.
+// A value of zero means no limit.
+func (c *Config) CatalogMaxTransferRate() int64 {
+	rate := c.GetIntKey(constants.CATALOG_MAX_TRANSFER_RATE_KB_ENV_VAR)
+	if rate <= 0 {
+		return 0
+	}
+
+	return int64(rate) * 1024
+}
+
+// CatalogTransferWindows returns the raw time windows specification in which
+// background catalog transfers are allowed to run, for example
+// "mon-fri 19:00-07:00; sat,sun 00:00-24:00", entries are separated by ";".
+// An empty value means always.
+func (c *Config) CatalogTransferWindows() string {
+	return strings.TrimSpace(c.GetKey(constants.CATALOG_TRANSFER_WINDOWS_ENV_VAR))
+}
+
+// CatalogCacheEvictionPolicy returns the policy used to choose which cache
+// items are removed first when the cache needs space, defaults to lru.
+func (c *Config) CatalogCacheEvictionPolicy() string {
+	return strings.ToLower(strings.TrimSpace(c.GetKey(constants.CATALOG_CACHE_EVICTION_POLICY_ENV_VAR)))
+}
+
+// CatalogCacheScrubInterval returns how often the catalog cache integrity is
+// verified in the background, zero disables the background scrub.
+func (c *Config) CatalogCacheScrubInterval() time.Duration {
+	hours := c.GetIntKey(constants.CATALOG_CACHE_SCRUB_INTERVAL_HOURS_ENV_VAR)
+	if hours <= 0 {
+		return 0
+	}
+
+	return time.Duration(hours) * time.Hour
+}
+
+// CatalogPushFileChecksums returns true if a push should record the checksum
+// of every machine file in the manifest, it is off by default as hashing
+// multi-GB bundles is expensive. Without them the cache scrub checks sizes.
+func (c *Config) CatalogPushFileChecksums() bool {
+	return c.GetBoolKey(constants.CATALOG_PUSH_FILE_CHECKSUMS_ENV_VAR)
+}
+
+// SecretsStore returns the name of the store used to resolve secret:// references,
+// defaults to the local encrypted store.
+func (c *Config) SecretsStore() string {
+	store := strings.ToLower(strings.TrimSpace(c.GetKey(constants.SECRETS_STORE_ENV_VAR)))
+	if store == "" {
+		return "local"
+	}
+
+	return store
+}
+
+// SecretsLocalStorePath returns the file used by the local encrypted secret store,
+// defaults to secrets.json in the database folder.
+func (c *Config) SecretsLocalStorePath() (string, error) {
+	if path := c.GetKey(constants.SECRETS_LOCAL_STORE_PATH_ENV_VAR); path != "" {
+		return path, nil
+	}
+
+	folder := c.DatabaseFolder()
+	if folder == "" {
+		rootFolder, err := c.RootFolder()
+		if err != nil {
+			return "", err
+		}
+		folder = rootFolder
+	}
+
+	return filepath.Join(folder, "secrets.json"), nil
+}
+
+func (c *Config) VaultAddress() string {
+	return strings.TrimRight(c.GetKey(constants.VAULT_ADDR_ENV_VAR), "/")
+}
+
+func (c *Config) VaultToken() string {
+	return c.GetKey(constants.VAULT_TOKEN_ENV_VAR)
+}
+
+func (c *Config) VaultNamespace() string {
+	return c.GetKey(constants.VAULT_NAMESPACE_ENV_VAR)
+}
+
+// VaultKvMount returns the mount path of the Vault KV secrets engine, defaults to secret.
+func (c *Config) VaultKvMount() string {
+	mount := strings.Trim(c.GetKey(constants.VAULT_KV_MOUNT_ENV_VAR), "/")
+	if mount == "" {
+		return "secret"
+	}
+
+	return mount
+}
+
+// VaultKvVersion returns the version of the Vault KV secrets engine, 1 or 2, defaults to 2.
+func (c *Config) VaultKvVersion() int {
+	if c.GetIntKey(constants.VAULT_KV_VERSION_ENV_VAR) == 1 {
+		return 1
+	}
+
+	return 2
+}
+
+// IsAuditLogEnabled returns true unless AUDIT_LOG_ENABLED is set to false
+func (c *Config) IsAuditLogEnabled() bool {
+	envVar := strings.ToLower(c.GetKey(constants.AUDIT_LOG_ENABLED_ENV_VAR))
+	return envVar != "false" && envVar != "0"
+}
+
+// AuditLogPath returns the file where the audit log is appended, defaults to
+// audit.jsonl next to the database
+func (c *Config) AuditLogPath() (string, error) {
+	if path := c.GetKey(constants.AUDIT_LOG_PATH_ENV_VAR); path != "" {
+		return path, nil
+	}
+
+	folder := c.DatabaseFolder()
+	if folder == "" {
+		rootFolder, err := c.RootFolder()
+		if err != nil {
+			return "", err
+		}
+		folder = rootFolder
+	}
+
+	return filepath.Join(folder, "audit.jsonl"), nil
+}
+
+// AuditLogRetention returns how long the audit entries are kept, 0 keeps them
+// forever
+func (c *Config) AuditLogRetention() time.Duration {
+	value := c.GetKey(constants.AUDIT_LOG_RETENTION_DAYS_ENV_VAR)
+	if value == "" {
+		return time.Duration(constants.DEFAULT_AUDIT_LOG_RETENTION_DAYS) * 24 * time.Hour
+	}
+	days := c.GetIntKey(constants.AUDIT_LOG_RETENTION_DAYS_ENV_VAR)
+	if days <= 0 {
+		return 0
+	}
+
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// AuditLogSyslogAddress returns the syslog server the audit entries are
+// forwarded to, for example udp://localhost:514
+func (c *Config) AuditLogSyslogAddress() string {
+	return strings.TrimSpace(c.GetKey(constants.AUDIT_LOG_SYSLOG_ADDRESS_ENV_VAR))
+}
+
+// TrustedProxies returns the addresses or CIDR ranges of the proxies whose
+// X-Forwarded-For and X-Real-IP headers are trusted, separated by commas
+func (c *Config) TrustedProxies() []string {
+	result := make([]string, 0)
+	for _, value := range strings.Split(c.GetKey(constants.TRUSTED_PROXIES_ENV_VAR), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
+
 func (c *Config) IsBetaEnabled() bool {
 	if appversion.Get().IsBeta() {
 		return true
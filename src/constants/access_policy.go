@@ -0,0 +1,58 @@
+package constants
+
+const (
+	ACCESS_POLICY_EFFECT_ALLOW = "allow"
+	ACCESS_POLICY_EFFECT_DENY  = "deny"
+)
+
+const (
+	ACCESS_POLICY_RESOURCE_MACHINE            = "machine"
+	ACCESS_POLICY_RESOURCE_ORCHESTRATOR_HOST  = "orchestrator_host"
+	ACCESS_POLICY_RESOURCE_REVERSE_PROXY_HOST = "reverse_proxy_host"
+)
+
+const (
+	ACCESS_POLICY_ACTION_ALL        = "*"
+	ACCESS_POLICY_ACTION_READ       = "read"
+	ACCESS_POLICY_ACTION_CREATE     = "create"
+	ACCESS_POLICY_ACTION_UPDATE     = "update"
+	ACCESS_POLICY_ACTION_DELETE     = "delete"
+	ACCESS_POLICY_ACTION_START      = "start"
+	ACCESS_POLICY_ACTION_STOP       = "stop"
+	ACCESS_POLICY_ACTION_RESTART    = "restart"
+	ACCESS_POLICY_ACTION_SUSPEND    = "suspend"
+	ACCESS_POLICY_ACTION_RESUME     = "resume"
+	ACCESS_POLICY_ACTION_RESET      = "reset"
+	ACCESS_POLICY_ACTION_PAUSE      = "pause"
+	ACCESS_POLICY_ACTION_CLONE      = "clone"
+	ACCESS_POLICY_ACTION_EXECUTE    = "execute"
+	ACCESS_POLICY_ACTION_SNAPSHOT   = "snapshot"
+	ACCESS_POLICY_ACTION_REGISTER   = "register"
+	ACCESS_POLICY_ACTION_UNREGISTER = "unregister"
+)
+
+var AllAccessPolicyResources = []string{
+	ACCESS_POLICY_RESOURCE_MACHINE,
+	ACCESS_POLICY_RESOURCE_ORCHESTRATOR_HOST,
+	ACCESS_POLICY_RESOURCE_REVERSE_PROXY_HOST,
+}
+
+var AllAccessPolicyActions = []string{
+	ACCESS_POLICY_ACTION_ALL,
+	ACCESS_POLICY_ACTION_READ,
+	ACCESS_POLICY_ACTION_CREATE,
+	ACCESS_POLICY_ACTION_UPDATE,
+	ACCESS_POLICY_ACTION_DELETE,
+	ACCESS_POLICY_ACTION_START,
+	ACCESS_POLICY_ACTION_STOP,
+	ACCESS_POLICY_ACTION_RESTART,
+	ACCESS_POLICY_ACTION_SUSPEND,
+	ACCESS_POLICY_ACTION_RESUME,
+	ACCESS_POLICY_ACTION_RESET,
+	ACCESS_POLICY_ACTION_PAUSE,
+	ACCESS_POLICY_ACTION_CLONE,
+	ACCESS_POLICY_ACTION_EXECUTE,
+	ACCESS_POLICY_ACTION_SNAPSHOT,
+	ACCESS_POLICY_ACTION_REGISTER,
+	ACCESS_POLICY_ACTION_UNREGISTER,
+}
@@ -0,0 +1,9 @@
+package constants
+
+const (
+	API_KEY_RESOURCE_CATALOG  = "catalog"
+	API_KEY_RESOURCE_HOST_TAG = "host_tag"
+	// API_KEY_DEFAULT_ROTATION_OVERLAP is how long the previous secret of a
+	// rotated key keeps working
+	API_KEY_DEFAULT_ROTATION_OVERLAP = "24h"
+)
@@ -88,6 +88,15 @@ var ClaimCategoryMap = map[string]ClaimCategory{
 	UPDATE_CLAIM_CLAIM: {ClaimGroupAdministration, "Claim", ClaimActionUpdate},
 	DELETE_CLAIM_CLAIM: {ClaimGroupAdministration, "Claim", ClaimActionDelete},
 
+	// ── Administration › Audit Log ────────────────────────────────────────
+	LIST_AUDIT_LOG_CLAIM: {ClaimGroupAdministration, "Audit Log", ClaimActionRead},
+
+	// ── Administration › Access Policy ────────────────────────────────────
+	LIST_ACCESS_POLICY_CLAIM:   {ClaimGroupAdministration, "Access Policy", ClaimActionRead},
+	CREATE_ACCESS_POLICY_CLAIM: {ClaimGroupAdministration, "Access Policy", ClaimActionCreate},
+	UPDATE_ACCESS_POLICY_CLAIM: {ClaimGroupAdministration, "Access Policy", ClaimActionUpdate},
+	DELETE_ACCESS_POLICY_CLAIM: {ClaimGroupAdministration, "Access Policy", ClaimActionDelete},
+
 	// ── Administration › System (broad CRUD grants) ───────────────────────
 	READ_ONLY_CLAIM: {ClaimGroupAdministration, "System", ClaimActionRead},
 	LIST_CLAIM:      {ClaimGroupAdministration, "System", ClaimActionRead},
@@ -174,8 +183,16 @@ var ClaimCategoryMap = map[string]ClaimCategory{
 	UPDATE_REVERSE_PROXY_HOST_TCP_ROUTE_CLAIM: {ClaimGroupReverseProxy, "TCP Route", ClaimActionUpdate},
 	DELETE_REVERSE_PROXY_HOST_TCP_ROUTE_CLAIM: {ClaimGroupReverseProxy, "TCP Route", ClaimActionDelete},
 
+	// ── Reverse Proxy › UDP Route ─────────────────────────────────────────
+	LIST_REVERSE_PROXY_HOST_UDP_ROUTES_CLAIM:  {ClaimGroupReverseProxy, "UDP Route", ClaimActionRead},
+	CREATE_REVERSE_PROXY_HOST_UDP_ROUTE_CLAIM: {ClaimGroupReverseProxy, "UDP Route", ClaimActionCreate},
+	UPDATE_REVERSE_PROXY_HOST_UDP_ROUTE_CLAIM: {ClaimGroupReverseProxy, "UDP Route", ClaimActionUpdate},
+	DELETE_REVERSE_PROXY_HOST_UDP_ROUTE_CLAIM: {ClaimGroupReverseProxy, "UDP Route", ClaimActionDelete},
+
 	// ── Cache ─────────────────────────────────────────────────────────────
 	LIST_CACHE_CLAIM:        {ClaimGroupCache, "Cache", ClaimActionRead},
+	CREATE_CACHE_ITEM_CLAIM: {ClaimGroupCache, "Cache", ClaimActionCreate},
+	UPDATE_CACHE_ITEM_CLAIM: {ClaimGroupCache, "Cache", ClaimActionUpdate},
 	DELETE_CACHE_ITEM_CLAIM: {ClaimGroupCache, "Cache", ClaimActionDelete},
 	DELETE_ALL_CACHE_CLAIM:  {ClaimGroupCache, "Cache", ClaimActionDelete},
 
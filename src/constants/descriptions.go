@@ -43,6 +43,15 @@ var ClaimDescriptionMap = map[string]string{
 	UPDATE_CLAIM_CLAIM: "Modify existing claims.",
 	DELETE_CLAIM_CLAIM: "Remove claims from the system.",
 
+	// ── Administration › Audit Log ────────────────────────────────────────
+	LIST_AUDIT_LOG_CLAIM: "View and export the audit log of changes made through the API.",
+
+	// ── Administration › Access Policy ────────────────────────────────────
+	LIST_ACCESS_POLICY_CLAIM:   "View the access policies of virtual machines, hosts and reverse proxy hosts.",
+	CREATE_ACCESS_POLICY_CLAIM: "Create access policies.",
+	UPDATE_ACCESS_POLICY_CLAIM: "Modify existing access policies.",
+	DELETE_ACCESS_POLICY_CLAIM: "Remove access policies.",
+
 	// ── VMs › VM ──────────────────────────────────────────────────────────
 	LIST_VM_CLAIM:            "View all virtual machines.",
 	CREATE_VM_CLAIM:          "Create and provision new virtual machines.",
@@ -121,9 +130,15 @@ var ClaimDescriptionMap = map[string]string{
 	CREATE_REVERSE_PROXY_HOST_TCP_ROUTE_CLAIM: "Add TCP routes to reverse proxy hosts.",
 	UPDATE_REVERSE_PROXY_HOST_TCP_ROUTE_CLAIM: "Modify TCP routes on reverse proxy hosts.",
 	DELETE_REVERSE_PROXY_HOST_TCP_ROUTE_CLAIM: "Remove TCP routes from reverse proxy hosts.",
+	LIST_REVERSE_PROXY_HOST_UDP_ROUTES_CLAIM:  "View UDP routes on reverse proxy hosts.",
+	CREATE_REVERSE_PROXY_HOST_UDP_ROUTE_CLAIM: "Add UDP routes to reverse proxy hosts.",
+	UPDATE_REVERSE_PROXY_HOST_UDP_ROUTE_CLAIM: "Modify UDP routes on reverse proxy hosts.",
+	DELETE_REVERSE_PROXY_HOST_UDP_ROUTE_CLAIM: "Remove UDP routes from reverse proxy hosts.",
 
 	// ── Cache ─────────────────────────────────────────────────────────────
 	LIST_CACHE_CLAIM:        "View items currently stored in the catalog cache.",
+	CREATE_CACHE_ITEM_CLAIM: "Download catalog manifests into the catalog cache ahead of a pull.",
+	UPDATE_CACHE_ITEM_CLAIM: "Pin and unpin catalog cache items so they are never evicted.",
 	DELETE_CACHE_ITEM_CLAIM: "Remove a specific item from the catalog cache.",
 	DELETE_ALL_CACHE_CLAIM:  "Clear all items from the catalog cache.",
 
@@ -1,8 +1,28 @@
 package constants
 
 const (
-	JWT_PRIVATE_KEY_ENV_VAR    = "JWT_PRIVATE_KEY"
-	JWT_HMACS_SECRET_ENV_VAR   = "JWT_HMACS_SECRET" // #nosec G101 This is not a hardcoded password, it is just the variable name we use to store the secret
-	JWT_DURATION_ENV_VAR       = "JWT_DURATION"
-	JWT_SIGN_ALGORITHM_ENV_VAR = "JWT_SIGN_ALGORITHM"
+	JWT_PRIVATE_KEY_ENV_VAR      = "JWT_PRIVATE_KEY"
+	JWT_HMACS_SECRET_ENV_VAR     = "JWT_HMACS_SECRET" // #nosec G101 This is not a hardcoded password, it is just the variable name we use to store the secret
+	JWT_DURATION_ENV_VAR         = "JWT_DURATION"
+	JWT_REFRESH_DURATION_ENV_VAR = "JWT_REFRESH_DURATION"
+	JWT_SIGN_ALGORITHM_ENV_VAR   = "JWT_SIGN_ALGORITHM"
 )
+
+// reasons recorded when a session is revoked
+const (
+	SESSION_REVOKED_LOGOUT               = "logout"
+	SESSION_REVOKED_BY_USER              = "revoked by the user"
+	SESSION_REVOKED_BY_ADMINISTRATOR     = "revoked by an administrator"
+	SESSION_REVOKED_PASSWORD_CHANGED     = "password changed"
+	SESSION_REVOKED_USER_BLOCKED         = "user blocked"
+	SESSION_REVOKED_USER_DISABLED        = "user disabled"
+	SESSION_REVOKED_REFRESH_TOKEN_REUSED = "refresh token reused"
+)
+
+// ExpiredSessionsCleanupIntervalMinutes is how often the expired sessions and
+// revoked tokens are removed from the database
+const ExpiredSessionsCleanupIntervalMinutes = 15
+
+// SESSION_AUTHENTICATION_PASSWORD is recorded in the sessions started with the
+// local password of the user, the other sessions record the identity provider
+const SESSION_AUTHENTICATION_PASSWORD = "password"
@@ -0,0 +1,36 @@
+package constants
+
+const (
+	LDAP_URL_ENV_VAR                  = "LDAP_URL"
+	LDAP_START_TLS_ENV_VAR            = "LDAP_START_TLS"
+	LDAP_INSECURE_SKIP_VERIFY_ENV_VAR = "LDAP_INSECURE_SKIP_VERIFY"
+	LDAP_CA_CERT_FILE_ENV_VAR         = "LDAP_CA_CERT_FILE"
+	LDAP_BIND_DN_ENV_VAR              = "LDAP_BIND_DN"
+	LDAP_BIND_PASSWORD_ENV_VAR        = "LDAP_BIND_PASSWORD" // #nosec G101 This is not a hardcoded password, it is just the variable name we use to store the password
+	LDAP_USER_SEARCH_BASE_ENV_VAR     = "LDAP_USER_SEARCH_BASE"
+	LDAP_USER_FILTER_ENV_VAR          = "LDAP_USER_FILTER"
+	LDAP_USERNAME_ATTRIBUTE_ENV_VAR   = "LDAP_USERNAME_ATTRIBUTE"
+	LDAP_EMAIL_ATTRIBUTE_ENV_VAR      = "LDAP_EMAIL_ATTRIBUTE"
+	LDAP_NAME_ATTRIBUTE_ENV_VAR       = "LDAP_NAME_ATTRIBUTE"
+	LDAP_GROUP_SEARCH_BASE_ENV_VAR    = "LDAP_GROUP_SEARCH_BASE"
+	LDAP_GROUP_FILTER_ENV_VAR         = "LDAP_GROUP_FILTER"
+	LDAP_GROUP_NAME_ATTRIBUTE_ENV_VAR = "LDAP_GROUP_NAME_ATTRIBUTE"
+	LDAP_GROUP_ROLES_ENV_VAR          = "LDAP_GROUP_ROLES"
+	LDAP_GROUP_CLAIMS_ENV_VAR         = "LDAP_GROUP_CLAIMS"
+	LDAP_AUTO_PROVISION_ENV_VAR       = "LDAP_AUTO_PROVISION"
+	LDAP_LINK_LOCAL_USERS_ENV_VAR     = "LDAP_LINK_LOCAL_USERS"
+	LDAP_SYNC_INTERVAL_ENV_VAR        = "LDAP_SYNC_INTERVAL"
+)
+
+const (
+	LDAP_IDENTITY_PROVIDER            = "ldap"
+	LDAP_DEFAULT_USER_FILTER          = "(|(uid={username})(mail={username}))"
+	LDAP_DEFAULT_USERNAME_ATTRIBUTE   = "uid"
+	LDAP_DEFAULT_EMAIL_ATTRIBUTE      = "mail"
+	LDAP_DEFAULT_NAME_ATTRIBUTE       = "cn"
+	LDAP_DEFAULT_GROUP_FILTER         = "(|(member={dn})(uniqueMember={dn})(memberUid={username}))"
+	LDAP_DEFAULT_GROUP_NAME_ATTRIBUTE = "cn"
+	LDAP_DEFAULT_SYNC_INTERVAL        = "1h"
+	// LDAP_SYNC_DISABLED_REASON marks the users disabled by the directory sync
+	LDAP_SYNC_DISABLED_REASON = "the user no longer exists in the LDAP directory"
+)
@@ -13,6 +13,10 @@ type RequestIdKey string
 
 var REQUEST_ID_KEY RequestIdKey = "REQUEST_ID"
 
+type AuditContextKey string
+
+var AUDIT_CONTEXT_KEY AuditContextKey = "AUDIT_CONTEXT"
+
 const (
 	SqlNoRows = "sql: no rows in result set"
 )
@@ -44,6 +48,7 @@ const (
 	DEFAULT_REVERSE_PROXY_HOST                   = "0.0.0.0"
 	DEFAULT_NOTIFICATION_REFRESH_INTERVAL_IN_SEC = 5
 	DEFAULT_VM_CACHE_REFRESH_INTERVAL_SECONDS    = 300 // 5 minutes
+	DEFAULT_AUDIT_LOG_RETENTION_DAYS             = 90
 
 	INTERNAL_API_CLIENT                          = "X-INTERNAL-API-CLIENT"
 	ORCHESTRATOR_JOB_ID_HEADER                   = "X-ORCHESTRATOR-JOB-ID"
@@ -100,6 +105,8 @@ const (
 	ORCHESTRATOR_PUBLIC_URL                                 = "ORCHESTRATOR_PUBLIC_URL"
 	DATABASE_FOLDER_ENV_VAR                                 = "DATABASE_FOLDER"
 	DATABASE_NUMBER_BACKUP_FILES_ENV_VAR                    = "DATABASE_NUMBER_BACKUP_FILES"
+	DATABASE_MASTER_KEY_ENV_VAR                             = "DATABASE_MASTER_KEY"
+	DATABASE_MASTER_KEY_FILE_ENV_VAR                        = "DATABASE_MASTER_KEY_FILE"
 	DATABASE_BACKUP_INTERVAL_ENV_VAR                        = "DATABASE_BACKUP_INTERVAL_MINUTES"
 	DATABASE_SAVE_INTERVAL_ENV_VAR                          = "DATABASE_SAVE_INTERVAL_MINUTES"
 	CATALOG_CACHE_FOLDER_ENV_VAR                            = "CATALOG_CACHE_FOLDER"
@@ -109,6 +116,11 @@ const (
 	CATALOG_COMPRESS_VM_ENV_VAR                             = "CATALOG_COMPRESS_VM"
 	CATALOG_COMPRESS_VM_RATIO_ENV_VAR                       = "CATALOG_COMPRESS_VM_RATIO"
 	CATALOG_ENABLE_PROVIDER_CREDENTIALS_OBFUSCATION_ENV_VAR = "CATALOG_ENABLE_PROVIDER_CREDENTIALS_OBFUSCATION"
+	CATALOG_MAX_TRANSFER_RATE_KB_ENV_VAR                    = "CATALOG_MAX_TRANSFER_RATE_KB"
+	CATALOG_TRANSFER_WINDOWS_ENV_VAR                        = "CATALOG_TRANSFER_WINDOWS"
+	CATALOG_CACHE_EVICTION_POLICY_ENV_VAR                   = "CATALOG_CACHE_EVICTION_POLICY"
+	CATALOG_CACHE_SCRUB_INTERVAL_HOURS_ENV_VAR              = "CATALOG_CACHE_SCRUB_INTERVAL_HOURS"
+	CATALOG_PUSH_FILE_CHECKSUMS_ENV_VAR                     = "CATALOG_PUSH_FILE_CHECKSUMS"
 	CORS_ALLOWED_HEADERS_ENV_VAR                            = "CORS_ALLOWED_HEADERS"
 	CORS_ALLOWED_METHODS_ENV_VAR                            = "CORS_ALLOWED_METHODS"
 	CORS_ALLOWED_ORIGINS_ENV_VAR                            = "CORS_ALLOWED_ORIGINS"
@@ -135,6 +147,18 @@ const (
 	ENABLED_MODULES_ENV_VAR                                 = "ENABLED_MODULES"
 	ENABLE_INSECURE_KEY_SSH_ENV_VAR                         = "ENABLE_SSH_INSECURE_KEY"
 	BASE_URL_ENV_VAR                                        = "BASE_URL"
+	SECRETS_STORE_ENV_VAR                                   = "SECRETS_STORE"
+	SECRETS_LOCAL_STORE_PATH_ENV_VAR                        = "SECRETS_LOCAL_STORE_PATH"
+	VAULT_ADDR_ENV_VAR                                      = "VAULT_ADDR"
+	VAULT_TOKEN_ENV_VAR                                     = "VAULT_TOKEN"
+	VAULT_NAMESPACE_ENV_VAR                                 = "VAULT_NAMESPACE"
+	VAULT_KV_MOUNT_ENV_VAR                                  = "VAULT_KV_MOUNT"
+	VAULT_KV_VERSION_ENV_VAR                                = "VAULT_KV_VERSION"
+	AUDIT_LOG_ENABLED_ENV_VAR                               = "AUDIT_LOG_ENABLED"
+	AUDIT_LOG_PATH_ENV_VAR                                  = "AUDIT_LOG_PATH"
+	AUDIT_LOG_RETENTION_DAYS_ENV_VAR                        = "AUDIT_LOG_RETENTION_DAYS"
+	AUDIT_LOG_SYSLOG_ADDRESS_ENV_VAR                        = "AUDIT_LOG_SYSLOG_ADDRESS"
+	TRUSTED_PROXIES_ENV_VAR                                 = "TRUSTED_PROXIES"
 )
 
 const (
@@ -157,6 +181,8 @@ const (
 	CLONE_COMMAND                      = "clone"
 	INIT_ORCHESTRATOR_CLIENT_COMMAND   = "init-orchestrator-client"
 	REGISTER_WITH_ORCHESTRATOR_COMMAND = "register-with-orchestrator"
+	SECRETS_COMMAND                    = "secrets"
+	DATABASE_COMMAND                   = "database"
 
 	TEST_FLAG                       = "test"
 	TEST_CATALOG_PROVIDERS_FLAG     = "catalog-providers"
@@ -174,6 +200,8 @@ const (
 	HOST_NAME_FLAG                  = "host-name"
 	TAGS_FLAG                       = "tags"
 	PD_VERSION_FLAG                 = "pd-version"
+	SECRET_VALUE_FLAG               = "value"
+	NEW_KEY_FILE_FLAG               = "new-key-file"
 
 	ENROLLMENT_TOKEN_HEADER              = "X-Enrollment-Token"
 	DEFAULT_ENROLLMENT_TOKEN_TTL_MINUTES = 15
@@ -205,6 +233,9 @@ const (
 	PD_FILE_COMPRESS_PACK_LEVEL_FLAG = "compress-pack-level"
 	PD_FILE_VM_REMOTE_PATH_FLAG      = "vm-remote-path"
 	PD_FILE_VM_SIZE_FLAG             = "vm-size"
+	PD_FILE_STAGE_FLAG               = "stage"
+	PD_FILE_ARG_FLAG                 = "arg"
+	PD_FILE_FORMAT_FLAG              = "format"
 )
 
 const (
@@ -280,6 +311,8 @@ const (
 
 	// Cache Claims
 	LIST_CACHE_CLAIM        = "LIST_CACHE"
+	CREATE_CACHE_ITEM_CLAIM = "CREATE_CACHE_ITEM"
+	UPDATE_CACHE_ITEM_CLAIM = "UPDATE_CACHE_ITEM"
 	DELETE_CACHE_ITEM_CLAIM = "DELET_CACHE_ITEM"
 	DELETE_ALL_CACHE_CLAIM  = "DELETE_ALL_CACHE"
 
@@ -296,6 +329,10 @@ const (
 	CREATE_REVERSE_PROXY_HOST_TCP_ROUTE_CLAIM  = "CREATE_REVERSE_PROXY_HOST_TCP_ROUTE"
 	DELETE_REVERSE_PROXY_HOST_TCP_ROUTE_CLAIM  = "DELETE_REVERSE_PROXY_HOST_TCP_ROUTE"
 	UPDATE_REVERSE_PROXY_HOST_TCP_ROUTE_CLAIM  = "UPDATE_REVERSE_PROXY_HOST_TCP_ROUTE"
+	LIST_REVERSE_PROXY_HOST_UDP_ROUTES_CLAIM   = "LIST_REVERSE_PROXY_HOST_UDP_ROUTES"
+	CREATE_REVERSE_PROXY_HOST_UDP_ROUTE_CLAIM  = "CREATE_REVERSE_PROXY_HOST_UDP_ROUTE"
+	DELETE_REVERSE_PROXY_HOST_UDP_ROUTE_CLAIM  = "DELETE_REVERSE_PROXY_HOST_UDP_ROUTE"
+	UPDATE_REVERSE_PROXY_HOST_UDP_ROUTE_CLAIM  = "UPDATE_REVERSE_PROXY_HOST_UDP_ROUTE"
 
 	EXECUTE_SSH_CLAIM = "EXECUTE_SSH"
 
@@ -322,6 +359,14 @@ const (
 	JOBS_MANAGER_LIST_OWN_CLAIM = "JOB_MANAGER_LIST_OWN"
 	JOBS_MANAGER_DELETE_CLAIM   = "JOB_MANAGER_DELETE"
 	JOBS_MANAGER_DEBUG_CLAIM    = "JOB_MANAGER_DEBUG"
+
+	LIST_AUDIT_LOG_CLAIM = "LIST_AUDIT_LOG"
+
+	// Access Policy Claims
+	LIST_ACCESS_POLICY_CLAIM   = "LIST_ACCESS_POLICY"
+	CREATE_ACCESS_POLICY_CLAIM = "CREATE_ACCESS_POLICY"
+	UPDATE_ACCESS_POLICY_CLAIM = "UPDATE_ACCESS_POLICY"
+	DELETE_ACCESS_POLICY_CLAIM = "DELETE_ACCESS_POLICY"
 )
 
 var AllSystemRoles = []string{
@@ -385,14 +430,25 @@ var AllSystemClaims = []string{
 	CREATE_REVERSE_PROXY_HOST_TCP_ROUTE_CLAIM,
 	DELETE_REVERSE_PROXY_HOST_TCP_ROUTE_CLAIM,
 	UPDATE_REVERSE_PROXY_HOST_TCP_ROUTE_CLAIM,
+	LIST_REVERSE_PROXY_HOST_UDP_ROUTES_CLAIM,
+	CREATE_REVERSE_PROXY_HOST_UDP_ROUTE_CLAIM,
+	DELETE_REVERSE_PROXY_HOST_UDP_ROUTE_CLAIM,
+	UPDATE_REVERSE_PROXY_HOST_UDP_ROUTE_CLAIM,
 	EXECUTE_SSH_CLAIM,
 	LIST_CACHE_CLAIM,
+	CREATE_CACHE_ITEM_CLAIM,
+	UPDATE_CACHE_ITEM_CLAIM,
 	DELETE_CACHE_ITEM_CLAIM,
 	DELETE_ALL_CACHE_CLAIM,
 	JOBS_MANAGER_LIST_CLAIM,
 	JOBS_MANAGER_LIST_OWN_CLAIM,
 	JOBS_MANAGER_DELETE_CLAIM,
 	JOBS_MANAGER_DEBUG_CLAIM,
+	LIST_AUDIT_LOG_CLAIM,
+	LIST_ACCESS_POLICY_CLAIM,
+	CREATE_ACCESS_POLICY_CLAIM,
+	UPDATE_ACCESS_POLICY_CLAIM,
+	DELETE_ACCESS_POLICY_CLAIM,
 	CATALOG_MANAGER_LIST_CLAIM,
 	CATALOG_MANAGER_LIST_OWN_CLAIM,
 	CATALOG_MANAGER_CREATE_CLAIM,
@@ -474,8 +530,14 @@ var AllSuperUserClaims = []string{
 	CREATE_REVERSE_PROXY_HOST_TCP_ROUTE_CLAIM,
 	DELETE_REVERSE_PROXY_HOST_TCP_ROUTE_CLAIM,
 	UPDATE_REVERSE_PROXY_HOST_TCP_ROUTE_CLAIM,
+	LIST_REVERSE_PROXY_HOST_UDP_ROUTES_CLAIM,
+	CREATE_REVERSE_PROXY_HOST_UDP_ROUTE_CLAIM,
+	DELETE_REVERSE_PROXY_HOST_UDP_ROUTE_CLAIM,
+	UPDATE_REVERSE_PROXY_HOST_UDP_ROUTE_CLAIM,
 	EXECUTE_SSH_CLAIM,
 	LIST_CACHE_CLAIM,
+	CREATE_CACHE_ITEM_CLAIM,
+	UPDATE_CACHE_ITEM_CLAIM,
 	DELETE_CACHE_ITEM_CLAIM,
 	DELETE_ALL_CACHE_CLAIM,
 	CATALOG_MANAGER_LIST_CLAIM,
@@ -496,6 +558,11 @@ var AllSuperUserClaims = []string{
 	JOBS_MANAGER_LIST_CLAIM,
 	JOBS_MANAGER_LIST_OWN_CLAIM,
 	JOBS_MANAGER_DELETE_CLAIM,
+	LIST_AUDIT_LOG_CLAIM,
+	LIST_ACCESS_POLICY_CLAIM,
+	CREATE_ACCESS_POLICY_CLAIM,
+	UPDATE_ACCESS_POLICY_CLAIM,
+	DELETE_ACCESS_POLICY_CLAIM,
 	CREATE_SNAPSHOT_VM_CLAIM,
 	CREATE_OWN_VM_SNAPSHOT_CLAIM,
 	DELETE_SNAPSHOT_VM_CLAIM,
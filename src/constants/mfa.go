@@ -0,0 +1,12 @@
+package constants
+
+const (
+	MFA_ISSUER_ENV_VAR             = "MFA_ISSUER"
+	MFA_CHALLENGE_DURATION_ENV_VAR = "MFA_CHALLENGE_DURATION"
+	MFA_MAX_ATTEMPTS_ENV_VAR       = "MFA_MAX_ATTEMPTS"
+
+	MFA_DEFAULT_ISSUER = "Parallels DevOps"
+	// SESSION_AUTHENTICATION_MFA_SUFFIX is appended to the authentication
+	// method of the sessions started with a second factor
+	SESSION_AUTHENTICATION_MFA_SUFFIX = "+totp"
+)
@@ -0,0 +1,25 @@
+package constants
+
+const (
+	OIDC_ISSUER_URL_ENV_VAR              = "OIDC_ISSUER_URL"
+	OIDC_CLIENT_ID_ENV_VAR               = "OIDC_CLIENT_ID"
+	OIDC_CLIENT_SECRET_ENV_VAR           = "OIDC_CLIENT_SECRET" // #nosec G101 This is not a hardcoded password, it is just the variable name we use to store the secret
+	OIDC_REDIRECT_URL_ENV_VAR            = "OIDC_REDIRECT_URL"
+	OIDC_POST_LOGIN_REDIRECT_URL_ENV_VAR = "OIDC_POST_LOGIN_REDIRECT_URL"
+	OIDC_SCOPES_ENV_VAR                  = "OIDC_SCOPES"
+	OIDC_AUDIENCE_ENV_VAR                = "OIDC_AUDIENCE"
+	OIDC_USERNAME_CLAIM_ENV_VAR          = "OIDC_USERNAME_CLAIM"
+	OIDC_GROUPS_CLAIM_ENV_VAR            = "OIDC_GROUPS_CLAIM"
+	OIDC_GROUP_ROLES_ENV_VAR             = "OIDC_GROUP_ROLES"
+	OIDC_GROUP_CLAIMS_ENV_VAR            = "OIDC_GROUP_CLAIMS"
+	OIDC_AUTO_PROVISION_ENV_VAR          = "OIDC_AUTO_PROVISION"
+	OIDC_LINK_LOCAL_USERS_ENV_VAR        = "OIDC_LINK_LOCAL_USERS"
+)
+
+const (
+	OIDC_IDENTITY_PROVIDER        = "oidc"
+	OIDC_DEFAULT_SCOPES           = "openid profile email"
+	OIDC_DEFAULT_USERNAME_CLAIM   = "preferred_username"
+	OIDC_DEFAULT_GROUPS_CLAIM     = "groups"
+	OIDC_LOGIN_REQUEST_EXPIRES_IN = 600
+)
@@ -0,0 +1,25 @@
+package constants
+
+const (
+	RATE_LIMIT_ENABLED_ENV_VAR            = "RATE_LIMIT_ENABLED"
+	RATE_LIMIT_STORE_ENV_VAR              = "RATE_LIMIT_STORE"
+	RATE_LIMIT_GLOBAL_ENV_VAR             = "RATE_LIMIT_GLOBAL"
+	RATE_LIMIT_PER_USER_ENV_VAR           = "RATE_LIMIT_PER_USER"
+	RATE_LIMIT_PER_API_KEY_ENV_VAR        = "RATE_LIMIT_PER_API_KEY"
+	RATE_LIMIT_PER_IP_ENV_VAR             = "RATE_LIMIT_PER_IP"
+	RATE_LIMIT_ROUTE_GROUPS_ENV_VAR       = "RATE_LIMIT_ROUTE_GROUPS"
+	RATE_LIMIT_ROUTE_GROUP_LIMITS_ENV_VAR = "RATE_LIMIT_ROUTE_GROUP_LIMITS"
+	RATE_LIMIT_EXEMPT_ENV_VAR             = "RATE_LIMIT_EXEMPT"
+)
+
+const (
+	RATE_LIMIT_MEMORY_STORE          = "memory"
+	RATE_LIMIT_MYSQL_STORE           = "mysql"
+	RATE_LIMIT_DEFAULT_PER_USER      = "600/1m"
+	RATE_LIMIT_DEFAULT_PER_API_KEY   = "600/1m"
+	RATE_LIMIT_DEFAULT_PER_IP        = "1200/1m"
+	RATE_LIMIT_EXEMPT_IP_PREFIX      = "ip:"
+	RATE_LIMIT_EXEMPT_USER_PREFIX    = "user:"
+	RATE_LIMIT_EXEMPT_API_KEY_PREFIX = "api_key:"
+	RATE_LIMIT_EXEMPT_ROUTE_PREFIX   = "route:"
+)
@@ -0,0 +1,90 @@
+package constants
+
+const (
+	REVERSE_PROXY_UPSTREAM_ROUND_ROBIN       = "round_robin"
+	REVERSE_PROXY_UPSTREAM_LEAST_CONNECTIONS = "least_connections"
+	REVERSE_PROXY_UPSTREAM_IP_HASH           = "ip_hash"
+
+	REVERSE_PROXY_UPSTREAM_DEFAULT_MAX_FAILS           = 3
+	REVERSE_PROXY_UPSTREAM_DEFAULT_FAIL_TIMEOUT        = "30s"
+	REVERSE_PROXY_UPSTREAM_DEFAULT_CHECK_INTERVAL      = "10s"
+	REVERSE_PROXY_UPSTREAM_DEFAULT_CHECK_TIMEOUT       = "2s"
+	REVERSE_PROXY_UPSTREAM_DEFAULT_HEALTHY_THRESHOLD   = 2
+	REVERSE_PROXY_UPSTREAM_DEFAULT_UNHEALTHY_THRESHOLD = 2
+)
+
+var AllReverseProxyUpstreamStrategies = []string{
+	REVERSE_PROXY_UPSTREAM_ROUND_ROBIN,
+	REVERSE_PROXY_UPSTREAM_LEAST_CONNECTIONS,
+	REVERSE_PROXY_UPSTREAM_IP_HASH,
+}
+
+const (
+	REVERSE_PROXY_ACME_DIRECTORY_URL_ENV_VAR        = "REVERSE_PROXY_ACME_DIRECTORY_URL"
+	REVERSE_PROXY_ACME_EMAIL_ENV_VAR                = "REVERSE_PROXY_ACME_EMAIL"
+	REVERSE_PROXY_ACME_CA_CERT_ENV_VAR              = "REVERSE_PROXY_ACME_CA_CERT"
+	REVERSE_PROXY_ACME_INSECURE_SKIP_VERIFY_ENV_VAR = "REVERSE_PROXY_ACME_INSECURE_SKIP_VERIFY"
+	REVERSE_PROXY_ACME_HTTP_PORT_ENV_VAR            = "REVERSE_PROXY_ACME_HTTP_PORT"
+	REVERSE_PROXY_ACME_RENEW_BEFORE_ENV_VAR         = "REVERSE_PROXY_ACME_RENEW_BEFORE"
+	REVERSE_PROXY_ACME_CHECK_INTERVAL_ENV_VAR       = "REVERSE_PROXY_ACME_CHECK_INTERVAL"
+	REVERSE_PROXY_ACME_DNS_PROPAGATION_WAIT_ENV_VAR = "REVERSE_PROXY_ACME_DNS_PROPAGATION_WAIT"
+	REVERSE_PROXY_ACME_DNS_EXEC_PATH_ENV_VAR        = "REVERSE_PROXY_ACME_DNS_EXEC_PATH"
+	REVERSE_PROXY_ACME_DNS_CHALLTESTSRV_URL_ENV_VAR = "REVERSE_PROXY_ACME_DNS_CHALLTESTSRV_URL"
+
+	REVERSE_PROXY_ACME_HTTP_01     = "http-01"
+	REVERSE_PROXY_ACME_TLS_ALPN_01 = "tls-alpn-01"
+	REVERSE_PROXY_ACME_DNS_01      = "dns-01"
+
+	REVERSE_PROXY_ACME_DEFAULT_DIRECTORY_URL    = "https://acme-v02.api.letsencrypt.org/directory"
+	REVERSE_PROXY_ACME_DEFAULT_HTTP_PORT        = "80"
+	REVERSE_PROXY_ACME_DEFAULT_CHALLTESTSRV_URL = "http://localhost:8055"
+)
+
+var AllReverseProxyAcmeChallenges = []string{
+	REVERSE_PROXY_ACME_HTTP_01,
+	REVERSE_PROXY_ACME_TLS_ALPN_01,
+	REVERSE_PROXY_ACME_DNS_01,
+}
+
+const (
+	REVERSE_PROXY_AUTH_DEVOPS       = "devops"
+	REVERSE_PROXY_AUTH_BASIC        = "basic"
+	REVERSE_PROXY_AUTH_FORWARD_AUTH = "forward_auth"
+
+	REVERSE_PROXY_AUTH_USER_HEADER   = "X-Forwarded-User"
+	REVERSE_PROXY_AUTH_EMAIL_HEADER  = "X-Forwarded-Email"
+	REVERSE_PROXY_AUTH_ROLES_HEADER  = "X-Forwarded-Roles"
+	REVERSE_PROXY_AUTH_METHOD_HEADER = "X-Forwarded-Auth-Method"
+
+	REVERSE_PROXY_AUTH_DEFAULT_REALM                = "Restricted"
+	REVERSE_PROXY_AUTH_DEFAULT_FORWARD_AUTH_TIMEOUT = "5s"
+)
+
+const (
+	REVERSE_PROXY_PROTOCOL_HTTP1 = "http1"
+	REVERSE_PROXY_PROTOCOL_HTTP2 = "http2"
+	REVERSE_PROXY_PROTOCOL_GRPC  = "grpc"
+)
+
+var AllReverseProxyProtocols = []string{
+	REVERSE_PROXY_PROTOCOL_HTTP1,
+	REVERSE_PROXY_PROTOCOL_HTTP2,
+	REVERSE_PROXY_PROTOCOL_GRPC,
+}
+
+// ReverseProxyAuthIdentityHeaders are set by the proxy with the identity of
+// the caller, the values sent by the client are always removed
+var ReverseProxyAuthIdentityHeaders = []string{
+	REVERSE_PROXY_AUTH_USER_HEADER,
+	REVERSE_PROXY_AUTH_EMAIL_HEADER,
+	REVERSE_PROXY_AUTH_ROLES_HEADER,
+	REVERSE_PROXY_AUTH_METHOD_HEADER,
+}
+
+const (
+	REVERSE_PROXY_ACCESS_LOG_ENABLED_ENV_VAR = "REVERSE_PROXY_ACCESS_LOG_ENABLED"
+	REVERSE_PROXY_ACCESS_LOG_PATH_ENV_VAR    = "REVERSE_PROXY_ACCESS_LOG_PATH"
+	REVERSE_PROXY_ACCESS_LOG_EVENTS_ENV_VAR  = "REVERSE_PROXY_ACCESS_LOG_EVENTS"
+
+	REVERSE_PROXY_ACCESS_LOG_EVENT = "REVERSE_PROXY_ACCESS_LOG"
+)
@@ -0,0 +1,517 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
+	"github.com/Parallels/prl-devops-service/errors"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/mappers"
+	"github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/orchestrator"
+	"github.com/Parallels/prl-devops-service/restapi"
+	"github.com/Parallels/prl-devops-service/security/apikey"
+	"github.com/Parallels/prl-devops-service/security/policy"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+
+	"github.com/gorilla/mux"
+)
+
+// resourceResolver returns the attributes of the resource of the request
+// that the conditions of the access policies can use, when the resource
+// cannot be found only the type and id are set
+type resourceResolver func(ctx basecontext.ApiContext, r *http.Request) policy.Resource
+
+// withAccessPolicy rejects the request with a 403 when the access policies
+// do not allow the action on the resource of the route. Requests that are
+// not authorized are left to the end of the authorization chain
+func withAccessPolicy(action string, resolve resourceResolver) restapi.Adapter {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorizationContext := basecontext.GetAuthorizationContext(r.Context())
+			if authorizationContext == nil || !authorizationContext.IsAuthorized {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := GetBaseContext(r)
+			subject := getPolicySubject(ctx)
+			policies, err := getAccessPolicies(ctx, subject)
+			if err != nil {
+				// without the policies the deny rules cannot be applied
+				ReturnApiError(ctx, w, models.NewFromError(err))
+				return
+			}
+			if len(policies) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			resource := resolve(ctx, r)
+			decision := policy.Evaluate(policies, subject, resource, action)
+			if !decision.Allowed {
+				ctx.LogInfof("[AccessPolicy] %v on %v %v denied: %v", action, resource.Type, resource.ID, decision.Reason)
+				w.WriteHeader(http.StatusForbidden)
+				_ = json.NewEncoder(w).Encode(models.ApiErrorResponse{
+					Message: "Access denied, " + decision.Reason,
+					Code:    http.StatusForbidden,
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// filterByAccessPolicy removes the items the caller cannot read, the
+// resources are only built when the caller is restricted by a policy
+func filterByAccessPolicy[T any](ctx basecontext.ApiContext, items []T, newResolver func() func(T) policy.Resource) ([]T, error) {
+	subject := getPolicySubject(ctx)
+	policies, err := getAccessPolicies(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	if len(policies) == 0 {
+		return items, nil
+	}
+
+	toResource := newResolver()
+	result := make([]T, 0, len(items))
+	for _, item := range items {
+		if policy.Evaluate(policies, subject, toResource(item), constants.ACCESS_POLICY_ACTION_READ).Allowed {
+			result = append(result, item)
+		}
+	}
+
+	return result, nil
+}
+
+func filterMachinesByAccessPolicy(ctx basecontext.ApiContext, vms []models.ParallelsVM) ([]models.ParallelsVM, error) {
+	return filterByAccessPolicy(ctx, vms, func() func(models.ParallelsVM) policy.Resource {
+		owners := getResourceOwners(ctx, constants.ACCESS_POLICY_RESOURCE_MACHINE)
+		hosts := getOrchestratorHostsById(ctx)
+		return func(vm models.ParallelsVM) policy.Resource {
+			return machinePolicyResource(vm, owners, hosts)
+		}
+	})
+}
+
+func filterOrchestratorHostsByAccessPolicy(ctx basecontext.ApiContext, hosts []*data_models.OrchestratorHost) ([]*data_models.OrchestratorHost, error) {
+	return filterByAccessPolicy(ctx, hosts, func() func(*data_models.OrchestratorHost) policy.Resource {
+		return func(host *data_models.OrchestratorHost) policy.Resource {
+			return orchestratorHostPolicyResource(*host)
+		}
+	})
+}
+
+func filterReverseProxyHostsByAccessPolicy(ctx basecontext.ApiContext, hosts []models.ReverseProxyHost) ([]models.ReverseProxyHost, error) {
+	return filterByAccessPolicy(ctx, hosts, func() func(models.ReverseProxyHost) policy.Resource {
+		owners := getResourceOwners(ctx, constants.ACCESS_POLICY_RESOURCE_REVERSE_PROXY_HOST)
+		return func(host models.ReverseProxyHost) policy.Resource {
+			return reverseProxyHostPolicyResource(host, owners)
+		}
+	})
+}
+
+// filterOrchestratorReverseProxyHostsByAccessPolicy filters the reverse proxy
+// hosts of an orchestrator host, they take the attributes of the host
+func filterOrchestratorReverseProxyHostsByAccessPolicy(ctx basecontext.ApiContext, r *http.Request, hosts []*data_models.ReverseProxyHost) ([]*data_models.ReverseProxyHost, error) {
+	return filterByAccessPolicy(ctx, hosts, func() func(*data_models.ReverseProxyHost) policy.Resource {
+		host := orchestratorHostResource(ctx, r)
+		return func(item *data_models.ReverseProxyHost) policy.Resource {
+			resource := reverseProxyHostPolicyResource(mappers.DtoReverseProxyHostToApi(*item), nil)
+			resource.HostID = host.ID
+			resource.HostTags = host.HostTags
+			return resource
+		}
+	})
+}
+
+// getApiKeyScope returns the catalogs and host tags the api key of the
+// request is restricted to, both are empty when it is not restricted
+func getApiKeyScope(ctx basecontext.ApiContext) ([]string, []string) {
+	authorizationContext := ctx.GetAuthorizationContext()
+	if authorizationContext == nil {
+		return nil, nil
+	}
+
+	return authorizationContext.ScopedCatalogs, authorizationContext.ScopedHostTags
+}
+
+func filterCatalogManifestsByApiKeyScope(ctx basecontext.ApiContext, manifests []data_models.CatalogManifest) []data_models.CatalogManifest {
+	catalogs, _ := getApiKeyScope(ctx)
+	if len(catalogs) == 0 {
+		return manifests
+	}
+
+	result := make([]data_models.CatalogManifest, 0, len(manifests))
+	for _, manifest := range manifests {
+		if apikey.AllowsCatalog(catalogs, manifest.CatalogId) {
+			result = append(result, manifest)
+		}
+	}
+
+	return result
+}
+
+func filterOrchestratorHostsByApiKeyScope(ctx basecontext.ApiContext, hosts []*data_models.OrchestratorHost) []*data_models.OrchestratorHost {
+	_, hostTags := getApiKeyScope(ctx)
+	if len(hostTags) == 0 {
+		return hosts
+	}
+
+	result := make([]*data_models.OrchestratorHost, 0, len(hosts))
+	for _, host := range hosts {
+		if apikey.AllowsHostTags(hostTags, host.Tags) {
+			result = append(result, host)
+		}
+	}
+
+	return result
+}
+
+// filterMachinesByApiKeyScope keeps the orchestrator machines running on the
+// hosts the api key can use
+func filterMachinesByApiKeyScope(ctx basecontext.ApiContext, vms []models.ParallelsVM) []models.ParallelsVM {
+	_, hostTags := getApiKeyScope(ctx)
+	if len(hostTags) == 0 {
+		return vms
+	}
+
+	hosts := getOrchestratorHostsById(ctx)
+	result := make([]models.ParallelsVM, 0, len(vms))
+	for _, vm := range vms {
+		host, ok := hosts[strings.ToLower(vm.HostId)]
+		if ok && apikey.AllowsHostTags(hostTags, host.Tags) {
+			result = append(result, vm)
+		}
+	}
+
+	return result
+}
+
+func getPolicySubject(ctx basecontext.ApiContext) policy.Subject {
+	subject := policy.Subject{}
+	authorizationContext := ctx.GetAuthorizationContext()
+	if authorizationContext == nil {
+		return subject
+	}
+
+	subject.Roles = authorizationContext.GetEffectiveRoles()
+	subject.Claims = authorizationContext.GetEffectiveClaims()
+	subject.IsSuperUser = authorizationContext.IsSuperUser || data.IsRootUser(ctx)
+	if authorizationContext.AuthorizedBy == "ApiKeyAuthorization" {
+		subject.ApiKey = authorizationContext.ApiKeyName
+	}
+	if user := authorizationContext.User; user != nil {
+		subject.ID = user.ID
+		subject.Username = user.Username
+		subject.Email = user.Email
+		subject.IsSuperUser = subject.IsSuperUser || user.IsSuperUser
+	}
+	for _, role := range subject.Roles {
+		if strings.EqualFold(role, constants.SUPER_USER_ROLE) {
+			subject.IsSuperUser = true
+		}
+	}
+
+	return subject
+}
+
+// getAccessPolicies returns nil when the subject is not restricted by the
+// policies so the resources do not need to be looked up. The callers deny the
+// request when the policies cannot be read, so a storage error does not turn
+// the deny policies off
+func getAccessPolicies(ctx basecontext.ApiContext, subject policy.Subject) ([]data_models.AccessPolicy, error) {
+	if subject.IsSuperUser {
+		return nil, nil
+	}
+
+	dbService, err := serviceprovider.GetDatabaseService(ctx)
+	if err != nil {
+		ctx.LogErrorf("[AccessPolicy] Error getting the database service: %v", err)
+		return nil, errors.NewFromErrorWithCodef(err, http.StatusInternalServerError, "unable to read the access policies")
+	}
+	policies, err := dbService.GetAccessPolicies(ctx, "")
+	if err != nil {
+		ctx.LogErrorf("[AccessPolicy] Error getting the access policies: %v", err)
+		return nil, errors.NewFromErrorWithCodef(err, http.StatusInternalServerError, "unable to read the access policies")
+	}
+
+	return policies, nil
+}
+
+// setResourceOwner records the caller as the owner of a resource it created
+func setResourceOwner(ctx basecontext.ApiContext, resourceType string, resourceId string, ownerId string, ownerName string) {
+	if resourceId == "" || ownerId == "" {
+		return
+	}
+
+	dbService, err := serviceprovider.GetDatabaseService(ctx)
+	if err != nil {
+		ctx.LogErrorf("[AccessPolicy] Error getting the database service: %v", err)
+		return
+	}
+	if err := dbService.SetResourceOwner(ctx, data_models.ResourceOwner{
+		ResourceType: resourceType,
+		ResourceID:   resourceId,
+		OwnerID:      ownerId,
+		OwnerName:    ownerName,
+	}); err != nil {
+		ctx.LogErrorf("[AccessPolicy] Error setting the owner of %v %v: %v", resourceType, resourceId, err)
+	}
+}
+
+// setCallerAsResourceOwner records the caller of the request as the owner
+func setCallerAsResourceOwner(ctx basecontext.ApiContext, resourceType string, resourceId string) {
+	ownerId, ownerName := getCallerOwner(ctx)
+	setResourceOwner(ctx, resourceType, resourceId, ownerId, ownerName)
+}
+
+func deleteResourceOwner(ctx basecontext.ApiContext, resourceType string, resourceId string) {
+	dbService, err := serviceprovider.GetDatabaseService(ctx)
+	if err != nil {
+		return
+	}
+	if err := dbService.DeleteResourceOwner(ctx, resourceType, resourceId); err != nil {
+		ctx.LogErrorf("[AccessPolicy] Error deleting the owner of %v %v: %v", resourceType, resourceId, err)
+	}
+}
+
+// getCallerOwner returns the id and name recorded as the owner of the
+// resources created by the request, the api key is the owner when the
+// request has no user
+func getCallerOwner(ctx basecontext.ApiContext) (string, string) {
+	authorizationContext := ctx.GetAuthorizationContext()
+	if authorizationContext == nil {
+		return "", ""
+	}
+	if user := authorizationContext.User; user != nil {
+		return user.ID, user.Username
+	}
+	if authorizationContext.AuthorizedBy == "ApiKeyAuthorization" {
+		return authorizationContext.ApiKeyName, authorizationContext.ApiKeyName
+	}
+
+	return "", ""
+}
+
+func getResourceOwners(ctx basecontext.ApiContext, resourceType string) map[string]data_models.ResourceOwner {
+	dbService, err := serviceprovider.GetDatabaseService(ctx)
+	if err != nil {
+		return map[string]data_models.ResourceOwner{}
+	}
+	owners, err := dbService.GetResourceOwners(ctx, resourceType)
+	if err != nil {
+		return map[string]data_models.ResourceOwner{}
+	}
+
+	return owners
+}
+
+func getOrchestratorHostsById(ctx basecontext.ApiContext) map[string]data_models.OrchestratorHost {
+	result := make(map[string]data_models.OrchestratorHost)
+	dbService, err := serviceprovider.GetDatabaseService(ctx)
+	if err != nil {
+		return result
+	}
+	hosts, err := dbService.GetOrchestratorHosts(ctx, "")
+	if err != nil {
+		return result
+	}
+	for _, host := range hosts {
+		result[strings.ToLower(host.ID)] = host
+	}
+
+	return result
+}
+
+func machinePolicyResource(vm models.ParallelsVM, owners map[string]data_models.ResourceOwner, hosts map[string]data_models.OrchestratorHost) policy.Resource {
+	resource := policy.Resource{
+		Type:   constants.ACCESS_POLICY_RESOURCE_MACHINE,
+		ID:     vm.ID,
+		Name:   vm.Name,
+		HostID: vm.HostId,
+		Host:   vm.Host,
+		State:  vm.State,
+		OS:     vm.OS,
+		Owner:  owners[strings.ToLower(vm.ID)].OwnerID,
+	}
+	if host, ok := hosts[strings.ToLower(vm.HostId)]; ok {
+		resource.HostTags = host.Tags
+		if resource.Host == "" {
+			resource.Host = host.Host
+		}
+	}
+
+	return resource
+}
+
+func orchestratorHostPolicyResource(host data_models.OrchestratorHost) policy.Resource {
+	name := host.Description
+	if name == "" {
+		name = host.Host
+	}
+
+	return policy.Resource{
+		Type:     constants.ACCESS_POLICY_RESOURCE_ORCHESTRATOR_HOST,
+		ID:       host.ID,
+		Name:     name,
+		HostID:   host.ID,
+		Host:     host.Host,
+		HostTags: host.Tags,
+		Tags:     host.Tags,
+		State:    host.State,
+		OS:       host.OsName,
+	}
+}
+
+func reverseProxyHostPolicyResource(host models.ReverseProxyHost, owners map[string]data_models.ResourceOwner) policy.Resource {
+	return policy.Resource{
+		Type:  constants.ACCESS_POLICY_RESOURCE_REVERSE_PROXY_HOST,
+		ID:    host.ID,
+		Name:  host.Name,
+		Host:  host.GetHost(),
+		Owner: owners[strings.ToLower(host.ID)].OwnerID,
+	}
+}
+
+// localMachineResource resolves the machine of the {id} variable on this host
+func localMachineResource(ctx basecontext.ApiContext, r *http.Request) policy.Resource {
+	id := mux.Vars(r)["id"]
+	resource := policy.Resource{Type: constants.ACCESS_POLICY_RESOURCE_MACHINE, ID: id}
+	provider := serviceprovider.Get()
+	if provider == nil || provider.ParallelsDesktopService == nil {
+		return resource
+	}
+	vm, err := provider.ParallelsDesktopService.GetVmSync(ctx, id)
+	if err != nil || vm == nil {
+		return resource
+	}
+
+	return machinePolicyResource(*vm, getResourceOwners(ctx, constants.ACCESS_POLICY_RESOURCE_MACHINE), nil)
+}
+
+// newMachineResource is a machine that is being created, the caller is its
+// owner. When the route has a host {id} the machine takes its attributes
+func newMachineResource(ctx basecontext.ApiContext, r *http.Request) policy.Resource {
+	owner, _ := getCallerOwner(ctx)
+	resource := policy.Resource{Type: constants.ACCESS_POLICY_RESOURCE_MACHINE, Owner: owner}
+	if hostId := mux.Vars(r)["id"]; hostId != "" {
+		host := orchestratorHostResource(ctx, r)
+		resource.HostID = host.ID
+		resource.Host = host.Host
+		resource.HostTags = host.HostTags
+	}
+
+	return resource
+}
+
+// orchestratorMachineResource resolves the machine of the {id} variable on
+// any of the hosts of the orchestrator
+func orchestratorMachineResource(ctx basecontext.ApiContext, r *http.Request) policy.Resource {
+	id := mux.Vars(r)["id"]
+	vm, err := orchestrator.NewOrchestratorService(ctx).GetVirtualMachine(ctx, id, false)
+	if err != nil || vm == nil {
+		return policy.Resource{Type: constants.ACCESS_POLICY_RESOURCE_MACHINE, ID: id}
+	}
+
+	return orchestratorVirtualMachinePolicyResource(ctx, *vm)
+}
+
+// orchestratorHostMachineResource resolves the machine of the {vmId}
+// variable on the host of the {id} variable
+func orchestratorHostMachineResource(ctx basecontext.ApiContext, r *http.Request) policy.Resource {
+	vars := mux.Vars(r)
+	vm, err := orchestrator.NewOrchestratorService(ctx).GetHostVirtualMachine(ctx, vars["id"], vars["vmId"], false)
+	if err != nil || vm == nil {
+		host := orchestratorHostResource(ctx, r)
+		return policy.Resource{
+			Type:     constants.ACCESS_POLICY_RESOURCE_MACHINE,
+			ID:       vars["vmId"],
+			HostID:   host.ID,
+			Host:     host.Host,
+			HostTags: host.HostTags,
+		}
+	}
+
+	return orchestratorVirtualMachinePolicyResource(ctx, *vm)
+}
+
+func orchestratorVirtualMachinePolicyResource(ctx basecontext.ApiContext, vm data_models.VirtualMachine) policy.Resource {
+	resource := policy.Resource{
+		Type:   constants.ACCESS_POLICY_RESOURCE_MACHINE,
+		ID:     vm.ID,
+		Name:   vm.Name,
+		HostID: vm.HostId,
+		Host:   vm.Host,
+		State:  vm.State,
+		OS:     vm.OS,
+		Owner:  getResourceOwners(ctx, constants.ACCESS_POLICY_RESOURCE_MACHINE)[strings.ToLower(vm.ID)].OwnerID,
+	}
+	if host, ok := getOrchestratorHostsById(ctx)[strings.ToLower(vm.HostId)]; ok {
+		resource.HostTags = host.Tags
+	}
+
+	return resource
+}
+
+// orchestratorHostResource resolves the orchestrator host of the {id}
+// variable
+func orchestratorHostResource(ctx basecontext.ApiContext, r *http.Request) policy.Resource {
+	id := mux.Vars(r)["id"]
+	dbService, err := serviceprovider.GetDatabaseService(ctx)
+	if err != nil {
+		return policy.Resource{Type: constants.ACCESS_POLICY_RESOURCE_ORCHESTRATOR_HOST, ID: id, HostID: id}
+	}
+	host, err := dbService.GetOrchestratorHost(ctx, id)
+	if err != nil || host == nil {
+		return policy.Resource{Type: constants.ACCESS_POLICY_RESOURCE_ORCHESTRATOR_HOST, ID: id, HostID: id}
+	}
+
+	return orchestratorHostPolicyResource(*host)
+}
+
+// reverseProxyHostResource resolves the reverse proxy host of the {id}
+// variable on this host
+func reverseProxyHostResource(ctx basecontext.ApiContext, r *http.Request) policy.Resource {
+	id := mux.Vars(r)["id"]
+	resource := policy.Resource{Type: constants.ACCESS_POLICY_RESOURCE_REVERSE_PROXY_HOST, ID: id}
+	dbService, err := serviceprovider.GetDatabaseService(ctx)
+	if err != nil {
+		return resource
+	}
+	host, err := dbService.GetReverseProxyHost(ctx, id)
+	if err != nil || host == nil {
+		return resource
+	}
+
+	return reverseProxyHostPolicyResource(mappers.DtoReverseProxyHostToApi(*host), getResourceOwners(ctx, constants.ACCESS_POLICY_RESOURCE_REVERSE_PROXY_HOST))
+}
+
+// newReverseProxyHostResource is a reverse proxy host that is being created
+// on this host, the caller is its owner
+func newReverseProxyHostResource(ctx basecontext.ApiContext, r *http.Request) policy.Resource {
+	owner, _ := getCallerOwner(ctx)
+	return policy.Resource{Type: constants.ACCESS_POLICY_RESOURCE_REVERSE_PROXY_HOST, Owner: owner}
+}
+
+// orchestratorReverseProxyHostResource is the reverse proxy host of the
+// {reverse_proxy_host_id} variable on the orchestrator host of the {id}
+// variable, the attributes of the host are used as the reverse proxy host is
+// kept by the remote host
+func orchestratorReverseProxyHostResource(ctx basecontext.ApiContext, r *http.Request) policy.Resource {
+	host := orchestratorHostResource(ctx, r)
+	return policy.Resource{
+		Type:     constants.ACCESS_POLICY_RESOURCE_REVERSE_PROXY_HOST,
+		ID:       mux.Vars(r)["reverse_proxy_host_id"],
+		HostID:   host.ID,
+		Host:     host.Host,
+		HostTags: host.HostTags,
+	}
+}
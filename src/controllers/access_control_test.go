@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/security/policy"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPolicyRequest(t *testing.T) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/machines", nil)
+	authCtx := &basecontext.AuthorizationContext{
+		IsAuthorized: true,
+		AuthorizedBy: "test",
+		User:         &models.ApiUser{ID: "user-id", Username: "user", Roles: []string{constants.USER_ROLE}},
+	}
+	return req.WithContext(context.WithValue(req.Context(), constants.AUTHORIZATION_CONTEXT_KEY, authCtx))
+}
+
+// TestAccessPolicyDeniesWhenPoliciesCannotBeRead ensures a storage error
+// does not turn the deny policies off
+func TestAccessPolicyDeniesWhenPoliciesCannotBeRead(t *testing.T) {
+	serviceprovider.NewMockProvider()
+	req := newPolicyRequest(t)
+
+	called := false
+	handler := withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, func(ctx basecontext.ApiContext, r *http.Request) policy.Resource {
+		return policy.Resource{Type: constants.ACCESS_POLICY_RESOURCE_MACHINE, ID: "vm"}
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.False(t, called)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	ctx := basecontext.NewBaseContextFromRequest(req)
+	ctx.DisableLog()
+	vms, err := filterMachinesByAccessPolicy(ctx, []models.ParallelsVM{{ID: "vm"}})
+	require.Error(t, err)
+	assert.Nil(t, vms)
+}
@@ -0,0 +1,303 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/mappers"
+	"github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/restapi"
+	"github.com/Parallels/prl-devops-service/security/policy"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+
+	"github.com/cjlapao/common-go/helper/http_helper"
+	"github.com/gorilla/mux"
+)
+
+func registerAccessPoliciesHandlers(ctx basecontext.ApiContext, version string) {
+	ctx.LogInfof("Registering version %s Access Policies handlers", version)
+
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/auth/policies").
+		WithRequiredClaim(constants.LIST_ACCESS_POLICY_CLAIM).
+		WithHandler(GetAccessPoliciesHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/auth/policies/{id}").
+		WithRequiredClaim(constants.LIST_ACCESS_POLICY_CLAIM).
+		WithHandler(GetAccessPolicyHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.POST).
+		WithVersion(version).
+		WithPath("/auth/policies").
+		WithRequiredClaim(constants.CREATE_ACCESS_POLICY_CLAIM).
+		WithHandler(CreateAccessPolicyHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.PUT).
+		WithVersion(version).
+		WithPath("/auth/policies/{id}").
+		WithRequiredClaim(constants.UPDATE_ACCESS_POLICY_CLAIM).
+		WithHandler(UpdateAccessPolicyHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.DELETE).
+		WithVersion(version).
+		WithPath("/auth/policies/{id}").
+		WithRequiredClaim(constants.DELETE_ACCESS_POLICY_CLAIM).
+		WithHandler(DeleteAccessPolicyHandler()).
+		Register()
+}
+
+// @Summary		Gets all the access policies
+// @Description	This endpoint returns all the access policies of machines, orchestrator hosts and reverse proxy hosts
+// @Tags			Authorization
+// @Produce		json
+// @Param			filter	header		string	false	"X-Filter"
+// @Success		200		{object}	[]models.AccessPolicyResponse
+// @Failure		400		{object}	models.ApiErrorResponse
+// @Failure		401		{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/auth/policies [get]
+func GetAccessPoliciesHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		policies, err := dbService.GetAccessPolicies(ctx, GetFilterHeader(r))
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(mappers.AccessPoliciesDtoToApi(policies))
+		ctx.LogInfof("Access policies returned: %v", len(policies))
+	}
+}
+
+// @Summary		Gets an access policy
+// @Description	This endpoint returns an access policy by id or name
+// @Tags			Authorization
+// @Produce		json
+// @Param			id	path		string	true	"Policy ID or Name"
+// @Success		200	{object}	models.AccessPolicyResponse
+// @Failure		400	{object}	models.ApiErrorResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/auth/policies/{id} [get]
+func GetAccessPolicyHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		vars := mux.Vars(r)
+		id := vars["id"]
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		accessPolicy, err := dbService.GetAccessPolicy(ctx, id)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(mappers.AccessPolicyDtoToApi(*accessPolicy))
+		ctx.LogInfof("Access policy %s returned", id)
+	}
+}
+
+// @Summary		Creates an access policy
+// @Description	This endpoint creates an access policy, the condition is checked before the policy is saved
+// @Tags			Authorization
+// @Produce		json
+// @Param			request	body		models.AccessPolicyRequest	true	"Access Policy"
+// @Success		201		{object}	models.AccessPolicyResponse
+// @Failure		400		{object}	models.ApiErrorResponse
+// @Failure		401		{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/auth/policies [post]
+func CreateAccessPolicyHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		request, ok := getAccessPolicyRequest(ctx, w, r)
+		if !ok {
+			return
+		}
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		accessPolicy, err := dbService.CreateAccessPolicy(ctx, mappers.AccessPolicyRequestToDto(*request))
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		response := mappers.AccessPolicyDtoToApi(*accessPolicy)
+		auditChange(r, accessPolicy.ID, nil, response)
+
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("Access policy %s created", accessPolicy.Name)
+	}
+}
+
+// @Summary		Updates an access policy
+// @Description	This endpoint updates an access policy
+// @Tags			Authorization
+// @Produce		json
+// @Param			id		path		string						true	"Policy ID or Name"
+// @Param			request	body		models.AccessPolicyRequest	true	"Access Policy"
+// @Success		200		{object}	models.AccessPolicyResponse
+// @Failure		400		{object}	models.ApiErrorResponse
+// @Failure		401		{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/auth/policies/{id} [put]
+func UpdateAccessPolicyHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		vars := mux.Vars(r)
+		id := vars["id"]
+
+		request, ok := getAccessPolicyRequest(ctx, w, r)
+		if !ok {
+			return
+		}
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		existing, err := dbService.GetAccessPolicy(ctx, id)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		dto := mappers.AccessPolicyRequestToDto(*request)
+		dto.ID = existing.ID
+		accessPolicy, err := dbService.UpdateAccessPolicy(ctx, dto)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		response := mappers.AccessPolicyDtoToApi(*accessPolicy)
+		auditChange(r, accessPolicy.ID, mappers.AccessPolicyDtoToApi(*existing), response)
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("Access policy %s updated", accessPolicy.Name)
+	}
+}
+
+// @Summary		Deletes an access policy
+// @Description	This endpoint deletes an access policy
+// @Tags			Authorization
+// @Produce		json
+// @Param			id	path	string	true	"Policy ID or Name"
+// @Success		202
+// @Failure		400	{object}	models.ApiErrorResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/auth/policies/{id} [delete]
+func DeleteAccessPolicyHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		vars := mux.Vars(r)
+		id := vars["id"]
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		accessPolicy, err := dbService.GetAccessPolicy(ctx, id)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		if err := dbService.DeleteAccessPolicy(ctx, accessPolicy.ID); err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		auditChange(r, accessPolicy.ID, mappers.AccessPolicyDtoToApi(*accessPolicy), nil)
+
+		w.WriteHeader(http.StatusAccepted)
+		ctx.LogInfof("Access policy %s deleted", accessPolicy.Name)
+	}
+}
+
+func getAccessPolicyRequest(ctx basecontext.ApiContext, w http.ResponseWriter, r *http.Request) (*models.AccessPolicyRequest, bool) {
+	var request models.AccessPolicyRequest
+	if err := http_helper.MapRequestBody(r, &request); err != nil {
+		ReturnApiError(ctx, w, models.ApiErrorResponse{
+			Message: "Invalid request body: " + err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return nil, false
+	}
+	if err := request.Validate(); err != nil {
+		ReturnApiError(ctx, w, models.ApiErrorResponse{
+			Message: "Invalid request body: " + err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return nil, false
+	}
+	if err := policy.Validate(request.Condition); err != nil {
+		ReturnApiError(ctx, w, models.ApiErrorResponse{
+			Message: "Invalid request body: " + err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return nil, false
+	}
+
+	return &request, true
+}
@@ -5,13 +5,18 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Parallels/prl-devops-service/basecontext"
 	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
+	dbmodels "github.com/Parallels/prl-devops-service/data/models"
 	"github.com/Parallels/prl-devops-service/errors"
 	"github.com/Parallels/prl-devops-service/mappers"
 	"github.com/Parallels/prl-devops-service/models"
 	"github.com/Parallels/prl-devops-service/restapi"
+	"github.com/Parallels/prl-devops-service/security"
 	"github.com/Parallels/prl-devops-service/serviceprovider"
 
 	"github.com/cjlapao/common-go/helper/http_helper"
@@ -77,6 +82,15 @@ func registerApiKeysHandlers(ctx basecontext.ApiContext, version string) {
 		WithHandler(DeleteApiKeyHandler()).
 		Register()
 
+	restapi.NewController().
+		WithMethod(restapi.PUT).
+		WithVersion(version).
+		WithPath("/auth/api_keys/{id}/rotate").
+		WithRequiredClaim(constants.UPDATE_API_KEY_CLAIM).
+		WithRequiredClaim(constants.UPDATE_OWN_API_KEY_CLAIM).
+		WithHandler(RotateApiKeyHandler()).
+		Register()
+
 	restapi.NewController().
 		WithMethod(restapi.PUT).
 		WithVersion(version).
@@ -158,6 +172,13 @@ func CreateApiKeyHandler() restapi.ControllerHandler {
 			}
 		}
 
+		if err := validateApiKeyScopeClaims(ctx, dbService, dtoApiKey); err != nil {
+			rsp := models.NewFromError(err)
+			createApiKeyDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "ValidateScope")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(createApiKeyDiag, rsp.Code))
+			return
+		}
+
 		dtoApiKeyResult, err := dbService.CreateApiKey(ctx, dtoApiKey)
 		if err != nil {
 			rsp := models.NewFromError(err)
@@ -166,6 +187,7 @@ func CreateApiKeyHandler() restapi.ControllerHandler {
 			return
 		}
 		response := mappers.ApiKeyDtoToApiKeyResponse(*dtoApiKeyResult)
+		auditChange(r, response.ID, nil, response)
 		response.Encoded = base64.StdEncoding.EncodeToString([]byte(request.Key + ":" + request.Secret))
 
 		w.WriteHeader(http.StatusCreated)
@@ -248,6 +270,7 @@ func DeleteApiKeyHandler() restapi.ControllerHandler {
 		vars := mux.Vars(r)
 		id := vars["id"]
 
+		before := getAuditApiKey(ctx, dbService, id)
 		err = dbService.DeleteApiKey(ctx, id)
 		if err != nil {
 			rsp := models.NewFromError(err)
@@ -256,6 +279,7 @@ func DeleteApiKeyHandler() restapi.ControllerHandler {
 			return
 		}
 
+		auditChange(r, id, before, nil)
 		w.WriteHeader(http.StatusAccepted)
 		ctx.LogInfof("Api Key deleted successfully")
 	}
@@ -339,6 +363,7 @@ func RevokeApiKeyHandler() restapi.ControllerHandler {
 		vars := mux.Vars(r)
 		id := vars["id"]
 
+		before := getAuditApiKey(ctx, dbService, id)
 		err = dbService.RevokeKey(ctx, id)
 		if err != nil {
 			rsp := models.NewFromError(err)
@@ -347,7 +372,159 @@ func RevokeApiKeyHandler() restapi.ControllerHandler {
 			return
 		}
 
+		auditChange(r, id, before, getAuditApiKey(ctx, dbService, id))
 		w.WriteHeader(http.StatusAccepted)
 		ctx.LogInfof("Api Key revoked successfully")
 	}
 }
+
+// @Summary		Rotates an api key
+// @Description	This endpoint replaces the secret of an api key, the previous secret keeps working during the overlap
+// @Tags			Api Keys
+// @Produce		json
+// @Claims			"UPDATE_API_KEY"
+// @Param			id			path	string						true	"Api Key ID"
+// @Param			rotation	body	models.ApiKeyRotateRequest	false	"Body"
+// @Success		200			{object}	models.ApiKeyResponse
+// @Failure		400			{object}	models.ApiErrorDiagnosticsResponse
+// @Failure		401			{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/auth/api_keys/{id}/rotate [put]
+func RotateApiKeyHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		rotateApiKeyDiag := errors.NewDiagnostics("/auth/api_keys/{id}/rotate [put]")
+		var request models.ApiKeyRotateRequest
+		if r.ContentLength > 0 {
+			if err := http_helper.MapRequestBody(r, &request); err != nil {
+				rotateApiKeyDiag.AddError(strconv.Itoa(http.StatusBadRequest), "Invalid request body: "+err.Error(), "MapRequestBody")
+				ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(rotateApiKeyDiag, http.StatusBadRequest))
+				return
+			}
+		}
+		if err := request.Validate(); err != nil {
+			rotateApiKeyDiag.AddError(strconv.Itoa(http.StatusBadRequest), err.Error(), "Validate")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(rotateApiKeyDiag, http.StatusBadRequest))
+			return
+		}
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			rotateApiKeyDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "ServiceProvider")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(rotateApiKeyDiag, rsp.Code))
+			return
+		}
+
+		vars := mux.Vars(r)
+		id := vars["id"]
+
+		dtoApiKey, err := dbService.GetApiKey(ctx, id)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			rotateApiKeyDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "GetApiKey")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(rotateApiKeyDiag, rsp.Code))
+			return
+		}
+		if dtoApiKey.Type == "internal" {
+			rotateApiKeyDiag.AddError(strconv.Itoa(http.StatusForbidden), "Internal API keys cannot be rotated", "Validation")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(rotateApiKeyDiag, http.StatusForbidden))
+			return
+		}
+		authContext := ctx.GetAuthorizationContext()
+		if authContext != nil && authContext.User != nil {
+			// Users with only UPDATE_OWN_API_KEY_CLAIM can only rotate their own keys
+			if !authContext.HasEffectiveClaim(constants.UPDATE_API_KEY_CLAIM) && dtoApiKey.UserID != authContext.User.ID {
+				rotateApiKeyDiag.AddError(strconv.Itoa(http.StatusForbidden), "You do not have permission to rotate API keys of other users", "Validation")
+				ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(rotateApiKeyDiag, http.StatusForbidden))
+				return
+			}
+		}
+
+		if request.Secret == "" {
+			request.Secret, err = security.GenerateCryptoRandomString(32)
+			if err != nil {
+				rsp := models.NewFromError(err)
+				rotateApiKeyDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "GenerateSecret")
+				ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(rotateApiKeyDiag, rsp.Code))
+				return
+			}
+		}
+		overlap, _ := time.ParseDuration(request.Overlap)
+
+		before := getAuditApiKey(ctx, dbService, dtoApiKey.ID)
+		dtoApiKey, err = dbService.RotateApiKey(ctx, dtoApiKey.ID, request.Secret, overlap)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			rotateApiKeyDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "RotateApiKey")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(rotateApiKeyDiag, rsp.Code))
+			return
+		}
+
+		response := mappers.ApiKeyDtoToApiKeyResponse(*dtoApiKey)
+		auditChange(r, dtoApiKey.ID, before, response)
+		response.Encoded = base64.StdEncoding.EncodeToString([]byte(dtoApiKey.Key + ":" + request.Secret))
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("Api Key rotated successfully")
+	}
+}
+
+// validateApiKeyScopeClaims checks the claims of the scope exist and that the
+// user of the key has them
+func validateApiKeyScopeClaims(ctx basecontext.ApiContext, dbService *data.JsonDatabase, apiKey dbmodels.ApiKey) error {
+	if apiKey.Scope == nil || len(apiKey.Scope.Claims) == 0 {
+		return nil
+	}
+
+	for _, claim := range apiKey.Scope.Claims {
+		if dbClaim, err := dbService.GetClaim(ctx, claim); err != nil || dbClaim == nil {
+			return errors.NewWithCodef(http.StatusBadRequest, "claim %v does not exist", claim)
+		}
+	}
+	if apiKey.UserID == "" {
+		return nil
+	}
+
+	user, err := dbService.GetUser(ctx, apiKey.UserID)
+	if err != nil {
+		return err
+	}
+	for _, role := range user.Roles {
+		if strings.EqualFold(role.Name, constants.SUPER_USER_ROLE) {
+			return nil
+		}
+	}
+
+	userClaims := mappers.ComputeEffectiveClaimIDs(*user)
+	for _, claim := range apiKey.Scope.Claims {
+		found := false
+		for _, userClaim := range userClaims {
+			if strings.EqualFold(claim, userClaim) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.NewWithCodef(http.StatusForbidden, "the user of the api key does not have the claim %v", claim)
+		}
+	}
+
+	return nil
+}
+
+// getAuditApiKey returns the api key as the api returns it for the audit log,
+// nil if it does not exist
+func getAuditApiKey(ctx basecontext.ApiContext, dbService *data.JsonDatabase, id string) *models.ApiKeyResponse {
+	dtoApiKey, err := dbService.GetApiKey(ctx, id)
+	if err != nil || dtoApiKey == nil {
+		return nil
+	}
+
+	apiKey := mappers.ApiKeyDtoToApiKeyResponse(*dtoApiKey)
+	return &apiKey
+}
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/audit"
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/restapi"
+)
+
+func registerAuditHandlers(ctx basecontext.ApiContext, version string) {
+	ctx.LogInfof("Registering version %s Audit handlers", version)
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/audit").
+		WithRequiredClaim(constants.LIST_AUDIT_LOG_CLAIM).
+		WithHandler(GetAuditLogHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/audit/export").
+		WithRequiredClaim(constants.LIST_AUDIT_LOG_CLAIM).
+		WithHandler(ExportAuditLogHandler()).
+		Register()
+}
+
+// @Summary		Gets the audit log
+// @Description	This endpoint returns the changes made through the api, newest first unless a sort is set. It accepts the actor, resource_type, resource_id, action, outcome, request_id, from and to query parameters besides the list filter, sort and pagination
+// @Tags			Audit
+// @Produce		json
+// @Param			actor			query		string	false	"User or api key that made the change"
+// @Param			resource_type	query		string	false	"Resource type"
+// @Param			resource_id		query		string	false	"Resource id"
+// @Param			action			query		string	false	"Action"
+// @Param			outcome			query		string	false	"success, failure or denied"
+// @Param			request_id		query		string	false	"Request id"
+// @Param			from			query		string	false	"Start time, RFC 3339 or YYYY-MM-DD"
+// @Param			to				query		string	false	"End time, RFC 3339 or YYYY-MM-DD"
+// @Success		200				{object}	[]audit.Entry
+// @Failure		400				{object}	models.ApiErrorResponse
+// @Failure		401				{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/audit [get]
+func GetAuditLogHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		entries, err := queryAuditLog(r)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+		if r.URL.Query().Get("sort") == "" {
+			reverseAuditEntries(entries)
+		}
+
+		ReturnApiListResponse(ctx, w, r, entries, http.StatusOK)
+		ctx.LogInfof("Audit log returned: %v", len(entries))
+	}
+}
+
+// @Summary		Exports the audit log
+// @Description	This endpoint exports the audit log as JSON lines or RFC 5424 syslog messages, oldest first. It accepts the same filters as the audit log
+// @Tags			Audit
+// @Produce		plain
+// @Param			format	query		string	false	"jsonl (default) or syslog"
+// @Success		200		{string}	string
+// @Failure		400		{object}	models.ApiErrorResponse
+// @Failure		401		{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/audit/export [get]
+func ExportAuditLogHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		format := strings.ToLower(r.URL.Query().Get("format"))
+		if format == "" {
+			format = "jsonl"
+		}
+		if format != "jsonl" && format != "syslog" {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.Newf("invalid format %v, use jsonl or syslog", format), http.StatusBadRequest))
+			return
+		}
+
+		entries, err := queryAuditLog(r)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		hostname, _ := os.Hostname()
+		extension := "jsonl"
+		restapi.SetContentType("application/x-ndjson", w)
+		if format == "syslog" {
+			extension = "log"
+			restapi.SetContentType("text/plain", w)
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=audit-%s.%s", time.Now().UTC().Format("20060102150405"), extension))
+		w.WriteHeader(http.StatusOK)
+
+		encoder := json.NewEncoder(w)
+		for _, entry := range entries {
+			if format == "jsonl" {
+				_ = encoder.Encode(entry)
+				continue
+			}
+			if message, err := audit.FormatSyslog(entry, hostname); err == nil {
+				_, _ = fmt.Fprintln(w, message)
+			}
+		}
+		ctx.LogInfof("Audit log exported: %v entries as %v", len(entries), format)
+	}
+}
+
+func queryAuditLog(r *http.Request) ([]audit.Entry, error) {
+	auditLog := audit.Get()
+	if auditLog == nil {
+		return nil, errors.NewWithCode("the audit log is not enabled", http.StatusNotFound)
+	}
+
+	values := r.URL.Query()
+	query := audit.Query{
+		Actor:        values.Get("actor"),
+		ResourceType: values.Get("resource_type"),
+		ResourceId:   values.Get("resource_id"),
+		Action:       values.Get("action"),
+		Outcome:      values.Get("outcome"),
+		RequestId:    values.Get("request_id"),
+	}
+
+	var err error
+	if query.From, err = audit.ParseTime(values.Get("from")); err != nil {
+		return nil, err
+	}
+	if query.To, err = audit.ParseTime(values.Get("to")); err != nil {
+		return nil, err
+	}
+	// a date without a time includes the whole day
+	if len(strings.TrimSpace(values.Get("to"))) == len("2006-01-02") {
+		query.To = query.To.Add(24*time.Hour - time.Millisecond)
+	}
+
+	entries, err := auditLog.Query(query)
+	if err != nil {
+		return nil, errors.NewWithCodef(http.StatusInternalServerError, "error reading the audit log: %v", err)
+	}
+
+	return entries, nil
+}
+
+// auditChange records the resource changed by the request with its state
+// before and after the change, before is nil for created resources and after
+// is nil for deleted ones
+func auditChange(r *http.Request, resourceId string, before interface{}, after interface{}) {
+	audit.SetResourceId(r.Context(), resourceId)
+	audit.RecordChange(r.Context(), before, after)
+}
+
+func reverseAuditEntries(entries []audit.Entry) {
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+}
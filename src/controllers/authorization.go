@@ -3,18 +3,28 @@ package controllers
 import (
 	"encoding/json"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Parallels/prl-devops-service/audit"
 	"github.com/Parallels/prl-devops-service/basecontext"
 	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
 	dbmodels "github.com/Parallels/prl-devops-service/data/models"
 	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/helpers"
 	"github.com/Parallels/prl-devops-service/mappers"
 	models "github.com/Parallels/prl-devops-service/models"
 	"github.com/Parallels/prl-devops-service/restapi"
+	"github.com/Parallels/prl-devops-service/security"
 	"github.com/Parallels/prl-devops-service/security/apikey"
 	bruteforceguard "github.com/Parallels/prl-devops-service/security/brute_force_guard"
 	"github.com/Parallels/prl-devops-service/security/jwt"
+	"github.com/Parallels/prl-devops-service/security/ldap"
+	"github.com/Parallels/prl-devops-service/security/mfa"
+	"github.com/Parallels/prl-devops-service/security/oidc"
 	"github.com/Parallels/prl-devops-service/security/password"
 	"github.com/Parallels/prl-devops-service/serviceprovider"
 
@@ -27,7 +37,7 @@ func registerAuthorizationHandlers(ctx basecontext.ApiContext, version string) {
 		WithMethod(restapi.POST).
 		WithVersion(version).
 		WithPath("/auth/token").
-		WithHandler(GetTokenHandler()).
+		WithHandler(restapi.AuditAuthenticationHandler(audit.ActionLogin, GetTokenHandler())).
 		Register()
 
 	restapi.NewController().
@@ -36,10 +46,39 @@ func registerAuthorizationHandlers(ctx basecontext.ApiContext, version string) {
 		WithPath("/auth/token/validate").
 		WithHandler(ValidateTokenHandler()).
 		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.POST).
+		WithVersion(version).
+		WithPath("/auth/token/refresh").
+		WithHandler(restapi.AuditAuthenticationHandler(audit.ActionRefresh, RefreshTokenHandler())).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.POST).
+		WithVersion(version).
+		WithPath("/auth/logout").
+		WithAuthorization().
+		WithHandler(LogoutHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/auth/oidc/login").
+		WithHandler(OidcLoginHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/auth/oidc/callback").
+		WithHandler(restapi.AuditAuthenticationHandler(audit.ActionLogin, OidcCallbackHandler())).
+		Register()
 }
 
 // @Summary		Generates a token
-// @Description	This endpoint generates a token
+// @Description	This endpoint generates a token, users with multi-factor authentication get a challenge to complete at /v1/auth/token/mfa instead
 // @Tags			Authorization
 // @Produce		json
 // @Param			login	body		models.LoginRequest	true	"Body"
@@ -82,8 +121,11 @@ func GetTokenHandler() restapi.ControllerHandler {
 			idOrEmail = "" // fallback, shouldn't happen due to validation
 		}
 
+		audit.SetActor(r.Context(), audit.ActorTypeUser, idOrEmail)
+
 		var user *dbmodels.User
 		var apiKeyId string
+		isDirectoryLogin := false
 
 		if request.ApiKey != "" {
 			result, err := apikey.ValidateApiKey(ctx, dbService, request.ApiKey)
@@ -99,7 +141,19 @@ func GetTokenHandler() restapi.ControllerHandler {
 				return
 			}
 
+			// tokens are not bound to the scope, scoped keys have to be sent
+			// on every request
+			if result.Scope != nil {
+				getTokenDiag.AddError(strconv.Itoa(http.StatusForbidden), "Scoped API keys cannot be exchanged for a token, use the X-Api-Key header instead", "CheckScope")
+				ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(getTokenDiag, http.StatusForbidden))
+				return
+			}
+
 			apiKeyId = result.ApiKeyId
+			audit.SetActor(r.Context(), audit.ActorTypeApiKey, result.ApiKeyName)
+			if err := dbService.UpdateApiKeyUsage(ctx, apiKeyId, restapi.GetClientIp(r)); err != nil {
+				ctx.LogWarnf("Error recording the usage of the Api Key: %v", err)
+			}
 			if result.UserID != "" {
 				user, err = dbService.GetUser(ctx, result.UserID)
 				if err != nil || user == nil {
@@ -115,6 +169,23 @@ func GetTokenHandler() restapi.ControllerHandler {
 					return
 				}
 			}
+		} else if ldapSvc := ldap.Get(); ldapSvc != nil && ldapSvc.HandlesUser(getOptionalUser(ctx, dbService, idOrEmail)) {
+			// the directory checks the password, the user is created on its
+			// first login
+			user, err = ldapSvc.Login(ctx, dbService, idOrEmail, request.Password)
+			if err != nil {
+				rsp := models.NewFromError(err)
+				getTokenDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "LdapLogin")
+				ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithMessageAndCode(getTokenDiag, "Invalid User or Password", rsp.Code))
+
+				if existing := getOptionalUser(ctx, dbService, idOrEmail); existing != nil {
+					if diag := bruteforceguard.Get().Process(existing.ID, false, "Invalid Password"); diag.HasErrors() {
+						ctx.LogErrorf("Error processing brute force guard: %v", diag)
+					}
+				}
+				return
+			}
+			isDirectoryLogin = true
 		} else {
 			user, err = dbService.GetUser(ctx, idOrEmail)
 			if err != nil {
@@ -131,10 +202,20 @@ func GetTokenHandler() restapi.ControllerHandler {
 			}
 		}
 
+		if request.ApiKey == "" {
+			audit.SetActor(r.Context(), audit.ActorTypeUser, user.Username)
+		}
+
+		if user.Disabled {
+			getTokenDiag.AddError(strconv.Itoa(http.StatusUnauthorized), "User is disabled", "Disabled")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithMessageAndCode(getTokenDiag, "User is disabled", http.StatusUnauthorized))
+			return
+		}
+
 		bruteForceSvc := bruteforceguard.Get()
 
 		passwdSvc := password.Get()
-		if request.ApiKey == "" {
+		if request.ApiKey == "" && !isDirectoryLogin {
 			if err := passwdSvc.Compare(request.Password, user.ID, user.Password); err != nil {
 				rsp := models.NewFromError(err)
 				getTokenDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "Compare")
@@ -147,24 +228,54 @@ func GetTokenHandler() restapi.ControllerHandler {
 			}
 		}
 
-		userRoles := make([]string, 0)
-		for _, userRole := range user.Roles {
-			userRoles = append(userRoles, userRole.Name)
+		authenticationMethod := constants.SESSION_AUTHENTICATION_PASSWORD
+		if isDirectoryLogin {
+			authenticationMethod = constants.LDAP_IDENTITY_PROVIDER
 		}
-		// Use effective claims (direct + role-inherited, deduplicated) so the JWT
-		// reflects the user's full permission set.
-		userClaims := mappers.ComputeEffectiveClaimIDs(*user)
 
-		claims := map[string]interface{}{
-			"email":    user.Email,
-			"username": user.Name,
-			"uid":      user.ID,
-			"roles":    userRoles,
-			"claims":   userClaims,
+		// users with a second factor only get a challenge here, the token is
+		// issued once the code is verified
+		if mfaSvc := mfa.Get(); request.ApiKey == "" && mfaSvc.IsRequired(ctx, dbService, user) {
+			challenge, err := mfaSvc.NewChallenge(user, authenticationMethod)
+			if err != nil {
+				rsp := models.NewFromError(err)
+				getTokenDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "NewMfaChallenge")
+				ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(getTokenDiag, rsp.Code))
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(models.LoginResponse{
+				Email:                 user.Email,
+				MfaRequired:           true,
+				MfaToken:              challenge.Token,
+				MfaExpiresAt:          challenge.ExpiresAt.Unix(),
+				MfaEnrollmentRequired: challenge.EnrollmentRequired,
+			})
+			ctx.LogInfof("User %s requires multi-factor authentication", user.Email)
+			return
 		}
+
+		claims := getUserTokenClaims(user)
 		if apiKeyId != "" {
 			claims["api_key_id"] = apiKeyId
 		}
+
+		// users get a session they can refresh, api keys can be exchanged
+		// again instead
+		var session *dbmodels.UserSession
+		var refreshToken string
+		if request.ApiKey == "" {
+			session, refreshToken, err = newUserSession(ctx, dbService, user, r, authenticationMethod)
+			if err != nil {
+				rsp := models.NewFromError(err)
+				getTokenDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "CreateUserSession")
+				ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(getTokenDiag, rsp.Code))
+				return
+			}
+			claims["sid"] = session.ID
+		}
+
 		tokenSvc := jwt.Get()
 		tokenStr, err := tokenSvc.Sign(claims)
 		if err != nil {
@@ -198,6 +309,7 @@ func GetTokenHandler() restapi.ControllerHandler {
 			Email:     responseEmail,
 			ExpiresAt: int64(token.Claims["exp"].(float64)),
 		}
+		setSessionResponse(&response, session, refreshToken)
 
 		if request.ApiKey == "" {
 			if diag := bruteForceSvc.Process(user.ID, true, "Success"); diag.HasErrors() {
@@ -269,3 +381,398 @@ func ValidateTokenHandler() restapi.ControllerHandler {
 		ctx.LogInfof("Token for user %s is valid", email)
 	}
 }
+
+// @Summary		Refreshes a token
+// @Description	This endpoint exchanges a refresh token for a new token and refresh token, the refresh token can only be used once
+// @Tags			Authorization
+// @Produce		json
+// @Param			refreshRequest	body		models.RefreshTokenRequest	true	"Body"
+// @Success		200				{object}	models.LoginResponse
+// @Failure		400				{object}	models.ApiErrorDiagnosticsResponse
+// @Failure		401				{object}	models.ApiErrorDiagnosticsResponse
+// @Router			/v1/auth/token/refresh [post]
+func RefreshTokenHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		refreshTokenDiag := errors.NewDiagnostics("/auth/token/refresh")
+		var request models.RefreshTokenRequest
+		if err := http_helper.MapRequestBody(r, &request); err != nil {
+			refreshTokenDiag.AddError(strconv.Itoa(http.StatusBadRequest), "Invalid request body: "+err.Error(), "MapRequestBody")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(refreshTokenDiag, http.StatusBadRequest))
+			return
+		}
+		if err := request.Validate(); err != nil {
+			refreshTokenDiag.AddError(strconv.Itoa(http.StatusBadRequest), "Invalid request body: "+err.Error(), "Validate")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(refreshTokenDiag, http.StatusBadRequest))
+			return
+		}
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			refreshTokenDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "ServiceProvider")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(refreshTokenDiag, rsp.Code))
+			return
+		}
+
+		sessionId, _, found := strings.Cut(request.RefreshToken, ".")
+		if !found || sessionId == "" {
+			refreshTokenDiag.AddError(strconv.Itoa(http.StatusUnauthorized), "Invalid refresh token", "RefreshToken")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(refreshTokenDiag, http.StatusUnauthorized))
+			return
+		}
+
+		rotatedRefreshToken, err := newRefreshToken(sessionId)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			refreshTokenDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "GenerateRefreshToken")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(refreshTokenDiag, rsp.Code))
+			return
+		}
+
+		session, err := dbService.RefreshUserSession(ctx, sessionId, request.RefreshToken, rotatedRefreshToken, restapi.GetClientIp(r))
+		if err != nil {
+			rsp := models.NewFromError(err)
+			refreshTokenDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "RefreshUserSession")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithMessageAndCode(refreshTokenDiag, "Invalid refresh token", rsp.Code))
+			return
+		}
+
+		user, err := dbService.GetUser(ctx, session.UserID)
+		if err != nil || user == nil {
+			audit.SetActor(r.Context(), audit.ActorTypeUser, session.UserID)
+			refreshTokenDiag.AddError(strconv.Itoa(http.StatusUnauthorized), "User not found", "GetUser")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(refreshTokenDiag, http.StatusUnauthorized))
+			return
+		}
+		audit.SetActor(r.Context(), audit.ActorTypeUser, user.Username)
+		if user.Disabled || user.Blocked {
+			reason := constants.SESSION_REVOKED_USER_DISABLED
+			if user.Blocked {
+				reason = constants.SESSION_REVOKED_USER_BLOCKED
+			}
+			if err := dbService.RevokeUserSession(ctx, session.ID, reason); err != nil {
+				ctx.LogWarnf("Error revoking the session %v: %v", session.ID, err)
+			}
+			refreshTokenDiag.AddError(strconv.Itoa(http.StatusUnauthorized), "User is disabled", "Disabled")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithMessageAndCode(refreshTokenDiag, "User is disabled", http.StatusUnauthorized))
+			return
+		}
+
+		claims := getUserTokenClaims(user)
+		claims["sid"] = session.ID
+		tokenSvc := jwt.Get()
+		tokenStr, err := tokenSvc.Sign(claims)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			refreshTokenDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "Sign")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(refreshTokenDiag, rsp.Code))
+			return
+		}
+		token, err := tokenSvc.Parse(tokenStr)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			refreshTokenDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "Parse")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(refreshTokenDiag, rsp.Code))
+			return
+		}
+
+		response := models.LoginResponse{
+			Token:     tokenStr,
+			Email:     user.Email,
+			ExpiresAt: int64(token.Claims["exp"].(float64)),
+		}
+		setSessionResponse(&response, session, rotatedRefreshToken)
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("Session %s of user %s refreshed", session.ID, user.Email)
+	}
+}
+
+// @Summary		Logs out
+// @Description	This endpoint revokes the token of the request and its session
+// @Tags			Authorization
+// @Produce		json
+// @Success		202
+// @Failure		400	{object}	models.ApiErrorResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		BearerAuth
+// @Router			/v1/auth/logout [post]
+func LogoutHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		authContext := ctx.GetAuthorizationContext()
+		if authContext == nil || (authContext.SessionId == "" && authContext.TokenId == "") {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("only the tokens issued by the service can be revoked"), http.StatusBadRequest))
+			return
+		}
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(err, http.StatusInternalServerError))
+			return
+		}
+
+		if authContext.SessionId != "" {
+			if err := dbService.RevokeUserSession(ctx, authContext.SessionId, constants.SESSION_REVOKED_LOGOUT); err != nil {
+				ReturnApiError(ctx, w, models.NewFromError(err))
+				return
+			}
+		}
+		if authContext.TokenId != "" {
+			revokedToken := dbmodels.RevokedToken{
+				ID:        authContext.TokenId,
+				ExpiresAt: authContext.TokenExpiresAt.UTC().Format(time.RFC3339Nano),
+			}
+			if authContext.User != nil {
+				revokedToken.UserID = authContext.User.ID
+			}
+			if err := dbService.RevokeToken(ctx, revokedToken); err != nil {
+				ReturnApiError(ctx, w, models.NewFromError(err))
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		ctx.LogInfof("Token %s logged out", authContext.TokenId)
+	}
+}
+
+// @Summary		Starts a single sign-on login
+// @Description	This endpoint redirects the user to the identity provider using the OIDC authorization code flow with PKCE
+// @Tags			Authorization
+// @Produce		json
+// @Success		302
+// @Failure		400	{object}	models.ApiErrorResponse
+// @Failure		404	{object}	models.ApiErrorResponse
+// @Router			/v1/auth/oidc/login [get]
+func OidcLoginHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		oidcSvc := oidc.Get()
+		if oidcSvc == nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("single sign-on is not configured"), http.StatusNotFound))
+			return
+		}
+
+		loginUrl, err := oidcSvc.NewLoginUrl()
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		http.Redirect(w, r, loginUrl, http.StatusFound)
+		ctx.LogInfof("Redirecting to the identity provider")
+	}
+}
+
+// @Summary		Completes a single sign-on login
+// @Description	This endpoint exchanges the authorization code of the identity provider, provisions the user and returns a token. When OIDC_POST_LOGIN_REDIRECT_URL is set the user is redirected to it with the token in the url fragment
+// @Tags			Authorization
+// @Produce		json
+// @Param			code	query		string	true	"Authorization code"
+// @Param			state	query		string	true	"State"
+// @Success		200		{object}	models.LoginResponse
+// @Failure		400		{object}	models.ApiErrorResponse
+// @Failure		401		{object}	models.ApiErrorResponse
+// @Router			/v1/auth/oidc/callback [get]
+func OidcCallbackHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		oidcSvc := oidc.Get()
+		if oidcSvc == nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("single sign-on is not configured"), http.StatusNotFound))
+			return
+		}
+
+		query := r.URL.Query()
+		if idpError := query.Get("error"); idpError != "" {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.Newf("the identity provider returned %v: %v", idpError, query.Get("error_description")), http.StatusUnauthorized))
+			return
+		}
+
+		identity, err := oidcSvc.CompleteLogin(query.Get("code"), query.Get("state"))
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(err, http.StatusInternalServerError))
+			return
+		}
+
+		actor := identity.Email
+		if actor == "" {
+			actor = identity.Subject
+		}
+		audit.SetActor(r.Context(), audit.ActorTypeUser, actor)
+		user, err := oidcSvc.ProvisionUser(ctx, dbService, identity)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		audit.SetActor(r.Context(), audit.ActorTypeUser, user.Username)
+		session, refreshToken, err := newUserSession(ctx, dbService, user, r, constants.OIDC_IDENTITY_PROVIDER)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+		claims := getUserTokenClaims(user)
+		claims["sid"] = session.ID
+
+		tokenSvc := jwt.Get()
+		tokenStr, err := tokenSvc.Sign(claims)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(err, http.StatusInternalServerError))
+			return
+		}
+		token, err := tokenSvc.Parse(tokenStr)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(err, http.StatusInternalServerError))
+			return
+		}
+
+		response := models.LoginResponse{
+			Token:     tokenStr,
+			Email:     user.Email,
+			ExpiresAt: int64(token.Claims["exp"].(float64)),
+		}
+		setSessionResponse(&response, session, refreshToken)
+
+		if redirectUrl := oidcSvc.Options.PostLoginRedirectUrl; redirectUrl != "" {
+			fragment := url.Values{}
+			fragment.Set("token", response.Token)
+			fragment.Set("email", response.Email)
+			fragment.Set("expires_at", strconv.FormatInt(response.ExpiresAt, 10))
+			fragment.Set("refresh_token", response.RefreshToken)
+			fragment.Set("refresh_expires_at", strconv.FormatInt(response.RefreshExpiresAt, 10))
+			http.Redirect(w, r, redirectUrl+"#"+fragment.Encode(), http.StatusFound)
+			ctx.LogInfof("User %s logged in with the identity provider", user.Email)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("User %s logged in with the identity provider", user.Email)
+	}
+}
+
+// newUserSession starts a session for the user, the refresh token of the
+// session is only returned here
+func newUserSession(ctx basecontext.ApiContext, dbService *data.JsonDatabase, user *dbmodels.User, r *http.Request, authenticationMethod string) (*dbmodels.UserSession, string, error) {
+	sessionId := helpers.GenerateId()
+	refreshToken, err := newRefreshToken(sessionId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	session, err := dbService.CreateUserSession(ctx, dbmodels.UserSession{
+		ID:                   sessionId,
+		UserID:               user.ID,
+		AuthenticationMethod: authenticationMethod,
+		IpAddress:            restapi.GetClientIp(r),
+		UserAgent:            r.UserAgent(),
+		ExpiresAt:            time.Now().UTC().Add(jwt.Get().Options.GetRefreshTokenDuration()).Format(time.RFC3339Nano),
+	}, refreshToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return session, refreshToken, nil
+}
+
+// newUserLoginResponse starts a session for the user and issues its token
+func newUserLoginResponse(ctx basecontext.ApiContext, dbService *data.JsonDatabase, user *dbmodels.User, r *http.Request, authenticationMethod string) (*models.LoginResponse, error) {
+	session, refreshToken, err := newUserSession(ctx, dbService, user, r, authenticationMethod)
+	if err != nil {
+		return nil, err
+	}
+	claims := getUserTokenClaims(user)
+	claims["sid"] = session.ID
+
+	tokenSvc := jwt.Get()
+	tokenStr, err := tokenSvc.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+	token, err := tokenSvc.Parse(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+
+	response := models.LoginResponse{
+		Token:     tokenStr,
+		Email:     user.Email,
+		ExpiresAt: int64(token.Claims["exp"].(float64)),
+	}
+	setSessionResponse(&response, session, refreshToken)
+
+	return &response, nil
+}
+
+// newRefreshToken returns a refresh token for the session, the session id is
+// part of the token so it can be found without knowing the user
+func newRefreshToken(sessionId string) (string, error) {
+	secret, err := security.GenerateCryptoRandomString(64)
+	if err != nil {
+		return "", err
+	}
+
+	return sessionId + "." + secret, nil
+}
+
+func setSessionResponse(response *models.LoginResponse, session *dbmodels.UserSession, refreshToken string) {
+	if session == nil {
+		return
+	}
+
+	response.SessionId = session.ID
+	response.RefreshToken = refreshToken
+	if expiresAt, err := time.Parse(time.RFC3339Nano, session.ExpiresAt); err == nil {
+		response.RefreshExpiresAt = expiresAt.Unix()
+	}
+}
+
+// getOptionalUser returns the user or nil if it does not exist
+func getOptionalUser(ctx basecontext.ApiContext, dbService *data.JsonDatabase, idOrEmail string) *dbmodels.User {
+	user, err := dbService.GetUser(ctx, idOrEmail)
+	if err != nil {
+		return nil
+	}
+
+	return user
+}
+
+// getUserTokenClaims returns the claims of the tokens we issue for a user
+func getUserTokenClaims(user *dbmodels.User) map[string]interface{} {
+	userRoles := make([]string, 0)
+	for _, userRole := range user.Roles {
+		userRoles = append(userRoles, userRole.Name)
+	}
+	// Use effective claims (direct + role-inherited, deduplicated) so the JWT
+	// reflects the user's full permission set.
+	userClaims := mappers.ComputeEffectiveClaimIDs(*user)
+
+	return map[string]interface{}{
+		"email":    user.Email,
+		"username": user.Name,
+		"uid":      user.ID,
+		"roles":    userRoles,
+		"claims":   userClaims,
+	}
+}
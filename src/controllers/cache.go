@@ -6,16 +6,21 @@ import (
 	"strconv"
 
 	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/catalog"
 	"github.com/Parallels/prl-devops-service/catalog/cacheservice"
+	catalog_models "github.com/Parallels/prl-devops-service/catalog/models"
+	"github.com/Parallels/prl-devops-service/catalog/transferwindow"
 	"github.com/Parallels/prl-devops-service/config"
 	"github.com/Parallels/prl-devops-service/constants"
 	"github.com/Parallels/prl-devops-service/errors"
 	"github.com/Parallels/prl-devops-service/helpers"
+	"github.com/Parallels/prl-devops-service/jobs"
 	"github.com/Parallels/prl-devops-service/mappers"
 	"github.com/Parallels/prl-devops-service/models"
 	"github.com/Parallels/prl-devops-service/restapi"
 	"github.com/Parallels/prl-devops-service/serviceprovider"
 	diskspace "github.com/Parallels/prl-devops-service/serviceprovider/diskSpace"
+	"github.com/cjlapao/common-go/helper/http_helper"
 	"github.com/gorilla/mux"
 )
 
@@ -64,6 +69,62 @@ func registerCacheHandlers(ctx basecontext.ApiContext, version string) {
 		WithHandler(GetCatalogCacheHandler()).
 		Register()
 
+	restapi.NewController().
+		WithMethod(restapi.POST).
+		WithVersion(version).
+		WithPath("/cache").
+		WithRequiredClaim(constants.CREATE_CACHE_ITEM_CLAIM).
+		WithHandler(CreateCatalogCacheItemHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/cache/cleanup/preview").
+		WithRequiredClaim(constants.LIST_CACHE_CLAIM).
+		WithHandler(GetCatalogCacheCleanupPreviewHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/cache/transfers").
+		WithRequiredClaim(constants.LIST_CACHE_CLAIM).
+		WithHandler(GetCatalogCacheQueuedTransfersHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/cache/scrub").
+		WithRequiredClaim(constants.LIST_CACHE_CLAIM).
+		WithHandler(GetCatalogCacheScrubHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.POST).
+		WithVersion(version).
+		WithPath("/cache/scrub").
+		WithRequiredClaim(constants.UPDATE_CACHE_ITEM_CLAIM).
+		WithHandler(CreateCatalogCacheScrubHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.PUT).
+		WithVersion(version).
+		WithPath("/cache/{catalogId}/{version}/pin").
+		WithRequiredClaim(constants.UPDATE_CACHE_ITEM_CLAIM).
+		WithHandler(PinCatalogCacheItemHandler(true)).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.PUT).
+		WithVersion(version).
+		WithPath("/cache/{catalogId}/{version}/unpin").
+		WithRequiredClaim(constants.UPDATE_CACHE_ITEM_CLAIM).
+		WithHandler(PinCatalogCacheItemHandler(false)).
+		Register()
+
 	restapi.NewController().
 		WithMethod(restapi.DELETE).
 		WithVersion(version).
@@ -172,6 +233,82 @@ func GetCatalogCacheHandler() restapi.ControllerHandler {
 	}
 }
 
+// @Summary		Caches a catalog manifest
+// @Description	This endpoint caches a catalog manifest in the background without pulling a machine, if the manifest is already cached it returns straight away
+// @Tags			Catalogs
+// @Produce		json
+// @Param			cacheRequest	body		catalog_models.CacheCatalogManifestRequest	true	"Cache request"
+// @Success		200				{object}	catalog_models.CacheCatalogManifestResponse
+// @Success		202				{object}	catalog_models.CacheCatalogManifestResponse
+// @Failure		400				{object}	models.ApiErrorResponse
+// @Failure		401				{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/cache [post]
+func CreateCatalogCacheItemHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		userContext := ctx.GetUser()
+		if userContext == nil {
+			ReturnApiError(ctx, w, models.ApiErrorResponse{Code: http.StatusUnauthorized, Message: "User not found"})
+			return
+		}
+
+		var request catalog_models.CacheCatalogManifestRequest
+		if err := http_helper.MapRequestBody(r, &request); err != nil {
+			ReturnApiError(ctx, w, models.ApiErrorResponse{
+				Message: "Invalid request body: " + err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+		if err := request.Validate(); err != nil {
+			ReturnApiError(ctx, w, models.ApiErrorResponse{
+				Message: "Invalid request body: " + err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+
+		manifestSvc := catalog.NewManifestService(ctx)
+		response, err := manifestSvc.GetCacheStatus(&request)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		if response.Cached {
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(response)
+			ctx.LogInfof("Manifest %v version %v is already cached", response.CatalogId, response.Version)
+			return
+		}
+
+		jobManager := jobs.Get(ctx)
+		if jobManager == nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("Job Manager is not available"), http.StatusInternalServerError))
+			return
+		}
+
+		job, err := jobManager.CreateNewJob(userContext.ID, "catalog", "cache", "Initializing catalog cache")
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(err, http.StatusInternalServerError))
+			return
+		}
+
+		asyncCtx := basecontext.NewRootBaseContext()
+		go catalog.NewManifestService(asyncCtx).AsyncCache(job.ID, &request)
+
+		response.JobId = job.ID
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("Caching manifest %v version %v, job ID: %v", response.CatalogId, response.Version, job.ID)
+	}
+}
+
 // @Summary		Deletes all catalog cache
 // @Description	This endpoint returns all the remote catalog cache if any
 // @Tags			Catalogs
@@ -315,3 +452,193 @@ func DeleteCatalogCacheItemVersionHandler() restapi.ControllerHandler {
 		ctx.LogInfof("Manifests cached item %v removed", len(catalogId))
 	}
 }
+
+// @Summary		Previews the next catalog cache cleanup
+// @Description	This endpoint returns the cache items the next cleanup would remove using the configured eviction policy, nothing is removed
+// @Tags			Catalogs
+// @Produce		json
+// @Param			size	query		int	false	"Size in MB of the item to make space for"
+// @Success		200		{object}	catalog_models.CacheCleanupPreview
+// @Failure		400		{object}	models.ApiErrorResponse
+// @Failure		401		{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/cache/cleanup/preview [get]
+func GetCatalogCacheCleanupPreviewHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		size := int64(0)
+		if value := r.URL.Query().Get("size"); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || parsed < 0 {
+				ReturnApiError(ctx, w, models.ApiErrorResponse{
+					Message: "Invalid size, it must be a positive number of megabytes",
+					Code:    http.StatusBadRequest,
+				})
+				return
+			}
+			size = parsed
+		}
+
+		catalogCacheSvc, err := cacheservice.NewCacheService(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		response, err := catalogCacheSvc.PreviewCleanup(size)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("Cache cleanup preview would remove %v items", len(response.Items))
+	}
+}
+
+// @Summary		Gets the queued catalog transfers
+// @Description	This endpoint returns the background transfers waiting for a transfer window to open
+// @Tags			Catalogs
+// @Produce		json
+// @Success		200	{object}	[]transferwindow.QueuedTransfer
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/cache/transfers [get]
+func GetCatalogCacheQueuedTransfersHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		response := transferwindow.Get(ctx).QueuedTransfers()
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("Returned %v queued catalog transfers", len(response))
+	}
+}
+
+// @Summary		Pins or unpins a catalog cache item
+// @Description	This endpoint pins or unpins a cached version, pinned items are never removed by the cache cleanup
+// @Tags			Catalogs
+// @Produce		json
+// @Param			catalogId	path		string	true	"Catalog ID"
+// @Param			version		path		string	true	"Version"
+// @Success		200			{object}	catalog_models.VirtualMachineCatalogManifest
+// @Failure		400			{object}	models.ApiErrorResponse
+// @Failure		401			{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/cache/{catalogId}/{version}/pin [put]
+// @Router			/v1/cache/{catalogId}/{version}/unpin [put]
+func PinCatalogCacheItemHandler(pinned bool) restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		vars := mux.Vars(r)
+		catalogId := vars["catalogId"]
+		version := vars["version"]
+
+		catalogCacheSvc, err := cacheservice.NewCacheService(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		response, err := catalogCacheSvc.SetCacheItemPinned(catalogId, version, pinned)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("Cache item %v version %v pinned: %v", catalogId, version, pinned)
+	}
+}
+
+// @Summary		Gets the last catalog cache scrub report
+// @Description	This endpoint returns the result of the last catalog cache integrity verification
+// @Tags			Catalogs
+// @Produce		json
+// @Success		200	{object}	catalog_models.CacheScrubReport
+// @Failure		404	{object}	models.ApiErrorResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/cache/scrub [get]
+func GetCatalogCacheScrubHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		report := catalog.GetLastCacheScrubReport()
+		if report == nil {
+			ReturnApiError(ctx, w, models.ApiErrorResponse{
+				Message: "The catalog cache was not scrubbed yet",
+				Code:    http.StatusNotFound,
+			})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(report)
+		ctx.LogInfof("Returned last catalog cache scrub report")
+	}
+}
+
+// @Summary		Starts a catalog cache scrub
+// @Description	This endpoint verifies the checksums of every cached item in the background, corrupted items are quarantined and downloaded again
+// @Tags			Catalogs
+// @Produce		json
+// @Success		202	{object}	models.JobResponse
+// @Failure		409	{object}	models.ApiErrorResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/cache/scrub [post]
+func CreateCatalogCacheScrubHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		userContext := ctx.GetUser()
+		if userContext == nil {
+			ReturnApiError(ctx, w, models.ApiErrorResponse{Code: http.StatusUnauthorized, Message: "User not found"})
+			return
+		}
+
+		if catalog.IsCacheScrubRunning() {
+			ReturnApiError(ctx, w, models.NewFromError(catalog.ErrCacheScrubRunning))
+			return
+		}
+
+		jobManager := jobs.Get(ctx)
+		if jobManager == nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("Job Manager is not available"), http.StatusInternalServerError))
+			return
+		}
+
+		job, err := jobManager.CreateNewJob(userContext.ID, "catalog", "cache_scrub", "Verifying catalog cache")
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(err, http.StatusInternalServerError))
+			return
+		}
+
+		asyncCtx := basecontext.NewRootBaseContext()
+		go catalog.NewManifestService(asyncCtx).AsyncScrubCache(job.ID)
+
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(mappers.MapJobToApiJob(*job))
+		ctx.LogInfof("Started catalog cache scrub, job ID: %v", job.ID)
+	}
+}
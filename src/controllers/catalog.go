@@ -14,6 +14,7 @@ import (
 	catalog_models "github.com/Parallels/prl-devops-service/catalog/models"
 	"github.com/Parallels/prl-devops-service/config"
 	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
 	data_models "github.com/Parallels/prl-devops-service/data/models"
 	"github.com/Parallels/prl-devops-service/helpers"
 	"github.com/Parallels/prl-devops-service/jobs"
@@ -38,6 +39,24 @@ func registerCatalogManifestHandlers(ctx basecontext.ApiContext, version string)
 		WithHandler(GetCatalogManifestsHandler()).
 		Register()
 
+	// The search is under _search so it cannot shadow a catalog with the id
+	// search, and it needs to be registered before the catalog id route
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/catalog/_search").
+		WithRequiredClaim(constants.LIST_CATALOG_MANIFEST_CLAIM).
+		WithHandler(SearchCatalogManifestsHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/catalog/_search/facets").
+		WithRequiredClaim(constants.LIST_CATALOG_MANIFEST_CLAIM).
+		WithHandler(GetCatalogSearchFacetsHandler()).
+		Register()
+
 	restapi.NewController().
 		WithMethod(restapi.GET).
 		WithVersion(version).
@@ -243,6 +262,12 @@ func registerCatalogManifestHandlers(ctx basecontext.ApiContext, version string)
 // @Description	This endpoint returns all the remote catalogs
 // @Tags			Catalogs
 // @Produce		json
+// @Param			filter		query		string	false	"Filter expression, e.g. state = running and (name ~ ^build or cpu >= 4)"
+// @Param			sort		query		string	false	"Comma separated properties, prefix with - to sort descending"
+// @Param			page		query		int		false	"Page, starts at 1"
+// @Param			page_size	query		int		false	"Page size"
+// @Param			cursor		query		string	false	"Cursor token from the Link header"
+// @Param			fields		query		string	false	"Comma separated fields to return"
 // @Success		200	{object}	[]map[string][]models.CatalogManifest
 // @Failure		400	{object}	models.ApiErrorResponse
 // @Failure		401	{object}	models.OAuthErrorResponse
@@ -266,72 +291,31 @@ func GetCatalogManifestsHandler() restapi.ControllerHandler {
 			return
 		}
 
-		if len(manifestsDto) == 0 {
-			w.WriteHeader(http.StatusOK)
-			response := make([]models.CatalogManifest, 0)
-			defer r.Body.Close()
-			_ = json.NewEncoder(w).Encode(response)
-			ctx.LogInfof("Manifests returned: %v", len(response))
-			return
-		}
-
-		result := make([]map[string][]models.CatalogManifest, 0)
-		for _, manifest := range manifestsDto {
-
-			var resultManifest map[string][]models.CatalogManifest
-			for _, r := range result {
-				if _, ok := r[manifest.CatalogId]; ok {
-					resultManifest = r
-					break
-				}
-			}
+		manifests := mappers.DtoCatalogManifestsToApi(filterCatalogManifestsByApiKeyScope(ctx, manifestsDto))
 
-			if resultManifest == nil {
-				resultManifest := make(map[string][]models.CatalogManifest)
-				resultManifest[manifest.CatalogId] = append(resultManifest[manifest.CatalogId], mappers.DtoCatalogManifestToApi(manifest))
-				result = append(result, resultManifest)
-			} else {
-				if _, ok := resultManifest[manifest.CatalogId]; !ok {
-					resultManifest[manifest.CatalogId] = []models.CatalogManifest{}
-				}
-				resultManifest[manifest.CatalogId] = append(resultManifest[manifest.CatalogId], mappers.DtoCatalogManifestToApi(manifest))
+		// obfuscate provider credentials for external calls
+		if r.Header.Get(constants.INTERNAL_API_CLIENT) != "true" && config.Get().EnableCredentialsObfuscation() {
+			for i := range manifests {
+				manifests[i].Provider = obfuscateCatalogProvider(manifests[i].Provider)
 			}
 		}
 
-		// responseManifests := mappers.DtoCatalogManifestsToApi(manifestsDto)
+		page, items, ok := getListPage(ctx, w, r, manifests)
+		if !ok {
+			return
+		}
 
-		// obfuscate provider credentials for external calls
-		cfg := config.Get()
-		enableObfuscation := cfg.EnableCredentialsObfuscation()
-		internalClientHeader := r.Header.Get(constants.INTERNAL_API_CLIENT)
-		if internalClientHeader != "true" && enableObfuscation {
-			for ru, resultItem := range result {
-				for ri, v := range resultItem {
-					for mk, manifest := range v {
-						// obfuscate provider credentials for external calls
-						if manifest.Provider != nil {
-							newProvider := &models.RemoteVirtualMachineProvider{}
-							newProvider.Type = manifest.Provider.Type
-							newProvider.Host = manifest.Provider.Host
-							newProvider.Port = manifest.Provider.Port
-							newProvider.Username = helpers.ObfuscateString(manifest.Provider.Username)
-							newProvider.Password = helpers.ObfuscateString(manifest.Provider.Password)
-							newProvider.ApiKey = helpers.ObfuscateString(manifest.Provider.ApiKey)
-							if manifest.Provider.Meta != nil {
-								newProvider.Meta = make(map[string]string)
-
-								for k, v := range manifest.Provider.Meta {
-									newProvider.Meta[k] = helpers.ObfuscateString(v)
-								}
-							}
-							manifest.Provider = newProvider
-							v[mk] = manifest
-						}
-					}
-					resultItem[ri] = v
-				}
-				result[ru] = resultItem
+		// the page is grouped by catalog id keeping the order of the manifests
+		result := make([]map[string][]interface{}, 0)
+		groups := make(map[string]map[string][]interface{})
+		for i, manifest := range page {
+			group, exists := groups[manifest.CatalogId]
+			if !exists {
+				group = make(map[string][]interface{})
+				groups[manifest.CatalogId] = group
+				result = append(result, group)
 			}
+			group[manifest.CatalogId] = append(group[manifest.CatalogId], items[i])
 		}
 
 		w.WriteHeader(http.StatusOK)
@@ -686,6 +670,7 @@ func TaintCatalogManifestVersionHandler() restapi.ControllerHandler {
 		}
 
 		resultData := mappers.DtoCatalogManifestToApi(*result)
+		auditChange(r, catalogId, mappers.DtoCatalogManifestToApi(*manifest), resultData)
 		// obfuscate provider credentials for external calls
 		cfg := config.Get()
 		enableObfuscation := cfg.EnableCredentialsObfuscation()
@@ -773,6 +758,7 @@ func UnTaintCatalogManifestVersionHandler() restapi.ControllerHandler {
 		}
 
 		resultData := mappers.DtoCatalogManifestToApi(*result)
+		auditChange(r, catalogId, mappers.DtoCatalogManifestToApi(*manifest), resultData)
 		// obfuscate provider credentials for external calls
 		cfg := config.Get()
 		enableObfuscation := cfg.EnableCredentialsObfuscation()
@@ -852,6 +838,7 @@ func RevokeCatalogManifestVersionHandler() restapi.ControllerHandler {
 		}
 
 		resultData := mappers.DtoCatalogManifestToApi(*result)
+		auditChange(r, catalogId, mappers.DtoCatalogManifestToApi(*manifest), resultData)
 		// obfuscate provider credentials for external calls
 		cfg := config.Get()
 		enableObfuscation := cfg.EnableCredentialsObfuscation()
@@ -1684,6 +1671,7 @@ func DeleteCatalogManifestHandler() restapi.ControllerHandler {
 
 		vars := mux.Vars(r)
 		catalogId := vars["catalogId"]
+		before := getAuditCatalogManifests(ctx, dbService, catalogId, "", "")
 
 		cleanRemote := http_helper.GetHttpRequestStrValue(r, constants.DELETE_REMOTE_MANIFEST_QUERY)
 		// by default we will clean the remote manifest
@@ -1702,6 +1690,7 @@ func DeleteCatalogManifestHandler() restapi.ControllerHandler {
 			}
 		}
 
+		auditChange(r, catalogId, before, nil)
 		err = dbService.DeleteCatalogManifest(ctx, catalogId)
 		if err != nil {
 			errorDeletingRecord = err
@@ -1753,6 +1742,7 @@ func DeleteCatalogManifestVersionHandler() restapi.ControllerHandler {
 		vars := mux.Vars(r)
 		catalogId := vars["catalogId"]
 		version := vars["version"]
+		before := getAuditCatalogManifests(ctx, dbService, catalogId, version, "")
 
 		cleanRemote := http_helper.GetHttpRequestStrValue(r, constants.DELETE_REMOTE_MANIFEST_QUERY)
 		// by default we will clean the remote manifest
@@ -1771,6 +1761,7 @@ func DeleteCatalogManifestVersionHandler() restapi.ControllerHandler {
 			}
 		}
 
+		auditChange(r, catalogId, before, nil)
 		err = dbService.DeleteCatalogManifestVersion(ctx, catalogId, version)
 		if err != nil {
 			errorDeletingRecord = err
@@ -1824,6 +1815,7 @@ func DeleteCatalogManifestVersionArchitectureHandler() restapi.ControllerHandler
 		catalogId := vars["catalogId"]
 		version := vars["version"]
 		architecture := vars["architecture"]
+		before := getAuditCatalogManifests(ctx, dbService, catalogId, version, architecture)
 
 		cleanRemote := http_helper.GetHttpRequestStrValue(r, constants.DELETE_REMOTE_MANIFEST_QUERY)
 		// by default we will clean the remote manifest
@@ -1842,6 +1834,7 @@ func DeleteCatalogManifestVersionArchitectureHandler() restapi.ControllerHandler
 			}
 		}
 
+		auditChange(r, catalogId, before, nil)
 		err = dbService.DeleteCatalogManifestVersionArch(ctx, catalogId, version, architecture)
 		if err != nil {
 			errorDeletingRecord = err
@@ -2597,3 +2590,28 @@ func UpdateCatalogManifestMetadataHandler() restapi.ControllerHandler {
 		ctx.LogInfof("Manifest Metadata Updated: %v", updatedManifest.ID)
 	}
 }
+
+// getAuditCatalogManifests returns the manifests a delete is about to remove so
+// the audit log keeps what was deleted, an empty version or architecture
+// matches all of them
+func getAuditCatalogManifests(ctx basecontext.ApiContext, dbService *data.JsonDatabase, catalogId string, version string, architecture string) []models.CatalogManifest {
+	var manifests []data_models.CatalogManifest
+	var err error
+	switch {
+	case version == "":
+		manifests, err = dbService.GetCatalogManifestsByCatalogId(ctx, catalogId)
+	case architecture == "":
+		manifests, err = dbService.GetCatalogManifestsByCatalogIdAndVersion(ctx, catalogId, version)
+	default:
+		var manifest *data_models.CatalogManifest
+		manifest, err = dbService.GetCatalogManifestsByCatalogIdVersionAndArch(ctx, catalogId, version, architecture)
+		if manifest != nil {
+			manifests = []data_models.CatalogManifest{*manifest}
+		}
+	}
+	if err != nil || len(manifests) == 0 {
+		return nil
+	}
+
+	return mappers.DtoCatalogManifestsToApi(manifests)
+}
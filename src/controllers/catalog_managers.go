@@ -27,6 +27,7 @@ import (
 	"github.com/Parallels/prl-devops-service/mappers"
 	"github.com/Parallels/prl-devops-service/models"
 	"github.com/Parallels/prl-devops-service/restapi"
+	"github.com/Parallels/prl-devops-service/secrets"
 	"github.com/Parallels/prl-devops-service/security"
 	"github.com/Parallels/prl-devops-service/serviceprovider"
 	"github.com/Parallels/prl-devops-service/serviceprovider/apiclient"
@@ -581,7 +582,12 @@ func CreateCatalogManagerHandler() restapi.ControllerHandler {
 			}
 		}
 
-		if err := validateCatalogManagerConnection(ctx, newMgr.URL, newMgr.Username, decryptCatalogManagerSecret(newMgr.Password), decryptCatalogManagerSecret(newMgr.ApiKey)); err != nil {
+		username, password, apiKey, err := resolveCatalogManagerCredentials(ctx, *newMgr)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(err, http.StatusBadRequest))
+			return
+		}
+		if err := validateCatalogManagerConnection(ctx, newMgr.URL, username, password, apiKey); err != nil {
 			ReturnApiError(ctx, w, models.NewFromErrorWithCode(err, http.StatusBadRequest))
 			return
 		}
@@ -672,7 +678,12 @@ func UpdateCatalogManagerHandler() restapi.ControllerHandler {
 			return
 		}
 
-		if err := validateCatalogManagerConnection(ctx, updatedMgr.URL, updatedMgr.Username, decryptCatalogManagerSecret(updatedMgr.Password), decryptCatalogManagerSecret(updatedMgr.ApiKey)); err != nil {
+		username, password, apiKey, err := resolveCatalogManagerCredentials(ctx, updatedMgr)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(err, http.StatusBadRequest))
+			return
+		}
+		if err := validateCatalogManagerConnection(ctx, updatedMgr.URL, username, password, apiKey); err != nil {
 			ReturnApiError(ctx, w, models.NewFromErrorWithCode(err, http.StatusBadRequest))
 			return
 		}
@@ -801,7 +812,7 @@ func AsyncPushCatalogManifestToCatalogManagerHandler() restapi.ControllerHandler
 
 		// Build the host= part from the catalog manager credentials and merge with the
 		// user-provided storage connection (stripping any host= they may have included).
-		hostPart, err := buildCatalogManagerConnection(*mgr)
+		hostPart, err := buildCatalogManagerConnection(ctx, *mgr)
 		if err != nil {
 			ReturnApiError(ctx, w, models.NewFromErrorWithCode(err, http.StatusBadRequest))
 			return
@@ -905,7 +916,7 @@ func PullCatalogManifestFromCatalogManagerHandler(async bool) restapi.Controller
 			return
 		}
 
-		connection, err := buildCatalogManagerConnection(*mgr)
+		connection, err := buildCatalogManagerConnection(ctx, *mgr)
 		if err != nil {
 			ReturnApiError(ctx, w, models.NewFromErrorWithCode(err, http.StatusBadRequest))
 			return
@@ -1038,41 +1049,64 @@ func decryptCatalogManagerSecret(value string) string {
 	return decrypted
 }
 
+// resolveCatalogManagerCredentials decrypts the catalog manager credentials and
+// resolves the secret references they may hold, like secret://name or env://VAR
+func resolveCatalogManagerCredentials(ctx basecontext.ApiContext, manager data_models.CatalogManager) (string, string, string, error) {
+	username, err := secrets.Resolve(ctx, manager.Username)
+	if err != nil {
+		return "", "", "", err
+	}
+	password, err := secrets.Resolve(ctx, decryptCatalogManagerSecret(manager.Password))
+	if err != nil {
+		return "", "", "", err
+	}
+	apiKey, err := secrets.Resolve(ctx, decryptCatalogManagerSecret(manager.ApiKey))
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return username, password, apiKey, nil
+}
+
 func getCatalogManagerAuthorizer(ctx basecontext.ApiContext, manager data_models.CatalogManager, targetUrl string) (*apiclient.HttpClientServiceAuthorizer, error) {
-	password := decryptCatalogManagerSecret(manager.Password)
-	apiKey := decryptCatalogManagerSecret(manager.ApiKey)
+	username, password, apiKey, err := resolveCatalogManagerCredentials(ctx, manager)
+	if err != nil {
+		return nil, err
+	}
 
 	client := apiclient.NewHttpClient(ctx)
 	if apiKey != "" {
 		client.AuthorizeWithApiKey(apiKey)
 	}
 
-	if manager.Username != "" && password != "" {
-		client.AuthorizeWithUsernameAndPassword(manager.Username, password)
+	if username != "" && password != "" {
+		client.AuthorizeWithUsernameAndPassword(username, password)
 	}
 
-	if apiKey == "" && (manager.Username == "" || password == "") {
+	if apiKey == "" && (username == "" || password == "") {
 		return nil, nil
 	}
 
 	return client.Authorize(ctx, targetUrl)
 }
 
-func buildCatalogManagerConnection(manager data_models.CatalogManager) (string, error) {
+func buildCatalogManagerConnection(ctx basecontext.ApiContext, manager data_models.CatalogManager) (string, error) {
 	host := strings.TrimSpace(manager.URL)
 	if host == "" {
 		return "", errors.New("catalog manager url is required")
 	}
 
-	password := decryptCatalogManagerSecret(manager.Password)
-	apiKey := decryptCatalogManagerSecret(manager.ApiKey)
+	username, password, apiKey, err := resolveCatalogManagerCredentials(ctx, manager)
+	if err != nil {
+		return "", err
+	}
 
 	if apiKey != "" {
 		return fmt.Sprintf("host=%s@%s", apiKey, host), nil
 	}
 
-	if manager.Username != "" && password != "" {
-		return fmt.Sprintf("host=%s:%s@%s", manager.Username, password, host), nil
+	if username != "" && password != "" {
+		return fmt.Sprintf("host=%s:%s@%s", username, password, host), nil
 	}
 
 	return "host=" + host, nil
@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/constants"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/helpers"
+	"github.com/Parallels/prl-devops-service/mappers"
+	"github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/restapi"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+)
+
+// @Summary		Searches the catalog manifests
+// @Description	This endpoint searches the catalog manifests the user can see, the results use the shared list query parameters for filtering, sorting and pagination
+// @Tags			Catalogs
+// @Produce		json
+// @Param			q					query		string	false	"Text to search in the name, description and catalog id"
+// @Param			tags				query		string	false	"Comma separated tags, all need to match"
+// @Param			architecture		query		string	false	"Architecture"
+// @Param			min_size			query		int		false	"Minimum size"
+// @Param			max_size			query		int		false	"Maximum size"
+// @Param			created_after		query		string	false	"Created after, RFC3339 or YYYY-MM-DD"
+// @Param			created_before		query		string	false	"Created before, RFC3339 or YYYY-MM-DD"
+// @Param			downloaded_after	query		string	false	"Last downloaded after, RFC3339 or YYYY-MM-DD"
+// @Param			downloaded_before	query		string	false	"Last downloaded before, RFC3339 or YYYY-MM-DD"
+// @Param			tainted				query		bool	false	"Tainted"
+// @Param			revoked				query		bool	false	"Revoked"
+// @Param			required_roles		query		string	false	"Comma separated roles the manifest requires"
+// @Param			fits_cpu			query		int		false	"Only manifests whose minimum cpu fits"
+// @Param			fits_memory			query		int		false	"Only manifests whose minimum memory fits"
+// @Param			fits_disk			query		int		false	"Only manifests whose minimum disk fits"
+// @Param			filter				query		string	false	"Filter expression, e.g. version = 1.0.0 or size > 1000"
+// @Param			sort				query		string	false	"Comma separated properties, prefix with - to sort descending"
+// @Param			page				query		int		false	"Page, starts at 1"
+// @Param			page_size			query		int		false	"Page size"
+// @Param			cursor				query		string	false	"Cursor token from the Link header"
+// @Param			fields				query		string	false	"Comma separated fields to return"
+// @Success		200					{object}	[]models.CatalogManifest
+// @Failure		400					{object}	models.ApiErrorResponse
+// @Failure		401					{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/catalog/_search [get]
+func SearchCatalogManifestsHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		result, ok := searchCatalogManifests(ctx, w, r)
+		if !ok {
+			return
+		}
+
+		manifests := mappers.DtoCatalogManifestsToApi(result.Items)
+		// obfuscate provider credentials for external calls
+		if r.Header.Get(constants.INTERNAL_API_CLIENT) != "true" && config.Get().EnableCredentialsObfuscation() {
+			for i := range manifests {
+				manifests[i].Provider = obfuscateCatalogProvider(manifests[i].Provider)
+			}
+		}
+
+		ReturnApiListResponse(ctx, w, r, manifests, http.StatusOK)
+		ctx.LogInfof("Catalog search matched %v manifests", len(manifests))
+	}
+}
+
+// @Summary		Gets the facets of a catalog search
+// @Description	This endpoint counts the catalog ids, tags, architectures, tainted and revoked manifests matching the search
+// @Tags			Catalogs
+// @Produce		json
+// @Param			q					query		string	false	"Text to search in the name, description and catalog id"
+// @Param			tags				query		string	false	"Comma separated tags, all need to match"
+// @Param			architecture		query		string	false	"Architecture"
+// @Param			min_size			query		int		false	"Minimum size"
+// @Param			max_size			query		int		false	"Maximum size"
+// @Param			created_after		query		string	false	"Created after, RFC3339 or YYYY-MM-DD"
+// @Param			created_before		query		string	false	"Created before, RFC3339 or YYYY-MM-DD"
+// @Param			downloaded_after	query		string	false	"Last downloaded after, RFC3339 or YYYY-MM-DD"
+// @Param			downloaded_before	query		string	false	"Last downloaded before, RFC3339 or YYYY-MM-DD"
+// @Param			tainted				query		bool	false	"Tainted"
+// @Param			revoked				query		bool	false	"Revoked"
+// @Param			required_roles		query		string	false	"Comma separated roles the manifest requires"
+// @Param			fits_cpu			query		int		false	"Only manifests whose minimum cpu fits"
+// @Param			fits_memory			query		int		false	"Only manifests whose minimum memory fits"
+// @Param			fits_disk			query		int		false	"Only manifests whose minimum disk fits"
+// @Success		200					{object}	models.CatalogSearchFacets
+// @Failure		400					{object}	models.ApiErrorResponse
+// @Failure		401					{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/catalog/_search/facets [get]
+func GetCatalogSearchFacetsHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		result, ok := searchCatalogManifests(ctx, w, r)
+		if !ok {
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(mappers.DtoCatalogSearchFacetsToApi(result.Facets))
+		ctx.LogInfof("Catalog search facets counted over %v manifests", len(result.Items))
+	}
+}
+
+// searchCatalogManifests runs the search filters of the request, on error it
+// writes the response and returns false.
+func searchCatalogManifests(ctx basecontext.ApiContext, w http.ResponseWriter, r *http.Request) (*data_models.CatalogSearchResult, bool) {
+	dbService, err := serviceprovider.GetDatabaseService(ctx)
+	if err != nil {
+		ReturnApiError(ctx, w, models.NewFromErrorWithCode(err, http.StatusInternalServerError))
+		return nil, false
+	}
+
+	query, err := parseCatalogSearchQuery(r.URL.Query())
+	if err != nil {
+		ReturnApiError(ctx, w, models.NewFromError(err))
+		return nil, false
+	}
+
+	query.CatalogIds, _ = getApiKeyScope(ctx)
+
+	result, err := dbService.SearchCatalogManifests(ctx, *query)
+	if err != nil {
+		ReturnApiError(ctx, w, models.NewFromError(err))
+		return nil, false
+	}
+
+	return result, true
+}
+
+func obfuscateCatalogProvider(provider *models.RemoteVirtualMachineProvider) *models.RemoteVirtualMachineProvider {
+	if provider == nil {
+		return nil
+	}
+
+	newProvider := &models.RemoteVirtualMachineProvider{
+		Type:     provider.Type,
+		Host:     provider.Host,
+		Port:     provider.Port,
+		Username: helpers.ObfuscateString(provider.Username),
+		Password: helpers.ObfuscateString(provider.Password),
+		ApiKey:   helpers.ObfuscateString(provider.ApiKey),
+	}
+	if provider.Meta != nil {
+		newProvider.Meta = make(map[string]string)
+		for k, v := range provider.Meta {
+			newProvider.Meta[k] = helpers.ObfuscateString(v)
+		}
+	}
+
+	return newProvider
+}
+
+func parseCatalogSearchQuery(values url.Values) (*data_models.CatalogSearchQuery, error) {
+	query := &data_models.CatalogSearchQuery{
+		Text:          strings.TrimSpace(values.Get("q")),
+		Tags:          splitQueryList(values.Get("tags")),
+		Architecture:  strings.TrimSpace(values.Get("architecture")),
+		RequiredRoles: splitQueryList(values.Get("required_roles")),
+	}
+
+	var err error
+	if query.MinSize, err = parseQueryInt64(values, "min_size"); err != nil {
+		return nil, err
+	}
+	if query.MaxSize, err = parseQueryInt64(values, "max_size"); err != nil {
+		return nil, err
+	}
+	for name, target := range map[string]*int{
+		"fits_cpu":    &query.FitsCpu,
+		"fits_memory": &query.FitsMemory,
+		"fits_disk":   &query.FitsDisk,
+	} {
+		value, err := parseQueryInt64(values, name)
+		if err != nil {
+			return nil, err
+		}
+		*target = int(value)
+	}
+	for name, target := range map[string]**time.Time{
+		"created_after":     &query.CreatedAfter,
+		"created_before":    &query.CreatedBefore,
+		"downloaded_after":  &query.DownloadedAfter,
+		"downloaded_before": &query.DownloadedBefore,
+	} {
+		if *target, err = parseQueryDate(values, name); err != nil {
+			return nil, err
+		}
+	}
+	if query.Tainted, err = parseQueryBool(values, "tainted"); err != nil {
+		return nil, err
+	}
+	if query.Revoked, err = parseQueryBool(values, "revoked"); err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+func splitQueryList(value string) []string {
+	result := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+func parseQueryInt64(values url.Values, name string) (int64, error) {
+	value := values.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	result, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.NewWithCodef(400, "invalid %v, it must be a number", name)
+	}
+
+	return result, nil
+}
+
+func parseQueryBool(values url.Values, name string) (*bool, error) {
+	value := values.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, errors.NewWithCodef(400, "invalid %v, it must be true or false", name)
+	}
+
+	return &result, nil
+}
+
+func parseQueryDate(values url.Values, name string) (*time.Time, error) {
+	value := values.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if result, err := time.Parse(layout, value); err == nil {
+			return &result, nil
+		}
+	}
+
+	return nil, errors.NewWithCodef(400, "invalid %v, use RFC3339 or YYYY-MM-DD", name)
+}
@@ -242,6 +242,7 @@ func CreateClaimHandler() restapi.ControllerHandler {
 		}
 
 		response := mappers.DtoClaimToApi(*claim)
+		auditChange(r, response.ID, nil, response)
 
 		emitAuthEvent(constants.EventAuthClaimAdded, models.AuthClaimEvent{ClaimID: claim.ID})
 		w.WriteHeader(http.StatusCreated)
@@ -278,6 +279,11 @@ func DeleteClaimHandler() restapi.ControllerHandler {
 		vars := mux.Vars(r)
 		id := vars["id"]
 
+		var before *models.ClaimResponse
+		if dtoClaim, err := dbService.GetClaim(ctx, id); err == nil && dtoClaim != nil {
+			claim := mappers.DtoClaimToApi(*dtoClaim)
+			before = &claim
+		}
 		err = dbService.DeleteClaim(ctx, id)
 		if err != nil {
 			rsp := models.NewFromError(err)
@@ -286,6 +292,7 @@ func DeleteClaimHandler() restapi.ControllerHandler {
 			return
 		}
 
+		auditChange(r, id, before, nil)
 		emitAuthEvent(constants.EventAuthClaimRemoved, models.AuthClaimEvent{ClaimID: id})
 		w.WriteHeader(http.StatusAccepted)
 		ctx.LogInfof("Claim deleted successfully")
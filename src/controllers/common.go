@@ -7,8 +7,10 @@ import (
 
 	"github.com/Parallels/prl-devops-service/basecontext"
 	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
 	"github.com/Parallels/prl-devops-service/errors"
 	"github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/restapi"
 	"github.com/Parallels/prl-devops-service/serviceprovider"
 	"github.com/Parallels/prl-devops-service/serviceprovider/system"
 )
@@ -42,6 +44,44 @@ func GetFilterHeader(r *http.Request) string {
 	return r.Header.Get("X-Filter")
 }
 
+// getListPage applies the filter, sort, pagination and fields query parameters
+// to the items and sets the list headers. It returns the items of the page and
+// their field selection, on error it writes the response and returns false.
+func getListPage[T interface{}](ctx basecontext.ApiContext, w http.ResponseWriter, r *http.Request, items []T) ([]T, []interface{}, bool) {
+	query, err := restapi.ParseListQuery(r)
+	if err != nil {
+		ReturnApiError(ctx, w, models.NewFromError(err))
+		return nil, nil, false
+	}
+
+	page, listPage, err := data.ApplyListQuery(items, query)
+	if err != nil {
+		ReturnApiError(ctx, w, models.NewFromError(err))
+		return nil, nil, false
+	}
+
+	selected, err := restapi.SelectFields(page, query.Fields)
+	if err != nil {
+		ReturnApiError(ctx, w, models.NewFromError(err))
+		return nil, nil, false
+	}
+
+	restapi.WriteListHeaders(w, r, listPage)
+	return page, selected, true
+}
+
+// ReturnApiListResponse writes a page of the items using the list query
+// parameters of the request
+func ReturnApiListResponse[T interface{}](ctx basecontext.ApiContext, w http.ResponseWriter, r *http.Request, items []T, code int) {
+	_, selected, ok := getListPage(ctx, w, r, items)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(selected)
+}
+
 func GetBaseContext(r *http.Request) *basecontext.BaseContext {
 	ctx := basecontext.NewBaseContextFromRequest(r)
 
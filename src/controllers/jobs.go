@@ -120,8 +120,7 @@ func GetJobsHandler() restapi.ControllerHandler {
 			jobs = make([]models.JobResponse, 0)
 		}
 
-		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(jobs)
+		ReturnApiListResponse(ctx, w, r, jobs, http.StatusOK)
 		ctx.LogInfof("Jobs returned successfully")
 	}
 }
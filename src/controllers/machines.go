@@ -47,6 +47,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/{id}").
 		WithRequiredClaim(constants.LIST_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, localMachineResource)).
 		WithHandler(GetVirtualMachineHandler()).
 		Register()
 
@@ -55,6 +56,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines").
 		WithRequiredClaim(constants.CREATE_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_CREATE, newMachineResource)).
 		WithHandler(CreateVirtualMachineHandler()).
 		Register()
 
@@ -63,6 +65,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/async").
 		WithRequiredClaim(constants.CREATE_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_CREATE, newMachineResource)).
 		WithHandler(AsyncCreateVirtualMachineHandler()).
 		Register()
 
@@ -73,6 +76,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithOrClaims().
 		WithRequiredClaim(constants.CREATE_SNAPSHOT_VM_CLAIM).
 		WithRequiredClaim(constants.CREATE_OWN_VM_SNAPSHOT_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_SNAPSHOT, localMachineResource)).
 		WithHandler(CreateVMSnapshot()).
 		Register()
 
@@ -83,6 +87,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithOrClaims().
 		WithRequiredClaim(constants.DELETE_SNAPSHOT_VM_CLAIM).
 		WithRequiredClaim(constants.DELETE_OWN_VM_SNAPSHOT_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_SNAPSHOT, localMachineResource)).
 		WithHandler(DeleteVMSnapshot()).
 		Register()
 
@@ -93,6 +98,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithOrClaims().
 		WithRequiredClaim(constants.DELETE_ALL_SNAPSHOTS_VM_CLAIM).
 		WithRequiredClaim(constants.DELETE_ALL_OWN_VM_SNAPSHOTS_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_SNAPSHOT, localMachineResource)).
 		WithHandler(DeleteAllVMSnapshots()).
 		Register()
 
@@ -103,6 +109,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithOrClaims().
 		WithRequiredClaim(constants.LIST_SNAPSHOT_VM_CLAIM).
 		WithRequiredClaim(constants.LIST_OWN_VM_SNAPSHOT_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, localMachineResource)).
 		WithHandler(ListVMSnapshot()).
 		Register()
 
@@ -113,6 +120,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithPath("/machines/{id}/snapshots/{snapshot_id}/revert").
 		WithRequiredClaim(constants.REVERT_SNAPSHOT_VM_CLAIM).
 		WithRequiredClaim(constants.REVERT_OWN_VM_SNAPSHOT_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_SNAPSHOT, localMachineResource)).
 		WithHandler(RevertVMSnapshot()).
 		Register()
 
@@ -121,6 +129,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/{id}").
 		WithRequiredClaim(constants.DELETE_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_DELETE, localMachineResource)).
 		WithHandler(DeleteVirtualMachineHandler()).
 		Register()
 
@@ -129,6 +138,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/register").
 		WithRequiredClaim(constants.CREATE_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_REGISTER, newMachineResource)).
 		WithHandler(RegisterVirtualMachineHandler()).
 		Register()
 
@@ -137,6 +147,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/{id}/unregister").
 		WithRequiredClaim(constants.UPDATE_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UNREGISTER, localMachineResource)).
 		WithHandler(UnregisterVirtualMachineHandler()).
 		Register()
 
@@ -145,6 +156,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/{id}/start").
 		WithRequiredClaim(constants.UPDATE_VM_STATES_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_START, localMachineResource)).
 		WithHandler(StartVirtualMachineHandler()).
 		Register()
 
@@ -153,6 +165,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/{id}/stop").
 		WithRequiredClaim(constants.UPDATE_VM_STATES_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_STOP, localMachineResource)).
 		WithHandler(StopVirtualMachineHandler()).
 		Register()
 
@@ -161,6 +174,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/{id}/restart").
 		WithRequiredClaim(constants.UPDATE_VM_STATES_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_RESTART, localMachineResource)).
 		WithHandler(RestartVirtualMachineHandler()).
 		Register()
 
@@ -169,6 +183,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/{id}/pause").
 		WithRequiredClaim(constants.UPDATE_VM_STATES_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_PAUSE, localMachineResource)).
 		WithHandler(PauseVirtualMachineHandler()).
 		Register()
 
@@ -177,6 +192,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/{id}/resume").
 		WithRequiredClaim(constants.UPDATE_VM_STATES_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_RESUME, localMachineResource)).
 		WithHandler(ResumeMachineController()).
 		Register()
 
@@ -185,6 +201,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/{id}/reset").
 		WithRequiredClaim(constants.UPDATE_VM_STATES_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_RESET, localMachineResource)).
 		WithHandler(ResetMachineController()).
 		Register()
 
@@ -193,6 +210,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/{id}/suspend").
 		WithRequiredClaim(constants.UPDATE_VM_STATES_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_SUSPEND, localMachineResource)).
 		WithHandler(SuspendVirtualMachineHandler()).
 		Register()
 
@@ -201,6 +219,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/{id}/status").
 		WithRequiredClaim(constants.LIST_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, localMachineResource)).
 		WithHandler(GetVirtualMachineStatusHandler()).
 		Register()
 
@@ -209,6 +228,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/{id}/set").
 		WithRequiredClaim(constants.UPDATE_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, localMachineResource)).
 		WithHandler(SetVirtualMachineHandler()).
 		Register()
 
@@ -217,6 +237,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/{id}/execute").
 		WithRequiredClaim(constants.EXECUTE_COMMAND_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_EXECUTE, localMachineResource)).
 		WithHandler(ExecuteCommandOnVirtualMachineHandler()).
 		Register()
 
@@ -225,6 +246,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/{id}/upload").
 		WithRequiredClaim(constants.UPDATE_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_EXECUTE, localMachineResource)).
 		WithHandler(UploadFileToVirtualMachineHandler()).
 		Register()
 
@@ -233,6 +255,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/{id}/rename").
 		WithRequiredClaim(constants.UPDATE_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, localMachineResource)).
 		WithHandler(RenameVirtualMachineHandler()).
 		Register()
 
@@ -241,6 +264,7 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 		WithVersion(version).
 		WithPath("/machines/{id}/clone").
 		WithRequiredClaim(constants.CREATE_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_CLONE, localMachineResource)).
 		WithHandler(CloneVirtualMachineHandler()).
 		Register()
 }
@@ -249,6 +273,12 @@ func registerVirtualMachinesHandlers(ctx basecontext.ApiContext, version string)
 // @Description	This endpoint returns all the virtual machines
 // @Tags			Machines
 // @Produce		json
+// @Param			filter		query		string	false	"Filter expression, e.g. state = running and (name ~ ^build or cpu >= 4)"
+// @Param			sort		query		string	false	"Comma separated properties, prefix with - to sort descending"
+// @Param			page		query		int		false	"Page, starts at 1"
+// @Param			page_size	query		int		false	"Page size"
+// @Param			cursor		query		string	false	"Cursor token from the Link header"
+// @Param			fields		query		string	false	"Comma separated fields to return"
 // @Param			filter	header		string	false	"X-Filter"
 // @Success		200		{object}	[]models.ParallelsVM
 // @Failure		400		{object}	models.ApiErrorResponse
@@ -270,17 +300,16 @@ func GetVirtualMachinesHandler() restapi.ControllerHandler {
 			return
 		}
 
-		if len(vms) == 0 {
-			w.WriteHeader(http.StatusOK)
+		if vms == nil {
 			vms = make([]models.ParallelsVM, 0)
-			defer r.Body.Close()
-			_ = json.NewEncoder(w).Encode(vms)
-			ctx.LogInfof("No machines found")
+		}
+		vms, err = filterMachinesByAccessPolicy(ctx, vms)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
 			return
 		}
 
-		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(vms)
+		ReturnApiListResponse(ctx, w, r, vms, http.StatusOK)
 		ctx.LogInfof("Machines returned: %v", len(vms))
 	}
 }
@@ -643,11 +672,16 @@ func DeleteVirtualMachineHandler() restapi.ControllerHandler {
 		// Get force parameter from query string
 		force := r.URL.Query().Get("force") == "true"
 
+		// the machine is gone after the delete, keep its state for the audit log
+		before, _ := svc.GetVm(ctx, id)
+
 		err := svc.DeleteVm(ctx, id, force)
 		if err != nil {
 			ReturnApiError(ctx, w, models.NewFromError(err))
 			return
 		}
+		deleteResourceOwner(ctx, constants.ACCESS_POLICY_RESOURCE_MACHINE, id)
+		auditChange(r, id, before, nil)
 
 		w.WriteHeader(http.StatusAccepted)
 		ctx.LogInfof("Machine deleted: %v", id)
@@ -816,6 +850,7 @@ func CloneVirtualMachineHandler() restapi.ControllerHandler {
 
 		result.Id = vmId.ID
 		result.Status = "Success"
+		setCallerAsResourceOwner(ctx, constants.ACCESS_POLICY_RESOURCE_MACHINE, result.Id)
 
 		w.WriteHeader(http.StatusOK)
 		_ = json.NewEncoder(w).Encode(result)
@@ -1086,6 +1121,8 @@ func RegisterVirtualMachineHandler() restapi.ControllerHandler {
 			vms[0].Name = request.MachineName
 		}
 
+		setCallerAsResourceOwner(ctx, constants.ACCESS_POLICY_RESOURCE_MACHINE, vms[0].ID)
+
 		w.WriteHeader(http.StatusOK)
 		_ = json.NewEncoder(w).Encode(vms[0])
 		ctx.LogInfof("Machine registered: %v", vms[0].ID)
@@ -1191,6 +1228,8 @@ func CreateVirtualMachineHandler() restapi.ControllerHandler {
 				return
 			}
 
+			setCallerAsResourceOwner(ctx, constants.ACCESS_POLICY_RESOURCE_MACHINE, response.ID)
+			auditChange(r, response.ID, nil, response)
 			w.WriteHeader(http.StatusOK)
 			defer r.Body.Close()
 			_ = json.NewEncoder(w).Encode(response)
@@ -1203,6 +1242,8 @@ func CreateVirtualMachineHandler() restapi.ControllerHandler {
 				return
 			}
 
+			setCallerAsResourceOwner(ctx, constants.ACCESS_POLICY_RESOURCE_MACHINE, response.ID)
+			auditChange(r, response.ID, nil, response)
 			w.WriteHeader(http.StatusOK)
 			defer r.Body.Close()
 			_ = json.NewEncoder(w).Encode(response)
@@ -1236,6 +1277,9 @@ func CreateVirtualMachineHandler() restapi.ControllerHandler {
 					ReturnApiError(ctx, w, models.NewFromError(err))
 					return
 				}
+				setCallerAsResourceOwner(ctx, constants.ACCESS_POLICY_RESOURCE_MACHINE, response.ID)
+				setCallerAsResourceOwner(ctx, constants.ACCESS_POLICY_RESOURCE_MACHINE, response.ID)
+				auditChange(r, response.ID, nil, response)
 				w.WriteHeader(http.StatusOK)
 				defer r.Body.Close()
 				_ = json.NewEncoder(w).Encode(response)
@@ -1266,6 +1310,8 @@ func CreateVirtualMachineHandler() restapi.ControllerHandler {
 			resultMessage := fmt.Sprintf("Virtual machine %s created", response.ID)
 			_ = jobManager.MarkJobCompleteWithRecord(job.ID, resultMessage, response.ID, response.Name, "virtual_machine", response.Host)
 
+			setCallerAsResourceOwner(ctx, constants.ACCESS_POLICY_RESOURCE_MACHINE, response.ID)
+			auditChange(r, response.ID, nil, response)
 			w.WriteHeader(http.StatusOK)
 			defer r.Body.Close()
 			_ = json.NewEncoder(w).Encode(response)
@@ -1304,6 +1350,8 @@ func AsyncCreateVirtualMachineHandler() restapi.ControllerHandler {
 			return
 		}
 
+		ownerId, ownerName := getCallerOwner(ctx)
+
 		var request models.CreateVirtualMachineRequest
 		if err := http_helper.MapRequestBody(r, &request); err != nil {
 			ReturnApiError(ctx, w, models.ApiErrorResponse{
@@ -1364,6 +1412,7 @@ func AsyncCreateVirtualMachineHandler() restapi.ControllerHandler {
 						}
 						return
 					}
+					setResourceOwner(asyncCtx, constants.ACCESS_POLICY_RESOURCE_MACHINE, result.ID, ownerId, ownerName)
 					if jobManager != nil {
 						_ = jobManager.MarkJobCompleteWithRecord(orchJobID,
 							fmt.Sprintf("Virtual machine %s created", result.ID),
@@ -1412,6 +1461,7 @@ func AsyncCreateVirtualMachineHandler() restapi.ControllerHandler {
 				return
 			}
 
+			setResourceOwner(asyncCtx, constants.ACCESS_POLICY_RESOURCE_MACHINE, result.ID, ownerId, ownerName)
 			resultMessage := fmt.Sprintf("Virtual machine %s created", result.ID)
 			_ = jobManager.MarkJobCompleteWithRecord(jobID, resultMessage, result.ID, result.Name, "virtual_machine", result.Host)
 		}(job.ID, request)
@@ -2044,10 +2094,15 @@ func resolveCatalogMachineConnection(ctx basecontext.ApiContext, request *models
 			return "", errors.NewWithCode(errResp.Message, errResp.Code)
 		}
 
-		managerConnection, err := buildCatalogManagerConnection(*mgr)
+		managerConnection, err := buildCatalogManagerConnection(ctx, *mgr)
 		if err != nil {
 			return "", errors.NewWithCode(err.Error(), http.StatusBadRequest)
 		}
+
+		// The catalog manager bandwidth limit applies unless the request asks for a lower one
+		if mgr.MaxTransferRateKb > 0 && (request.MaxTransferRateKb <= 0 || request.MaxTransferRateKb > mgr.MaxTransferRateKb) {
+			request.MaxTransferRateKb = mgr.MaxTransferRateKb
+		}
 		return managerConnection, nil
 	}
 
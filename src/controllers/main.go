@@ -10,9 +10,13 @@ func RegisterV1Handlers(ctx basecontext.ApiContext) error {
 	version := "v1"
 	registerAuthorizationHandlers(ctx, version)
 	registerUsersHandlers(ctx, version)
+	registerSessionsHandlers(ctx, version)
+	registerMfaHandlers(ctx, version)
 	registerApiKeysHandlers(ctx, version)
 	registerClaimsHandlers(ctx, version)
 	registerRolesHandlers(ctx, version)
+	registerAuditHandlers(ctx, version)
+	registerAccessPoliciesHandlers(ctx, version)
 	if config.Get().IsCatalogManager() {
 		registerCatalogManagerHandlers(ctx, version)
 	}
@@ -32,6 +36,7 @@ func RegisterV1Handlers(ctx basecontext.ApiContext) error {
 	registerUserConfigsHandlers(ctx, version)
 	if config.Get().IsOrchestrator() {
 		registerOrchestratorHostsHandlers(ctx, version)
+		registerOrchestratorCacheWarmupHandlers(ctx, version)
 	}
 	registerSshHandlers(ctx, version)
 	registerPerformanceHandlers(ctx, version)
@@ -0,0 +1,531 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/Parallels/prl-devops-service/audit"
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
+	dbmodels "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/mappers"
+	"github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/restapi"
+	"github.com/Parallels/prl-devops-service/security/mfa"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+
+	"github.com/cjlapao/common-go/helper/http_helper"
+	"github.com/gorilla/mux"
+)
+
+func registerMfaHandlers(ctx basecontext.ApiContext, version string) {
+	ctx.LogInfof("Registering version %s Mfa handlers", version)
+	restapi.NewController().
+		WithMethod(restapi.POST).
+		WithVersion(version).
+		WithPath("/auth/token/mfa").
+		WithHandler(restapi.AuditAuthenticationHandler(audit.ActionLogin, CompleteMfaLoginHandler())).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.POST).
+		WithVersion(version).
+		WithPath("/auth/token/mfa/enroll").
+		WithHandler(StartMfaLoginEnrollmentHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/auth/mfa").
+		WithAuthorization().
+		WithHandler(GetMfaStatusHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.POST).
+		WithVersion(version).
+		WithPath("/auth/mfa/totp").
+		WithAuthorization().
+		WithHandler(StartMfaEnrollmentHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.POST).
+		WithVersion(version).
+		WithPath("/auth/mfa/totp/verify").
+		WithAuthorization().
+		WithHandler(VerifyMfaEnrollmentHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.DELETE).
+		WithVersion(version).
+		WithPath("/auth/mfa/totp").
+		WithAuthorization().
+		WithHandler(DisableMfaHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.DELETE).
+		WithVersion(version).
+		WithPath("/auth/users/{id}/mfa").
+		WithRequiredClaim(constants.UPDATE_USER_CLAIM).
+		WithHandler(ResetUserMfaHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.PUT).
+		WithVersion(version).
+		WithPath("/auth/roles/{id}/mfa").
+		WithRequiredClaim(constants.UPDATE_ROLE_CLAIM).
+		WithHandler(UpdateRoleMfaHandler()).
+		Register()
+}
+
+// @Summary		Completes a login with a second factor
+// @Description	This endpoint exchanges the mfa token of a login and a code of the authenticator app, or a recovery code, for a token. Users that did not enroll yet complete the enrollment and get their recovery codes
+// @Tags			Authorization
+// @Produce		json
+// @Param			body	body		models.MfaTokenRequest	true	"Body"
+// @Success		200		{object}	models.LoginResponse
+// @Failure		400		{object}	models.ApiErrorDiagnosticsResponse
+// @Failure		401		{object}	models.ApiErrorDiagnosticsResponse
+// @Router			/v1/auth/token/mfa [post]
+func CompleteMfaLoginHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		mfaLoginDiag := errors.NewDiagnostics("/auth/token/mfa")
+		var request models.MfaTokenRequest
+		if err := http_helper.MapRequestBody(r, &request); err != nil {
+			mfaLoginDiag.AddError(strconv.Itoa(http.StatusBadRequest), "Invalid request body: "+err.Error(), "MapRequestBody")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(mfaLoginDiag, http.StatusBadRequest))
+			return
+		}
+		if err := request.Validate(); err != nil {
+			mfaLoginDiag.AddError(strconv.Itoa(http.StatusBadRequest), "Invalid request body: "+err.Error(), "Validate")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(mfaLoginDiag, http.StatusBadRequest))
+			return
+		}
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			mfaLoginDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "ServiceProvider")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(mfaLoginDiag, rsp.Code))
+			return
+		}
+
+		mfaSvc := mfa.Get()
+		challenge, err := mfaSvc.GetChallenge(request.MfaToken)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			mfaLoginDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "GetMfaChallenge")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(mfaLoginDiag, rsp.Code))
+			return
+		}
+
+		audit.SetActor(r.Context(), audit.ActorTypeUser, challenge.UserID)
+		user, recoveryCodes, err := mfaSvc.CompleteChallenge(ctx, dbService, challenge.Token, request.Code, request.RecoveryCode)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			mfaLoginDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "CompleteMfaChallenge")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(mfaLoginDiag, rsp.Code))
+			return
+		}
+		audit.SetActor(r.Context(), audit.ActorTypeUser, user.Username)
+		if user.Disabled {
+			mfaLoginDiag.AddError(strconv.Itoa(http.StatusUnauthorized), "User is disabled", "Disabled")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithMessageAndCode(mfaLoginDiag, "User is disabled", http.StatusUnauthorized))
+			return
+		}
+
+		response, err := newUserLoginResponse(ctx, dbService, user, r, challenge.AuthenticationMethod+constants.SESSION_AUTHENTICATION_MFA_SUFFIX)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			mfaLoginDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "NewUserLoginResponse")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(mfaLoginDiag, rsp.Code))
+			return
+		}
+		response.RecoveryCodes = recoveryCodes
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("User %s logged in with multi-factor authentication", user.Email)
+	}
+}
+
+// @Summary		Starts the enrollment of a second factor during a login
+// @Description	This endpoint returns a new authenticator secret for users whose roles require a second factor they did not enroll yet
+// @Tags			Authorization
+// @Produce		json
+// @Param			body	body		models.MfaTokenRequest	true	"Body"
+// @Success		200		{object}	models.MfaEnrollmentResponse
+// @Failure		400		{object}	models.ApiErrorDiagnosticsResponse
+// @Failure		401		{object}	models.ApiErrorDiagnosticsResponse
+// @Router			/v1/auth/token/mfa/enroll [post]
+func StartMfaLoginEnrollmentHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		enrollDiag := errors.NewDiagnostics("/auth/token/mfa/enroll")
+		var request models.MfaTokenRequest
+		if err := http_helper.MapRequestBody(r, &request); err != nil {
+			enrollDiag.AddError(strconv.Itoa(http.StatusBadRequest), "Invalid request body: "+err.Error(), "MapRequestBody")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(enrollDiag, http.StatusBadRequest))
+			return
+		}
+		if err := request.Validate(); err != nil {
+			enrollDiag.AddError(strconv.Itoa(http.StatusBadRequest), "Invalid request body: "+err.Error(), "Validate")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(enrollDiag, http.StatusBadRequest))
+			return
+		}
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			enrollDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "ServiceProvider")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(enrollDiag, rsp.Code))
+			return
+		}
+
+		mfaSvc := mfa.Get()
+		challenge, err := mfaSvc.GetChallenge(request.MfaToken)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			enrollDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "GetMfaChallenge")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(enrollDiag, rsp.Code))
+			return
+		}
+		if !challenge.EnrollmentRequired {
+			rsp := models.NewFromError(mfa.ErrAlreadyEnrolled)
+			enrollDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "CheckEnrollment")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(enrollDiag, rsp.Code))
+			return
+		}
+
+		user, err := dbService.GetUser(ctx, challenge.UserID)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			enrollDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "GetUser")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(enrollDiag, rsp.Code))
+			return
+		}
+
+		secret, provisioningUri, err := mfaSvc.StartEnrollment(ctx, dbService, user)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			enrollDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "StartEnrollment")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(enrollDiag, rsp.Code))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(models.MfaEnrollmentResponse{Secret: secret, ProvisioningUri: provisioningUri})
+		ctx.LogInfof("User %s started the multi-factor authentication enrollment", user.Email)
+	}
+}
+
+// @Summary		Gets the multi-factor authentication status
+// @Description	This endpoint returns the multi-factor authentication status of the user of the request
+// @Tags			Authorization
+// @Produce		json
+// @Success		200	{object}	models.MfaStatusResponse
+// @Failure		400	{object}	models.ApiErrorDiagnosticsResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/auth/mfa [get]
+func GetMfaStatusHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		statusDiag := errors.NewDiagnostics("/auth/mfa")
+		dbService, user, err := getMfaRequestUser(ctx)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			statusDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "GetUser")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(statusDiag, rsp.Code))
+			return
+		}
+
+		response := models.MfaStatusResponse{
+			Required: mfa.Get().IsRequiredByRole(ctx, dbService, user),
+		}
+		if user.Mfa != nil && user.Mfa.Enabled {
+			response.Enabled = true
+			response.EnabledAt = user.Mfa.EnabledAt
+			response.RecoveryCodes = len(user.Mfa.RecoveryCodes)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("Multi-factor authentication status of user %s returned", user.Email)
+	}
+}
+
+// @Summary		Starts the enrollment of a second factor
+// @Description	This endpoint returns a new authenticator secret and its provisioning uri, the enrollment is completed at /v1/auth/mfa/totp/verify
+// @Tags			Authorization
+// @Produce		json
+// @Success		200	{object}	models.MfaEnrollmentResponse
+// @Failure		400	{object}	models.ApiErrorDiagnosticsResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/auth/mfa/totp [post]
+func StartMfaEnrollmentHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		enrollDiag := errors.NewDiagnostics("/auth/mfa/totp")
+		dbService, user, err := getMfaRequestUser(ctx)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			enrollDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "GetUser")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(enrollDiag, rsp.Code))
+			return
+		}
+
+		secret, provisioningUri, err := mfa.Get().StartEnrollment(ctx, dbService, user)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			enrollDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "StartEnrollment")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(enrollDiag, rsp.Code))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(models.MfaEnrollmentResponse{Secret: secret, ProvisioningUri: provisioningUri})
+		ctx.LogInfof("User %s started the multi-factor authentication enrollment", user.Email)
+	}
+}
+
+// @Summary		Completes the enrollment of a second factor
+// @Description	This endpoint enables the second factor once a code of the authenticator app is verified, the recovery codes are only returned here
+// @Tags			Authorization
+// @Produce		json
+// @Param			body	body		models.MfaCodeRequest	true	"Body"
+// @Success		200		{object}	models.MfaRecoveryCodesResponse
+// @Failure		400		{object}	models.ApiErrorDiagnosticsResponse
+// @Failure		401		{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/auth/mfa/totp/verify [post]
+func VerifyMfaEnrollmentHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		verifyDiag := errors.NewDiagnostics("/auth/mfa/totp/verify")
+		var request models.MfaCodeRequest
+		if err := http_helper.MapRequestBody(r, &request); err != nil {
+			verifyDiag.AddError(strconv.Itoa(http.StatusBadRequest), "Invalid request body: "+err.Error(), "MapRequestBody")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(verifyDiag, http.StatusBadRequest))
+			return
+		}
+		if request.Code == "" {
+			verifyDiag.AddError(strconv.Itoa(http.StatusBadRequest), "Invalid request body: Code is required", "Validate")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(verifyDiag, http.StatusBadRequest))
+			return
+		}
+
+		dbService, user, err := getMfaRequestUser(ctx)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			verifyDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "GetUser")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(verifyDiag, rsp.Code))
+			return
+		}
+
+		recoveryCodes, err := mfa.Get().CompleteEnrollment(ctx, dbService, user, request.Code)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			verifyDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "CompleteEnrollment")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(verifyDiag, rsp.Code))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(models.MfaRecoveryCodesResponse{RecoveryCodes: recoveryCodes})
+		ctx.LogInfof("User %s enabled multi-factor authentication", user.Email)
+	}
+}
+
+// @Summary		Disables the second factor
+// @Description	This endpoint removes the second factor of the user of the request after checking a code, users whose roles require a second factor cannot remove it
+// @Tags			Authorization
+// @Produce		json
+// @Param			body	body	models.MfaCodeRequest	true	"Body"
+// @Success		202
+// @Failure		400	{object}	models.ApiErrorDiagnosticsResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/auth/mfa/totp [delete]
+func DisableMfaHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		disableDiag := errors.NewDiagnostics("/auth/mfa/totp [delete]")
+		var request models.MfaCodeRequest
+		if err := http_helper.MapRequestBody(r, &request); err != nil {
+			disableDiag.AddError(strconv.Itoa(http.StatusBadRequest), "Invalid request body: "+err.Error(), "MapRequestBody")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(disableDiag, http.StatusBadRequest))
+			return
+		}
+		if err := request.Validate(); err != nil {
+			disableDiag.AddError(strconv.Itoa(http.StatusBadRequest), "Invalid request body: "+err.Error(), "Validate")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(disableDiag, http.StatusBadRequest))
+			return
+		}
+
+		dbService, user, err := getMfaRequestUser(ctx)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			disableDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "GetUser")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(disableDiag, rsp.Code))
+			return
+		}
+
+		if err := mfa.Get().Disable(ctx, dbService, user, request.Code, request.RecoveryCode); err != nil {
+			rsp := models.NewFromError(err)
+			disableDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "DisableMfa")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(disableDiag, rsp.Code))
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		ctx.LogInfof("User %s disabled multi-factor authentication", user.Email)
+	}
+}
+
+// @Summary		Resets the second factor of a user
+// @Description	This endpoint removes the second factor of a user that lost its authenticator and recovery codes, users whose roles require a second factor enroll again on the next login
+// @Tags			Users
+// @Produce		json
+// @Param			id	path	string	true	"User ID"
+// @Success		202
+// @Failure		400	{object}	models.ApiErrorDiagnosticsResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/auth/users/{id}/mfa [delete]
+func ResetUserMfaHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		id := mux.Vars(r)["id"]
+		resetDiag := errors.NewDiagnostics("/auth/users/" + id + "/mfa [delete]")
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			resetDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "ServiceProvider")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(resetDiag, rsp.Code))
+			return
+		}
+
+		user, err := dbService.GetUser(ctx, id)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			resetDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "GetUser")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(resetDiag, rsp.Code))
+			return
+		}
+
+		before := getAuditUser(ctx, dbService, user.ID)
+		if err := dbService.DisableUserMfa(ctx, user.ID); err != nil {
+			rsp := models.NewFromError(err)
+			resetDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "DisableUserMfa")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(resetDiag, rsp.Code))
+			return
+		}
+		auditChange(r, user.ID, before, getAuditUser(ctx, dbService, user.ID))
+
+		w.WriteHeader(http.StatusAccepted)
+		ctx.LogInfof("Multi-factor authentication of user %s reset", user.Email)
+	}
+}
+
+// @Summary		Sets the multi-factor authentication policy of a role
+// @Description	This endpoint sets whether the users of the role have to log in with a second factor
+// @Tags			Roles
+// @Produce		json
+// @Param			id		path		string					true	"Role ID"
+// @Param			body	body		models.RoleMfaRequest	true	"Body"
+// @Success		200		{object}	models.RoleResponse
+// @Failure		400		{object}	models.ApiErrorDiagnosticsResponse
+// @Failure		401		{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/auth/roles/{id}/mfa [put]
+func UpdateRoleMfaHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		id := mux.Vars(r)["id"]
+		roleMfaDiag := errors.NewDiagnostics("/auth/roles/" + id + "/mfa [put]")
+		var request models.RoleMfaRequest
+		if err := http_helper.MapRequestBody(r, &request); err != nil {
+			roleMfaDiag.AddError(strconv.Itoa(http.StatusBadRequest), "Invalid request body: "+err.Error(), "MapRequestBody")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(roleMfaDiag, http.StatusBadRequest))
+			return
+		}
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			roleMfaDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "ServiceProvider")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(roleMfaDiag, rsp.Code))
+			return
+		}
+
+		before := getAuditRole(ctx, dbService, id)
+		role, err := dbService.UpdateRoleMfaRequired(ctx, id, request.Required)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			roleMfaDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "UpdateRoleMfaRequired")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(roleMfaDiag, rsp.Code))
+			return
+		}
+
+		response := mappers.DtoRoleToApi(*role)
+		auditChange(r, role.ID, before, response)
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("Multi-factor authentication policy of role %s set to %v", role.ID, request.Required)
+	}
+}
+
+// getMfaRequestUser returns the user of the request, api keys that are not
+// linked to a user cannot enroll a second factor
+func getMfaRequestUser(ctx basecontext.ApiContext) (*data.JsonDatabase, *dbmodels.User, error) {
+	dbService, err := serviceprovider.GetDatabaseService(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	authContext := ctx.GetAuthorizationContext()
+	if authContext == nil || authContext.User == nil {
+		return nil, nil, errors.NewWithCode("the request is not authenticated as a user", http.StatusBadRequest)
+	}
+
+	user, err := dbService.GetUser(ctx, authContext.User.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dbService, user, nil
+}
@@ -36,6 +36,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}").
 		WithRequiredClaim(constants.LIST_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, orchestratorHostResource)).
 		WithHandler(GetOrchestratorHostHandler()).
 		Register()
 
@@ -44,6 +45,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/hardware").
 		WithRequiredClaim(constants.LIST_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, orchestratorHostResource)).
 		WithHandler(GetOrchestratorHostHardwareInfoHandler()).
 		Register()
 
@@ -92,6 +94,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}").
 		WithRequiredClaim(constants.DELETE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_DELETE, orchestratorHostResource)).
 		WithHandler(UnregisterOrchestratorHostHandler()).
 		Register()
 
@@ -100,6 +103,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}").
 		WithRequiredClaim(constants.UPDATE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorHostResource)).
 		WithHandler(UpdateOrchestratorHostHandler()).
 		Register()
 
@@ -108,6 +112,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/enable").
 		WithRequiredClaim(constants.UPDATE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorHostResource)).
 		WithHandler(EnableOrchestratorHostsHandler()).
 		Register()
 
@@ -116,6 +121,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/disable").
 		WithRequiredClaim(constants.UPDATE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorHostResource)).
 		WithHandler(DisableOrchestratorHostsHandler()).
 		Register()
 
@@ -132,6 +138,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/overview/{id}/resources").
 		WithRequiredClaim(constants.LIST_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, orchestratorHostResource)).
 		WithHandler(GetOrchestratorHostResourcesHandler()).
 		Register()
 
@@ -148,6 +155,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/machines/{id}").
 		WithRequiredClaim(constants.LIST_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, orchestratorMachineResource)).
 		WithHandler(GetOrchestratorVirtualMachineHandler()).
 		Register()
 
@@ -156,6 +164,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/machines/{id}").
 		WithRequiredClaim(constants.DELETE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_DELETE, orchestratorMachineResource)).
 		WithHandler(DeleteOrchestratorVirtualMachineHandler()).
 		Register()
 
@@ -164,6 +173,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/machines/{id}/status").
 		WithRequiredClaim(constants.LIST_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, orchestratorMachineResource)).
 		WithHandler(GetOrchestratorVirtualMachineStatusHandler()).
 		Register()
 
@@ -172,6 +182,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/machines/{id}/rename").
 		WithRequiredClaim(constants.UPDATE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorMachineResource)).
 		WithHandler(RenameOrchestratorVirtualMachineHandler()).
 		Register()
 
@@ -180,6 +191,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/machines/{id}/set").
 		WithRequiredClaim(constants.UPDATE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorMachineResource)).
 		WithHandler(SetOrchestratorVirtualMachineHandler()).
 		Register()
 
@@ -188,6 +200,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/machines/{id}/start").
 		WithRequiredClaim(constants.UPDATE_VM_STATES_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_START, orchestratorMachineResource)).
 		WithHandler(StartOrchestratorVirtualMachineHandler()).
 		Register()
 
@@ -196,6 +209,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/machines/{id}/stop").
 		WithRequiredClaim(constants.UPDATE_VM_STATES_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_STOP, orchestratorMachineResource)).
 		WithHandler(StopOrchestratorVirtualMachineHandler()).
 		Register()
 
@@ -204,6 +218,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/machines/{id}/restart").
 		WithRequiredClaim(constants.UPDATE_VM_STATES_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_RESTART, orchestratorMachineResource)).
 		WithHandler(RestartOrchestratorVirtualMachineHandler()).
 		Register()
 
@@ -212,6 +227,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/machines/{id}/suspend").
 		WithRequiredClaim(constants.UPDATE_VM_STATES_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_SUSPEND, orchestratorMachineResource)).
 		WithHandler(SuspendOrchestratorVirtualMachineHandler()).
 		Register()
 
@@ -220,6 +236,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/machines/{id}/resume").
 		WithRequiredClaim(constants.UPDATE_VM_STATES_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_RESUME, orchestratorMachineResource)).
 		WithHandler(ResumeOrchestratorVirtualMachineHandler()).
 		Register()
 
@@ -228,6 +245,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/machines/{id}/reset").
 		WithRequiredClaim(constants.UPDATE_VM_STATES_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_RESET, orchestratorMachineResource)).
 		WithHandler(ResetOrchestratorVirtualMachineHandler()).
 		Register()
 
@@ -236,6 +254,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/machines/{id}/pause").
 		WithRequiredClaim(constants.UPDATE_VM_STATES_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_PAUSE, orchestratorMachineResource)).
 		WithHandler(PauseOrchestratorVirtualMachineHandler()).
 		Register()
 
@@ -244,6 +263,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/machines/{id}/clone").
 		WithRequiredClaim(constants.UPDATE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_CLONE, orchestratorMachineResource)).
 		WithHandler(CloneOrchestratorVirtualMachineHandler()).
 		Register()
 
@@ -252,6 +272,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/machines/{id}/execute").
 		WithRequiredClaim(constants.EXECUTE_COMMAND_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_EXECUTE, orchestratorMachineResource)).
 		WithHandler(ExecutesOrchestratorVirtualMachineHandler()).
 		Register()
 
@@ -268,6 +289,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}").
 		WithRequiredClaim(constants.LIST_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, orchestratorHostMachineResource)).
 		WithHandler(GetOrchestratorHostVirtualMachineHandler()).
 		Register()
 
@@ -276,6 +298,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}").
 		WithRequiredClaim(constants.DELETE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_DELETE, orchestratorHostMachineResource)).
 		WithHandler(DeleteOrchestratorHostVirtualMachineHandler()).
 		Register()
 
@@ -284,6 +307,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/status").
 		WithRequiredClaim(constants.LIST_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, orchestratorHostMachineResource)).
 		WithHandler(GetOrchestratorHostVirtualMachineStatusHandler()).
 		Register()
 
@@ -292,6 +316,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/rename").
 		WithRequiredClaim(constants.UPDATE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorHostMachineResource)).
 		WithHandler(RenameOrchestratorHostVirtualMachineHandler()).
 		Register()
 
@@ -300,6 +325,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/set").
 		WithRequiredClaim(constants.UPDATE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorHostMachineResource)).
 		WithHandler(SetOrchestratorHostVirtualMachineHandler()).
 		Register()
 
@@ -308,6 +334,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/start").
 		WithRequiredClaim(constants.UPDATE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_START, orchestratorHostMachineResource)).
 		WithHandler(StartOrchestratorHostVirtualMachineHandler()).
 		Register()
 
@@ -316,6 +343,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/stop").
 		WithRequiredClaim(constants.UPDATE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_STOP, orchestratorHostMachineResource)).
 		WithHandler(StopOrchestratorHostVirtualMachineHandler()).
 		Register()
 
@@ -324,6 +352,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/restart").
 		WithRequiredClaim(constants.UPDATE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_RESTART, orchestratorHostMachineResource)).
 		WithHandler(RestartOrchestratorHostVirtualMachineHandler()).
 		Register()
 
@@ -332,6 +361,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/suspend").
 		WithRequiredClaim(constants.UPDATE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_SUSPEND, orchestratorHostMachineResource)).
 		WithHandler(SuspendOrchestratorHostVirtualMachineHandler()).
 		Register()
 
@@ -340,6 +370,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/resume").
 		WithRequiredClaim(constants.UPDATE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_RESUME, orchestratorHostMachineResource)).
 		WithHandler(ResumeOrchestratorHostVirtualMachineHandler()).
 		Register()
 
@@ -348,6 +379,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/reset").
 		WithRequiredClaim(constants.UPDATE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_RESET, orchestratorHostMachineResource)).
 		WithHandler(ResetOrchestratorHostVirtualMachineHandler()).
 		Register()
 
@@ -356,6 +388,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/pause").
 		WithRequiredClaim(constants.UPDATE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_PAUSE, orchestratorHostMachineResource)).
 		WithHandler(PauseOrchestratorHostVirtualMachineHandler()).
 		Register()
 
@@ -364,6 +397,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/clone").
 		WithRequiredClaim(constants.UPDATE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_CLONE, orchestratorHostMachineResource)).
 		WithHandler(CloneOrchestratorHostVirtualMachineHandler()).
 		Register()
 
@@ -372,6 +406,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/execute").
 		WithRequiredClaim(constants.EXECUTE_COMMAND_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_EXECUTE, orchestratorHostMachineResource)).
 		WithHandler(ExecutesOrchestratorHostVirtualMachineHandler()).
 		Register()
 
@@ -380,6 +415,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines/register").
 		WithRequiredClaim(constants.CREATE_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_REGISTER, newMachineResource)).
 		WithHandler(RegisterOrchestratorHostVirtualMachineHandler()).
 		Register()
 
@@ -388,6 +424,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/unregister").
 		WithRequiredClaim(constants.UPDATE_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UNREGISTER, orchestratorHostMachineResource)).
 		WithHandler(UnregisterOrchestratorHostVirtualMachineHandler()).
 		Register()
 
@@ -396,6 +433,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines").
 		WithRequiredClaim(constants.CREATE_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_CREATE, newMachineResource)).
 		WithHandler(CreateOrchestratorHostVirtualMachineHandler()).
 		Register()
 
@@ -404,6 +442,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/machines/async").
 		WithRequiredClaim(constants.CREATE_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_CREATE, newMachineResource)).
 		WithHandler(AsyncCreateOrchestratorHostVirtualMachineHandler()).
 		Register()
 
@@ -415,6 +454,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/snapshots").
 		WithRequiredClaim(constants.LIST_SNAPSHOT_VM_CLAIM).
 		WithRequiredClaim(constants.LIST_OWN_VM_SNAPSHOT_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, orchestratorHostMachineResource)).
 		WithHandler(ListOrchestratorHostVirtualMachineSnapshots()).
 		Register()
 
@@ -425,6 +465,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/snapshots").
 		WithRequiredClaim(constants.CREATE_SNAPSHOT_VM_CLAIM).
 		WithRequiredClaim(constants.CREATE_OWN_VM_SNAPSHOT_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_SNAPSHOT, orchestratorHostMachineResource)).
 		WithHandler(CreateOrchestratorHostVirtualMachineSnapshot()).
 		Register()
 
@@ -435,6 +476,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/snapshots").
 		WithRequiredClaim(constants.DELETE_ALL_SNAPSHOTS_VM_CLAIM).
 		WithRequiredClaim(constants.DELETE_ALL_OWN_VM_SNAPSHOTS_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_SNAPSHOT, orchestratorHostMachineResource)).
 		WithHandler(DeleteAllOrchestratorHostVirtualMachineSnapshots()).
 		Register()
 
@@ -445,6 +487,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/snapshots/{snapshot_id}").
 		WithRequiredClaim(constants.DELETE_SNAPSHOT_VM_CLAIM).
 		WithRequiredClaim(constants.DELETE_OWN_VM_SNAPSHOT_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_SNAPSHOT, orchestratorHostMachineResource)).
 		WithHandler(DeleteOrchestratorHostVirtualMachineSnapshot()).
 		Register()
 
@@ -455,6 +498,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithPath("/orchestrator/hosts/{id}/machines/{vmId}/snapshots/{snapshot_id}/revert").
 		WithRequiredClaim(constants.REVERT_SNAPSHOT_VM_CLAIM).
 		WithRequiredClaim(constants.REVERT_OWN_VM_SNAPSHOT_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_SNAPSHOT, orchestratorHostMachineResource)).
 		WithHandler(RevertOrchestratorHostVirtualMachineSnapshot()).
 		Register()
 
@@ -466,6 +510,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithPath("/orchestrator/machines/{id}/snapshots").
 		WithRequiredClaim(constants.LIST_SNAPSHOT_VM_CLAIM).
 		WithRequiredClaim(constants.LIST_OWN_VM_SNAPSHOT_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, orchestratorMachineResource)).
 		WithHandler(ListOrchestratorVirtualMachineSnapshots()).
 		Register()
 
@@ -476,6 +521,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithPath("/orchestrator/machines/{id}/snapshots").
 		WithRequiredClaim(constants.CREATE_SNAPSHOT_VM_CLAIM).
 		WithRequiredClaim(constants.CREATE_OWN_VM_SNAPSHOT_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_SNAPSHOT, orchestratorMachineResource)).
 		WithHandler(CreateOrchestratorVirtualMachineSnapshot()).
 		Register()
 
@@ -486,6 +532,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithPath("/orchestrator/machines/{id}/snapshots").
 		WithRequiredClaim(constants.DELETE_ALL_SNAPSHOTS_VM_CLAIM).
 		WithRequiredClaim(constants.DELETE_ALL_OWN_VM_SNAPSHOTS_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_SNAPSHOT, orchestratorMachineResource)).
 		WithHandler(DeleteAllOrchestratorVirtualMachineSnapshots()).
 		Register()
 
@@ -496,6 +543,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithPath("/orchestrator/machines/{id}/snapshots/{snapshot_id}").
 		WithRequiredClaim(constants.DELETE_SNAPSHOT_VM_CLAIM).
 		WithRequiredClaim(constants.DELETE_OWN_VM_SNAPSHOT_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_SNAPSHOT, orchestratorMachineResource)).
 		WithHandler(DeleteOrchestratorVirtualMachineSnapshot()).
 		Register()
 
@@ -506,6 +554,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithPath("/orchestrator/machines/{id}/snapshots/{snapshot_id}/revert").
 		WithRequiredClaim(constants.REVERT_SNAPSHOT_VM_CLAIM).
 		WithRequiredClaim(constants.REVERT_OWN_VM_SNAPSHOT_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_SNAPSHOT, orchestratorMachineResource)).
 		WithHandler(RevertOrchestratorVirtualMachineSnapshot()).
 		Register()
 
@@ -514,6 +563,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/machines").
 		WithRequiredClaim(constants.CREATE_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_CREATE, newMachineResource)).
 		WithHandler(CreateOrchestratorVirtualMachineHandler()).
 		Register()
 
@@ -522,6 +572,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/machines/async").
 		WithRequiredClaim(constants.CREATE_VM_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_CREATE, newMachineResource)).
 		WithHandler(AsyncCreateOrchestratorVirtualMachineHandler()).
 		Register()
 
@@ -531,6 +582,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/cache").
 		WithRequiredClaim(constants.SUPER_USER_ROLE).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, orchestratorHostResource)).
 		WithHandler(GetOrchestratorHostCatalogCacheHandler()).
 		Register()
 
@@ -539,6 +591,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/cache").
 		WithRequiredClaim(constants.SUPER_USER_ROLE).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorHostResource)).
 		WithHandler(DeleteOrchestratorHostCatalogCacheHandler()).
 		Register()
 
@@ -547,6 +600,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/cache/{catalog_id}").
 		WithRequiredClaim(constants.SUPER_USER_ROLE).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorHostResource)).
 		WithHandler(DeleteOrchestratorHostCatalogCacheItemHandler()).
 		Register()
 
@@ -555,6 +609,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/cache/{catalog_id}/{catalog_version}").
 		WithRequiredClaim(constants.SUPER_USER_ROLE).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorHostResource)).
 		WithHandler(DeleteOrchestratorHostCatalogCacheItemVersionHandler()).
 		Register()
 		// endregion
@@ -565,9 +620,27 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/reverse-proxy").
 		WithRequiredClaim(constants.LIST_REVERSE_PROXY_HOSTS_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, orchestratorHostResource)).
 		WithHandler(GetOrchestratorHostReverseProxyConfigHandler()).
 		Register()
 
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/orchestrator/hosts/{id}/reverse-proxy/stats").
+		WithRequiredClaim(constants.LIST_REVERSE_PROXY_HOSTS_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, orchestratorHostResource)).
+		WithHandler(GetOrchestratorHostReverseProxyStatsHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/orchestrator/reverse-proxy/stats").
+		WithRequiredClaim(constants.LIST_REVERSE_PROXY_HOSTS_CLAIM).
+		WithHandler(GetOrchestratorReverseProxyStatsHandler()).
+		Register()
+
 	restapi.NewController().
 		WithMethod(restapi.GET).
 		WithVersion(version).
@@ -581,6 +654,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/reverse-proxy/hosts/{reverse_proxy_host_id}").
 		WithRequiredClaim(constants.LIST_REVERSE_PROXY_HOSTS_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, orchestratorReverseProxyHostResource)).
 		WithHandler(GetOrchestratorHostReverseProxyHostHandler()).
 		Register()
 
@@ -589,6 +663,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/reverse-proxy/hosts").
 		WithRequiredClaim(constants.CREATE_REVERSE_PROXY_HOST_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_CREATE, orchestratorReverseProxyHostResource)).
 		WithHandler(CreateOrchestratorHostReverseProxyHostHandler()).
 		Register()
 
@@ -597,6 +672,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/reverse-proxy/hosts/{reverse_proxy_host_id}").
 		WithRequiredClaim(constants.UPDATE_REVERSE_PROXY_HOST_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorReverseProxyHostResource)).
 		WithHandler(UpdateOrchestratorHostReverseProxyHostHandler()).
 		Register()
 
@@ -605,6 +681,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/reverse-proxy/hosts/{reverse_proxy_host_id}").
 		WithRequiredClaim(constants.DELETE_REVERSE_PROXY_HOST_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_DELETE, orchestratorReverseProxyHostResource)).
 		WithHandler(DeleteOrchestratorHostReverseProxyHostHandler()).
 		Register()
 
@@ -613,6 +690,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/reverse-proxy/hosts/{reverse_proxy_host_id}/http_routes").
 		WithRequiredClaim(constants.UPDATE_REVERSE_PROXY_HOST_HTTP_ROUTE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorReverseProxyHostResource)).
 		WithHandler(UpsertOrchestratorHostReverseProxyHostHttpRouteHandler()).
 		Register()
 
@@ -621,6 +699,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/reverse-proxy/hosts/{reverse_proxy_host_id}/http_routes/{route_id}").
 		WithRequiredClaim(constants.DELETE_REVERSE_PROXY_HOST_HTTP_ROUTE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorReverseProxyHostResource)).
 		WithHandler(DeleteOrchestratorHostReverseProxyHostHttpRouteHandler()).
 		Register()
 
@@ -629,14 +708,25 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/reverse-proxy/hosts/{reverse_proxy_host_id}/tcp_route").
 		WithRequiredClaim(constants.UPDATE_REVERSE_PROXY_HOST_TCP_ROUTE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorReverseProxyHostResource)).
 		WithHandler(UpdateOrchestratorHostReverseProxyHostTcpRouteHandler()).
 		Register()
 
+	restapi.NewController().
+		WithMethod(restapi.POST).
+		WithVersion(version).
+		WithPath("/orchestrator/hosts/{id}/reverse-proxy/hosts/{reverse_proxy_host_id}/udp_route").
+		WithRequiredClaim(constants.UPDATE_REVERSE_PROXY_HOST_UDP_ROUTE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorReverseProxyHostResource)).
+		WithHandler(UpdateOrchestratorHostReverseProxyHostUdpRouteHandler()).
+		Register()
+
 	restapi.NewController().
 		WithMethod(restapi.PUT).
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/reverse-proxy/restart").
 		WithRequiredClaim(constants.CONFIGURE_REVERSE_PROXY_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorHostResource)).
 		WithHandler(RestartsOrchestratorHostReverseProxyHandler()).
 		Register()
 
@@ -645,6 +735,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/reverse-proxy/enable").
 		WithRequiredClaim(constants.CONFIGURE_REVERSE_PROXY_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorHostResource)).
 		WithHandler(EnableOrchestratorHostReverseProxyHandler()).
 		Register()
 
@@ -653,6 +744,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/reverse-proxy/disable").
 		WithRequiredClaim(constants.CONFIGURE_REVERSE_PROXY_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, orchestratorHostResource)).
 		WithHandler(DisableOrchestratorHostReverseProxyHandler()).
 		Register()
 	// endregion
@@ -663,6 +755,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/logs/stream").
 		WithRequiredRole(constants.SUPER_USER_ROLE).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, orchestratorHostResource)).
 		WithHandler(StreamOrchestratorHostSystemLogs()).
 		Register()
 
@@ -671,6 +764,7 @@ func registerOrchestratorHostsHandlers(ctx basecontext.ApiContext, version strin
 		WithVersion(version).
 		WithPath("/orchestrator/hosts/{id}/logs").
 		WithRequiredRole(constants.SUPER_USER_ROLE).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, orchestratorHostResource)).
 		WithHandler(GetOrchestratorHostSystemLogs()).
 		Register()
 	// endregion
@@ -699,6 +793,12 @@ func GetOrchestratorHostsHandler() restapi.ControllerHandler {
 			ReturnApiError(ctx, w, models.NewFromError(err))
 			return
 		}
+		dtoOrchestratorHosts = filterOrchestratorHostsByApiKeyScope(ctx, dtoOrchestratorHosts)
+		dtoOrchestratorHosts, err = filterOrchestratorHostsByAccessPolicy(ctx, dtoOrchestratorHosts)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
 
 		if len(dtoOrchestratorHosts) == 0 {
 			w.WriteHeader(http.StatusOK)
@@ -1124,6 +1224,12 @@ func GetOrchestratorHostResourcesHandler() restapi.ControllerHandler {
 // @Description	This endpoint returns orchestrator Virtual Machines
 // @Tags			Orchestrator
 // @Produce		json
+// @Param			filter		query		string	false	"Filter expression, e.g. state = running and (name ~ ^build or cpu >= 4)"
+// @Param			sort		query		string	false	"Comma separated properties, prefix with - to sort descending"
+// @Param			page		query		int		false	"Page, starts at 1"
+// @Param			page_size	query		int		false	"Page size"
+// @Param			cursor		query		string	false	"Cursor token from the Link header"
+// @Param			fields		query		string	false	"Comma separated fields to return"
 // @Success		200	{object}	[]models.ParallelsVM
 // @Failure		400	{object}	models.ApiErrorResponse
 // @Failure		401	{object}	models.OAuthErrorResponse
@@ -1152,9 +1258,14 @@ func GetOrchestratorVirtualMachinesHandler() restapi.ControllerHandler {
 		for _, vm := range vms {
 			response = append(response, mappers.MapDtoVirtualMachineToApi(vm))
 		}
+		response = filterMachinesByApiKeyScope(ctx, response)
+		response, err = filterMachinesByAccessPolicy(ctx, response)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
 
-		w.WriteHeader(http.StatusAccepted)
-		_ = json.NewEncoder(w).Encode(response)
+		ReturnApiListResponse(ctx, w, r, response, http.StatusAccepted)
 		ctx.LogInfof("Returned %v virtual machines from all hosts", len(response))
 	}
 }
@@ -1229,6 +1340,7 @@ func DeleteOrchestratorVirtualMachineHandler() restapi.ControllerHandler {
 			ReturnApiError(ctx, w, models.NewFromError(err))
 			return
 		}
+		deleteResourceOwner(ctx, constants.ACCESS_POLICY_RESOURCE_MACHINE, id)
 
 		w.WriteHeader(http.StatusAccepted)
 		ctx.LogInfof("Successfully deleted the orchestrator virtual machine %s", id)
@@ -1782,6 +1894,11 @@ func GetOrchestratorHostVirtualMachinesHandler() restapi.ControllerHandler {
 		for _, vm := range vms {
 			response = append(response, mappers.MapDtoVirtualMachineToApi(*vm))
 		}
+		response, err = filterMachinesByAccessPolicy(ctx, response)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
 
 		w.WriteHeader(http.StatusAccepted)
 		_ = json.NewEncoder(w).Encode(response)
@@ -1864,6 +1981,7 @@ func DeleteOrchestratorHostVirtualMachineHandler() restapi.ControllerHandler {
 			ReturnApiError(ctx, w, models.NewFromError(err))
 			return
 		}
+		deleteResourceOwner(ctx, constants.ACCESS_POLICY_RESOURCE_MACHINE, vmId)
 
 		w.WriteHeader(http.StatusAccepted)
 		ctx.LogInfof("Successfully deleted the orchestrator virtual machine %s", vmId)
@@ -3035,6 +3153,7 @@ func CreateOrchestratorHostVirtualMachineHandler() restapi.ControllerHandler {
 			return
 		}
 
+		setCallerAsResourceOwner(ctx, constants.ACCESS_POLICY_RESOURCE_MACHINE, response.ID)
 		_ = jobManager.MarkJobCompleteWithRecord(job.ID, fmt.Sprintf("Virtual machine %s created on host %s", response.Name, id), response.ID, response.Name, "virtual_machine", response.Host)
 
 		w.WriteHeader(http.StatusOK)
@@ -3142,6 +3261,7 @@ func CreateOrchestratorVirtualMachineHandler() restapi.ControllerHandler {
 			return
 		}
 
+		setCallerAsResourceOwner(ctx, constants.ACCESS_POLICY_RESOURCE_MACHINE, response.ID)
 		_ = jobManager.MarkJobCompleteWithRecord(job.ID, fmt.Sprintf("Virtual machine %s created", response.ID), response.ID, response.Name, "virtual_machine", response.Host)
 
 		w.WriteHeader(http.StatusOK)
@@ -3185,6 +3305,68 @@ func GetOrchestratorHostReverseProxyConfigHandler() restapi.ControllerHandler {
 	}
 }
 
+// @Summary		Gets orchestrator host reverse proxy traffic statistics
+// @Description	This endpoint returns the traffic counters of the reverse proxy hosts and routes of an orchestrator host
+// @Tags			Orchestrator
+// @Produce		json
+// @Param			id	path		string	true	"Host ID"
+// @Success		200	{object}	models.ReverseProxyStats
+// @Failure		400	{object}	models.ApiErrorResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/orchestrator/hosts/{id}/reverse-proxy/stats [get]
+func GetOrchestratorHostReverseProxyStatsHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		vars := mux.Vars(r)
+		id := vars["id"]
+
+		orchestratorSvc := orchestrator.NewOrchestratorService(ctx)
+		response, err := orchestratorSvc.GetHostReverseProxyStats(ctx, id)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("Successfully got orchestrator host %s reverse proxy stats", id)
+	}
+}
+
+// @Summary		Gets orchestrator reverse proxy traffic statistics
+// @Description	This endpoint returns the reverse proxy traffic counters of every healthy orchestrator host and their totals
+// @Tags			Orchestrator
+// @Produce		json
+// @Success		200	{object}	models.OrchestratorReverseProxyStats
+// @Failure		400	{object}	models.ApiErrorResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/orchestrator/reverse-proxy/stats [get]
+func GetOrchestratorReverseProxyStatsHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		orchestratorSvc := orchestrator.NewOrchestratorService(ctx)
+		response, err := orchestratorSvc.GetReverseProxyStats(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("Successfully got orchestrator reverse proxy stats")
+	}
+}
+
 // @Summary		Gets orchestrator host reverse proxy hosts
 // @Description	This endpoint returns orchestrator host reverse proxy hosts
 // @Tags			Orchestrator
@@ -3211,6 +3393,11 @@ func GetOrchestratorHostReverseProxyHostsHandler() restapi.ControllerHandler {
 			ReturnApiError(ctx, w, models.NewFromError(err))
 			return
 		}
+		response, err = filterOrchestratorReverseProxyHostsByAccessPolicy(ctx, r, response)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
 
 		w.WriteHeader(http.StatusOK)
 		_ = json.NewEncoder(w).Encode(response)
@@ -3525,6 +3712,57 @@ func UpdateOrchestratorHostReverseProxyHostTcpRouteHandler() restapi.ControllerH
 	}
 }
 
+// @Summary		Update an orchestrator host reverse proxy host udp route
+// @Description	This endpoint updates an orchestrator host reverse proxy host udp route
+// @Tags			Orchestrator
+// @Produce		json
+// @Param			request	body		models.ReverseProxyHostUdpRouteCreateRequest	true	"Update Host Reverse Proxy Host udp Routes Request"
+// @Success		200		{object}	models.ReverseProxyHost
+// @Failure		400		{object}	models.ApiErrorResponse
+// @Failure		401		{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/orchestrator/hosts/{id}/reverse-proxy/hosts/{reverse_proxy_host_id}/udp_route [post]
+func UpdateOrchestratorHostReverseProxyHostUdpRouteHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		var request models.ReverseProxyHostUdpRouteCreateRequest
+
+		if err := http_helper.MapRequestBody(r, &request); err != nil {
+			ReturnApiError(ctx, w, models.ApiErrorResponse{
+				Message: "Invalid request body: " + err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+
+		if err := request.Validate(); err != nil {
+			ReturnApiError(ctx, w, models.ApiErrorResponse{
+				Message: "Invalid request body: " + err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+
+		vars := mux.Vars(r)
+		id := vars["id"]
+		rpHostId := vars["reverse_proxy_host_id"]
+
+		orchestratorSvc := orchestrator.NewOrchestratorService(ctx)
+		response, err := orchestratorSvc.UpdateHostReverseProxyHostUdpRoute(ctx, id, rpHostId, request)
+		if err != nil {
+			ReturnApiError(ctx, w, *err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("Successfully updated the orchestrator host reverse proxy host %s", response.ID)
+	}
+}
+
 // @Summary		Restarts orchestrator host reverse proxy
 // @Description	This endpoint restarts orchestrator host reverse proxy
 // @Tags			Orchestrator
@@ -4195,6 +4433,7 @@ func AsyncCreateOrchestratorVirtualMachineHandler() restapi.ControllerHandler {
 			ReturnApiError(ctx, w, models.ApiErrorResponse{Code: http.StatusUnauthorized, Message: "User not found"})
 			return
 		}
+		ownerId, ownerName := getCallerOwner(ctx)
 
 		var request models.CreateVirtualMachineRequest
 		if err := http_helper.MapRequestBody(r, &request); err != nil {
@@ -4285,6 +4524,7 @@ func AsyncCreateOrchestratorVirtualMachineHandler() restapi.ControllerHandler {
 						asyncCtx.LogInfof("[Temp API Key] Remote host will use temp key for job %s", jobID)
 						return
 					}
+					setResourceOwner(asyncCtx, constants.ACCESS_POLICY_RESOURCE_MACHINE, result.ID, ownerId, ownerName)
 					_ = jobManager.MarkJobCompleteWithRecord(jobID, fmt.Sprintf("Virtual machine %s created", result.ID), result.ID, result.Name, "virtual_machine", result.Host)
 				}(job.ID, request)
 
@@ -4332,6 +4572,7 @@ func AsyncCreateOrchestratorVirtualMachineHandler() restapi.ControllerHandler {
 				// Async dispatch succeeded — HostJobEventHandler will complete the job.
 				return
 			}
+			setResourceOwner(asyncCtx, constants.ACCESS_POLICY_RESOURCE_MACHINE, result.ID, ownerId, ownerName)
 			_ = jobManager.MarkJobCompleteWithRecord(jobID, fmt.Sprintf("Virtual machine %s created", result.ID), result.ID, result.Name, "virtual_machine", result.Host)
 		}(job.ID, callerID, request)
 
@@ -4365,6 +4606,7 @@ func AsyncCreateOrchestratorHostVirtualMachineHandler() restapi.ControllerHandle
 			ReturnApiError(ctx, w, models.ApiErrorResponse{Code: http.StatusUnauthorized, Message: "User not found"})
 			return
 		}
+		ownerId, ownerName := getCallerOwner(ctx)
 
 		vars := mux.Vars(r)
 		id := vars["id"]
@@ -4440,6 +4682,7 @@ func AsyncCreateOrchestratorHostVirtualMachineHandler() restapi.ControllerHandle
 			if err == nil {
 				hostName = dbHost.Description
 			}
+			setResourceOwner(asyncCtx, constants.ACCESS_POLICY_RESOURCE_MACHINE, result.ID, ownerId, ownerName)
 			_ = jobManager.MarkJobCompleteWithRecord(jobID, fmt.Sprintf("Virtual machine %s created on host %s", result.Name, hostName), result.ID, result.Name, "virtual_machine", result.Host)
 		}(job.ID, id, request)
 
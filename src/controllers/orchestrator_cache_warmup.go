@@ -0,0 +1,384 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/mappers"
+	"github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/orchestrator"
+	"github.com/Parallels/prl-devops-service/restapi"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+
+	"github.com/cjlapao/common-go/helper/http_helper"
+	"github.com/gorilla/mux"
+)
+
+func registerOrchestratorCacheWarmupHandlers(ctx basecontext.ApiContext, version string) {
+	ctx.LogInfof("Registering version %s Orchestrator Cache Warmup handlers", version)
+
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/orchestrator/cache/policies").
+		WithRequiredRole(constants.SUPER_USER_ROLE).
+		WithHandler(GetOrchestratorCacheWarmupPoliciesHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/orchestrator/cache/policies/{id}").
+		WithRequiredRole(constants.SUPER_USER_ROLE).
+		WithHandler(GetOrchestratorCacheWarmupPolicyHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.POST).
+		WithVersion(version).
+		WithPath("/orchestrator/cache/policies").
+		WithRequiredRole(constants.SUPER_USER_ROLE).
+		WithHandler(CreateOrchestratorCacheWarmupPolicyHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.PUT).
+		WithVersion(version).
+		WithPath("/orchestrator/cache/policies/{id}").
+		WithRequiredRole(constants.SUPER_USER_ROLE).
+		WithHandler(UpdateOrchestratorCacheWarmupPolicyHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.DELETE).
+		WithVersion(version).
+		WithPath("/orchestrator/cache/policies/{id}").
+		WithRequiredRole(constants.SUPER_USER_ROLE).
+		WithHandler(DeleteOrchestratorCacheWarmupPolicyHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/orchestrator/cache/policies/{id}/compliance").
+		WithRequiredRole(constants.SUPER_USER_ROLE).
+		WithHandler(GetOrchestratorCacheWarmupPolicyComplianceHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/orchestrator/hosts/{id}/cache/compliance").
+		WithRequiredRole(constants.SUPER_USER_ROLE).
+		WithHandler(GetOrchestratorHostCacheWarmupComplianceHandler()).
+		Register()
+}
+
+// @Summary		Gets all the cache warmup policies
+// @Description	This endpoint returns all the cache warmup policies of the orchestrator
+// @Tags			Orchestrator
+// @Produce		json
+// @Success		200	{object}	[]models.CacheWarmupPolicyResponse
+// @Failure		400	{object}	models.ApiErrorResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/orchestrator/cache/policies [get]
+func GetOrchestratorCacheWarmupPoliciesHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		policies, err := dbService.GetCacheWarmupPolicies(ctx, GetFilterHeader(r))
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(mappers.CacheWarmupPoliciesDtoToApi(policies))
+		ctx.LogInfof("Cache warmup policies returned: %v", len(policies))
+	}
+}
+
+// @Summary		Gets a cache warmup policy
+// @Description	This endpoint returns a cache warmup policy by id or name
+// @Tags			Orchestrator
+// @Produce		json
+// @Param			id	path		string	true	"Policy ID or Name"
+// @Success		200	{object}	models.CacheWarmupPolicyResponse
+// @Failure		400	{object}	models.ApiErrorResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/orchestrator/cache/policies/{id} [get]
+func GetOrchestratorCacheWarmupPolicyHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		vars := mux.Vars(r)
+		id := vars["id"]
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		policy, err := dbService.GetCacheWarmupPolicy(ctx, id)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(mappers.CacheWarmupPolicyDtoToApi(*policy))
+		ctx.LogInfof("Cache warmup policy %s returned", id)
+	}
+}
+
+// @Summary		Creates a cache warmup policy
+// @Description	This endpoint creates a cache warmup policy, hosts matching the policy tags will cache the catalog manifest in the background
+// @Tags			Orchestrator
+// @Produce		json
+// @Param			request	body		models.CacheWarmupPolicyRequest	true	"Cache Warmup Policy"
+// @Success		201		{object}	models.CacheWarmupPolicyResponse
+// @Failure		400		{object}	models.ApiErrorResponse
+// @Failure		401		{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/orchestrator/cache/policies [post]
+func CreateOrchestratorCacheWarmupPolicyHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		var request models.CacheWarmupPolicyRequest
+		if err := http_helper.MapRequestBody(r, &request); err != nil {
+			ReturnApiError(ctx, w, models.ApiErrorResponse{
+				Message: "Invalid request body: " + err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+		if err := request.Validate(); err != nil {
+			ReturnApiError(ctx, w, models.ApiErrorResponse{
+				Message: "Invalid request body: " + err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		policy, err := dbService.CreateCacheWarmupPolicy(ctx, mappers.CacheWarmupPolicyRequestToDto(request))
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		orchestratorSvc := orchestrator.NewOrchestratorService(ctx)
+		go orchestratorSvc.ApplyCacheWarmupPolicy(*policy)
+
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(mappers.CacheWarmupPolicyDtoToApi(*policy))
+		ctx.LogInfof("Cache warmup policy %s created", policy.Name)
+	}
+}
+
+// @Summary		Updates a cache warmup policy
+// @Description	This endpoint updates a cache warmup policy
+// @Tags			Orchestrator
+// @Produce		json
+// @Param			id		path		string							true	"Policy ID or Name"
+// @Param			request	body		models.CacheWarmupPolicyRequest	true	"Cache Warmup Policy"
+// @Success		200		{object}	models.CacheWarmupPolicyResponse
+// @Failure		400		{object}	models.ApiErrorResponse
+// @Failure		401		{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/orchestrator/cache/policies/{id} [put]
+func UpdateOrchestratorCacheWarmupPolicyHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		vars := mux.Vars(r)
+		id := vars["id"]
+
+		var request models.CacheWarmupPolicyRequest
+		if err := http_helper.MapRequestBody(r, &request); err != nil {
+			ReturnApiError(ctx, w, models.ApiErrorResponse{
+				Message: "Invalid request body: " + err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+		if err := request.Validate(); err != nil {
+			ReturnApiError(ctx, w, models.ApiErrorResponse{
+				Message: "Invalid request body: " + err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		existing, err := dbService.GetCacheWarmupPolicy(ctx, id)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		dto := mappers.CacheWarmupPolicyRequestToDto(request)
+		dto.ID = existing.ID
+		// the policy was read back with the credentials masked, keep the stored ones
+		if dto.Connection == mappers.ObfuscateConnectionString(existing.Connection) {
+			dto.Connection = existing.Connection
+		}
+		policy, err := dbService.UpdateCacheWarmupPolicy(ctx, dto)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		orchestratorSvc := orchestrator.NewOrchestratorService(ctx)
+		orchestratorSvc.RemoveCacheWarmupPolicyState(policy.ID)
+		go orchestratorSvc.ApplyCacheWarmupPolicy(*policy)
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(mappers.CacheWarmupPolicyDtoToApi(*policy))
+		ctx.LogInfof("Cache warmup policy %s updated", policy.Name)
+	}
+}
+
+// @Summary		Deletes a cache warmup policy
+// @Description	This endpoint deletes a cache warmup policy, items already cached on the hosts are kept
+// @Tags			Orchestrator
+// @Produce		json
+// @Param			id	path	string	true	"Policy ID or Name"
+// @Success		202
+// @Failure		400	{object}	models.ApiErrorResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/orchestrator/cache/policies/{id} [delete]
+func DeleteOrchestratorCacheWarmupPolicyHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		vars := mux.Vars(r)
+		id := vars["id"]
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		policy, err := dbService.GetCacheWarmupPolicy(ctx, id)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		if err := dbService.DeleteCacheWarmupPolicy(ctx, policy.ID); err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		orchestrator.NewOrchestratorService(ctx).RemoveCacheWarmupPolicyState(policy.ID)
+
+		w.WriteHeader(http.StatusAccepted)
+		ctx.LogInfof("Cache warmup policy %s deleted", policy.Name)
+	}
+}
+
+// @Summary		Gets the compliance of a cache warmup policy
+// @Description	This endpoint returns the cache state of the policy manifest on every host matching the policy
+// @Tags			Orchestrator
+// @Produce		json
+// @Param			id	path		string	true	"Policy ID or Name"
+// @Success		200	{object}	models.CacheWarmupComplianceResponse
+// @Failure		400	{object}	models.ApiErrorResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/orchestrator/cache/policies/{id}/compliance [get]
+func GetOrchestratorCacheWarmupPolicyComplianceHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		vars := mux.Vars(r)
+		id := vars["id"]
+
+		orchestratorSvc := orchestrator.NewOrchestratorService(ctx)
+		response, err := orchestratorSvc.GetCacheWarmupPolicyCompliance(ctx, id)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("Cache warmup policy %s compliance returned", id)
+	}
+}
+
+// @Summary		Gets the cache warmup compliance of a host
+// @Description	This endpoint returns the state of every cache warmup policy that applies to the host
+// @Tags			Orchestrator
+// @Produce		json
+// @Param			id	path		string	true	"Host ID"
+// @Success		200	{object}	models.CacheWarmupComplianceResponse
+// @Failure		400	{object}	models.ApiErrorResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/orchestrator/hosts/{id}/cache/compliance [get]
+func GetOrchestratorHostCacheWarmupComplianceHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+
+		vars := mux.Vars(r)
+		id := vars["id"]
+
+		orchestratorSvc := orchestrator.NewOrchestratorService(ctx)
+		response, err := orchestratorSvc.GetHostCacheWarmupCompliance(ctx, id)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("Host %s cache warmup compliance returned", id)
+	}
+}
@@ -39,50 +39,78 @@ func registerReverseProxyHandlers(ctx basecontext.ApiContext, version string) {
 		WithMethod(restapi.POST).
 		WithVersion(version).WithPath("/reverse-proxy/hosts").
 		WithRequiredClaim(constants.CREATE_REVERSE_PROXY_HOST_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_CREATE, newReverseProxyHostResource)).
 		WithHandler(CreateReverseProxyHostHandler()).
 		Register()
 	restapi.NewController().
 		WithMethod(restapi.GET).
 		WithVersion(version).WithPath("/reverse-proxy/hosts/{id}").
 		WithRequiredClaim(constants.LIST_REVERSE_PROXY_HOSTS_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, reverseProxyHostResource)).
 		WithHandler(GetReverseProxyHostHandler()).
 		Register()
 	restapi.NewController().
 		WithMethod(restapi.PUT).
 		WithVersion(version).WithPath("/reverse-proxy/hosts/{id}").
 		WithRequiredClaim(constants.UPDATE_REVERSE_PROXY_HOST_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, reverseProxyHostResource)).
 		WithHandler(UpdateReverseProxyHostHandler()).
 		Register()
 	restapi.NewController().
 		WithMethod(restapi.DELETE).
 		WithVersion(version).WithPath("/reverse-proxy/hosts/{id}").
 		WithRequiredClaim(constants.DELETE_REVERSE_PROXY_HOST_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_DELETE, reverseProxyHostResource)).
 		WithHandler(DeleteReverseProxyHostHandler()).
 		Register()
 	restapi.NewController().
 		WithMethod(restapi.POST).
 		WithVersion(version).WithPath("/reverse-proxy/hosts/{id}/http_routes").
 		WithRequiredClaim(constants.UPDATE_REVERSE_PROXY_HOST_HTTP_ROUTE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, reverseProxyHostResource)).
 		WithHandler(UpsertReverseProxyHostHttpRouteHandler()).
 		Register()
 	restapi.NewController().
 		WithMethod(restapi.DELETE).
 		WithVersion(version).WithPath("/reverse-proxy/hosts/{id}/http_routes/{http_route_id}").
 		WithRequiredClaim(constants.DELETE_REVERSE_PROXY_HOST_HTTP_ROUTE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, reverseProxyHostResource)).
 		WithHandler(DeleteReverseProxyHostHttpRoutesHandler()).
 		Register()
 	restapi.NewController().
 		WithMethod(restapi.PUT).
 		WithVersion(version).WithPath("/reverse-proxy/hosts/{id}/http_routes/order").
 		WithRequiredClaim(constants.UPDATE_REVERSE_PROXY_HOST_HTTP_ROUTE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, reverseProxyHostResource)).
 		WithHandler(UpdateReverseProxyHostHttpRouteOrderHandler()).
 		Register()
 	restapi.NewController().
 		WithMethod(restapi.POST).
 		WithVersion(version).WithPath("/reverse-proxy/hosts/{id}/tcp_route").
 		WithRequiredClaim(constants.UPDATE_REVERSE_PROXY_HOST_TCP_ROUTE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, reverseProxyHostResource)).
 		WithHandler(UpdateReverseProxyHostTcpRouteHandler()).
 		Register()
+	restapi.NewController().
+		WithMethod(restapi.POST).
+		WithVersion(version).WithPath("/reverse-proxy/hosts/{id}/udp_route").
+		WithRequiredClaim(constants.UPDATE_REVERSE_PROXY_HOST_UDP_ROUTE_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_UPDATE, reverseProxyHostResource)).
+		WithHandler(UpdateReverseProxyHostUdpRouteHandler()).
+		Register()
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).WithPath("/reverse-proxy/hosts/{id}/stats").
+		WithRequiredClaim(constants.LIST_REVERSE_PROXY_HOSTS_CLAIM).
+		WithExtraAdapter(withAccessPolicy(constants.ACCESS_POLICY_ACTION_READ, reverseProxyHostResource)).
+		WithHandler(GetReverseProxyHostStatsHandler()).
+		Register()
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).WithPath("/reverse-proxy/stats").
+		WithRequiredClaim(constants.LIST_REVERSE_PROXY_HOSTS_CLAIM).
+		WithHandler(GetReverseProxyStatsHandler()).
+		Register()
 	restapi.NewController().
 		WithMethod(restapi.PUT).
 		WithVersion(version).WithPath("/reverse-proxy/restart").
@@ -123,6 +151,22 @@ func enrichHostWithVmDetails(ctx basecontext.ApiContext, host *models.ReversePro
 		}
 	}
 
+	if host.UdpRoute != nil && host.UdpRoute.TargetVmId != "" {
+		vm, err := svc.GetVmSync(ctx, host.UdpRoute.TargetVmId)
+		if err == nil && vm != nil {
+			host.UdpRoute.TargetVmDetails = &models.ReverseProxyRouteVmDetails{
+				Name:                  vm.Name,
+				State:                 vm.State,
+				OS:                    vm.OS,
+				Uptime:                vm.Uptime,
+				GuestToolsState:       vm.GuestTools.State,
+				GuestToolsVersion:     vm.GuestTools.Version,
+				InternalIpAddress:     vm.InternalIpAddress,
+				HostExternalIpAddress: vm.HostExternalIpAddress,
+			}
+		}
+	}
+
 	for _, route := range host.HttpRoutes {
 		if route.TargetVmId != "" {
 			vm, err := svc.GetVmSync(ctx, route.TargetVmId)
@@ -175,6 +219,12 @@ func GetReverseProxyConfigHandler() restapi.ControllerHandler {
 // @Description	This endpoint returns all the reverse proxy hosts
 // @Tags			ReverseProxy
 // @Produce		json
+// @Param			filter		query		string	false	"Filter expression, e.g. state = running and (name ~ ^build or cpu >= 4)"
+// @Param			sort		query		string	false	"Comma separated properties, prefix with - to sort descending"
+// @Param			page		query		int		false	"Page, starts at 1"
+// @Param			page_size	query		int		false	"Page size"
+// @Param			cursor		query		string	false	"Cursor token from the Link header"
+// @Param			fields		query		string	false	"Comma separated fields to return"
 // @Success		200	{object}	[]models.ReverseProxyHost
 // @Failure		400	{object}	models.ApiErrorResponse
 // @Failure		401	{object}	models.OAuthErrorResponse
@@ -205,13 +255,17 @@ func GetReverseProxyHostsHandler() restapi.ControllerHandler {
 		}
 
 		result := mappers.DtoReverseProxyHostsToApi(dtoRpHosts)
+		result, err = filterReverseProxyHostsByAccessPolicy(ctx, result)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
 
 		for i := range result {
 			enrichHostWithVmDetails(ctx, &result[i])
 		}
 
-		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(result)
+		ReturnApiListResponse(ctx, w, r, result, http.StatusOK)
 		ctx.LogInfof("Claims returned successfully")
 	}
 }
@@ -317,6 +371,8 @@ func CreateReverseProxyHostHandler() restapi.ControllerHandler {
 
 		response := mappers.DtoReverseProxyHostToApi(*resultDto)
 		enrichHostWithVmDetails(ctx, &response)
+		setCallerAsResourceOwner(ctx, constants.ACCESS_POLICY_RESOURCE_REVERSE_PROXY_HOST, response.ID)
+		auditChange(r, response.ID, nil, response)
 
 		rps := reverse_proxy.Get(ctx)
 		if err := rps.Restart(); err != nil {
@@ -385,16 +441,23 @@ func UpdateReverseProxyHostHandler() restapi.ControllerHandler {
 			ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("reverse proxy host not found"), http.StatusNotFound))
 			return
 		}
+		before := mappers.DtoReverseProxyHostToApi(*dtoHost)
 
-		if dtoHost.TcpRoute != nil {
+		if dtoHost.TcpRoute != nil || dtoHost.UdpRoute != nil {
 			if request.Cors != nil {
-				ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("cors is not allowed for tcp route"), http.StatusBadRequest))
+				ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("cors is not allowed for tcp and udp routes"), http.StatusBadRequest))
 				return
 			}
 			if request.Tls != nil {
-				ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("tls is not allowed for tcp route"), http.StatusBadRequest))
+				ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("tls is not allowed for tcp and udp routes"), http.StatusBadRequest))
 				return
 			}
+			if request.Auth != nil {
+				if err := request.Auth.Validate(true); err != nil {
+					ReturnApiError(ctx, w, models.NewFromError(err))
+					return
+				}
+			}
 		}
 
 		mappedDtoHost := mappers.ApiUpdateRequestReverseProxyHostToDto(request)
@@ -414,6 +477,7 @@ func UpdateReverseProxyHostHandler() restapi.ControllerHandler {
 
 		response := mappers.DtoReverseProxyHostToApi(*resultDto)
 		enrichHostWithVmDetails(ctx, &response)
+		auditChange(r, id, before, mappers.DtoReverseProxyHostToApi(*resultDto))
 
 		w.WriteHeader(http.StatusCreated)
 		_ = json.NewEncoder(w).Encode(response)
@@ -451,11 +515,19 @@ func DeleteReverseProxyHostHandler() restapi.ControllerHandler {
 		vars := mux.Vars(r)
 		id := vars["id"]
 
+		var before *models.ReverseProxyHost
+		if dtoHost, err := dbService.GetReverseProxyHost(ctx, id); err == nil && dtoHost != nil {
+			host := mappers.DtoReverseProxyHostToApi(*dtoHost)
+			before = &host
+		}
+
 		err = dbService.DeleteReverseProxyHost(ctx, id)
 		if err != nil {
 			ReturnApiError(ctx, w, models.NewFromError(err))
 			return
 		}
+		deleteResourceOwner(ctx, constants.ACCESS_POLICY_RESOURCE_REVERSE_PROXY_HOST, id)
+		auditChange(r, id, before, nil)
 
 		rps := reverse_proxy.Get(ctx)
 		if err := rps.Restart(); err != nil {
@@ -525,10 +597,16 @@ func UpsertReverseProxyHostHttpRouteHandler() restapi.ControllerHandler {
 			return
 		}
 
+		before := mappers.DtoReverseProxyHostToApi(*dtoHost)
+
 		if dtoHost.TcpRoute != nil {
 			ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("cannot update reverse proxy HTTP route when TCP routes are present"), http.StatusBadRequest))
 			return
 		}
+		if dtoHost.UdpRoute != nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("cannot update reverse proxy HTTP route when UDP routes are present"), http.StatusBadRequest))
+			return
+		}
 
 		httpRouteID := ""
 		for i, httpRoute := range dtoHost.HttpRoutes {
@@ -564,6 +642,7 @@ func UpsertReverseProxyHostHttpRouteHandler() restapi.ControllerHandler {
 
 		dtoHost, _ = dbService.GetReverseProxyHost(ctx, id)
 		response := mappers.DtoReverseProxyHostToApi(*dtoHost)
+		auditChange(r, id, before, response)
 		enrichHostWithVmDetails(ctx, &response)
 
 		rps := reverse_proxy.Get(ctx)
@@ -610,11 +689,20 @@ func DeleteReverseProxyHostHttpRoutesHandler() restapi.ControllerHandler {
 		id := vars["id"]
 		httpRouteID := vars["http_route_id"]
 
+		var before *models.ReverseProxyHost
+		if dtoHost, err := dbService.GetReverseProxyHost(ctx, id); err == nil && dtoHost != nil {
+			host := mappers.DtoReverseProxyHostToApi(*dtoHost)
+			before = &host
+		}
+
 		err = dbService.DeleteReverseProxyHostHttpRoute(ctx, id, httpRouteID)
 		if err != nil {
 			ReturnApiError(ctx, w, models.NewFromError(err))
 			return
 		}
+		if dtoHost, err := dbService.GetReverseProxyHost(ctx, id); err == nil && dtoHost != nil {
+			auditChange(r, id, before, mappers.DtoReverseProxyHostToApi(*dtoHost))
+		}
 
 		rps := reverse_proxy.Get(ctx)
 		if err := rps.Restart(); err != nil {
@@ -756,6 +844,8 @@ func UpdateReverseProxyHostTcpRouteHandler() restapi.ControllerHandler {
 			return
 		}
 
+		before := mappers.DtoReverseProxyHostToApi(*dtoHost)
+
 		if dtoHost.HttpRoutes != nil {
 			ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("cannot update reverse proxy TCP route when HTTP routes are present"), http.StatusBadRequest))
 			return
@@ -780,6 +870,7 @@ func UpdateReverseProxyHostTcpRouteHandler() restapi.ControllerHandler {
 
 		dtoHost, _ = dbService.GetReverseProxyHost(ctx, id)
 		response := mappers.DtoReverseProxyHostToApi(*dtoHost)
+		auditChange(r, id, before, response)
 		enrichHostWithVmDetails(ctx, &response)
 
 		rps := reverse_proxy.Get(ctx)
@@ -794,6 +885,211 @@ func UpdateReverseProxyHostTcpRouteHandler() restapi.ControllerHandler {
 	}
 }
 
+// @Summary		Updates a reverse proxy host UDP route
+// @Description	This endpoint updates a reverse proxy host UDP route
+// @Tags			ReverseProxy
+// @Produce		json
+// @Param			id								path		string										true	"Reverse Proxy Host ID"
+// @Param			reverse_proxy_udp_route_request	body		models.ReverseProxyHostUdpRouteCreateRequest	true	"Reverse Host Request"
+// @Success		200								{object}	models.ReverseProxyHost
+// @Failure		400								{object}	models.ApiErrorResponse
+// @Failure		401								{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/reverse-proxy/hosts/{id}/udp_route [post]
+func UpdateReverseProxyHostUdpRouteHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		var request models.ReverseProxyHostUdpRouteCreateRequest
+		if err := http_helper.MapRequestBody(r, &request); err != nil {
+			ReturnApiError(ctx, w, models.ApiErrorResponse{
+				Message: "Invalid request body: " + err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+		if err := request.Validate(); err != nil {
+			ReturnApiError(ctx, w, models.ApiErrorResponse{
+				Message: "Invalid request body: " + err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+		cfg := config.Get()
+		if !cfg.IsReverseProxyEnabled() {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("reverse proxy is disabled"), http.StatusBadRequest))
+			return
+		}
+
+		vars := mux.Vars(r)
+		id := vars["id"]
+
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(err, http.StatusInternalServerError))
+			return
+		}
+
+		dtoHost, err := dbService.GetReverseProxyHost(ctx, id)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		if dtoHost == nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("reverse proxy host not found"), http.StatusNotFound))
+			return
+		}
+
+		before := mappers.DtoReverseProxyHostToApi(*dtoHost)
+
+		if dtoHost.HttpRoutes != nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("cannot update reverse proxy UDP route when HTTP routes are present"), http.StatusBadRequest))
+			return
+		}
+
+		dtoUpdateUdpRoute := mappers.ApiReverseProxyHostUdpRouteCreateRequestToDto(request)
+		if request.TargetVmId != "" {
+			dtoUpdateUdpRoute.TargetHost = ""
+		} else {
+			dtoUpdateUdpRoute.TargetVmId = ""
+		}
+		resultDto, resultErr := dbService.UpdateReverseProxyHostUdpRoute(ctx, id, dtoUpdateUdpRoute)
+		if resultErr != nil {
+			ReturnApiError(ctx, w, models.NewFromError(resultErr))
+			return
+		}
+		if resultDto == nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("reverse proxy host udp route was not updated successfully"), http.StatusNotFound))
+			return
+		}
+
+		dtoHost, _ = dbService.GetReverseProxyHost(ctx, id)
+		response := mappers.DtoReverseProxyHostToApi(*dtoHost)
+		auditChange(r, id, before, response)
+		enrichHostWithVmDetails(ctx, &response)
+
+		rps := reverse_proxy.Get(ctx)
+		if err := rps.Restart(); err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("Reverse Proxy Host UDP Route upserted successfully")
+	}
+}
+
+// @Summary		Gets the traffic statistics of a reverse proxy host
+// @Description	This endpoint returns the request, error, status, bytes and latency counters of the routes of a reverse proxy host
+// @Tags			ReverseProxy
+// @Produce		json
+// @Param			id	path		string	true	"Reverse Proxy Host ID"
+// @Success		200	{object}	models.ReverseProxyHostStats
+// @Failure		400	{object}	models.ApiErrorResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/reverse-proxy/hosts/{id}/stats  [get]
+func GetReverseProxyHostStatsHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(err, http.StatusInternalServerError))
+			return
+		}
+
+		cfg := config.Get()
+		if !cfg.IsReverseProxyEnabled() {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("reverse proxy is disabled"), http.StatusBadRequest))
+			return
+		}
+
+		vars := mux.Vars(r)
+		id := vars["id"]
+
+		dtoRpHost, err := dbService.GetReverseProxyHost(ctx, id)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		result := reverse_proxy.Get(ctx).GetHostStats(*dtoRpHost)
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(result)
+		ctx.LogInfof("Reverse Proxy Host stats returned successfully")
+	}
+}
+
+// @Summary		Gets the traffic statistics of the reverse proxy
+// @Description	This endpoint returns the traffic counters of every reverse proxy host and their totals
+// @Tags			ReverseProxy
+// @Produce		json
+// @Success		200	{object}	models.ReverseProxyStats
+// @Failure		400	{object}	models.ApiErrorResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/reverse-proxy/stats [get]
+func GetReverseProxyStatsHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(err, http.StatusInternalServerError))
+			return
+		}
+
+		cfg := config.Get()
+		if !cfg.IsReverseProxyEnabled() {
+			ReturnApiError(ctx, w, models.NewFromErrorWithCode(errors.New("reverse proxy is disabled"), http.StatusBadRequest))
+			return
+		}
+
+		dtoRpHosts, err := dbService.GetReverseProxyHosts(ctx, "")
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+
+		allowedHosts, err := filterReverseProxyHostsByAccessPolicy(ctx, mappers.DtoReverseProxyHostsToApi(dtoRpHosts))
+		if err != nil {
+			ReturnApiError(ctx, w, models.NewFromError(err))
+			return
+		}
+		allowed := make(map[string]bool)
+		for _, host := range allowedHosts {
+			allowed[host.ID] = true
+		}
+
+		rps := reverse_proxy.Get(ctx)
+		result := models.ReverseProxyStats{
+			Hosts: make([]models.ReverseProxyHostStats, 0),
+		}
+		for _, dtoRpHost := range dtoRpHosts {
+			if !allowed[dtoRpHost.ID] {
+				continue
+			}
+			hostStats := rps.GetHostStats(dtoRpHost)
+			result.Totals.Add(hostStats.Totals)
+			result.Hosts = append(result.Hosts, hostStats)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(result)
+		ctx.LogInfof("Reverse Proxy stats returned successfully")
+	}
+}
+
 // @Summary		Restarts the reverse proxy
 // @Description	This endpoint will restart the reverse proxy
 // @Tags			ReverseProxy
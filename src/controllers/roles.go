@@ -8,6 +8,7 @@ import (
 
 	"github.com/Parallels/prl-devops-service/basecontext"
 	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
 	"github.com/Parallels/prl-devops-service/errors"
 	"github.com/Parallels/prl-devops-service/helpers"
 	"github.com/Parallels/prl-devops-service/mappers"
@@ -219,6 +220,7 @@ func CreateRoleHandler() restapi.ControllerHandler {
 		}
 
 		response := mappers.DtoRoleToApi(*role)
+		auditChange(r, response.ID, nil, response)
 
 		emitAuthEvent(constants.EventAuthRoleAdded, models.AuthRoleEvent{RoleID: response.ID})
 		w.WriteHeader(http.StatusCreated)
@@ -256,6 +258,7 @@ func DeleteRoleHandler() restapi.ControllerHandler {
 		vars := mux.Vars(r)
 		id := vars["id"]
 
+		before := getAuditRole(ctx, dbService, id)
 		err = dbService.DeleteRole(ctx, id)
 		if err != nil {
 			rsp := models.NewFromError(err)
@@ -264,6 +267,7 @@ func DeleteRoleHandler() restapi.ControllerHandler {
 			return
 		}
 
+		auditChange(r, id, before, nil)
 		emitAuthEvent(constants.EventAuthRoleRemoved, models.AuthRoleEvent{RoleID: id})
 		w.WriteHeader(http.StatusAccepted)
 		ctx.LogInfof("Role deleted successfully")
@@ -358,6 +362,7 @@ func AddClaimToRoleHandler() restapi.ControllerHandler {
 		vars := mux.Vars(r)
 		id := vars["id"]
 
+		before := getAuditRole(ctx, dbService, id)
 		if err := dbService.AddClaimToRole(ctx, id, request.Name); err != nil {
 			rsp := models.NewFromError(err)
 			diag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "DatabaseService")
@@ -373,6 +378,8 @@ func AddClaimToRoleHandler() restapi.ControllerHandler {
 			return
 		}
 
+		auditChange(r, id, before, mappers.DtoRoleToApi(*dtoRole))
+
 		// Return the specific claim that was added.
 		claimName := strings.ToUpper(helpers.NormalizeString(request.Name))
 		for _, c := range dtoRole.Claims {
@@ -420,6 +427,7 @@ func RemoveClaimFromRoleHandler() restapi.ControllerHandler {
 		id := vars["id"]
 		claimId := vars["claim_id"]
 
+		before := getAuditRole(ctx, dbService, id)
 		if err := dbService.RemoveClaimFromRole(ctx, id, claimId); err != nil {
 			rsp := models.NewFromError(err)
 			diag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "DatabaseService")
@@ -427,8 +435,21 @@ func RemoveClaimFromRoleHandler() restapi.ControllerHandler {
 			return
 		}
 
+		auditChange(r, id, before, getAuditRole(ctx, dbService, id))
 		emitAuthEvent(constants.EventAuthRoleClaimRemoved, models.AuthRoleClaimEvent{RoleID: id, ClaimID: claimId})
 		w.WriteHeader(http.StatusAccepted)
 		ctx.LogInfof("Claim %s removed from role %s", claimId, id)
 	}
 }
+
+// getAuditRole returns the role as the api returns it for the audit log, nil
+// if it does not exist
+func getAuditRole(ctx basecontext.ApiContext, dbService *data.JsonDatabase, id string) *models.RoleResponse {
+	dtoRole, err := dbService.GetRole(ctx, id)
+	if err != nil || dtoRole == nil {
+		return nil
+	}
+
+	role := mappers.DtoRoleToApi(*dtoRole)
+	return &role
+}
@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/mappers"
+	"github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/restapi"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+
+	"github.com/gorilla/mux"
+)
+
+func registerSessionsHandlers(ctx basecontext.ApiContext, version string) {
+	ctx.LogInfof("Registering version %s Sessions handlers", version)
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/auth/sessions").
+		WithAuthorization().
+		WithHandler(GetUserSessionsHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.DELETE).
+		WithVersion(version).
+		WithPath("/auth/sessions").
+		WithAuthorization().
+		WithHandler(RevokeUserSessionsHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.DELETE).
+		WithVersion(version).
+		WithPath("/auth/sessions/{session_id}").
+		WithAuthorization().
+		WithHandler(RevokeUserSessionHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.GET).
+		WithVersion(version).
+		WithPath("/auth/users/{id}/sessions").
+		WithRequiredClaim(constants.LIST_USER_CLAIM).
+		WithHandler(GetUserSessionsHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.DELETE).
+		WithVersion(version).
+		WithPath("/auth/users/{id}/sessions").
+		WithRequiredClaim(constants.UPDATE_USER_CLAIM).
+		WithHandler(RevokeUserSessionsHandler()).
+		Register()
+
+	restapi.NewController().
+		WithMethod(restapi.DELETE).
+		WithVersion(version).
+		WithPath("/auth/users/{id}/sessions/{session_id}").
+		WithRequiredClaim(constants.UPDATE_USER_CLAIM).
+		WithHandler(RevokeUserSessionHandler()).
+		Register()
+}
+
+// @Summary		Gets the sessions of a user
+// @Description	This endpoint returns the active sessions of the user of the request, or of the given user
+// @Tags			Sessions
+// @Produce		json
+// @Param			id	path	string	false	"User ID"
+// @Success		200	{object}	[]models.UserSessionResponse
+// @Failure		400	{object}	models.ApiErrorDiagnosticsResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/auth/sessions [get]
+// @Router			/v1/auth/users/{id}/sessions [get]
+func GetUserSessionsHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		getSessionsDiag := errors.NewDiagnostics("/auth/sessions")
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			getSessionsDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "ServiceProvider")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(getSessionsDiag, rsp.Code))
+			return
+		}
+
+		userId, err := getSessionsUserId(ctx, dbService, r)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			getSessionsDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "GetUser")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(getSessionsDiag, rsp.Code))
+			return
+		}
+
+		sessions, err := dbService.GetUserSessions(ctx, userId)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			getSessionsDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "GetUserSessions")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(getSessionsDiag, rsp.Code))
+			return
+		}
+
+		response := mappers.DtoUserSessionsToApiResponse(sessions)
+		if authContext := ctx.GetAuthorizationContext(); authContext != nil && authContext.SessionId != "" {
+			for i := range response {
+				response[i].Current = response[i].ID == authContext.SessionId
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(response)
+		ctx.LogInfof("Sessions returned: %v", len(response))
+	}
+}
+
+// @Summary		Revokes the sessions of a user
+// @Description	This endpoint logs the user out everywhere, the sessions of the user and the tokens issued before are revoked
+// @Tags			Sessions
+// @Produce		json
+// @Param			id	path	string	false	"User ID"
+// @Success		200	{object}	models.RevokeSessionsResponse
+// @Failure		400	{object}	models.ApiErrorDiagnosticsResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/auth/sessions [delete]
+// @Router			/v1/auth/users/{id}/sessions [delete]
+func RevokeUserSessionsHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		revokeSessionsDiag := errors.NewDiagnostics("/auth/sessions [delete]")
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			revokeSessionsDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "ServiceProvider")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(revokeSessionsDiag, rsp.Code))
+			return
+		}
+
+		userId, err := getSessionsUserId(ctx, dbService, r)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			revokeSessionsDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "GetUser")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(revokeSessionsDiag, rsp.Code))
+			return
+		}
+
+		reason := constants.SESSION_REVOKED_BY_USER
+		if mux.Vars(r)["id"] != "" {
+			reason = constants.SESSION_REVOKED_BY_ADMINISTRATOR
+		}
+		count, err := dbService.RevokeUserSessions(ctx, userId, reason)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			revokeSessionsDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "RevokeUserSessions")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(revokeSessionsDiag, rsp.Code))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(models.RevokeSessionsResponse{Revoked: count})
+		ctx.LogInfof("Sessions of user %v revoked: %v", userId, count)
+	}
+}
+
+// @Summary		Revokes a session
+// @Description	This endpoint revokes a session of the user of the request, or of the given user
+// @Tags			Sessions
+// @Produce		json
+// @Param			id			path	string	false	"User ID"
+// @Param			session_id	path	string	true	"Session ID"
+// @Success		202
+// @Failure		400	{object}	models.ApiErrorDiagnosticsResponse
+// @Failure		401	{object}	models.OAuthErrorResponse
+// @Failure		404	{object}	models.ApiErrorDiagnosticsResponse
+// @Security		ApiKeyAuth
+// @Security		BearerAuth
+// @Router			/v1/auth/sessions/{session_id} [delete]
+// @Router			/v1/auth/users/{id}/sessions/{session_id} [delete]
+func RevokeUserSessionHandler() restapi.ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := GetBaseContext(r)
+		defer Recover(ctx, r, w)
+		vars := mux.Vars(r)
+		sessionId := vars["session_id"]
+		revokeSessionDiag := errors.NewDiagnostics("/auth/sessions/" + sessionId + " [delete]")
+		dbService, err := serviceprovider.GetDatabaseService(ctx)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			revokeSessionDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "ServiceProvider")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(revokeSessionDiag, rsp.Code))
+			return
+		}
+
+		userId, err := getSessionsUserId(ctx, dbService, r)
+		if err != nil {
+			rsp := models.NewFromError(err)
+			revokeSessionDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "GetUser")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(revokeSessionDiag, rsp.Code))
+			return
+		}
+
+		// the sessions of other users are reported as not found
+		session, err := dbService.GetUserSession(ctx, sessionId)
+		if err == nil && !strings.EqualFold(session.UserID, userId) {
+			err = data.ErrUserSessionNotFound
+		}
+		if err != nil {
+			rsp := models.NewFromError(err)
+			revokeSessionDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "GetUserSession")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(revokeSessionDiag, rsp.Code))
+			return
+		}
+
+		reason := constants.SESSION_REVOKED_BY_USER
+		if vars["id"] != "" {
+			reason = constants.SESSION_REVOKED_BY_ADMINISTRATOR
+		}
+		if err := dbService.RevokeUserSession(ctx, session.ID, reason); err != nil {
+			rsp := models.NewFromError(err)
+			revokeSessionDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "RevokeUserSession")
+			ReturnApiErrorWithDiagnostics(ctx, w, models.NewDiagnosticsWithCode(revokeSessionDiag, rsp.Code))
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		ctx.LogInfof("Session %v revoked", session.ID)
+	}
+}
+
+// getSessionsUserId returns the user in the path or the user of the request,
+// api keys that are not linked to a user have no sessions
+func getSessionsUserId(ctx basecontext.ApiContext, dbService *data.JsonDatabase, r *http.Request) (string, error) {
+	if id := mux.Vars(r)["id"]; id != "" {
+		user, err := dbService.GetUser(ctx, id)
+		if err != nil {
+			return "", err
+		}
+
+		return user.ID, nil
+	}
+
+	authContext := ctx.GetAuthorizationContext()
+	if authContext == nil || authContext.User == nil {
+		return "", errors.NewWithCode("the request is not authenticated as a user", http.StatusBadRequest)
+	}
+
+	return authContext.User.ID, nil
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/Parallels/prl-devops-service/basecontext"
 	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
 	"github.com/Parallels/prl-devops-service/errors"
 	"github.com/Parallels/prl-devops-service/mappers"
 	"github.com/Parallels/prl-devops-service/models"
@@ -113,6 +114,12 @@ func registerUsersHandlers(ctx basecontext.ApiContext, version string) {
 // @Description	This endpoint returns all the users
 // @Tags			Users
 // @Produce		json
+// @Param			filter		query		string	false	"Filter expression, e.g. state = running and (name ~ ^build or cpu >= 4)"
+// @Param			sort		query		string	false	"Comma separated properties, prefix with - to sort descending"
+// @Param			page		query		int		false	"Page, starts at 1"
+// @Param			page_size	query		int		false	"Page size"
+// @Param			cursor		query		string	false	"Cursor token from the Link header"
+// @Param			fields		query		string	false	"Comma separated fields to return"
 // @Success		200	{object}	[]models.ApiUser
 // @Failure		400	{object}	models.ApiErrorDiagnosticsResponse
 // @Failure		401	{object}	models.OAuthErrorResponse
@@ -141,19 +148,12 @@ func GetUsersHandler() restapi.ControllerHandler {
 			return
 		}
 
-		if len(users) == 0 {
-			w.WriteHeader(http.StatusOK)
-			response := make([]models.ApiUser, 0)
-			defer r.Body.Close()
-			_ = json.NewEncoder(w).Encode(response)
-			ctx.LogInfof("Users returned: %v", len(response))
-			return
+		result := make([]models.ApiUser, 0)
+		if len(users) > 0 {
+			result = mappers.DtoUsersToApiResponse(users)
 		}
 
-		result := mappers.DtoUsersToApiResponse(users)
-
-		w.WriteHeader(http.StatusOK)
-		_ = json.NewEncoder(w).Encode(result)
+		ReturnApiListResponse(ctx, w, r, result, http.StatusOK)
 		ctx.LogInfof("Users returned: %v", len(result))
 	}
 }
@@ -320,6 +320,7 @@ func CreateUserHandler() restapi.ControllerHandler {
 
 		response := mappers.DtoUserToApiResponse(*dtoUser)
 
+		auditChange(r, response.ID, nil, response)
 		emitAuthEvent(constants.EventAuthUserAdded, models.AuthUserEvent{UserID: response.ID})
 		w.WriteHeader(http.StatusCreated)
 		_ = json.NewEncoder(w).Encode(response)
@@ -354,6 +355,7 @@ func DeleteUserHandler() restapi.ControllerHandler {
 			return
 		}
 
+		before := getAuditUser(ctx, dbService, id)
 		err = dbService.DeleteUser(ctx, id)
 		if err != nil {
 			rsp := models.NewFromError(err)
@@ -362,6 +364,7 @@ func DeleteUserHandler() restapi.ControllerHandler {
 			return
 		}
 
+		auditChange(r, id, before, nil)
 		emitAuthEvent(constants.EventAuthUserRemoved, models.AuthUserEvent{UserID: id})
 		w.WriteHeader(http.StatusAccepted)
 		ctx.LogInfof("User deleted: %v", id)
@@ -407,6 +410,7 @@ func UpdateUserHandler() restapi.ControllerHandler {
 			return
 		}
 
+		before := getAuditUser(ctx, dbService, id)
 		dtoUser := mappers.ApiUserUpdateRequestToDto(request)
 		dtoUser.ID = id
 		err = dbService.UpdateUser(ctx, dtoUser)
@@ -417,6 +421,7 @@ func UpdateUserHandler() restapi.ControllerHandler {
 			return
 		}
 
+		auditChange(r, id, before, getAuditUser(ctx, dbService, id))
 		emitAuthEvent(constants.EventAuthUserUpdated, models.AuthUserEvent{UserID: id})
 		w.WriteHeader(http.StatusAccepted)
 		ctx.LogInfof("User updated: %v", id)
@@ -507,6 +512,7 @@ func AddRoleToUserHandler() restapi.ControllerHandler {
 			return
 		}
 
+		before := getAuditUser(ctx, dbService, id)
 		if err := dbService.AddRoleToUser(ctx, id, request.Name); err != nil {
 			rsp := models.NewFromError(err)
 			addRoleToUserDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "AddRoleToUser")
@@ -514,6 +520,7 @@ func AddRoleToUserHandler() restapi.ControllerHandler {
 			return
 		}
 
+		auditChange(r, id, before, getAuditUser(ctx, dbService, id))
 		emitAuthEvent(constants.EventAuthUserRoleAdded, models.AuthUserRoleEvent{UserID: id, RoleID: request.Name})
 		w.WriteHeader(http.StatusCreated)
 		_ = json.NewEncoder(w).Encode(request)
@@ -550,6 +557,7 @@ func RemoveRoleFromUserHandler() restapi.ControllerHandler {
 			return
 		}
 
+		before := getAuditUser(ctx, dbService, id)
 		if err = dbService.RemoveRoleFromUser(ctx, id, roleId); err != nil {
 			rsp := models.NewFromError(err)
 			removeRoleFromUserDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "RemoveRoleFromUser")
@@ -557,6 +565,7 @@ func RemoveRoleFromUserHandler() restapi.ControllerHandler {
 			return
 		}
 
+		auditChange(r, id, before, getAuditUser(ctx, dbService, id))
 		emitAuthEvent(constants.EventAuthUserRoleRemoved, models.AuthUserRoleEvent{UserID: id, RoleID: roleId})
 		w.WriteHeader(http.StatusAccepted)
 		ctx.LogInfof("Role removed from user: %v", id)
@@ -647,6 +656,7 @@ func AddClaimToUserHandler() restapi.ControllerHandler {
 			return
 		}
 
+		before := getAuditUser(ctx, dbService, id)
 		if err := dbService.AddClaimToUser(ctx, id, request.Name); err != nil {
 			rsp := models.NewFromError(err)
 			addClaimToUserDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "AddClaimToUser")
@@ -654,6 +664,7 @@ func AddClaimToUserHandler() restapi.ControllerHandler {
 			return
 		}
 
+		auditChange(r, id, before, getAuditUser(ctx, dbService, id))
 		emitAuthEvent(constants.EventAuthUserClaimAdded, models.AuthUserClaimEvent{UserID: id, ClaimID: request.Name})
 		w.WriteHeader(http.StatusCreated)
 		_ = json.NewEncoder(w).Encode(request)
@@ -690,6 +701,7 @@ func RemoveClaimFromUserHandler() restapi.ControllerHandler {
 			return
 		}
 
+		before := getAuditUser(ctx, dbService, id)
 		if err = dbService.RemoveClaimFromUser(ctx, id, claimId); err != nil {
 			rsp := models.NewFromError(err)
 			removeClaimFromUserDiag.AddError(strconv.Itoa(rsp.Code), rsp.Message, "RemoveClaimFromUser")
@@ -697,8 +709,21 @@ func RemoveClaimFromUserHandler() restapi.ControllerHandler {
 			return
 		}
 
+		auditChange(r, id, before, getAuditUser(ctx, dbService, id))
 		emitAuthEvent(constants.EventAuthUserClaimRemoved, models.AuthUserClaimEvent{UserID: id, ClaimID: claimId})
 		w.WriteHeader(http.StatusAccepted)
 		ctx.LogInfof("Claim removed from user: %v", id)
 	}
 }
+
+// getAuditUser returns the user as the api returns it for the audit log, nil
+// if it does not exist
+func getAuditUser(ctx basecontext.ApiContext, dbService *data.JsonDatabase, id string) *models.ApiUser {
+	dtoUser, err := dbService.GetUser(ctx, id)
+	if err != nil || dtoUser == nil {
+		return nil
+	}
+
+	user := mappers.DtoUserToApiResponse(*dtoUser)
+	return &user
+}
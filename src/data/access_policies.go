@@ -0,0 +1,246 @@
+package data
+
+import (
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/helpers"
+)
+
+var (
+	ErrAccessPolicyNotFound      = errors.NewWithCode("access policy not found", 404)
+	ErrAccessPolicyAlreadyExists = errors.NewWithCode("access policy already exists", 400)
+)
+
+func (j *JsonDatabase) GetAccessPolicies(ctx basecontext.ApiContext, filter string) ([]models.AccessPolicy, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	dbFilter, err := ParseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	j.dataMutex.RLock()
+	policies := make([]models.AccessPolicy, len(j.data.AccessPolicies))
+	copy(policies, j.data.AccessPolicies)
+	j.dataMutex.RUnlock()
+
+	filteredData, err := FilterByProperty(policies, dbFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	return filteredData, nil
+}
+
+func (j *JsonDatabase) GetAccessPolicy(ctx basecontext.ApiContext, idOrName string) (*models.AccessPolicy, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.RLock()
+	defer j.dataMutex.RUnlock()
+
+	for _, policy := range j.data.AccessPolicies {
+		if strings.EqualFold(policy.ID, idOrName) || strings.EqualFold(policy.Name, idOrName) {
+			return &policy, nil
+		}
+	}
+
+	return nil, ErrAccessPolicyNotFound
+}
+
+func (j *JsonDatabase) CreateAccessPolicy(ctx basecontext.ApiContext, policy models.AccessPolicy) (*models.AccessPolicy, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	if policy.ID == "" {
+		policy.ID = helpers.GenerateId()
+	}
+
+	if existing, _ := j.GetAccessPolicy(ctx, policy.Name); existing != nil {
+		return nil, ErrAccessPolicyAlreadyExists
+	}
+
+	policy.Effect = strings.ToLower(policy.Effect)
+	policy.Roles = normalizeAccessPolicyRoles(policy.Roles)
+	policy.CreatedAt = helpers.GetUtcCurrentDateTime()
+	policy.UpdatedAt = helpers.GetUtcCurrentDateTime()
+	policy.DbRecord = &models.DbRecord{}
+
+	j.dataMutex.Lock()
+	j.data.AccessPolicies = append(j.data.AccessPolicies, policy)
+	j.dataMutex.Unlock()
+
+	if err := j.SaveAsync(ctx); err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+func (j *JsonDatabase) UpdateAccessPolicy(ctx basecontext.ApiContext, policy models.AccessPolicy) (*models.AccessPolicy, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	if existing, _ := j.GetAccessPolicy(ctx, policy.Name); existing != nil && !strings.EqualFold(existing.ID, policy.ID) {
+		return nil, ErrAccessPolicyAlreadyExists
+	}
+
+	j.dataMutex.Lock()
+	for i, existing := range j.data.AccessPolicies {
+		if !strings.EqualFold(existing.ID, policy.ID) {
+			continue
+		}
+
+		for {
+			if IsRecordLocked(j.data.AccessPolicies[i].DbRecord) {
+				continue
+			}
+			LockRecord(ctx, j.data.AccessPolicies[i].DbRecord)
+			j.data.AccessPolicies[i].Name = policy.Name
+			j.data.AccessPolicies[i].Description = policy.Description
+			j.data.AccessPolicies[i].Effect = strings.ToLower(policy.Effect)
+			j.data.AccessPolicies[i].Roles = normalizeAccessPolicyRoles(policy.Roles)
+			j.data.AccessPolicies[i].Users = policy.Users
+			j.data.AccessPolicies[i].Resources = policy.Resources
+			j.data.AccessPolicies[i].Actions = policy.Actions
+			j.data.AccessPolicies[i].Condition = policy.Condition
+			j.data.AccessPolicies[i].Enabled = policy.Enabled
+			j.data.AccessPolicies[i].UpdatedAt = helpers.GetUtcCurrentDateTime()
+			UnlockRecord(ctx, j.data.AccessPolicies[i].DbRecord)
+			break
+		}
+
+		result := j.data.AccessPolicies[i]
+		j.dataMutex.Unlock()
+
+		if err := j.SaveAsync(ctx); err != nil {
+			return nil, err
+		}
+		return &result, nil
+	}
+	j.dataMutex.Unlock()
+
+	return nil, ErrAccessPolicyNotFound
+}
+
+func (j *JsonDatabase) DeleteAccessPolicy(ctx basecontext.ApiContext, id string) error {
+	if !j.IsConnected() {
+		return ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.Lock()
+	for i, policy := range j.data.AccessPolicies {
+		if strings.EqualFold(policy.ID, id) || strings.EqualFold(policy.Name, id) {
+			j.data.AccessPolicies = append(j.data.AccessPolicies[:i], j.data.AccessPolicies[i+1:]...)
+			j.dataMutex.Unlock()
+			return j.SaveAsync(ctx)
+		}
+	}
+	j.dataMutex.Unlock()
+
+	return ErrAccessPolicyNotFound
+}
+
+// GetResourceOwner returns who created the resource, resources created before
+// the owners were recorded have none
+func (j *JsonDatabase) GetResourceOwner(ctx basecontext.ApiContext, resourceType string, resourceId string) (*models.ResourceOwner, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.RLock()
+	defer j.dataMutex.RUnlock()
+
+	for _, owner := range j.data.ResourceOwners {
+		if owner.ResourceType == resourceType && strings.EqualFold(owner.ResourceID, resourceId) {
+			return &owner, nil
+		}
+	}
+
+	return nil, errors.NewWithCodef(404, "owner of %v %v not found", resourceType, resourceId)
+}
+
+// GetResourceOwners returns the owners of the resources of a type by the id of
+// the resource
+func (j *JsonDatabase) GetResourceOwners(ctx basecontext.ApiContext, resourceType string) (map[string]models.ResourceOwner, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.RLock()
+	defer j.dataMutex.RUnlock()
+
+	result := make(map[string]models.ResourceOwner)
+	for _, owner := range j.data.ResourceOwners {
+		if owner.ResourceType == resourceType {
+			result[strings.ToLower(owner.ResourceID)] = owner
+		}
+	}
+
+	return result, nil
+}
+
+func (j *JsonDatabase) SetResourceOwner(ctx basecontext.ApiContext, owner models.ResourceOwner) error {
+	if !j.IsConnected() {
+		return ErrDatabaseNotConnected
+	}
+	if owner.ResourceType == "" || owner.ResourceID == "" {
+		return errors.NewWithCode("resource type and id cannot be empty", 400)
+	}
+	if owner.OwnerID == "" {
+		return errors.NewWithCode("resource owner cannot be empty", 400)
+	}
+
+	owner.CreatedAt = helpers.GetUtcCurrentDateTime()
+
+	j.dataMutex.Lock()
+	found := false
+	for i, existing := range j.data.ResourceOwners {
+		if existing.ResourceType == owner.ResourceType && strings.EqualFold(existing.ResourceID, owner.ResourceID) {
+			j.data.ResourceOwners[i] = owner
+			found = true
+			break
+		}
+	}
+	if !found {
+		j.data.ResourceOwners = append(j.data.ResourceOwners, owner)
+	}
+	j.dataMutex.Unlock()
+
+	return j.SaveAsync(ctx)
+}
+
+func (j *JsonDatabase) DeleteResourceOwner(ctx basecontext.ApiContext, resourceType string, resourceId string) error {
+	if !j.IsConnected() {
+		return ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.Lock()
+	for i, owner := range j.data.ResourceOwners {
+		if owner.ResourceType == resourceType && strings.EqualFold(owner.ResourceID, resourceId) {
+			j.data.ResourceOwners = append(j.data.ResourceOwners[:i], j.data.ResourceOwners[i+1:]...)
+			j.dataMutex.Unlock()
+			return j.SaveAsync(ctx)
+		}
+	}
+	j.dataMutex.Unlock()
+
+	return nil
+}
+
+func normalizeAccessPolicyRoles(roles []string) []string {
+	result := make([]string, 0, len(roles))
+	for _, role := range roles {
+		result = append(result, helpers.NormalizeStringUpper(role))
+	}
+
+	return result
+}
@@ -0,0 +1,109 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAccessPolicy(t *testing.T) {
+	db, tmpDir := setupTestDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+
+	policy, err := db.CreateAccessPolicy(ctx, models.AccessPolicy{
+		Name:      "team-ios",
+		Effect:    "ALLOW",
+		Roles:     []string{"team ios"},
+		Resources: []string{"machine"},
+		Actions:   []string{"start", "stop"},
+		Condition: "'ios' in resource.host_tags",
+		Enabled:   true,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, policy.ID)
+	assert.Equal(t, "allow", policy.Effect)
+	assert.Equal(t, []string{"TEAM_IOS"}, policy.Roles)
+
+	loaded, err := db.GetAccessPolicy(ctx, "TEAM-IOS")
+	require.NoError(t, err)
+	assert.Equal(t, policy.ID, loaded.ID)
+
+	_, err = db.CreateAccessPolicy(ctx, models.AccessPolicy{Name: "team-ios", Effect: "deny"})
+	assert.Equal(t, 400, errors.GetSystemErrorCode(err))
+}
+
+func TestUpdateAndDeleteAccessPolicy(t *testing.T) {
+	db, tmpDir := setupTestDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+
+	policy, err := db.CreateAccessPolicy(ctx, models.AccessPolicy{
+		Name:      "no-delete",
+		Effect:    "deny",
+		Resources: []string{"machine"},
+		Actions:   []string{"delete"},
+		Enabled:   true,
+	})
+	require.NoError(t, err)
+	other, err := db.CreateAccessPolicy(ctx, models.AccessPolicy{Name: "other", Effect: "allow", Enabled: true})
+	require.NoError(t, err)
+
+	policy.Actions = []string{"delete", "unregister"}
+	policy.Enabled = false
+	updated, err := db.UpdateAccessPolicy(ctx, *policy)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"delete", "unregister"}, updated.Actions)
+	assert.False(t, updated.Enabled)
+
+	other.Name = "no-delete"
+	_, err = db.UpdateAccessPolicy(ctx, *other)
+	assert.Equal(t, ErrAccessPolicyAlreadyExists, err)
+
+	policies, err := db.GetAccessPolicies(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, policies, 2)
+
+	require.NoError(t, db.DeleteAccessPolicy(ctx, policy.ID))
+	_, err = db.GetAccessPolicy(ctx, policy.ID)
+	assert.Equal(t, ErrAccessPolicyNotFound, err)
+	assert.Equal(t, ErrAccessPolicyNotFound, db.DeleteAccessPolicy(ctx, policy.ID))
+}
+
+func TestResourceOwners(t *testing.T) {
+	db, tmpDir := setupTestDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+
+	require.NoError(t, db.SetResourceOwner(ctx, models.ResourceOwner{ResourceType: "machine", ResourceID: "VM-1", OwnerID: "user-1", OwnerName: "alice"}))
+	require.NoError(t, db.SetResourceOwner(ctx, models.ResourceOwner{ResourceType: "reverse_proxy_host", ResourceID: "vm-1", OwnerID: "user-2"}))
+	assert.Error(t, db.SetResourceOwner(ctx, models.ResourceOwner{ResourceType: "machine", ResourceID: "vm-2"}))
+
+	owner, err := db.GetResourceOwner(ctx, "machine", "vm-1")
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", owner.OwnerID)
+
+	require.NoError(t, db.SetResourceOwner(ctx, models.ResourceOwner{ResourceType: "machine", ResourceID: "vm-1", OwnerID: "user-3"}))
+	owners, err := db.GetResourceOwners(ctx, "machine")
+	require.NoError(t, err)
+	assert.Len(t, owners, 1)
+	assert.Equal(t, "user-3", owners["vm-1"].OwnerID)
+
+	require.NoError(t, db.DeleteResourceOwner(ctx, "machine", "vm-1"))
+	_, err = db.GetResourceOwner(ctx, "machine", "vm-1")
+	assert.Equal(t, 404, errors.GetSystemErrorCode(err))
+
+	owner, err = db.GetResourceOwner(ctx, "reverse_proxy_host", "vm-1")
+	require.NoError(t, err)
+	assert.Equal(t, "user-2", owner.OwnerID)
+}
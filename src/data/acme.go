@@ -0,0 +1,153 @@
+package data
+
+import (
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/helpers"
+)
+
+var (
+	ErrAcmeAccountNotFound     = errors.NewWithCode("acme account not found", 404)
+	ErrAcmeCertificateNotFound = errors.NewWithCode("acme certificate not found", 404)
+)
+
+// GetAcmeAccount returns the account registered with the directory for the
+// email, an empty email is an account without contact
+func (j *JsonDatabase) GetAcmeAccount(ctx basecontext.ApiContext, directoryUrl string, email string) (*models.AcmeAccount, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.RLock()
+	defer j.dataMutex.RUnlock()
+
+	for _, account := range j.data.AcmeAccounts {
+		if account.DirectoryUrl == directoryUrl && strings.EqualFold(account.Email, email) {
+			return &account, nil
+		}
+	}
+
+	return nil, ErrAcmeAccountNotFound
+}
+
+func (j *JsonDatabase) SaveAcmeAccount(ctx basecontext.ApiContext, account models.AcmeAccount) (*models.AcmeAccount, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+	if account.DirectoryUrl == "" || account.Key == "" {
+		return nil, errors.NewWithCode("acme account directory and key cannot be empty", 400)
+	}
+
+	j.dataMutex.Lock()
+	found := false
+	for i, existing := range j.data.AcmeAccounts {
+		if existing.DirectoryUrl == account.DirectoryUrl && strings.EqualFold(existing.Email, account.Email) {
+			account.ID = existing.ID
+			account.CreatedAt = existing.CreatedAt
+			j.data.AcmeAccounts[i] = account
+			found = true
+			break
+		}
+	}
+	if !found {
+		account.ID = helpers.GenerateId()
+		account.CreatedAt = helpers.GetUtcCurrentDateTime()
+		j.data.AcmeAccounts = append(j.data.AcmeAccounts, account)
+	}
+	j.dataMutex.Unlock()
+
+	if err := j.SaveAsync(ctx); err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+func (j *JsonDatabase) GetAcmeCertificates(ctx basecontext.ApiContext) ([]models.AcmeCertificate, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.RLock()
+	certificates := make([]models.AcmeCertificate, len(j.data.AcmeCertificates))
+	copy(certificates, j.data.AcmeCertificates)
+	j.dataMutex.RUnlock()
+
+	return certificates, nil
+}
+
+// GetAcmeCertificate returns the certificate issued for a reverse proxy host
+func (j *JsonDatabase) GetAcmeCertificate(ctx basecontext.ApiContext, hostId string) (*models.AcmeCertificate, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.RLock()
+	defer j.dataMutex.RUnlock()
+
+	for _, certificate := range j.data.AcmeCertificates {
+		if strings.EqualFold(certificate.HostID, hostId) {
+			return &certificate, nil
+		}
+	}
+
+	return nil, ErrAcmeCertificateNotFound
+}
+
+// SaveAcmeCertificate stores the certificate of a reverse proxy host, a
+// renewed certificate replaces the previous one
+func (j *JsonDatabase) SaveAcmeCertificate(ctx basecontext.ApiContext, certificate models.AcmeCertificate) (*models.AcmeCertificate, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+	if certificate.HostID == "" {
+		return nil, errors.NewWithCode("acme certificate host id cannot be empty", 400)
+	}
+
+	certificate.UpdatedAt = helpers.GetUtcCurrentDateTime()
+
+	j.dataMutex.Lock()
+	found := false
+	for i, existing := range j.data.AcmeCertificates {
+		if strings.EqualFold(existing.HostID, certificate.HostID) {
+			certificate.ID = existing.ID
+			certificate.CreatedAt = existing.CreatedAt
+			j.data.AcmeCertificates[i] = certificate
+			found = true
+			break
+		}
+	}
+	if !found {
+		certificate.ID = helpers.GenerateId()
+		certificate.CreatedAt = certificate.UpdatedAt
+		j.data.AcmeCertificates = append(j.data.AcmeCertificates, certificate)
+	}
+	j.dataMutex.Unlock()
+
+	if err := j.SaveAsync(ctx); err != nil {
+		return nil, err
+	}
+
+	return &certificate, nil
+}
+
+func (j *JsonDatabase) DeleteAcmeCertificate(ctx basecontext.ApiContext, hostId string) error {
+	if !j.IsConnected() {
+		return ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.Lock()
+	for i, certificate := range j.data.AcmeCertificates {
+		if strings.EqualFold(certificate.HostID, hostId) {
+			j.data.AcmeCertificates = append(j.data.AcmeCertificates[:i], j.data.AcmeCertificates[i+1:]...)
+			j.dataMutex.Unlock()
+			return j.SaveAsync(ctx)
+		}
+	}
+	j.dataMutex.Unlock()
+
+	return nil
+}
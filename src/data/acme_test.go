@@ -0,0 +1,60 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcmeAccounts(t *testing.T) {
+	db, tmpDir := setupTestDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+
+	_, err := db.GetAcmeAccount(ctx, "https://acme.test/dir", "ops@example.com")
+	assert.Equal(t, ErrAcmeAccountNotFound, err)
+
+	account, err := db.SaveAcmeAccount(ctx, models.AcmeAccount{DirectoryUrl: "https://acme.test/dir", Email: "ops@example.com", Key: "key-1"})
+	require.NoError(t, err)
+	updated, err := db.SaveAcmeAccount(ctx, models.AcmeAccount{DirectoryUrl: "https://acme.test/dir", Email: "OPS@example.com", Key: "key-2"})
+	require.NoError(t, err)
+	assert.Equal(t, account.ID, updated.ID)
+
+	loaded, err := db.GetAcmeAccount(ctx, "https://acme.test/dir", "ops@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "key-2", loaded.Key)
+
+	_, err = db.SaveAcmeAccount(ctx, models.AcmeAccount{DirectoryUrl: "https://acme.test/dir"})
+	assert.Error(t, err)
+}
+
+func TestAcmeCertificates(t *testing.T) {
+	db, tmpDir := setupTestDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+
+	host, err := db.CreateReverseProxyHost(ctx, models.ReverseProxyHost{Host: "0.0.0.0", Port: "443"})
+	require.NoError(t, err)
+
+	certificate, err := db.SaveAcmeCertificate(ctx, models.AcmeCertificate{HostID: host.ID, Domains: []string{"a.example.com"}, Certificate: "cert-1", Key: "key-1"})
+	require.NoError(t, err)
+	renewed, err := db.SaveAcmeCertificate(ctx, models.AcmeCertificate{HostID: host.ID, Domains: []string{"a.example.com"}, Certificate: "cert-2", Key: "key-2"})
+	require.NoError(t, err)
+	assert.Equal(t, certificate.ID, renewed.ID)
+
+	certificates, err := db.GetAcmeCertificates(ctx)
+	require.NoError(t, err)
+	require.Len(t, certificates, 1)
+	assert.Equal(t, "cert-2", certificates[0].Certificate)
+
+	require.NoError(t, db.DeleteReverseProxyHost(ctx, host.ID))
+	_, err = db.GetAcmeCertificate(ctx, host.ID)
+	assert.Equal(t, ErrAcmeCertificateNotFound, err)
+}
@@ -2,6 +2,7 @@ package data
 
 import (
 	"strings"
+	"time"
 
 	"github.com/Parallels/prl-devops-service/basecontext"
 	"github.com/Parallels/prl-devops-service/constants"
@@ -14,6 +15,7 @@ import (
 var (
 	ErrApiKeyNotFound      = errors.NewWithCode("API Key not found", 404)
 	ErrApiKeyAlreadyExists = errors.NewWithCode("API Key already exists", 500)
+	ErrApiKeyRevoked       = errors.NewWithCode("API Key has been revoked", 400)
 )
 
 func GetOwnRecords[T interface{ GetUserID() string }](ctx basecontext.ApiContext, records ...T) []T {
@@ -267,3 +269,84 @@ func (j *JsonDatabase) RevokeKey(ctx basecontext.ApiContext, id string) error {
 
 	return nil
 }
+
+// UpdateApiKeyUsage records when and from where the api key was last used
+func (j *JsonDatabase) UpdateApiKeyUsage(ctx basecontext.ApiContext, id string, sourceIp string) error {
+	if !j.IsConnected() {
+		return ErrDatabaseNotConnected
+	}
+
+	for i, apiKey := range j.data.ApiKeys {
+		if apiKey.ID != id {
+			continue
+		}
+
+		for {
+			if IsRecordLocked(j.data.ApiKeys[i].DbRecord) {
+				continue
+			}
+			LockRecord(ctx, j.data.ApiKeys[i].DbRecord)
+			j.data.ApiKeys[i].LastUsedAt = helpers.GetUtcCurrentDateTime()
+			j.data.ApiKeys[i].LastUsedIp = sourceIp
+			UnlockRecord(ctx, j.data.ApiKeys[i].DbRecord)
+			break
+		}
+
+		return nil
+	}
+
+	return ErrApiKeyNotFound
+}
+
+// RotateApiKey replaces the secret of the api key, the previous secret keeps
+// working for the overlap so clients can move to the new one
+func (j *JsonDatabase) RotateApiKey(ctx basecontext.ApiContext, id string, secret string, overlap time.Duration) (*models.ApiKey, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	key, err := j.GetApiKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.Revoked {
+		return nil, ErrApiKeyRevoked
+	}
+
+	passwdSvc := password.Get()
+	hashSecret, err := passwdSvc.Hash(secret, key.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, apiKey := range j.data.ApiKeys {
+		if apiKey.ID != key.ID {
+			continue
+		}
+
+		for {
+			if IsRecordLocked(j.data.ApiKeys[i].DbRecord) {
+				continue
+			}
+			LockRecord(ctx, j.data.ApiKeys[i].DbRecord)
+			now := time.Now().UTC()
+			j.data.ApiKeys[i].PreviousSecret = ""
+			j.data.ApiKeys[i].PreviousSecretExpiresAt = ""
+			if overlap > 0 {
+				j.data.ApiKeys[i].PreviousSecret = apiKey.Secret
+				j.data.ApiKeys[i].PreviousSecretExpiresAt = now.Add(overlap).Format(time.RFC3339Nano)
+			}
+			j.data.ApiKeys[i].Secret = hashSecret
+			j.data.ApiKeys[i].RotatedAt = now.Format(time.RFC3339Nano)
+			j.data.ApiKeys[i].UpdatedAt = helpers.GetUtcCurrentDateTime()
+			UnlockRecord(ctx, j.data.ApiKeys[i].DbRecord)
+			break
+		}
+
+		result := j.data.ApiKeys[i]
+		normalizeApiKeyType(&result)
+		return &result, nil
+	}
+
+	return nil, ErrApiKeyNotFound
+}
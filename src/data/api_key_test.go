@@ -603,3 +603,52 @@ func TestBackwardCompatibilityWithOldApiKeys(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "external", legacy2.Type)
 }
+
+func TestRotateApiKey(t *testing.T) {
+	db, tmpDir := setupTestDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	ctx := basecontext.NewBaseContext()
+	ctx.DisableLog()
+
+	created, err := db.CreateApiKey(ctx, models.ApiKey{ID: "rotate-key", Name: "Rotate Key", Key: "ROTATE_KEY", Secret: "secret"})
+	require.NoError(t, err)
+
+	rotated, err := db.RotateApiKey(ctx, "ROTATE_KEY", "new-secret", time.Hour)
+	require.NoError(t, err)
+	assert.NotEqual(t, created.Secret, rotated.Secret)
+	assert.Equal(t, created.Secret, rotated.PreviousSecret)
+	assert.NotEmpty(t, rotated.RotatedAt)
+	expiresAt, err := time.Parse(time.RFC3339Nano, rotated.PreviousSecretExpiresAt)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+
+	// rotating without an overlap drops the previous secret straight away
+	rotated, err = db.RotateApiKey(ctx, "ROTATE_KEY", "newer-secret", 0)
+	require.NoError(t, err)
+	assert.Empty(t, rotated.PreviousSecret)
+	assert.Empty(t, rotated.PreviousSecretExpiresAt)
+
+	require.NoError(t, db.RevokeKey(ctx, "ROTATE_KEY"))
+	_, err = db.RotateApiKey(ctx, "ROTATE_KEY", "secret", time.Hour)
+	assert.ErrorIs(t, err, ErrApiKeyRevoked)
+}
+
+func TestUpdateApiKeyUsage(t *testing.T) {
+	db, tmpDir := setupTestDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	ctx := basecontext.NewBaseContext()
+	ctx.DisableLog()
+
+	_, err := db.CreateApiKey(ctx, models.ApiKey{ID: "usage-key", Name: "Usage Key", Key: "USAGE_KEY", Secret: "secret"})
+	require.NoError(t, err)
+
+	require.NoError(t, db.UpdateApiKeyUsage(ctx, "usage-key", "10.0.0.1"))
+	key, err := db.GetApiKey(ctx, "usage-key")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", key.LastUsedIp)
+	assert.NotEmpty(t, key.LastUsedAt)
+
+	assert.ErrorIs(t, db.UpdateApiKeyUsage(ctx, "missing", "10.0.0.1"), ErrApiKeyNotFound)
+}
@@ -0,0 +1,143 @@
+package data
+
+import (
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/helpers"
+)
+
+var (
+	ErrCacheWarmupPolicyNotFound      = errors.NewWithCode("cache warmup policy not found", 404)
+	ErrCacheWarmupPolicyAlreadyExists = errors.NewWithCode("cache warmup policy already exists", 400)
+)
+
+func (j *JsonDatabase) GetCacheWarmupPolicies(ctx basecontext.ApiContext, filter string) ([]models.CacheWarmupPolicy, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	dbFilter, err := ParseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	j.dataMutex.RLock()
+	policies := make([]models.CacheWarmupPolicy, len(j.data.CacheWarmupPolicies))
+	copy(policies, j.data.CacheWarmupPolicies)
+	j.dataMutex.RUnlock()
+
+	filteredData, err := FilterByProperty(policies, dbFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	return filteredData, nil
+}
+
+func (j *JsonDatabase) GetCacheWarmupPolicy(ctx basecontext.ApiContext, idOrName string) (*models.CacheWarmupPolicy, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.RLock()
+	defer j.dataMutex.RUnlock()
+
+	for _, policy := range j.data.CacheWarmupPolicies {
+		if strings.EqualFold(policy.ID, idOrName) || strings.EqualFold(policy.Name, idOrName) {
+			return &policy, nil
+		}
+	}
+
+	return nil, ErrCacheWarmupPolicyNotFound
+}
+
+func (j *JsonDatabase) CreateCacheWarmupPolicy(ctx basecontext.ApiContext, policy models.CacheWarmupPolicy) (*models.CacheWarmupPolicy, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	if policy.ID == "" {
+		policy.ID = helpers.GenerateId()
+	}
+
+	if existing, _ := j.GetCacheWarmupPolicy(ctx, policy.Name); existing != nil {
+		return nil, ErrCacheWarmupPolicyAlreadyExists
+	}
+
+	policy.CatalogId = helpers.NormalizeStringUpper(policy.CatalogId)
+	policy.CreatedAt = helpers.GetUtcCurrentDateTime()
+	policy.UpdatedAt = helpers.GetUtcCurrentDateTime()
+
+	j.dataMutex.Lock()
+	j.data.CacheWarmupPolicies = append(j.data.CacheWarmupPolicies, policy)
+	j.dataMutex.Unlock()
+
+	if err := j.SaveAsync(ctx); err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+func (j *JsonDatabase) UpdateCacheWarmupPolicy(ctx basecontext.ApiContext, policy models.CacheWarmupPolicy) (*models.CacheWarmupPolicy, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.Lock()
+	for i, existing := range j.data.CacheWarmupPolicies {
+		if !strings.EqualFold(existing.ID, policy.ID) {
+			continue
+		}
+
+		for {
+			if IsRecordLocked(j.data.CacheWarmupPolicies[i].DbRecord) {
+				continue
+			}
+			LockRecord(ctx, j.data.CacheWarmupPolicies[i].DbRecord)
+			j.data.CacheWarmupPolicies[i].Name = policy.Name
+			j.data.CacheWarmupPolicies[i].CatalogId = helpers.NormalizeStringUpper(policy.CatalogId)
+			j.data.CacheWarmupPolicies[i].Version = policy.Version
+			j.data.CacheWarmupPolicies[i].Channel = policy.Channel
+			j.data.CacheWarmupPolicies[i].Connection = policy.Connection
+			j.data.CacheWarmupPolicies[i].HostTags = policy.HostTags
+			j.data.CacheWarmupPolicies[i].MaxTransferRateKb = policy.MaxTransferRateKb
+			j.data.CacheWarmupPolicies[i].Enabled = policy.Enabled
+			j.data.CacheWarmupPolicies[i].UpdatedAt = helpers.GetUtcCurrentDateTime()
+			UnlockRecord(ctx, j.data.CacheWarmupPolicies[i].DbRecord)
+			break
+		}
+
+		result := j.data.CacheWarmupPolicies[i]
+		j.dataMutex.Unlock()
+
+		if err := j.SaveAsync(ctx); err != nil {
+			return nil, err
+		}
+		return &result, nil
+	}
+	j.dataMutex.Unlock()
+
+	return nil, ErrCacheWarmupPolicyNotFound
+}
+
+func (j *JsonDatabase) DeleteCacheWarmupPolicy(ctx basecontext.ApiContext, id string) error {
+	if !j.IsConnected() {
+		return ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.Lock()
+	for i, policy := range j.data.CacheWarmupPolicies {
+		if strings.EqualFold(policy.ID, id) || strings.EqualFold(policy.Name, id) {
+			j.data.CacheWarmupPolicies = append(j.data.CacheWarmupPolicies[:i], j.data.CacheWarmupPolicies[i+1:]...)
+			j.dataMutex.Unlock()
+			return j.SaveAsync(ctx)
+		}
+	}
+	j.dataMutex.Unlock()
+
+	return ErrCacheWarmupPolicyNotFound
+}
@@ -0,0 +1,69 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateCacheWarmupPolicy(t *testing.T) {
+	db, tmpDir := setupTestDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+
+	policy, err := db.CreateCacheWarmupPolicy(ctx, models.CacheWarmupPolicy{
+		Name:      "ci-runners",
+		CatalogId: "macos-runner",
+		Channel:   "latest",
+		HostTags:  []string{"ci"},
+		Enabled:   true,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, policy.ID)
+	assert.Equal(t, "MACOS-RUNNER", policy.CatalogId)
+
+	loaded, err := db.GetCacheWarmupPolicy(ctx, "CI-RUNNERS")
+	require.NoError(t, err)
+	assert.Equal(t, policy.ID, loaded.ID)
+
+	_, err = db.CreateCacheWarmupPolicy(ctx, models.CacheWarmupPolicy{Name: "ci-runners", CatalogId: "other"})
+	assert.Equal(t, 400, errors.GetSystemErrorCode(err))
+}
+
+func TestUpdateAndDeleteCacheWarmupPolicy(t *testing.T) {
+	db, tmpDir := setupTestDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+
+	policy, err := db.CreateCacheWarmupPolicy(ctx, models.CacheWarmupPolicy{
+		Name:      "builders",
+		CatalogId: "builder",
+		Version:   "1.0.0",
+		Enabled:   true,
+	})
+	require.NoError(t, err)
+
+	policy.Version = "2.0.0"
+	policy.Enabled = false
+	updated, err := db.UpdateCacheWarmupPolicy(ctx, *policy)
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0", updated.Version)
+	assert.False(t, updated.Enabled)
+
+	policies, err := db.GetCacheWarmupPolicies(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, policies, 1)
+
+	require.NoError(t, db.DeleteCacheWarmupPolicy(ctx, policy.ID))
+	_, err = db.GetCacheWarmupPolicy(ctx, policy.ID)
+	assert.Equal(t, ErrCacheWarmupPolicyNotFound, err)
+	assert.Equal(t, ErrCacheWarmupPolicyNotFound, db.DeleteCacheWarmupPolicy(ctx, policy.ID))
+}
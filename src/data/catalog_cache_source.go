@@ -0,0 +1,97 @@
+package data
+
+import (
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/helpers"
+)
+
+var ErrCatalogCacheSourceNotFound = errors.NewWithCode("catalog cache source not found", 404)
+
+func catalogCacheSourceMatches(source models.CatalogCacheSource, catalogId string, version string, architecture string) bool {
+	return strings.EqualFold(source.CatalogId, catalogId) &&
+		strings.EqualFold(source.Version, version) &&
+		strings.EqualFold(source.Architecture, architecture)
+}
+
+func (j *JsonDatabase) GetCatalogCacheSource(ctx basecontext.ApiContext, catalogId string, version string, architecture string) (*models.CatalogCacheSource, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.RLock()
+	defer j.dataMutex.RUnlock()
+
+	for _, source := range j.data.CatalogCacheSources {
+		if catalogCacheSourceMatches(source, catalogId, version, architecture) {
+			return &source, nil
+		}
+	}
+
+	return nil, ErrCatalogCacheSourceNotFound
+}
+
+// SetCatalogCacheSource creates or updates the source of a cached catalog
+// manifest version.
+func (j *JsonDatabase) SetCatalogCacheSource(ctx basecontext.ApiContext, source models.CatalogCacheSource) (*models.CatalogCacheSource, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	source.CatalogId = helpers.NormalizeStringUpper(source.CatalogId)
+	source.UpdatedAt = helpers.GetUtcCurrentDateTime()
+
+	j.dataMutex.Lock()
+	for i, existing := range j.data.CatalogCacheSources {
+		if !catalogCacheSourceMatches(existing, source.CatalogId, source.Version, source.Architecture) {
+			continue
+		}
+
+		if existing.ConnectionRef == source.ConnectionRef {
+			j.dataMutex.Unlock()
+			return &existing, nil
+		}
+
+		j.data.CatalogCacheSources[i].ConnectionRef = source.ConnectionRef
+		j.data.CatalogCacheSources[i].UpdatedAt = source.UpdatedAt
+		result := j.data.CatalogCacheSources[i]
+		j.dataMutex.Unlock()
+
+		if err := j.SaveAsync(ctx); err != nil {
+			return nil, err
+		}
+		return &result, nil
+	}
+
+	source.ID = helpers.GenerateId()
+	source.CreatedAt = source.UpdatedAt
+	j.data.CatalogCacheSources = append(j.data.CatalogCacheSources, source)
+	j.dataMutex.Unlock()
+
+	if err := j.SaveAsync(ctx); err != nil {
+		return nil, err
+	}
+
+	return &source, nil
+}
+
+func (j *JsonDatabase) DeleteCatalogCacheSource(ctx basecontext.ApiContext, catalogId string, version string, architecture string) error {
+	if !j.IsConnected() {
+		return ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.Lock()
+	for i, source := range j.data.CatalogCacheSources {
+		if catalogCacheSourceMatches(source, catalogId, version, architecture) {
+			j.data.CatalogCacheSources = append(j.data.CatalogCacheSources[:i], j.data.CatalogCacheSources[i+1:]...)
+			j.dataMutex.Unlock()
+			return j.SaveAsync(ctx)
+		}
+	}
+	j.dataMutex.Unlock()
+
+	return ErrCatalogCacheSourceNotFound
+}
@@ -0,0 +1,65 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetCatalogCacheSource(t *testing.T) {
+	db, tmpDir := setupTestDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+
+	source, err := db.SetCatalogCacheSource(ctx, models.CatalogCacheSource{
+		CatalogId:     "macos-runner",
+		Version:       "1.0.0",
+		Architecture:  "arm64",
+		ConnectionRef: "secret://catalog-cache-source-one",
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, source.ID)
+	assert.Equal(t, "MACOS-RUNNER", source.CatalogId)
+
+	updated, err := db.SetCatalogCacheSource(ctx, models.CatalogCacheSource{
+		CatalogId:     "macos-runner",
+		Version:       "1.0.0",
+		Architecture:  "arm64",
+		ConnectionRef: "secret://catalog-cache-source-two",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, source.ID, updated.ID)
+
+	loaded, err := db.GetCatalogCacheSource(ctx, "macos-runner", "1.0.0", "ARM64")
+	require.NoError(t, err)
+	assert.Equal(t, "secret://catalog-cache-source-two", loaded.ConnectionRef)
+
+	_, err = db.GetCatalogCacheSource(ctx, "macos-runner", "1.0.0", "x86_64")
+	assert.Equal(t, 404, errors.GetSystemErrorCode(err))
+}
+
+func TestDeleteCatalogCacheSource(t *testing.T) {
+	db, tmpDir := setupTestDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+
+	_, err := db.SetCatalogCacheSource(ctx, models.CatalogCacheSource{
+		CatalogId:    "builder",
+		Version:      "2.0.0",
+		Architecture: "x86_64",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, db.DeleteCatalogCacheSource(ctx, "builder", "2.0.0", "x86_64"))
+	_, err = db.GetCatalogCacheSource(ctx, "builder", "2.0.0", "x86_64")
+	assert.Equal(t, 404, errors.GetSystemErrorCode(err))
+	assert.Equal(t, 404, errors.GetSystemErrorCode(db.DeleteCatalogCacheSource(ctx, "builder", "2.0.0", "x86_64")))
+}
@@ -0,0 +1,163 @@
+package data
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/data/models"
+)
+
+// SearchCatalogManifests returns the catalog manifests the user can see
+// matching the query together with the facets counted over all of them.
+func (j *JsonDatabase) SearchCatalogManifests(ctx basecontext.ApiContext, query models.CatalogSearchQuery) (*models.CatalogSearchResult, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	manifests, err := j.GetCatalogManifests(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := models.CatalogSearchResult{
+		Items: make([]models.CatalogManifest, 0),
+		Facets: models.CatalogSearchFacets{
+			CatalogIds:    make(map[string]int),
+			Tags:          make(map[string]int),
+			Architectures: make(map[string]int),
+		},
+	}
+
+	for _, manifest := range manifests {
+		if !catalogSearchMatches(query, manifest) {
+			continue
+		}
+		result.Items = append(result.Items, manifest)
+		addCatalogSearchFacets(&result.Facets, manifest)
+	}
+
+	return &result, nil
+}
+
+func addCatalogSearchFacets(f *models.CatalogSearchFacets, manifest models.CatalogManifest) {
+	f.CatalogIds[manifest.CatalogId]++
+	if manifest.Architecture != "" {
+		f.Architectures[strings.ToLower(manifest.Architecture)]++
+	}
+	seen := make(map[string]bool)
+	for _, tag := range manifest.Tags {
+		tag = strings.ToLower(tag)
+		if !seen[tag] {
+			f.Tags[tag]++
+			seen[tag] = true
+		}
+	}
+	if manifest.Tainted {
+		f.Tainted++
+	}
+	if manifest.Revoked {
+		f.Revoked++
+	}
+}
+
+func catalogSearchMatches(q models.CatalogSearchQuery, manifest models.CatalogManifest) bool {
+	if len(q.CatalogIds) > 0 {
+		found := false
+		for _, catalogId := range q.CatalogIds {
+			if strings.EqualFold(catalogId, manifest.CatalogId) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.Text != "" {
+		text := strings.ToLower(q.Text)
+		if !strings.Contains(strings.ToLower(manifest.Name), text) &&
+			!strings.Contains(strings.ToLower(manifest.Description), text) &&
+			!strings.Contains(strings.ToLower(manifest.CatalogId), text) {
+			return false
+		}
+	}
+	for _, tag := range q.Tags {
+		if !manifest.HasTag(tag) {
+			return false
+		}
+	}
+	if q.Architecture != "" && !strings.EqualFold(manifest.Architecture, q.Architecture) {
+		return false
+	}
+	if q.MinSize > 0 && manifest.Size < q.MinSize {
+		return false
+	}
+	if q.MaxSize > 0 && manifest.Size > q.MaxSize {
+		return false
+	}
+	if !dateInRange(manifest.CreatedAt, q.CreatedAfter, q.CreatedBefore) {
+		return false
+	}
+	if !dateInRange(manifest.LastDownloadedAt, q.DownloadedAfter, q.DownloadedBefore) {
+		return false
+	}
+	if q.Tainted != nil && manifest.Tainted != *q.Tainted {
+		return false
+	}
+	if q.Revoked != nil && manifest.Revoked != *q.Revoked {
+		return false
+	}
+	for _, role := range q.RequiredRoles {
+		found := false
+		for _, manifestRole := range manifest.RequiredRoles {
+			if strings.EqualFold(manifestRole, role) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.FitsCpu > 0 || q.FitsMemory > 0 || q.FitsDisk > 0 {
+		spec := manifest.MinimumSpecRequirements
+		if spec != nil {
+			if q.FitsCpu > 0 && spec.Cpu > q.FitsCpu {
+				return false
+			}
+			if q.FitsMemory > 0 && spec.Memory > q.FitsMemory {
+				return false
+			}
+			if q.FitsDisk > 0 && spec.Disk > q.FitsDisk {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// dateInRange checks if a stored date is inside the range, a date that is not
+// set or cannot be parsed never matches a range.
+func dateInRange(value string, after *time.Time, before *time.Time) bool {
+	if after == nil && before == nil {
+		return true
+	}
+
+	date, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return false
+	}
+	if after != nil && date.Before(*after) {
+		return false
+	}
+	if before != nil && date.After(*before) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,113 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCatalogSearchDB(t *testing.T) (*JsonDatabase, string, basecontext.ApiContext) {
+	db, tmpDir := setupTestDB(t)
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+
+	manifests := []models.CatalogManifest{
+		{
+			CatalogId: "UBUNTU", Name: "ubuntu", Description: "Ubuntu server", Version: "1.0.0", Architecture: "arm64",
+			Size: 1000, Tags: []string{"stable", "linux"}, DownloadCount: 5, LastDownloadedAt: "2024-05-01T10:00:00Z",
+			MinimumSpecRequirements: &models.MinimumSpecRequirement{Cpu: 2, Memory: 2048, Disk: 10},
+		},
+		{
+			CatalogId: "UBUNTU", Name: "ubuntu", Description: "Ubuntu server", Version: "1.0.0", Architecture: "x86_64",
+			Size: 1200, Tags: []string{"linux"}, DownloadCount: 1, Tainted: true,
+		},
+		{
+			CatalogId: "MACOS", Name: "macos-runner", Description: "Build runner with xcode", Version: "2.0.0", Architecture: "arm64",
+			Size: 50000, Tags: []string{"stable"}, DownloadCount: 20, RequiredRoles: []string{"BUILDERS"}, LastDownloadedAt: "2024-06-01T10:00:00Z",
+			MinimumSpecRequirements: &models.MinimumSpecRequirement{Cpu: 8, Memory: 16384, Disk: 100},
+		},
+	}
+	for _, manifest := range manifests {
+		_, err := db.CreateCatalogManifest(ctx, manifest)
+		require.NoError(t, err)
+	}
+
+	return db, tmpDir, ctx
+}
+
+func searchIds(result *models.CatalogSearchResult) []string {
+	ids := make([]string, 0)
+	for _, item := range result.Items {
+		ids = append(ids, item.Name+"/"+item.Architecture)
+	}
+	return ids
+}
+
+func TestSearchCatalogManifests_Filters(t *testing.T) {
+	db, tmpDir, ctx := setupCatalogSearchDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	after := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)
+	tainted := true
+
+	tests := []struct {
+		name     string
+		query    models.CatalogSearchQuery
+		expected []string
+	}{
+		{"text in description", models.CatalogSearchQuery{Text: "XCODE"}, []string{"macos-runner/arm64"}},
+		{"text in name", models.CatalogSearchQuery{Text: "ubuntu"}, []string{"ubuntu/arm64", "ubuntu/x86_64"}},
+		{"tags", models.CatalogSearchQuery{Tags: []string{"stable", "linux"}}, []string{"ubuntu/arm64"}},
+		{"architecture", models.CatalogSearchQuery{Architecture: "ARM64"}, []string{"ubuntu/arm64", "macos-runner/arm64"}},
+		{"size range", models.CatalogSearchQuery{MinSize: 1100, MaxSize: 2000}, []string{"ubuntu/x86_64"}},
+		{"downloaded after", models.CatalogSearchQuery{DownloadedAfter: &after}, []string{"macos-runner/arm64"}},
+		{"tainted", models.CatalogSearchQuery{Tainted: &tainted}, []string{"ubuntu/x86_64"}},
+		{"required roles", models.CatalogSearchQuery{RequiredRoles: []string{"builders"}}, []string{"macos-runner/arm64"}},
+		{"catalog ids", models.CatalogSearchQuery{CatalogIds: []string{"macos"}}, []string{"macos-runner/arm64"}},
+		{"fits spec", models.CatalogSearchQuery{FitsCpu: 4, FitsMemory: 8192}, []string{"ubuntu/arm64", "ubuntu/x86_64"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := db.SearchCatalogManifests(ctx, tt.query)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, searchIds(result))
+		})
+	}
+}
+
+func TestSearchCatalogManifests_Facets(t *testing.T) {
+	db, tmpDir, ctx := setupCatalogSearchDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	result, err := db.SearchCatalogManifests(ctx, models.CatalogSearchQuery{})
+	require.NoError(t, err)
+
+	assert.Len(t, result.Items, 3)
+	assert.Equal(t, 2, result.Facets.CatalogIds["UBUNTU"])
+	assert.Equal(t, 2, result.Facets.Tags["stable"])
+	assert.Equal(t, 2, result.Facets.Architectures["arm64"])
+	assert.Equal(t, 1, result.Facets.Tainted)
+
+	result, err = db.SearchCatalogManifests(ctx, models.CatalogSearchQuery{Architecture: "x86_64"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Facets.Architectures["x86_64"])
+	assert.Zero(t, result.Facets.Architectures["arm64"])
+}
+
+func TestSearchCatalogManifests_InvalidQuery(t *testing.T) {
+	db, tmpDir, ctx := setupCatalogSearchDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	_, err := db.SearchCatalogManifests(ctx, models.CatalogSearchQuery{MinSize: 10, MaxSize: 5})
+	assert.Equal(t, 400, errors.GetSystemErrorCode(err))
+
+	_, err = db.SearchCatalogManifests(ctx, models.CatalogSearchQuery{MinSize: -1})
+	assert.Equal(t, 400, errors.GetSystemErrorCode(err))
+}
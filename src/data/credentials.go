@@ -0,0 +1,470 @@
+package data
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/secrets"
+	"github.com/Parallels/prl-devops-service/security"
+	"github.com/Parallels/prl-devops-service/security/envelope"
+)
+
+const (
+	CredentialStateEncrypted = "encrypted"
+	CredentialStatePlaintext = "plaintext"
+	CredentialStateReference = "reference"
+)
+
+// credentialMetaKeys are the catalog provider attributes that hold credentials
+var credentialMetaKeys = []string{"password", "api_key", "access_key", "secret_key", "session_token", "storage_account_key", "token", "client_secret"}
+
+// CredentialRecord is a database field that holds a credential
+type CredentialRecord struct {
+	Collection string `json:"collection"`
+	RecordId   string `json:"record_id"`
+	Field      string `json:"field"`
+	State      string `json:"state"`
+	KeyId      string `json:"key_id,omitempty"`
+}
+
+type credentialField struct {
+	collection string
+	recordId   string
+	field      string
+	value      string
+	set        func(value string)
+}
+
+// credentialFields returns the non empty fields of the data that hold
+// credentials, set updates the record the field belongs to
+func (d *Data) credentialFields() []credentialField {
+	result := make([]credentialField, 0)
+	add := func(collection string, recordId string, field string, value *string) {
+		if *value == "" {
+			return
+		}
+		result = append(result, credentialField{
+			collection: collection,
+			recordId:   recordId,
+			field:      field,
+			value:      *value,
+			set:        func(v string) { *value = v },
+		})
+	}
+
+	for i := range d.Users {
+		record := &d.Users[i]
+		if record.Mfa != nil {
+			add("users", record.ID, "mfa.secret", &record.Mfa.Secret)
+			add("users", record.ID, "mfa.pending_secret", &record.Mfa.PendingSecret)
+		}
+	}
+	for i := range d.CatalogManagers {
+		record := &d.CatalogManagers[i]
+		add("catalog_managers", record.ID, "password", &record.Password)
+		add("catalog_managers", record.ID, "api_key", &record.ApiKey)
+	}
+	for i := range d.OrchestratorHosts {
+		record := &d.OrchestratorHosts[i]
+		if record.Authentication != nil {
+			add("orchestrator_hosts", record.ID, "authentication.password", &record.Authentication.Password)
+			add("orchestrator_hosts", record.ID, "authentication.api_key", &record.Authentication.ApiKey)
+		}
+	}
+	for i := range d.ManifestsCatalog {
+		record := &d.ManifestsCatalog[i]
+		if record.Provider == nil {
+			continue
+		}
+		add("catalog_manifests", record.ID, "provider.password", &record.Provider.Password)
+		add("catalog_manifests", record.ID, "provider.api_key", &record.Provider.ApiKey)
+		keys := make([]string, 0)
+		for key := range record.Provider.Meta {
+			if isCredentialMetaKey(key) && record.Provider.Meta[key] != "" {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			meta := record.Provider.Meta
+			metaKey := key
+			result = append(result, credentialField{
+				collection: "catalog_manifests",
+				recordId:   record.ID,
+				field:      "provider.meta." + key,
+				value:      meta[key],
+				set:        func(v string) { meta[metaKey] = v },
+			})
+		}
+	}
+	for i := range d.CacheWarmupPolicies {
+		record := &d.CacheWarmupPolicies[i]
+		add("cache_warmup_policies", record.ID, "connection", &record.Connection)
+	}
+	for i := range d.ReverseProxyHosts {
+		record := &d.ReverseProxyHosts[i]
+		if record.Tls != nil {
+			add("reverse_proxy_hosts", record.ID, "tls.key", &record.Tls.Key)
+		}
+	}
+	for i := range d.AcmeAccounts {
+		record := &d.AcmeAccounts[i]
+		add("acme_accounts", record.ID, "key", &record.Key)
+	}
+	for i := range d.AcmeCertificates {
+		record := &d.AcmeCertificates[i]
+		add("acme_certificates", record.ID, "key", &record.Key)
+	}
+
+	return result
+}
+
+// withCredentialCopies returns a copy of the data where the records holding
+// credentials do not share memory with the original, so the copy can be
+// encrypted while the service keeps using the plain values
+func (d *Data) withCredentialCopies() Data {
+	result := *d
+	result.Users = append([]models.User(nil), d.Users...)
+	for i := range result.Users {
+		if mfa := result.Users[i].Mfa; mfa != nil {
+			copied := *mfa
+			result.Users[i].Mfa = &copied
+		}
+	}
+	result.CatalogManagers = append([]models.CatalogManager(nil), d.CatalogManagers...)
+	result.CacheWarmupPolicies = append([]models.CacheWarmupPolicy(nil), d.CacheWarmupPolicies...)
+
+	result.OrchestratorHosts = append([]models.OrchestratorHost(nil), d.OrchestratorHosts...)
+	for i := range result.OrchestratorHosts {
+		if authentication := result.OrchestratorHosts[i].Authentication; authentication != nil {
+			copied := *authentication
+			result.OrchestratorHosts[i].Authentication = &copied
+		}
+	}
+
+	result.ManifestsCatalog = append([]models.CatalogManifest(nil), d.ManifestsCatalog...)
+	for i := range result.ManifestsCatalog {
+		if provider := result.ManifestsCatalog[i].Provider; provider != nil {
+			copied := *provider
+			if provider.Meta != nil {
+				copied.Meta = make(map[string]string, len(provider.Meta))
+				for key, value := range provider.Meta {
+					copied.Meta[key] = value
+				}
+			}
+			result.ManifestsCatalog[i].Provider = &copied
+		}
+	}
+
+	result.ReverseProxyHosts = append([]models.ReverseProxyHost(nil), d.ReverseProxyHosts...)
+	for i := range result.ReverseProxyHosts {
+		if tls := result.ReverseProxyHosts[i].Tls; tls != nil {
+			copied := *tls
+			result.ReverseProxyHosts[i].Tls = &copied
+		}
+	}
+	result.AcmeAccounts = append([]models.AcmeAccount(nil), d.AcmeAccounts...)
+	result.AcmeCertificates = append([]models.AcmeCertificate(nil), d.AcmeCertificates...)
+
+	return result
+}
+
+func isCredentialMetaKey(key string) bool {
+	for _, credentialKey := range credentialMetaKeys {
+		if strings.EqualFold(key, credentialKey) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getDatabaseMasterKey returns the configured master key or nil when the
+// credentials are not encrypted at rest
+func getDatabaseMasterKey() (*envelope.MasterKey, error) {
+	value := config.Get().DatabaseMasterKey()
+	if value == "" {
+		return nil, nil
+	}
+
+	return envelope.ParseMasterKey(value)
+}
+
+// encryptCredentials returns a copy of the data with the credentials encrypted
+// by the master key, the secret references are kept as they are
+func encryptCredentials(data *Data, key *envelope.MasterKey) (Data, error) {
+	if key == nil {
+		return *data, nil
+	}
+
+	result := data.withCredentialCopies()
+	for _, field := range result.credentialFields() {
+		if secrets.IsReference(field.value) {
+			continue
+		}
+		encrypted, err := key.Encrypt(field.value)
+		if err != nil {
+			return Data{}, errors.NewFromErrorf(err, "error encrypting %v %v of %v", field.collection, field.field, field.recordId)
+		}
+		field.set(encrypted)
+	}
+
+	return result, nil
+}
+
+// decryptCredentials decrypts the credentials of the data in place, the values
+// that cannot be decrypted are kept encrypted so they are not lost on the next
+// save and an error is returned so the caller does not use them as they are
+func decryptCredentials(ctx basecontext.ApiContext, data *Data, key *envelope.MasterKey) error {
+	failed := 0
+	for _, field := range data.credentialFields() {
+		if !envelope.IsEncrypted(field.value) {
+			continue
+		}
+		if key == nil {
+			ctx.LogErrorf("[Database] %v %v of %v is encrypted but DATABASE_MASTER_KEY is not set", field.collection, field.field, field.recordId)
+			failed++
+			continue
+		}
+
+		decrypted, err := key.Decrypt(field.value)
+		if err != nil {
+			ctx.LogErrorf("[Database] Error decrypting %v %v of %v: %v", field.collection, field.field, field.recordId, err)
+			failed++
+			continue
+		}
+		field.set(decrypted)
+	}
+
+	if failed > 0 {
+		return errors.Newf("%d credentials could not be decrypted with the database master key", failed)
+	}
+	return nil
+}
+
+// decodeDatabaseContent reads the content of a database file, decrypting it
+// first if it was saved with the ENCRYPTION_PRIVATE_KEY
+func decodeDatabaseContent(content []byte) (Data, error) {
+	var data Data
+	if err := json.Unmarshal(content, &data); err == nil {
+		return data, nil
+	} else if config.Get().EncryptionPrivateKey() == "" {
+		return data, err
+	}
+
+	decrypted, err := security.DecryptString(config.Get().EncryptionPrivateKey(), content)
+	if err != nil {
+		return data, err
+	}
+	if err := json.Unmarshal([]byte(decrypted), &data); err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
+
+// encodeDatabaseContent returns the content of a database file, encrypting it
+// if the ENCRYPTION_PRIVATE_KEY is set
+func encodeDatabaseContent(data *Data) ([]byte, error) {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if config.Get().EncryptionPrivateKey() == "" {
+		return content, nil
+	}
+
+	return security.EncryptString(config.Get().EncryptionPrivateKey(), string(content))
+}
+
+// AuditDatabaseCredentials lists the records of a database file that hold
+// credentials and whether they are encrypted at rest
+func AuditDatabaseCredentials(ctx basecontext.ApiContext, filename string) ([]CredentialRecord, error) {
+	content, err := os.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return nil, err
+	}
+	data, err := decodeDatabaseContent(content)
+	if err != nil {
+		return nil, errors.NewFromErrorf(err, "error reading database file %v", filename)
+	}
+
+	result := make([]CredentialRecord, 0)
+	for _, field := range data.credentialFields() {
+		record := CredentialRecord{
+			Collection: field.collection,
+			RecordId:   field.recordId,
+			Field:      field.field,
+			State:      CredentialStatePlaintext,
+		}
+		switch {
+		case envelope.IsEncrypted(field.value):
+			record.State = CredentialStateEncrypted
+			record.KeyId = envelope.KeyID(field.value)
+		case secrets.IsReference(field.value):
+			record.State = CredentialStateReference
+		}
+		result = append(result, record)
+	}
+
+	return result, nil
+}
+
+// databaseCompanionFiles returns the backup and residual save files next to a
+// database file, the service recovers from them so they hold credentials too
+func databaseCompanionFiles(filename string) ([]string, error) {
+	result := make([]string, 0)
+	seen := map[string]bool{}
+	for _, glob := range []string{".save.bak.*", "*.save", "*.save_bak", "*.panic"} {
+		files, err := filepath.Glob(filename + glob)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if !seen[file] {
+				seen[file] = true
+				result = append(result, file)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// rotateCredentials wraps the credentials of the data in place with the new
+// master key and returns how many were rotated
+func rotateCredentials(data *Data, currentKey *envelope.MasterKey, newKey *envelope.MasterKey) (int, error) {
+	rotated := 0
+	for _, field := range data.credentialFields() {
+		if secrets.IsReference(field.value) {
+			continue
+		}
+
+		var value string
+		var err error
+		switch {
+		case !envelope.IsEncrypted(field.value):
+			value, err = newKey.Encrypt(field.value)
+		case currentKey == nil:
+			return 0, errors.Newf("%v %v of %v is encrypted with the master key %v, the current key is required", field.collection, field.field, field.recordId, envelope.KeyID(field.value))
+		default:
+			value, err = currentKey.Rewrap(field.value, newKey)
+		}
+		if err != nil {
+			return 0, errors.NewFromErrorf(err, "error rotating %v %v of %v", field.collection, field.field, field.recordId)
+		}
+		field.set(value)
+		rotated++
+	}
+
+	return rotated, nil
+}
+
+// RotateDatabaseMasterKey wraps the data keys of the credentials in a database
+// file with the new master key, plain credentials are encrypted with it. The
+// backups and residual save files are rotated as well so a later recovery can
+// still decrypt them, nothing is written if any of the files cannot be
+// rotated. The current key can be nil when the credentials were never
+// encrypted. The service should be stopped while the file is rotated
+func RotateDatabaseMasterKey(ctx basecontext.ApiContext, filename string, currentKey *envelope.MasterKey, newKey *envelope.MasterKey) (int, error) {
+	if newKey == nil {
+		return 0, errors.New("the new master key is required")
+	}
+
+	lock, err := acquireFileLock(filename + ".lock")
+	if err != nil {
+		return 0, errors.NewFromError(err)
+	}
+	defer lock.release()
+
+	companions, err := databaseCompanionFiles(filename)
+	if err != nil {
+		return 0, errors.NewFromError(err)
+	}
+
+	rotated := 0
+	contents := make(map[string][]byte)
+	for _, file := range append([]string{filename}, companions...) {
+		content, err := os.ReadFile(filepath.Clean(file))
+		if err != nil {
+			return 0, err
+		}
+		if file != filename && len(content) == 0 {
+			continue
+		}
+		data, err := decodeDatabaseContent(content)
+		if err != nil {
+			return 0, errors.NewFromErrorf(err, "error reading database file %v", file)
+		}
+
+		count, err := rotateCredentials(&data, currentKey, newKey)
+		if err != nil {
+			return 0, errors.NewFromErrorf(err, "error rotating %v, remove the file if it is no longer needed", file)
+		}
+		if file == filename {
+			rotated = count
+		}
+
+		encoded, err := encodeDatabaseContent(&data)
+		if err != nil {
+			return 0, err
+		}
+		contents[file] = encoded
+	}
+
+	for _, file := range companions {
+		if encoded, ok := contents[file]; ok {
+			if err := writeFileAtomically(file, encoded); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := writeFileAtomically(filename, contents[filename]); err != nil {
+		return 0, err
+	}
+
+	ctx.LogInfof("[Database] Rotated %d credentials and %d backup files to the master key %v", rotated, len(contents)-1, newKey.ID())
+	return rotated, nil
+}
+
+// writeFileAtomically writes the content to a temp file in the same folder and
+// renames it over the destination so the file is never left half written
+func writeFileAtomically(filename string, content []byte) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.save")
+	if err != nil {
+		return err
+	}
+	tempFileName := tempFile.Name()
+
+	renamed := false
+	defer func() {
+		if !renamed {
+			_ = os.Remove(tempFileName)
+		}
+	}()
+
+	if _, err := tempFile.Write(content); err != nil {
+		_ = tempFile.Close()
+		return err
+	}
+	if err := tempFile.Sync(); err != nil {
+		_ = tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tempFileName, filename); err != nil {
+		return err
+	}
+	renamed = true
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/security/envelope"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMasterKey(t *testing.T) *envelope.MasterKey {
+	value, err := envelope.GenerateMasterKey()
+	require.NoError(t, err)
+	key, err := envelope.ParseMasterKey(value)
+	require.NoError(t, err)
+
+	return key
+}
+
+func newCredentialsTestData() Data {
+	return Data{
+		CatalogManagers: []models.CatalogManager{
+			{ID: "manager", Password: "manager-password", ApiKey: "secret://manager-api-key"},
+		},
+		OrchestratorHosts: []models.OrchestratorHost{
+			{ID: "host", Authentication: &models.OrchestratorHostAuthentication{Username: "user", Password: "host-password"}},
+		},
+		ManifestsCatalog: []models.CatalogManifest{
+			{ID: "manifest", Provider: &models.CatalogManifestProvider{
+				Type: "aws-s3",
+				Meta: map[string]string{"bucket": "demo", "secret_key": "aws-secret"},
+			}},
+		},
+		CacheWarmupPolicies: []models.CacheWarmupPolicy{
+			{ID: "policy", Connection: "provider=aws-s3;secret_key=aws-secret"},
+		},
+	}
+}
+
+func TestEncryptCredentials(t *testing.T) {
+	ctx := basecontext.NewBaseContext()
+	ctx.DisableLog()
+	key := newTestMasterKey(t)
+	data := newCredentialsTestData()
+
+	encrypted, err := encryptCredentials(&data, key)
+	require.NoError(t, err)
+
+	// the data used by the service keeps the plain values
+	assert.Equal(t, "manager-password", data.CatalogManagers[0].Password)
+	assert.Equal(t, "host-password", data.OrchestratorHosts[0].Authentication.Password)
+	assert.Equal(t, "aws-secret", data.ManifestsCatalog[0].Provider.Meta["secret_key"])
+
+	assert.True(t, envelope.IsEncrypted(encrypted.CatalogManagers[0].Password))
+	assert.Equal(t, "secret://manager-api-key", encrypted.CatalogManagers[0].ApiKey)
+	assert.True(t, envelope.IsEncrypted(encrypted.OrchestratorHosts[0].Authentication.Password))
+	assert.Equal(t, "user", encrypted.OrchestratorHosts[0].Authentication.Username)
+	assert.True(t, envelope.IsEncrypted(encrypted.ManifestsCatalog[0].Provider.Meta["secret_key"]))
+	assert.Equal(t, "demo", encrypted.ManifestsCatalog[0].Provider.Meta["bucket"])
+	assert.True(t, envelope.IsEncrypted(encrypted.CacheWarmupPolicies[0].Connection))
+
+	require.NoError(t, decryptCredentials(ctx, &encrypted, key))
+	assert.Equal(t, "manager-password", encrypted.CatalogManagers[0].Password)
+	assert.Equal(t, "host-password", encrypted.OrchestratorHosts[0].Authentication.Password)
+	assert.Equal(t, "aws-secret", encrypted.ManifestsCatalog[0].Provider.Meta["secret_key"])
+	assert.Equal(t, "provider=aws-s3;secret_key=aws-secret", encrypted.CacheWarmupPolicies[0].Connection)
+}
+
+func TestDecryptCredentials_WrongKeyKeepsValue(t *testing.T) {
+	ctx := basecontext.NewBaseContext()
+	ctx.DisableLog()
+	key := newTestMasterKey(t)
+	data := newCredentialsTestData()
+
+	encrypted, err := encryptCredentials(&data, key)
+	require.NoError(t, err)
+	value := encrypted.CatalogManagers[0].Password
+
+	assert.Error(t, decryptCredentials(ctx, &encrypted, newTestMasterKey(t)))
+	assert.Equal(t, value, encrypted.CatalogManagers[0].Password)
+
+	assert.Error(t, decryptCredentials(ctx, &encrypted, nil))
+	assert.Equal(t, value, encrypted.CatalogManagers[0].Password)
+}
+
+func TestAuditAndRotateDatabaseMasterKey(t *testing.T) {
+	ctx := basecontext.NewBaseContext()
+	ctx.DisableLog()
+	filename := filepath.Join(t.TempDir(), "data.json")
+	data := newCredentialsTestData()
+	content, err := encodeDatabaseContent(&data)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filename, content, 0o600))
+
+	records, err := AuditDatabaseCredentials(ctx, filename)
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	states := map[string]string{}
+	for _, record := range records {
+		states[record.Collection+"/"+record.Field] = record.State
+	}
+	assert.Equal(t, CredentialStatePlaintext, states["catalog_managers/password"])
+	assert.Equal(t, CredentialStateReference, states["catalog_managers/api_key"])
+	assert.Equal(t, CredentialStatePlaintext, states["orchestrator_hosts/authentication.password"])
+	assert.Equal(t, CredentialStatePlaintext, states["catalog_manifests/provider.meta.secret_key"])
+	assert.Equal(t, CredentialStatePlaintext, states["cache_warmup_policies/connection"])
+
+	firstKey := newTestMasterKey(t)
+	rotated, err := RotateDatabaseMasterKey(ctx, filename, nil, firstKey)
+	require.NoError(t, err)
+	assert.Equal(t, 4, rotated)
+
+	records, err = AuditDatabaseCredentials(ctx, filename)
+	require.NoError(t, err)
+	for _, record := range records {
+		if record.State != CredentialStateReference {
+			assert.Equal(t, CredentialStateEncrypted, record.State)
+			assert.Equal(t, firstKey.ID(), record.KeyId)
+		}
+	}
+
+	secondKey := newTestMasterKey(t)
+	_, err = RotateDatabaseMasterKey(ctx, filename, nil, secondKey)
+	assert.ErrorContains(t, err, "the current key is required")
+
+	rotated, err = RotateDatabaseMasterKey(ctx, filename, firstKey, secondKey)
+	require.NoError(t, err)
+	assert.Equal(t, 4, rotated)
+
+	content, err = os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "manager-password")
+	stored, err := decodeDatabaseContent(content)
+	require.NoError(t, err)
+	require.NoError(t, decryptCredentials(ctx, &stored, secondKey))
+	assert.Equal(t, "manager-password", stored.CatalogManagers[0].Password)
+	assert.Equal(t, "provider=aws-s3;secret_key=aws-secret", stored.CacheWarmupPolicies[0].Connection)
+}
+
+func TestRotateDatabaseMasterKey_RotatesBackupAndSaveFiles(t *testing.T) {
+	ctx := basecontext.NewBaseContext()
+	ctx.DisableLog()
+	filename := filepath.Join(t.TempDir(), "data.json")
+	firstKey := newTestMasterKey(t)
+	data := newCredentialsTestData()
+	encrypted, err := encryptCredentials(&data, firstKey)
+	require.NoError(t, err)
+	content, err := encodeDatabaseContent(&encrypted)
+	require.NoError(t, err)
+	companions := []string{filename + ".save.bak.2024-01-01-00-00-00", filename + ".1234.save"}
+	for _, file := range append([]string{filename}, companions...) {
+		require.NoError(t, os.WriteFile(file, content, 0o600))
+	}
+
+	secondKey := newTestMasterKey(t)
+	rotated, err := RotateDatabaseMasterKey(ctx, filename, firstKey, secondKey)
+	require.NoError(t, err)
+	assert.Equal(t, 4, rotated)
+
+	for _, file := range append([]string{filename}, companions...) {
+		records, err := AuditDatabaseCredentials(ctx, file)
+		require.NoError(t, err)
+		for _, record := range records {
+			if record.State != CredentialStateReference {
+				assert.Equal(t, secondKey.ID(), record.KeyId, file)
+			}
+		}
+	}
+}
+
+func TestRotateDatabaseMasterKey_RefusesUnreadableBackup(t *testing.T) {
+	ctx := basecontext.NewBaseContext()
+	ctx.DisableLog()
+	filename := filepath.Join(t.TempDir(), "data.json")
+	firstKey := newTestMasterKey(t)
+	data := newCredentialsTestData()
+	encrypted, err := encryptCredentials(&data, firstKey)
+	require.NoError(t, err)
+	content, err := encodeDatabaseContent(&encrypted)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filename, content, 0o600))
+
+	// the backup was written with a key that is no longer known
+	staleData := newCredentialsTestData()
+	stale, err := encryptCredentials(&staleData, newTestMasterKey(t))
+	require.NoError(t, err)
+	staleContent, err := encodeDatabaseContent(&stale)
+	require.NoError(t, err)
+	backup := filename + ".save.bak.2024-01-01-00-00-00"
+	require.NoError(t, os.WriteFile(backup, staleContent, 0o600))
+
+	_, err = RotateDatabaseMasterKey(ctx, filename, firstKey, newTestMasterKey(t))
+	assert.ErrorContains(t, err, backup)
+
+	// nothing was written
+	current, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, content, current)
+}
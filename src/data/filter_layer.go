@@ -107,6 +107,14 @@ func iterateMaps(value reflect.Value, f func(key, val reflect.Value)) {
 
 // Get the property value
 func getProperty(value reflect.Value, propertyName string) reflect.Value {
+	if value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	value = reflect.Indirect(value)
+	if !value.IsValid() || (value.Kind() != reflect.Struct && value.Kind() != reflect.Map) {
+		return reflect.Value{}
+	}
+
 	// Split the property name into parts
 	parts := strings.Split(propertyName, ".")
 	if len(parts) == 1 {
@@ -140,13 +148,17 @@ func getProperty(value reflect.Value, propertyName string) reflect.Value {
 			}
 		}
 	} else {
+		if value.Kind() == reflect.Map {
+			return getProperty(getProperty(value, parts[0]), strings.Join(parts[1:], "."))
+		}
+
 		// Get the type of the value
 		valueType := value.Type()
 		// Find the field with the specified property name
 		for i := 0; i < valueType.NumField(); i++ {
 			field := valueType.Field(i)
 			if strings.EqualFold(field.Name, parts[0]) {
-				return getProperty(value.FieldByName(parts[0]), strings.Join(parts[1:], "."))
+				return getProperty(value.Field(i), strings.Join(parts[1:], "."))
 			}
 			tagValue := field.Tag.Get("json")
 			tagValue = strings.Split(tagValue, ",")[0]
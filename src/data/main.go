@@ -31,23 +31,31 @@ var (
 )
 
 type Data struct {
-	Schema            models.DatabaseSchema                `json:"schema"`
-	Configuration     *models.Configuration                `json:"configuration"`
-	Users             []models.User                        `json:"users"`
-	Claims            []models.Claim                       `json:"claims"`
-	Roles             []models.Role                        `json:"roles"`
-	ApiKeys           []models.ApiKey                      `json:"api_keys"`
-	PackerTemplates   []models.PackerTemplate              `json:"virtual_machine_templates"`
-	ManifestsCatalog  []models.CatalogManifest             `json:"catalog_manifests"`
-	OrchestratorHosts []models.OrchestratorHost            `json:"orchestrator_hosts"`
-	HostsVMSnapshots  []models.HostsVMSnapshotsRecord      `json:"orchestrator_snapshots"`
-	ReverseProxy      *models.ReverseProxy                 `json:"reverse_proxy"`
-	ReverseProxyHosts []models.ReverseProxyHost            `json:"reverse_proxy_hosts"`
-	CatalogManagers   []models.CatalogManager              `json:"catalog_managers"`
-	Jobs              []models.Job                         `json:"jobs"`
-	VMSnapshots       []models.VMSnapshots                 `json:"vm_snapshots"`
-	EnrollmentTokens  []models.OrchestratorEnrollmentToken `json:"enrollment_tokens"`
-	UserConfigs       []models.UserConfig                  `json:"user_configs"`
+	Schema              models.DatabaseSchema                `json:"schema"`
+	Configuration       *models.Configuration                `json:"configuration"`
+	Users               []models.User                        `json:"users"`
+	Claims              []models.Claim                       `json:"claims"`
+	Roles               []models.Role                        `json:"roles"`
+	ApiKeys             []models.ApiKey                      `json:"api_keys"`
+	PackerTemplates     []models.PackerTemplate              `json:"virtual_machine_templates"`
+	ManifestsCatalog    []models.CatalogManifest             `json:"catalog_manifests"`
+	OrchestratorHosts   []models.OrchestratorHost            `json:"orchestrator_hosts"`
+	HostsVMSnapshots    []models.HostsVMSnapshotsRecord      `json:"orchestrator_snapshots"`
+	ReverseProxy        *models.ReverseProxy                 `json:"reverse_proxy"`
+	ReverseProxyHosts   []models.ReverseProxyHost            `json:"reverse_proxy_hosts"`
+	CatalogManagers     []models.CatalogManager              `json:"catalog_managers"`
+	Jobs                []models.Job                         `json:"jobs"`
+	VMSnapshots         []models.VMSnapshots                 `json:"vm_snapshots"`
+	EnrollmentTokens    []models.OrchestratorEnrollmentToken `json:"enrollment_tokens"`
+	UserConfigs         []models.UserConfig                  `json:"user_configs"`
+	CacheWarmupPolicies []models.CacheWarmupPolicy           `json:"cache_warmup_policies"`
+	CatalogCacheSources []models.CatalogCacheSource          `json:"catalog_cache_sources"`
+	UserSessions        []models.UserSession                 `json:"user_sessions"`
+	RevokedTokens       []models.RevokedToken                `json:"revoked_tokens"`
+	AccessPolicies      []models.AccessPolicy                `json:"access_policies"`
+	ResourceOwners      []models.ResourceOwner               `json:"resource_owners"`
+	AcmeAccounts        []models.AcmeAccount                 `json:"acme_accounts"`
+	AcmeCertificates    []models.AcmeCertificate             `json:"acme_certificates"`
 }
 
 type JsonDatabase struct {
@@ -105,10 +113,13 @@ func NewJsonDatabase(ctx basecontext.ApiContext, filename string) *JsonDatabase
 	// Starting the automatic backup
 	memoryDatabase.RunBackup(ctx)
 
-	// Start ghost job cleanup goroutine to detect and cancel stalled jobs
+	// Start ghost job cleanup goroutine to detect and cancel stalled jobs, it
+	// also removes the expired sessions and revoked tokens
 	go func() {
 		ticker := time.NewTicker(time.Duration(constants.GhostJobCheckIntervalSeconds) * time.Second)
 		defer ticker.Stop()
+		sessionsTicker := time.NewTicker(time.Duration(constants.ExpiredSessionsCleanupIntervalMinutes) * time.Minute)
+		defer sessionsTicker.Stop()
 		for {
 			select {
 			case <-memoryDatabase.cancel:
@@ -116,6 +127,10 @@ func NewJsonDatabase(ctx basecontext.ApiContext, filename string) *JsonDatabase
 				return
 			case <-ticker.C:
 				memoryDatabase.DetectStaleJobs(ctx)
+			case <-sessionsTicker.C:
+				if err := memoryDatabase.DeleteExpiredUserSessions(ctx); err != nil {
+					ctx.LogWarnf("[Database] Could not purge expired sessions: %v", err)
+				}
 			}
 		}
 	}()
@@ -294,9 +309,19 @@ func (j *JsonDatabase) processSave(ctx basecontext.ApiContext) error {
 	}
 	defer lock.release()
 
-	// Marshal the data while holding only the read lock.
+	masterKey, err := getDatabaseMasterKey()
+	if err != nil {
+		ctx.LogErrorf("[Database] Error reading the database master key: %v", err)
+		return errors.NewFromError(err)
+	}
+
+	// Encrypt the credentials of a copy and marshal it while holding only the read lock.
 	j.dataMutex.RLock()
-	jsonString, err := json.MarshalIndent(j.data, "", "  ")
+	data, err := encryptCredentials(&j.data, masterKey)
+	var jsonString []byte
+	if err == nil {
+		jsonString, err = json.MarshalIndent(data, "", "  ")
+	}
 	j.dataMutex.RUnlock()
 	if err != nil {
 		ctx.LogDebugf("[Database] Error marshalling data: %v", err)
@@ -466,6 +491,10 @@ func (j *JsonDatabase) recoverFromResidualSaveFiles(ctx basecontext.ApiContext,
 			ctx.LogErrorf("[Database] Error unmarshalling save file %s: %v", latestSaveFile, err)
 			return false, err
 		}
+		if err := j.decryptLoadedCredentials(ctx, &data); err != nil {
+			ctx.LogErrorf("[Database] Not recovering from save file %s: %v", latestSaveFile, err)
+			return false, nil
+		}
 		j.dataMutex.Lock()
 		j.data = data
 		j.connected = true
@@ -516,6 +545,10 @@ func (j *JsonDatabase) recoverFromBackupFile(ctx basecontext.ApiContext) error {
 			ctx.LogErrorf("[Database] Error unmarshalling backup file %s: %v", latestBackupFile, err)
 			return err
 		}
+		if err := j.decryptLoadedCredentials(ctx, &data); err != nil {
+			ctx.LogErrorf("[Database] Error recovering from backup file %s: %v", latestBackupFile, err)
+			return err
+		}
 		j.dataMutex.Lock()
 		j.data = data
 		j.dataMutex.Unlock()
@@ -545,27 +578,15 @@ func (j *JsonDatabase) loadFromFile(ctx basecontext.ApiContext) error {
 		return err
 	}
 
-	// Trying to read the file unencrypted
-	err = json.Unmarshal(content, &data)
+	// Trying to read the file unencrypted and then encrypted
+	data, err = decodeDatabaseContent(content)
 	if err != nil {
-		// Trying to read the file encrypted
-		cfg := config.Get()
-		if cfg.EncryptionPrivateKey() == "" {
-			ctx.LogErrorf("[Database] Error reading database file: %v", err)
-			return err
-		}
-
-		content, err := security.DecryptString(cfg.EncryptionPrivateKey(), content)
-		if err != nil {
-			ctx.LogErrorf("[Database] Error decrypting database file: %v", err)
-			return err
-		}
-
-		err = json.Unmarshal([]byte(content), &data)
-		if err != nil {
-			ctx.LogErrorf("[Database] Error reading database file: %v", err)
-			return err
-		}
+		ctx.LogErrorf("[Database] Error reading database file: %v", err)
+		return err
+	}
+	if err := j.decryptLoadedCredentials(ctx, &data); err != nil {
+		ctx.LogErrorf("[Database] Error reading database file: %v", err)
+		return err
 	}
 
 	j.dataMutex.Lock()
@@ -579,6 +600,16 @@ func (j *JsonDatabase) loadFromFile(ctx basecontext.ApiContext) error {
 	return nil
 }
 
+// decryptLoadedCredentials decrypts the credentials encrypted at rest with the
+// database master key after the data is read from a file
+func (j *JsonDatabase) decryptLoadedCredentials(ctx basecontext.ApiContext, data *Data) error {
+	masterKey, err := getDatabaseMasterKey()
+	if err != nil {
+		ctx.LogErrorf("[Database] Error reading the database master key: %v", err)
+	}
+	return decryptCredentials(ctx, data, masterKey)
+}
+
 func (j *JsonDatabase) loadFromEmpty(ctx basecontext.ApiContext) error {
 	ctx.LogInfof("[Database] Database file is empty, creating new file")
 	j.dataMutex.Lock()
@@ -0,0 +1,32 @@
+package models
+
+// AccessPolicy allows or denies actions on machines, orchestrator hosts or
+// reverse proxy hosts. The policy applies to the users in Users or with one
+// of the Roles, when both are empty it applies to everyone. The Condition is
+// an expression on the subject, the resource and the action, an empty
+// condition always matches.
+type AccessPolicy struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Effect      string   `json:"effect"`
+	Roles       []string `json:"roles,omitempty"`
+	Users       []string `json:"users,omitempty"`
+	Resources   []string `json:"resources"`
+	Actions     []string `json:"actions"`
+	Condition   string   `json:"condition,omitempty"`
+	Enabled     bool     `json:"enabled"`
+	CreatedAt   string   `json:"created_at"`
+	UpdatedAt   string   `json:"updated_at"`
+	*DbRecord   `json:"db_record"`
+}
+
+// ResourceOwner is the user that created a machine or a reverse proxy host,
+// the owner is exposed to the access policies as resource.owner
+type ResourceOwner struct {
+	ResourceType string `json:"resource_type"`
+	ResourceID   string `json:"resource_id"`
+	OwnerID      string `json:"owner_id"`
+	OwnerName    string `json:"owner_name,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
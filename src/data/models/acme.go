@@ -0,0 +1,28 @@
+package models
+
+// AcmeAccount is the account registered with an ACME directory, the key is
+// the PEM encoded private key of the account
+type AcmeAccount struct {
+	ID           string `json:"id"`
+	DirectoryUrl string `json:"directory_url"`
+	Email        string `json:"email,omitempty"`
+	Uri          string `json:"uri,omitempty"`
+	Key          string `json:"key"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// AcmeCertificate is the certificate issued by the ACME directory for a
+// reverse proxy host, the certificate holds the PEM encoded chain
+type AcmeCertificate struct {
+	ID           string   `json:"id"`
+	HostID       string   `json:"host_id"`
+	Domains      []string `json:"domains"`
+	DirectoryUrl string   `json:"directory_url"`
+	Challenge    string   `json:"challenge,omitempty"`
+	Certificate  string   `json:"certificate"`
+	Key          string   `json:"key"`
+	NotBefore    string   `json:"not_before"`
+	NotAfter     string   `json:"not_after"`
+	CreatedAt    string   `json:"created_at"`
+	UpdatedAt    string   `json:"updated_at"`
+}
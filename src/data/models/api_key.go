@@ -1,20 +1,46 @@
 package models
 
 type ApiKey struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Key       string `json:"key"`
-	Secret    string `json:"secret"`
-	Revoked   bool   `json:"revoked"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
-	RevokedAt string `json:"revoked_at"`
-	ExpiresAt string `json:"expires_at"`
-	UserID    string `json:"user_id,omitempty"`
-	Type      string `json:"type,omitempty"` // "internal" or "external". Default: "external"
-	*DbRecord `json:"db_record"`
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Key       string       `json:"key"`
+	Secret    string       `json:"secret"`
+	Revoked   bool         `json:"revoked"`
+	CreatedAt string       `json:"created_at"`
+	UpdatedAt string       `json:"updated_at"`
+	RevokedAt string       `json:"revoked_at"`
+	ExpiresAt string       `json:"expires_at"`
+	UserID    string       `json:"user_id,omitempty"`
+	Type      string       `json:"type,omitempty"` // "internal" or "external". Default: "external"
+	Scope     *ApiKeyScope `json:"scope,omitempty"`
+	// PreviousSecret keeps working until PreviousSecretExpiresAt after the
+	// key is rotated so clients can roll out the new secret
+	PreviousSecret          string `json:"previous_secret,omitempty"`
+	PreviousSecretExpiresAt string `json:"previous_secret_expires_at,omitempty"`
+	RotatedAt               string `json:"rotated_at,omitempty"`
+	LastUsedAt              string `json:"last_used_at,omitempty"`
+	LastUsedIp              string `json:"last_used_ip,omitempty"`
+	*DbRecord               `json:"db_record"`
 }
 
 func (a ApiKey) GetUserID() string {
 	return a.UserID
 }
+
+// ApiKeyScope limits what an api key can do, empty fields do not restrict
+// the key
+type ApiKeyScope struct {
+	Claims      []string         `json:"claims,omitempty"`
+	Methods     []string         `json:"methods,omitempty"`
+	Paths       []string         `json:"paths,omitempty"`
+	SourceCidrs []string         `json:"source_cidrs,omitempty"`
+	RateLimit   int              `json:"rate_limit,omitempty"`
+	Resources   []ApiKeyResource `json:"resources,omitempty"`
+}
+
+// ApiKeyResource is a resource an api key is restricted to, for example a
+// catalog id or a host tag
+type ApiKeyResource struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
@@ -0,0 +1,16 @@
+package models
+
+type CacheWarmupPolicy struct {
+	ID                string   `json:"id"`
+	Name              string   `json:"name"`
+	CatalogId         string   `json:"catalog_id"`
+	Version           string   `json:"version,omitempty"`
+	Channel           string   `json:"channel,omitempty"`
+	Connection        string   `json:"connection,omitempty"`
+	HostTags          []string `json:"host_tags,omitempty"`
+	MaxTransferRateKb int64    `json:"max_transfer_rate_kb,omitempty"`
+	Enabled           bool     `json:"enabled"`
+	CreatedAt         string   `json:"created_at"`
+	UpdatedAt         string   `json:"updated_at"`
+	*DbRecord         `json:"db_record"`
+}
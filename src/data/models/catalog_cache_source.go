@@ -0,0 +1,15 @@
+package models
+
+// CatalogCacheSource remembers where a cached catalog manifest was downloaded
+// from so a corrupt cache item can be downloaded again. The connection itself
+// is kept in the secret store, only its secret:// reference is saved here.
+type CatalogCacheSource struct {
+	ID            string `json:"id"`
+	CatalogId     string `json:"catalog_id"`
+	Version       string `json:"version"`
+	Architecture  string `json:"architecture"`
+	ConnectionRef string `json:"connection_ref,omitempty"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+	*DbRecord     `json:"db_record"`
+}
@@ -12,6 +12,7 @@ type CatalogManager struct {
 	ApiKey               string   `json:"api_key,omitempty" yaml:"api_key,omitempty"`
 	Global               bool     `json:"global" yaml:"global"`
 	RequiredClaims       []string `json:"required_claims,omitempty" yaml:"required_claims,omitempty"`
+	MaxTransferRateKb    int64    `json:"max_transfer_rate_kb,omitempty" yaml:"max_transfer_rate_kb,omitempty"`
 	OwnerID              string   `json:"owner_id" yaml:"owner_id"`
 	CreatedAt            string   `json:"created_at" yaml:"created_at"`
 	UpdatedAt            string   `json:"updated_at" yaml:"updated_at"`
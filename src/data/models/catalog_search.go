@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+// CatalogSearchQuery filters the catalog manifests, empty values are ignored
+// and all the set filters need to match.
+type CatalogSearchQuery struct {
+	Text             string
+	Tags             []string
+	Architecture     string
+	MinSize          int64
+	MaxSize          int64
+	CreatedAfter     *time.Time
+	CreatedBefore    *time.Time
+	DownloadedAfter  *time.Time
+	DownloadedBefore *time.Time
+	Tainted          *bool
+	Revoked          *bool
+	RequiredRoles    []string
+	// CatalogIds limits the search to these catalogs, empty searches all
+	CatalogIds []string
+	// Only returns manifests whose minimum requirements fit in these resources
+	FitsCpu    int
+	FitsMemory int
+	FitsDisk   int
+}
+
+type CatalogSearchFacets struct {
+	CatalogIds    map[string]int
+	Tags          map[string]int
+	Architectures map[string]int
+	Tainted       int
+	Revoked       int
+}
+
+// CatalogSearchResult holds every manifest matching the search, sorting and
+// pagination are applied by the list query layer
+type CatalogSearchResult struct {
+	Items  []CatalogManifest
+	Facets CatalogSearchFacets
+}
+
+func (q *CatalogSearchQuery) Validate() error {
+	if q.MinSize < 0 || q.MaxSize < 0 {
+		return errors.NewWithCode("size filters cannot be negative", 400)
+	}
+	if q.MaxSize > 0 && q.MinSize > q.MaxSize {
+		return errors.NewWithCode("min size cannot be bigger than max size", 400)
+	}
+	return nil
+}
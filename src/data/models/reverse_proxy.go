@@ -3,6 +3,8 @@ package models
 import (
 	"regexp"
 	"strings"
+
+	"github.com/Parallels/prl-devops-service/constants"
 )
 
 type ReverseProxy struct {
@@ -41,8 +43,10 @@ type ReverseProxyHost struct {
 	Port       string                       `json:"port"`
 	Tls        *ReverseProxyHostTls         `json:"tls,omitempty"`
 	Cors       *ReverseProxyHostCors        `json:"cors,omitempty"`
+	Auth       *ReverseProxyAuth            `json:"auth,omitempty"`
 	HttpRoutes []*ReverseProxyHostHttpRoute `json:"http_routes,omitempty"`
 	TcpRoute   *ReverseProxyHostTcpRoute    `json:"tcp_route,omitempty"`
+	UdpRoute   *ReverseProxyHostUdpRoute    `json:"udp_route,omitempty"`
 }
 
 func (o *ReverseProxyHost) GetHost() string {
@@ -99,6 +103,10 @@ func (o *ReverseProxyHost) Diff(source ReverseProxyHost) bool {
 		}
 	}
 
+	if diffAuth(o.Auth, source.Auth) {
+		return true
+	}
+
 	if len(o.HttpRoutes) != len(source.HttpRoutes) {
 		return true
 	}
@@ -120,6 +128,18 @@ func (o *ReverseProxyHost) Diff(source ReverseProxyHost) bool {
 		}
 	}
 
+	if o.UdpRoute == nil && source.UdpRoute != nil {
+		return true
+	}
+	if o.UdpRoute != nil && source.UdpRoute == nil {
+		return true
+	}
+	if o.UdpRoute != nil && source.UdpRoute != nil {
+		if o.UdpRoute.Diff(*source.UdpRoute) {
+			return true
+		}
+	}
+
 	return false
 }
 
@@ -166,9 +186,16 @@ func (o *ReverseProxyHostCors) Diff(source ReverseProxyHostCors) bool {
 }
 
 type ReverseProxyHostTls struct {
-	Enabled bool   `json:"enabled,omitempty" yaml:"enabled,omitempty"`
-	Cert    string `json:"cert,omitempty" yaml:"cert,omitempty"`
-	Key     string `json:"key,omitempty" yaml:"key,omitempty"`
+	Enabled bool                     `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Cert    string                   `json:"cert,omitempty" yaml:"cert,omitempty"`
+	Key     string                   `json:"key,omitempty" yaml:"key,omitempty"`
+	Acme    *ReverseProxyHostTlsAcme `json:"acme,omitempty" yaml:"acme,omitempty"`
+}
+
+// IsAcmeEnabled returns true when the certificate of the host is requested
+// from the ACME directory instead of the Cert and Key
+func (o *ReverseProxyHostTls) IsAcmeEnabled() bool {
+	return o != nil && o.Enabled && o.Acme != nil && o.Acme.Enabled
 }
 
 func (o *ReverseProxyHostTls) Diff(source ReverseProxyHostTls) bool {
@@ -185,21 +212,90 @@ func (o *ReverseProxyHostTls) Diff(source ReverseProxyHostTls) bool {
 	if o.Key != source.Key {
 		return true
 	}
+	if o.Acme == nil && source.Acme != nil {
+		return true
+	}
+	if o.Acme != nil && source.Acme == nil {
+		return true
+	}
+	if o.Acme != nil && source.Acme != nil {
+		if o.Acme.Diff(*source.Acme) {
+			return true
+		}
+	}
+	return false
+}
+
+type ReverseProxyHostTlsAcme struct {
+	Enabled     bool     `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Domains     []string `json:"domains,omitempty" yaml:"domains,omitempty"`
+	Email       string   `json:"email,omitempty" yaml:"email,omitempty"`
+	Challenge   string   `json:"challenge,omitempty" yaml:"challenge,omitempty"`
+	DnsProvider string   `json:"dns_provider,omitempty" yaml:"dns_provider,omitempty"`
+}
+
+func (o *ReverseProxyHostTlsAcme) Diff(source ReverseProxyHostTlsAcme) bool {
+	if o == nil {
+		return true
+	}
+
+	if o.Enabled != source.Enabled {
+		return true
+	}
+	if len(o.Domains) != len(source.Domains) {
+		return true
+	}
+	for i, domain := range o.Domains {
+		if domain != source.Domains[i] {
+			return true
+		}
+	}
+	if o.Email != source.Email {
+		return true
+	}
+	if o.Challenge != source.Challenge {
+		return true
+	}
+	if o.DnsProvider != source.DnsProvider {
+		return true
+	}
 	return false
 }
 
 type ReverseProxyHostHttpRoute struct {
-	ID              string            `json:"id,omitempty" yaml:"id,omitempty"`
-	Order           int               `json:"order,omitempty" yaml:"order,omitempty"`
-	Path            string            `json:"path,omitempty" yaml:"path,omitempty"`
-	TargetVmId      string            `json:"target_vm_id,omitempty" yaml:"target_vm_id,omitempty"`
-	TargetHost      string            `json:"target_host,omitempty" yaml:"target_host,omitempty"`
-	TargetPort      string            `json:"target_port,omitempty" yaml:"target_port,omitempty"`
-	Schema          string            `json:"schema,omitempty" yaml:"scheme,omitempty"`
-	Pattern         string            `json:"pattern,omitempty" yaml:"pattern,omitempty"`
-	RegexpPattern   *regexp.Regexp    `json:"-" yaml:"-"`
-	RequestHeaders  map[string]string `json:"request_headers,omitempty" yaml:"request_headers,omitempty"`
-	ResponseHeaders map[string]string `json:"response_headers,omitempty" yaml:"response_headers,omitempty"`
+	ID              string                `json:"id,omitempty" yaml:"id,omitempty"`
+	Order           int                   `json:"order,omitempty" yaml:"order,omitempty"`
+	Path            string                `json:"path,omitempty" yaml:"path,omitempty"`
+	TargetVmId      string                `json:"target_vm_id,omitempty" yaml:"target_vm_id,omitempty"`
+	TargetHost      string                `json:"target_host,omitempty" yaml:"target_host,omitempty"`
+	TargetPort      string                `json:"target_port,omitempty" yaml:"target_port,omitempty"`
+	Schema          string                `json:"schema,omitempty" yaml:"scheme,omitempty"`
+	Pattern         string                `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	RegexpPattern   *regexp.Regexp        `json:"-" yaml:"-"`
+	RequestHeaders  map[string]string     `json:"request_headers,omitempty" yaml:"request_headers,omitempty"`
+	ResponseHeaders map[string]string     `json:"response_headers,omitempty" yaml:"response_headers,omitempty"`
+	Upstream        *ReverseProxyUpstream `json:"upstream,omitempty" yaml:"upstream,omitempty"`
+	Auth            *ReverseProxyAuth     `json:"auth,omitempty" yaml:"auth,omitempty"`
+	// Protocol is the protocol used with the target, http2 uses h2c for the
+	// http targets and grpc is http2 with streaming
+	Protocol  string                              `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	Streaming bool                                `json:"streaming,omitempty" yaml:"streaming,omitempty"`
+	WebSocket *ReverseProxyHostHttpRouteWebSocket `json:"websocket,omitempty" yaml:"websocket,omitempty"`
+}
+
+// ReverseProxyHostHttpRouteWebSocket configures the upgraded connections of
+// a route, routes without it accept them with the default idle timeout
+type ReverseProxyHostHttpRouteWebSocket struct {
+	Enabled     bool   `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	IdleTimeout string `json:"idle_timeout,omitempty" yaml:"idle_timeout,omitempty"`
+}
+
+func (o *ReverseProxyHostHttpRoute) IsHttp2() bool {
+	return o.Protocol == constants.REVERSE_PROXY_PROTOCOL_HTTP2 || o.Protocol == constants.REVERSE_PROXY_PROTOCOL_GRPC
+}
+
+func (o *ReverseProxyHostHttpRoute) IsStreaming() bool {
+	return o.Streaming || o.Protocol == constants.REVERSE_PROXY_PROTOCOL_GRPC
 }
 
 func (o *ReverseProxyHostHttpRoute) Diff(source ReverseProxyHostHttpRoute) bool {
@@ -259,7 +355,22 @@ func (o *ReverseProxyHostHttpRoute) Diff(source ReverseProxyHostHttpRoute) bool
 			return true
 		}
 	}
-	return false
+
+	if diffAuth(o.Auth, source.Auth) {
+		return true
+	}
+
+	if o.Protocol != source.Protocol || o.Streaming != source.Streaming {
+		return true
+	}
+	if (o.WebSocket == nil) != (source.WebSocket == nil) {
+		return true
+	}
+	if o.WebSocket != nil && *o.WebSocket != *source.WebSocket {
+		return true
+	}
+
+	return diffUpstream(o.Upstream, source.Upstream)
 }
 
 func (r *ReverseProxyHostHttpRoute) GetRoute() string {
@@ -275,10 +386,11 @@ func (r *ReverseProxyHostHttpRoute) GetRoute() string {
 }
 
 type ReverseProxyHostTcpRoute struct {
-	ID         string `json:"id,omitempty" yaml:"id,omitempty"`
-	TargetPort string `json:"target_port,omitempty" yaml:"target_port,omitempty"`
-	TargetHost string `json:"target_host,omitempty" yaml:"target_host,omitempty"`
-	TargetVmId string `json:"target_vm_id,omitempty" yaml:"target_vm_id,omitempty"`
+	ID         string                `json:"id,omitempty" yaml:"id,omitempty"`
+	TargetPort string                `json:"target_port,omitempty" yaml:"target_port,omitempty"`
+	TargetHost string                `json:"target_host,omitempty" yaml:"target_host,omitempty"`
+	TargetVmId string                `json:"target_vm_id,omitempty" yaml:"target_vm_id,omitempty"`
+	Upstream   *ReverseProxyUpstream `json:"upstream,omitempty" yaml:"upstream,omitempty"`
 }
 
 func (o *ReverseProxyHostTcpRoute) Diff(source ReverseProxyHostTcpRoute) bool {
@@ -295,5 +407,126 @@ func (o *ReverseProxyHostTcpRoute) Diff(source ReverseProxyHostTcpRoute) bool {
 	if o.TargetVmId != source.TargetVmId {
 		return true
 	}
+
+	return diffUpstream(o.Upstream, source.Upstream)
+}
+
+// ReverseProxyHostUdpRoute forwards the datagrams of the host to a single
+// target, the clients are tracked by address until they are idle for the
+// IdleTimeout, and the least recently seen client is dropped when there are
+// already MaxSessions
+type ReverseProxyHostUdpRoute struct {
+	ID          string `json:"id,omitempty" yaml:"id,omitempty"`
+	TargetPort  string `json:"target_port,omitempty" yaml:"target_port,omitempty"`
+	TargetHost  string `json:"target_host,omitempty" yaml:"target_host,omitempty"`
+	TargetVmId  string `json:"target_vm_id,omitempty" yaml:"target_vm_id,omitempty"`
+	IdleTimeout string `json:"idle_timeout,omitempty" yaml:"idle_timeout,omitempty"`
+	MaxSessions int    `json:"max_sessions,omitempty" yaml:"max_sessions,omitempty"`
+}
+
+func (o *ReverseProxyHostUdpRoute) Diff(source ReverseProxyHostUdpRoute) bool {
+	if o == nil {
+		return true
+	}
+
+	if o.TargetPort != source.TargetPort {
+		return true
+	}
+	if o.TargetHost != source.TargetHost {
+		return true
+	}
+	if o.TargetVmId != source.TargetVmId {
+		return true
+	}
+	if o.IdleTimeout != source.IdleTimeout {
+		return true
+	}
+	if o.MaxSessions != source.MaxSessions {
+		return true
+	}
+
 	return false
 }
+
+// ReverseProxyUpstream is a pool of targets for a route, the virtual machines
+// with any of the VmTags are added to the pool when they are running
+type ReverseProxyUpstream struct {
+	Strategy    string                           `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	Targets     []ReverseProxyUpstreamTarget     `json:"targets,omitempty" yaml:"targets,omitempty"`
+	VmTags      []string                         `json:"vm_tags,omitempty" yaml:"vm_tags,omitempty"`
+	HealthCheck *ReverseProxyUpstreamHealthCheck `json:"health_check,omitempty" yaml:"health_check,omitempty"`
+	MaxFails    int                              `json:"max_fails,omitempty" yaml:"max_fails,omitempty"`
+	FailTimeout string                           `json:"fail_timeout,omitempty" yaml:"fail_timeout,omitempty"`
+}
+
+func (o *ReverseProxyUpstream) Diff(source ReverseProxyUpstream) bool {
+	if o == nil {
+		return true
+	}
+
+	if o.Strategy != source.Strategy {
+		return true
+	}
+	if o.MaxFails != source.MaxFails {
+		return true
+	}
+	if o.FailTimeout != source.FailTimeout {
+		return true
+	}
+	if len(o.Targets) != len(source.Targets) {
+		return true
+	}
+	for i, target := range o.Targets {
+		if target != source.Targets[i] {
+			return true
+		}
+	}
+	if len(o.VmTags) != len(source.VmTags) {
+		return true
+	}
+	for i, tag := range o.VmTags {
+		if tag != source.VmTags[i] {
+			return true
+		}
+	}
+
+	if o.HealthCheck == nil && source.HealthCheck != nil {
+		return true
+	}
+	if o.HealthCheck != nil && source.HealthCheck == nil {
+		return true
+	}
+	if o.HealthCheck != nil && source.HealthCheck != nil && *o.HealthCheck != *source.HealthCheck {
+		return true
+	}
+
+	return false
+}
+
+func diffUpstream(upstream *ReverseProxyUpstream, source *ReverseProxyUpstream) bool {
+	if upstream == nil && source == nil {
+		return false
+	}
+	if upstream == nil || source == nil {
+		return true
+	}
+
+	return upstream.Diff(*source)
+}
+
+type ReverseProxyUpstreamTarget struct {
+	TargetVmId string `json:"target_vm_id,omitempty" yaml:"target_vm_id,omitempty"`
+	TargetHost string `json:"target_host,omitempty" yaml:"target_host,omitempty"`
+	TargetPort string `json:"target_port,omitempty" yaml:"target_port,omitempty"`
+}
+
+// ReverseProxyUpstreamHealthCheck checks the targets of a pool, an empty Path
+// checks that the port accepts connections
+type ReverseProxyUpstreamHealthCheck struct {
+	Enabled            bool   `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Path               string `json:"path,omitempty" yaml:"path,omitempty"`
+	Interval           string `json:"interval,omitempty" yaml:"interval,omitempty"`
+	Timeout            string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	HealthyThreshold   int    `json:"healthy_threshold,omitempty" yaml:"healthy_threshold,omitempty"`
+	UnhealthyThreshold int    `json:"unhealthy_threshold,omitempty" yaml:"unhealthy_threshold,omitempty"`
+}
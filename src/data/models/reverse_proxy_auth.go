@@ -0,0 +1,111 @@
+package models
+
+// ReverseProxyAuth protects a host or an http route, the address lists apply
+// to every request and the request has to pass one of the enabled methods
+type ReverseProxyAuth struct {
+	AllowedIps  []string                 `json:"allowed_ips,omitempty" yaml:"allowed_ips,omitempty"`
+	DeniedIps   []string                 `json:"denied_ips,omitempty" yaml:"denied_ips,omitempty"`
+	Devops      *ReverseProxyAuthDevops  `json:"devops,omitempty" yaml:"devops,omitempty"`
+	Basic       *ReverseProxyAuthBasic   `json:"basic,omitempty" yaml:"basic,omitempty"`
+	ForwardAuth *ReverseProxyAuthForward `json:"forward_auth,omitempty" yaml:"forward_auth,omitempty"`
+}
+
+// ReverseProxyAuthDevops accepts the tokens and API keys of the service, the
+// users need one of the Roles and one of the Claims when they are set
+type ReverseProxyAuthDevops struct {
+	Enabled bool     `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Roles   []string `json:"roles,omitempty" yaml:"roles,omitempty"`
+	Claims  []string `json:"claims,omitempty" yaml:"claims,omitempty"`
+}
+
+type ReverseProxyAuthBasic struct {
+	Enabled bool                        `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Realm   string                      `json:"realm,omitempty" yaml:"realm,omitempty"`
+	Users   []ReverseProxyAuthBasicUser `json:"users,omitempty" yaml:"users,omitempty"`
+}
+
+// ReverseProxyAuthBasicUser keeps the bcrypt hash of the password
+type ReverseProxyAuthBasicUser struct {
+	Username     string `json:"username" yaml:"username"`
+	PasswordHash string `json:"password_hash" yaml:"password_hash"`
+}
+
+// ReverseProxyAuthForward asks an external service whether the request is
+// allowed, the ResponseHeaders of an allowed answer are passed to the target
+type ReverseProxyAuthForward struct {
+	Enabled         bool     `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Url             string   `json:"url,omitempty" yaml:"url,omitempty"`
+	ResponseHeaders []string `json:"response_headers,omitempty" yaml:"response_headers,omitempty"`
+	Timeout         string   `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+func (o *ReverseProxyAuth) Diff(source ReverseProxyAuth) bool {
+	if o == nil {
+		return true
+	}
+
+	if diffStrings(o.AllowedIps, source.AllowedIps) || diffStrings(o.DeniedIps, source.DeniedIps) {
+		return true
+	}
+
+	if (o.Devops == nil) != (source.Devops == nil) {
+		return true
+	}
+	if o.Devops != nil {
+		if o.Devops.Enabled != source.Devops.Enabled || diffStrings(o.Devops.Roles, source.Devops.Roles) || diffStrings(o.Devops.Claims, source.Devops.Claims) {
+			return true
+		}
+	}
+
+	if (o.Basic == nil) != (source.Basic == nil) {
+		return true
+	}
+	if o.Basic != nil {
+		if o.Basic.Enabled != source.Basic.Enabled || o.Basic.Realm != source.Basic.Realm || len(o.Basic.Users) != len(source.Basic.Users) {
+			return true
+		}
+		for i, user := range o.Basic.Users {
+			if user != source.Basic.Users[i] {
+				return true
+			}
+		}
+	}
+
+	if (o.ForwardAuth == nil) != (source.ForwardAuth == nil) {
+		return true
+	}
+	if o.ForwardAuth != nil {
+		if o.ForwardAuth.Enabled != source.ForwardAuth.Enabled || o.ForwardAuth.Url != source.ForwardAuth.Url || o.ForwardAuth.Timeout != source.ForwardAuth.Timeout {
+			return true
+		}
+		if diffStrings(o.ForwardAuth.ResponseHeaders, source.ForwardAuth.ResponseHeaders) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func diffAuth(auth *ReverseProxyAuth, source *ReverseProxyAuth) bool {
+	if auth == nil && source == nil {
+		return false
+	}
+	if auth == nil || source == nil {
+		return true
+	}
+
+	return auth.Diff(*source)
+}
+
+func diffStrings(values []string, source []string) bool {
+	if len(values) != len(source) {
+		return true
+	}
+	for i, value := range values {
+		if value != source[i] {
+			return true
+		}
+	}
+
+	return false
+}
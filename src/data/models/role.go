@@ -5,6 +5,7 @@ type Role struct {
 	Name        string  `json:"name"`
 	Description string  `json:"description,omitempty"`
 	Internal    bool    `json:"internal"`
+	MfaRequired bool    `json:"mfa_required,omitempty"`
 	Claims      []Claim `json:"claims,omitempty"`
 	Users       []User  `json:"-"`
 }
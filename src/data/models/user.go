@@ -1,17 +1,35 @@
 package models
 
 type User struct {
-	ID                  string  `json:"id,omitempty"`
-	Username            string  `json:"username"`
-	Name                string  `json:"name"`
-	Email               string  `json:"email"`
-	Password            string  `json:"password,omitempty"`
-	CreatedAt           string  `json:"created_at,omitempty"`
-	UpdatedAt           string  `json:"updated_at,omitempty"`
-	Roles               []Role  `json:"roles,omitempty"`
-	Claims              []Claim `json:"claims,omitempty"`
-	FailedLoginAttempts int     `json:"failed_login_attempts,omitempty"`
-	Blocked             bool    `json:"blocked,omitempty"`
-	BlockedSince        string  `json:"blocked_since,omitempty"`
-	BlockedReason       string  `json:"blocked_reason,omitempty"`
+	ID                  string   `json:"id,omitempty"`
+	Username            string   `json:"username"`
+	Name                string   `json:"name"`
+	Email               string   `json:"email"`
+	Password            string   `json:"password,omitempty"`
+	CreatedAt           string   `json:"created_at,omitempty"`
+	UpdatedAt           string   `json:"updated_at,omitempty"`
+	Roles               []Role   `json:"roles,omitempty"`
+	Claims              []Claim  `json:"claims,omitempty"`
+	FailedLoginAttempts int      `json:"failed_login_attempts,omitempty"`
+	Blocked             bool     `json:"blocked,omitempty"`
+	BlockedSince        string   `json:"blocked_since,omitempty"`
+	BlockedReason       string   `json:"blocked_reason,omitempty"`
+	IdentityProvider    string   `json:"identity_provider,omitempty"`
+	Disabled            bool     `json:"disabled,omitempty"`
+	DisabledReason      string   `json:"disabled_reason,omitempty"`
+	SessionsRevokedAt   string   `json:"sessions_revoked_at,omitempty"`
+	Mfa                 *UserMfa `json:"mfa,omitempty"`
+}
+
+// UserMfa holds the TOTP enrollment of a user, the recovery codes are hashed
+// and removed once used
+type UserMfa struct {
+	Enabled       bool     `json:"enabled,omitempty"`
+	Secret        string   `json:"secret,omitempty"`
+	PendingSecret string   `json:"pending_secret,omitempty"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	EnabledAt     string   `json:"enabled_at,omitempty"`
+	// LastUsedStep is the time step of the last code used, older codes are
+	// rejected so a code cannot be replayed
+	LastUsedStep int64 `json:"last_used_step,omitempty"`
 }
@@ -0,0 +1,29 @@
+package models
+
+// UserSession is a login of a user, the refresh token of the session is
+// rotated every time it is used and only its hash is stored
+type UserSession struct {
+	ID                       string `json:"id"`
+	UserID                   string `json:"user_id"`
+	RefreshTokenHash         string `json:"refresh_token_hash"`
+	PreviousRefreshTokenHash string `json:"previous_refresh_token_hash,omitempty"`
+	AuthenticationMethod     string `json:"authentication_method,omitempty"`
+	IpAddress                string `json:"ip_address,omitempty"`
+	UserAgent                string `json:"user_agent,omitempty"`
+	CreatedAt                string `json:"created_at"`
+	LastUsedAt               string `json:"last_used_at,omitempty"`
+	ExpiresAt                string `json:"expires_at"`
+	Revoked                  bool   `json:"revoked,omitempty"`
+	RevokedAt                string `json:"revoked_at,omitempty"`
+	RevokedReason            string `json:"revoked_reason,omitempty"`
+	*DbRecord                `json:"db_record"`
+}
+
+// RevokedToken is an access token that was revoked before it expired, it is
+// kept until the token expires
+type RevokedToken struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id,omitempty"`
+	ExpiresAt string `json:"expires_at"`
+	RevokedAt string `json:"revoked_at"`
+}
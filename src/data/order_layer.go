@@ -33,10 +33,10 @@ func orderByPropertyAsc[T interface{}](objects []T, property string) ([]T, error
 	rv := reflect.ValueOf(objects)
 	// swap := reflect.Swapper(objects)
 
-	sort.Slice(objects, func(i, j int) bool {
-		iVal := reflect.Indirect(rv.Index(i)).FieldByName(property).String()
-		jVal := reflect.Indirect(rv.Index(j)).FieldByName(property).String()
-		return iVal < jVal
+	sort.SliceStable(objects, func(i, j int) bool {
+		iVal := reflect.Indirect(rv.Index(i)).FieldByName(property)
+		jVal := reflect.Indirect(rv.Index(j)).FieldByName(property)
+		return lessValue(iVal, jVal)
 	})
 
 	return objects, nil
@@ -46,11 +46,32 @@ func orderByPropertyDesc[T interface{}](objects []T, property string) ([]T, erro
 	rv := reflect.ValueOf(objects)
 	// swap := reflect.Swapper(objects)
 
-	sort.Slice(objects, func(i, j int) bool {
-		iVal := reflect.Indirect(rv.Index(i)).FieldByName(property).String()
-		jVal := reflect.Indirect(rv.Index(j)).FieldByName(property).String()
-		return iVal > jVal
+	sort.SliceStable(objects, func(i, j int) bool {
+		iVal := reflect.Indirect(rv.Index(i)).FieldByName(property)
+		jVal := reflect.Indirect(rv.Index(j)).FieldByName(property)
+		return lessValue(jVal, iVal)
 	})
 
 	return objects, nil
 }
+
+// lessValue compares numbers and booleans by value and everything else by its
+// string representation.
+func lessValue(a reflect.Value, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() || a.Kind() != b.Kind() {
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	default:
+		return a.String() < b.String()
+	}
+}
@@ -0,0 +1,55 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderTestRecord struct {
+	Name  string
+	Size  int64
+	Ratio float64
+	Valid bool
+}
+
+func orderTestNames(records []orderTestRecord) []string {
+	result := make([]string, 0)
+	for _, record := range records {
+		result = append(result, record.Name)
+	}
+	return result
+}
+
+func TestOrderByProperty(t *testing.T) {
+	records := func() []orderTestRecord {
+		return []orderTestRecord{
+			{Name: "b", Size: 10, Ratio: 0.5, Valid: true},
+			{Name: "c", Size: 9, Ratio: 0.1, Valid: false},
+			{Name: "a", Size: 100, Ratio: 0.9, Valid: true},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		order    *Order
+		expected []string
+	}{
+		{"nil order keeps the order", nil, []string{"b", "c", "a"}},
+		{"string asc", &Order{Property: "Name", Direction: OrderDirectionAsc}, []string{"a", "b", "c"}},
+		{"string desc", &Order{Property: "Name", Direction: OrderDirectionDesc}, []string{"c", "b", "a"}},
+		{"int asc", &Order{Property: "Size", Direction: OrderDirectionAsc}, []string{"c", "b", "a"}},
+		{"int desc", &Order{Property: "Size", Direction: OrderDirectionDesc}, []string{"a", "b", "c"}},
+		{"float asc", &Order{Property: "Ratio", Direction: OrderDirectionAsc}, []string{"c", "b", "a"}},
+		{"bool asc", &Order{Property: "Valid", Direction: OrderDirectionAsc}, []string{"c", "b", "a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := OrderByProperty(records(), tt.order)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, orderTestNames(result))
+		})
+	}
+}
@@ -0,0 +1,562 @@
+package data
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+const (
+	DefaultListPageSize = 50
+	MaxListPageSize     = 1000
+)
+
+type FilterOperator string
+
+const (
+	FilterOperatorEqual          FilterOperator = "="
+	FilterOperatorNotEqual       FilterOperator = "!="
+	FilterOperatorGreater        FilterOperator = ">"
+	FilterOperatorGreaterOrEqual FilterOperator = ">="
+	FilterOperatorLess           FilterOperator = "<"
+	FilterOperatorLessOrEqual    FilterOperator = "<="
+	FilterOperatorRegex          FilterOperator = "~"
+	FilterOperatorContains       FilterOperator = "contains"
+	FilterOperatorStartsWith     FilterOperator = "startswith"
+	FilterOperatorEndsWith       FilterOperator = "endswith"
+)
+
+type FilterLogicalOperator string
+
+const (
+	FilterLogicalOperatorAnd FilterLogicalOperator = "AND"
+	FilterLogicalOperatorOr  FilterLogicalOperator = "OR"
+)
+
+// FilterCondition compares a property, using its field or json name, with a value
+type FilterCondition struct {
+	Property string
+	Operator FilterOperator
+	Value    string
+	regex    *regexp.Regexp
+}
+
+// FilterExpression is either a single condition or two expressions joined by
+// a logical operator
+type FilterExpression struct {
+	Condition *FilterCondition
+	Operator  FilterLogicalOperator
+	Left      *FilterExpression
+	Right     *FilterExpression
+}
+
+// ListQuery holds the filtering, sorting, pagination and field selection of a
+// list request
+type ListQuery struct {
+	Filter   *FilterExpression
+	Sort     []Order
+	Page     int
+	PageSize int
+	Cursor   string
+	Fields   []string
+}
+
+// ListPage describes the page returned by ApplyListQuery
+type ListPage struct {
+	Total      int
+	Page       int
+	PageSize   int
+	TotalPages int
+	NextCursor string
+	Paginated  bool
+	UsesCursor bool
+}
+
+// ParseFilterExpression parses expressions like
+// `state = running and (name ~ "^build" or cpu >= 4)`, AND takes precedence
+// over OR and values with spaces need to be quoted.
+func ParseFilterExpression(expression string) (*FilterExpression, error) {
+	if strings.TrimSpace(expression) == "" {
+		return nil, nil
+	}
+
+	tokens, err := tokenizeFilterExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := filterExpressionParser{tokens: tokens}
+	result, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.position < len(parser.tokens) {
+		return nil, errors.NewWithCodef(400, "invalid filter, unexpected %v", parser.tokens[parser.position].value)
+	}
+
+	return result, nil
+}
+
+// ParseSort parses a comma separated list of properties, a property prefixed
+// with - is sorted descending
+func ParseSort(sort string) []Order {
+	result := make([]Order, 0)
+	for _, part := range strings.Split(sort, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		order := Order{Property: part, Direction: OrderDirectionAsc}
+		if strings.HasPrefix(part, "-") {
+			order.Property = strings.TrimPrefix(part, "-")
+			order.Direction = OrderDirectionDesc
+		} else if strings.HasPrefix(part, "+") {
+			order.Property = strings.TrimPrefix(part, "+")
+		}
+		result = append(result, order)
+	}
+
+	return result
+}
+
+// Matches returns true if the object satisfies the expression
+func (e *FilterExpression) Matches(obj interface{}) bool {
+	if e == nil {
+		return true
+	}
+	if e.Condition != nil {
+		return e.Condition.Matches(obj)
+	}
+	if e.Operator == FilterLogicalOperatorOr {
+		return e.Left.Matches(obj) || e.Right.Matches(obj)
+	}
+
+	return e.Left.Matches(obj) && e.Right.Matches(obj)
+}
+
+// Matches returns true if the object property satisfies the condition, objects
+// without the property never match
+func (c *FilterCondition) Matches(obj interface{}) bool {
+	property := reflect.Indirect(getProperty(reflect.ValueOf(obj), c.Property))
+	if !propertyIsValid(property) {
+		return false
+	}
+
+	value := property.Interface()
+	switch property.Kind() {
+	case reflect.Slice, reflect.Array:
+		// a list matches if any of its items match, or none of them for !=
+		if c.Operator == FilterOperatorNotEqual {
+			equal := FilterCondition{Property: c.Property, Operator: FilterOperatorEqual, Value: c.Value}
+			for i := 0; i < property.Len(); i++ {
+				if equal.matchesValue(reflect.Indirect(property.Index(i))) {
+					return false
+				}
+			}
+			return true
+		}
+		for i := 0; i < property.Len(); i++ {
+			if c.matchesValue(reflect.Indirect(property.Index(i))) {
+				return true
+			}
+		}
+		return false
+	case reflect.Map:
+		return c.matchesString(fmt.Sprintf("%v", value))
+	}
+
+	return c.matchesValue(property)
+}
+
+func (c *FilterCondition) matchesValue(property reflect.Value) bool {
+	if !property.IsValid() {
+		return false
+	}
+
+	var compare int
+	switch property.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		expected, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			return c.matchesString(fmt.Sprintf("%v", property.Interface()))
+		}
+		actual, _ := strconv.ParseFloat(fmt.Sprintf("%v", property.Interface()), 64)
+		switch {
+		case actual < expected:
+			compare = -1
+		case actual > expected:
+			compare = 1
+		}
+	case reflect.Bool:
+		expected, err := strconv.ParseBool(c.Value)
+		if err != nil {
+			return false
+		}
+		if property.Bool() != expected {
+			compare = 1
+		}
+	default:
+		return c.matchesString(fmt.Sprintf("%v", property.Interface()))
+	}
+
+	return c.matchesCompare(compare)
+}
+
+func (c *FilterCondition) matchesString(actual string) bool {
+	switch c.Operator {
+	case FilterOperatorRegex:
+		return c.regex.MatchString(actual)
+	case FilterOperatorContains:
+		return strings.Contains(strings.ToLower(actual), strings.ToLower(c.Value))
+	case FilterOperatorStartsWith:
+		return strings.HasPrefix(strings.ToLower(actual), strings.ToLower(c.Value))
+	case FilterOperatorEndsWith:
+		return strings.HasSuffix(strings.ToLower(actual), strings.ToLower(c.Value))
+	}
+
+	return c.matchesCompare(strings.Compare(actual, c.Value))
+}
+
+func (c *FilterCondition) matchesCompare(compare int) bool {
+	switch c.Operator {
+	case FilterOperatorEqual:
+		return compare == 0
+	case FilterOperatorNotEqual:
+		return compare != 0
+	case FilterOperatorGreater:
+		return compare > 0
+	case FilterOperatorGreaterOrEqual:
+		return compare >= 0
+	case FilterOperatorLess:
+		return compare < 0
+	case FilterOperatorLessOrEqual:
+		return compare <= 0
+	}
+
+	return false
+}
+
+// FilterByExpression returns the objects matching the expression
+func FilterByExpression[T interface{}](objects []T, expression *FilterExpression) []T {
+	if expression == nil {
+		return objects
+	}
+
+	result := make([]T, 0)
+	for _, obj := range objects {
+		if expression.Matches(obj) {
+			result = append(result, obj)
+		}
+	}
+
+	return result
+}
+
+// SortByProperties sorts the objects by one or more properties, the properties
+// can use the field or json names and dots for nested fields
+func SortByProperties[T interface{}](objects []T, orders []Order) {
+	if len(orders) == 0 {
+		return
+	}
+
+	values := make([][]reflect.Value, len(objects))
+	for i, obj := range objects {
+		values[i] = make([]reflect.Value, len(orders))
+		for o, order := range orders {
+			values[i][o] = reflect.Indirect(getProperty(reflect.ValueOf(obj), order.Property))
+		}
+	}
+
+	indexes := make([]int, len(objects))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		a, b := indexes[i], indexes[j]
+		for o, order := range orders {
+			aVal := values[a][o]
+			bVal := values[b][o]
+			if order.Direction == OrderDirectionDesc {
+				aVal, bVal = bVal, aVal
+			}
+			if lessValue(aVal, bVal) {
+				return true
+			}
+			if lessValue(bVal, aVal) {
+				return false
+			}
+		}
+		return false
+	})
+
+	sorted := make([]T, len(objects))
+	for i, index := range indexes {
+		sorted[i] = objects[index]
+	}
+	copy(objects, sorted)
+}
+
+// ApplyListQuery filters, sorts and paginates the objects. Without a page,
+// page size or cursor all the matching objects are returned.
+func ApplyListQuery[T interface{}](objects []T, query *ListQuery) ([]T, *ListPage, error) {
+	if query == nil {
+		query = &ListQuery{}
+	}
+
+	result := FilterByExpression(objects, query.Filter)
+	SortByProperties(result, query.Sort)
+
+	page := &ListPage{
+		Total: len(result),
+	}
+	if query.Page == 0 && query.PageSize == 0 && query.Cursor == "" {
+		page.Page = 1
+		page.PageSize = len(result)
+		page.TotalPages = 1
+		return result, page, nil
+	}
+
+	page.Paginated = true
+	page.PageSize = query.PageSize
+	if page.PageSize <= 0 {
+		page.PageSize = DefaultListPageSize
+	}
+	if page.PageSize > MaxListPageSize {
+		return nil, nil, errors.NewWithCodef(400, "page_size cannot be bigger than %v", MaxListPageSize)
+	}
+
+	start := 0
+	if query.Cursor != "" {
+		offset, err := DecodeListCursor(query.Cursor)
+		if err != nil {
+			return nil, nil, err
+		}
+		page.UsesCursor = true
+		start = offset
+		page.Page = offset/page.PageSize + 1
+	} else {
+		if query.Page < 0 {
+			return nil, nil, errors.NewWithCode("page cannot be negative", 400)
+		}
+		page.Page = query.Page
+		if page.Page == 0 {
+			page.Page = 1
+		}
+		start = (page.Page - 1) * page.PageSize
+	}
+	page.TotalPages = (page.Total + page.PageSize - 1) / page.PageSize
+
+	if start >= len(result) {
+		return make([]T, 0), page, nil
+	}
+	end := start + page.PageSize
+	if end > len(result) {
+		end = len(result)
+	}
+	if end < len(result) {
+		page.NextCursor = EncodeListCursor(end)
+	}
+
+	return result[start:end], page, nil
+}
+
+// EncodeListCursor returns the opaque token for a position in a list
+func EncodeListCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("offset:%d", offset)))
+}
+
+// DecodeListCursor returns the position in a list of a cursor token
+func DecodeListCursor(cursor string) (int, error) {
+	content, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(content), "offset:") {
+		return 0, errors.NewWithCode("invalid cursor", 400)
+	}
+
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(content), "offset:"))
+	if err != nil || offset < 0 {
+		return 0, errors.NewWithCode("invalid cursor", 400)
+	}
+
+	return offset, nil
+}
+
+type filterTokenType int
+
+const (
+	filterTokenText filterTokenType = iota
+	filterTokenQuoted
+	filterTokenOperator
+	filterTokenOpenParen
+	filterTokenCloseParen
+)
+
+type filterToken struct {
+	kind  filterTokenType
+	value string
+}
+
+func tokenizeFilterExpression(expression string) ([]filterToken, error) {
+	tokens := make([]filterToken, 0)
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{kind: filterTokenOpenParen, value: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{kind: filterTokenCloseParen, value: ")"})
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			var value strings.Builder
+			for ; end < len(runes) && runes[end] != r; end++ {
+				if runes[end] == '\\' && end+1 < len(runes) && runes[end+1] == r {
+					end++
+				}
+				value.WriteRune(runes[end])
+			}
+			if end >= len(runes) {
+				return nil, errors.NewWithCode("invalid filter, missing closing quote", 400)
+			}
+			tokens = append(tokens, filterToken{kind: filterTokenQuoted, value: value.String()})
+			i = end + 1
+		case strings.ContainsRune("=!<>~", r):
+			end := i + 1
+			for end < len(runes) && strings.ContainsRune("=!<>~", runes[end]) {
+				end++
+			}
+			operator := string(runes[i:end])
+			switch FilterOperator(operator) {
+			case FilterOperatorEqual, FilterOperatorNotEqual, FilterOperatorGreater, FilterOperatorGreaterOrEqual,
+				FilterOperatorLess, FilterOperatorLessOrEqual, FilterOperatorRegex:
+			case "==":
+				operator = string(FilterOperatorEqual)
+			default:
+				return nil, errors.NewWithCodef(400, "invalid filter operator %v", operator)
+			}
+			tokens = append(tokens, filterToken{kind: filterTokenOperator, value: operator})
+			i = end
+		default:
+			end := i
+			for end < len(runes) && !strings.ContainsRune(" \t\n()\"'=!<>~", runes[end]) {
+				end++
+			}
+			tokens = append(tokens, filterToken{kind: filterTokenText, value: string(runes[i:end])})
+			i = end
+		}
+	}
+
+	return tokens, nil
+}
+
+type filterExpressionParser struct {
+	tokens   []filterToken
+	position int
+}
+
+func (p *filterExpressionParser) peekKeyword(keyword string) bool {
+	if p.position >= len(p.tokens) {
+		return false
+	}
+	token := p.tokens[p.position]
+	return token.kind == filterTokenText && strings.EqualFold(token.value, keyword)
+}
+
+func (p *filterExpressionParser) parseOr() (*FilterExpression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword(string(FilterLogicalOperatorOr)) {
+		p.position++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &FilterExpression{Operator: FilterLogicalOperatorOr, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterExpressionParser) parseAnd() (*FilterExpression, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword(string(FilterLogicalOperatorAnd)) {
+		p.position++
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &FilterExpression{Operator: FilterLogicalOperatorAnd, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterExpressionParser) parseTerm() (*FilterExpression, error) {
+	if p.position >= len(p.tokens) {
+		return nil, errors.NewWithCode("invalid filter, unexpected end of expression", 400)
+	}
+
+	if p.tokens[p.position].kind == filterTokenOpenParen {
+		p.position++
+		expression, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.position >= len(p.tokens) || p.tokens[p.position].kind != filterTokenCloseParen {
+			return nil, errors.NewWithCode("invalid filter, missing closing parenthesis", 400)
+		}
+		p.position++
+		return expression, nil
+	}
+
+	if p.position+3 > len(p.tokens) {
+		return nil, errors.NewWithCode("invalid filter, expected property operator value", 400)
+	}
+	property := p.tokens[p.position]
+	operator := p.tokens[p.position+1]
+	value := p.tokens[p.position+2]
+	if property.kind != filterTokenText || value.kind == filterTokenOpenParen || value.kind == filterTokenCloseParen || value.kind == filterTokenOperator {
+		return nil, errors.NewWithCode("invalid filter, expected property operator value", 400)
+	}
+
+	condition := &FilterCondition{Property: property.value, Value: value.value}
+	switch {
+	case operator.kind == filterTokenOperator:
+		condition.Operator = FilterOperator(operator.value)
+	case operator.kind == filterTokenText && strings.EqualFold(operator.value, string(FilterOperatorContains)):
+		condition.Operator = FilterOperatorContains
+	case operator.kind == filterTokenText && strings.EqualFold(operator.value, string(FilterOperatorStartsWith)):
+		condition.Operator = FilterOperatorStartsWith
+	case operator.kind == filterTokenText && strings.EqualFold(operator.value, string(FilterOperatorEndsWith)):
+		condition.Operator = FilterOperatorEndsWith
+	default:
+		return nil, errors.NewWithCodef(400, "invalid filter operator %v", operator.value)
+	}
+	if condition.Operator == FilterOperatorRegex {
+		regex, err := regexp.Compile(condition.Value)
+		if err != nil {
+			return nil, errors.NewWithCodef(400, "invalid filter regular expression %v", condition.Value)
+		}
+		condition.regex = regex
+	}
+
+	p.position += 3
+	return &FilterExpression{Condition: condition}, nil
+}
@@ -0,0 +1,109 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type queryTestHost struct {
+	Name  string   `json:"name"`
+	State string   `json:"state"`
+	Cpu   int      `json:"cpu"`
+	Tags  []string `json:"tags"`
+	Arch  *queryTestArch
+}
+
+type queryTestArch struct {
+	Family string `json:"family"`
+}
+
+func queryTestHosts() []queryTestHost {
+	return []queryTestHost{
+		{Name: "build-01", State: "running", Cpu: 4, Tags: []string{"ci"}, Arch: &queryTestArch{Family: "arm64"}},
+		{Name: "build-02", State: "stopped", Cpu: 8, Tags: []string{"ci", "large"}},
+		{Name: "dev box", State: "running", Cpu: 2},
+	}
+}
+
+func queryTestNames(hosts []queryTestHost) []string {
+	names := make([]string, 0)
+	for _, host := range hosts {
+		names = append(names, host.Name)
+	}
+	return names
+}
+
+func TestParseFilterExpression(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		expected   []string
+	}{
+		{"equal", "state = running", []string{"build-01", "dev box"}},
+		{"quoted value", `name = "dev box"`, []string{"dev box"}},
+		{"numeric compare", "cpu >= 4", []string{"build-01", "build-02"}},
+		{"regex", "name ~ ^build", []string{"build-01", "build-02"}},
+		{"contains", "NAME contains BOX", []string{"dev box"}},
+		{"and before or", "state = stopped or state = running and cpu < 4", []string{"build-02", "dev box"}},
+		{"parenthesis", "(state = stopped or state = running) and cpu < 4", []string{"dev box"}},
+		{"list contains", "tags = large", []string{"build-02"}},
+		{"list not equal", "tags != ci", []string{"dev box"}},
+		{"nested pointer", "arch.family = arm64", []string{"build-01"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, err := ParseFilterExpression(tt.expression)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, queryTestNames(FilterByExpression(queryTestHosts(), expression)))
+		})
+	}
+}
+
+func TestParseFilterExpression_Invalid(t *testing.T) {
+	for _, expression := range []string{"state", "state running", "state = running and", "(state = running", `name = "open`, "name ~ (", "state => running"} {
+		_, err := ParseFilterExpression(expression)
+		assert.Error(t, err, expression)
+		assert.Equal(t, 400, errors.GetSystemErrorCode(err), expression)
+	}
+}
+
+func TestSortByProperties(t *testing.T) {
+	hosts := queryTestHosts()
+	SortByProperties(hosts, ParseSort("state,-cpu"))
+
+	assert.Equal(t, []string{"build-01", "dev box", "build-02"}, queryTestNames(hosts))
+}
+
+func TestApplyListQuery_Pages(t *testing.T) {
+	page, info, err := ApplyListQuery(queryTestHosts(), &ListQuery{Page: 2, PageSize: 2})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"dev box"}, queryTestNames(page))
+	assert.Equal(t, 3, info.Total)
+	assert.Equal(t, 2, info.TotalPages)
+	assert.Empty(t, info.NextCursor)
+
+	page, info, err = ApplyListQuery(queryTestHosts(), nil)
+	require.NoError(t, err)
+	assert.Len(t, page, 3)
+	assert.False(t, info.Paginated)
+}
+
+func TestApplyListQuery_Cursor(t *testing.T) {
+	page, info, err := ApplyListQuery(queryTestHosts(), &ListQuery{Cursor: EncodeListCursor(0), PageSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"build-01", "build-02"}, queryTestNames(page))
+	require.NotEmpty(t, info.NextCursor)
+
+	page, info, err = ApplyListQuery(queryTestHosts(), &ListQuery{Cursor: info.NextCursor, PageSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev box"}, queryTestNames(page))
+	assert.Empty(t, info.NextCursor)
+
+	_, _, err = ApplyListQuery(queryTestHosts(), &ListQuery{Cursor: "invalid"})
+	assert.Equal(t, 400, errors.GetSystemErrorCode(err))
+}
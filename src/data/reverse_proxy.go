@@ -17,6 +17,8 @@ var (
 	ErrorReverseProxyHttpRouteOrderUpdate      = errors.NewWithCode("cannot update reverse proxy host http route and change order at the same time", 400)
 	ErrorReverseProxyTcpRouteNotFound          = errors.NewWithCode("reverse proxy host tcp route not found", 404)
 	ErrorReverseProxyTcpRouteWithHttpRouteHost = errors.NewWithCode("cannot update reverse proxy TCP route when HTTP routes are present", 400)
+	ErrorReverseProxyUdpRouteNotFound          = errors.NewWithCode("reverse proxy host udp route not found", 404)
+	ErrorReverseProxyUdpRouteWithHttpRouteHost = errors.NewWithCode("cannot update reverse proxy UDP route when HTTP routes are present", 400)
 )
 
 func normalizeReverseProxyHttpRoutesOrder(routes []*models.ReverseProxyHostHttpRoute) {
@@ -139,6 +141,9 @@ func (j *JsonDatabase) CreateReverseProxyHost(ctx basecontext.ApiContext, rpHost
 	if rpHost.TcpRoute != nil {
 		rpHost.TcpRoute.ID = helpers.GenerateId()
 	}
+	if rpHost.UdpRoute != nil {
+		rpHost.UdpRoute.ID = helpers.GenerateId()
+	}
 	if rpHost.HttpRoutes != nil {
 		for _, route := range rpHost.HttpRoutes {
 			route.ID = helpers.GenerateId()
@@ -163,6 +168,12 @@ func (j *JsonDatabase) DeleteReverseProxyHost(ctx basecontext.ApiContext, idOrNa
 	for i, rpHost := range j.data.ReverseProxyHosts {
 		if strings.EqualFold(rpHost.ID, idOrName) || strings.EqualFold(rpHost.GetHost(), idOrName) {
 			j.data.ReverseProxyHosts = append(j.data.ReverseProxyHosts[:i], j.data.ReverseProxyHosts[i+1:]...)
+			for k, certificate := range j.data.AcmeCertificates {
+				if strings.EqualFold(certificate.HostID, rpHost.ID) {
+					j.data.AcmeCertificates = append(j.data.AcmeCertificates[:k], j.data.AcmeCertificates[k+1:]...)
+					break
+				}
+			}
 			_ = j.SaveNow(ctx)
 			return nil
 		}
@@ -200,6 +211,9 @@ func (j *JsonDatabase) UpdateReverseProxyHost(ctx basecontext.ApiContext, rpHost
 				if rpHost.Cors != nil {
 					j.data.ReverseProxyHosts[i].Cors = rpHost.Cors
 				}
+				if rpHost.Auth != nil {
+					j.data.ReverseProxyHosts[i].Auth = rpHost.Auth
+				}
 
 				_ = j.SaveNow(ctx)
 				return &j.data.ReverseProxyHosts[i], nil
@@ -422,3 +436,37 @@ func (j *JsonDatabase) UpdateReverseProxyHostTcpRoute(ctx basecontext.ApiContext
 
 	return nil, ErrorReverseProxyHostNotFound
 }
+
+func (j *JsonDatabase) UpdateReverseProxyHostUdpRoute(ctx basecontext.ApiContext, rpHostId string, route models.ReverseProxyHostUdpRoute) (*models.ReverseProxyHostUdpRoute, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	rpHost, err := j.GetReverseProxyHost(ctx, rpHostId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rpHost.HttpRoutes) > 0 {
+		return nil, ErrorReverseProxyUdpRouteWithHttpRouteHost
+	}
+
+	if rpHost.UdpRoute == nil {
+		return nil, ErrorReverseProxyUdpRouteNotFound
+	}
+
+	route.ID = rpHost.UdpRoute.ID
+	if !rpHost.UdpRoute.Diff(route) {
+		return rpHost.UdpRoute, nil
+	}
+
+	for i, h := range j.data.ReverseProxyHosts {
+		if h.ID == rpHost.ID {
+			j.data.ReverseProxyHosts[i].UdpRoute = &route
+			_ = j.SaveNow(ctx)
+			return &route, nil
+		}
+	}
+
+	return nil, ErrorReverseProxyHostNotFound
+}
@@ -241,6 +241,24 @@ func (j *JsonDatabase) UpdateRoleDescription(ctx basecontext.ApiContext, idOrNam
 	return ErrRoleNotFound
 }
 
+// UpdateRoleMfaRequired sets whether the users of the role have to log in
+// with a second factor, internal roles can also require it
+func (j *JsonDatabase) UpdateRoleMfaRequired(ctx basecontext.ApiContext, idOrName string, required bool) (*models.Role, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+	if idOrName == "" {
+		return nil, ErrRoleEmptyNameOrId
+	}
+	for i, r := range j.data.Roles {
+		if strings.EqualFold(r.ID, idOrName) || strings.EqualFold(r.Name, idOrName) {
+			j.data.Roles[i].MfaRequired = required
+			return j.GetRole(ctx, r.ID)
+		}
+	}
+	return nil, ErrRoleNotFound
+}
+
 func (j *JsonDatabase) UpdateRole(ctx basecontext.ApiContext, role *models.Role) (*models.Role, error) {
 	if !j.IsConnected() {
 		return nil, ErrDatabaseNotConnected
@@ -0,0 +1,154 @@
+package data
+
+import (
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/helpers"
+)
+
+var (
+	ErrUserMfaNotEnrolled     = errors.NewWithCode("multi-factor authentication is not enrolled", 400)
+	ErrUserMfaCodeAlreadyUsed = errors.NewWithCode("multi-factor authentication code was already used", 401)
+)
+
+// SetUserMfaPendingSecret starts the enrollment of the user, the secret is
+// only used once a code generated with it was verified
+func (j *JsonDatabase) SetUserMfaPendingSecret(ctx basecontext.ApiContext, userId string, secret string) error {
+	if !j.IsConnected() {
+		return ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.Lock()
+	defer j.dataMutex.Unlock()
+
+	for i, user := range j.data.Users {
+		if strings.EqualFold(user.ID, userId) {
+			if j.data.Users[i].Mfa == nil {
+				j.data.Users[i].Mfa = &models.UserMfa{}
+			}
+			j.data.Users[i].Mfa.PendingSecret = secret
+			j.data.Users[i].UpdatedAt = helpers.GetUtcCurrentDateTime()
+
+			return nil
+		}
+	}
+
+	return ErrUserNotFound
+}
+
+// EnableUserMfa completes the enrollment of the user, the pending secret
+// becomes the secret of the user and the recovery codes are replaced
+func (j *JsonDatabase) EnableUserMfa(ctx basecontext.ApiContext, userId string, recoveryCodes []string, usedStep int64) error {
+	if !j.IsConnected() {
+		return ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.Lock()
+	defer j.dataMutex.Unlock()
+
+	for i, user := range j.data.Users {
+		if strings.EqualFold(user.ID, userId) {
+			if user.Mfa == nil || user.Mfa.PendingSecret == "" {
+				return ErrUserMfaNotEnrolled
+			}
+
+			j.data.Users[i].Mfa = &models.UserMfa{
+				Enabled:       true,
+				Secret:        user.Mfa.PendingSecret,
+				RecoveryCodes: recoveryCodes,
+				EnabledAt:     helpers.GetUtcCurrentDateTime(),
+				LastUsedStep:  usedStep,
+			}
+			j.data.Users[i].UpdatedAt = helpers.GetUtcCurrentDateTime()
+
+			return nil
+		}
+	}
+
+	return ErrUserNotFound
+}
+
+// DisableUserMfa removes the enrollment of the user
+func (j *JsonDatabase) DisableUserMfa(ctx basecontext.ApiContext, userId string) error {
+	if !j.IsConnected() {
+		return ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.Lock()
+	defer j.dataMutex.Unlock()
+
+	for i, user := range j.data.Users {
+		if strings.EqualFold(user.ID, userId) {
+			j.data.Users[i].Mfa = nil
+			j.data.Users[i].UpdatedAt = helpers.GetUtcCurrentDateTime()
+
+			return nil
+		}
+	}
+
+	return ErrUserNotFound
+}
+
+// UpdateUserMfaLastUsedStep records the time step of a verified code, steps
+// that are not newer than the last one are rejected
+func (j *JsonDatabase) UpdateUserMfaLastUsedStep(ctx basecontext.ApiContext, userId string, step int64) error {
+	if !j.IsConnected() {
+		return ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.Lock()
+	defer j.dataMutex.Unlock()
+
+	for i, user := range j.data.Users {
+		if strings.EqualFold(user.ID, userId) {
+			if user.Mfa == nil || !user.Mfa.Enabled {
+				return ErrUserMfaNotEnrolled
+			}
+			if step <= user.Mfa.LastUsedStep {
+				return ErrUserMfaCodeAlreadyUsed
+			}
+
+			j.data.Users[i].Mfa.LastUsedStep = step
+			return nil
+		}
+	}
+
+	return ErrUserNotFound
+}
+
+// UseUserMfaRecoveryCode removes the hashed recovery code from the user, it
+// returns false when the user does not have it
+func (j *JsonDatabase) UseUserMfaRecoveryCode(ctx basecontext.ApiContext, userId string, recoveryCodeHash string) (bool, error) {
+	if !j.IsConnected() {
+		return false, ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.Lock()
+	defer j.dataMutex.Unlock()
+
+	for i, user := range j.data.Users {
+		if strings.EqualFold(user.ID, userId) {
+			if user.Mfa == nil || !user.Mfa.Enabled {
+				return false, ErrUserMfaNotEnrolled
+			}
+
+			for index, code := range user.Mfa.RecoveryCodes {
+				if sameHash(code, recoveryCodeHash) {
+					remaining := make([]string, 0, len(user.Mfa.RecoveryCodes)-1)
+					remaining = append(remaining, user.Mfa.RecoveryCodes[:index]...)
+					remaining = append(remaining, user.Mfa.RecoveryCodes[index+1:]...)
+					j.data.Users[i].Mfa.RecoveryCodes = remaining
+					j.data.Users[i].UpdatedAt = helpers.GetUtcCurrentDateTime()
+					return true, nil
+				}
+			}
+
+			return false, nil
+		}
+	}
+
+	return false, ErrUserNotFound
+}
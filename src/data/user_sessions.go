@@ -0,0 +1,316 @@
+package data
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/helpers"
+)
+
+var (
+	ErrUserSessionNotFound = errors.NewWithCode("session not found", 404)
+	ErrUserSessionExpired  = errors.NewWithCode("session has expired", 401)
+	ErrUserSessionRevoked  = errors.NewWithCode("session has been revoked", 401)
+	ErrInvalidRefreshToken = errors.NewWithCode("refresh token is not valid", 401)
+)
+
+// CreateUserSession stores a new session, only the hash of the refresh token
+// is kept
+func (j *JsonDatabase) CreateUserSession(ctx basecontext.ApiContext, session models.UserSession, refreshToken string) (*models.UserSession, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+	if session.UserID == "" {
+		return nil, errors.NewWithCode("session user cannot be empty", 400)
+	}
+	if refreshToken == "" {
+		return nil, errors.NewWithCode("session refresh token cannot be empty", 400)
+	}
+
+	if session.ID == "" {
+		session.ID = helpers.GenerateId()
+	}
+	session.RefreshTokenHash = hashRefreshToken(refreshToken)
+	session.PreviousRefreshTokenHash = ""
+	session.CreatedAt = helpers.GetUtcCurrentDateTime()
+	session.LastUsedAt = session.CreatedAt
+	session.DbRecord = &models.DbRecord{}
+
+	j.dataMutex.Lock()
+	j.data.UserSessions = append(j.data.UserSessions, session)
+	j.dataMutex.Unlock()
+
+	return &session, nil
+}
+
+func (j *JsonDatabase) GetUserSession(ctx basecontext.ApiContext, id string) (*models.UserSession, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.RLock()
+	defer j.dataMutex.RUnlock()
+
+	for _, session := range j.data.UserSessions {
+		if session.ID == id {
+			copy := session
+			return &copy, nil
+		}
+	}
+
+	return nil, ErrUserSessionNotFound
+}
+
+// GetUserSessions returns the sessions of the user that are still active
+func (j *JsonDatabase) GetUserSessions(ctx basecontext.ApiContext, userId string) ([]models.UserSession, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.RLock()
+	defer j.dataMutex.RUnlock()
+
+	result := make([]models.UserSession, 0)
+	for _, session := range j.data.UserSessions {
+		if !strings.EqualFold(session.UserID, userId) || session.Revoked || isExpired(session.ExpiresAt) {
+			continue
+		}
+		result = append(result, session)
+	}
+
+	return result, nil
+}
+
+// RefreshUserSession replaces the refresh token of the session. A refresh
+// token can only be used once, using the previous one again means it was
+// stolen so the session is revoked
+func (j *JsonDatabase) RefreshUserSession(ctx basecontext.ApiContext, id string, refreshToken string, newRefreshToken string, sourceIp string) (*models.UserSession, error) {
+	if !j.IsConnected() {
+		return nil, ErrDatabaseNotConnected
+	}
+
+	hash := hashRefreshToken(refreshToken)
+
+	j.dataMutex.Lock()
+	defer j.dataMutex.Unlock()
+
+	for i, session := range j.data.UserSessions {
+		if session.ID != id {
+			continue
+		}
+
+		if session.Revoked {
+			return nil, ErrUserSessionRevoked
+		}
+		if isExpired(session.ExpiresAt) {
+			return nil, ErrUserSessionExpired
+		}
+
+		if !sameHash(session.RefreshTokenHash, hash) {
+			if session.PreviousRefreshTokenHash != "" && sameHash(session.PreviousRefreshTokenHash, hash) {
+				j.revokeUserSession(ctx, i, constants.SESSION_REVOKED_REFRESH_TOKEN_REUSED)
+				ctx.LogWarnf("[Sessions] Refresh token of session %v was reused, the session was revoked", session.ID)
+			}
+			return nil, ErrInvalidRefreshToken
+		}
+
+		for {
+			if IsRecordLocked(j.data.UserSessions[i].DbRecord) {
+				continue
+			}
+			LockRecord(ctx, j.data.UserSessions[i].DbRecord)
+			j.data.UserSessions[i].PreviousRefreshTokenHash = session.RefreshTokenHash
+			j.data.UserSessions[i].RefreshTokenHash = hashRefreshToken(newRefreshToken)
+			j.data.UserSessions[i].LastUsedAt = helpers.GetUtcCurrentDateTime()
+			if sourceIp != "" {
+				j.data.UserSessions[i].IpAddress = sourceIp
+			}
+			UnlockRecord(ctx, j.data.UserSessions[i].DbRecord)
+			break
+		}
+
+		result := j.data.UserSessions[i]
+		return &result, nil
+	}
+
+	return nil, ErrInvalidRefreshToken
+}
+
+func (j *JsonDatabase) RevokeUserSession(ctx basecontext.ApiContext, id string, reason string) error {
+	if !j.IsConnected() {
+		return ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.Lock()
+	defer j.dataMutex.Unlock()
+
+	for i, session := range j.data.UserSessions {
+		if session.ID == id {
+			j.revokeUserSession(ctx, i, reason)
+			return nil
+		}
+	}
+
+	return ErrUserSessionNotFound
+}
+
+// RevokeUserSessions logs the user out everywhere, the sessions of the user are
+// revoked and so are the tokens issued before now, including the ones that do
+// not belong to a session
+func (j *JsonDatabase) RevokeUserSessions(ctx basecontext.ApiContext, userId string, reason string) (int, error) {
+	if !j.IsConnected() {
+		return 0, ErrDatabaseNotConnected
+	}
+
+	user, err := j.GetUser(ctx, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	return j.revokeUserSessions(ctx, user.ID, reason), nil
+}
+
+// RevokeToken adds an access token to the revocation list
+func (j *JsonDatabase) RevokeToken(ctx basecontext.ApiContext, token models.RevokedToken) error {
+	if !j.IsConnected() {
+		return ErrDatabaseNotConnected
+	}
+	if token.ID == "" {
+		return errors.NewWithCode("token id cannot be empty", 400)
+	}
+
+	token.RevokedAt = helpers.GetUtcCurrentDateTime()
+
+	j.dataMutex.Lock()
+	defer j.dataMutex.Unlock()
+
+	for _, revoked := range j.data.RevokedTokens {
+		if revoked.ID == token.ID {
+			return nil
+		}
+	}
+	j.data.RevokedTokens = append(j.data.RevokedTokens, token)
+
+	return nil
+}
+
+func (j *JsonDatabase) IsTokenRevoked(ctx basecontext.ApiContext, id string) bool {
+	if !j.IsConnected() || id == "" {
+		return false
+	}
+
+	j.dataMutex.RLock()
+	defer j.dataMutex.RUnlock()
+
+	for _, revoked := range j.data.RevokedTokens {
+		if revoked.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DeleteExpiredUserSessions removes the sessions that expired or were revoked
+// and the revoked tokens that already expired
+func (j *JsonDatabase) DeleteExpiredUserSessions(ctx basecontext.ApiContext) error {
+	if !j.IsConnected() {
+		return ErrDatabaseNotConnected
+	}
+
+	j.dataMutex.Lock()
+	defer j.dataMutex.Unlock()
+
+	sessions := j.data.UserSessions[:0]
+	for _, session := range j.data.UserSessions {
+		if session.Revoked || isExpired(session.ExpiresAt) {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	j.data.UserSessions = sessions
+
+	tokens := j.data.RevokedTokens[:0]
+	for _, token := range j.data.RevokedTokens {
+		if isExpired(token.ExpiresAt) {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	j.data.RevokedTokens = tokens
+
+	return nil
+}
+
+// revokeUserSessions revokes every session of the user and records when it
+// happened so older tokens are rejected
+func (j *JsonDatabase) revokeUserSessions(ctx basecontext.ApiContext, userId string, reason string) int {
+	j.dataMutex.Lock()
+	defer j.dataMutex.Unlock()
+
+	count := 0
+	for i, session := range j.data.UserSessions {
+		if !strings.EqualFold(session.UserID, userId) || session.Revoked {
+			continue
+		}
+		j.revokeUserSession(ctx, i, reason)
+		count++
+	}
+
+	for i, user := range j.data.Users {
+		if strings.EqualFold(user.ID, userId) {
+			j.data.Users[i].SessionsRevokedAt = helpers.GetUtcCurrentDateTime()
+			break
+		}
+	}
+
+	if count > 0 && ctx != nil {
+		ctx.LogInfof("[Sessions] Revoked %v sessions of user %v, %v", count, userId, reason)
+	}
+
+	return count
+}
+
+// revokeUserSession expects the caller to hold the data lock
+func (j *JsonDatabase) revokeUserSession(ctx basecontext.ApiContext, index int, reason string) {
+	for {
+		if IsRecordLocked(j.data.UserSessions[index].DbRecord) {
+			continue
+		}
+		LockRecord(ctx, j.data.UserSessions[index].DbRecord)
+		j.data.UserSessions[index].Revoked = true
+		j.data.UserSessions[index].RevokedAt = helpers.GetUtcCurrentDateTime()
+		j.data.UserSessions[index].RevokedReason = reason
+		UnlockRecord(ctx, j.data.UserSessions[index].DbRecord)
+		break
+	}
+}
+
+func hashRefreshToken(refreshToken string) string {
+	hash := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(hash[:])
+}
+
+func sameHash(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func isExpired(value string) bool {
+	if value == "" {
+		return false
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return false
+	}
+
+	return time.Now().UTC().After(expiresAt)
+}
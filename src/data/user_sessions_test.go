@@ -0,0 +1,187 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createSessionTestUser(t *testing.T, db *JsonDatabase, ctx basecontext.ApiContext) *models.User {
+	for _, roleName := range constants.DefaultRoles {
+		_, _ = db.CreateRole(ctx, models.Role{Name: roleName, ID: roleName})
+	}
+	for _, claimName := range constants.DefaultClaims {
+		_, _ = db.CreateClaim(ctx, models.Claim{Name: claimName, ID: claimName})
+	}
+
+	user, err := db.CreateUser(ctx, models.User{
+		Username: "sessionuser",
+		Email:    "session@example.com",
+		Name:     "Session Test",
+		Password: "password",
+	})
+	require.NoError(t, err)
+
+	return user
+}
+
+func TestRefreshUserSession(t *testing.T) {
+	db, tmpDir := setupTestDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+	user := createSessionTestUser(t, db, ctx)
+
+	session, err := db.CreateUserSession(ctx, models.UserSession{
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(time.Hour).Format(time.RFC3339Nano),
+	}, "first")
+	require.NoError(t, err)
+	assert.NotEmpty(t, session.ID)
+	assert.NotEqual(t, "first", session.RefreshTokenHash)
+
+	_, err = db.RefreshUserSession(ctx, session.ID, "other", "second", "")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	refreshed, err := db.RefreshUserSession(ctx, session.ID, "first", "second", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", refreshed.IpAddress)
+
+	// the refresh token can only be used once, using it again revokes the
+	// session
+	_, err = db.RefreshUserSession(ctx, session.ID, "first", "third", "")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	loaded, err := db.GetUserSession(ctx, session.ID)
+	require.NoError(t, err)
+	assert.True(t, loaded.Revoked)
+	assert.Equal(t, constants.SESSION_REVOKED_REFRESH_TOKEN_REUSED, loaded.RevokedReason)
+
+	_, err = db.RefreshUserSession(ctx, session.ID, "second", "third", "")
+	assert.ErrorIs(t, err, ErrUserSessionRevoked)
+}
+
+func TestRefreshUserSession_Expired(t *testing.T) {
+	db, tmpDir := setupTestDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+	user := createSessionTestUser(t, db, ctx)
+
+	session, err := db.CreateUserSession(ctx, models.UserSession{
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(-time.Minute).Format(time.RFC3339Nano),
+	}, "first")
+	require.NoError(t, err)
+
+	_, err = db.RefreshUserSession(ctx, session.ID, "first", "second", "")
+	assert.ErrorIs(t, err, ErrUserSessionExpired)
+
+	sessions, err := db.GetUserSessions(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	require.NoError(t, db.DeleteExpiredUserSessions(ctx))
+	_, err = db.GetUserSession(ctx, session.ID)
+	assert.ErrorIs(t, err, ErrUserSessionNotFound)
+}
+
+func TestRevokeUserSessions(t *testing.T) {
+	db, tmpDir := setupTestDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+	user := createSessionTestUser(t, db, ctx)
+
+	expiresAt := time.Now().UTC().Add(time.Hour).Format(time.RFC3339Nano)
+	for _, token := range []string{"first", "second"} {
+		_, err := db.CreateUserSession(ctx, models.UserSession{UserID: user.ID, ExpiresAt: expiresAt}, token)
+		require.NoError(t, err)
+	}
+
+	sessions, err := db.GetUserSessions(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	require.NoError(t, db.RevokeUserSession(ctx, sessions[0].ID, constants.SESSION_REVOKED_LOGOUT))
+	sessions, err = db.GetUserSessions(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	count, err := db.RevokeUserSessions(ctx, user.ID, constants.SESSION_REVOKED_BY_USER)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	sessions, err = db.GetUserSessions(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	loaded, err := db.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.NotEmpty(t, loaded.SessionsRevokedAt)
+}
+
+func TestUpdateUser_RevokesSessions(t *testing.T) {
+	db, tmpDir := setupTestDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+	user := createSessionTestUser(t, db, ctx)
+
+	expiresAt := time.Now().UTC().Add(time.Hour).Format(time.RFC3339Nano)
+	session, err := db.CreateUserSession(ctx, models.UserSession{UserID: user.ID, ExpiresAt: expiresAt}, "first")
+	require.NoError(t, err)
+
+	// changing the name keeps the sessions
+	require.NoError(t, db.UpdateUser(ctx, models.User{ID: user.ID, Name: "Renamed"}))
+	loaded, err := db.GetUserSession(ctx, session.ID)
+	require.NoError(t, err)
+	assert.False(t, loaded.Revoked)
+
+	require.NoError(t, db.UpdateUser(ctx, models.User{ID: user.ID, Password: "new-password"}))
+	loaded, err = db.GetUserSession(ctx, session.ID)
+	require.NoError(t, err)
+	assert.True(t, loaded.Revoked)
+	assert.Equal(t, constants.SESSION_REVOKED_PASSWORD_CHANGED, loaded.RevokedReason)
+
+	session, err = db.CreateUserSession(ctx, models.UserSession{UserID: user.ID, ExpiresAt: expiresAt}, "second")
+	require.NoError(t, err)
+	require.NoError(t, db.UpdateUserBlockStatus(ctx, models.User{ID: user.ID, Blocked: true, FailedLoginAttempts: 5}))
+	loaded, err = db.GetUserSession(ctx, session.ID)
+	require.NoError(t, err)
+	assert.True(t, loaded.Revoked)
+	assert.Equal(t, constants.SESSION_REVOKED_USER_BLOCKED, loaded.RevokedReason)
+}
+
+func TestRevokeToken(t *testing.T) {
+	db, tmpDir := setupTestDB(t)
+	defer cleanupTestDB(t, tmpDir, db)
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+
+	assert.False(t, db.IsTokenRevoked(ctx, "token-id"))
+	require.NoError(t, db.RevokeToken(ctx, models.RevokedToken{
+		ID:        "token-id",
+		ExpiresAt: time.Now().UTC().Add(time.Minute).Format(time.RFC3339Nano),
+	}))
+	require.NoError(t, db.RevokeToken(ctx, models.RevokedToken{
+		ID:        "expired-token-id",
+		ExpiresAt: time.Now().UTC().Add(-time.Minute).Format(time.RFC3339Nano),
+	}))
+	assert.True(t, db.IsTokenRevoked(ctx, "token-id"))
+	assert.True(t, db.IsTokenRevoked(ctx, "expired-token-id"))
+
+	require.NoError(t, db.DeleteExpiredUserSessions(ctx))
+	assert.True(t, db.IsTokenRevoked(ctx, "token-id"))
+	assert.False(t, db.IsTokenRevoked(ctx, "expired-token-id"))
+}
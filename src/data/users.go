@@ -179,6 +179,7 @@ func (j *JsonDatabase) UpdateUser(ctx basecontext.ApiContext, key models.User) e
 					return err
 				}
 				j.data.Users[i].Password = hashedPassword
+				j.revokeUserSessions(ctx, user.ID, constants.SESSION_REVOKED_PASSWORD_CHANGED)
 			}
 			if key.Email != "" {
 				j.data.Users[i].Email = key.Email
@@ -206,6 +207,9 @@ func (j *JsonDatabase) UpdateUserBlockStatus(ctx basecontext.ApiContext, key mod
 
 	for i, user := range j.data.Users {
 		if user.ID == key.ID {
+			if key.Blocked && !user.Blocked {
+				j.revokeUserSessions(ctx, user.ID, constants.SESSION_REVOKED_USER_BLOCKED)
+			}
 			j.data.Users[i].Blocked = key.Blocked
 			j.data.Users[i].BlockedSince = key.BlockedSince
 			j.data.Users[i].BlockedReason = key.BlockedReason
@@ -219,6 +223,32 @@ func (j *JsonDatabase) UpdateUserBlockStatus(ctx basecontext.ApiContext, key mod
 	return ErrUserNotFound
 }
 
+// UpdateUserDisabledStatus disables or enables a user, disabled users cannot
+// log in until they are enabled again
+func (j *JsonDatabase) UpdateUserDisabledStatus(ctx basecontext.ApiContext, id string, disabled bool, reason string) error {
+	if !j.IsConnected() {
+		return ErrDatabaseNotConnected
+	}
+
+	for i, user := range j.data.Users {
+		if strings.EqualFold(user.ID, id) {
+			if disabled && !user.Disabled {
+				j.revokeUserSessions(ctx, user.ID, constants.SESSION_REVOKED_USER_DISABLED)
+			}
+			j.data.Users[i].Disabled = disabled
+			j.data.Users[i].DisabledReason = reason
+			if !disabled {
+				j.data.Users[i].DisabledReason = ""
+			}
+			j.data.Users[i].UpdatedAt = helpers.GetUtcCurrentDateTime()
+
+			return nil
+		}
+	}
+
+	return ErrUserNotFound
+}
+
 func (j *JsonDatabase) UpdateRootPassword(ctx basecontext.ApiContext, newPassword string) error {
 	if !j.IsConnected() {
 		return ErrDatabaseNotConnected
@@ -233,6 +263,7 @@ func (j *JsonDatabase) UpdateRootPassword(ctx basecontext.ApiContext, newPasswor
 			}
 			j.data.Users[i].Password = hashedPassword
 			j.data.Users[i].UpdatedAt = helpers.GetUtcCurrentDateTime()
+			j.revokeUserSessions(ctx, user.ID, constants.SESSION_REVOKED_PASSWORD_CHANGED)
 
 			return nil
 		}
@@ -0,0 +1,49 @@
+package mappers
+
+import (
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/models"
+)
+
+func AccessPolicyRequestToDto(m models.AccessPolicyRequest) data_models.AccessPolicy {
+	mapped := data_models.AccessPolicy{
+		Name:        m.Name,
+		Description: m.Description,
+		Effect:      m.Effect,
+		Roles:       m.Roles,
+		Users:       m.Users,
+		Resources:   m.Resources,
+		Actions:     m.Actions,
+		Condition:   m.Condition,
+	}
+	if m.Enabled != nil {
+		mapped.Enabled = *m.Enabled
+	}
+
+	return mapped
+}
+
+func AccessPolicyDtoToApi(m data_models.AccessPolicy) models.AccessPolicyResponse {
+	return models.AccessPolicyResponse{
+		ID:          m.ID,
+		Name:        m.Name,
+		Description: m.Description,
+		Effect:      m.Effect,
+		Roles:       m.Roles,
+		Users:       m.Users,
+		Resources:   m.Resources,
+		Actions:     m.Actions,
+		Condition:   m.Condition,
+		Enabled:     m.Enabled,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+	}
+}
+
+func AccessPoliciesDtoToApi(m []data_models.AccessPolicy) []models.AccessPolicyResponse {
+	mapped := make([]models.AccessPolicyResponse, 0)
+	for _, v := range m {
+		mapped = append(mapped, AccessPolicyDtoToApi(v))
+	}
+	return mapped
+}
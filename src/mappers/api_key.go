@@ -18,6 +18,7 @@ func ApiKeyRequestToDto(model models.ApiKeyRequest) data_models.ApiKey {
 		RevokedAt: model.RevokedAt,
 		ExpiresAt: model.ExpiresAt,
 		UserID:    model.UserID,
+		Scope:     ApiKeyScopeToDto(model.Scope),
 	}
 
 	return mapped
@@ -25,13 +26,18 @@ func ApiKeyRequestToDto(model models.ApiKeyRequest) data_models.ApiKey {
 
 func ApiKeyDtoToApiKeyResponse(m data_models.ApiKey) models.ApiKeyResponse {
 	mapped := models.ApiKeyResponse{
-		ID:        m.ID,
-		Name:      m.Name,
-		Key:       m.Key,
-		Revoked:   m.Revoked,
-		ExpiresAt: m.ExpiresAt,
-		RevokedAt: m.RevokedAt,
-		UserID:    m.UserID,
+		ID:                      m.ID,
+		Name:                    m.Name,
+		Key:                     m.Key,
+		Revoked:                 m.Revoked,
+		ExpiresAt:               m.ExpiresAt,
+		RevokedAt:               m.RevokedAt,
+		UserID:                  m.UserID,
+		Scope:                   DtoApiKeyScopeToApi(m.Scope),
+		RotatedAt:               m.RotatedAt,
+		PreviousSecretExpiresAt: m.PreviousSecretExpiresAt,
+		LastUsedAt:              m.LastUsedAt,
+		LastUsedIp:              m.LastUsedIp,
 	}
 
 	return mapped
@@ -45,3 +51,41 @@ func ApiKeysDtoToApiKeyResponse(m []data_models.ApiKey) []models.ApiKeyResponse
 
 	return mapped
 }
+
+func ApiKeyScopeToDto(m *models.ApiKeyScope) *data_models.ApiKeyScope {
+	if m == nil {
+		return nil
+	}
+
+	mapped := data_models.ApiKeyScope{
+		Claims:      m.Claims,
+		Methods:     m.Methods,
+		Paths:       m.Paths,
+		SourceCidrs: m.SourceCidrs,
+		RateLimit:   m.RateLimit,
+	}
+	for _, resource := range m.Resources {
+		mapped.Resources = append(mapped.Resources, data_models.ApiKeyResource{Type: resource.Type, Value: resource.Value})
+	}
+
+	return &mapped
+}
+
+func DtoApiKeyScopeToApi(m *data_models.ApiKeyScope) *models.ApiKeyScope {
+	if m == nil {
+		return nil
+	}
+
+	mapped := models.ApiKeyScope{
+		Claims:      m.Claims,
+		Methods:     m.Methods,
+		Paths:       m.Paths,
+		SourceCidrs: m.SourceCidrs,
+		RateLimit:   m.RateLimit,
+	}
+	for _, resource := range m.Resources {
+		mapped.Resources = append(mapped.Resources, models.ApiKeyScopeResource{Type: resource.Type, Value: resource.Value})
+	}
+
+	return &mapped
+}
@@ -0,0 +1,76 @@
+package mappers
+
+import (
+	"strings"
+
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/models"
+)
+
+func CacheWarmupPolicyRequestToDto(m models.CacheWarmupPolicyRequest) data_models.CacheWarmupPolicy {
+	mapped := data_models.CacheWarmupPolicy{
+		Name:              m.Name,
+		CatalogId:         m.CatalogId,
+		Version:           m.Version,
+		Channel:           m.Channel,
+		Connection:        m.Connection,
+		HostTags:          m.HostTags,
+		MaxTransferRateKb: m.MaxTransferRateKb,
+	}
+	if m.Enabled != nil {
+		mapped.Enabled = *m.Enabled
+	}
+
+	return mapped
+}
+
+func CacheWarmupPolicyDtoToApi(m data_models.CacheWarmupPolicy) models.CacheWarmupPolicyResponse {
+	return models.CacheWarmupPolicyResponse{
+		ID:                m.ID,
+		Name:              m.Name,
+		CatalogId:         m.CatalogId,
+		Version:           m.Version,
+		Channel:           m.Channel,
+		Connection:        ObfuscateConnectionString(m.Connection),
+		HostTags:          m.HostTags,
+		MaxTransferRateKb: m.MaxTransferRateKb,
+		Enabled:           m.Enabled,
+		CreatedAt:         m.CreatedAt,
+		UpdatedAt:         m.UpdatedAt,
+	}
+}
+
+func CacheWarmupPoliciesDtoToApi(m []data_models.CacheWarmupPolicy) []models.CacheWarmupPolicyResponse {
+	mapped := make([]models.CacheWarmupPolicyResponse, 0)
+	for _, v := range m {
+		mapped = append(mapped, CacheWarmupPolicyDtoToApi(v))
+	}
+	return mapped
+}
+
+// ObfuscateConnectionString masks the credentials of a key=value;key=value
+// connection string, secret references are kept as they do not hold the value
+func ObfuscateConnectionString(connection string) string {
+	if connection == "" {
+		return connection
+	}
+
+	parts := strings.Split(connection, ";")
+	for i, part := range parts {
+		keyValue := strings.SplitN(part, "=", 2)
+		if len(keyValue) != 2 || keyValue[1] == "" || strings.Contains(keyValue[1], "://") {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(keyValue[0]))
+		for _, secret := range []string{"key", "secret", "password", "token", "user"} {
+			if strings.Contains(key, secret) {
+				// Just a visual indicator that it exists
+				parts[i] = keyValue[0] + "=********"
+				break
+			}
+		}
+	}
+
+	return strings.Join(parts, ";")
+}
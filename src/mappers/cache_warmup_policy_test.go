@@ -0,0 +1,26 @@
+package mappers
+
+import (
+	"testing"
+
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateConnectionString(t *testing.T) {
+	connection := "provider=aws-s3;bucket=images;region=eu-west-1;access_key=AKIA1234;secret_key=s3cr3t;session_token=secret://vault/aws/token"
+	result := ObfuscateConnectionString(connection)
+
+	assert.Equal(t, "provider=aws-s3;bucket=images;region=eu-west-1;access_key=********;secret_key=********;session_token=secret://vault/aws/token", result)
+	assert.Equal(t, "", ObfuscateConnectionString(""))
+}
+
+func TestCacheWarmupPolicyDtoToApi_ObfuscatesConnection(t *testing.T) {
+	policy := data_models.CacheWarmupPolicy{
+		ID:         "1",
+		Connection: "provider=minio;username=admin;password=admin123",
+	}
+
+	result := CacheWarmupPolicyDtoToApi(policy)
+	assert.Equal(t, "provider=minio;username=********;password=********", result.Connection)
+}
@@ -598,3 +598,13 @@ func BaseRemoteVirtualMachineProviderToApi(m *catalog_models.CatalogManifestProv
 
 	return result
 }
+
+func DtoCatalogSearchFacetsToApi(m data_models.CatalogSearchFacets) models.CatalogSearchFacets {
+	return models.CatalogSearchFacets{
+		CatalogIds:    m.CatalogIds,
+		Tags:          m.Tags,
+		Architectures: m.Architectures,
+		Tainted:       m.Tainted,
+		Revoked:       m.Revoked,
+	}
+}
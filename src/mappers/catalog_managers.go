@@ -22,6 +22,7 @@ func ToCatalogManagerResponse(mgr *data_models.CatalogManager, includeCredential
 		AuthenticationMethod: mgr.AuthenticationMethod,
 		Global:               mgr.Global,
 		RequiredClaims:       mgr.RequiredClaims,
+		MaxTransferRateKb:    mgr.MaxTransferRateKb,
 		OwnerID:              mgr.OwnerID,
 		CreatedAt:            mgr.CreatedAt,
 		UpdatedAt:            mgr.UpdatedAt,
@@ -84,6 +85,7 @@ func FromCatalogManagerRequest(req *models.CatalogManagerRequest) *data_models.C
 		Username:             req.Username,
 		Global:               req.Global,
 		RequiredClaims:       req.RequiredClaims,
+		MaxTransferRateKb:    req.MaxTransferRateKb,
 	}
 
 	cfg := config.Get()
@@ -125,6 +127,7 @@ func UpdateCatalogManagerFromRequest(mgr *data_models.CatalogManager, req *model
 	mgr.Username = req.Username
 	mgr.Global = req.Global
 	mgr.RequiredClaims = req.RequiredClaims
+	mgr.MaxTransferRateKb = req.MaxTransferRateKb
 
 	cfg := config.Get()
 
@@ -7,15 +7,16 @@ import (
 
 func MapPullCatalogManifestRequestFromCreateCatalogVirtualMachineRequest(m models.CreateCatalogVirtualMachineRequest) catalog_models.PullCatalogManifestRequest {
 	mapped := catalog_models.PullCatalogManifestRequest{
-		CatalogId:        m.CatalogId,
-		MachineName:      m.MachineName,
-		Owner:            m.Owner,
-		Version:          m.Version,
-		Architecture:     m.Architecture,
-		Connection:       m.Connection,
-		ProviderMetadata: m.ProviderMetadata,
-		StartAfterPull:   m.StartAfterPull,
-		Path:             m.Path,
+		CatalogId:         m.CatalogId,
+		MachineName:       m.MachineName,
+		Owner:             m.Owner,
+		Version:           m.Version,
+		Architecture:      m.Architecture,
+		Connection:        m.Connection,
+		ProviderMetadata:  m.ProviderMetadata,
+		StartAfterPull:    m.StartAfterPull,
+		MaxTransferRateKb: m.MaxTransferRateKb,
+		Path:              m.Path,
 	}
 
 	return mapped
@@ -0,0 +1,86 @@
+package mappers
+
+import (
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/models"
+)
+
+func DtoReverseProxyAuthToApi(m *data_models.ReverseProxyAuth) *models.ReverseProxyAuth {
+	if m == nil {
+		return nil
+	}
+
+	r := models.ReverseProxyAuth{
+		AllowedIps: m.AllowedIps,
+		DeniedIps:  m.DeniedIps,
+	}
+	if m.Devops != nil {
+		r.Devops = &models.ReverseProxyAuthDevops{
+			Enabled: m.Devops.Enabled,
+			Roles:   m.Devops.Roles,
+			Claims:  m.Devops.Claims,
+		}
+	}
+	if m.Basic != nil {
+		r.Basic = &models.ReverseProxyAuthBasic{
+			Enabled: m.Basic.Enabled,
+			Realm:   m.Basic.Realm,
+		}
+		for _, user := range m.Basic.Users {
+			r.Basic.Users = append(r.Basic.Users, models.ReverseProxyAuthBasicUser{
+				Username:     user.Username,
+				PasswordHash: user.PasswordHash,
+			})
+		}
+	}
+	if m.ForwardAuth != nil {
+		r.ForwardAuth = &models.ReverseProxyAuthForward{
+			Enabled:         m.ForwardAuth.Enabled,
+			Url:             m.ForwardAuth.Url,
+			ResponseHeaders: m.ForwardAuth.ResponseHeaders,
+			Timeout:         m.ForwardAuth.Timeout,
+		}
+	}
+
+	return &r
+}
+
+func ApiReverseProxyAuthToDto(m *models.ReverseProxyAuth) *data_models.ReverseProxyAuth {
+	if m == nil {
+		return nil
+	}
+
+	r := data_models.ReverseProxyAuth{
+		AllowedIps: m.AllowedIps,
+		DeniedIps:  m.DeniedIps,
+	}
+	if m.Devops != nil {
+		r.Devops = &data_models.ReverseProxyAuthDevops{
+			Enabled: m.Devops.Enabled,
+			Roles:   m.Devops.Roles,
+			Claims:  m.Devops.Claims,
+		}
+	}
+	if m.Basic != nil {
+		r.Basic = &data_models.ReverseProxyAuthBasic{
+			Enabled: m.Basic.Enabled,
+			Realm:   m.Basic.Realm,
+		}
+		for _, user := range m.Basic.Users {
+			r.Basic.Users = append(r.Basic.Users, data_models.ReverseProxyAuthBasicUser{
+				Username:     user.Username,
+				PasswordHash: user.PasswordHash,
+			})
+		}
+	}
+	if m.ForwardAuth != nil {
+		r.ForwardAuth = &data_models.ReverseProxyAuthForward{
+			Enabled:         m.ForwardAuth.Enabled,
+			Url:             m.ForwardAuth.Url,
+			ResponseHeaders: m.ForwardAuth.ResponseHeaders,
+			Timeout:         m.ForwardAuth.Timeout,
+		}
+	}
+
+	return &r
+}
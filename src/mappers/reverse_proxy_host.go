@@ -25,6 +25,8 @@ func DtoReverseProxyHostToApi(m data_models.ReverseProxyHost) models.ReverseProx
 		r.Cors = &e
 	}
 
+	r.Auth = DtoReverseProxyAuthToApi(m.Auth)
+
 	if m.HttpRoutes != nil {
 		for _, route := range m.HttpRoutes {
 			e := DtoReverseProxyHostHttpRouteToApi(*route)
@@ -37,6 +39,11 @@ func DtoReverseProxyHostToApi(m data_models.ReverseProxyHost) models.ReverseProx
 		r.TcpRoute = &e
 	}
 
+	if m.UdpRoute != nil {
+		e := DtoReverseProxyHostUdpRouteToApi(*m.UdpRoute)
+		r.UdpRoute = &e
+	}
+
 	return r
 }
 
@@ -67,6 +74,8 @@ func ApiReverseProxyHostToDto(m models.ReverseProxyHost) data_models.ReverseProx
 		r.Cors = &e
 	}
 
+	r.Auth = ApiReverseProxyAuthToDto(m.Auth)
+
 	if m.HttpRoutes != nil {
 		for _, route := range m.HttpRoutes {
 			e := ApiReverseProxyHostHttpRouteToDto(*route)
@@ -79,6 +88,11 @@ func ApiReverseProxyHostToDto(m models.ReverseProxyHost) data_models.ReverseProx
 		r.TcpRoute = &e
 	}
 
+	if m.UdpRoute != nil {
+		e := ApiReverseProxyHostUdpRouteToDto(*m.UdpRoute)
+		r.UdpRoute = &e
+	}
+
 	return r
 }
 
@@ -108,6 +122,8 @@ func ApiCreateRequestReverseProxyHostToDto(m models.ReverseProxyHostCreateReques
 		r.Cors = &e
 	}
 
+	r.Auth = ApiReverseProxyAuthToDto(m.Auth)
+
 	if m.HttpRoutes != nil {
 		for _, route := range m.HttpRoutes {
 			e := ApiReverseProxyHostHttpRouteToDto(*route)
@@ -120,6 +136,11 @@ func ApiCreateRequestReverseProxyHostToDto(m models.ReverseProxyHostCreateReques
 		r.TcpRoute = &e
 	}
 
+	if m.UdpRoute != nil {
+		e := ApiReverseProxyHostUdpRouteToDto(*m.UdpRoute)
+		r.UdpRoute = &e
+	}
+
 	return r
 }
 
@@ -140,6 +161,8 @@ func ApiUpdateRequestReverseProxyHostToDto(m models.ReverseProxyHostUpdateReques
 		r.Cors = &e
 	}
 
+	r.Auth = ApiReverseProxyAuthToDto(m.Auth)
+
 	return r
 }
 
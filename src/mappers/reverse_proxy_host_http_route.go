@@ -19,6 +19,11 @@ func DtoReverseProxyHostHttpRouteToApi(m data_models.ReverseProxyHostHttpRoute)
 		Pattern:         m.Pattern,
 		RequestHeaders:  m.RequestHeaders,
 		ResponseHeaders: m.ResponseHeaders,
+		Upstream:        DtoReverseProxyUpstreamToApi(m.Upstream),
+		Auth:            DtoReverseProxyAuthToApi(m.Auth),
+		Protocol:        m.Protocol,
+		Streaming:       m.Streaming,
+		WebSocket:       DtoReverseProxyHostHttpRouteWebSocketToApi(m.WebSocket),
 	}
 }
 
@@ -43,6 +48,11 @@ func ApiReverseProxyHostHttpRouteToDto(m models.ReverseProxyHostHttpRoute) data_
 		Pattern:         m.Pattern,
 		RequestHeaders:  m.RequestHeaders,
 		ResponseHeaders: m.ResponseHeaders,
+		Upstream:        ApiReverseProxyUpstreamToDto(m.Upstream),
+		Auth:            ApiReverseProxyAuthToDto(m.Auth),
+		Protocol:        m.Protocol,
+		Streaming:       m.Streaming,
+		WebSocket:       ApiReverseProxyHostHttpRouteWebSocketToDto(m.WebSocket),
 	}
 }
 
@@ -66,11 +76,38 @@ func ApiReverseProxyHostCreateHttpRouteToDto(m models.ReverseProxyHostHttpRouteC
 		Schema:          m.Schema,
 		RequestHeaders:  m.RequestHeaders,
 		ResponseHeaders: m.ResponseHeaders,
+		Upstream:        ApiReverseProxyUpstreamToDto(m.Upstream),
+		Auth:            ApiReverseProxyAuthToDto(m.Auth),
+		Protocol:        m.Protocol,
+		Streaming:       m.Streaming,
+		WebSocket:       ApiReverseProxyHostHttpRouteWebSocketToDto(m.WebSocket),
 	}
 
 	return result
 }
 
+func DtoReverseProxyHostHttpRouteWebSocketToApi(m *data_models.ReverseProxyHostHttpRouteWebSocket) *models.ReverseProxyHostHttpRouteWebSocket {
+	if m == nil {
+		return nil
+	}
+
+	return &models.ReverseProxyHostHttpRouteWebSocket{
+		Enabled:     m.Enabled,
+		IdleTimeout: m.IdleTimeout,
+	}
+}
+
+func ApiReverseProxyHostHttpRouteWebSocketToDto(m *models.ReverseProxyHostHttpRouteWebSocket) *data_models.ReverseProxyHostHttpRouteWebSocket {
+	if m == nil {
+		return nil
+	}
+
+	return &data_models.ReverseProxyHostHttpRouteWebSocket{
+		Enabled:     m.Enabled,
+		IdleTimeout: m.IdleTimeout,
+	}
+}
+
 func ConfigReverseProxyHostHttpRouteToDto(m config_models.ReverseProxyConfigServerHttpRoute) data_models.ReverseProxyHostHttpRoute {
 	return data_models.ReverseProxyHostHttpRoute{
 		ID:              helpers.GenerateId(),
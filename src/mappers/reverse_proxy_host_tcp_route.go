@@ -13,6 +13,7 @@ func DtoReverseProxyHostTcpRouteToApi(m data_models.ReverseProxyHostTcpRoute) mo
 		TargetPort: m.TargetPort,
 		TargetHost: m.TargetHost,
 		TargetVmId: m.TargetVmId,
+		Upstream:   DtoReverseProxyUpstreamToApi(m.Upstream),
 	}
 }
 
@@ -31,6 +32,7 @@ func ApiReverseProxyHostTcpRouteToDto(m models.ReverseProxyHostTcpRoute) data_mo
 		TargetPort: m.TargetPort,
 		TargetHost: m.TargetHost,
 		TargetVmId: m.TargetVmId,
+		Upstream:   ApiReverseProxyUpstreamToDto(m.Upstream),
 	}
 }
 
@@ -48,6 +50,7 @@ func ApiReverseProxyHostTcpRouteCreateRequestToDto(m models.ReverseProxyHostTcpR
 		TargetPort: m.TargetPort,
 		TargetHost: m.TargetHost,
 		TargetVmId: m.TargetVmId,
+		Upstream:   ApiReverseProxyUpstreamToDto(m.Upstream),
 	}
 }
 
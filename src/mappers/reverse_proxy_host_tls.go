@@ -11,6 +11,15 @@ func DtoReverseProxyHostTlsToApi(m data_models.ReverseProxyHostTls) models.Rever
 		Cert:    m.Cert,
 		Key:     m.Key,
 	}
+	if m.Acme != nil {
+		r.Acme = &models.ReverseProxyHostTlsAcme{
+			Enabled:     m.Acme.Enabled,
+			Domains:     m.Acme.Domains,
+			Email:       m.Acme.Email,
+			Challenge:   m.Acme.Challenge,
+			DnsProvider: m.Acme.DnsProvider,
+		}
+	}
 
 	return r
 }
@@ -21,6 +30,15 @@ func ApiReverseProxyHostTlsToDto(m models.ReverseProxyHostTls) data_models.Rever
 		Cert:    m.Cert,
 		Key:     m.Key,
 	}
+	if m.Acme != nil {
+		r.Acme = &data_models.ReverseProxyHostTlsAcme{
+			Enabled:     m.Acme.Enabled,
+			Domains:     m.Acme.Domains,
+			Email:       m.Acme.Email,
+			Challenge:   m.Acme.Challenge,
+			DnsProvider: m.Acme.DnsProvider,
+		}
+	}
 
 	return r
 }
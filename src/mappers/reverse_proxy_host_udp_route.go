@@ -0,0 +1,38 @@
+package mappers
+
+import (
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/models"
+)
+
+func DtoReverseProxyHostUdpRouteToApi(m data_models.ReverseProxyHostUdpRoute) models.ReverseProxyHostUdpRoute {
+	return models.ReverseProxyHostUdpRoute{
+		ID:          m.ID,
+		TargetPort:  m.TargetPort,
+		TargetHost:  m.TargetHost,
+		TargetVmId:  m.TargetVmId,
+		IdleTimeout: m.IdleTimeout,
+		MaxSessions: m.MaxSessions,
+	}
+}
+
+func ApiReverseProxyHostUdpRouteToDto(m models.ReverseProxyHostUdpRoute) data_models.ReverseProxyHostUdpRoute {
+	return data_models.ReverseProxyHostUdpRoute{
+		ID:          m.ID,
+		TargetPort:  m.TargetPort,
+		TargetHost:  m.TargetHost,
+		TargetVmId:  m.TargetVmId,
+		IdleTimeout: m.IdleTimeout,
+		MaxSessions: m.MaxSessions,
+	}
+}
+
+func ApiReverseProxyHostUdpRouteCreateRequestToDto(m models.ReverseProxyHostUdpRouteCreateRequest) data_models.ReverseProxyHostUdpRoute {
+	return data_models.ReverseProxyHostUdpRoute{
+		TargetPort:  m.TargetPort,
+		TargetHost:  m.TargetHost,
+		TargetVmId:  m.TargetVmId,
+		IdleTimeout: m.IdleTimeout,
+		MaxSessions: m.MaxSessions,
+	}
+}
@@ -0,0 +1,70 @@
+package mappers
+
+import (
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/models"
+)
+
+func DtoReverseProxyUpstreamToApi(m *data_models.ReverseProxyUpstream) *models.ReverseProxyUpstream {
+	if m == nil {
+		return nil
+	}
+
+	r := models.ReverseProxyUpstream{
+		Strategy:    m.Strategy,
+		VmTags:      m.VmTags,
+		MaxFails:    m.MaxFails,
+		FailTimeout: m.FailTimeout,
+	}
+	for _, target := range m.Targets {
+		r.Targets = append(r.Targets, models.ReverseProxyUpstreamTarget{
+			TargetVmId: target.TargetVmId,
+			TargetHost: target.TargetHost,
+			TargetPort: target.TargetPort,
+		})
+	}
+	if m.HealthCheck != nil {
+		r.HealthCheck = &models.ReverseProxyUpstreamHealthCheck{
+			Enabled:            m.HealthCheck.Enabled,
+			Path:               m.HealthCheck.Path,
+			Interval:           m.HealthCheck.Interval,
+			Timeout:            m.HealthCheck.Timeout,
+			HealthyThreshold:   m.HealthCheck.HealthyThreshold,
+			UnhealthyThreshold: m.HealthCheck.UnhealthyThreshold,
+		}
+	}
+
+	return &r
+}
+
+func ApiReverseProxyUpstreamToDto(m *models.ReverseProxyUpstream) *data_models.ReverseProxyUpstream {
+	if m == nil {
+		return nil
+	}
+
+	r := data_models.ReverseProxyUpstream{
+		Strategy:    m.Strategy,
+		VmTags:      m.VmTags,
+		MaxFails:    m.MaxFails,
+		FailTimeout: m.FailTimeout,
+	}
+	for _, target := range m.Targets {
+		r.Targets = append(r.Targets, data_models.ReverseProxyUpstreamTarget{
+			TargetVmId: target.TargetVmId,
+			TargetHost: target.TargetHost,
+			TargetPort: target.TargetPort,
+		})
+	}
+	if m.HealthCheck != nil {
+		r.HealthCheck = &data_models.ReverseProxyUpstreamHealthCheck{
+			Enabled:            m.HealthCheck.Enabled,
+			Path:               m.HealthCheck.Path,
+			Interval:           m.HealthCheck.Interval,
+			Timeout:            m.HealthCheck.Timeout,
+			HealthyThreshold:   m.HealthCheck.HealthyThreshold,
+			UnhealthyThreshold: m.HealthCheck.UnhealthyThreshold,
+		}
+	}
+
+	return &r
+}
@@ -14,6 +14,7 @@ func DtoRoleToApi(model data_models.Role) models.RoleResponse {
 		Name:        model.Name,
 		Description: model.Description,
 		Internal:    model.Internal,
+		MfaRequired: model.MfaRequired,
 		Claims:      []models.ClaimResponse{},
 		Users:       []models.ApiUser{},
 	}
@@ -40,10 +40,14 @@ func ApiUserUpdateRequestToDto(model models.UserUpdateRequest) data_models.User
 
 func DtoUserToApiResponse(model data_models.User) models.ApiUser {
 	user := models.ApiUser{
-		ID:       model.ID,
-		Username: model.Username,
-		Name:     model.Name,
-		Email:    model.Email,
+		ID:               model.ID,
+		Username:         model.Username,
+		Name:             model.Name,
+		Email:            model.Email,
+		IdentityProvider: model.IdentityProvider,
+		Disabled:         model.Disabled,
+		DisabledReason:   model.DisabledReason,
+		MfaEnabled:       model.Mfa != nil && model.Mfa.Enabled,
 	}
 	for _, role := range model.Roles {
 		user.Roles = append(user.Roles, role.ID)
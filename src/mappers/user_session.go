@@ -0,0 +1,30 @@
+package mappers
+
+import (
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/models"
+)
+
+func DtoUserSessionToApiResponse(m data_models.UserSession) models.UserSessionResponse {
+	mapped := models.UserSessionResponse{
+		ID:                   m.ID,
+		UserID:               m.UserID,
+		AuthenticationMethod: m.AuthenticationMethod,
+		IpAddress:            m.IpAddress,
+		UserAgent:            m.UserAgent,
+		CreatedAt:            m.CreatedAt,
+		LastUsedAt:           m.LastUsedAt,
+		ExpiresAt:            m.ExpiresAt,
+	}
+
+	return mapped
+}
+
+func DtoUserSessionsToApiResponse(m []data_models.UserSession) []models.UserSessionResponse {
+	mapped := make([]models.UserSessionResponse, 0)
+	for _, session := range m {
+		mapped = append(mapped, DtoUserSessionToApiResponse(session))
+	}
+
+	return mapped
+}
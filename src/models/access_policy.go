@@ -0,0 +1,86 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+// AccessPolicyRequest allows or denies actions on machines, orchestrator
+// hosts and reverse proxy hosts to the users and roles of the policy, or to
+// everyone when both are empty. The condition is an expression on subject,
+// resource and action, for example "ios" in resource.host_tags
+type AccessPolicyRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Effect      string   `json:"effect"`
+	Roles       []string `json:"roles,omitempty"`
+	Users       []string `json:"users,omitempty"`
+	Resources   []string `json:"resources"`
+	Actions     []string `json:"actions"`
+	Condition   string   `json:"condition,omitempty"`
+	Enabled     *bool    `json:"enabled,omitempty"`
+}
+
+func (r *AccessPolicyRequest) Validate() error {
+	if r.Name == "" {
+		return errors.NewWithCode("name is required", 400)
+	}
+	if r.Effect == "" {
+		r.Effect = constants.ACCESS_POLICY_EFFECT_ALLOW
+	}
+	r.Effect = strings.ToLower(r.Effect)
+	if r.Effect != constants.ACCESS_POLICY_EFFECT_ALLOW && r.Effect != constants.ACCESS_POLICY_EFFECT_DENY {
+		return errors.NewWithCodef(400, "effect must be %v or %v", constants.ACCESS_POLICY_EFFECT_ALLOW, constants.ACCESS_POLICY_EFFECT_DENY)
+	}
+	if len(r.Resources) == 0 {
+		return errors.NewWithCode("resources are required", 400)
+	}
+	for i, resource := range r.Resources {
+		r.Resources[i] = strings.ToLower(resource)
+		if !containsString(constants.AllAccessPolicyResources, r.Resources[i]) {
+			return errors.NewWithCodef(400, "invalid resource %v, allowed values are %v", resource, strings.Join(constants.AllAccessPolicyResources, ", "))
+		}
+	}
+	if len(r.Actions) == 0 {
+		return errors.NewWithCode("actions are required", 400)
+	}
+	for i, action := range r.Actions {
+		r.Actions[i] = strings.ToLower(action)
+		if !containsString(constants.AllAccessPolicyActions, r.Actions[i]) {
+			return errors.NewWithCodef(400, "invalid action %v, allowed values are %v", action, strings.Join(constants.AllAccessPolicyActions, ", "))
+		}
+	}
+	if r.Enabled == nil {
+		enabled := true
+		r.Enabled = &enabled
+	}
+
+	return nil
+}
+
+type AccessPolicyResponse struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Effect      string   `json:"effect"`
+	Roles       []string `json:"roles,omitempty"`
+	Users       []string `json:"users,omitempty"`
+	Resources   []string `json:"resources"`
+	Actions     []string `json:"actions"`
+	Condition   string   `json:"condition,omitempty"`
+	Enabled     bool     `json:"enabled"`
+	CreatedAt   string   `json:"created_at,omitempty"`
+	UpdatedAt   string   `json:"updated_at,omitempty"`
+}
+
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
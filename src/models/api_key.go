@@ -1,9 +1,12 @@
 package models
 
 import (
+	"net"
+	"net/http"
 	"strings"
 	"time"
 
+	"github.com/Parallels/prl-devops-service/constants"
 	"github.com/Parallels/prl-devops-service/errors"
 	"github.com/Parallels/prl-devops-service/helpers"
 )
@@ -18,6 +21,9 @@ type ApiKeyRequest struct {
 	RevokedAt string `json:"revoked_at,omitempty"`
 	ExpiresAt string `json:"expires_at,omitempty"`
 	UserID    string `json:"user_id,omitempty"`
+	// Scope limits what the key can do, keys without a scope have the access
+	// of their user
+	Scope *ApiKeyScope `json:"scope,omitempty"`
 }
 
 func (r *ApiKeyRequest) Validate() error {
@@ -39,6 +45,99 @@ func (r *ApiKeyRequest) Validate() error {
 	}
 
 	r.Key = strings.ToUpper(helpers.NormalizeString(r.Key))
+	if r.Scope != nil {
+		if err := r.Scope.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type ApiKeyScope struct {
+	// Claims the key is limited to, they need to be a subset of the claims of
+	// the user of the key
+	Claims []string `json:"claims,omitempty"`
+	// Methods are the allowed http methods
+	Methods []string `json:"methods,omitempty"`
+	// Paths are the allowed paths, a path ending with * allows every path
+	// starting with it
+	Paths []string `json:"paths,omitempty"`
+	// SourceCidrs are the networks the key can be used from
+	SourceCidrs []string `json:"source_cidrs,omitempty"`
+	// RateLimit is the maximum number of requests per minute
+	RateLimit int                   `json:"rate_limit,omitempty"`
+	Resources []ApiKeyScopeResource `json:"resources,omitempty"`
+}
+
+// ApiKeyScopeResource restricts the key to a resource, the type is catalog or
+// host_tag
+type ApiKeyScopeResource struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+func (s *ApiKeyScope) Validate() error {
+	for i, claim := range s.Claims {
+		s.Claims[i] = strings.ToUpper(helpers.NormalizeString(claim))
+	}
+	for i, method := range s.Methods {
+		s.Methods[i] = strings.ToUpper(strings.TrimSpace(method))
+		switch s.Methods[i] {
+		case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		default:
+			return errors.NewWithCodef(400, "Invalid request body: method %v is not valid", method)
+		}
+	}
+	for _, path := range s.Paths {
+		if !strings.HasPrefix(path, "/") {
+			return errors.NewWithCodef(400, "Invalid request body: path %v needs to start with /", path)
+		}
+	}
+	for i, cidr := range s.SourceCidrs {
+		cidr = strings.TrimSpace(cidr)
+		if ip := net.ParseIP(cidr); ip != nil {
+			if ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return errors.NewWithCodef(400, "Invalid request body: source cidr %v is not valid", s.SourceCidrs[i])
+		}
+		s.SourceCidrs[i] = cidr
+	}
+	if s.RateLimit < 0 {
+		return errors.NewWithCode("Invalid request body: rate limit cannot be negative", 400)
+	}
+	for i, resource := range s.Resources {
+		s.Resources[i].Type = strings.ToLower(strings.TrimSpace(resource.Type))
+		if s.Resources[i].Type != constants.API_KEY_RESOURCE_CATALOG && s.Resources[i].Type != constants.API_KEY_RESOURCE_HOST_TAG {
+			return errors.NewWithCodef(400, "Invalid request body: resource type %v is not valid, use %v or %v", resource.Type, constants.API_KEY_RESOURCE_CATALOG, constants.API_KEY_RESOURCE_HOST_TAG)
+		}
+		if strings.TrimSpace(resource.Value) == "" {
+			return errors.NewWithCode("Invalid request body: resource value is required", 400)
+		}
+	}
+
+	return nil
+}
+
+type ApiKeyRotateRequest struct {
+	// Secret is the new secret, a random one is generated when empty
+	Secret string `json:"secret,omitempty"`
+	// Overlap is how long the previous secret keeps working, for example 1h
+	Overlap string `json:"overlap,omitempty"`
+}
+
+func (r *ApiKeyRotateRequest) Validate() error {
+	if r.Overlap == "" {
+		r.Overlap = constants.API_KEY_DEFAULT_ROTATION_OVERLAP
+	}
+	if overlap, err := time.ParseDuration(r.Overlap); err != nil || overlap < 0 {
+		return errors.NewWithCode("Invalid request body: overlap is not a valid duration", 400)
+	}
 
 	return nil
 }
@@ -55,4 +154,10 @@ type ApiKeyResponse struct {
 	UserEmail    string `json:"user_email,omitempty"`
 	UserName     string `json:"user_name,omitempty"`
 	UserUsername string `json:"user_username,omitempty"`
+
+	Scope                   *ApiKeyScope `json:"scope,omitempty"`
+	RotatedAt               string       `json:"rotated_at,omitempty"`
+	PreviousSecretExpiresAt string       `json:"previous_secret_expires_at,omitempty"`
+	LastUsedAt              string       `json:"last_used_at,omitempty"`
+	LastUsedIp              string       `json:"last_used_ip,omitempty"`
 }
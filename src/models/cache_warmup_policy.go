@@ -0,0 +1,97 @@
+package models
+
+import (
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+type CacheWarmupStatus string
+
+const (
+	CacheWarmupStatusPending   CacheWarmupStatus = "pending"
+	CacheWarmupStatusCaching   CacheWarmupStatus = "caching"
+	CacheWarmupStatusCompliant CacheWarmupStatus = "compliant"
+	CacheWarmupStatusFailed    CacheWarmupStatus = "failed"
+)
+
+// CacheWarmupPolicyRequest declares a catalog manifest that should be kept in
+// the cache of every host matching the host tags, an empty list of tags
+// matches all hosts. Either a version or a channel can be set, a channel
+// follows the manifest tagged with it so new versions are cached as they are
+// pushed.
+type CacheWarmupPolicyRequest struct {
+	Name              string   `json:"name"`
+	CatalogId         string   `json:"catalog_id"`
+	Version           string   `json:"version,omitempty"`
+	Channel           string   `json:"channel,omitempty"`
+	Connection        string   `json:"connection,omitempty"`
+	HostTags          []string `json:"host_tags,omitempty"`
+	MaxTransferRateKb int64    `json:"max_transfer_rate_kb,omitempty"`
+	Enabled           *bool    `json:"enabled,omitempty"`
+}
+
+func (r *CacheWarmupPolicyRequest) Validate() error {
+	if r.Name == "" {
+		return errors.NewWithCode("name is required", 400)
+	}
+	if r.CatalogId == "" {
+		return errors.NewWithCode("catalog_id is required", 400)
+	}
+	if r.Connection == "" {
+		return errors.NewWithCode("connection is required", 400)
+	}
+	if r.Version != "" && r.Channel != "" {
+		return errors.NewWithCode("version and channel cannot be used together", 400)
+	}
+	if r.MaxTransferRateKb < 0 {
+		return errors.NewWithCode("max_transfer_rate_kb cannot be negative", 400)
+	}
+	if r.Enabled == nil {
+		enabled := true
+		r.Enabled = &enabled
+	}
+
+	return nil
+}
+
+type CacheWarmupPolicyResponse struct {
+	ID                string   `json:"id"`
+	Name              string   `json:"name"`
+	CatalogId         string   `json:"catalog_id"`
+	Version           string   `json:"version,omitempty"`
+	Channel           string   `json:"channel,omitempty"`
+	Connection        string   `json:"connection,omitempty"`
+	HostTags          []string `json:"host_tags,omitempty"`
+	MaxTransferRateKb int64    `json:"max_transfer_rate_kb,omitempty"`
+	Enabled           bool     `json:"enabled"`
+	CreatedAt         string   `json:"created_at,omitempty"`
+	UpdatedAt         string   `json:"updated_at,omitempty"`
+}
+
+// CacheWarmupHostCompliance is the state of a cache warmup policy on a host.
+type CacheWarmupHostCompliance struct {
+	PolicyId     string            `json:"policy_id"`
+	PolicyName   string            `json:"policy_name"`
+	HostId       string            `json:"host_id"`
+	Host         string            `json:"host"`
+	CatalogId    string            `json:"catalog_id"`
+	Version      string            `json:"version,omitempty"`
+	Architecture string            `json:"architecture,omitempty"`
+	Status       CacheWarmupStatus `json:"status"`
+	JobId        string            `json:"job_id,omitempty"`
+	Message      string            `json:"message,omitempty"`
+	LastChecked  string            `json:"last_checked,omitempty"`
+}
+
+type CacheWarmupComplianceResponse struct {
+	Total     int                         `json:"total"`
+	Compliant int                         `json:"compliant"`
+	Hosts     []CacheWarmupHostCompliance `json:"hosts"`
+}
+
+func (r *CacheWarmupComplianceResponse) Add(item CacheWarmupHostCompliance) {
+	r.Hosts = append(r.Hosts, item)
+	r.Total++
+	if item.Status == CacheWarmupStatusCompliant {
+		r.Compliant++
+	}
+}
@@ -0,0 +1,9 @@
+package models
+
+type CatalogSearchFacets struct {
+	CatalogIds    map[string]int `json:"catalog_ids"`
+	Tags          map[string]int `json:"tags"`
+	Architectures map[string]int `json:"architectures"`
+	Tainted       int            `json:"tainted"`
+	Revoked       int            `json:"revoked"`
+}
@@ -9,17 +9,18 @@ import (
 )
 
 type CreateCatalogVirtualMachineRequest struct {
-	CatalogId        string                     `json:"catalog_id"`
-	Version          string                     `json:"version,omitempty"`
-	Architecture     string                     `json:"architecture,omitempty"`
-	Owner            string                     `json:"owner,omitempty"`
-	MachineName      string                     `json:"machine_name,omitempty"`
-	Connection       string                     `json:"connection,omitempty"`
-	CatalogManagerId string                     `json:"catalog_manager_id,omitempty"`
-	Path             string                     `json:"path,omitempty"`
-	ProviderMetadata map[string]string          `json:"provider_metadata,omitempty"`
-	StartAfterPull   bool                       `json:"start_after_pull,omitempty"`
-	Specs            *CreateVirtualMachineSpecs `json:"specs,omitempty"`
+	CatalogId         string                     `json:"catalog_id"`
+	Version           string                     `json:"version,omitempty"`
+	Architecture      string                     `json:"architecture,omitempty"`
+	Owner             string                     `json:"owner,omitempty"`
+	MachineName       string                     `json:"machine_name,omitempty"`
+	Connection        string                     `json:"connection,omitempty"`
+	CatalogManagerId  string                     `json:"catalog_manager_id,omitempty"`
+	Path              string                     `json:"path,omitempty"`
+	ProviderMetadata  map[string]string          `json:"provider_metadata,omitempty"`
+	StartAfterPull    bool                       `json:"start_after_pull,omitempty"`
+	MaxTransferRateKb int64                      `json:"max_transfer_rate_kb,omitempty"`
+	Specs             *CreateVirtualMachineSpecs `json:"specs,omitempty"`
 }
 
 func (r *CreateCatalogVirtualMachineRequest) Validate() error {
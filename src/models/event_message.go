@@ -131,6 +131,28 @@ type ReverseProxyForwardEvent struct {
 	SourceIp           string `json:"source_ip,omitempty"`
 }
 
+// ReverseProxyAccessLogEvent is an entry of the reverse proxy access log, a
+// request for http routes and a connection or session for tcp and udp routes
+type ReverseProxyAccessLogEvent struct {
+	Timestamp          string  `json:"timestamp"`
+	ReverseProxyHostId string  `json:"reverse_proxy_host_id"`
+	Host               string  `json:"host"`
+	RouteId            string  `json:"route_id,omitempty"`
+	Route              string  `json:"route,omitempty"`
+	TrafficType        string  `json:"traffic_type"`
+	ClientIp           string  `json:"client_ip"`
+	RequestId          string  `json:"request_id,omitempty"`
+	Method             string  `json:"method,omitempty"`
+	Path               string  `json:"path,omitempty"`
+	Upstream           string  `json:"upstream,omitempty"`
+	TargetVmId         string  `json:"target_vm_id,omitempty"`
+	Status             int     `json:"status,omitempty"`
+	BytesIn            int64   `json:"bytes_in"`
+	BytesOut           int64   `json:"bytes_out"`
+	LatencyMs          float64 `json:"latency_ms"`
+	Error              string  `json:"error,omitempty"`
+}
+
 type ReverseProxyRouteUpdatedEvent struct {
 	ReverseProxyHostId string `json:"reverse_proxy_host_id,omitempty"`
 	TargetVmId         string `json:"target_vm_id,omitempty"`
@@ -171,6 +193,31 @@ type CacheItemRemovedEvent struct {
 	Architecture string `json:"architecture,omitempty"`
 }
 
+type CacheItemCorruptedEvent struct {
+	CatalogId      string `json:"catalog_id"`
+	Version        string `json:"version"`
+	Architecture   string `json:"architecture,omitempty"`
+	Reason         string `json:"reason"`
+	QuarantinePath string `json:"quarantine_path,omitempty"`
+}
+
+type CacheItemRepairEvent struct {
+	CatalogId    string `json:"catalog_id"`
+	Version      string `json:"version"`
+	Architecture string `json:"architecture,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+type CacheScrubCompletedEvent struct {
+	JobId        string `json:"job_id,omitempty"`
+	Checked      int    `json:"checked"`
+	Healthy      int    `json:"healthy"`
+	Skipped      int    `json:"skipped"`
+	Corrupted    int    `json:"corrupted"`
+	Repaired     int    `json:"repaired"`
+	RepairFailed int    `json:"repair_failed"`
+}
+
 type MacVMsRunningNowEvent struct {
 	MacVmsRunning []string `json:"mac_vms_running"`
 }
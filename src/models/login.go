@@ -34,6 +34,31 @@ type LoginResponse struct {
 	Email     string `json:"email,omitempty"`
 	Token     string `json:"token,omitempty"`
 	ExpiresAt int64  `json:"expires_at,omitempty"`
+	// RefreshToken is only issued for user logins, it can be used once to get
+	// a new token and refresh token for the session
+	RefreshToken     string `json:"refresh_token,omitempty"`
+	RefreshExpiresAt int64  `json:"refresh_expires_at,omitempty"`
+	SessionId        string `json:"session_id,omitempty"`
+	// MfaRequired is set when the user has to complete the login with a
+	// second factor, no token is issued until then
+	MfaRequired           bool   `json:"mfa_required,omitempty"`
+	MfaToken              string `json:"mfa_token,omitempty"`
+	MfaExpiresAt          int64  `json:"mfa_expires_at,omitempty"`
+	MfaEnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
+	// RecoveryCodes are only returned when the login completed the enrollment
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (r *RefreshTokenRequest) Validate() error {
+	if r.RefreshToken == "" {
+		return errors.NewWithCode("Refresh token is required", 400)
+	}
+
+	return nil
 }
 
 type ValidateTokenRequest struct {
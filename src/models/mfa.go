@@ -0,0 +1,51 @@
+package models
+
+import "github.com/Parallels/prl-devops-service/errors"
+
+type MfaStatusResponse struct {
+	Enabled bool `json:"enabled"`
+	// Required is set when a role of the user requires a second factor
+	Required      bool   `json:"required"`
+	EnabledAt     string `json:"enabled_at,omitempty"`
+	RecoveryCodes int    `json:"recovery_codes_remaining"`
+}
+
+type MfaEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningUri string `json:"provisioning_uri"`
+}
+
+type MfaRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MfaCodeRequest struct {
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+func (r *MfaCodeRequest) Validate() error {
+	if r.Code == "" && r.RecoveryCode == "" {
+		return errors.NewWithCode("Either code or recovery_code must be provided", 400)
+	}
+
+	return nil
+}
+
+type MfaTokenRequest struct {
+	MfaToken     string `json:"mfa_token"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+func (r *MfaTokenRequest) Validate() error {
+	if r.MfaToken == "" {
+		return errors.NewWithCode("Mfa token is required", 400)
+	}
+
+	return nil
+}
+
+type RoleMfaRequest struct {
+	Required bool `json:"required"`
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type ReverseProxyAuth struct {
+	AllowedIps  []string                 `json:"allowed_ips,omitempty" yaml:"allowed_ips,omitempty"`
+	DeniedIps   []string                 `json:"denied_ips,omitempty" yaml:"denied_ips,omitempty"`
+	Devops      *ReverseProxyAuthDevops  `json:"devops,omitempty" yaml:"devops,omitempty"`
+	Basic       *ReverseProxyAuthBasic   `json:"basic,omitempty" yaml:"basic,omitempty"`
+	ForwardAuth *ReverseProxyAuthForward `json:"forward_auth,omitempty" yaml:"forward_auth,omitempty"`
+}
+
+// Validate checks the auth of a host or a route, tcp and udp hosts can only
+// use the address lists. The plain passwords of the basic users are replaced by
+// their hash
+func (o *ReverseProxyAuth) Validate(addressOnly bool) error {
+	for _, value := range append(append([]string{}, o.AllowedIps...), o.DeniedIps...) {
+		if !isValidIpOrCidr(value) {
+			return errors.NewWithCodef(400, "invalid address or cidr %v", value)
+		}
+	}
+
+	if addressOnly && (o.Devops != nil || o.Basic != nil || o.ForwardAuth != nil) {
+		return errors.NewWithCode("tcp and udp routes can only be protected by the allowed and denied addresses", 400)
+	}
+
+	if o.Basic != nil {
+		if err := o.Basic.Validate(); err != nil {
+			return err
+		}
+	}
+	if o.ForwardAuth != nil {
+		if err := o.ForwardAuth.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type ReverseProxyAuthDevops struct {
+	Enabled bool     `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Roles   []string `json:"roles,omitempty" yaml:"roles,omitempty"`
+	Claims  []string `json:"claims,omitempty" yaml:"claims,omitempty"`
+}
+
+type ReverseProxyAuthBasic struct {
+	Enabled bool                        `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Realm   string                      `json:"realm,omitempty" yaml:"realm,omitempty"`
+	Users   []ReverseProxyAuthBasicUser `json:"users,omitempty" yaml:"users,omitempty"`
+}
+
+func (o *ReverseProxyAuthBasic) Validate() error {
+	if o.Enabled && len(o.Users) == 0 {
+		return errors.NewWithCode("basic auth needs at least one user", 400)
+	}
+
+	for i := range o.Users {
+		user := &o.Users[i]
+		if user.Username == "" || strings.Contains(user.Username, ":") {
+			return errors.NewWithCodef(400, "invalid basic auth username %v", user.Username)
+		}
+		if user.Password != "" {
+			hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+			if err != nil {
+				return errors.NewFromErrorWithCodef(err, 400, "error hashing the password of %v", user.Username)
+			}
+			user.PasswordHash = string(hash)
+			user.Password = ""
+		}
+		if user.PasswordHash == "" {
+			return errors.NewWithCodef(400, "missing password for basic auth user %v", user.Username)
+		}
+		if _, err := bcrypt.Cost([]byte(user.PasswordHash)); err != nil {
+			return errors.NewWithCodef(400, "the password hash of %v is not a bcrypt hash", user.Username)
+		}
+	}
+
+	return nil
+}
+
+// ReverseProxyAuthBasicUser takes the plain Password or the bcrypt hash, like
+// the ones created by htpasswd -B, only the hash is stored
+type ReverseProxyAuthBasicUser struct {
+	Username     string `json:"username" yaml:"username"`
+	Password     string `json:"password,omitempty" yaml:"password,omitempty"`
+	PasswordHash string `json:"password_hash,omitempty" yaml:"password_hash,omitempty"`
+}
+
+type ReverseProxyAuthForward struct {
+	Enabled         bool     `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Url             string   `json:"url,omitempty" yaml:"url,omitempty"`
+	ResponseHeaders []string `json:"response_headers,omitempty" yaml:"response_headers,omitempty"`
+	Timeout         string   `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+func (o *ReverseProxyAuthForward) Validate() error {
+	if o.Enabled || o.Url != "" {
+		parsed, err := url.Parse(o.Url)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return errors.NewWithCodef(400, "invalid forward auth url %v", o.Url)
+		}
+	}
+	if o.Timeout != "" {
+		timeout, err := time.ParseDuration(o.Timeout)
+		if err != nil || timeout <= 0 {
+			return errors.NewWithCodef(400, "invalid forward auth timeout %v", o.Timeout)
+		}
+	}
+
+	return nil
+}
+
+func isValidIpOrCidr(value string) bool {
+	if strings.Contains(value, "/") {
+		_, _, err := net.ParseCIDR(value)
+		return err == nil
+	}
+
+	return net.ParseIP(value) != nil
+}
@@ -8,9 +8,13 @@ type ReverseProxyHostTcpRoute struct {
 	TargetHost      string                      `json:"target_host,omitempty" yaml:"target_host,omitempty"`
 	TargetVmId      string                      `json:"target_vm_id,omitempty" yaml:"target_vm_id,omitempty"`
 	TargetVmDetails *ReverseProxyRouteVmDetails `json:"target_vm_details,omitempty" yaml:"target_vm_details,omitempty"`
+	Upstream        *ReverseProxyUpstream       `json:"upstream,omitempty" yaml:"upstream,omitempty"`
 }
 
 func (r *ReverseProxyHostTcpRoute) Validate() error {
+	if r.Upstream != nil {
+		return r.Upstream.Validate(r.TargetPort)
+	}
 	if r.TargetHost == "" && r.TargetVmId == "" {
 		return errors.NewWithCode("missing target host or target vm id for TCP route", 400)
 	}
@@ -22,12 +26,16 @@ func (r *ReverseProxyHostTcpRoute) Validate() error {
 }
 
 type ReverseProxyHostTcpRouteCreateRequest struct {
-	TargetPort string `json:"target_port,omitempty" yaml:"target_port,omitempty"`
-	TargetHost string `json:"target_host,omitempty" yaml:"target_host,omitempty"`
-	TargetVmId string `json:"target_vm_id,omitempty" yaml:"target_vm_id,omitempty"`
+	TargetPort string                `json:"target_port,omitempty" yaml:"target_port,omitempty"`
+	TargetHost string                `json:"target_host,omitempty" yaml:"target_host,omitempty"`
+	TargetVmId string                `json:"target_vm_id,omitempty" yaml:"target_vm_id,omitempty"`
+	Upstream   *ReverseProxyUpstream `json:"upstream,omitempty" yaml:"upstream,omitempty"`
 }
 
 func (r *ReverseProxyHostTcpRouteCreateRequest) Validate() error {
+	if r.Upstream != nil {
+		return r.Upstream.Validate(r.TargetPort)
+	}
 	if r.TargetHost == "" && r.TargetVmId == "" {
 		return errors.NewWithCode("missing target host or target vm id for TCP route", 400)
 	}
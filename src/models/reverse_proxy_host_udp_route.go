@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+type ReverseProxyHostUdpRoute struct {
+	ID              string                      `json:"id,omitempty" yaml:"id,omitempty"`
+	TargetPort      string                      `json:"target_port,omitempty" yaml:"target_port,omitempty"`
+	TargetHost      string                      `json:"target_host,omitempty" yaml:"target_host,omitempty"`
+	TargetVmId      string                      `json:"target_vm_id,omitempty" yaml:"target_vm_id,omitempty"`
+	TargetVmDetails *ReverseProxyRouteVmDetails `json:"target_vm_details,omitempty" yaml:"target_vm_details,omitempty"`
+	IdleTimeout     string                      `json:"idle_timeout,omitempty" yaml:"idle_timeout,omitempty"`
+	MaxSessions     int                         `json:"max_sessions,omitempty" yaml:"max_sessions,omitempty"`
+}
+
+func (r *ReverseProxyHostUdpRoute) Validate() error {
+	return validateUdpRoute(r.TargetHost, r.TargetVmId, r.TargetPort, r.IdleTimeout, r.MaxSessions)
+}
+
+type ReverseProxyHostUdpRouteCreateRequest struct {
+	TargetPort  string `json:"target_port,omitempty" yaml:"target_port,omitempty"`
+	TargetHost  string `json:"target_host,omitempty" yaml:"target_host,omitempty"`
+	TargetVmId  string `json:"target_vm_id,omitempty" yaml:"target_vm_id,omitempty"`
+	IdleTimeout string `json:"idle_timeout,omitempty" yaml:"idle_timeout,omitempty"`
+	MaxSessions int    `json:"max_sessions,omitempty" yaml:"max_sessions,omitempty"`
+}
+
+func (r *ReverseProxyHostUdpRouteCreateRequest) Validate() error {
+	return validateUdpRoute(r.TargetHost, r.TargetVmId, r.TargetPort, r.IdleTimeout, r.MaxSessions)
+}
+
+func validateUdpRoute(targetHost, targetVmId, targetPort, idleTimeout string, maxSessions int) error {
+	if targetHost == "" && targetVmId == "" {
+		return errors.NewWithCode("missing target host or target vm id for UDP route", 400)
+	}
+	if targetPort == "" {
+		return errors.NewWithCode("missing target port for UDP route", 400)
+	}
+	if idleTimeout != "" {
+		timeout, err := time.ParseDuration(idleTimeout)
+		if err != nil || timeout <= 0 {
+			return errors.NewWithCodef(400, "invalid udp idle timeout %v", idleTimeout)
+		}
+	}
+	if maxSessions < 0 {
+		return errors.NewWithCodef(400, "invalid udp max sessions %v", maxSessions)
+	}
+
+	return nil
+}
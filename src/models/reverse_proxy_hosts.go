@@ -9,8 +9,10 @@ type ReverseProxyHost struct {
 	Port       string                       `json:"port"`
 	Tls        *ReverseProxyHostTls         `json:"tls,omitempty"`
 	Cors       *ReverseProxyHostCors        `json:"cors,omitempty"`
+	Auth       *ReverseProxyAuth            `json:"auth,omitempty"`
 	HttpRoutes []*ReverseProxyHostHttpRoute `json:"http_routes,omitempty"`
 	TcpRoute   *ReverseProxyHostTcpRoute    `json:"tcp_route,omitempty"`
+	UdpRoute   *ReverseProxyHostUdpRoute    `json:"udp_route,omitempty"`
 }
 
 type ReverseProxyRouteVmDetails struct {
@@ -37,8 +39,10 @@ type ReverseProxyHostCreateRequest struct {
 	Port       string                       `json:"port"`
 	Tls        *ReverseProxyHostTls         `json:"tls,omitempty"`
 	Cors       *ReverseProxyHostCors        `json:"cors,omitempty"`
+	Auth       *ReverseProxyAuth            `json:"auth,omitempty"`
 	HttpRoutes []*ReverseProxyHostHttpRoute `json:"http_routes,omitempty"`
 	TcpRoute   *ReverseProxyHostTcpRoute    `json:"tcp_route,omitempty"`
+	UdpRoute   *ReverseProxyHostUdpRoute    `json:"udp_route,omitempty"`
 }
 
 func (o *ReverseProxyHostCreateRequest) GetHost() string {
@@ -68,13 +72,25 @@ func (o *ReverseProxyHostCreateRequest) Validate() error {
 		}
 	}
 
-	if len(o.HttpRoutes) == 0 && o.TcpRoute == nil {
+	if o.Auth != nil {
+		if err := o.Auth.Validate(o.TcpRoute != nil || o.UdpRoute != nil); err != nil {
+			return err
+		}
+	}
+
+	if len(o.HttpRoutes) == 0 && o.TcpRoute == nil && o.UdpRoute == nil {
 		return errors.NewWithCode("missing reverse proxy host routes", 400)
 	}
 
 	if len(o.HttpRoutes) > 0 && o.TcpRoute != nil {
 		return errors.NewWithCode("reverse proxy host cannot have both http and tcp routes", 400)
 	}
+	if len(o.HttpRoutes) > 0 && o.UdpRoute != nil {
+		return errors.NewWithCode("reverse proxy host cannot have both http and udp routes", 400)
+	}
+	if o.TcpRoute != nil && o.UdpRoute != nil {
+		return errors.NewWithCode("reverse proxy host cannot have both tcp and udp routes", 400)
+	}
 
 	if o.TcpRoute != nil {
 		if o.Cors != nil {
@@ -88,6 +104,18 @@ func (o *ReverseProxyHostCreateRequest) Validate() error {
 		}
 	}
 
+	if o.UdpRoute != nil {
+		if o.Cors != nil {
+			return errors.NewWithCode("reverse proxy host cannot have cors and udp route", 400)
+		}
+		if o.Tls != nil {
+			return errors.NewWithCode("reverse proxy host cannot have tls and udp route", 400)
+		}
+		if err := o.UdpRoute.Validate(); err != nil {
+			return err
+		}
+	}
+
 	for _, route := range o.HttpRoutes {
 		if err := route.Validate(); err != nil {
 			return err
@@ -103,6 +131,7 @@ type ReverseProxyHostUpdateRequest struct {
 	Port string                `json:"port"`
 	Tls  *ReverseProxyHostTls  `json:"tls,omitempty"`
 	Cors *ReverseProxyHostCors `json:"cors,omitempty"`
+	Auth *ReverseProxyAuth     `json:"auth,omitempty"`
 }
 
 func (o *ReverseProxyHostUpdateRequest) GetHost() string {
@@ -131,5 +160,11 @@ func (o *ReverseProxyHostUpdateRequest) Validate() error {
 			return err
 		}
 	}
+
+	if o.Auth != nil {
+		if err := o.Auth.Validate(false); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -1,14 +1,24 @@
 package models
 
-import "github.com/Parallels/prl-devops-service/errors"
+import (
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/errors"
+)
 
 type ReverseProxyHostTls struct {
-	Enabled bool   `json:"enabled,omitempty" yaml:"enabled,omitempty"`
-	Cert    string `json:"cert,omitempty" yaml:"cert,omitempty"`
-	Key     string `json:"key,omitempty" yaml:"key,omitempty"`
+	Enabled bool                     `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Cert    string                   `json:"cert,omitempty" yaml:"cert,omitempty"`
+	Key     string                   `json:"key,omitempty" yaml:"key,omitempty"`
+	Acme    *ReverseProxyHostTlsAcme `json:"acme,omitempty" yaml:"acme,omitempty"`
 }
 
 func (o *ReverseProxyHostTls) Validate() error {
+	if o.Acme != nil && o.Acme.Enabled {
+		return o.Acme.Validate()
+	}
+
 	if o.Cert == "" {
 		return errors.NewWithCode("missing reverse proxy host tls cert", 400)
 	}
@@ -17,3 +27,55 @@ func (o *ReverseProxyHostTls) Validate() error {
 	}
 	return nil
 }
+
+// ReverseProxyHostTlsAcme requests the certificate of the host from the ACME
+// directory, the Cert and Key of the host are not needed
+type ReverseProxyHostTlsAcme struct {
+	Enabled     bool     `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Domains     []string `json:"domains,omitempty" yaml:"domains,omitempty"`
+	Email       string   `json:"email,omitempty" yaml:"email,omitempty"`
+	Challenge   string   `json:"challenge,omitempty" yaml:"challenge,omitempty"`
+	DnsProvider string   `json:"dns_provider,omitempty" yaml:"dns_provider,omitempty"`
+}
+
+func (o *ReverseProxyHostTlsAcme) Validate() error {
+	o.Challenge = strings.ToLower(strings.TrimSpace(o.Challenge))
+	if o.Challenge == "" {
+		o.Challenge = constants.REVERSE_PROXY_ACME_HTTP_01
+	}
+	valid := false
+	for _, challenge := range constants.AllReverseProxyAcmeChallenges {
+		if o.Challenge == challenge {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return errors.NewWithCodef(400, "invalid acme challenge %v, it must be one of %v", o.Challenge, strings.Join(constants.AllReverseProxyAcmeChallenges, ", "))
+	}
+
+	if len(o.Domains) == 0 {
+		return errors.NewWithCode("missing reverse proxy host tls acme domains", 400)
+	}
+	for i, domain := range o.Domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" || strings.ContainsAny(domain, " /:") {
+			return errors.NewWithCodef(400, "invalid acme domain %v", o.Domains[i])
+		}
+		if strings.Contains(domain, "*") {
+			if !strings.HasPrefix(domain, "*.") || strings.Count(domain, "*") > 1 {
+				return errors.NewWithCodef(400, "invalid acme domain %v", o.Domains[i])
+			}
+			if o.Challenge != constants.REVERSE_PROXY_ACME_DNS_01 {
+				return errors.NewWithCodef(400, "acme domain %v needs the %v challenge", o.Domains[i], constants.REVERSE_PROXY_ACME_DNS_01)
+			}
+		}
+		o.Domains[i] = domain
+	}
+
+	if o.Challenge == constants.REVERSE_PROXY_ACME_DNS_01 && o.DnsProvider == "" {
+		return errors.NewWithCode("missing acme dns provider for the dns-01 challenge", 400)
+	}
+
+	return nil
+}
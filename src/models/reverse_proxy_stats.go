@@ -0,0 +1,115 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// ReverseProxyStats is the traffic of the reverse proxy hosts of a service,
+// the orchestrator sets the HostId of the host it got them from
+type ReverseProxyStats struct {
+	HostId   string                   `json:"host_id,omitempty"`
+	HostName string                   `json:"host_name,omitempty"`
+	Totals   ReverseProxyTrafficStats `json:"totals"`
+	Hosts    []ReverseProxyHostStats  `json:"hosts"`
+}
+
+type ReverseProxyHostStats struct {
+	ReverseProxyHostId string                   `json:"reverse_proxy_host_id"`
+	Host               string                   `json:"host,omitempty"`
+	Totals             ReverseProxyTrafficStats `json:"totals"`
+	Routes             []ReverseProxyRouteStats `json:"routes"`
+}
+
+type ReverseProxyRouteStats struct {
+	RouteId     string `json:"route_id,omitempty"`
+	Route       string `json:"route,omitempty"`
+	TrafficType string `json:"traffic_type"`
+	ReverseProxyTrafficStats
+}
+
+// ReverseProxyTrafficStats counts the requests of a route, for tcp and udp
+// routes every connection or session is a request
+type ReverseProxyTrafficStats struct {
+	Requests          int64                        `json:"requests"`
+	ActiveConnections int64                        `json:"active_connections"`
+	Errors            int64                        `json:"errors"`
+	StatusCodes       map[string]int64             `json:"status_codes,omitempty"`
+	BytesIn           int64                        `json:"bytes_in"`
+	BytesOut          int64                        `json:"bytes_out"`
+	Latency           ReverseProxyLatencyHistogram `json:"latency"`
+}
+
+// ReverseProxyLatencyHistogram has cumulative buckets, each one counts the
+// requests that took up to its Le
+type ReverseProxyLatencyHistogram struct {
+	Count     int64                       `json:"count"`
+	SumMs     float64                     `json:"sum_ms"`
+	AverageMs float64                     `json:"average_ms"`
+	Buckets   []ReverseProxyLatencyBucket `json:"buckets,omitempty"`
+}
+
+type ReverseProxyLatencyBucket struct {
+	Le    string `json:"le"`
+	Count int64  `json:"count"`
+}
+
+// Add sums the traffic of another route or host
+func (s *ReverseProxyTrafficStats) Add(other ReverseProxyTrafficStats) {
+	s.Requests += other.Requests
+	s.ActiveConnections += other.ActiveConnections
+	s.Errors += other.Errors
+	s.BytesIn += other.BytesIn
+	s.BytesOut += other.BytesOut
+	for class, count := range other.StatusCodes {
+		if s.StatusCodes == nil {
+			s.StatusCodes = make(map[string]int64)
+		}
+		s.StatusCodes[class] += count
+	}
+	s.Latency.Add(other.Latency)
+}
+
+// Add merges the buckets of another histogram, the buckets are kept sorted by
+// their Le so they stay cumulative when the histograms have different bounds
+func (h *ReverseProxyLatencyHistogram) Add(other ReverseProxyLatencyHistogram) {
+	h.Count += other.Count
+	h.SumMs += other.SumMs
+	h.AverageMs = 0
+	if h.Count > 0 {
+		h.AverageMs = h.SumMs / float64(h.Count)
+	}
+
+	for _, bucket := range other.Buckets {
+		found := false
+		for i := range h.Buckets {
+			if h.Buckets[i].Le == bucket.Le {
+				h.Buckets[i].Count += bucket.Count
+				found = true
+				break
+			}
+		}
+		if !found {
+			h.Buckets = append(h.Buckets, bucket)
+		}
+	}
+	sort.SliceStable(h.Buckets, func(i, j int) bool {
+		return latencyBucketBound(h.Buckets[i].Le) < latencyBucketBound(h.Buckets[j].Le)
+	})
+}
+
+func latencyBucketBound(le string) time.Duration {
+	if bound, err := time.ParseDuration(le); err == nil {
+		return bound
+	}
+
+	return time.Duration(math.MaxInt64)
+}
+
+// OrchestratorReverseProxyStats is the traffic of the reverse proxy of every
+// host of the orchestrator
+type OrchestratorReverseProxyStats struct {
+	Totals ReverseProxyTrafficStats `json:"totals"`
+	Hosts  []ReverseProxyStats      `json:"hosts"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReverseProxyTrafficStatsAddLatency(t *testing.T) {
+	var totals ReverseProxyTrafficStats
+	totals.Add(ReverseProxyTrafficStats{
+		Requests: 2,
+		Latency: ReverseProxyLatencyHistogram{
+			Count: 2,
+			SumMs: 30,
+			Buckets: []ReverseProxyLatencyBucket{
+				{Le: "10ms", Count: 1},
+				{Le: "1s", Count: 2},
+				{Le: "+Inf", Count: 2},
+			},
+		},
+	})
+	totals.Add(ReverseProxyTrafficStats{
+		Requests: 1,
+		Latency: ReverseProxyLatencyHistogram{
+			Count: 1,
+			SumMs: 3,
+			Buckets: []ReverseProxyLatencyBucket{
+				{Le: "5ms", Count: 1},
+				{Le: "10ms", Count: 1},
+				{Le: "+Inf", Count: 1},
+			},
+		},
+	})
+
+	assert.Equal(t, int64(3), totals.Requests)
+	assert.Equal(t, int64(3), totals.Latency.Count)
+	assert.Equal(t, 33.0, totals.Latency.SumMs)
+	assert.Equal(t, 11.0, totals.Latency.AverageMs)
+	assert.Equal(t, []ReverseProxyLatencyBucket{
+		{Le: "5ms", Count: 1},
+		{Le: "10ms", Count: 2},
+		{Le: "1s", Count: 2},
+		{Le: "+Inf", Count: 3},
+	}, totals.Latency.Buckets)
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+type ReverseProxyUpstream struct {
+	Strategy    string                           `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	Targets     []ReverseProxyUpstreamTarget     `json:"targets,omitempty" yaml:"targets,omitempty"`
+	VmTags      []string                         `json:"vm_tags,omitempty" yaml:"vm_tags,omitempty"`
+	HealthCheck *ReverseProxyUpstreamHealthCheck `json:"health_check,omitempty" yaml:"health_check,omitempty"`
+	MaxFails    int                              `json:"max_fails,omitempty" yaml:"max_fails,omitempty"`
+	FailTimeout string                           `json:"fail_timeout,omitempty" yaml:"fail_timeout,omitempty"`
+}
+
+// Validate checks the pool, targets without a port and the virtual machines
+// found by tag use the port of the route
+func (o *ReverseProxyUpstream) Validate(routePort string) error {
+	o.Strategy = strings.ToLower(strings.TrimSpace(o.Strategy))
+	if o.Strategy == "" {
+		o.Strategy = constants.REVERSE_PROXY_UPSTREAM_ROUND_ROBIN
+	}
+	valid := false
+	for _, strategy := range constants.AllReverseProxyUpstreamStrategies {
+		if o.Strategy == strategy {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return errors.NewWithCodef(400, "invalid upstream strategy %v, it must be one of %v", o.Strategy, strings.Join(constants.AllReverseProxyUpstreamStrategies, ", "))
+	}
+
+	if len(o.Targets) == 0 && len(o.VmTags) == 0 {
+		return errors.NewWithCode("upstream needs at least one target or vm tag", 400)
+	}
+	for _, target := range o.Targets {
+		if target.TargetHost == "" && target.TargetVmId == "" {
+			return errors.NewWithCode("missing target host or target vm id for upstream target", 400)
+		}
+		if target.TargetPort == "" && routePort == "" {
+			return errors.NewWithCode("missing target port for upstream target", 400)
+		}
+	}
+	if len(o.VmTags) > 0 && routePort == "" {
+		return errors.NewWithCode("missing target port for the upstream vm tags", 400)
+	}
+
+	if o.MaxFails < 0 {
+		return errors.NewWithCode("upstream max fails cannot be negative", 400)
+	}
+	if err := validateUpstreamDuration("fail timeout", o.FailTimeout); err != nil {
+		return err
+	}
+	if o.HealthCheck != nil {
+		if err := o.HealthCheck.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type ReverseProxyUpstreamTarget struct {
+	TargetVmId string `json:"target_vm_id,omitempty" yaml:"target_vm_id,omitempty"`
+	TargetHost string `json:"target_host,omitempty" yaml:"target_host,omitempty"`
+	TargetPort string `json:"target_port,omitempty" yaml:"target_port,omitempty"`
+}
+
+type ReverseProxyUpstreamHealthCheck struct {
+	Enabled            bool   `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Path               string `json:"path,omitempty" yaml:"path,omitempty"`
+	Interval           string `json:"interval,omitempty" yaml:"interval,omitempty"`
+	Timeout            string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	HealthyThreshold   int    `json:"healthy_threshold,omitempty" yaml:"healthy_threshold,omitempty"`
+	UnhealthyThreshold int    `json:"unhealthy_threshold,omitempty" yaml:"unhealthy_threshold,omitempty"`
+}
+
+func (o *ReverseProxyUpstreamHealthCheck) Validate() error {
+	if o.Path != "" && !strings.HasPrefix(o.Path, "/") {
+		return errors.NewWithCode("upstream health check path must start with /", 400)
+	}
+	if err := validateUpstreamDuration("health check interval", o.Interval); err != nil {
+		return err
+	}
+	if err := validateUpstreamDuration("health check timeout", o.Timeout); err != nil {
+		return err
+	}
+	if o.HealthyThreshold < 0 || o.UnhealthyThreshold < 0 {
+		return errors.NewWithCode("upstream health check thresholds cannot be negative", 400)
+	}
+
+	return nil
+}
+
+func validateUpstreamDuration(name string, value string) error {
+	if value == "" {
+		return nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return errors.NewWithCodef(400, "invalid upstream %v %v", name, value)
+	}
+
+	return nil
+}
@@ -2,31 +2,50 @@ package models
 
 import (
 	"regexp"
+	"strings"
+	"time"
 
+	"github.com/Parallels/prl-devops-service/constants"
 	"github.com/Parallels/prl-devops-service/errors"
 )
 
 type ReverseProxyHostHttpRoute struct {
-	ID              string                      `json:"id,omitempty" yaml:"id,omitempty"`
-	Order           int                         `json:"order,omitempty" yaml:"order,omitempty"`
-	Path            string                      `json:"path,omitempty" yaml:"path,omitempty"`
-	TargetVmId      string                      `json:"target_vm_id,omitempty" yaml:"target_vm_id,omitempty"`
-	TargetHost      string                      `json:"target_host,omitempty" yaml:"target_host,omitempty"`
-	TargetPort      string                      `json:"target_port,omitempty" yaml:"target_port,omitempty"`
-	TargetVmDetails *ReverseProxyRouteVmDetails `json:"target_vm_details,omitempty" yaml:"target_vm_details,omitempty"`
-	Schema          string                      `json:"schema,omitempty" yaml:"scheme,omitempty"`
-	Pattern         string                      `json:"pattern,omitempty" yaml:"pattern,omitempty"`
-	RegexpPattern   *regexp.Regexp              `json:"-" yaml:"-"`
-	RequestHeaders  map[string]string           `json:"request_headers,omitempty" yaml:"request_headers,omitempty"`
-	ResponseHeaders map[string]string           `json:"response_headers,omitempty" yaml:"response_headers,omitempty"`
+	ID              string                              `json:"id,omitempty" yaml:"id,omitempty"`
+	Order           int                                 `json:"order,omitempty" yaml:"order,omitempty"`
+	Path            string                              `json:"path,omitempty" yaml:"path,omitempty"`
+	TargetVmId      string                              `json:"target_vm_id,omitempty" yaml:"target_vm_id,omitempty"`
+	TargetHost      string                              `json:"target_host,omitempty" yaml:"target_host,omitempty"`
+	TargetPort      string                              `json:"target_port,omitempty" yaml:"target_port,omitempty"`
+	TargetVmDetails *ReverseProxyRouteVmDetails         `json:"target_vm_details,omitempty" yaml:"target_vm_details,omitempty"`
+	Schema          string                              `json:"schema,omitempty" yaml:"scheme,omitempty"`
+	Pattern         string                              `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	RegexpPattern   *regexp.Regexp                      `json:"-" yaml:"-"`
+	RequestHeaders  map[string]string                   `json:"request_headers,omitempty" yaml:"request_headers,omitempty"`
+	ResponseHeaders map[string]string                   `json:"response_headers,omitempty" yaml:"response_headers,omitempty"`
+	Upstream        *ReverseProxyUpstream               `json:"upstream,omitempty" yaml:"upstream,omitempty"`
+	Auth            *ReverseProxyAuth                   `json:"auth,omitempty" yaml:"auth,omitempty"`
+	Protocol        string                              `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	Streaming       bool                                `json:"streaming,omitempty" yaml:"streaming,omitempty"`
+	WebSocket       *ReverseProxyHostHttpRouteWebSocket `json:"websocket,omitempty" yaml:"websocket,omitempty"`
+}
+
+type ReverseProxyHostHttpRouteWebSocket struct {
+	Enabled     bool   `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	IdleTimeout string `json:"idle_timeout,omitempty" yaml:"idle_timeout,omitempty"`
 }
 
 func (r *ReverseProxyHostHttpRoute) Validate() error {
-	if r.TargetHost == "" && r.TargetVmId == "" {
-		return errors.NewWithCode("missing target host or target vm id for TCP route", 400)
-	}
-	if r.TargetPort == "" {
-		return errors.NewWithCode("missing target port for TCP route", 400)
+	if r.Upstream != nil {
+		if err := r.Upstream.Validate(r.TargetPort); err != nil {
+			return err
+		}
+	} else {
+		if r.TargetHost == "" && r.TargetVmId == "" {
+			return errors.NewWithCode("missing target host or target vm id for TCP route", 400)
+		}
+		if r.TargetPort == "" {
+			return errors.NewWithCode("missing target port for TCP route", 400)
+		}
 	}
 	if r.Path == "" && r.Pattern == "" {
 		return errors.NewWithCode("missing path or pattern for HTTP route", 400)
@@ -36,28 +55,49 @@ func (r *ReverseProxyHostHttpRoute) Validate() error {
 		return errors.NewWithCode("HTTP route cannot have both path and pattern", 400)
 	}
 
+	if r.Auth != nil {
+		if err := r.Auth.Validate(false); err != nil {
+			return err
+		}
+	}
+
+	if err := validateHttpRouteProtocol(&r.Protocol, r.WebSocket); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 type ReverseProxyHostHttpRouteCreateRequest struct {
-	Order           int               `json:"order,omitempty" yaml:"order,omitempty"`
-	Path            string            `json:"path,omitempty" yaml:"path,omitempty"`
-	TargetVmId      string            `json:"target_vm_id,omitempty" yaml:"target_vm_id,omitempty"`
-	TargetHost      string            `json:"target_host,omitempty" yaml:"target_host,omitempty"`
-	TargetPort      string            `json:"target_port,omitempty" yaml:"target_port,omitempty"`
-	Schema          string            `json:"schema,omitempty" yaml:"scheme,omitempty"`
-	Pattern         string            `json:"pattern,omitempty" yaml:"pattern,omitempty"`
-	RequestHeaders  map[string]string `json:"request_headers,omitempty" yaml:"request_headers,omitempty"`
-	ResponseHeaders map[string]string `json:"response_headers,omitempty" yaml:"response_headers,omitempty"`
+	Order           int                                 `json:"order,omitempty" yaml:"order,omitempty"`
+	Path            string                              `json:"path,omitempty" yaml:"path,omitempty"`
+	TargetVmId      string                              `json:"target_vm_id,omitempty" yaml:"target_vm_id,omitempty"`
+	TargetHost      string                              `json:"target_host,omitempty" yaml:"target_host,omitempty"`
+	TargetPort      string                              `json:"target_port,omitempty" yaml:"target_port,omitempty"`
+	Schema          string                              `json:"schema,omitempty" yaml:"scheme,omitempty"`
+	Pattern         string                              `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	RequestHeaders  map[string]string                   `json:"request_headers,omitempty" yaml:"request_headers,omitempty"`
+	ResponseHeaders map[string]string                   `json:"response_headers,omitempty" yaml:"response_headers,omitempty"`
+	Upstream        *ReverseProxyUpstream               `json:"upstream,omitempty" yaml:"upstream,omitempty"`
+	Auth            *ReverseProxyAuth                   `json:"auth,omitempty" yaml:"auth,omitempty"`
+	Protocol        string                              `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	Streaming       bool                                `json:"streaming,omitempty" yaml:"streaming,omitempty"`
+	WebSocket       *ReverseProxyHostHttpRouteWebSocket `json:"websocket,omitempty" yaml:"websocket,omitempty"`
 }
 
 func (r *ReverseProxyHostHttpRouteCreateRequest) Validate() error {
-	if r.TargetPort == "" {
-		return errors.NewWithCode("missing target port for TCP route", 400)
-	}
+	if r.Upstream != nil {
+		if err := r.Upstream.Validate(r.TargetPort); err != nil {
+			return err
+		}
+	} else {
+		if r.TargetPort == "" {
+			return errors.NewWithCode("missing target port for TCP route", 400)
+		}
 
-	if r.TargetHost == "" && r.TargetVmId == "" {
-		return errors.NewWithCode("missing target host or target vm id for TCP route", 400)
+		if r.TargetHost == "" && r.TargetVmId == "" {
+			return errors.NewWithCode("missing target host or target vm id for TCP route", 400)
+		}
 	}
 	if r.Path == "" && r.Pattern == "" {
 		return errors.NewWithCode("missing path or pattern for HTTP route", 400)
@@ -67,6 +107,16 @@ func (r *ReverseProxyHostHttpRouteCreateRequest) Validate() error {
 		return errors.NewWithCode("HTTP route cannot have both path and pattern", 400)
 	}
 
+	if r.Auth != nil {
+		if err := r.Auth.Validate(false); err != nil {
+			return err
+		}
+	}
+
+	if err := validateHttpRouteProtocol(&r.Protocol, r.WebSocket); err != nil {
+		return err
+	}
+
 	if r.Pattern != "" {
 		_, err := regexp.Compile(r.Pattern)
 		if err != nil {
@@ -88,6 +138,38 @@ func (r *ReverseProxyHostHttpRouteCreateRequest) GetRoute() string {
 	return ""
 }
 
+// validateHttpRouteProtocol checks the protocol of a route, the upgraded
+// connections of websockets only work over http1
+func validateHttpRouteProtocol(protocol *string, webSocket *ReverseProxyHostHttpRouteWebSocket) error {
+	*protocol = strings.ToLower(strings.TrimSpace(*protocol))
+	if *protocol != "" {
+		valid := false
+		for _, value := range constants.AllReverseProxyProtocols {
+			if *protocol == value {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return errors.NewWithCodef(400, "invalid protocol %v for HTTP route, it must be one of %v", *protocol, strings.Join(constants.AllReverseProxyProtocols, ", "))
+		}
+	}
+
+	if webSocket != nil {
+		if webSocket.Enabled && (*protocol == constants.REVERSE_PROXY_PROTOCOL_HTTP2 || *protocol == constants.REVERSE_PROXY_PROTOCOL_GRPC) {
+			return errors.NewWithCodef(400, "websockets cannot be used with the %v protocol", *protocol)
+		}
+		if webSocket.IdleTimeout != "" {
+			timeout, err := time.ParseDuration(webSocket.IdleTimeout)
+			if err != nil || timeout <= 0 {
+				return errors.NewWithCodef(400, "invalid websocket idle timeout %v", webSocket.IdleTimeout)
+			}
+		}
+	}
+
+	return nil
+}
+
 type ReverseProxyHostHttpRouteReorderRequest struct {
 	ID    string `json:"id"`
 	Order int    `json:"order"`
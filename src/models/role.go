@@ -32,6 +32,7 @@ type RoleResponse struct {
 	Name        string          `json:"name"`
 	Description string          `json:"description,omitempty"`
 	Internal    bool            `json:"internal"`
+	MfaRequired bool            `json:"mfa_required,omitempty"`
 	Claims      []ClaimResponse `json:"claims"`
 	Users       []ApiUser       `json:"users"`
 }
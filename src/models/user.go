@@ -51,14 +51,18 @@ type UserClaimResponse struct {
 }
 
 type ApiUser struct {
-	ID              string              `json:"id,omitempty"`
-	Username        string              `json:"username"`
-	Name            string              `json:"name,omitempty"`
-	Email           string              `json:"email"`
-	Roles           []string            `json:"roles,omitempty"`
-	Claims          []string            `json:"claims,omitempty"`
-	EffectiveClaims []UserClaimResponse `json:"effective_claims"`
-	IsSuperUser     bool                `json:"isSuperUser"`
+	ID               string              `json:"id,omitempty"`
+	Username         string              `json:"username"`
+	Name             string              `json:"name,omitempty"`
+	Email            string              `json:"email"`
+	Roles            []string            `json:"roles,omitempty"`
+	Claims           []string            `json:"claims,omitempty"`
+	EffectiveClaims  []UserClaimResponse `json:"effective_claims"`
+	IsSuperUser      bool                `json:"isSuperUser"`
+	IdentityProvider string              `json:"identity_provider,omitempty"`
+	Disabled         bool                `json:"disabled,omitempty"`
+	DisabledReason   string              `json:"disabled_reason,omitempty"`
+	MfaEnabled       bool                `json:"mfa_enabled,omitempty"`
 }
 
 type UserUpdateRequest struct {
@@ -0,0 +1,18 @@
+package models
+
+type UserSessionResponse struct {
+	ID                   string `json:"id"`
+	UserID               string `json:"user_id"`
+	AuthenticationMethod string `json:"authentication_method,omitempty"`
+	IpAddress            string `json:"ip_address,omitempty"`
+	UserAgent            string `json:"user_agent,omitempty"`
+	CreatedAt            string `json:"created_at"`
+	LastUsedAt           string `json:"last_used_at,omitempty"`
+	ExpiresAt            string `json:"expires_at"`
+	// Current is set on the session of the token used in the request
+	Current bool `json:"current,omitempty"`
+}
+
+type RevokeSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
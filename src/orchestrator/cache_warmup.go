@@ -0,0 +1,325 @@
+package orchestrator
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	catalog_models "github.com/Parallels/prl-devops-service/catalog/models"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/helpers"
+	apimodels "github.com/Parallels/prl-devops-service/models"
+)
+
+var (
+	cacheWarmupState   = make(map[string]apimodels.CacheWarmupHostCompliance)
+	cacheWarmupLock    sync.RWMutex
+	cacheWarmupRunLock sync.Mutex
+)
+
+func cacheWarmupKey(policyId string, hostId string) string {
+	return policyId + "/" + hostId
+}
+
+// hostMatchesTags checks if the host has all of the tags, an empty list of
+// tags matches every host.
+func hostMatchesTags(host models.OrchestratorHost, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, hostTag := range host.Tags {
+			if strings.EqualFold(hostTag, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ApplyCacheWarmupPolicies asks every host matching an enabled policy to cache
+// the policy manifest. Hosts only download what they do not have, so running
+// this on every full refresh keeps channels up to date as new versions are
+// pushed and recovers items that were evicted from the cache.
+func (s *OrchestratorService) ApplyCacheWarmupPolicies() {
+	if !cacheWarmupRunLock.TryLock() {
+		return
+	}
+	defer cacheWarmupRunLock.Unlock()
+
+	policies, err := s.db.GetCacheWarmupPolicies(s.ctx, "")
+	if err != nil {
+		s.ctx.LogErrorf("[Orchestrator] Error getting cache warmup policies: %v", err)
+		return
+	}
+	if len(policies) == 0 {
+		return
+	}
+
+	hosts, err := s.db.GetOrchestratorHosts(s.ctx, "")
+	if err != nil {
+		s.ctx.LogErrorf("[Orchestrator] Error getting hosts for cache warmup: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, host := range hosts {
+		wg.Add(1)
+		go func(host models.OrchestratorHost) {
+			defer wg.Done()
+			for _, policy := range policies {
+				if !policy.Enabled || !hostMatchesTags(host, policy.HostTags) {
+					continue
+				}
+				s.applyCacheWarmupPolicy(host, policy)
+			}
+		}(host)
+	}
+	wg.Wait()
+}
+
+func (s *OrchestratorService) applyCacheWarmupPolicy(host models.OrchestratorHost, policy models.CacheWarmupPolicy) {
+	key := cacheWarmupKey(policy.ID, host.ID)
+	cacheWarmupLock.RLock()
+	previous, exists := cacheWarmupState[key]
+	cacheWarmupLock.RUnlock()
+
+	item := apimodels.CacheWarmupHostCompliance{
+		PolicyId:    policy.ID,
+		PolicyName:  policy.Name,
+		HostId:      host.ID,
+		Host:        getHostName(host),
+		CatalogId:   policy.CatalogId,
+		Version:     policy.Version,
+		Status:      apimodels.CacheWarmupStatusPending,
+		LastChecked: helpers.GetUtcCurrentDateTime(),
+	}
+	if exists {
+		item.Version = previous.Version
+		item.Architecture = previous.Architecture
+	}
+
+	defer func() {
+		cacheWarmupLock.Lock()
+		cacheWarmupState[key] = item
+		cacheWarmupLock.Unlock()
+	}()
+
+	if !host.Enabled || host.State != "healthy" {
+		item.Message = "Host is not available"
+		return
+	}
+
+	// A previous request is still running on the host, we will not start a
+	// new download until it finishes
+	if exists && previous.Status == apimodels.CacheWarmupStatusCaching && previous.JobId != "" {
+		job, err := s.CallGetHostJob(&host, previous.JobId)
+		if err == nil {
+			switch job.State {
+			case constants.JobStateInit, constants.JobStatePending, constants.JobStateRunning:
+				item.Status = apimodels.CacheWarmupStatusCaching
+				item.JobId = previous.JobId
+				item.Message = job.Message
+				return
+			case constants.JobStateFailed:
+				item.Status = apimodels.CacheWarmupStatusFailed
+				item.Message = job.Error
+				return
+			}
+		}
+	}
+
+	request := catalog_models.CacheCatalogManifestRequest{
+		CatalogId:         policy.CatalogId,
+		Version:           policy.Version,
+		Channel:           policy.Channel,
+		Connection:        policy.Connection,
+		MaxTransferRateKb: policy.MaxTransferRateKb,
+	}
+	response, err := s.CallCacheHostCatalogManifest(&host, request)
+	if err != nil {
+		item.Status = apimodels.CacheWarmupStatusFailed
+		item.Message = err.Error()
+		s.ctx.LogWarnf("[Orchestrator] Error applying cache warmup policy %s on host %s: %v", policy.Name, host.Host, err)
+		return
+	}
+
+	item.Version = response.Version
+	item.Architecture = response.Architecture
+	if response.Cached {
+		item.Status = apimodels.CacheWarmupStatusCompliant
+	} else {
+		item.Status = apimodels.CacheWarmupStatusCaching
+		item.JobId = response.JobId
+	}
+}
+
+// ApplyCacheWarmupPolicy runs a single policy straight away, used when a
+// policy is created or updated so hosts do not wait for the next refresh.
+func (s *OrchestratorService) ApplyCacheWarmupPolicy(policy models.CacheWarmupPolicy) {
+	if !policy.Enabled {
+		return
+	}
+
+	hosts, err := s.db.GetOrchestratorHosts(s.ctx, "")
+	if err != nil {
+		s.ctx.LogErrorf("[Orchestrator] Error getting hosts for cache warmup: %v", err)
+		return
+	}
+
+	for _, host := range hosts {
+		if hostMatchesTags(host, policy.HostTags) {
+			s.applyCacheWarmupPolicy(host, policy)
+		}
+	}
+}
+
+// RemoveCacheWarmupPolicyState forgets the compliance of a deleted policy.
+func (s *OrchestratorService) RemoveCacheWarmupPolicyState(policyId string) {
+	cacheWarmupLock.Lock()
+	defer cacheWarmupLock.Unlock()
+
+	for key, item := range cacheWarmupState {
+		if item.PolicyId == policyId {
+			delete(cacheWarmupState, key)
+		}
+	}
+}
+
+func (s *OrchestratorService) GetCacheWarmupPolicyCompliance(ctx basecontext.ApiContext, policyId string) (*apimodels.CacheWarmupComplianceResponse, error) {
+	policy, err := s.db.GetCacheWarmupPolicy(ctx, policyId)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts, err := s.db.GetOrchestratorHosts(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	response := &apimodels.CacheWarmupComplianceResponse{
+		Hosts: make([]apimodels.CacheWarmupHostCompliance, 0),
+	}
+	for _, host := range hosts {
+		if !hostMatchesTags(host, policy.HostTags) {
+			continue
+		}
+		response.Add(getCacheWarmupCompliance(*policy, host))
+	}
+
+	return response, nil
+}
+
+func (s *OrchestratorService) GetHostCacheWarmupCompliance(ctx basecontext.ApiContext, hostId string) (*apimodels.CacheWarmupComplianceResponse, error) {
+	host, err := s.db.GetOrchestratorHost(ctx, hostId)
+	if err != nil {
+		return nil, err
+	}
+	if host == nil {
+		return nil, errors.NewWithCodef(404, "Host %s not found", hostId)
+	}
+
+	policies, err := s.db.GetCacheWarmupPolicies(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	response := &apimodels.CacheWarmupComplianceResponse{
+		Hosts: make([]apimodels.CacheWarmupHostCompliance, 0),
+	}
+	for _, policy := range policies {
+		if !policy.Enabled || !hostMatchesTags(*host, policy.HostTags) {
+			continue
+		}
+		response.Add(getCacheWarmupCompliance(policy, *host))
+	}
+
+	return response, nil
+}
+
+// getCacheWarmupCompliance returns the last known state of the policy on the
+// host, the host cache list is used to confirm compliant items are still
+// there in case they were removed since the last check.
+func getCacheWarmupCompliance(policy models.CacheWarmupPolicy, host models.OrchestratorHost) apimodels.CacheWarmupHostCompliance {
+	cacheWarmupLock.RLock()
+	item, exists := cacheWarmupState[cacheWarmupKey(policy.ID, host.ID)]
+	cacheWarmupLock.RUnlock()
+
+	if !exists {
+		return apimodels.CacheWarmupHostCompliance{
+			PolicyId:   policy.ID,
+			PolicyName: policy.Name,
+			HostId:     host.ID,
+			Host:       getHostName(host),
+			CatalogId:  policy.CatalogId,
+			Version:    policy.Version,
+			Status:     apimodels.CacheWarmupStatusPending,
+		}
+	}
+
+	if item.Status == apimodels.CacheWarmupStatusCompliant && host.CacheItems != nil {
+		found := false
+		for _, cacheItem := range host.CacheItems {
+			if strings.EqualFold(cacheItem.CatalogId, item.CatalogId) && strings.EqualFold(cacheItem.Version, item.Version) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			item.Status = apimodels.CacheWarmupStatusPending
+			item.Message = "Item is no longer in the host cache"
+		}
+	}
+
+	return item
+}
+
+func (s *OrchestratorService) CallCacheHostCatalogManifest(host *models.OrchestratorHost, request catalog_models.CacheCatalogManifestRequest) (*catalog_models.CacheCatalogManifestResponse, error) {
+	httpClient := s.getApiClient(*host)
+	httpClient.WithTimeout(2 * time.Minute)
+	url, err := helpers.JoinUrl([]string{host.GetHost(), "/v1/cache"})
+	if err != nil {
+		return nil, err
+	}
+
+	var response catalog_models.CacheCatalogManifestResponse
+	apiResponse, err := httpClient.Post(url.String(), request, &response)
+	if err != nil {
+		if apiResponse != nil && apiResponse.ApiError != nil {
+			return nil, errors.NewWithCodef(apiResponse.StatusCode, "Error caching catalog %s on host %s: %s", request.CatalogId, host.Host, apiResponse.ApiError.Message)
+		}
+		return nil, err
+	}
+
+	if apiResponse.StatusCode != 200 && apiResponse.StatusCode != 202 {
+		return nil, errors.NewWithCodef(400, "Error caching catalog %s on host %s: %v", request.CatalogId, host.Host, apiResponse.StatusCode)
+	}
+
+	return &response, nil
+}
+
+func (s *OrchestratorService) CallGetHostJob(host *models.OrchestratorHost, jobId string) (*apimodels.JobResponse, error) {
+	httpClient := s.getApiClient(*host)
+	url, err := helpers.JoinUrl([]string{host.GetHost(), "/v1/jobs", jobId})
+	if err != nil {
+		return nil, err
+	}
+
+	var response apimodels.JobResponse
+	apiResponse, err := httpClient.Get(url.String(), &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiResponse.StatusCode != 200 {
+		return nil, errors.NewWithCodef(400, "Error getting job %s for host %s: %v", jobId, host.Host, apiResponse.StatusCode)
+	}
+
+	return &response, nil
+}
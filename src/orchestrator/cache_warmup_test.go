@@ -0,0 +1,53 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/data/models"
+	apimodels "github.com/Parallels/prl-devops-service/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostMatchesTags(t *testing.T) {
+	host := models.OrchestratorHost{ID: "host-1", Tags: []string{"CI", "arm64"}}
+
+	assert.True(t, hostMatchesTags(host, nil))
+	assert.True(t, hostMatchesTags(host, []string{"ci"}))
+	assert.True(t, hostMatchesTags(host, []string{"ci", "ARM64"}))
+	assert.False(t, hostMatchesTags(host, []string{"ci", "x86_64"}))
+}
+
+func TestGetCacheWarmupCompliance(t *testing.T) {
+	policy := models.CacheWarmupPolicy{ID: "policy-1", Name: "runners", CatalogId: "RUNNER", Channel: "latest"}
+	host := models.OrchestratorHost{ID: "host-1", Host: "host-1.local"}
+	defer func() {
+		cacheWarmupLock.Lock()
+		delete(cacheWarmupState, cacheWarmupKey(policy.ID, host.ID))
+		cacheWarmupLock.Unlock()
+	}()
+
+	assert.Equal(t, apimodels.CacheWarmupStatusPending, getCacheWarmupCompliance(policy, host).Status)
+
+	cacheWarmupLock.Lock()
+	cacheWarmupState[cacheWarmupKey(policy.ID, host.ID)] = apimodels.CacheWarmupHostCompliance{
+		PolicyId:  policy.ID,
+		HostId:    host.ID,
+		CatalogId: "RUNNER",
+		Version:   "2.0.0",
+		Status:    apimodels.CacheWarmupStatusCompliant,
+	}
+	cacheWarmupLock.Unlock()
+
+	host.CacheItems = []apimodels.HostCatalogCacheItem{{CatalogId: "runner", Version: "2.0.0"}}
+	assert.Equal(t, apimodels.CacheWarmupStatusCompliant, getCacheWarmupCompliance(policy, host).Status)
+
+	// the item was evicted from the host cache since the last check
+	host.CacheItems = []apimodels.HostCatalogCacheItem{{CatalogId: "runner", Version: "1.0.0"}}
+	assert.Equal(t, apimodels.CacheWarmupStatusPending, getCacheWarmupCompliance(policy, host).Status)
+
+	response := &apimodels.CacheWarmupComplianceResponse{}
+	response.Add(apimodels.CacheWarmupHostCompliance{Status: apimodels.CacheWarmupStatusCompliant})
+	response.Add(apimodels.CacheWarmupHostCompliance{Status: apimodels.CacheWarmupStatusCaching})
+	assert.Equal(t, 2, response.Total)
+	assert.Equal(t, 1, response.Compliant)
+}
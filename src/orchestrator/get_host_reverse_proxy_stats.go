@@ -0,0 +1,103 @@
+package orchestrator
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/helpers"
+	apimodels "github.com/Parallels/prl-devops-service/models"
+)
+
+func (s *OrchestratorService) GetHostReverseProxyStats(ctx basecontext.ApiContext, hostId string) (*apimodels.ReverseProxyStats, error) {
+	host, err := s.GetHost(ctx, hostId)
+	if err != nil {
+		return nil, err
+	}
+
+	if host == nil {
+		return nil, errors.NewWithCodef(404, "Host %s not found", hostId)
+	}
+
+	return s.CallGetHostReverseProxyStats(host)
+}
+
+// GetReverseProxyStats returns the traffic of the reverse proxy of every
+// healthy host, the hosts that fail to answer are left out
+func (s *OrchestratorService) GetReverseProxyStats(ctx basecontext.ApiContext) (*apimodels.OrchestratorReverseProxyStats, error) {
+	hosts, err := s.db.GetOrchestratorHosts(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &apimodels.OrchestratorReverseProxyStats{
+		Hosts: make([]apimodels.ReverseProxyStats, 0),
+	}
+
+	var wg sync.WaitGroup
+	mutex := sync.Mutex{}
+	for _, host := range hosts {
+		if !host.Enabled || host.State != HealthyState {
+			continue
+		}
+
+		wg.Add(1)
+		go func(host data_models.OrchestratorHost) {
+			defer wg.Done()
+			stats, err := s.CallGetHostReverseProxyStats(&host)
+			if err != nil {
+				ctx.LogErrorf("[Orchestrator] Error getting reverse proxy stats for host %s: %v", host.Host, err)
+				return
+			}
+
+			mutex.Lock()
+			result.Hosts = append(result.Hosts, *stats)
+			mutex.Unlock()
+		}(host)
+	}
+	wg.Wait()
+
+	sort.Slice(result.Hosts, func(i, j int) bool {
+		return result.Hosts[i].HostName < result.Hosts[j].HostName
+	})
+	for _, stats := range result.Hosts {
+		result.Totals.Add(stats.Totals)
+	}
+
+	return result, nil
+}
+
+func (s *OrchestratorService) CallGetHostReverseProxyStats(host *data_models.OrchestratorHost) (*apimodels.ReverseProxyStats, error) {
+	if host == nil {
+		return nil, errors.NewWithCodef(404, "Host not found")
+	}
+
+	if !host.Enabled {
+		return nil, errors.NewWithCodef(400, "Host %s is disabled", host.ID)
+	}
+
+	if host.State != HealthyState {
+		return nil, errors.NewWithCodef(400, "Host %s is not healthy", host.ID)
+	}
+
+	httpClient := s.getApiClient(*host)
+	httpClient.WithTimeout(1 * time.Minute)
+	path := "/reverse-proxy/stats"
+	url, err := helpers.JoinUrl([]string{host.GetHost(), path})
+	if err != nil {
+		return nil, err
+	}
+
+	var response apimodels.ReverseProxyStats
+	_, err = httpClient.Get(url.String(), &response)
+	if err != nil {
+		return nil, err
+	}
+
+	response.HostId = host.ID
+	response.HostName = getHostName(*host)
+	return &response, nil
+}
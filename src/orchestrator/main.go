@@ -124,6 +124,9 @@ func (s *OrchestratorService) Start(waitForInit bool) {
 		s.ctx.LogInfof("[Orchestrator] Startup full refresh complete for %d hosts", len(startupHosts))
 	}
 
+	// Background: make sure hosts have the catalog items declared in the cache warmup policies.
+	go s.ApplyCacheWarmupPolicies()
+
 	// Background: periodic full refresh (self-healing) on a longer interval.
 	go s.runFullRefreshLoop()
 
@@ -167,7 +170,7 @@ func (s *OrchestratorService) Start(waitForInit bool) {
 
 // runFullRefreshLoop runs a full data refresh for all hosts every fullRefreshInterval.
 // This is the self-healing mechanism that re-syncs VMs, snapshots, hardware, and cache
-// in case any WebSocket events were missed.
+// in case any WebSocket events were missed, it also re-applies the cache warmup policies.
 func (s *OrchestratorService) runFullRefreshLoop() {
 	ticker := time.NewTicker(s.fullRefreshInterval)
 	defer ticker.Stop()
@@ -185,6 +188,7 @@ func (s *OrchestratorService) runFullRefreshLoop() {
 			for _, host := range dtoOrchestratorHosts {
 				go s.fullRefreshHost(host, true)
 			}
+			go s.ApplyCacheWarmupPolicies()
 		}
 	}
 }
@@ -1,5 +1,7 @@
 package diagnostics
 
+import "fmt"
+
 type PDFileDiagnostics struct {
 	errors   []error
 	warnings []error
@@ -39,3 +41,13 @@ func (pd *PDFileDiagnostics) Append(diagnostics *PDFileDiagnostics) {
 	pd.errors = append(pd.errors, diagnostics.errors...)
 	pd.warnings = append(pd.warnings, diagnostics.warnings...)
 }
+
+// AppendStage appends the diagnostics of a stage prefixing them with its name
+func (pd *PDFileDiagnostics) AppendStage(stage string, diagnostics *PDFileDiagnostics) {
+	for _, err := range diagnostics.errors {
+		pd.errors = append(pd.errors, fmt.Errorf("stage %v: %w", stage, err))
+	}
+	for _, warning := range diagnostics.warnings {
+		pd.warnings = append(pd.warnings, fmt.Errorf("stage %v: %w", stage, warning))
+	}
+}
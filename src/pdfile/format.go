@@ -0,0 +1,289 @@
+package pdfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/helpers"
+	"github.com/Parallels/prl-devops-service/pdfile/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Format writes the pd file in the pdfile, yaml or json format
+func Format(pdFile *models.PDFile, format string) (string, error) {
+	if pdFile == nil {
+		return "", fmt.Errorf("pd file is empty")
+	}
+
+	switch strings.ToLower(format) {
+	case FormatYaml, "yml":
+		var content bytes.Buffer
+		encoder := yaml.NewEncoder(&content)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(toStructured(pdFile)); err != nil {
+			return "", err
+		}
+		return content.String(), nil
+	case FormatJson:
+		content, err := json.MarshalIndent(toStructured(pdFile), "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(content) + "\n", nil
+	case FormatPDFile, "":
+		return formatLines(pdFile), nil
+	default:
+		return "", fmt.Errorf("unsupported pd file format %v, allowed values are pdfile, yaml and json", format)
+	}
+}
+
+// toStructured returns a copy of the pd file where the stages only contain
+// the values that differ from the global ones
+func toStructured(pdFile *models.PDFile) *models.PDFile {
+	result := pdFile.Copy()
+	result.Operation = ""
+	if len(pdFile.Stages) > 0 {
+		result.Command = ""
+	}
+	if result.Command != "" && result.Command != defaultCommand(result) {
+		result.Operation = result.Command
+	}
+	result.Command = ""
+
+	for _, stage := range pdFile.Stages {
+		stageFile := stage.PDFile.Copy()
+		if stageFile.Command != "" && stageFile.Command != defaultCommand(stageFile) {
+			stageFile.Operation = stageFile.Command
+		} else {
+			stageFile.Operation = ""
+		}
+		stageFile.Command = ""
+		stageFile.Args = nil
+		stageFile.Vars = nil
+		removeInherited(stageFile, result)
+		result.Stages = append(result.Stages, &models.PDFileStage{
+			Name:   stage.Name,
+			PDFile: *stageFile,
+		})
+	}
+
+	return result
+}
+
+// removeInherited clears the values of a stage that are the same as the
+// global values, using the json representation to compare them
+func removeInherited(stage *models.PDFile, global *models.PDFile) {
+	stageDocument := map[string]interface{}{}
+	globalDocument := map[string]interface{}{}
+	stageContent, _ := json.Marshal(stage)
+	globalContent, _ := json.Marshal(global)
+	_ = json.Unmarshal(stageContent, &stageDocument)
+	_ = json.Unmarshal(globalContent, &globalDocument)
+
+	for key, value := range stageDocument {
+		globalValue, ok := globalDocument[key]
+		if !ok {
+			continue
+		}
+		stageValue, _ := json.Marshal(value)
+		inheritedValue, _ := json.Marshal(globalValue)
+		if string(stageValue) == string(inheritedValue) {
+			delete(stageDocument, key)
+		}
+	}
+
+	cleared := models.NewPdFile()
+	content, _ := json.Marshal(stageDocument)
+	_ = json.Unmarshal(content, cleared)
+	cleared.Raw = nil
+	*stage = *cleared
+}
+
+func defaultCommand(pdFile *models.PDFile) string {
+	if pdFile.To != "" {
+		return "push"
+	}
+	if pdFile.From != "" {
+		return "pull"
+	}
+
+	return ""
+}
+
+func formatLines(pdFile *models.PDFile) string {
+	lines := make([]string, 0)
+	lines = append(lines, formatVariables("ARG", pdFile.Args)...)
+	lines = append(lines, formatVariables("VAR", pdFile.Vars)...)
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+
+	globalLines := formatDirectives(pdFile)
+	if len(pdFile.Stages) > 0 {
+		lines = append(lines, globalLines...)
+		for _, stage := range pdFile.Stages {
+			lines = append(lines, "", fmt.Sprintf("STAGE %v", stage.Name))
+			// list directives are appended to the global values when processed
+			stageFile := stage.PDFile.Copy()
+			stageFile.Roles = trimInherited(stageFile.Roles, pdFile.Roles)
+			stageFile.Claims = trimInherited(stageFile.Claims, pdFile.Claims)
+			stageFile.Tags = trimInherited(stageFile.Tags, pdFile.Tags)
+			stageFile.Execute = trimInherited(stageFile.Execute, pdFile.Execute)
+			for _, line := range formatDirectives(stageFile) {
+				if !containsLine(globalLines, line) {
+					lines = append(lines, line)
+				}
+			}
+			if stage.Command != "" {
+				lines = append(lines, fmt.Sprintf("RUN %v", stage.Command))
+			}
+			lines = append(lines, formatOnFail(stage.OnFail)...)
+		}
+	} else {
+		lines = append(lines, globalLines...)
+		if pdFile.Command != "" {
+			lines = append(lines, fmt.Sprintf("RUN %v", pdFile.Command))
+		}
+		lines = append(lines, formatOnFail(pdFile.OnFail)...)
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// formatDirectives returns the lines of the pd file without the RUN command,
+// the variables and the ONFAIL block
+func formatDirectives(pdFile *models.PDFile) []string {
+	lines := make([]string, 0)
+	add := func(directive string, value string) {
+		if value != "" {
+			lines = append(lines, fmt.Sprintf("%v %v", directive, value))
+		}
+	}
+	addBool := func(directive string, value bool) {
+		if value {
+			lines = append(lines, fmt.Sprintf("%v true", directive))
+		}
+	}
+
+	add("FROM", pdFile.From)
+	add("TO", pdFile.To)
+	addBool("INSECURE", pdFile.Insecure)
+	if pdFile.Authentication != nil {
+		add("AUTHENTICATE USERNAME", pdFile.Authentication.Username)
+		add("AUTHENTICATE PASSWORD", pdFile.Authentication.Password)
+		add("AUTHENTICATE API_KEY", pdFile.Authentication.ApiKey)
+	}
+	if pdFile.Provider != nil {
+		parts := []string{fmt.Sprintf("name=%v", pdFile.Provider.Name)}
+		keys := make([]string, 0, len(pdFile.Provider.Attributes))
+		for key := range pdFile.Provider.Attributes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			parts = append(parts, fmt.Sprintf("%v=%v", key, pdFile.Provider.Attributes[key]))
+		}
+		add("PROVIDER", strings.Join(parts, ";"))
+	}
+	add("DESCRIPTION", pdFile.Description)
+	add("CATALOG_ID", pdFile.CatalogId)
+	add("VERSION", pdFile.Version)
+	add("ARCHITECTURE", pdFile.Architecture)
+	add("LOCAL_PATH", pdFile.LocalPath)
+	add("DESTINATION", pdFile.Destination)
+	add("MACHINE_NAME", pdFile.MachineName)
+	add("OWNER", pdFile.Owner)
+	add("CLIENT", pdFile.Client)
+	addBool("START_AFTER_PULL", pdFile.StartAfterPull)
+	add("ROLE", strings.Join(pdFile.Roles, ","))
+	add("CLAIM", strings.Join(pdFile.Claims, ","))
+	add("TAG", strings.Join(pdFile.Tags, ","))
+	if pdFile.MinimumSpecRequirements != nil {
+		if pdFile.MinimumSpecRequirements.Cpu > 0 {
+			add("MINIMUM_REQUIREMENT CPU", fmt.Sprintf("%d", pdFile.MinimumSpecRequirements.Cpu))
+		}
+		if pdFile.MinimumSpecRequirements.Memory > 0 {
+			add("MINIMUM_REQUIREMENT MEMORY", fmt.Sprintf("%d", pdFile.MinimumSpecRequirements.Memory))
+		}
+		if pdFile.MinimumSpecRequirements.Disk > 0 {
+			add("MINIMUM_REQUIREMENT DISK", fmt.Sprintf("%d", pdFile.MinimumSpecRequirements.Disk))
+		}
+	}
+	if pdFile.Clone {
+		lines = append(lines, strings.TrimSpace(fmt.Sprintf("CLONE %v", pdFile.CloneTo)))
+	}
+	add("CLONE_DESTINATION", pdFile.CloneToDestination)
+	addBool("IS_COMPRESSED", pdFile.IsCompressed)
+	addBool("COMPRESS_PACK", pdFile.CompressPack)
+	if pdFile.CompressPackLevel != 0 {
+		if level, err := helpers.GetCompressRatioEnvValue(pdFile.CompressPackLevel); err == nil {
+			add("COMPRESS_PACK_LEVEL", level)
+		}
+	}
+	add("VM_TYPE", pdFile.VMType)
+	if pdFile.VMSize > 0 {
+		add("VM_SIZE", fmt.Sprintf("%d", pdFile.VMSize))
+	}
+	add("VM_REMOTE_PATH", pdFile.VMRemotePath)
+	addBool("FORCE", pdFile.Force)
+	add("SNAPSHOT_NAME", pdFile.SnapshotName)
+	for _, command := range pdFile.Execute {
+		add("EXECUTE", command)
+	}
+
+	return lines
+}
+
+func formatVariables(directive string, values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, fmt.Sprintf("%v %v=%v", directive, key, values[key]))
+	}
+
+	return lines
+}
+
+func formatOnFail(commands []string) []string {
+	if len(commands) == 0 {
+		return nil
+	}
+
+	lines := []string{"ONFAIL"}
+	for _, command := range commands {
+		lines = append(lines, fmt.Sprintf("  EXECUTE %v", command))
+	}
+
+	return append(lines, "END")
+}
+
+func trimInherited(values []string, inherited []string) []string {
+	if len(values) < len(inherited) {
+		return values
+	}
+	for i, value := range inherited {
+		if values[i] != value {
+			return values
+		}
+	}
+
+	return values[len(inherited):]
+}
+
+func containsLine(lines []string, line string) bool {
+	for _, item := range lines {
+		if item == line {
+			return true
+		}
+	}
+
+	return false
+}
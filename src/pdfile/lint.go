@@ -0,0 +1,159 @@
+package pdfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/catalog"
+	"github.com/Parallels/prl-devops-service/pdfile/diagnostics"
+	"github.com/Parallels/prl-devops-service/pdfile/models"
+	"github.com/Parallels/prl-devops-service/secrets"
+)
+
+// Lint validates the pd file and checks for the problems that would otherwise
+// only show up while it runs, like conflicting directives, missing credentials,
+// invalid provider attributes or templates that could not be resolved
+func (p *PDFileService) Lint() *diagnostics.PDFileDiagnostics {
+	diag := p.Validate()
+	if len(p.pdfile.Stages) == 0 {
+		diag.Append(p.lintFile(p.pdfile))
+		return diag
+	}
+
+	stages, err := p.getStagesToRun()
+	if err != nil {
+		return diag
+	}
+	for _, stage := range stages {
+		diag.AppendStage(stage.Name, p.lintFile(&stage.PDFile))
+	}
+
+	return diag
+}
+
+func (p *PDFileService) lintFile(pdFile *models.PDFile) *diagnostics.PDFileDiagnostics {
+	diag := diagnostics.NewPDFileDiagnostics()
+	command := strings.ToLower(pdFile.Command)
+
+	if pdFile.From != "" && pdFile.To != "" {
+		diag.AddError(fmt.Errorf("FROM %v and TO %v cannot be used together", pdFile.From, pdFile.To))
+	}
+
+	if isRemoteCommand(command) {
+		switch {
+		case !pdFile.HasAuthentication() && command == "list":
+			diag.AddWarning(fmt.Errorf("AUTHENTICATE not found, the catalog will be listed anonymously"))
+		case !pdFile.HasAuthentication():
+			diag.AddError(fmt.Errorf("AUTHENTICATE with a username and password or an api key is required to %v", command))
+		case pdFile.Authentication.ApiKey != "" && pdFile.Authentication.Username != "":
+			diag.AddWarning(fmt.Errorf("AUTHENTICATE has an api key and a username, the api key will be used"))
+		}
+		if pdFile.Insecure {
+			diag.AddWarning(fmt.Errorf("INSECURE connects to %v without tls", pdFile.GetHostUrl()))
+		}
+	}
+
+	if pdFile.Provider != nil {
+		// the secret references are only resolved when the pd file runs, here
+		// they just need to be present for the provider to accept the attributes
+		providerFile := pdFile.Copy()
+		for key, value := range providerFile.Provider.Attributes {
+			if secrets.IsReference(value) {
+				providerFile.Provider.Attributes[key] = "reference"
+			}
+		}
+		manifestService := catalog.NewManifestService(p.ctx)
+		if _, err := manifestService.GetProviderFromConnection(providerFile.GetProviderConnectionString()); err != nil {
+			if pdFile.Provider.Name == "" || strings.Contains(err.Error(), "not found") {
+				diag.AddError(fmt.Errorf("PROVIDER %q is not a known provider", pdFile.Provider.Name))
+			} else {
+				diag.AddError(fmt.Errorf("PROVIDER %v is invalid: %w", pdFile.Provider.Name, err))
+			}
+		}
+	}
+
+	diag.Append(lintSecretReferences(pdFile))
+
+	for _, template := range unresolvedTemplates(pdFile) {
+		name := strings.TrimSpace(templateRegex.FindStringSubmatch(template)[1])
+		if strings.HasPrefix(strings.ToLower(name), ".env.") {
+			diag.AddError(fmt.Errorf("environment variable %v is referenced but not set", name[len(".env."):]))
+			continue
+		}
+		diag.AddError(fmt.Errorf("%v could not be resolved, it is not an argument, variable or environment variable", template))
+	}
+
+	return diag
+}
+
+// lintSecretReferences checks that the env:// and file:// references point to
+// something that exists, secret:// references are only checked when the pd file runs
+func lintSecretReferences(pdFile *models.PDFile) *diagnostics.PDFileDiagnostics {
+	diag := diagnostics.NewPDFileDiagnostics()
+	values := secretValues(pdFile)
+	paths := make([]string, 0, len(values))
+	for path := range values {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if !secrets.IsReference(values[path]) {
+			continue
+		}
+		scheme, name, err := secrets.ParseReference(values[path])
+		if err != nil {
+			diag.AddError(fmt.Errorf("%v: %w", path, err))
+			continue
+		}
+		switch scheme {
+		case secrets.EnvScheme:
+			if _, ok := os.LookupEnv(name); !ok {
+				diag.AddError(fmt.Errorf("%v references the environment variable %v which is not set", path, name))
+			}
+		case secrets.FileScheme:
+			if _, err := os.Stat(name); err != nil {
+				diag.AddError(fmt.Errorf("%v references the file %v which cannot be read", path, name))
+			}
+		}
+	}
+
+	return diag
+}
+
+func isRemoteCommand(command string) bool {
+	switch command {
+	case "pull", "push", "list", "import", "import-vm":
+		return true
+	}
+
+	return false
+}
+
+// unresolvedTemplates returns the templates left in the values of the pd file
+func unresolvedTemplates(pdFile *models.PDFile) []string {
+	values := pdFile.Copy()
+	values.Raw = nil
+	content, err := json.Marshal(values)
+	if err != nil {
+		return nil
+	}
+
+	found := make(map[string]bool)
+	for _, line := range append(pdFile.Raw, string(content)) {
+		for _, template := range templateRegex.FindAllString(line, -1) {
+			found[template] = true
+		}
+	}
+
+	result := make([]string, 0, len(found))
+	for template := range found {
+		result = append(result, template)
+	}
+	sort.Strings(result)
+
+	return result
+}
@@ -0,0 +1,202 @@
+package pdfile
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	api_models "github.com/Parallels/prl-devops-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func errorMessages(errs []error) []string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+
+	return messages
+}
+
+func TestLint_ValidFile(t *testing.T) {
+	content := strings.Join([]string{
+		"FROM catalog.example.local",
+		"AUTHENTICATE API_KEY key",
+		"CATALOG_ID ubuntu",
+		"MACHINE_NAME runner",
+		"DESTINATION /Users/demo/Parallels",
+		"OWNER demo",
+	}, "\n")
+
+	result, diag := Process(newTestContext(), content)
+	require.False(t, diag.HasErrors(), diag.Errors())
+
+	lint := NewPDFileService(newTestContext(), result).Lint()
+	assert.False(t, lint.HasErrors(), lint.Errors())
+}
+
+func TestLint_Problems(t *testing.T) {
+	content := strings.Join([]string{
+		"FROM catalog.example.local",
+		"TO catalog.example.local",
+		"CATALOG_ID ubuntu",
+		"VERSION {{ .Env.PDFILE_TEST_UNSET_VERSION }}",
+		"DESCRIPTION {{ UNKNOWN }}",
+		"LOCAL_PATH /Users/demo/Parallels/runner.pvm",
+		"PROVIDER name=minio;bucket=demo",
+		"RUN push",
+	}, "\n")
+
+	result, diag := Process(newTestContext(), content)
+	require.False(t, diag.HasErrors(), diag.Errors())
+
+	lint := NewPDFileService(newTestContext(), result).Lint()
+	messages := errorMessages(lint.Errors())
+	assert.Contains(t, messages, "FROM catalog.example.local and TO catalog.example.local cannot be used together")
+	assert.Contains(t, messages, "AUTHENTICATE with a username and password or an api key is required to push")
+	assert.Contains(t, messages, "PROVIDER minio is invalid: missing bucket endpoint")
+	assert.Contains(t, messages, "environment variable PDFILE_TEST_UNSET_VERSION is referenced but not set")
+	assert.Contains(t, messages, "{{ UNKNOWN }} could not be resolved, it is not an argument, variable or environment variable")
+}
+
+func TestLint_UnknownProviderInStage(t *testing.T) {
+	content := strings.Join([]string{
+		"AUTHENTICATE API_KEY key",
+		"STAGE publish",
+		"TO catalog.example.local",
+		"CATALOG_ID ubuntu",
+		"LOCAL_PATH /Users/demo/Parallels/runner.pvm",
+		"PROVIDER name=dropbox;folder=demo",
+		"RUN push",
+	}, "\n")
+
+	result, diag := Process(newTestContext(), content)
+	require.False(t, diag.HasErrors(), diag.Errors())
+
+	lint := NewPDFileService(newTestContext(), result).Lint()
+	assert.Contains(t, errorMessages(lint.Errors()), "stage publish: PROVIDER \"dropbox\" is not a known provider")
+}
+
+func TestPlan_Pull(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/catalog/UBUNTU" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode([]api_models.CatalogManifest{
+			{CatalogId: "UBUNTU", Version: "1.2.0", Architecture: "arm64"},
+			{CatalogId: "UBUNTU", Version: "1.10.0", Architecture: "arm64", RequiredRoles: []string{"DEVOPS"}, MinimumSpecRequirements: &api_models.MinimumSpecRequirement{Cpu: 4, Memory: 8192}},
+			{CatalogId: "UBUNTU", Version: "2.0.0", Architecture: "x86_64"},
+		})
+	}))
+	defer server.Close()
+
+	originalArchitecture, originalHardwareInfo := getHostArchitecture, getHostHardwareInfo
+	defer func() {
+		getHostArchitecture, getHostHardwareInfo = originalArchitecture, originalHardwareInfo
+	}()
+	getHostArchitecture = func(ctx basecontext.ApiContext) (string, error) { return "arm64", nil }
+	getHostHardwareInfo = func(ctx basecontext.ApiContext) (*api_models.SystemHardwareInfo, error) {
+		return &api_models.SystemHardwareInfo{LogicalCpuCount: 2, MemorySize: 16384, FreeDiskSize: 100000}, nil
+	}
+
+	content := strings.Join([]string{
+		"FROM " + strings.TrimPrefix(server.URL, "http://"),
+		"INSECURE true",
+		"AUTHENTICATE API_KEY key",
+		"CATALOG_ID ubuntu",
+		"MACHINE_NAME runner",
+		"DESTINATION /Users/demo/Parallels",
+		"OWNER demo",
+	}, "\n")
+
+	result, diag := Process(newTestContext(), content)
+	require.False(t, diag.HasErrors(), diag.Errors())
+
+	steps, planDiag := NewPDFileService(newTestContext(), result).Plan(newTestContext())
+	require.Len(t, steps, 1)
+	require.NotEmpty(t, steps[0].Actions, planDiag.Errors())
+	assert.Equal(t, "pull", steps[0].Command)
+	assert.Contains(t, steps[0].Actions[0], "resolved UBUNTU version 1.10.0 for arm64")
+	assert.Contains(t, steps[0].String(), "the authenticated user has the required roles [DEVOPS]")
+	assert.Contains(t, steps[0].String(), "requires 8192 MB of memory, the host has 16384 MB")
+	assert.Equal(t, []string{"the machine requires 4 cpus but the host has 2"}, errorMessages(planDiag.Errors()))
+
+	result.Version = "3.0.0"
+	_, planDiag = NewPDFileService(newTestContext(), result).Plan(newTestContext())
+	assert.Equal(t, []string{"catalog ubuntu has no version 3.0.0 for arm64 or the user does not have the required roles or claims"}, errorMessages(planDiag.Errors()))
+
+	result.Authentication.ApiKey = "wrong"
+	_, planDiag = NewPDFileService(newTestContext(), result).Plan(newTestContext())
+	require.True(t, planDiag.HasErrors())
+	assert.Contains(t, planDiag.Errors()[0].Error(), "not authorized")
+}
+
+func TestPlan_Stages(t *testing.T) {
+	content := strings.Join([]string{
+		"MACHINE_NAME builder",
+		"STAGE provision",
+		"RUN execute",
+		"EXECUTE brew install git",
+		"ONFAIL",
+		"  EXECUTE echo cleanup",
+		"END",
+		"STAGE snapshot",
+		"RUN snapshot",
+		"SNAPSHOT_NAME provisioned",
+	}, "\n")
+
+	result, diag := Process(newTestContext(), content)
+	require.False(t, diag.HasErrors(), diag.Errors())
+
+	steps, planDiag := NewPDFileService(newTestContext(), result).Plan(newTestContext())
+	require.False(t, planDiag.HasErrors(), planDiag.Errors())
+	require.Len(t, steps, 2)
+	assert.Equal(t, "stage provision: execute\n  - execute \"brew install git\" on machine builder\n  - on failure execute \"echo cleanup\" on machine builder", steps[0].String())
+	assert.Equal(t, []string{"create the snapshot provisioned of machine builder"}, steps[1].Actions)
+}
+
+func TestSecretReferences(t *testing.T) {
+	t.Setenv("PDFILE_TEST_API_KEY", "key")
+	content := strings.Join([]string{
+		"FROM catalog.example.local",
+		"AUTHENTICATE API_KEY env://PDFILE_TEST_API_KEY",
+		"CATALOG_ID ubuntu",
+		"MACHINE_NAME runner",
+		"DESTINATION /Users/demo/Parallels",
+		"OWNER demo",
+		"PROVIDER name=minio;bucket=demo;endpoint=http://minio.local;access_key=env://PDFILE_TEST_UNSET_ACCESS_KEY;secret_key=secret://minio#secret_key",
+	}, "\n")
+
+	result, diag := Process(newTestContext(), content)
+	require.False(t, diag.HasErrors(), diag.Errors())
+
+	lint := NewPDFileService(newTestContext(), result).Lint()
+	assert.Equal(t, []string{"PROVIDER.ATTRIBUTES.access_key references the environment variable PDFILE_TEST_UNSET_ACCESS_KEY which is not set"}, errorMessages(lint.Errors()))
+
+	resolved, err := resolveSecrets(newTestContext(), result)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "PROVIDER.ATTRIBUTES.access_key")
+
+	result.Provider = nil
+	resolved, err = resolveSecrets(newTestContext(), result)
+	require.NoError(t, err)
+	assert.Equal(t, "key", resolved.Authentication.ApiKey)
+	assert.Equal(t, "env://PDFILE_TEST_API_KEY", result.Authentication.ApiKey)
+
+	_, diag = Process(newTestContext(), "AUTHENTICATE PASSWORD file://relative/password")
+	assert.True(t, diag.HasErrors())
+
+	_, diag = ProcessStructured(newTestContext(), `{"AUTHENTICATION": {"API_KEY": "env://"}}`, FormatJson, nil)
+	require.True(t, diag.HasErrors())
+	assert.Contains(t, diag.Errors()[0].Error(), "$.AUTHENTICATION.API_KEY: ")
+}
@@ -12,6 +12,13 @@ import (
 )
 
 func Load(ctx basecontext.ApiContext, pdFilepath string) (*models.PDFile, *diagnostics.PDFileDiagnostics) {
+	return LoadWithArgs(ctx, pdFilepath, nil)
+}
+
+// LoadWithArgs loads a pd file, files with a .yaml, .yml or .json extension
+// are read as structured pd files, overriding the default value of its ARG
+// declarations with the provided arguments
+func LoadWithArgs(ctx basecontext.ApiContext, pdFilepath string, args map[string]string) (*models.PDFile, *diagnostics.PDFileDiagnostics) {
 	lines := []string{}
 	diag := diagnostics.NewPDFileDiagnostics()
 
@@ -31,6 +38,11 @@ func Load(ctx basecontext.ApiContext, pdFilepath string) (*models.PDFile, *diagn
 		return nil, diag
 	}
 
-	result, diag := Process(ctx, strings.Join(lines, "\n"))
+	content := strings.Join(lines, "\n")
+	if format := GetFormat(pdFilepath); format != FormatPDFile {
+		return ProcessStructured(ctx, content, format, args)
+	}
+
+	result, diag := ProcessWithArgs(ctx, content, args)
 	return result, diag
 }
@@ -49,6 +49,7 @@ func NewPDFileService(ctx basecontext.ApiContext, pdFile *models.PDFile) *PDFile
 			&processors.VmTypeCommandProcessor{},
 			&processors.CompressPackLevelCommandProcessor{},
 			&processors.CloneDestinationCommandProcessor{},
+			&processors.SnapshotNameCommandProcessor{},
 		},
 
 		pdfile: pdFile,
@@ -56,35 +57,66 @@ func NewPDFileService(ctx basecontext.ApiContext, pdFile *models.PDFile) *PDFile
 }
 
 func (p *PDFileService) Run(ctx basecontext.ApiContext) (interface{}, *diagnostics.PDFileDiagnostics) {
+	if len(p.pdfile.Stages) > 0 {
+		return p.runStages(ctx)
+	}
+
+	out, diag := p.runCommand(ctx)
+	if diag.HasErrors() && len(p.pdfile.OnFail) > 0 {
+		diag.Append(p.runOnFail(ctx))
+	}
+
+	return out, diag
+}
+
+func (p *PDFileService) runCommand(ctx basecontext.ApiContext) (interface{}, *diagnostics.PDFileDiagnostics) {
 	diag := diagnostics.NewPDFileDiagnostics()
+	pdFile, err := resolveSecrets(ctx, p.pdfile)
+	if err != nil {
+		diag.AddError(err)
+		return nil, diag
+	}
+	resolved := &PDFileService{ctx: p.ctx, processors: p.processors, pdfile: pdFile}
 
 	if strings.EqualFold(p.pdfile.Command, "list") {
-		url := p.pdfile.GetHostCatalogUrl()
-		out, runDiag := p.runList(ctx, url)
+		url := resolved.pdfile.GetHostCatalogUrl()
+		out, runDiag := resolved.runList(ctx, url)
 		diag.Append(runDiag)
 		return out, diag
 	}
 
 	if strings.EqualFold(p.pdfile.Command, "push") {
-		out, runDiag := p.runPush(ctx)
+		out, runDiag := resolved.runPush(ctx)
 		diag.Append(runDiag)
 		return out, diag
 	}
 
 	if strings.EqualFold(p.pdfile.Command, "pull") {
-		out, runDiag := p.runPull(ctx)
+		out, runDiag := resolved.runPull(ctx)
 		diag.Append(runDiag)
 		return out, diag
 	}
 
 	if strings.EqualFold(p.pdfile.Command, "import") {
-		out, runDiag := p.runImport(ctx)
+		out, runDiag := resolved.runImport(ctx)
 		diag.Append(runDiag)
 		return out, diag
 	}
 
 	if strings.EqualFold(p.pdfile.Command, "import-vm") {
-		out, runDiag := p.runImportVM(ctx)
+		out, runDiag := resolved.runImportVM(ctx)
+		diag.Append(runDiag)
+		return out, diag
+	}
+
+	if strings.EqualFold(p.pdfile.Command, "execute") {
+		out, runDiag := resolved.runExecute(ctx)
+		diag.Append(runDiag)
+		return out, diag
+	}
+
+	if strings.EqualFold(p.pdfile.Command, "snapshot") {
+		out, runDiag := resolved.runSnapshot(ctx)
 		diag.Append(runDiag)
 		return out, diag
 	}
@@ -7,7 +7,7 @@ import (
 
 type PDFile struct {
 	Raw                     []string                      `json:"-" yaml:"-"`
-	Insecure                bool                          `json:"-" yaml:"-"`
+	Insecure                bool                          `json:"INSECURE,omitempty" yaml:"INSECURE,omitempty"`
 	Host                    string                        `json:"-" yaml:"-"`
 	From                    string                        `json:"FROM,omitempty" yaml:"FROM,omitempty"`
 	To                      string                        `json:"TO,omitempty" yaml:"TO,omitempty"`
@@ -42,6 +42,12 @@ type PDFile struct {
 	Force                   bool                          `json:"FORCE,omitempty" yaml:"FORCE,omitempty"`
 	Operation               string                        `json:"RUN,omitempty" yaml:"RUN,omitempty"`
 	Client                  string                        `json:"CLIENT,omitempty" yaml:"CLIENT,omitempty"`
+	SnapshotName            string                        `json:"SNAPSHOT_NAME,omitempty" yaml:"SNAPSHOT_NAME,omitempty"`
+	Args                    map[string]string             `json:"ARGS,omitempty" yaml:"ARGS,omitempty"`
+	Vars                    map[string]string             `json:"VARS,omitempty" yaml:"VARS,omitempty"`
+	OnFail                  []string                      `json:"ONFAIL,omitempty" yaml:"ONFAIL,omitempty"`
+	Stages                  []*PDFileStage                `json:"STAGES,omitempty" yaml:"STAGES,omitempty"`
+	TargetStage             string                        `json:"-" yaml:"-"`
 }
 
 func NewPdFile() *PDFile {
@@ -54,6 +60,60 @@ func NewPdFile() *PDFile {
 	}
 }
 
+// Copy returns a copy of the pd file that does not share any slices, maps or
+// pointers with the original, the stages are not copied.
+func (p *PDFile) Copy() *PDFile {
+	result := *p
+	result.Raw = append([]string{}, p.Raw...)
+	result.Roles = append([]string{}, p.Roles...)
+	result.Claims = append([]string{}, p.Claims...)
+	result.Tags = append([]string{}, p.Tags...)
+	result.Execute = append([]string{}, p.Execute...)
+	result.OnFail = append([]string{}, p.OnFail...)
+	result.Stages = nil
+	if p.Authentication != nil {
+		authentication := *p.Authentication
+		result.Authentication = &authentication
+	}
+	if p.Provider != nil {
+		provider := *p.Provider
+		provider.Attributes = make(map[string]string)
+		for key, value := range p.Provider.Attributes {
+			provider.Attributes[key] = value
+		}
+		result.Provider = &provider
+	}
+	if p.MinimumSpecRequirements != nil {
+		requirements := *p.MinimumSpecRequirements
+		result.MinimumSpecRequirements = &requirements
+	}
+	if p.Args != nil {
+		result.Args = make(map[string]string)
+		for key, value := range p.Args {
+			result.Args[key] = value
+		}
+	}
+	if p.Vars != nil {
+		result.Vars = make(map[string]string)
+		for key, value := range p.Vars {
+			result.Vars[key] = value
+		}
+	}
+
+	return &result
+}
+
+// GetStage returns the stage with the name, nil if it does not exist
+func (p *PDFile) GetStage(name string) *PDFileStage {
+	for _, stage := range p.Stages {
+		if strings.EqualFold(stage.Name, name) {
+			return stage
+		}
+	}
+
+	return nil
+}
+
 func (p *PDFile) HasAuthentication() bool {
 	if p.Authentication == nil {
 		return false
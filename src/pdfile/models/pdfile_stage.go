@@ -0,0 +1,9 @@
+package models
+
+// PDFileStage is a named step of a multi-stage pd file, it holds the global
+// directives of the file together with the ones declared in the stage.
+type PDFileStage struct {
+	Name   string `json:"NAME" yaml:"NAME"`
+	Line   int    `json:"-" yaml:"-"`
+	PDFile `yaml:",inline"`
+}
@@ -0,0 +1,359 @@
+package pdfile
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/helpers"
+	api_models "github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/pdfile/diagnostics"
+	"github.com/Parallels/prl-devops-service/pdfile/models"
+	"github.com/Parallels/prl-devops-service/serviceprovider/apiclient"
+	"github.com/Parallels/prl-devops-service/serviceprovider/system"
+)
+
+var (
+	getHostArchitecture = func(ctx basecontext.ApiContext) (string, error) {
+		return system.Get().GetArchitecture(ctx)
+	}
+	getHostHardwareInfo = func(ctx basecontext.ApiContext) (*api_models.SystemHardwareInfo, error) {
+		return system.Get().GetHardwareInfo(ctx)
+	}
+)
+
+// PlanStep describes what running a pd file or one of its stages would do
+type PlanStep struct {
+	Stage   string
+	Command string
+	Actions []string
+}
+
+// String returns the step as a title followed by one line per action
+func (s PlanStep) String() string {
+	title := s.Command
+	if s.Stage != "" {
+		title = fmt.Sprintf("stage %v: %v", s.Stage, s.Command)
+	}
+
+	lines := []string{title}
+	for _, action := range s.Actions {
+		lines = append(lines, fmt.Sprintf("  - %v", action))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Plan resolves what the pd file would do without running it, it connects to
+// the catalog to resolve the manifests and checks the local hardware but no
+// machine is transferred or changed
+func (p *PDFileService) Plan(ctx basecontext.ApiContext) ([]PlanStep, *diagnostics.PDFileDiagnostics) {
+	diag := diagnostics.NewPDFileDiagnostics()
+	if len(p.pdfile.Stages) == 0 {
+		step, stepDiag := p.planFile(ctx, p.pdfile, false)
+		diag.Append(stepDiag)
+		return []PlanStep{step}, diag
+	}
+
+	stages, err := p.getStagesToRun()
+	if err != nil {
+		diag.AddError(err)
+		return nil, diag
+	}
+
+	steps := make([]PlanStep, 0, len(stages))
+	for _, stage := range stages {
+		step, stepDiag := p.planFile(ctx, &stage.PDFile, true)
+		step.Stage = stage.Name
+		diag.AppendStage(stage.Name, stepDiag)
+		steps = append(steps, step)
+	}
+
+	return steps, diag
+}
+
+func (p *PDFileService) planFile(ctx basecontext.ApiContext, pdFile *models.PDFile, isStage bool) (PlanStep, *diagnostics.PDFileDiagnostics) {
+	diag := diagnostics.NewPDFileDiagnostics()
+	step := PlanStep{Command: strings.ToLower(pdFile.Command)}
+	if isRemoteCommand(step.Command) {
+		resolved, err := resolveSecrets(ctx, pdFile)
+		if err != nil {
+			diag.AddError(err)
+			return step, diag
+		}
+		pdFile = resolved
+	}
+
+	switch step.Command {
+	case "pull":
+		p.planPull(ctx, pdFile, &step, diag)
+	case "push":
+		p.planPush(ctx, pdFile, &step, diag, isStage)
+	case "list":
+		step.Actions = append(step.Actions, fmt.Sprintf("list the manifests of %v", pdFile.GetHostCatalogUrl()))
+	case "import", "import-vm":
+		step.Actions = append(step.Actions, fmt.Sprintf("%v %v version %v (%v) into %v", step.Command, pdFile.CatalogId, pdFile.Version, pdFile.Architecture, pdFile.GetHostUrl()))
+		if pdFile.VMRemotePath != "" {
+			step.Actions = append(step.Actions, fmt.Sprintf("use the remote machine %v", pdFile.VMRemotePath))
+		}
+	case "execute":
+		for _, command := range pdFile.Execute {
+			step.Actions = append(step.Actions, fmt.Sprintf("execute %q on machine %v", command, pdFile.MachineName))
+		}
+	case "snapshot":
+		step.Actions = append(step.Actions, fmt.Sprintf("create the snapshot %v of machine %v", pdFile.SnapshotName, pdFile.MachineName))
+	default:
+		diag.AddError(fmt.Errorf("unknown command %v", pdFile.Command))
+	}
+
+	for _, command := range pdFile.OnFail {
+		step.Actions = append(step.Actions, fmt.Sprintf("on failure execute %q on machine %v", command, pdFile.MachineName))
+	}
+
+	return step, diag
+}
+
+func (p *PDFileService) planPull(ctx basecontext.ApiContext, pdFile *models.PDFile, step *PlanStep, diag *diagnostics.PDFileDiagnostics) {
+	architecture, err := getHostArchitecture(ctx)
+	if err != nil {
+		diag.AddError(fmt.Errorf("unable to determine the host architecture: %w", err))
+		return
+	}
+	if pdFile.Architecture != "" && !strings.EqualFold(pdFile.Architecture, architecture) {
+		diag.AddWarning(fmt.Errorf("ARCHITECTURE %v is ignored, the pull uses the host architecture %v", pdFile.Architecture, architecture))
+	}
+
+	manifest, err := p.resolveManifest(ctx, pdFile, architecture)
+	if err != nil {
+		diag.AddError(err)
+		return
+	}
+
+	step.Actions = append(step.Actions, fmt.Sprintf("resolved %v version %v for %v from %v", manifest.CatalogId, manifest.Version, manifest.Architecture, pdFile.GetHostUrl()))
+	if manifest.Tainted {
+		diag.AddError(fmt.Errorf("version %v of %v is tainted and cannot be pulled", manifest.Version, manifest.CatalogId))
+	}
+	if manifest.Revoked {
+		diag.AddError(fmt.Errorf("version %v of %v is revoked and cannot be pulled", manifest.Version, manifest.CatalogId))
+	}
+	if len(manifest.RequiredRoles) > 0 || len(manifest.RequiredClaims) > 0 {
+		step.Actions = append(step.Actions, fmt.Sprintf("the authenticated user has the required roles [%v] and claims [%v]", strings.Join(manifest.RequiredRoles, ", "), strings.Join(manifest.RequiredClaims, ", ")))
+	}
+
+	requirements := pdFile.MinimumSpecRequirements
+	if manifest.MinimumSpecRequirements != nil {
+		requirements = &models.PdFileMinimumSpecRequirement{
+			Cpu:    manifest.MinimumSpecRequirements.Cpu,
+			Memory: manifest.MinimumSpecRequirements.Memory,
+			Disk:   manifest.MinimumSpecRequirements.Disk,
+		}
+	}
+	if requirements != nil {
+		checkMinimumRequirements(ctx, requirements, step, diag)
+	}
+
+	owner := pdFile.Owner
+	if owner == "" {
+		owner = "the current user"
+	}
+	step.Actions = append(step.Actions, fmt.Sprintf("download the machine into %v as %v owned by %v", pdFile.Destination, pdFile.MachineName, owner))
+	if pdFile.Clone {
+		step.Actions = append(step.Actions, fmt.Sprintf("clone the machine as %v", pdFile.CloneTo))
+	}
+	if pdFile.StartAfterPull && !pdFile.Clone {
+		step.Actions = append(step.Actions, "start the machine")
+	}
+	for _, command := range pdFile.Execute {
+		step.Actions = append(step.Actions, fmt.Sprintf("execute %q on machine %v", command, pdFile.MachineName))
+	}
+}
+
+func (p *PDFileService) planPush(ctx basecontext.ApiContext, pdFile *models.PDFile, step *PlanStep, diag *diagnostics.PDFileDiagnostics, isStage bool) {
+	if _, err := os.Stat(pdFile.LocalPath); err != nil {
+		// an earlier stage can create the machine that is pushed
+		if isStage {
+			diag.AddWarning(fmt.Errorf("local path %v does not exist yet", pdFile.LocalPath))
+		} else {
+			diag.AddError(fmt.Errorf("local path %v does not exist", pdFile.LocalPath))
+		}
+	}
+
+	architecture := pdFile.Architecture
+	if architecture == "" {
+		hostArchitecture, err := getHostArchitecture(ctx)
+		if err != nil {
+			diag.AddError(fmt.Errorf("unable to determine the host architecture: %w", err))
+			return
+		}
+		architecture = hostArchitecture
+	}
+	version := pdFile.Version
+	if version == "" {
+		version = constants.LATEST_TAG
+	}
+
+	client := newPlanClient(ctx, pdFile)
+	var existing api_models.CatalogManifest
+	url := fmt.Sprintf("%s/%s/%s/%s", pdFile.GetHostUrl(), helpers.NormalizeStringUpper(pdFile.CatalogId), helpers.NormalizeString(version), architecture)
+	response, err := client.Get(url, &existing)
+	switch {
+	case err == nil:
+		step.Actions = append(step.Actions, fmt.Sprintf("replace the existing version %v of %v for %v", version, pdFile.CatalogId, architecture))
+	case response != nil && (response.StatusCode == 401 || response.StatusCode == 403):
+		diag.AddError(fmt.Errorf("the credentials are not authorized to access %v", pdFile.GetHostUrl()))
+		return
+	case response != nil && response.StatusCode == 404:
+		step.Actions = append(step.Actions, fmt.Sprintf("create version %v of %v for %v", version, pdFile.CatalogId, architecture))
+	default:
+		diag.AddError(fmt.Errorf("unable to connect to %v: %w", pdFile.GetHostUrl(), err))
+		return
+	}
+
+	provider := "unknown"
+	if pdFile.Provider != nil {
+		provider = pdFile.Provider.Name
+	}
+	step.Actions = append(step.Actions, fmt.Sprintf("upload %v to the %v provider", pdFile.LocalPath, provider))
+	if pdFile.CompressPack {
+		step.Actions = append(step.Actions, "compress the machine before uploading it")
+	}
+
+	p.checkServerNames(client, pdFile, "roles", pdFile.Roles, step, diag)
+	p.checkServerNames(client, pdFile, "claims", pdFile.Claims, step, diag)
+	if len(pdFile.Tags) > 0 {
+		step.Actions = append(step.Actions, fmt.Sprintf("tag the manifest with [%v]", strings.Join(pdFile.Tags, ", ")))
+	}
+	if pdFile.MinimumSpecRequirements != nil {
+		step.Actions = append(step.Actions, fmt.Sprintf("require cpu %v, memory %v MB and disk %v MB", pdFile.MinimumSpecRequirements.Cpu, pdFile.MinimumSpecRequirements.Memory, pdFile.MinimumSpecRequirements.Disk))
+	}
+}
+
+// resolveManifest returns the manifest that a pull would use, an empty or
+// latest version resolves to the manifest tagged as latest or the highest one
+func (p *PDFileService) resolveManifest(ctx basecontext.ApiContext, pdFile *models.PDFile, architecture string) (*api_models.CatalogManifest, error) {
+	client := newPlanClient(ctx, pdFile)
+	catalogId := helpers.NormalizeStringUpper(pdFile.CatalogId)
+	var manifests []api_models.CatalogManifest
+	response, err := client.Get(fmt.Sprintf("%s/%s", pdFile.GetHostUrl(), catalogId), &manifests)
+	if err != nil {
+		if response != nil && (response.StatusCode == 401 || response.StatusCode == 403) {
+			return nil, fmt.Errorf("the credentials are not authorized to access %v", pdFile.GetHostUrl())
+		}
+		if response != nil && response.StatusCode == 404 {
+			return nil, fmt.Errorf("catalog %v was not found or the user does not have the required roles or claims", pdFile.CatalogId)
+		}
+		return nil, fmt.Errorf("unable to connect to %v: %w", pdFile.GetHostUrl(), err)
+	}
+
+	version := pdFile.Version
+	var result *api_models.CatalogManifest
+	for i, manifest := range manifests {
+		if !strings.EqualFold(manifest.Architecture, architecture) {
+			continue
+		}
+		if version != "" && !strings.EqualFold(version, constants.LATEST_TAG) {
+			if strings.EqualFold(manifest.Version, helpers.NormalizeString(version)) {
+				return &manifests[i], nil
+			}
+			continue
+		}
+		if strings.EqualFold(manifest.Version, constants.LATEST_TAG) || hasTag(manifest.Tags, constants.LATEST_TAG) {
+			return &manifests[i], nil
+		}
+		if result == nil || helpers.NewVersion(result.Version).LessThan(helpers.NewVersion(manifest.Version)) {
+			result = &manifests[i]
+		}
+	}
+
+	if result == nil {
+		if version == "" {
+			version = constants.LATEST_TAG
+		}
+		return nil, fmt.Errorf("catalog %v has no version %v for %v or the user does not have the required roles or claims", pdFile.CatalogId, version, architecture)
+	}
+
+	return result, nil
+}
+
+// checkServerNames checks that the roles or claims set by the pd file exist
+func (p *PDFileService) checkServerNames(client *apiclient.HttpClientService, pdFile *models.PDFile, kind string, names []string, step *PlanStep, diag *diagnostics.PDFileDiagnostics) {
+	if len(names) == 0 {
+		return
+	}
+
+	var items []struct {
+		Name string `json:"name"`
+	}
+	url := fmt.Sprintf("%s/auth/%s", strings.TrimSuffix(pdFile.GetHostUrl(), "/catalog"), kind)
+	if _, err := client.Get(url, &items); err != nil {
+		diag.AddWarning(fmt.Errorf("unable to check the %v: %w", kind, err))
+		step.Actions = append(step.Actions, fmt.Sprintf("require the %v [%v]", kind, strings.Join(names, ", ")))
+		return
+	}
+
+	for _, name := range names {
+		found := false
+		for _, item := range items {
+			if strings.EqualFold(item.Name, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			diag.AddError(fmt.Errorf("%v %v does not exist in %v", strings.TrimSuffix(kind, "s"), name, pdFile.GetHostUrl()))
+		}
+	}
+	step.Actions = append(step.Actions, fmt.Sprintf("require the %v [%v]", kind, strings.Join(names, ", ")))
+}
+
+func checkMinimumRequirements(ctx basecontext.ApiContext, requirements *models.PdFileMinimumSpecRequirement, step *PlanStep, diag *diagnostics.PDFileDiagnostics) {
+	hardware, err := getHostHardwareInfo(ctx)
+	if err != nil || hardware == nil {
+		diag.AddWarning(fmt.Errorf("unable to check the minimum requirements against the host hardware: %v", err))
+		return
+	}
+
+	if requirements.Cpu > 0 {
+		if hardware.LogicalCpuCount < requirements.Cpu {
+			diag.AddError(fmt.Errorf("the machine requires %v cpus but the host has %v", requirements.Cpu, hardware.LogicalCpuCount))
+		}
+		step.Actions = append(step.Actions, fmt.Sprintf("requires %v cpus, the host has %v", requirements.Cpu, hardware.LogicalCpuCount))
+	}
+	if requirements.Memory > 0 {
+		if hardware.MemorySize < float64(requirements.Memory) {
+			diag.AddError(fmt.Errorf("the machine requires %v MB of memory but the host has %.0f MB", requirements.Memory, hardware.MemorySize))
+		}
+		step.Actions = append(step.Actions, fmt.Sprintf("requires %v MB of memory, the host has %.0f MB", requirements.Memory, hardware.MemorySize))
+	}
+	if requirements.Disk > 0 {
+		if hardware.FreeDiskSize < float64(requirements.Disk) {
+			diag.AddError(fmt.Errorf("the machine requires %v MB of disk but the host has %.0f MB free", requirements.Disk, hardware.FreeDiskSize))
+		}
+		step.Actions = append(step.Actions, fmt.Sprintf("requires %v MB of disk, the host has %.0f MB free", requirements.Disk, hardware.FreeDiskSize))
+	}
+}
+
+func newPlanClient(ctx basecontext.ApiContext, pdFile *models.PDFile) *apiclient.HttpClientService {
+	client := apiclient.NewHttpClient(ctx)
+	if pdFile.Authentication != nil {
+		client.SetAuthorization(apiclient.HttpClientServiceAuthorization{
+			Username: pdFile.Authentication.Username,
+			Password: pdFile.Authentication.Password,
+			ApiKey:   pdFile.Authentication.ApiKey,
+		})
+	}
+
+	return client
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, item := range tags {
+		if strings.EqualFold(item, tag) {
+			return true
+		}
+	}
+
+	return false
+}
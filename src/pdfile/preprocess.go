@@ -0,0 +1,284 @@
+package pdfile
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/pdfile/diagnostics"
+)
+
+var templateRegex = regexp.MustCompile(`{{\s*([^}]+?)\s*}}`)
+
+type pdfileLine struct {
+	number int
+	text   string
+}
+
+type pdfileSection struct {
+	name   string
+	line   int
+	lines  []pdfileLine
+	onFail []string
+}
+
+type pdfileLayout struct {
+	global pdfileSection
+	stages []*pdfileSection
+	args   map[string]string
+	vars   map[string]string
+}
+
+type pdfileBlockType int
+
+const (
+	pdfileBlockIf pdfileBlockType = iota
+	pdfileBlockOnFail
+)
+
+type pdfileBlock struct {
+	blockType pdfileBlockType
+	line      int
+	active    bool
+	matched   bool
+	inElse    bool
+}
+
+// preprocess resolves the ARG and VAR declarations, the templates, the IF and
+// ONFAIL blocks and splits the file into its global section and stages.
+func preprocess(lines []string, args map[string]string) (*pdfileLayout, *diagnostics.PDFileDiagnostics) {
+	diag := diagnostics.NewPDFileDiagnostics()
+	layout := &pdfileLayout{
+		args: make(map[string]string),
+		vars: make(map[string]string),
+	}
+	current := &layout.global
+	blocks := make([]*pdfileBlock, 0)
+
+	isActive := func() bool {
+		for _, block := range blocks {
+			if !block.active {
+				return false
+			}
+		}
+		return true
+	}
+	inOnFail := func() bool {
+		for _, block := range blocks {
+			if block.blockType == pdfileBlockOnFail {
+				return true
+			}
+		}
+		return false
+	}
+
+	for i, rawLine := range lines {
+		lineNumber := i + 1
+		line := strings.TrimSpace(rawLine)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		keyword, argument := splitDirective(line)
+		switch keyword {
+		case "IF":
+			block := &pdfileBlock{blockType: pdfileBlockIf, line: lineNumber}
+			if isActive() {
+				result, err := evaluateCondition(layout.expand(argument))
+				if err != nil {
+					diag.AddError(fmt.Errorf("%v at line %d", err, lineNumber))
+				}
+				block.active = result
+				block.matched = result
+			}
+			blocks = append(blocks, block)
+			continue
+		case "ELSE":
+			if len(blocks) == 0 || blocks[len(blocks)-1].blockType != pdfileBlockIf || blocks[len(blocks)-1].inElse {
+				diag.AddError(fmt.Errorf("ELSE without IF at line %d", lineNumber))
+				continue
+			}
+			block := blocks[len(blocks)-1]
+			block.inElse = true
+			parentActive := true
+			for _, parent := range blocks[:len(blocks)-1] {
+				parentActive = parentActive && parent.active
+			}
+			block.active = parentActive && !block.matched
+			continue
+		case "END", "ENDIF":
+			if len(blocks) == 0 {
+				diag.AddError(fmt.Errorf("%v without IF or ONFAIL at line %d", keyword, lineNumber))
+				continue
+			}
+			blocks = blocks[:len(blocks)-1]
+			continue
+		}
+
+		if !isActive() {
+			continue
+		}
+
+		switch keyword {
+		case "ONFAIL":
+			if inOnFail() {
+				diag.AddError(fmt.Errorf("ONFAIL blocks cannot be nested, line %d", lineNumber))
+			}
+			blocks = append(blocks, &pdfileBlock{blockType: pdfileBlockOnFail, line: lineNumber, active: true})
+			continue
+		case "STAGE":
+			if len(blocks) > 0 {
+				diag.AddError(fmt.Errorf("STAGE cannot be declared inside a block, line %d", lineNumber))
+				continue
+			}
+			name := layout.expand(argument)
+			if name == "" || strings.Contains(name, " ") {
+				diag.AddError(fmt.Errorf("invalid stage name %q at line %d", name, lineNumber))
+				continue
+			}
+			for _, stage := range layout.stages {
+				if strings.EqualFold(stage.name, name) {
+					diag.AddError(fmt.Errorf("stage %v is declared twice, line %d", name, lineNumber))
+				}
+			}
+			current = &pdfileSection{name: name, line: lineNumber}
+			layout.stages = append(layout.stages, current)
+			continue
+		case "ARG", "VAR":
+			name, value, hasValue := parseVariableDeclaration(argument)
+			if name == "" {
+				diag.AddError(fmt.Errorf("%v is missing the name at line %d", keyword, lineNumber))
+				continue
+			}
+			value = layout.expand(value)
+			if keyword == "ARG" {
+				if override, ok := args[name]; ok {
+					value = override
+					hasValue = true
+				}
+				if !hasValue {
+					diag.AddError(fmt.Errorf("argument %v has no default value and was not provided, line %d", name, lineNumber))
+				}
+				layout.args[name] = value
+			} else {
+				layout.vars[name] = value
+			}
+			continue
+		}
+
+		line = layout.expand(line)
+		if inOnFail() {
+			onFailKeyword, onFailArgument := splitDirective(line)
+			if onFailKeyword != "EXECUTE" || onFailArgument == "" {
+				diag.AddError(fmt.Errorf("only EXECUTE commands are allowed in ONFAIL blocks, line %d", lineNumber))
+				continue
+			}
+			current.onFail = append(current.onFail, onFailArgument)
+			continue
+		}
+		current.lines = append(current.lines, pdfileLine{number: lineNumber, text: line})
+	}
+
+	for _, block := range blocks {
+		if block.blockType == pdfileBlockOnFail {
+			diag.AddError(fmt.Errorf("ONFAIL block at line %d is missing END", block.line))
+		} else {
+			diag.AddError(fmt.Errorf("IF block at line %d is missing END", block.line))
+		}
+	}
+
+	return layout, diag
+}
+
+// expand replaces the {{ .Args.NAME }}, {{ .Vars.NAME }} and {{ .Env.NAME }}
+// templates, a template without a prefix is looked up in the variables, the
+// arguments and then the environment. Unknown templates are kept as they are.
+func (l *pdfileLayout) expand(value string) string {
+	return templateRegex.ReplaceAllStringFunc(value, func(template string) string {
+		name := strings.TrimSpace(templateRegex.FindStringSubmatch(template)[1])
+		lowerName := strings.ToLower(name)
+		switch {
+		case strings.HasPrefix(lowerName, ".args."):
+			if v, ok := l.args[name[len(".args."):]]; ok {
+				return v
+			}
+		case strings.HasPrefix(lowerName, ".vars."):
+			if v, ok := l.vars[name[len(".vars."):]]; ok {
+				return v
+			}
+		case strings.HasPrefix(lowerName, ".env."):
+			if v, ok := os.LookupEnv(name[len(".env."):]); ok {
+				return v
+			}
+		default:
+			name = strings.TrimPrefix(name, ".")
+			if v, ok := l.vars[name]; ok {
+				return v
+			}
+			if v, ok := l.args[name]; ok {
+				return v
+			}
+			if v, ok := os.LookupEnv(name); ok {
+				return v
+			}
+		}
+
+		return template
+	})
+}
+
+func splitDirective(line string) (string, string) {
+	parts := strings.SplitN(strings.TrimSpace(line), " ", 2)
+	keyword := strings.ToUpper(parts[0])
+	if len(parts) == 1 {
+		return keyword, ""
+	}
+
+	return keyword, strings.TrimSpace(parts[1])
+}
+
+// parseVariableDeclaration parses NAME=value or NAME value declarations
+func parseVariableDeclaration(argument string) (string, string, bool) {
+	separator := strings.IndexAny(argument, "= ")
+	if separator == -1 {
+		return strings.TrimSpace(argument), "", false
+	}
+
+	name := strings.TrimSpace(argument[:separator])
+	value := strings.TrimSpace(argument[separator+1:])
+	return name, strings.Trim(value, "\""), true
+}
+
+// evaluateCondition evaluates `left == right`, `left != right` or a single
+// value that is true when it is not empty, false, 0 or no
+func evaluateCondition(condition string) (bool, error) {
+	condition = strings.TrimSpace(condition)
+	if condition == "" {
+		return false, fmt.Errorf("IF is missing the condition")
+	}
+
+	for _, operator := range []string{"!=", "=="} {
+		if index := strings.Index(condition, operator); index != -1 {
+			left := strings.Trim(strings.TrimSpace(condition[:index]), "\"")
+			right := strings.Trim(strings.TrimSpace(condition[index+len(operator):]), "\"")
+			if operator == "==" {
+				return left == right, nil
+			}
+			return left != right, nil
+		}
+	}
+
+	negate := strings.HasPrefix(condition, "!")
+	value := strings.Trim(strings.TrimSpace(strings.TrimPrefix(condition, "!")), "\"")
+	result := value != "" && !strings.EqualFold(value, "false") && value != "0" && !strings.EqualFold(value, "no")
+	// an unresolved template is not set
+	if templateRegex.MatchString(value) {
+		result = false
+	}
+	if negate {
+		return !result, nil
+	}
+
+	return result, nil
+}
@@ -2,8 +2,6 @@ package pdfile
 
 import (
 	"fmt"
-	"os"
-	"regexp"
 	"strings"
 
 	"github.com/Parallels/prl-devops-service/basecontext"
@@ -16,46 +14,59 @@ const (
 )
 
 func Process(ctx basecontext.ApiContext, fileContent string) (*models.PDFile, *diagnostics.PDFileDiagnostics) {
+	return ProcessWithArgs(ctx, fileContent, nil)
+}
+
+// ProcessWithArgs processes a pd file overriding the default value of its ARG
+// declarations with the provided arguments
+func ProcessWithArgs(ctx basecontext.ApiContext, fileContent string, args map[string]string) (*models.PDFile, *diagnostics.PDFileDiagnostics) {
 	result := models.NewPdFile()
-	result.Raw = strings.Split(fileContent, "\n")
-	diag := diagnostics.NewPDFileDiagnostics()
 	svc := NewPDFileService(ctx, result)
-	var envVars []string
-	for _, line := range result.Raw {
-		start := 0
-		for {
-			line = line[start:]
-			start = strings.Index(line, "{{")
-			if start == -1 {
-				break
-			}
-			end := strings.Index(line[start:], "}}")
-			if end == -1 {
-				break
-			}
-			envVar := line[start : start+end+2]
-			envVars = append(envVars, envVar)
-			start += end + 2
-		}
+
+	layout, diag := preprocess(strings.Split(fileContent, "\n"), args)
+	if len(layout.args) > 0 {
+		result.Args = layout.args
 	}
-	for _, envVar := range envVars {
-		envVarName := strings.ReplaceAll(envVar, "{{", "")
-		envVarName = strings.ReplaceAll(envVarName, "{{", "")
-		envVarName = strings.ReplaceAll(envVarName, "}}", "")
-		re := regexp.MustCompile(`(?i)\.env\.`)
-		envVarName = re.ReplaceAllString(envVarName, "")
+	if len(layout.vars) > 0 {
+		result.Vars = layout.vars
+	}
+	result.OnFail = layout.global.onFail
+	diag.Append(svc.processLines(ctx, result, layout.global.lines))
 
-		envVarName = strings.TrimSpace(envVarName)
-		if _, exists := os.LookupEnv(envVarName); exists {
-			fileContent = strings.ReplaceAll(fileContent, envVar, os.Getenv(envVarName))
-		}
+	if len(layout.stages) == 0 {
+		setDefaultCommand(result)
+		return result, diag
+	}
+
+	if len(result.OnFail) > 0 {
+		diag.AddError(fmt.Errorf("ONFAIL blocks need to be declared inside a stage in multi-stage files"))
+	}
+
+	for _, section := range layout.stages {
+		stageFile := result.Copy()
+		stageFile.Command = ""
+		stageFile.OnFail = section.onFail
+		stageDiag := svc.processLines(ctx, stageFile, section.lines)
+		setDefaultCommand(stageFile)
+		diag.AppendStage(section.name, stageDiag)
+
+		result.Stages = append(result.Stages, &models.PDFileStage{
+			Name:   section.name,
+			Line:   section.line,
+			PDFile: *stageFile,
+		})
 	}
-	result.Raw = strings.Split(fileContent, "\n")
 
-	for i, line := range result.Raw {
+	return result, diag
+}
+
+func (p *PDFileService) processLines(ctx basecontext.ApiContext, dest *models.PDFile, lines []pdfileLine) *diagnostics.PDFileDiagnostics {
+	diag := diagnostics.NewPDFileDiagnostics()
+	for _, line := range lines {
+		dest.Raw = append(dest.Raw, line.text)
 		executed := false
-		for _, processor := range svc.processors {
-			processExecuted, processDiag := processor.Process(ctx, line, result)
+		for _, processor := range p.processors {
+			processExecuted, processDiag := processor.Process(ctx, line.text, dest)
 			if processDiag.HasErrors() {
 				diag.Append(processDiag)
 			}
@@ -66,18 +77,20 @@ func Process(ctx basecontext.ApiContext, fileContent string) (*models.PDFile, *d
 		}
 
 		if !executed {
-			diag.AddError(fmt.Errorf("invalid command %v at line %d", line, i))
+			diag.AddError(fmt.Errorf("invalid command %v at line %d", line.text, line.number))
 		}
 	}
 
-	if result.Command == "" {
-		if result.From != "" {
-			result.Command = "pull"
+	return diag
+}
+
+func setDefaultCommand(pdFile *models.PDFile) {
+	if pdFile.Command == "" {
+		if pdFile.From != "" {
+			pdFile.Command = "pull"
 		}
-		if result.To != "" {
-			result.Command = "push"
+		if pdFile.To != "" {
+			pdFile.Command = "push"
 		}
 	}
-
-	return result, diag
 }
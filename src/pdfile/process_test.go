@@ -0,0 +1,191 @@
+package pdfile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestContext() basecontext.ApiContext {
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+	return ctx
+}
+
+func TestProcess_SingleStageIsUnchanged(t *testing.T) {
+	t.Setenv("PDFILE_TEST_HOST", "catalog.example.local")
+	content := strings.Join([]string{
+		"FROM {{ .Env.PDFILE_TEST_HOST }}",
+		"CATALOG_ID ubuntu",
+		"MACHINE_NAME runner",
+		"EXECUTE echo hello",
+	}, "\n")
+
+	result, diag := Process(newTestContext(), content)
+	require.False(t, diag.HasErrors(), diag.Errors())
+
+	assert.Equal(t, "catalog.example.local", result.From)
+	assert.Equal(t, "pull", result.Command)
+	assert.Equal(t, []string{"echo hello"}, result.Execute)
+	assert.Empty(t, result.Stages)
+}
+
+func TestProcess_ArgsAndVars(t *testing.T) {
+	content := strings.Join([]string{
+		"ARG VERSION=1.0.0",
+		"ARG ARCH=arm64",
+		"VAR NAME=ubuntu-{{ .Args.VERSION }}",
+		"FROM catalog.example.local",
+		"VERSION {{ VERSION }}",
+		"ARCHITECTURE {{ .Args.ARCH }}",
+		"MACHINE_NAME {{ .Vars.NAME }}",
+	}, "\n")
+
+	result, diag := ProcessWithArgs(newTestContext(), content, map[string]string{"VERSION": "2.0.0"})
+	require.False(t, diag.HasErrors(), diag.Errors())
+
+	assert.Equal(t, "2.0.0", result.Version)
+	assert.Equal(t, "arm64", result.Architecture)
+	assert.Equal(t, "ubuntu-2.0.0", result.MachineName)
+	assert.Equal(t, map[string]string{"VERSION": "2.0.0", "ARCH": "arm64"}, result.Args)
+}
+
+func TestProcess_ArgWithoutValue(t *testing.T) {
+	_, diag := Process(newTestContext(), "ARG VERSION\nFROM catalog.example.local")
+
+	require.True(t, diag.HasErrors())
+	assert.Contains(t, diag.Errors()[0].Error(), "VERSION")
+}
+
+func TestProcess_IfBlocks(t *testing.T) {
+	content := strings.Join([]string{
+		"ARG ENV=prod",
+		"FROM catalog.example.local",
+		"IF {{ ENV }} == prod",
+		"  TAG production",
+		"  IF {{ .Env.PDFILE_TEST_UNSET }}",
+		"    TAG never",
+		"  END",
+		"ELSE",
+		"  TAG development",
+		"END",
+	}, "\n")
+
+	result, diag := Process(newTestContext(), content)
+	require.False(t, diag.HasErrors(), diag.Errors())
+	assert.Equal(t, []string{"production"}, result.Tags)
+
+	result, diag = ProcessWithArgs(newTestContext(), content, map[string]string{"ENV": "dev"})
+	require.False(t, diag.HasErrors(), diag.Errors())
+	assert.Equal(t, []string{"development"}, result.Tags)
+}
+
+func TestProcess_UnclosedBlocks(t *testing.T) {
+	_, diag := Process(newTestContext(), "FROM catalog.example.local\nIF true\nTAG a")
+	require.True(t, diag.HasErrors())
+
+	_, diag = Process(newTestContext(), "FROM catalog.example.local\nEND")
+	require.True(t, diag.HasErrors())
+}
+
+func TestProcess_Stages(t *testing.T) {
+	content := strings.Join([]string{
+		"ARG VERSION=1.0.0",
+		"AUTHENTICATE API_KEY key",
+		"MACHINE_NAME builder",
+		"",
+		"STAGE base",
+		"FROM catalog.example.local",
+		"CATALOG_ID ubuntu",
+		"DESTINATION /Users/demo/Parallels",
+		"OWNER demo",
+		"RUN pull",
+		"",
+		"STAGE provision",
+		"RUN execute",
+		"EXECUTE brew install git",
+		"ONFAIL",
+		"  EXECUTE echo cleanup",
+		"END",
+		"",
+		"STAGE snapshot",
+		"RUN snapshot",
+		"SNAPSHOT_NAME provisioned-{{ VERSION }}",
+		"",
+		"STAGE publish",
+		"TO catalog.example.local",
+		"CATALOG_ID ubuntu-dev",
+		"VERSION {{ VERSION }}",
+		"LOCAL_PATH /Users/demo/Parallels/builder.pvm",
+		"PROVIDER provider=minio;bucket=demo",
+		"RUN push",
+	}, "\n")
+
+	result, diag := Process(newTestContext(), content)
+	require.False(t, diag.HasErrors(), diag.Errors())
+	require.Len(t, result.Stages, 4)
+
+	base := result.GetStage("base")
+	require.NotNil(t, base)
+	assert.Equal(t, "pull", base.Command)
+	assert.Equal(t, "builder", base.MachineName)
+	assert.Equal(t, "key", base.Authentication.ApiKey)
+
+	provision := result.GetStage("provision")
+	assert.Equal(t, "execute", provision.Command)
+	assert.Equal(t, []string{"brew install git"}, provision.Execute)
+	assert.Equal(t, []string{"echo cleanup"}, provision.OnFail)
+	assert.Empty(t, provision.From)
+
+	assert.Equal(t, "provisioned-1.0.0", result.GetStage("snapshot").SnapshotName)
+	assert.Equal(t, "push", result.GetStage("publish").Command)
+	assert.Nil(t, result.GetStage("missing"))
+
+	svc := NewPDFileService(newTestContext(), result)
+	assert.False(t, svc.Validate().HasErrors(), svc.Validate().Errors())
+}
+
+func TestProcess_StageErrorsAreReportedPerStage(t *testing.T) {
+	content := strings.Join([]string{
+		"STAGE base",
+		"FROM catalog.example.local",
+		"STAGE broken",
+		"UNKNOWN value",
+		"STAGE base",
+	}, "\n")
+
+	_, diag := Process(newTestContext(), content)
+	require.True(t, diag.HasErrors())
+
+	messages := make([]string, 0)
+	for _, err := range diag.Errors() {
+		messages = append(messages, err.Error())
+	}
+	assert.Contains(t, messages, "stage broken: invalid command UNKNOWN value at line 4")
+	assert.Contains(t, messages, "stage base is declared twice, line 5")
+}
+
+func TestValidate_TargetStage(t *testing.T) {
+	content := strings.Join([]string{
+		"STAGE provision",
+		"RUN execute",
+		"STAGE publish",
+		"RUN push",
+	}, "\n")
+
+	result, diag := Process(newTestContext(), content)
+	require.False(t, diag.HasErrors(), diag.Errors())
+
+	svc := NewPDFileService(newTestContext(), result)
+	validation := svc.Validate()
+	require.True(t, validation.HasErrors())
+	assert.Contains(t, validation.Errors()[0].Error(), "stage provision:")
+
+	result.TargetStage = "missing"
+	validation = svc.Validate()
+	require.True(t, validation.HasErrors())
+	assert.Equal(t, "stage missing not found", validation.Errors()[0].Error())
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/Parallels/prl-devops-service/basecontext"
 	"github.com/Parallels/prl-devops-service/pdfile/diagnostics"
 	"github.com/Parallels/prl-devops-service/pdfile/models"
+	"github.com/Parallels/prl-devops-service/secrets"
 )
 
 type AuthenticateCommandProcessor struct{}
@@ -39,6 +40,9 @@ func (p AuthenticateCommandProcessor) Process(ctx basecontext.ApiContext, line s
 	default:
 		diag.AddError(errors.New("invalid authentication type"))
 	}
+	if err := secrets.ValidateReference(strings.TrimSpace(strings.Join(argumentParts[1:], " "))); err != nil {
+		diag.AddError(err)
+	}
 	ctx.LogDebugf("Processed by AuthenticateCommandProcessor, line %v", line)
 	return true, diag
 }
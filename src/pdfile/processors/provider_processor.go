@@ -7,6 +7,7 @@ import (
 	"github.com/Parallels/prl-devops-service/basecontext"
 	"github.com/Parallels/prl-devops-service/pdfile/diagnostics"
 	"github.com/Parallels/prl-devops-service/pdfile/models"
+	"github.com/Parallels/prl-devops-service/secrets"
 )
 
 type ProviderCommandProcessor struct{}
@@ -51,6 +52,9 @@ func (p ProviderCommandProcessor) Process(ctx basecontext.ApiContext, line strin
 			dest.Provider.Name = provider.Name
 		}
 		for key, value := range provider.Attributes {
+			if err := secrets.ValidateReference(value); err != nil {
+				diag.AddError(err)
+			}
 			dest.Provider.Attributes[key] = value
 		}
 	}
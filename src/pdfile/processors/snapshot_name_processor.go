@@ -0,0 +1,29 @@
+package processors
+
+import (
+	"errors"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/pdfile/diagnostics"
+	"github.com/Parallels/prl-devops-service/pdfile/models"
+)
+
+type SnapshotNameCommandProcessor struct{}
+
+func (p SnapshotNameCommandProcessor) Process(ctx basecontext.ApiContext, line string, dest *models.PDFile) (bool, *diagnostics.PDFileDiagnostics) {
+	diag := diagnostics.NewPDFileDiagnostics()
+	command := getCommand(line)
+	if command == nil {
+		return false, diag
+	}
+	if command.Command != "SNAPSHOT_NAME" {
+		return false, diag
+	}
+	if command.Argument == "" {
+		diag.AddError(errors.New("snapshot name command is missing argument"))
+	}
+
+	dest.SnapshotName = command.Argument
+	ctx.LogDebugf("Processed by SnapshotNameCommandProcessor, line %v", line)
+	return true, diag
+}
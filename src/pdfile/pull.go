@@ -169,36 +169,13 @@ func (p *PDFileService) runPull(ctx basecontext.ApiContext) (interface{}, *diagn
 		}
 
 		if vm.State == "stopped" {
-			ctx.LogInfof("Starting machine %v", executeMachine)
-			err := provider.ParallelsDesktopService.StartVm(ctx, executeMachine)
-			if err != nil {
+			if err := startAndWaitForVm(ctx, executeMachine); err != nil {
 				diag.AddError(err)
 				if sendTelemetry {
 					sendTelemetryEvent(amplitudeEvent, telemetryItem, diag)
 				}
 				return nil, diag
 			}
-
-			counter := 0
-			for {
-				resp, err := provider.ParallelsDesktopService.ExecuteCommandOnVm(ctx, executeMachine, &api_models.VirtualMachineExecuteCommandRequest{
-					Command: "echo 'Waiting for machine to start'",
-				})
-				if err == nil && resp.ExitCode == 0 {
-					break
-				}
-
-				time.Sleep(1 * time.Second)
-				counter++
-				if counter > 60 {
-					diag.AddError(errors.New("timeout waiting for machine to start"))
-					if sendTelemetry {
-						sendTelemetryEvent(amplitudeEvent, telemetryItem, diag)
-					}
-					return nil, diag
-				}
-			}
-
 		}
 
 		for _, command := range p.pdfile.Execute {
@@ -0,0 +1,231 @@
+package pdfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/pdfile/diagnostics"
+	"github.com/Parallels/prl-devops-service/pdfile/models"
+)
+
+const SchemaId = "https://parallels.github.io/prl-devops-service/schemas/pdfile.schema.json"
+
+var (
+	pdfileType      = reflect.TypeOf(models.PDFile{})
+	pdfileStageType = reflect.TypeOf(models.PDFileStage{})
+)
+
+// Schema returns the JSON Schema of the yaml and json pd files, it is derived
+// from the json tags of models.PDFile
+func Schema() map[string]interface{} {
+	stage := structSchema(pdfileStageType)
+	stage["required"] = []string{"NAME"}
+
+	return map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     SchemaId,
+		"title":   "PDFile",
+		"$ref":    "#/$defs/PDFile",
+		"$defs": map[string]interface{}{
+			"PDFile":      structSchema(pdfileType),
+			"PDFileStage": stage,
+		},
+	}
+}
+
+// SchemaJSON returns the indented JSON Schema of the pd files
+func SchemaJSON() (string, error) {
+	content, err := json.MarshalIndent(Schema(), "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+func structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	addStructProperties(t, properties)
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+func addStructProperties(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			addStructProperties(field.Type, properties)
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = typeSchema(field.Type)
+	}
+}
+
+func typeSchema(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case pdfileType:
+		return map[string]interface{}{"$ref": "#/$defs/PDFile"}
+	case pdfileStageType:
+		return map[string]interface{}{"$ref": "#/$defs/PDFileStage"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	}
+
+	return map[string]interface{}{}
+}
+
+// validateSchema validates a decoded yaml or json document against the
+// schema, the errors contain the path of the invalid value
+func validateSchema(value interface{}, schema map[string]interface{}) *diagnostics.PDFileDiagnostics {
+	diag := diagnostics.NewPDFileDiagnostics()
+	defs, _ := schema["$defs"].(map[string]interface{})
+	validateSchemaValue(value, schema, defs, "$", diag)
+	return diag
+}
+
+func validateSchemaValue(value interface{}, schema map[string]interface{}, defs map[string]interface{}, path string, diag *diagnostics.PDFileDiagnostics) {
+	if ref, ok := schema["$ref"].(string); ok {
+		definition, ok := defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{})
+		if !ok {
+			diag.AddError(fmt.Errorf("%v: unknown schema reference %v", path, ref))
+			return
+		}
+		schema = definition
+	}
+
+	expectedType, _ := schema["type"].(string)
+	if expectedType != "" && !matchesSchemaType(value, expectedType) {
+		diag.AddError(fmt.Errorf("%v: expected %v but found %v", path, expectedType, describeSchemaValue(value)))
+		return
+	}
+
+	switch expectedType {
+	case "object":
+		object := value.(map[string]interface{})
+		if required, ok := schema["required"].([]string); ok {
+			for _, name := range required {
+				if _, exists := object[name]; !exists {
+					diag.AddError(fmt.Errorf("%v: missing required property %v", path, name))
+				}
+			}
+		}
+
+		properties, _ := schema["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			propertyPath := path + "." + key
+			if propertySchema, ok := properties[key].(map[string]interface{}); ok {
+				validateSchemaValue(object[key], propertySchema, defs, propertyPath, diag)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					diag.AddError(fmt.Errorf("%v: unknown property", propertyPath))
+				}
+			case map[string]interface{}:
+				validateSchemaValue(object[key], additional, defs, propertyPath, diag)
+			}
+		}
+	case "array":
+		items, _ := schema["items"].(map[string]interface{})
+		if items == nil {
+			return
+		}
+		for i, item := range value.([]interface{}) {
+			validateSchemaValue(item, items, defs, fmt.Sprintf("%v[%d]", path, i), diag)
+		}
+	}
+}
+
+func matchesSchemaType(value interface{}, expectedType string) bool {
+	switch expectedType {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		switch v := value.(type) {
+		case int, int64, uint64:
+			return true
+		case float64:
+			return v == math.Trunc(v)
+		}
+		return false
+	case "number":
+		switch value.(type) {
+		case int, int64, uint64, float64:
+			return true
+		}
+		return false
+	}
+
+	return true
+}
+
+func describeSchemaValue(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64, uint64, float64:
+		return "number"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
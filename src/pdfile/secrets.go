@@ -0,0 +1,84 @@
+package pdfile
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/pdfile/models"
+	"github.com/Parallels/prl-devops-service/secrets"
+)
+
+// secretValues returns the values of the pd file that can hold a secret
+// reference, keyed by their structured property path
+func secretValues(pdFile *models.PDFile) map[string]string {
+	result := make(map[string]string)
+	if pdFile.Authentication != nil {
+		result["AUTHENTICATION.USERNAME"] = pdFile.Authentication.Username
+		result["AUTHENTICATION.PASSWORD"] = pdFile.Authentication.Password
+		result["AUTHENTICATION.API_KEY"] = pdFile.Authentication.ApiKey
+	}
+	if pdFile.Provider != nil {
+		for key, value := range pdFile.Provider.Attributes {
+			result["PROVIDER.ATTRIBUTES."+key] = value
+		}
+	}
+
+	return result
+}
+
+// secretReferenceErrors checks the syntax of the secret references in the pd
+// file without resolving them
+func secretReferenceErrors(pdFile *models.PDFile) []string {
+	result := make([]string, 0)
+	for path, value := range secretValues(pdFile) {
+		if err := secrets.ValidateReference(value); err != nil {
+			result = append(result, fmt.Sprintf("%v: %v", path, err.Error()))
+		}
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// resolveSecrets returns a copy of the pd file where the secret://, env:// and
+// file:// references are replaced by their values, it is only called right
+// before a command uses them so the references are never written back
+func resolveSecrets(ctx basecontext.ApiContext, pdFile *models.PDFile) (*models.PDFile, error) {
+	result := pdFile.Copy()
+	result.Stages = pdFile.Stages
+	resolve := func(path string, value string) (string, error) {
+		resolved, err := secrets.Resolve(ctx, value)
+		if err != nil {
+			return "", fmt.Errorf("%v: %w", path, err)
+		}
+		return resolved, nil
+	}
+
+	var err error
+	if result.Authentication != nil {
+		if result.Authentication.Username, err = resolve("AUTHENTICATION.USERNAME", result.Authentication.Username); err != nil {
+			return nil, err
+		}
+		if result.Authentication.Password, err = resolve("AUTHENTICATION.PASSWORD", result.Authentication.Password); err != nil {
+			return nil, err
+		}
+		if result.Authentication.ApiKey, err = resolve("AUTHENTICATION.API_KEY", result.Authentication.ApiKey); err != nil {
+			return nil, err
+		}
+	}
+	if result.Provider != nil {
+		keys := make([]string, 0, len(result.Provider.Attributes))
+		for key := range result.Provider.Attributes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if result.Provider.Attributes[key], err = resolve("PROVIDER.ATTRIBUTES."+key, result.Provider.Attributes[key]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,189 @@
+package pdfile
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	api_models "github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/pdfile/diagnostics"
+	"github.com/Parallels/prl-devops-service/pdfile/models"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+)
+
+// getStagesToRun returns all the stages or only the target stage if one is set
+func (p *PDFileService) getStagesToRun() ([]*models.PDFileStage, error) {
+	if p.pdfile.TargetStage == "" {
+		return p.pdfile.Stages, nil
+	}
+
+	stage := p.pdfile.GetStage(p.pdfile.TargetStage)
+	if stage == nil {
+		return nil, fmt.Errorf("stage %v not found", p.pdfile.TargetStage)
+	}
+
+	return []*models.PDFileStage{stage}, nil
+}
+
+// runStages runs the stages in order stopping at the first one that fails
+func (p *PDFileService) runStages(ctx basecontext.ApiContext) (interface{}, *diagnostics.PDFileDiagnostics) {
+	diag := diagnostics.NewPDFileDiagnostics()
+	stages, err := p.getStagesToRun()
+	if err != nil {
+		diag.AddError(err)
+		return nil, diag
+	}
+
+	outputs := make([]string, 0)
+	for _, stage := range stages {
+		fmt.Printf("Running stage %v\n", stage.Name)
+		svc := NewPDFileService(ctx, &stage.PDFile)
+		validationDiag := svc.Validate()
+		if validationDiag.HasErrors() {
+			diag.AppendStage(stage.Name, validationDiag)
+			return nil, diag
+		}
+
+		out, stageDiag := svc.Run(ctx)
+		diag.AppendStage(stage.Name, stageDiag)
+		if stageDiag.HasErrors() {
+			return nil, diag
+		}
+		if out != nil {
+			outputs = append(outputs, fmt.Sprintf("%v", out))
+		}
+	}
+
+	return strings.Join(outputs, "\n"), diag
+}
+
+// runOnFail executes the ONFAIL commands on the machine of the pd file
+func (p *PDFileService) runOnFail(ctx basecontext.ApiContext) *diagnostics.PDFileDiagnostics {
+	diag := diagnostics.NewPDFileDiagnostics()
+	if len(p.pdfile.OnFail) == 0 {
+		return diag
+	}
+	if p.pdfile.MachineName == "" {
+		diag.AddWarning(errors.New("machine name not found, skipping the ONFAIL commands"))
+		return diag
+	}
+
+	for _, command := range p.pdfile.OnFail {
+		if err := executeOnVm(ctx, p.pdfile.MachineName, command); err != nil {
+			diag.AddError(fmt.Errorf("onfail: %w", err))
+			return diag
+		}
+	}
+
+	return diag
+}
+
+func (p *PDFileService) runExecute(ctx basecontext.ApiContext) (interface{}, *diagnostics.PDFileDiagnostics) {
+	diag := diagnostics.NewPDFileDiagnostics()
+	serviceprovider.InitParallelsServices(ctx)
+	provider := serviceprovider.Get()
+	if provider.ParallelsDesktopService == nil {
+		diag.AddError(errors.New("parallels Desktop service is not available"))
+		return nil, diag
+	}
+
+	vm, err := provider.ParallelsDesktopService.GetVmSync(ctx, p.pdfile.MachineName)
+	if err != nil {
+		diag.AddError(err)
+		return nil, diag
+	}
+	if vm.State == "stopped" {
+		if err := startAndWaitForVm(ctx, p.pdfile.MachineName); err != nil {
+			diag.AddError(err)
+			return nil, diag
+		}
+	}
+
+	for _, command := range p.pdfile.Execute {
+		clearLine()
+		fmt.Printf("\rExecuting command %v on machine %v", command, p.pdfile.MachineName)
+		if err := executeOnVm(ctx, p.pdfile.MachineName, command); err != nil {
+			diag.AddError(err)
+			return nil, diag
+		}
+	}
+
+	clearLine()
+	return fmt.Sprintf("Executed %v commands on machine %v", len(p.pdfile.Execute), p.pdfile.MachineName), diag
+}
+
+func (p *PDFileService) runSnapshot(ctx basecontext.ApiContext) (interface{}, *diagnostics.PDFileDiagnostics) {
+	diag := diagnostics.NewPDFileDiagnostics()
+	serviceprovider.InitParallelsServices(ctx)
+	provider := serviceprovider.Get()
+	if provider.ParallelsDesktopService == nil {
+		diag.AddError(errors.New("parallels Desktop service is not available"))
+		return nil, diag
+	}
+
+	vm, err := provider.ParallelsDesktopService.GetVmSync(ctx, p.pdfile.MachineName)
+	if err != nil {
+		diag.AddError(err)
+		return nil, diag
+	}
+
+	response, err := provider.ParallelsDesktopService.CreateVMSnapshot(ctx, vm.ID, &api_models.CreateVMSnapshotRequest{
+		SnapshotName:        p.pdfile.SnapshotName,
+		SnapshotDescription: p.pdfile.Description,
+	})
+	if err != nil {
+		diag.AddError(err)
+		return nil, diag
+	}
+
+	return fmt.Sprintf("Created snapshot %v (%v) of machine %v", response.SnapshotName, response.SnapshotId, p.pdfile.MachineName), diag
+}
+
+func startAndWaitForVm(ctx basecontext.ApiContext, machine string) error {
+	provider := serviceprovider.Get()
+	if provider.ParallelsDesktopService == nil {
+		return errors.New("parallels Desktop service is not available")
+	}
+
+	ctx.LogInfof("Starting machine %v", machine)
+	if err := provider.ParallelsDesktopService.StartVm(ctx, machine); err != nil {
+		return err
+	}
+
+	counter := 0
+	for {
+		resp, err := provider.ParallelsDesktopService.ExecuteCommandOnVm(ctx, machine, &api_models.VirtualMachineExecuteCommandRequest{
+			Command: "echo 'Waiting for machine to start'",
+		})
+		if err == nil && resp.ExitCode == 0 {
+			return nil
+		}
+
+		time.Sleep(1 * time.Second)
+		counter++
+		if counter > 60 {
+			return errors.New("timeout waiting for machine to start")
+		}
+	}
+}
+
+func executeOnVm(ctx basecontext.ApiContext, machine string, command string) error {
+	provider := serviceprovider.Get()
+	if provider.ParallelsDesktopService == nil {
+		return errors.New("parallels Desktop service is not available")
+	}
+
+	r, err := provider.ParallelsDesktopService.ExecuteCommandOnVm(ctx, machine, &api_models.VirtualMachineExecuteCommandRequest{
+		Command: command,
+	})
+	if err != nil {
+		return err
+	}
+	if r.ExitCode != 0 {
+		return fmt.Errorf("error executing command %v: %v", command, r.Error)
+	}
+
+	return nil
+}
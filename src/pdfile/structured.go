@@ -0,0 +1,247 @@
+package pdfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/pdfile/diagnostics"
+	"github.com/Parallels/prl-devops-service/pdfile/models"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatPDFile = "pdfile"
+	FormatYaml   = "yaml"
+	FormatJson   = "json"
+)
+
+// GetFormat returns the format of a pd file based on its extension, files that
+// are not yaml or json use the line format
+func GetFormat(pdFilepath string) string {
+	switch strings.ToLower(filepath.Ext(pdFilepath)) {
+	case ".yaml", ".yml":
+		return FormatYaml
+	case ".json":
+		return FormatJson
+	default:
+		return FormatPDFile
+	}
+}
+
+// ProcessStructured processes a yaml or json pd file, the document is validated
+// against the pd file schema before it is converted and the templates in its
+// string values are resolved the same way as in the line format
+func ProcessStructured(ctx basecontext.ApiContext, fileContent string, format string, args map[string]string) (*models.PDFile, *diagnostics.PDFileDiagnostics) {
+	diag := diagnostics.NewPDFileDiagnostics()
+
+	var document interface{}
+	switch format {
+	case FormatYaml:
+		if err := yaml.Unmarshal([]byte(fileContent), &document); err != nil {
+			diag.AddError(fmt.Errorf("invalid yaml: %w", err))
+			return nil, diag
+		}
+	case FormatJson:
+		if err := json.Unmarshal([]byte(fileContent), &document); err != nil {
+			diag.AddError(fmt.Errorf("invalid json: %w", err))
+			return nil, diag
+		}
+	default:
+		diag.AddError(fmt.Errorf("unsupported pd file format %v", format))
+		return nil, diag
+	}
+	if document == nil {
+		document = map[string]interface{}{}
+	}
+
+	diag.Append(validateSchema(document, Schema()))
+	if diag.HasErrors() {
+		return nil, diag
+	}
+
+	root := document.(map[string]interface{})
+	layout := &pdfileLayout{
+		args: make(map[string]string),
+		vars: make(map[string]string),
+	}
+	if declaredArgs, ok := root["ARGS"].(map[string]interface{}); ok {
+		for name, value := range declaredArgs {
+			layout.args[name] = fmt.Sprintf("%v", value)
+			if override, ok := args[name]; ok {
+				layout.args[name] = override
+			}
+		}
+	}
+	// vars can refer to the ones declared before them, so they are expanded in
+	// the order of the file as the line format does
+	if declaredVars, ok := root["VARS"].(map[string]interface{}); ok {
+		for _, name := range declaredVarsOrder(fileContent, declaredVars) {
+			layout.vars[name] = layout.expand(fmt.Sprintf("%v", declaredVars[name]))
+		}
+	}
+	root["ARGS"] = stringMapToDocument(layout.args)
+	root["VARS"] = stringMapToDocument(layout.vars)
+	expandDocument(layout, root)
+
+	stages, _ := root["STAGES"].([]interface{})
+	delete(root, "STAGES")
+
+	result := models.NewPdFile()
+	if err := decodeDocument(root, result); err != nil {
+		diag.AddError(err)
+		return nil, diag
+	}
+	if len(result.Args) == 0 {
+		result.Args = nil
+	}
+	if len(result.Vars) == 0 {
+		result.Vars = nil
+	}
+	rootReferenceErrors := secretReferenceErrors(result)
+	for _, message := range rootReferenceErrors {
+		diag.AddError(fmt.Errorf("$.%v", message))
+	}
+	if len(stages) == 0 {
+		finalizeStructured(result)
+		return result, diag
+	}
+
+	if len(result.OnFail) > 0 {
+		diag.AddError(fmt.Errorf("$.ONFAIL: ONFAIL needs to be declared inside a stage in multi-stage files"))
+	}
+
+	for i, item := range stages {
+		stageDocument := item.(map[string]interface{})
+		path := fmt.Sprintf("$.STAGES[%d]", i)
+		name, _ := stageDocument["NAME"].(string)
+		if name == "" || strings.Contains(name, " ") {
+			diag.AddError(fmt.Errorf("%v.NAME: invalid stage name %q", path, name))
+			continue
+		}
+		if result.GetStage(name) != nil {
+			diag.AddError(fmt.Errorf("%v.NAME: stage %v is declared twice", path, name))
+			continue
+		}
+		if _, ok := stageDocument["STAGES"]; ok {
+			diag.AddError(fmt.Errorf("%v.STAGES: stages cannot be nested", path))
+			continue
+		}
+
+		stageFile := result.Copy()
+		stageFile.Command = ""
+		stageFile.Operation = ""
+		stageFile.OnFail = nil
+		if err := decodeDocument(stageDocument, stageFile); err != nil {
+			diag.AddError(fmt.Errorf("%v: %w", path, err))
+			continue
+		}
+		finalizeStructured(stageFile)
+		for _, message := range secretReferenceErrors(stageFile) {
+			if !containsLine(rootReferenceErrors, message) {
+				diag.AddError(fmt.Errorf("%v.%v", path, message))
+			}
+		}
+
+		result.Stages = append(result.Stages, &models.PDFileStage{
+			Name:   name,
+			PDFile: *stageFile,
+		})
+	}
+
+	return result, diag
+}
+
+// declaredVarsOrder returns the names of the VARS in the order they are
+// declared in the file, json is read as yaml so both formats keep their order
+func declaredVarsOrder(fileContent string, declaredVars map[string]interface{}) []string {
+	result := make([]string, 0, len(declaredVars))
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(fileContent), &node); err == nil && len(node.Content) > 0 {
+		root := node.Content[0]
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value != "VARS" || root.Content[i+1].Kind != yaml.MappingNode {
+				continue
+			}
+			vars := root.Content[i+1]
+			for j := 0; j+1 < len(vars.Content); j += 2 {
+				if _, ok := declaredVars[vars.Content[j].Value]; ok {
+					result = append(result, vars.Content[j].Value)
+				}
+			}
+		}
+	}
+
+	// anything the node walk missed is still expanded, after the ordered ones
+	if len(result) != len(declaredVars) {
+		seen := make(map[string]bool)
+		for _, name := range result {
+			seen[name] = true
+		}
+		missing := make([]string, 0)
+		for name := range declaredVars {
+			if !seen[name] {
+				missing = append(missing, name)
+			}
+		}
+		sort.Strings(missing)
+		result = append(result, missing...)
+	}
+
+	return result
+}
+
+// decodeDocument decodes a validated document on top of the pd file, lists
+// replace the existing values while objects are merged
+func decodeDocument(document map[string]interface{}, dest *models.PDFile) error {
+	content, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(content, dest)
+}
+
+func finalizeStructured(pdFile *models.PDFile) {
+	if pdFile.Operation != "" {
+		pdFile.Command = pdFile.Operation
+	}
+	if pdFile.From != "" {
+		pdFile.Host = pdFile.From
+	} else if pdFile.To != "" {
+		pdFile.Host = pdFile.To
+	}
+	setDefaultCommand(pdFile)
+}
+
+func expandDocument(layout *pdfileLayout, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return layout.expand(v)
+	case map[string]interface{}:
+		for key, item := range v {
+			if key == "ARGS" || key == "VARS" {
+				continue
+			}
+			v[key] = expandDocument(layout, item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = expandDocument(layout, item)
+		}
+	}
+
+	return value
+}
+
+func stringMapToDocument(values map[string]string) map[string]interface{} {
+	result := make(map[string]interface{})
+	for key, value := range values {
+		result[key] = value
+	}
+
+	return result
+}
@@ -0,0 +1,250 @@
+package pdfile
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/pdfile/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testStructuredYaml = `
+ARGS:
+  VERSION: 1.0.0
+VARS:
+  NAME: builder-{{ VERSION }}
+AUTHENTICATION:
+  API_KEY: key
+MACHINE_NAME: "{{ .Vars.NAME }}"
+TAGS:
+  - base
+STAGES:
+  - NAME: base
+    FROM: catalog.example.local
+    CATALOG_ID: ubuntu
+    DESTINATION: /Users/demo/Parallels
+    OWNER: demo
+  - NAME: provision
+    RUN: execute
+    EXECUTE:
+      - brew install git
+    ONFAIL:
+      - echo cleanup
+  - NAME: publish
+    TO: catalog.example.local
+    CATALOG_ID: ubuntu-dev
+    VERSION: "{{ VERSION }}"
+    LOCAL_PATH: /Users/demo/Parallels/builder.pvm
+    TAGS:
+      - base
+      - dev
+    PROVIDER:
+      NAME: minio
+      ATTRIBUTES:
+        bucket: demo
+`
+
+func TestGetFormat(t *testing.T) {
+	assert.Equal(t, FormatYaml, GetFormat("build.pdfile.yaml"))
+	assert.Equal(t, FormatYaml, GetFormat("build.pdfile.YML"))
+	assert.Equal(t, FormatJson, GetFormat("build.pdfile.json"))
+	assert.Equal(t, FormatPDFile, GetFormat("build.pdfile"))
+}
+
+func TestProcessStructured_VarsExpandInDeclarationOrder(t *testing.T) {
+	tests := []struct {
+		format  string
+		content string
+	}{
+		{FormatYaml, "VARS:\n  A: one\n  B: \"{{ .Vars.A }}-two\"\n  C: \"{{ .Vars.B }}-three\"\n  D: \"{{ .Vars.C }}-four\"\nMACHINE_NAME: \"{{ .Vars.D }}\"\n"},
+		{FormatJson, `{"VARS": {"A": "one", "B": "{{ .Vars.A }}-two", "C": "{{ .Vars.B }}-three", "D": "{{ .Vars.C }}-four"}, "MACHINE_NAME": "{{ .Vars.D }}"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			// map iteration is random, so a single run could pass by chance
+			for i := 0; i < 20; i++ {
+				result, diag := ProcessStructured(newTestContext(), tt.content, tt.format, nil)
+				require.False(t, diag.HasErrors(), diag.Errors())
+				assert.Equal(t, "one-two-three-four", result.MachineName)
+				assert.Equal(t, "one-two-three-four", result.Vars["D"])
+			}
+		})
+	}
+}
+
+func TestProcessStructured_Yaml(t *testing.T) {
+	result, diag := ProcessStructured(newTestContext(), testStructuredYaml, FormatYaml, map[string]string{"VERSION": "2.0.0"})
+	require.False(t, diag.HasErrors(), diag.Errors())
+	require.Len(t, result.Stages, 3)
+
+	assert.Equal(t, "builder-2.0.0", result.MachineName)
+	assert.Equal(t, map[string]string{"VERSION": "2.0.0"}, result.Args)
+
+	base := result.GetStage("base")
+	assert.Equal(t, "pull", base.Command)
+	assert.Equal(t, "catalog.example.local", base.Host)
+	assert.Equal(t, "key", base.Authentication.ApiKey)
+	assert.Equal(t, "builder-2.0.0", base.MachineName)
+
+	provision := result.GetStage("provision")
+	assert.Equal(t, "execute", provision.Command)
+	assert.Equal(t, []string{"echo cleanup"}, provision.OnFail)
+	assert.Empty(t, provision.From)
+
+	publish := result.GetStage("publish")
+	assert.Equal(t, "push", publish.Command)
+	assert.Equal(t, "2.0.0", publish.Version)
+	assert.Equal(t, []string{"base", "dev"}, publish.Tags)
+	assert.Equal(t, "minio", publish.Provider.Name)
+
+	svc := NewPDFileService(newTestContext(), result)
+	assert.False(t, svc.Validate().HasErrors(), svc.Validate().Errors())
+}
+
+func TestProcessStructured_Json(t *testing.T) {
+	content := `{"FROM": "catalog.example.local", "CATALOG_ID": "ubuntu", "MACHINE_NAME": "runner", "INSECURE": true, "MINIMUM_REQUIREMENTS": {"cpu": 2}}`
+
+	result, diag := ProcessStructured(newTestContext(), content, FormatJson, nil)
+	require.False(t, diag.HasErrors(), diag.Errors())
+
+	assert.Equal(t, "pull", result.Command)
+	assert.True(t, result.Insecure)
+	assert.Equal(t, 2, result.MinimumSpecRequirements.Cpu)
+	assert.Equal(t, "http://catalog.example.local/api/catalog", result.GetHostUrl())
+}
+
+func TestProcessStructured_SchemaErrors(t *testing.T) {
+	content := strings.Join([]string{
+		"FROM: catalog.example.local",
+		"MACHINE: runner",
+		"TAGS: base",
+		"STAGES:",
+		"  - NAME: base",
+		"  - NAME: build",
+		"    MINIMUM_REQUIREMENTS:",
+		"      cpu: two",
+		"  - RUN: push",
+	}, "\n")
+
+	_, diag := ProcessStructured(newTestContext(), content, FormatYaml, nil)
+	require.True(t, diag.HasErrors())
+
+	messages := make([]string, 0)
+	for _, err := range diag.Errors() {
+		messages = append(messages, err.Error())
+	}
+	assert.Contains(t, messages, "$.MACHINE: unknown property")
+	assert.Contains(t, messages, "$.TAGS: expected array but found string")
+	assert.Contains(t, messages, "$.STAGES[1].MINIMUM_REQUIREMENTS.cpu: expected integer but found string")
+	assert.Contains(t, messages, "$.STAGES[2]: missing required property NAME")
+}
+
+func TestProcessStructured_InvalidStages(t *testing.T) {
+	content := `{"STAGES": [{"NAME": "base"}, {"NAME": "base"}], "ONFAIL": ["echo cleanup"]}`
+
+	_, diag := ProcessStructured(newTestContext(), content, FormatJson, nil)
+	require.True(t, diag.HasErrors())
+
+	messages := make([]string, 0)
+	for _, err := range diag.Errors() {
+		messages = append(messages, err.Error())
+	}
+	assert.Contains(t, messages, "$.STAGES[1].NAME: stage base is declared twice")
+	assert.Contains(t, messages, "$.ONFAIL: ONFAIL needs to be declared inside a stage in multi-stage files")
+}
+
+func TestSchema(t *testing.T) {
+	schema := Schema()
+	definitions := schema["$defs"].(map[string]interface{})
+	pdFile := definitions["PDFile"].(map[string]interface{})
+	properties := pdFile["properties"].(map[string]interface{})
+
+	assert.Equal(t, map[string]interface{}{"$ref": "#/$defs/PDFileStage"}, properties["STAGES"].(map[string]interface{})["items"])
+	assert.Equal(t, map[string]interface{}{"type": "boolean"}, properties["INSECURE"])
+	assert.NotContains(t, properties, "Raw")
+	assert.NotContains(t, properties, "TargetStage")
+
+	stage := definitions["PDFileStage"].(map[string]interface{})
+	assert.Equal(t, []string{"NAME"}, stage["required"])
+	assert.Contains(t, stage["properties"], "FROM")
+
+	content, err := SchemaJSON()
+	require.NoError(t, err)
+	assert.Contains(t, content, SchemaId)
+}
+
+func TestFormat_RoundTrip(t *testing.T) {
+	content := strings.Join([]string{
+		"ARG VERSION=1.0.0",
+		"AUTHENTICATE API_KEY key",
+		"MACHINE_NAME builder",
+		"TAG base",
+		"STAGE base",
+		"FROM catalog.example.local",
+		"CATALOG_ID ubuntu",
+		"DESTINATION /Users/demo/Parallels",
+		"OWNER demo",
+		"MINIMUM_REQUIREMENT CPU 2",
+		"STAGE provision",
+		"RUN execute",
+		"EXECUTE brew install git",
+		"ONFAIL",
+		"  EXECUTE echo cleanup",
+		"END",
+		"STAGE publish",
+		"TO catalog.example.local",
+		"CATALOG_ID ubuntu-dev",
+		"VERSION {{ VERSION }}",
+		"LOCAL_PATH /Users/demo/Parallels/builder.pvm",
+		"PROVIDER name=minio;bucket=demo",
+		"COMPRESS_PACK_LEVEL best_speed",
+		"TAG dev",
+	}, "\n")
+
+	original, diag := Process(newTestContext(), content)
+	require.False(t, diag.HasErrors(), diag.Errors())
+
+	for _, format := range []string{FormatYaml, FormatJson} {
+		structured, err := Format(original, format)
+		require.NoError(t, err)
+
+		converted, diag := ProcessStructured(newTestContext(), structured, format, nil)
+		require.False(t, diag.HasErrors(), diag.Errors())
+		assertSameStages(t, original, converted)
+
+		lines, err := Format(converted, FormatPDFile)
+		require.NoError(t, err)
+
+		reverted, diag := Process(newTestContext(), lines)
+		require.False(t, diag.HasErrors(), diag.Errors())
+		assertSameStages(t, original, reverted)
+	}
+}
+
+func TestFormat_InvalidFormat(t *testing.T) {
+	_, err := Format(models.NewPdFile(), "toml")
+	require.Error(t, err)
+}
+
+func assertSameStages(t *testing.T, expected *models.PDFile, actual *models.PDFile) {
+	require.Len(t, actual.Stages, len(expected.Stages))
+	for i, stage := range expected.Stages {
+		actualStage := actual.Stages[i]
+		assert.Equal(t, stage.Name, actualStage.Name)
+		assert.Equal(t, stage.Command, actualStage.Command)
+		assert.Equal(t, stage.From, actualStage.From)
+		assert.Equal(t, stage.To, actualStage.To)
+		assert.Equal(t, stage.CatalogId, actualStage.CatalogId)
+		assert.Equal(t, stage.Version, actualStage.Version)
+		assert.Equal(t, stage.MachineName, actualStage.MachineName)
+		assert.Equal(t, stage.Authentication, actualStage.Authentication)
+		assert.Equal(t, stage.Provider, actualStage.Provider)
+		assert.Equal(t, stage.MinimumSpecRequirements, actualStage.MinimumSpecRequirements)
+		assert.Equal(t, stage.CompressPackLevel, actualStage.CompressPackLevel)
+		assert.ElementsMatch(t, stage.Tags, actualStage.Tags)
+		assert.ElementsMatch(t, stage.Execute, actualStage.Execute)
+		assert.ElementsMatch(t, stage.OnFail, actualStage.OnFail)
+	}
+}
@@ -10,6 +10,18 @@ import (
 
 func (p *PDFileService) Validate() *diagnostics.PDFileDiagnostics {
 	diag := diagnostics.NewPDFileDiagnostics()
+	if len(p.pdfile.Stages) > 0 {
+		stages, err := p.getStagesToRun()
+		if err != nil {
+			diag.AddError(err)
+			return diag
+		}
+		for _, stage := range stages {
+			diag.AppendStage(stage.Name, NewPDFileService(p.ctx, &stage.PDFile).Validate())
+		}
+		return diag
+	}
+
 	hasFromOrTo := false
 	hasProvider := false
 	hasProviderName := false
@@ -95,6 +107,8 @@ func (p *PDFileService) Validate() *diagnostics.PDFileDiagnostics {
 			continue
 		case "IS_COMPRESSED":
 			continue
+		case "SNAPSHOT_NAME":
+			continue
 		case "PROVIDER":
 			namePart := ""
 			hasProvider = true
@@ -134,6 +148,13 @@ func (p *PDFileService) Validate() *diagnostics.PDFileDiagnostics {
 		}
 	}
 
+	if p.pdfile.Provider != nil {
+		hasProvider = true
+		if p.pdfile.Provider.Name != "" {
+			hasProviderName = true
+		}
+	}
+
 	if hasProvider {
 		if !hasProviderName {
 			diag.AddError(fmt.Errorf("provider name not found"))
@@ -150,10 +171,6 @@ func (p *PDFileService) Validate() *diagnostics.PDFileDiagnostics {
 		diag.AddError(fmt.Errorf("RUN command not found"))
 	}
 
-	if !hasFromOrTo && (p.pdfile.From == "" && p.pdfile.To == "") {
-		diag.AddError(fmt.Errorf("from command not found"))
-	}
-
 	if hasAuthentication || p.pdfile.Authentication != nil {
 		if (isUsernamePresent && !isPasswordPresent) && (p.pdfile.Authentication.Username != "" && p.pdfile.Authentication.Password == "") {
 			diag.AddError(fmt.Errorf("username was found but password was not found"))
@@ -175,7 +192,23 @@ func (p *PDFileService) Validate() *diagnostics.PDFileDiagnostics {
 		cmd = p.pdfile.Command
 	}
 
-	switch cmd {
+	isLocalCommand := strings.EqualFold(cmd, "execute") || strings.EqualFold(cmd, "snapshot")
+	if !isLocalCommand && !hasFromOrTo && (p.pdfile.From == "" && p.pdfile.To == "") {
+		diag.AddError(fmt.Errorf("from command not found"))
+	}
+
+	switch strings.ToLower(cmd) {
+	case "execute":
+		if p.pdfile.MachineName == "" {
+			diag.AddError(fmt.Errorf("machine name not found in pd file"))
+		}
+		if len(p.pdfile.Execute) == 0 {
+			diag.AddError(fmt.Errorf("execute command not found in pd file"))
+		}
+	case "snapshot":
+		if p.pdfile.MachineName == "" {
+			diag.AddError(fmt.Errorf("machine name not found in pd file"))
+		}
 	case "pull":
 		if p.pdfile.MachineName == "" {
 			diag.AddError(fmt.Errorf("machine name not found in pd file"))
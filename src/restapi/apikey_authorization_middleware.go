@@ -8,10 +8,13 @@ import (
 
 	"github.com/Parallels/prl-devops-service/basecontext"
 	"github.com/Parallels/prl-devops-service/constants"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
 	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/mappers"
 	"github.com/Parallels/prl-devops-service/models"
 	"github.com/Parallels/prl-devops-service/security/apikey"
 	"github.com/Parallels/prl-devops-service/serviceprovider"
+	"github.com/gorilla/mux"
 )
 
 type ApiKeyHeader struct {
@@ -58,6 +61,21 @@ func ApiKeyAuthorizationMiddlewareAdapter(roles []string, claims []string, roleC
 				return
 			}
 
+			if result.Scope != nil {
+				if err := checkApiKeyScope(baseCtx, r, result, authorizationContext, roles, claims, claimComparisonOperation); err != nil {
+					authError.ErrorDescription = err.Error()
+					authorizationContext.AuthorizationError = &authError
+					baseCtx.LogInfof("The Api Key scope does not allow the request: %v", err)
+					ctx := context.WithValue(r.Context(), constants.AUTHORIZATION_CONTEXT_KEY, authorizationContext)
+					next.ServeHTTP(w, r.WithContext(ctx))
+					return
+				}
+			}
+
+			if err := db.UpdateApiKeyUsage(baseCtx, result.ApiKeyId, GetClientIp(r)); err != nil {
+				baseCtx.LogWarnf("Error recording the usage of the Api Key: %v", err)
+			}
+
 			authorizationContext.IsAuthorized = true
 			authorizationContext.IsMicroService = true
 			authorizationContext.AuthorizedBy = "ApiKeyAuthorization"
@@ -70,6 +88,100 @@ func ApiKeyAuthorizationMiddlewareAdapter(roles []string, claims []string, roleC
 	}
 }
 
+// checkApiKeyScope checks the request against the scope of the api key and
+// limits the claims of the request to the ones of the scope
+func checkApiKeyScope(
+	ctx basecontext.ApiContext,
+	r *http.Request,
+	result *apikey.ApiKeyValidationResult,
+	authorizationContext *basecontext.AuthorizationContext,
+	roles []string,
+	claims []string,
+	claimComparisonOperation ComparisonOperation,
+) error {
+	db := serviceprovider.Get().JsonDatabase
+	scope := *result.Scope
+
+	// the key cannot do more than its user can do now
+	if result.UserID != "" {
+		user, err := db.GetUser(ctx, result.UserID)
+		if err != nil || user == nil {
+			return errors.New("the user of the Api Key does not exist")
+		}
+		if user.Disabled {
+			return errors.New("the user of the Api Key is disabled")
+		}
+		if len(scope.Claims) > 0 {
+			scopeClaims, err := limitScopeClaimsToUser(*user, scope.Claims)
+			if err != nil {
+				return err
+			}
+			scope.Claims = scopeClaims
+		}
+	}
+
+	request := apikey.ScopeRequest{
+		Method:           r.Method,
+		Path:             r.URL.Path,
+		SourceIp:         GetClientIp(r),
+		RequiredRoles:    roles,
+		RequiredClaims:   claims,
+		RequireAllClaims: normalizeComparisonOperation(claimComparisonOperation) == ComparisonOperationAnd,
+		GetHostTags: func(id string) []string {
+			host, err := db.GetOrchestratorHost(ctx, id)
+			if err != nil || host == nil {
+				return nil
+			}
+			return host.Tags
+		},
+		GetMachineHostId: func(id string) string {
+			hosts, err := db.GetOrchestratorHosts(ctx, "")
+			if err != nil {
+				return ""
+			}
+			for _, host := range hosts {
+				for _, machine := range host.VirtualMachines {
+					if strings.EqualFold(machine.ID, id) || strings.EqualFold(machine.Name, id) {
+						return host.ID
+					}
+				}
+			}
+			return ""
+		},
+	}
+	vars := mux.Vars(r)
+	if catalogId := vars["catalogId"]; catalogId != "" {
+		request.CatalogId = catalogId
+	} else {
+		request.CatalogId = vars["catalog_id"]
+	}
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			if strings.Contains(template, "/orchestrator/hosts/{id}") || strings.Contains(template, "/orchestrator/overview/{id}") {
+				request.HostId = vars["id"]
+			}
+			if strings.Contains(template, "/orchestrator/machines/{id}") {
+				request.MachineId = vars["id"]
+			}
+		}
+	}
+
+	if err := apikey.CheckScope(&scope, request); err != nil {
+		return err
+	}
+
+	if len(scope.Claims) > 0 {
+		authorizationContext.InjectedClaims = scope.Claims
+		authorizationContext.IsScopedApiKey = true
+	}
+	// the routes listing resources only return the ones of the scope
+	authorizationContext.ScopedCatalogs, authorizationContext.ScopedHostTags = apikey.ScopeResources(scope.Resources)
+	// the limit is counted by the rate limit middleware
+	authorizationContext.ApiKeyRateLimit = scope.RateLimit
+
+	return nil
+}
+
 func extractApiKey(headers http.Header) (*ApiKeyHeader, error) {
 	authHeader := headers.Get("X-Api-Key")
 	if authHeader == "" {
@@ -93,3 +205,29 @@ func extractApiKey(headers http.Header) (*ApiKeyHeader, error) {
 		Value: parts[1],
 	}, nil
 }
+
+// limitScopeClaimsToUser drops the claims of the scope the user no longer has,
+// super users keep all of them
+func limitScopeClaimsToUser(user data_models.User, claims []string) ([]string, error) {
+	for _, role := range user.Roles {
+		if strings.EqualFold(role.Name, constants.SUPER_USER_ROLE) {
+			return claims, nil
+		}
+	}
+
+	userClaims := mappers.ComputeEffectiveClaimIDs(user)
+	scopeClaims := make([]string, 0)
+	for _, claim := range claims {
+		for _, userClaim := range userClaims {
+			if strings.EqualFold(claim, userClaim) {
+				scopeClaims = append(scopeClaims, claim)
+				break
+			}
+		}
+	}
+	if len(scopeClaims) == 0 {
+		return nil, errors.New("the user of the Api Key no longer has any of the claims of its scope")
+	}
+
+	return scopeClaims, nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/Parallels/prl-devops-service/constants"
 	"github.com/Parallels/prl-devops-service/data"
 	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/security/ratelimit"
 	"github.com/Parallels/prl-devops-service/serviceprovider"
 	log "github.com/cjlapao/common-go-logger"
 	"github.com/stretchr/testify/assert"
@@ -147,3 +148,149 @@ func TestApiKeyAuthorizationMiddlewareAdapter_Expiration(t *testing.T) {
 		checkContext("REVOKED_KEY", false, "Api Key has been revoked")
 	})
 }
+
+func TestApiKeyAuthorizationMiddlewareAdapter_Scope(t *testing.T) {
+	common.Logger = log.Get()
+	ctx := basecontext.NewBaseContext()
+	ctx.DisableLog()
+	db := data.NewJsonDatabase(ctx, filepath.Join(t.TempDir(), "test_db.json"))
+	sp := serviceprovider.NewMockProvider()
+	sp.JsonDatabase = db
+	require.NoError(t, db.Connect(ctx))
+
+	// the rate limit of the scope applies without the configured limits
+	ratelimit.New(ctx).Options.WithEnabled(false)
+
+	_, err := db.CreateApiKey(ctx, models.ApiKey{
+		ID:     "scoped-id",
+		Name:   "Scoped",
+		Key:    "SCOPED_KEY",
+		Secret: "secret",
+		Scope: &models.ApiKeyScope{
+			Claims:      []string{constants.LIST_VM_CLAIM},
+			Methods:     []string{http.MethodGet},
+			SourceCidrs: []string{"127.0.0.0/8"},
+			RateLimit:   2,
+		},
+	})
+	require.NoError(t, err)
+	_, err = db.CreateApiKey(ctx, models.ApiKey{ID: "rotated-id", Name: "Rotated", Key: "ROTATED_KEY", Secret: "old-secret"})
+	require.NoError(t, err)
+	_, err = db.RotateApiKey(ctx, "rotated-id", "new-secret", time.Hour)
+	require.NoError(t, err)
+
+	serve := func(method string, remoteAddr string, key string, secret string, claims []string) (*httptest.ResponseRecorder, *basecontext.AuthorizationContext) {
+		t.Helper()
+		req := httptest.NewRequest(method, "/api/v1/machines", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Api-Key", base64.StdEncoding.EncodeToString([]byte(key+":"+secret)))
+		w := httptest.NewRecorder()
+
+		var authCtx *basecontext.AuthorizationContext
+		Adapt(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authCtx, _ = r.Context().Value(constants.AUTHORIZATION_CONTEXT_KEY).(*basecontext.AuthorizationContext)
+		}), ApiKeyAuthorizationMiddlewareAdapter(nil, claims, ComparisonOperationAnd, ComparisonOperationAnd), RateLimitMiddlewareAdapter()).ServeHTTP(w, req)
+
+		return w, authCtx
+	}
+
+	_, authCtx := serve(http.MethodGet, "127.0.0.1:1234", "SCOPED_KEY", "secret", []string{constants.LIST_VM_CLAIM})
+	require.NotNil(t, authCtx)
+	assert.True(t, authCtx.IsAuthorized)
+	assert.True(t, authCtx.IsScopedApiKey)
+	assert.Equal(t, []string{constants.LIST_VM_CLAIM}, authCtx.InjectedClaims)
+
+	key, err := db.GetApiKey(ctx, "scoped-id")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", key.LastUsedIp)
+	assert.NotEmpty(t, key.LastUsedAt)
+
+	_, authCtx = serve(http.MethodGet, "127.0.0.1:1234", "SCOPED_KEY", "secret", []string{constants.CREATE_VM_CLAIM})
+	assert.False(t, authCtx.IsAuthorized)
+	assert.Contains(t, authCtx.AuthorizationError.ErrorDescription, "does not contain the claims")
+
+	_, authCtx = serve(http.MethodDelete, "127.0.0.1:1234", "SCOPED_KEY", "secret", nil)
+	assert.False(t, authCtx.IsAuthorized)
+	assert.Contains(t, authCtx.AuthorizationError.ErrorDescription, "cannot be used for DELETE requests")
+
+	_, authCtx = serve(http.MethodGet, "192.168.1.10:1234", "SCOPED_KEY", "secret", nil)
+	assert.False(t, authCtx.IsAuthorized)
+	assert.Contains(t, authCtx.AuthorizationError.ErrorDescription, "cannot be used from 192.168.1.10")
+
+	// the second allowed request of the minute, the third is rate limited by
+	// the rate limit middleware
+	w, authCtx := serve(http.MethodGet, "127.0.0.1:1234", "SCOPED_KEY", "secret", nil)
+	assert.True(t, authCtx.IsAuthorized)
+	w, authCtx = serve(http.MethodGet, "127.0.0.1:1234", "SCOPED_KEY", "secret", nil)
+	assert.Nil(t, authCtx)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// both secrets of a rotated key work during the overlap
+	_, authCtx = serve(http.MethodGet, "127.0.0.1:1234", "ROTATED_KEY", "new-secret", nil)
+	assert.True(t, authCtx.IsAuthorized)
+	_, authCtx = serve(http.MethodGet, "127.0.0.1:1234", "ROTATED_KEY", "old-secret", nil)
+	assert.True(t, authCtx.IsAuthorized)
+	_, authCtx = serve(http.MethodGet, "127.0.0.1:1234", "ROTATED_KEY", "other-secret", nil)
+	assert.False(t, authCtx.IsAuthorized)
+}
+
+func TestApiKeyAuthorizationMiddlewareAdapter_ScopeClientAndUser(t *testing.T) {
+	common.Logger = log.Get()
+	t.Setenv(constants.TRUSTED_PROXIES_ENV_VAR, "10.0.0.1")
+	ctx := basecontext.NewBaseContext()
+	ctx.DisableLog()
+	db := data.NewJsonDatabase(ctx, filepath.Join(t.TempDir(), "test_db.json"))
+	sp := serviceprovider.NewMockProvider()
+	sp.JsonDatabase = db
+	require.NoError(t, db.Connect(ctx))
+	ratelimit.New(ctx).Options.WithEnabled(false)
+
+	role, err := db.CreateRole(ctx, models.Role{Name: "TESTER"})
+	require.NoError(t, err)
+	claim, err := db.CreateClaim(ctx, models.Claim{Name: "SCOPE_TESTER"})
+	require.NoError(t, err)
+	_, err = db.CreateUser(ctx, models.User{
+		ID: "disabled-user", Username: "disabled", Name: "Disabled", Email: "disabled@example.com", Disabled: true,
+		Roles: []models.Role{*role}, Claims: []models.Claim{*claim},
+	})
+	require.NoError(t, err)
+	_, err = db.CreateApiKey(ctx, models.ApiKey{
+		ID: "cidr-id", Name: "Cidr", Key: "CIDR_KEY", Secret: "secret",
+		Scope: &models.ApiKeyScope{SourceCidrs: []string{"192.168.1.0/24"}},
+	})
+	require.NoError(t, err)
+	_, err = db.CreateApiKey(ctx, models.ApiKey{
+		ID: "disabled-id", Name: "Disabled", Key: "DISABLED_KEY", Secret: "secret", UserID: "disabled-user",
+		Scope: &models.ApiKeyScope{Methods: []string{http.MethodGet}},
+	})
+	require.NoError(t, err)
+
+	serve := func(key string, forwardedFor string) *basecontext.AuthorizationContext {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/machines", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		req.Header.Set("X-Api-Key", base64.StdEncoding.EncodeToString([]byte(key+":secret")))
+
+		var authCtx *basecontext.AuthorizationContext
+		Adapt(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authCtx, _ = r.Context().Value(constants.AUTHORIZATION_CONTEXT_KEY).(*basecontext.AuthorizationContext)
+		}), ApiKeyAuthorizationMiddlewareAdapter(nil, nil, ComparisonOperationAnd, ComparisonOperationAnd)).ServeHTTP(httptest.NewRecorder(), req)
+		return authCtx
+	}
+
+	// the source of the scope is the client behind the trusted proxy
+	assert.True(t, serve("CIDR_KEY", "192.168.1.10").IsAuthorized)
+	authCtx := serve("CIDR_KEY", "172.16.0.10")
+	assert.False(t, authCtx.IsAuthorized)
+	assert.Contains(t, authCtx.AuthorizationError.ErrorDescription, "cannot be used from 172.16.0.10")
+	assert.False(t, serve("CIDR_KEY", "").IsAuthorized)
+
+	// a scope without claims still checks its user
+	authCtx = serve("DISABLED_KEY", "")
+	assert.False(t, authCtx.IsAuthorized)
+	assert.Contains(t, authCtx.AuthorizationError.ErrorDescription, "the user of the Api Key is disabled")
+}
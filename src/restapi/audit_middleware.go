@@ -0,0 +1,202 @@
+package restapi
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/audit"
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/gorilla/mux"
+)
+
+var versionSegmentRegex = regexp.MustCompile(`^v\d+$`)
+
+// AuditMiddlewareAdapter records the requests that change something in the
+// audit log. It needs to run after the authorization context is added so the
+// caller is known once the request is handled
+func AuditMiddlewareAdapter() Adapter {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auditLog := audit.Get()
+			if auditLog == nil || !isMutatingMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, requestAudit := audit.NewRequestContext(r.Context())
+			r = r.WithContext(ctx)
+			writer := &auditResponseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(writer, r)
+
+			entry := NewAuditEntry(r, requestAudit, writer.status)
+			if err := auditLog.Record(entry); err != nil {
+				basecontext.NewBaseContextFromRequest(r).LogErrorf("[Audit] Error recording %v %v: %v", r.Method, r.URL.Path, err)
+			}
+		})
+	}
+}
+
+// AuditAuthenticationHandler records every call of a handler that signs a
+// caller in, these routes are not authorized so the audit middleware does not
+// see them. The handler sets the actor with audit.SetActor once it knows who
+// is signing in
+func AuditAuthenticationHandler(action string, handler ControllerHandler) ControllerHandler {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auditLog := audit.Get()
+		if auditLog == nil {
+			handler(w, r)
+			return
+		}
+
+		ctx, requestAudit := audit.NewRequestContext(r.Context())
+		r = r.WithContext(ctx)
+		writer := &auditResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		handler(writer, r)
+
+		entry := NewAuditEntry(r, requestAudit, writer.status)
+		entry.Action = action
+		if err := auditLog.Record(entry); err != nil {
+			basecontext.NewBaseContextFromRequest(r).LogErrorf("[Audit] Error recording %v %v: %v", r.Method, r.URL.Path, err)
+		}
+	}
+}
+
+// NewAuditEntry builds the audit entry of a handled request, the resource id
+// set by the handler takes precedence over the id of the route
+func NewAuditEntry(r *http.Request, requestAudit *audit.RequestAudit, status int) audit.Entry {
+	entry := audit.Entry{
+		ActorType:  audit.ActorTypeAnonymous,
+		SourceIp:   GetClientIp(r),
+		RequestId:  GetRequestId(r),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Action:     getAuditAction(r.Method),
+		StatusCode: status,
+		Outcome:    audit.OutcomeSuccess,
+	}
+
+	if authorizationContext := basecontext.GetAuthorizationContext(r.Context()); authorizationContext != nil {
+		switch {
+		case authorizationContext.User != nil:
+			entry.ActorType = audit.ActorTypeUser
+			entry.Actor = authorizationContext.User.Username
+		case authorizationContext.AuthorizedBy == "ApiKeyAuthorization":
+			entry.ActorType = audit.ActorTypeApiKey
+			entry.Actor = authorizationContext.ApiKeyName
+		}
+	}
+
+	entry.ResourceType, entry.ResourceId = getAuditResource(r)
+	if requestAudit != nil {
+		if entry.Actor == "" && requestAudit.Actor != "" {
+			entry.ActorType = requestAudit.ActorType
+			entry.Actor = requestAudit.Actor
+		}
+		if requestAudit.ResourceId != "" {
+			entry.ResourceId = requestAudit.ResourceId
+		}
+		if len(requestAudit.Changes) > 0 {
+			entry.Changes = requestAudit.Changes
+		}
+	}
+
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		entry.Outcome = audit.OutcomeDenied
+	case status >= http.StatusBadRequest:
+		entry.Outcome = audit.OutcomeFailure
+	}
+
+	return entry
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+func getAuditAction(method string) string {
+	switch method {
+	case http.MethodPost:
+		return audit.ActionCreate
+	case http.MethodDelete:
+		return audit.ActionDelete
+	}
+
+	return audit.ActionUpdate
+}
+
+// getAuditResource returns the static segments of the route without the api
+// prefix and version, /api/v1/auth/users/{id} is auth/users, and the id
+// variable of the route
+func getAuditResource(r *http.Request) (string, string) {
+	path := r.URL.Path
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			path = template
+		}
+	}
+	prefix := strings.Trim(constants.DEFAULT_API_PREFIX, "/")
+	if globalHttpListener != nil && globalHttpListener.Options != nil && globalHttpListener.Options.ApiPrefix != "" {
+		prefix = strings.Trim(globalHttpListener.Options.ApiPrefix, "/")
+	}
+
+	segments := make([]string, 0)
+	for i, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if segment == "" || strings.HasPrefix(segment, "{") || versionSegmentRegex.MatchString(segment) {
+			continue
+		}
+		if i == 0 && prefix != "" && segment == prefix {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+
+	return strings.Join(segments, "/"), mux.Vars(r)["id"]
+}
+
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(content []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(content)
+}
+
+func (w *auditResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *auditResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+
+	return nil, nil, errors.New("the response writer does not support hijacking")
+}
+
+func (w *auditResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,133 @@
+package restapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/audit"
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/models"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveAuditRoute(t *testing.T, method string, template string, path string, authContext *basecontext.AuthorizationContext, handler http.HandlerFunc) audit.Entry {
+	var entry audit.Entry
+	router := mux.NewRouter()
+	router.HandleFunc(template, func(w http.ResponseWriter, r *http.Request) {
+		ctx, requestAudit := audit.NewRequestContext(r.Context())
+		r = r.WithContext(ctx)
+		writer := &auditResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		handler(writer, r)
+		entry = NewAuditEntry(r, requestAudit, writer.status)
+	}).Methods(method)
+
+	request := httptest.NewRequest(method, path, nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+	if authContext != nil {
+		request = request.WithContext(context.WithValue(request.Context(), constants.AUTHORIZATION_CONTEXT_KEY, authContext))
+	}
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	return entry
+}
+
+func TestNewAuditEntry_User(t *testing.T) {
+	authContext := &basecontext.AuthorizationContext{User: &models.ApiUser{Username: "alice"}}
+	entry := serveAuditRoute(t, http.MethodDelete, "/api/v1/auth/users/{id}", "/api/v1/auth/users/42", authContext, func(w http.ResponseWriter, r *http.Request) {
+		audit.RecordChange(r.Context(), map[string]string{"name": "alice"}, nil)
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	assert.Equal(t, "alice", entry.Actor)
+	assert.Equal(t, audit.ActorTypeUser, entry.ActorType)
+	assert.Equal(t, "auth/users", entry.ResourceType)
+	assert.Equal(t, "42", entry.ResourceId)
+	assert.Equal(t, audit.ActionDelete, entry.Action)
+	assert.Equal(t, audit.OutcomeSuccess, entry.Outcome)
+	assert.Equal(t, http.StatusAccepted, entry.StatusCode)
+	assert.Equal(t, "10.0.0.1", entry.SourceIp)
+	require.Contains(t, entry.Changes, "name")
+	assert.Equal(t, "alice", entry.Changes["name"].Before)
+}
+
+func TestNewAuditEntry_ApiKeyCreate(t *testing.T) {
+	authContext := &basecontext.AuthorizationContext{AuthorizedBy: "ApiKeyAuthorization", ApiKeyName: "ci"}
+	entry := serveAuditRoute(t, http.MethodPost, "/api/v1/catalog/{catalogId}/{version}/{architecture}/taint", "/api/v1/catalog/ubuntu/v2/arm64/taint", authContext, func(w http.ResponseWriter, r *http.Request) {
+		audit.SetResourceId(r.Context(), "ubuntu")
+		_, _ = w.Write([]byte("{}"))
+	})
+
+	assert.Equal(t, "ci", entry.Actor)
+	assert.Equal(t, audit.ActorTypeApiKey, entry.ActorType)
+	assert.Equal(t, "catalog/taint", entry.ResourceType)
+	assert.Equal(t, "ubuntu", entry.ResourceId)
+	assert.Equal(t, audit.ActionCreate, entry.Action)
+	assert.Equal(t, http.StatusOK, entry.StatusCode)
+}
+
+func TestNewAuditEntry_Outcome(t *testing.T) {
+	entry := serveAuditRoute(t, http.MethodPut, "/api/v1/config", "/api/v1/config", nil, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	assert.Equal(t, audit.ActorTypeAnonymous, entry.ActorType)
+	assert.Equal(t, audit.ActionUpdate, entry.Action)
+	assert.Equal(t, audit.OutcomeDenied, entry.Outcome)
+
+	entry = serveAuditRoute(t, http.MethodPut, "/api/v1/config", "/api/v1/config", nil, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.WriteHeader(http.StatusOK)
+	})
+	assert.Equal(t, audit.OutcomeFailure, entry.Outcome)
+	assert.Equal(t, http.StatusBadRequest, entry.StatusCode)
+}
+
+func TestGetClientIp_UntrustedProxyIgnoresHeaders(t *testing.T) {
+	t.Setenv(constants.TRUSTED_PROXIES_ENV_VAR, "")
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+	assert.Equal(t, "10.0.0.1", GetClientIp(request))
+
+	request.Header.Set("X-Real-IP", "10.0.0.2")
+	request.Header.Set("X-Forwarded-For", "10.0.0.3, 10.0.0.4")
+	assert.Equal(t, "10.0.0.1", GetClientIp(request))
+}
+
+func TestGetClientIp_TrustedProxy(t *testing.T) {
+	t.Setenv(constants.TRUSTED_PROXIES_ENV_VAR, "10.0.0.1, 192.168.0.0/16")
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+
+	request.Header.Set("X-Real-IP", "10.0.0.2")
+	assert.Equal(t, "10.0.0.2", GetClientIp(request))
+
+	// the address spoofed by the caller on the left is skipped
+	request.Header.Set("X-Forwarded-For", "1.1.1.1, 10.0.0.3, 192.168.1.1")
+	assert.Equal(t, "10.0.0.3", GetClientIp(request))
+
+	request.Header.Set("X-Forwarded-For", "192.168.1.2, 192.168.1.1")
+	assert.Equal(t, "192.168.1.2", GetClientIp(request))
+}
+
+func TestNewAuditEntry_ActorFromRequestAudit(t *testing.T) {
+	entry := serveAuditRoute(t, http.MethodPost, "/api/v1/auth/token", "/api/v1/auth/token", nil, func(w http.ResponseWriter, r *http.Request) {
+		audit.SetActor(r.Context(), audit.ActorTypeUser, "alice")
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	assert.Equal(t, "alice", entry.Actor)
+	assert.Equal(t, audit.ActorTypeUser, entry.ActorType)
+	assert.Equal(t, "auth/token", entry.ResourceType)
+	assert.Equal(t, audit.OutcomeDenied, entry.Outcome)
+
+	// the authorization context takes precedence
+	authContext := &basecontext.AuthorizationContext{User: &models.ApiUser{Username: "bob"}}
+	entry = serveAuditRoute(t, http.MethodPost, "/api/v1/auth/token", "/api/v1/auth/token", authContext, func(w http.ResponseWriter, r *http.Request) {
+		audit.SetActor(r.Context(), audit.ActorTypeUser, "alice")
+	})
+	assert.Equal(t, "bob", entry.Actor)
+}
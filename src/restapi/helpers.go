@@ -1,8 +1,11 @@
 package restapi
 
 import (
+	"net"
 	"net/http"
+	"strings"
 
+	"github.com/Parallels/prl-devops-service/config"
 	"github.com/Parallels/prl-devops-service/constants"
 )
 
@@ -25,3 +28,82 @@ func HasAuthorizationHeader(r *http.Request) bool {
 func HasApiKeyAuthorizationHeader(r *http.Request) bool {
 	return r.Header.Get("X-Api-Key") != ""
 }
+
+// GetClientIp returns the address of the caller, the forwarded headers are
+// only honoured when the connection comes from one of the TRUSTED_PROXIES so
+// callers cannot spoof their address
+func GetClientIp(r *http.Request) string {
+	remoteIp := GetRemoteIp(r)
+	trustedProxies := parseTrustedProxies(config.Get().TrustedProxies())
+	if !isTrustedProxy(trustedProxies, remoteIp) {
+		return remoteIp
+	}
+
+	// the proxies append the address they received the request from, the
+	// first one from the right that is not a trusted proxy is the caller
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		addresses := strings.Split(forwarded, ",")
+		for i := len(addresses) - 1; i >= 0; i-- {
+			address := strings.TrimSpace(addresses[i])
+			if address != "" && !isTrustedProxy(trustedProxies, address) {
+				return address
+			}
+		}
+		if address := strings.TrimSpace(addresses[0]); address != "" {
+			return address
+		}
+	}
+	if realIp := r.Header.Get("X-Real-IP"); realIp != "" {
+		return strings.TrimSpace(realIp)
+	}
+
+	return remoteIp
+}
+
+// parseTrustedProxies reads the addresses and CIDR ranges of the trusted
+// proxies, invalid entries are ignored
+func parseTrustedProxies(values []string) []*net.IPNet {
+	result := make([]*net.IPNet, 0)
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			if ip := net.ParseIP(value); ip != nil {
+				bits := 128
+				if ip.To4() != nil {
+					ip = ip.To4()
+					bits = 32
+				}
+				result = append(result, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			}
+			continue
+		}
+		if _, network, err := net.ParseCIDR(value); err == nil {
+			result = append(result, network)
+		}
+	}
+
+	return result
+}
+
+func isTrustedProxy(trustedProxies []*net.IPNet, address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetRemoteIp returns the address of the connection, unlike GetClientIp it
+// ignores the forwarded headers so it cannot be spoofed by the caller
+func GetRemoteIp(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+
+	return r.RemoteAddr
+}
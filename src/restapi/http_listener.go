@@ -115,6 +115,7 @@ func buildCORSHandler(cfg *config.Config, handler http.Handler) http.Handler {
 		handlers.AllowedOrigins(origins),
 		handlers.AllowedHeaders(headers),
 		handlers.AllowedMethods(methods),
+		handlers.ExposedHeaders([]string{TotalCountHeader, LinkHeader}),
 	)(handler)
 }
 
@@ -289,6 +290,7 @@ func (l *HttpListener) AddHandler(c ControllerHandler, path string, methods ...s
 
 	adapters := make([]Adapter, 0)
 	adapters = append(adapters, l.DefaultAdapters...)
+	adapters = append(adapters, RateLimitMiddlewareAdapter())
 
 	if l.GetApiPrefix() != "" && !strings.HasPrefix(path, l.Options.ApiPrefix) {
 		path = http_helper.JoinUrl(l.GetApiPrefix(), path)
@@ -354,11 +356,12 @@ func (l *HttpListener) AddAuthorizedHandlerWithExtraAdapters(
 	adapters = append(adapters, l.DefaultAdapters...)
 	adapters = append(adapters,
 		AddAuthorizationContextMiddlewareAdapter(),
+		AuditMiddlewareAdapter(),
 		TokenAuthorizationMiddlewareAdapter(roles, claims, roleComparisonOperation, claimComparisonOperation),
 		ApiKeyAuthorizationMiddlewareAdapter(roles, claims, roleComparisonOperation, claimComparisonOperation),
 		XClaimsMiddlewareAdapter())
 	adapters = append(adapters, extraAdapters...)
-	adapters = append(adapters, EndAuthorizationMiddlewareAdapter())
+	adapters = append(adapters, RateLimitMiddlewareAdapter(), EndAuthorizationMiddlewareAdapter())
 
 	if l.GetApiPrefix() != "" && !strings.HasPrefix(path, l.Options.ApiPrefix) {
 		path = http_helper.JoinUrl(l.GetApiPrefix(), path)
@@ -389,9 +392,11 @@ func (l *HttpListener) AddAuthorizedHandlerWithRolesAndClaims(
 	adapters = append(adapters, l.DefaultAdapters...)
 	adapters = append(adapters,
 		AddAuthorizationContextMiddlewareAdapter(),
+		AuditMiddlewareAdapter(),
 		TokenAuthorizationMiddlewareAdapter(roles, claims, roleComparisonOperation, claimComparisonOperation),
 		ApiKeyAuthorizationMiddlewareAdapter(roles, claims, roleComparisonOperation, claimComparisonOperation),
 		XClaimsMiddlewareAdapter(),
+		RateLimitMiddlewareAdapter(),
 		EndAuthorizationMiddlewareAdapter())
 
 	if l.GetApiPrefix() != "" && !strings.HasPrefix(path, l.Options.ApiPrefix) {
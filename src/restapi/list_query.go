@@ -0,0 +1,182 @@
+package restapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/data"
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+const (
+	TotalCountHeader = "X-Total-Count"
+	LinkHeader       = "Link"
+)
+
+// ParseListQuery reads the filter, sort, page, page_size, cursor and fields
+// query parameters of a list request
+func ParseListQuery(r *http.Request) (*data.ListQuery, error) {
+	values := r.URL.Query()
+	query := &data.ListQuery{
+		Sort:   data.ParseSort(values.Get("sort")),
+		Cursor: strings.TrimSpace(values.Get("cursor")),
+		Fields: make([]string, 0),
+	}
+
+	filter, err := data.ParseFilterExpression(values.Get("filter"))
+	if err != nil {
+		return nil, err
+	}
+	query.Filter = filter
+
+	if query.Page, err = parseListQueryInt(values, "page"); err != nil {
+		return nil, err
+	}
+	if query.PageSize, err = parseListQueryInt(values, "page_size"); err != nil {
+		return nil, err
+	}
+	if query.Cursor != "" && query.Page > 0 {
+		return nil, errors.NewWithCode("page and cursor cannot be used together", 400)
+	}
+
+	for _, field := range strings.Split(values.Get("fields"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			query.Fields = append(query.Fields, field)
+		}
+	}
+
+	return query, nil
+}
+
+func parseListQueryInt(values url.Values, name string) (int, error) {
+	value := values.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil || result < 0 {
+		return 0, errors.NewWithCodef(400, "invalid %v, it must be a positive number", name)
+	}
+
+	return result, nil
+}
+
+// WriteListHeaders adds the X-Total-Count and Link headers of a page, it needs
+// to be called before writing the status code
+func WriteListHeaders(w http.ResponseWriter, r *http.Request, page *data.ListPage) {
+	if page == nil {
+		return
+	}
+
+	w.Header().Set(TotalCountHeader, strconv.Itoa(page.Total))
+	if !page.Paginated {
+		return
+	}
+
+	links := make([]string, 0)
+	addLink := func(rel string, params map[string]string) {
+		linkUrl := *r.URL
+		query := linkUrl.Query()
+		for key, value := range params {
+			query.Del(key)
+			if value != "" {
+				query.Set(key, value)
+			}
+		}
+		linkUrl.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf("<%s>; rel=\"%s\"", linkUrl.RequestURI(), rel))
+	}
+
+	pageSize := strconv.Itoa(page.PageSize)
+	if page.UsesCursor {
+		if page.NextCursor != "" {
+			addLink("next", map[string]string{"cursor": page.NextCursor, "page_size": pageSize})
+		}
+	} else {
+		addLink("first", map[string]string{"page": "1", "page_size": pageSize})
+		if page.Page > 1 {
+			previous := page.Page - 1
+			if page.TotalPages > 0 && previous > page.TotalPages {
+				previous = page.TotalPages
+			}
+			addLink("prev", map[string]string{"page": strconv.Itoa(previous), "page_size": pageSize})
+		}
+		if page.Page < page.TotalPages {
+			addLink("next", map[string]string{"page": strconv.Itoa(page.Page + 1), "page_size": pageSize})
+		}
+		if page.TotalPages > 0 {
+			addLink("last", map[string]string{"page": strconv.Itoa(page.TotalPages), "page_size": pageSize})
+		}
+	}
+
+	if len(links) > 0 {
+		w.Header().Set(LinkHeader, strings.Join(links, ", "))
+	}
+}
+
+// SelectFields keeps only the requested json fields of each item, nested
+// fields can be selected with dots. Without fields the items are returned as is.
+func SelectFields[T interface{}](items []T, fields []string) ([]interface{}, error) {
+	result := make([]interface{}, 0, len(items))
+	if len(fields) == 0 {
+		for _, item := range items {
+			result = append(result, item)
+		}
+		return result, nil
+	}
+
+	for _, item := range items {
+		content, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal(content, &object); err != nil {
+			return nil, errors.NewWithCode("fields can only be selected on objects", 400)
+		}
+
+		selected := make(map[string]interface{})
+		for _, field := range fields {
+			selectField(object, selected, strings.Split(field, "."))
+		}
+		result = append(result, selected)
+	}
+
+	return result, nil
+}
+
+func selectField(source map[string]interface{}, target map[string]interface{}, path []string) {
+	var key string
+	var value interface{}
+	for k, v := range source {
+		if strings.EqualFold(k, path[0]) {
+			key = k
+			value = v
+			break
+		}
+	}
+	if key == "" {
+		return
+	}
+
+	if len(path) == 1 {
+		target[key] = value
+		return
+	}
+
+	nestedSource, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	nestedTarget, ok := target[key].(map[string]interface{})
+	if !ok {
+		nestedTarget = make(map[string]interface{})
+		target[key] = nestedTarget
+	}
+	selectField(nestedSource, nestedTarget, path[1:])
+}
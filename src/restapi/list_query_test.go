@@ -0,0 +1,74 @@
+package restapi
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/data"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseListQuery(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/machines?filter=state%20%3D%20running&sort=-name&page=2&page_size=10&fields=id,name", nil)
+
+	query, err := ParseListQuery(r)
+	require.NoError(t, err)
+
+	assert.NotNil(t, query.Filter)
+	assert.Equal(t, []data.Order{{Property: "name", Direction: data.OrderDirectionDesc}}, query.Sort)
+	assert.Equal(t, 2, query.Page)
+	assert.Equal(t, 10, query.PageSize)
+	assert.Equal(t, []string{"id", "name"}, query.Fields)
+}
+
+func TestParseListQuery_Invalid(t *testing.T) {
+	for _, url := range []string{"/machines?page=a", "/machines?page_size=-1", "/machines?filter=state", "/machines?page=1&cursor=abc"} {
+		_, err := ParseListQuery(httptest.NewRequest("GET", url, nil))
+		assert.Equal(t, 400, errors.GetSystemErrorCode(err), url)
+	}
+}
+
+func TestWriteListHeaders(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/machines?page=2&page_size=10&sort=name", nil)
+	w := httptest.NewRecorder()
+
+	WriteListHeaders(w, r, &data.ListPage{Total: 35, Page: 2, PageSize: 10, TotalPages: 4, Paginated: true})
+
+	assert.Equal(t, "35", w.Header().Get(TotalCountHeader))
+	link := w.Header().Get(LinkHeader)
+	assert.Contains(t, link, `</api/v1/machines?page=1&page_size=10&sort=name>; rel="first"`)
+	assert.Contains(t, link, `</api/v1/machines?page=1&page_size=10&sort=name>; rel="prev"`)
+	assert.Contains(t, link, `</api/v1/machines?page=3&page_size=10&sort=name>; rel="next"`)
+	assert.Contains(t, link, `</api/v1/machines?page=4&page_size=10&sort=name>; rel="last"`)
+}
+
+func TestWriteListHeaders_NotPaginated(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	WriteListHeaders(w, httptest.NewRequest("GET", "/machines", nil), &data.ListPage{Total: 3})
+
+	assert.Equal(t, "3", w.Header().Get(TotalCountHeader))
+	assert.Empty(t, w.Header().Get(LinkHeader))
+}
+
+func TestSelectFields(t *testing.T) {
+	type nested struct {
+		Family string `json:"family"`
+		Bits   int    `json:"bits"`
+	}
+	type item struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Arch nested `json:"arch"`
+	}
+
+	result, err := SelectFields([]item{{ID: "1", Name: "vm", Arch: nested{Family: "arm", Bits: 64}}}, []string{"id", "arch.family", "missing"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"id":   "1",
+		"arch": map[string]interface{}{"family": "arm"},
+	}}, result)
+}
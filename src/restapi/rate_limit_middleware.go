@@ -0,0 +1,84 @@
+package restapi
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/security/ratelimit"
+)
+
+// RateLimitMiddlewareAdapter rejects the requests over the configured limits
+// with a 429. On authorized routes it needs to run after the authorization
+// middlewares so the user or api key of the request is known
+func RateLimitMiddlewareAdapter() Adapter {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limiter := ratelimit.Get()
+			authorizationContext := basecontext.GetAuthorizationContext(r.Context())
+			hasApiKeyLimit := authorizationContext != nil && authorizationContext.ApiKeyRateLimit > 0
+			if !limiter.IsEnabled() && !hasApiKeyLimit {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			request := ratelimit.Request{
+				Ip:   GetClientIp(r),
+				Path: getRateLimitPath(r.URL.Path),
+			}
+			if authorizationContext != nil && authorizationContext.IsAuthorized {
+				switch {
+				case authorizationContext.AuthorizedBy == "ApiKeyAuthorization":
+					request.ApiKeyID = authorizationContext.ApiKeyName
+					if hasApiKeyLimit {
+						request.ApiKeyLimit = ratelimit.Limit{Requests: authorizationContext.ApiKeyRateLimit, Period: time.Minute}
+					}
+				case authorizationContext.User != nil:
+					request.UserID = authorizationContext.User.ID
+					request.Username = authorizationContext.User.Username
+					request.Email = authorizationContext.User.Email
+				}
+			}
+
+			decision := limiter.Allow(request)
+			if !decision.Allowed {
+				baseCtx := basecontext.NewBaseContextFromRequest(r)
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(decision.RetryAfter.Seconds())))))
+				w.WriteHeader(http.StatusTooManyRequests)
+				_ = json.NewEncoder(w).Encode(models.ApiErrorResponse{
+					Message: "The " + decision.Scope + " rate limit was exceeded",
+					Code:    http.StatusTooManyRequests,
+				})
+				baseCtx.LogInfof("[RateLimit] Request %v %v rejected, the %v limit was exceeded", r.Method, r.URL.Path, decision.Scope)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// getRateLimitPath removes the api prefix and the version from the path so
+// the route groups do not depend on them
+func getRateLimitPath(path string) string {
+	prefix := strings.Trim(constants.DEFAULT_API_PREFIX, "/")
+	if globalHttpListener != nil && globalHttpListener.Options != nil && globalHttpListener.Options.ApiPrefix != "" {
+		prefix = strings.Trim(globalHttpListener.Options.ApiPrefix, "/")
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 0 && prefix != "" && segments[0] == prefix {
+		segments = segments[1:]
+	}
+	if len(segments) > 0 && versionSegmentRegex.MatchString(segments[0]) {
+		segments = segments[1:]
+	}
+
+	return "/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,85 @@
+package restapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/security/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitMiddlewareAdapter(t *testing.T) {
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+	limiter := ratelimit.New(ctx)
+	limiter.Options.
+		WithEnabled(true).
+		WithPerUser(ratelimit.Limit{Requests: 1, Period: time.Minute}).
+		WithPerIp(ratelimit.Limit{}).
+		WithRouteGroup(ratelimit.RouteGroup{Name: "machines", Paths: []string{"/machines"}, Limit: ratelimit.Limit{Requests: 1, Period: 30 * time.Second}})
+	defer limiter.Options.WithEnabled(false)
+
+	handler := RateLimitMiddlewareAdapter()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(path string, userId string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		authContext := &basecontext.AuthorizationContext{IsAuthorized: true, User: &models.ApiUser{ID: userId}}
+		request = request.WithContext(context.WithValue(request.Context(), constants.AUTHORIZATION_CONTEXT_KEY, authContext))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/api/v1/machines", "first").Code)
+
+	response := serve("/api/v1/machines/123", "first")
+	assert.Equal(t, http.StatusTooManyRequests, response.Code)
+	assert.Equal(t, "30", response.Header().Get("Retry-After"))
+	assert.Contains(t, response.Body.String(), "machines route group")
+
+	// each user has its own buckets
+	assert.Equal(t, http.StatusOK, serve("/api/v1/machines", "second").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve("/api/v1/catalog", "second").Code)
+}
+
+func TestRateLimitMiddlewareAdapter_ClientBehindProxy(t *testing.T) {
+	t.Setenv(constants.TRUSTED_PROXIES_ENV_VAR, "10.0.0.1")
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+	limiter := ratelimit.New(ctx)
+	limiter.Options.
+		WithEnabled(true).
+		WithPerUser(ratelimit.Limit{}).
+		WithPerIp(ratelimit.Limit{Requests: 1, Period: time.Minute})
+	defer limiter.Options.WithEnabled(false)
+
+	handler := RateLimitMiddlewareAdapter()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(client string) int {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/catalog", nil)
+		request.RemoteAddr = "10.0.0.1:1234"
+		request.Header.Set("X-Forwarded-For", client)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	// the clients behind the trusted proxy have their own buckets
+	assert.Equal(t, http.StatusOK, serve("192.168.1.10"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("192.168.1.10"))
+	assert.Equal(t, http.StatusOK, serve("192.168.1.11"))
+}
+
+func TestGetRateLimitPath(t *testing.T) {
+	assert.Equal(t, "/machines/123", getRateLimitPath("/api/v1/machines/123"))
+	assert.Equal(t, "/orchestrator/machines", getRateLimitPath("/api/orchestrator/machines"))
+	assert.Equal(t, "/health/probe", getRateLimitPath("/health/probe"))
+}
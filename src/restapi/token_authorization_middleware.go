@@ -6,13 +6,16 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/Parallels/prl-devops-service/basecontext"
 	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
 	data_modules "github.com/Parallels/prl-devops-service/data/models"
 	"github.com/Parallels/prl-devops-service/mappers"
 	"github.com/Parallels/prl-devops-service/models"
 	"github.com/Parallels/prl-devops-service/security/jwt"
+	"github.com/Parallels/prl-devops-service/security/oidc"
 	"github.com/Parallels/prl-devops-service/serviceprovider"
 
 	"github.com/cjlapao/common-go/helper/http_helper"
@@ -98,10 +101,31 @@ func TokenAuthorizationMiddlewareAdapter(
 			authorized := true
 			baseCtx.LogInfof("Token Authorization layer started")
 
+			// Tokens issued by the identity provider are validated against its
+			// signing keys instead of our own
+			var identity *oidc.Identity
+			oidcSvc := oidc.Get()
+			if oidcSvc != nil && oidcSvc.IsIssuedBy(jwt_token) {
+				var err error
+				identity, err = oidcSvc.ValidateToken(jwt_token)
+				if err != nil {
+					authorized = false
+					response := models.OAuthErrorResponse{
+						Error:            models.OAuthUnauthorizedClient,
+						ErrorDescription: err.Error(),
+					}
+					authorizationContext.IsAuthorized = false
+					authorizationContext.AuthorizationError = &response
+					baseCtx.LogErrorf("Request failed to authorize, %v", response.ErrorDescription)
+				} else {
+					authorizationContext.Issuer = oidcSvc.Options.IssuerUrl
+				}
+			}
+
 			// Validating userToken against the keys
 			var token *jwt.JwtSystemToken
 			// Validating if the token can be parsed
-			if authorized {
+			if authorized && identity == nil {
 				jwtSvc := jwt.Get()
 				var err error
 				token, err = jwtSvc.Parse(jwt_token)
@@ -118,7 +142,7 @@ func TokenAuthorizationMiddlewareAdapter(
 			}
 
 			// Validating if the token is valid
-			if authorized {
+			if authorized && identity == nil {
 				valid, err := token.Valid()
 				if err != nil || !valid {
 					authorized = false
@@ -138,7 +162,7 @@ func TokenAuthorizationMiddlewareAdapter(
 
 			// Validating if the token has the correct email
 			var email interface{}
-			if authorized {
+			if authorized && identity == nil {
 				var err error
 				email, err = token.GetClaim("email")
 				if err != nil {
@@ -171,8 +195,21 @@ func TokenAuthorizationMiddlewareAdapter(
 					baseCtx.LogErrorf("Request failed to authorize, %v", response.ErrorDescription)
 				}
 
-				// validating if the user exists
-				if authorized {
+				// validating if the user exists, the users of the identity
+				// provider are created on their first request
+				if authorized && identity != nil {
+					dbUser, err = oidcSvc.ProvisionUser(baseCtx, db, identity)
+					if err != nil || dbUser == nil {
+						authorized = false
+						response := models.OAuthErrorResponse{
+							Error:            models.OAuthUnauthorizedClient,
+							ErrorDescription: fmt.Sprintf("Error provisioning user, %v", err),
+						}
+						authorizationContext.IsAuthorized = false
+						authorizationContext.AuthorizationError = &response
+						baseCtx.LogErrorf("Request failed to authorize, %v", response.ErrorDescription)
+					}
+				} else if authorized {
 					dbUser, err = db.GetUser(baseCtx, email.(string))
 					if err != nil || dbUser == nil {
 						authorized = false
@@ -186,6 +223,38 @@ func TokenAuthorizationMiddlewareAdapter(
 					}
 				}
 
+				// disabled users keep their tokens until they expire, they
+				// are rejected here so the access is revoked straight away
+				if authorized && dbUser.Disabled {
+					authorized = false
+					response := models.OAuthErrorResponse{
+						Error:            models.OAuthUnauthorizedClient,
+						ErrorDescription: fmt.Sprintf("User %v is disabled", dbUser.Email),
+					}
+					authorizationContext.IsAuthorized = false
+					authorizationContext.AuthorizationError = &response
+					baseCtx.LogErrorf("Request failed to authorize, %v", response.ErrorDescription)
+				}
+
+				// logging out and revoking the sessions of the user rejects
+				// the tokens that were already issued
+				if authorized && token != nil {
+					if err := validateTokenRevocation(baseCtx, db, token, dbUser); err != nil {
+						authorized = false
+						response := models.OAuthErrorResponse{
+							Error:            models.OAuthUnauthorizedClient,
+							ErrorDescription: err.Error(),
+						}
+						authorizationContext.IsAuthorized = false
+						authorizationContext.AuthorizationError = &response
+						baseCtx.LogErrorf("Request failed to authorize, %v", response.ErrorDescription)
+					} else {
+						authorizationContext.SessionId = token.GetSessionId()
+						authorizationContext.TokenId = token.GetTokenId()
+						authorizationContext.TokenExpiresAt, _ = token.GetExpiresAt()
+					}
+				}
+
 				if authorized {
 					// Checking for the Super Duper User
 					authorizationContext.IsSuperUser = false
@@ -283,6 +352,9 @@ func TokenAuthorizationMiddlewareAdapter(
 
 				authorizationContext.IsAuthorized = true
 				authorizationContext.AuthorizedBy = "TokenAuthorization"
+				if identity != nil {
+					authorizationContext.AuthorizedBy = "OidcAuthorization"
+				}
 			}
 
 			ctx := context.WithValue(r.Context(), constants.AUTHORIZATION_CONTEXT_KEY, authorizationContext)
@@ -295,3 +367,34 @@ func TokenAuthorizationMiddlewareAdapter(
 		})
 	}
 }
+
+// validateTokenRevocation rejects the tokens in the revocation list, the
+// tokens of revoked sessions and the tokens issued before the sessions of the
+// user were revoked
+func validateTokenRevocation(ctx basecontext.ApiContext, db *data.JsonDatabase, token *jwt.JwtSystemToken, user *data_modules.User) error {
+	if db.IsTokenRevoked(ctx, token.GetTokenId()) {
+		return errors.New("token has been revoked")
+	}
+
+	if sessionId := token.GetSessionId(); sessionId != "" {
+		session, err := db.GetUserSession(ctx, sessionId)
+		if err != nil || session.Revoked || !strings.EqualFold(session.UserID, user.ID) {
+			return errors.New("session has been revoked")
+		}
+	}
+
+	if user.SessionsRevokedAt != "" {
+		revokedAt, err := time.Parse(time.RFC3339Nano, user.SessionsRevokedAt)
+		if err != nil {
+			return nil
+		}
+		// tokens issued before the issue time was added cannot be checked
+		// against the revocation time, they are rejected
+		issuedAt, err := token.GetIssuedAt()
+		if err != nil || issuedAt.Before(revokedAt) {
+			return errors.New("token has been revoked")
+		}
+	}
+
+	return nil
+}
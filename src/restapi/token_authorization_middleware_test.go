@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/Parallels/prl-devops-service/basecontext"
 	"github.com/Parallels/prl-devops-service/constants"
@@ -160,3 +161,83 @@ func TestTokenAuthorizationMiddlewareAdapter(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenAuthorizationMiddlewareAdapter_Revocation(t *testing.T) {
+	jwtSvc := jwt.Get()
+	sp := serviceprovider.NewMockProvider()
+	ctx := basecontext.NewBaseContext()
+	ctx.DisableLog()
+	db := data.NewJsonDatabase(ctx, filepath.Join(t.TempDir(), "data.json"))
+	sp.JsonDatabase = db
+	require.NoError(t, db.Connect(ctx))
+
+	for _, role := range constants.DefaultRoles {
+		_, _ = db.CreateRole(ctx, data_modules.Role{ID: role, Name: role})
+	}
+	for _, claim := range constants.DefaultClaims {
+		_, _ = db.CreateClaim(ctx, data_modules.Claim{ID: claim, Name: claim})
+	}
+	user, err := db.CreateUser(ctx, data_modules.User{
+		ID:       "revoked-user-id",
+		Email:    "revoked@example.com",
+		Username: "revokeduser",
+		Name:     "Revoked User",
+		Password: "password",
+	})
+	require.NoError(t, err)
+
+	session, err := db.CreateUserSession(ctx, data_modules.UserSession{
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(time.Hour).Format(time.RFC3339Nano),
+	}, "refresh-token")
+	require.NoError(t, err)
+
+	sign := func(claims map[string]interface{}) string {
+		claims["email"] = user.Email
+		token, err := jwtSvc.Sign(claims)
+		require.NoError(t, err)
+		return token
+	}
+
+	authorize := func(token string) *basecontext.AuthorizationContext {
+		var authCtx *basecontext.AuthorizationContext
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authCtx = basecontext.NewBaseContextFromRequest(r).GetAuthorizationContext()
+		})
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		AddAuthorizationContextMiddlewareAdapter()(TokenAuthorizationMiddlewareAdapter(nil, nil, ComparisonOperationAnd, ComparisonOperationAnd)(handler)).ServeHTTP(httptest.NewRecorder(), req)
+		return authCtx
+	}
+
+	sessionToken := sign(map[string]interface{}{"sid": session.ID})
+	authCtx := authorize(sessionToken)
+	require.True(t, authCtx.IsAuthorized)
+	assert.Equal(t, session.ID, authCtx.SessionId)
+	assert.NotEmpty(t, authCtx.TokenId)
+
+	// a revoked token is rejected
+	revokedToken := sign(map[string]interface{}{})
+	require.True(t, authorize(revokedToken).IsAuthorized)
+	parsed, err := jwtSvc.Parse(revokedToken)
+	require.NoError(t, err)
+	require.NoError(t, db.RevokeToken(ctx, data_modules.RevokedToken{ID: parsed.GetTokenId(), ExpiresAt: time.Now().UTC().Add(time.Hour).Format(time.RFC3339Nano)}))
+	authCtx = authorize(revokedToken)
+	assert.False(t, authCtx.IsAuthorized)
+	assert.Equal(t, "token has been revoked", authCtx.AuthorizationError.ErrorDescription)
+
+	// a token of a revoked session is rejected
+	require.NoError(t, db.RevokeUserSession(ctx, session.ID, constants.SESSION_REVOKED_LOGOUT))
+	authCtx = authorize(sessionToken)
+	assert.False(t, authCtx.IsAuthorized)
+	assert.Equal(t, "session has been revoked", authCtx.AuthorizationError.ErrorDescription)
+
+	// logging out everywhere rejects the tokens issued before, even the
+	// ones without a session
+	otherToken := sign(map[string]interface{}{})
+	require.True(t, authorize(otherToken).IsAuthorized)
+	_, err = db.RevokeUserSessions(ctx, user.ID, constants.SESSION_REVOKED_BY_USER)
+	require.NoError(t, err)
+	assert.False(t, authorize(otherToken).IsAuthorized)
+	assert.True(t, authorize(sign(map[string]interface{}{})).IsAuthorized)
+}
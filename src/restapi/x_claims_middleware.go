@@ -19,7 +19,8 @@ import (
 // Security: headers are only honoured when the request is already identified as
 // coming from a trusted source (IsMicroService=true or
 // X-SOURCE=CATALOG_MANAGER_REQUEST), preventing end-users from escalating their
-// own permissions by adding these headers directly.
+// own permissions by adding these headers directly. The claims of scoped api
+// keys are never overridden.
 func XClaimsMiddlewareAdapter() Adapter {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -34,7 +35,8 @@ func XClaimsMiddlewareAdapter() Adapter {
 			isTrustedSource := authCtx.IsMicroService ||
 				strings.EqualFold(r.Header.Get("X-SOURCE"), "CATALOG_MANAGER_REQUEST")
 
-			if !isTrustedSource {
+			// scoped api keys can never widen the claims of their scope
+			if !isTrustedSource || authCtx.IsScopedApiKey {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -0,0 +1,290 @@
+package reverse_proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/constants"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	global_models "github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+)
+
+// accessLog writes every request, connection or session of the hosts to the
+// service log, a JSON lines file or the reverse_proxy events, each one is
+// enabled on its own
+type accessLog struct {
+	ctx    basecontext.ApiContext
+	log    bool
+	events bool
+	toFile bool
+	file   *os.File
+	mu     sync.Mutex
+}
+
+func newAccessLog(ctx basecontext.ApiContext) *accessLog {
+	cfg := config.Get()
+	l := &accessLog{
+		ctx:    ctx,
+		log:    cfg.GetBoolKey(constants.REVERSE_PROXY_ACCESS_LOG_ENABLED_ENV_VAR),
+		events: cfg.GetBoolKey(constants.REVERSE_PROXY_ACCESS_LOG_EVENTS_ENV_VAR),
+	}
+
+	if path := cfg.GetKey(constants.REVERSE_PROXY_ACCESS_LOG_PATH_ENV_VAR); path != "" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+		if err != nil {
+			ctx.LogErrorf("[Reverse Proxy] Error opening the access log %s: %v", path, err)
+		} else {
+			l.file = file
+			l.toFile = true
+		}
+	}
+
+	return l
+}
+
+func (l *accessLog) Write(entry global_models.ReverseProxyAccessLogEvent) {
+	if l == nil || (!l.log && !l.events && !l.toFile) {
+		return
+	}
+	if entry.Timestamp == "" {
+		entry.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+
+	if l.log {
+		l.ctx.LogInfof("[Reverse Proxy] [Access] %s", formatAccessLogEntry(entry))
+	}
+
+	if l.toFile {
+		if line, err := json.Marshal(entry); err == nil {
+			l.mu.Lock()
+			if l.file != nil {
+				_, err = l.file.Write(append(line, '\n'))
+			}
+			l.mu.Unlock()
+			if err != nil {
+				l.ctx.LogErrorf("[Reverse Proxy] Error writing the access log: %v", err)
+			}
+		}
+	}
+
+	if l.events {
+		if emitter := serviceprovider.GetEventEmitter(); emitter != nil && emitter.IsRunning() {
+			msg := global_models.NewEventMessage(constants.EventTypeReverseProxy, constants.REVERSE_PROXY_ACCESS_LOG_EVENT, entry)
+			go func() { _ = emitter.Broadcast(msg) }()
+		}
+	}
+}
+
+func (l *accessLog) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// formatAccessLogEntry returns the entry as key=value pairs for the service
+// log, the empty values are left out
+func formatAccessLogEntry(entry global_models.ReverseProxyAccessLogEvent) string {
+	fields := []struct {
+		key   string
+		value string
+	}{
+		{"host", entry.Host},
+		{"route", entry.Route},
+		{"type", entry.TrafficType},
+		{"client", entry.ClientIp},
+		{"request_id", entry.RequestId},
+		{"method", entry.Method},
+		{"path", entry.Path},
+		{"upstream", entry.Upstream},
+		{"vm", entry.TargetVmId},
+	}
+
+	var builder strings.Builder
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		fmt.Fprintf(&builder, "%s=%q ", field.key, field.value)
+	}
+	if entry.Status > 0 {
+		fmt.Fprintf(&builder, "status=%d ", entry.Status)
+	}
+	fmt.Fprintf(&builder, "bytes_in=%d bytes_out=%d latency_ms=%.3f", entry.BytesIn, entry.BytesOut, entry.LatencyMs)
+	if entry.Error != "" {
+		fmt.Fprintf(&builder, " error=%q", entry.Error)
+	}
+
+	return builder.String()
+}
+
+func clientIp(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+
+	return remoteAddr
+}
+
+func (rps *ReverseProxyService) trafficStats() *trafficStats {
+	rps.statsOnce.Do(func() {
+		rps.stats = newTrafficStats()
+	})
+
+	return rps.stats
+}
+
+// GetHostStats returns the traffic counters of the routes of the host
+func (rps *ReverseProxyService) GetHostStats(host data_models.ReverseProxyHost) global_models.ReverseProxyHostStats {
+	stats := rps.trafficStats().host(host.ID)
+	stats.Host = host.GetHost()
+	return stats
+}
+
+// recordAccess adds the request, connection or session to the counters of
+// the route and writes it to the access log
+func (rps *ReverseProxyService) recordAccess(stats *routeStats, entry global_models.ReverseProxyAccessLogEvent, latency time.Duration, failed bool) {
+	entry.LatencyMs = float64(latency.Microseconds()) / 1000
+	stats.record(entry.Status, entry.BytesIn, entry.BytesOut, latency, failed)
+	rps.accessLog.Write(entry)
+}
+
+// recordHttpAccess records a request once the response was sent, the target
+// is the member picked by the upstream pool when the route has one
+func (rps *ReverseProxyService) recordHttpAccess(host *data_models.ReverseProxyHost, r *http.Request, w *accessResponseWriter, bytesIn int64, start time.Time) {
+	route := httpRouteFromContext(r.Context())
+	entry := global_models.ReverseProxyAccessLogEvent{
+		ReverseProxyHostId: host.ID,
+		Host:               host.GetHost(),
+		TrafficType:        "http",
+		ClientIp:           clientIp(r.RemoteAddr),
+		RequestId:          r.Header.Get("X-Request-ID"),
+		Method:             r.Method,
+		Path:               r.URL.Path,
+		Status:             w.Status(),
+		BytesIn:            bytesIn + w.hijackedIn.Load(),
+		BytesOut:           w.written + w.hijackedOut.Load(),
+	}
+
+	if route != nil {
+		entry.RouteId = route.ID
+		entry.Route = route.GetRoute()
+		entry.TargetVmId = route.TargetVmId
+		if route.TargetHost != "" && route.TargetHost != "---" {
+			entry.Upstream = route.TargetHost
+			if route.TargetPort != "" {
+				entry.Upstream = net.JoinHostPort(route.TargetHost, route.TargetPort)
+			}
+		}
+	}
+	if selection, ok := r.Context().Value(upstreamSelectionKey{}).(*upstreamSelection); ok && route != nil && route.Upstream != nil {
+		entry.Upstream = ""
+		entry.TargetVmId = ""
+		if selection.member != nil {
+			entry.Upstream = selection.member.address
+			entry.TargetVmId = selection.member.vmId
+		}
+	}
+
+	rps.recordAccess(rps.trafficStats().httpRoute(host, route), entry, time.Since(start), false)
+}
+
+// accessResponseWriter keeps the status and the size of the response, the
+// bytes of upgraded connections are counted on the hijacked connection
+type accessResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	written     int64
+	hijackedIn  atomic.Int64
+	hijackedOut atomic.Int64
+}
+
+func (w *accessResponseWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *accessResponseWriter) WriteHeader(status int) {
+	if w.status == 0 || w.status < 200 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+func (w *accessResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *accessResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+
+	return &countingConn{Conn: conn, read: &w.hijackedIn, written: &w.hijackedOut}, rw, nil
+}
+
+type countingConn struct {
+	net.Conn
+	read    *atomic.Int64
+	written *atomic.Int64
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.written.Add(int64(n))
+	return n, err
+}
+
+// countingBody counts the bytes of the request body read by the proxy, the
+// transport can still be reading it after the response arrived
+type countingBody struct {
+	io.ReadCloser
+	read atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read.Add(int64(n))
+	return n, err
+}
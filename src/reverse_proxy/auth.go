@@ -0,0 +1,513 @@
+package reverse_proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/mappers"
+	"github.com/Parallels/prl-devops-service/restapi"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxVerifiedCredentials bounds the cache of the basic auth credentials that
+// were already checked, so bcrypt does not run on every request
+const maxVerifiedCredentials = 1024
+
+// authGateway protects a host or an http route, it is built when the host
+// listener starts so a change in the configuration needs a restart of the host
+type authGateway struct {
+	ctx     basecontext.ApiContext
+	config  data_models.ReverseProxyAuth
+	allowed []*net.IPNet
+	denied  []*net.IPNet
+
+	devops        http.Handler
+	forwardClient *http.Client
+
+	verifiedMu sync.Mutex
+	verified   map[string]bool
+}
+
+// authIdentity is the caller found by one of the methods, it is passed to
+// the target in the identity headers
+type authIdentity struct {
+	method   string
+	username string
+	email    string
+	roles    []string
+}
+
+type authIdentityKey struct{}
+
+func newAuthGateway(ctx basecontext.ApiContext, config *data_models.ReverseProxyAuth) *authGateway {
+	if config == nil {
+		return nil
+	}
+
+	gateway := &authGateway{
+		ctx:      ctx,
+		config:   *config,
+		allowed:  parseNetworks(ctx, config.AllowedIps),
+		denied:   parseNetworks(ctx, config.DeniedIps),
+		verified: make(map[string]bool),
+	}
+
+	if config.Devops != nil && config.Devops.Enabled {
+		gateway.devops = restapi.Adapt(
+			http.HandlerFunc(gateway.captureDevopsIdentity),
+			restapi.AddAuthorizationContextMiddlewareAdapter(),
+			restapi.TokenAuthorizationMiddlewareAdapter(config.Devops.Roles, config.Devops.Claims, restapi.ComparisonOperationOr, restapi.ComparisonOperationOr),
+			restapi.ApiKeyAuthorizationMiddlewareAdapter(config.Devops.Roles, config.Devops.Claims, restapi.ComparisonOperationOr, restapi.ComparisonOperationOr),
+		)
+	}
+
+	if config.ForwardAuth != nil && config.ForwardAuth.Enabled {
+		timeout, err := time.ParseDuration(config.ForwardAuth.Timeout)
+		if err != nil || timeout <= 0 {
+			timeout, _ = time.ParseDuration(constants.REVERSE_PROXY_AUTH_DEFAULT_FORWARD_AUTH_TIMEOUT)
+		}
+		gateway.forwardClient = &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+
+	return gateway
+}
+
+func parseNetworks(ctx basecontext.ApiContext, values []string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				ctx.LogWarnf("[Reverse Proxy] [Auth] Ignoring invalid address %s", value)
+				continue
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			ctx.LogWarnf("[Reverse Proxy] [Auth] Ignoring invalid cidr %s", value)
+			continue
+		}
+		networks = append(networks, network)
+	}
+
+	return networks
+}
+
+// allowsAddress checks the address lists, the denied list wins over the
+// allowed one and an empty allowed list lets every address in
+func (g *authGateway) allowsAddress(address string) bool {
+	if g == nil {
+		return true
+	}
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return len(g.allowed) == 0 && len(g.denied) == 0
+	}
+
+	for _, network := range g.denied {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	if len(g.allowed) == 0 {
+		return true
+	}
+	for _, network := range g.allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g *authGateway) isDevopsEnabled() bool {
+	return g.devops != nil
+}
+
+func (g *authGateway) isBasicEnabled() bool {
+	return g.config.Basic != nil && g.config.Basic.Enabled
+}
+
+func (g *authGateway) isForwardAuthEnabled() bool {
+	return g.forwardClient != nil
+}
+
+func (g *authGateway) hasMethods() bool {
+	return g.isDevopsEnabled() || g.isBasicEnabled() || g.isForwardAuthEnabled()
+}
+
+// Handler runs the gateway before next, the identity headers sent by the
+// caller are always removed so the target can trust them
+func (g *authGateway) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, header := range constants.ReverseProxyAuthIdentityHeaders {
+			r.Header.Del(header)
+		}
+
+		if !g.allowsAddress(r.RemoteAddr) {
+			g.ctx.LogInfof("[Reverse Proxy] [Auth] Address %s is not allowed for %s", restapi.GetRemoteIp(r), r.URL.Path)
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		if !g.hasMethods() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if g.isDevopsEnabled() && hasDevopsCredentials(r) {
+			identity, handled := g.authenticateDevops(w, r)
+			if handled {
+				return
+			}
+			if identity != nil {
+				removeDevopsCredentials(r)
+				setIdentityHeaders(r, identity)
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if g.isBasicEnabled() {
+			if identity := g.authenticateBasic(r); identity != nil {
+				r.Header.Del("Authorization")
+				setIdentityHeaders(r, identity)
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if g.isForwardAuthEnabled() {
+			if g.authenticateForward(w, r) {
+				r.Header.Set(constants.REVERSE_PROXY_AUTH_METHOD_HEADER, constants.REVERSE_PROXY_AUTH_FORWARD_AUTH)
+				next.ServeHTTP(w, r)
+			}
+			return
+		}
+
+		if g.isBasicEnabled() {
+			realm := g.config.Basic.Realm
+			if realm == "" {
+				realm = constants.REVERSE_PROXY_AUTH_DEFAULT_REALM
+			}
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", realm))
+		}
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+func hasDevopsCredentials(r *http.Request) bool {
+	query := r.URL.Query()
+	return strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") ||
+		restapi.HasApiKeyAuthorizationHeader(r) ||
+		query.Get("access_token") != "" ||
+		query.Get("authorization") != ""
+}
+
+// removeDevopsCredentials keeps the tokens and api keys of the service from
+// reaching the target
+func removeDevopsCredentials(r *http.Request) {
+	r.Header.Del("Authorization")
+	r.Header.Del("X-Api-Key")
+
+	query := r.URL.Query()
+	if query.Has("access_token") || query.Has("authorization") {
+		query.Del("access_token")
+		query.Del("authorization")
+		r.URL.RawQuery = query.Encode()
+	}
+}
+
+func setIdentityHeaders(r *http.Request, identity *authIdentity) {
+	r.Header.Set(constants.REVERSE_PROXY_AUTH_METHOD_HEADER, identity.method)
+	if identity.username != "" {
+		r.Header.Set(constants.REVERSE_PROXY_AUTH_USER_HEADER, identity.username)
+	}
+	if identity.email != "" {
+		r.Header.Set(constants.REVERSE_PROXY_AUTH_EMAIL_HEADER, identity.email)
+	}
+	if len(identity.roles) > 0 {
+		r.Header.Set(constants.REVERSE_PROXY_AUTH_ROLES_HEADER, strings.Join(identity.roles, ","))
+	}
+}
+
+// authResponseWriter tells whether the middlewares already answered the
+// request, like the api key rate limit does
+type authResponseWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *authResponseWriter) WriteHeader(statusCode int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *authResponseWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+// captureDevopsIdentity ends the devops middlewares, it keeps the identity in
+// the context of the request instead of answering it
+func (g *authGateway) captureDevopsIdentity(w http.ResponseWriter, r *http.Request) {
+	holder, ok := r.Context().Value(authIdentityKey{}).(*authIdentity)
+	if !ok {
+		return
+	}
+	authorizationContext := basecontext.GetAuthorizationContext(r.Context())
+	if authorizationContext == nil || !authorizationContext.IsAuthorized {
+		return
+	}
+	// the api key middleware only checks the scope of the key, the roles and
+	// claims of the gateway are checked against the user of the key here
+	if authorizationContext.AuthorizedBy == "ApiKeyAuthorization" && !g.apiKeyHasDevopsAccess(authorizationContext) {
+		return
+	}
+
+	holder.method = constants.REVERSE_PROXY_AUTH_DEVOPS
+	if authorizationContext.User != nil {
+		holder.username = authorizationContext.User.Username
+		holder.email = authorizationContext.User.Email
+		holder.roles = authorizationContext.User.Roles
+	} else if authorizationContext.ApiKeyName != "" {
+		holder.username = authorizationContext.ApiKeyName
+	}
+}
+
+// apiKeyHasDevopsAccess checks the user of the api key has one of the roles
+// and one of the claims of the gateway, like the token middleware does for
+// the users. Keys without a user cannot pass a gateway with roles or claims
+func (g *authGateway) apiKeyHasDevopsAccess(authorizationContext *basecontext.AuthorizationContext) bool {
+	roles := g.config.Devops.Roles
+	claims := g.config.Devops.Claims
+	if len(roles) == 0 && len(claims) == 0 {
+		return true
+	}
+
+	db := serviceprovider.Get().JsonDatabase
+	if db == nil {
+		return false
+	}
+	apiKey, err := db.GetApiKey(g.ctx, authorizationContext.ApiKeyName)
+	if err != nil || apiKey == nil || apiKey.UserID == "" {
+		g.ctx.LogDebugf("[Reverse Proxy] [Auth] Api Key %s has no user for the roles and claims of the gateway", authorizationContext.ApiKeyName)
+		return false
+	}
+	user, err := db.GetUser(g.ctx, apiKey.UserID)
+	if err != nil || user == nil || user.Disabled {
+		return false
+	}
+
+	userRoles := make([]string, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		if strings.EqualFold(role.Name, constants.SUPER_USER_ROLE) {
+			return true
+		}
+		userRoles = append(userRoles, role.Name)
+	}
+	userClaims := mappers.ComputeEffectiveClaimIDs(*user)
+	if authorizationContext.IsScopedApiKey && len(authorizationContext.InjectedClaims) > 0 {
+		// the claims of a scoped key are the ones its scope kept
+		userClaims = authorizationContext.InjectedClaims
+	}
+
+	if len(roles) > 0 && !containsAnyFold(userRoles, roles) {
+		g.ctx.LogDebugf("[Reverse Proxy] [Auth] The user of Api Key %s does not have any of the roles %v", authorizationContext.ApiKeyName, roles)
+		return false
+	}
+	if len(claims) > 0 && !containsAnyFold(userClaims, claims) {
+		g.ctx.LogDebugf("[Reverse Proxy] [Auth] The user of Api Key %s does not have any of the claims %v", authorizationContext.ApiKeyName, claims)
+		return false
+	}
+
+	return true
+}
+
+func containsAnyFold(values []string, expected []string) bool {
+	for _, value := range values {
+		for _, item := range expected {
+			if strings.EqualFold(value, item) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (g *authGateway) authenticateDevops(w http.ResponseWriter, r *http.Request) (*authIdentity, bool) {
+	identity := &authIdentity{}
+	writer := &authResponseWriter{ResponseWriter: w}
+	ctx := context.WithValue(r.Context(), authIdentityKey{}, identity)
+	g.devops.ServeHTTP(writer, r.WithContext(ctx))
+	if writer.written {
+		return nil, true
+	}
+	if identity.method == "" {
+		return nil, false
+	}
+
+	return identity, false
+}
+
+func (g *authGateway) authenticateBasic(r *http.Request) *authIdentity {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil
+	}
+
+	for _, user := range g.config.Basic.Users {
+		if user.Username != username {
+			continue
+		}
+
+		sum := sha256.Sum256([]byte(username + ":" + password + ":" + user.PasswordHash))
+		key := hex.EncodeToString(sum[:])
+		g.verifiedMu.Lock()
+		verified := g.verified[key]
+		g.verifiedMu.Unlock()
+		if !verified {
+			if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+				return nil
+			}
+			g.verifiedMu.Lock()
+			if len(g.verified) >= maxVerifiedCredentials {
+				g.verified = make(map[string]bool)
+			}
+			g.verified[key] = true
+			g.verifiedMu.Unlock()
+		}
+
+		return &authIdentity{
+			method:   constants.REVERSE_PROXY_AUTH_BASIC,
+			username: username,
+		}
+	}
+
+	return nil
+}
+
+// authenticateForward asks the forward auth url about the request, when it
+// is not allowed the answer of the url is sent back to the caller
+func (g *authGateway) authenticateForward(w http.ResponseWriter, r *http.Request) bool {
+	request, err := http.NewRequestWithContext(r.Context(), http.MethodGet, g.config.ForwardAuth.Url, nil)
+	if err != nil {
+		g.ctx.LogErrorf("[Reverse Proxy] [Auth] Error creating the forward auth request: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return false
+	}
+
+	request.Header = r.Header.Clone()
+	for _, header := range []string{"Connection", "Upgrade", "Keep-Alive", "Te", "Trailer", "Transfer-Encoding", "Content-Length"} {
+		request.Header.Del(header)
+	}
+	proto := "http"
+	if r.TLS != nil {
+		proto = "https"
+	}
+	request.Header.Set("X-Forwarded-Method", r.Method)
+	request.Header.Set("X-Forwarded-Proto", proto)
+	request.Header.Set("X-Forwarded-Host", r.Host)
+	request.Header.Set("X-Forwarded-Uri", r.URL.RequestURI())
+	request.Header.Set("X-Forwarded-For", restapi.GetRemoteIp(r))
+
+	response, err := g.forwardClient.Do(request)
+	if err != nil {
+		g.ctx.LogErrorf("[Reverse Proxy] [Auth] Error calling the forward auth url %s: %v", g.config.ForwardAuth.Url, err)
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return false
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		for _, header := range g.config.ForwardAuth.ResponseHeaders {
+			if values := response.Header.Values(header); len(values) > 0 {
+				r.Header.Del(header)
+				for _, value := range values {
+					r.Header.Add(header, value)
+				}
+			}
+		}
+		return true
+	}
+
+	for key, values := range response.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(response.StatusCode)
+	_, _ = io.Copy(w, response.Body)
+	return false
+}
+
+// httpRouteAuth picks the gateway of a request, the auth of a route replaces
+// the auth of the host for the requests of that route
+type httpRouteAuth struct {
+	host   *authGateway
+	routes map[*data_models.ReverseProxyHostHttpRoute]*authGateway
+}
+
+func newHttpRouteAuth(ctx basecontext.ApiContext, host *data_models.ReverseProxyHost) *httpRouteAuth {
+	result := &httpRouteAuth{
+		host:   newAuthGateway(ctx, host.Auth),
+		routes: make(map[*data_models.ReverseProxyHostHttpRoute]*authGateway),
+	}
+	for _, route := range host.HttpRoutes {
+		if gateway := newAuthGateway(ctx, route.Auth); gateway != nil {
+			result.routes[route] = gateway
+		}
+	}
+
+	return result
+}
+
+func (a *httpRouteAuth) gatewayFor(route *data_models.ReverseProxyHostHttpRoute) *authGateway {
+	if gateway, ok := a.routes[route]; ok {
+		return gateway
+	}
+
+	return a.host
+}
+
+// Handler uses the route matched for the request, see matchHttpRoute
+func (a *httpRouteAuth) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gateway := a.gatewayFor(httpRouteFromContext(r.Context()))
+		if gateway == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gateway.Handler(next).ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,239 @@
+package reverse_proxy
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/common"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+	log "github.com/cjlapao/common-go-logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestAuthGateway(config data_models.ReverseProxyAuth) *authGateway {
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+	return newAuthGateway(ctx, &config)
+}
+
+// serveAuth runs the request through the gateway and returns the response
+// and the request that reached the target, if any
+func serveAuth(gateway *authGateway, r *http.Request) (*httptest.ResponseRecorder, *http.Request) {
+	var forwarded *http.Request
+	handler := gateway.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r
+		w.WriteHeader(http.StatusOK)
+	}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, r)
+	return recorder, forwarded
+}
+
+func TestAuthGatewayAddressLists(t *testing.T) {
+	gateway := newTestAuthGateway(data_models.ReverseProxyAuth{
+		AllowedIps: []string{"10.0.0.0/8", "192.168.1.10"},
+		DeniedIps:  []string{"10.0.0.5"},
+	})
+
+	assert.True(t, gateway.allowsAddress("10.1.2.3:5000"))
+	assert.True(t, gateway.allowsAddress("192.168.1.10:5000"))
+	assert.False(t, gateway.allowsAddress("192.168.1.11:5000"))
+	assert.False(t, gateway.allowsAddress("10.0.0.5:5000"))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "172.16.0.1:5000"
+	r.Header.Set("X-Forwarded-For", "10.1.2.3")
+	recorder, forwarded := serveAuth(gateway, r)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Nil(t, forwarded)
+
+	var nilGateway *authGateway
+	assert.True(t, nilGateway.allowsAddress("172.16.0.1:5000"))
+}
+
+func TestAuthGatewayBasic(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	gateway := newTestAuthGateway(data_models.ReverseProxyAuth{
+		Basic: &data_models.ReverseProxyAuthBasic{
+			Enabled: true,
+			Realm:   "Dashboards",
+			Users:   []data_models.ReverseProxyAuthBasicUser{{Username: "admin", PasswordHash: string(hash)}},
+		},
+	})
+
+	t.Run("missing credentials", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		recorder, forwarded := serveAuth(gateway, r)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Contains(t, recorder.Header().Get("WWW-Authenticate"), `realm="Dashboards"`)
+		assert.Nil(t, forwarded)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.SetBasicAuth("admin", "wrong")
+		recorder, forwarded := serveAuth(gateway, r)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Nil(t, forwarded)
+	})
+
+	t.Run("valid credentials", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.SetBasicAuth("admin", "secret")
+			r.Header.Set(constants.REVERSE_PROXY_AUTH_ROLES_HEADER, "SUPER_USER")
+			recorder, forwarded := serveAuth(gateway, r)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			require.NotNil(t, forwarded)
+			assert.Empty(t, forwarded.Header.Get("Authorization"))
+			assert.Empty(t, forwarded.Header.Get(constants.REVERSE_PROXY_AUTH_ROLES_HEADER))
+			assert.Equal(t, "admin", forwarded.Header.Get(constants.REVERSE_PROXY_AUTH_USER_HEADER))
+			assert.Equal(t, constants.REVERSE_PROXY_AUTH_BASIC, forwarded.Header.Get(constants.REVERSE_PROXY_AUTH_METHOD_HEADER))
+		}
+		assert.Len(t, gateway.verified, 1)
+	})
+}
+
+func TestAuthGatewayForwardAuth(t *testing.T) {
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, http.MethodPost, r.Header.Get("X-Forwarded-Method"))
+		assert.Equal(t, "/dashboard?tab=1", r.Header.Get("X-Forwarded-Uri"))
+		if r.Header.Get("Cookie") != "session=valid" {
+			w.Header().Set("Location", "https://login.example.com")
+			w.WriteHeader(http.StatusFound)
+			return
+		}
+		w.Header().Set("X-Auth-User", "jane")
+		w.Header().Set("X-Other", "ignored")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer authServer.Close()
+
+	gateway := newTestAuthGateway(data_models.ReverseProxyAuth{
+		ForwardAuth: &data_models.ReverseProxyAuthForward{
+			Enabled:         true,
+			Url:             authServer.URL,
+			ResponseHeaders: []string{"X-Auth-User"},
+		},
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/dashboard?tab=1", nil)
+		r.Header.Set("Cookie", "session=valid")
+		r.Header.Set("X-Auth-User", "spoofed")
+		recorder, forwarded := serveAuth(gateway, r)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		require.NotNil(t, forwarded)
+		assert.Equal(t, "jane", forwarded.Header.Get("X-Auth-User"))
+		assert.Empty(t, forwarded.Header.Get("X-Other"))
+		assert.Equal(t, constants.REVERSE_PROXY_AUTH_FORWARD_AUTH, forwarded.Header.Get(constants.REVERSE_PROXY_AUTH_METHOD_HEADER))
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/dashboard?tab=1", nil)
+		recorder, forwarded := serveAuth(gateway, r)
+		assert.Equal(t, http.StatusFound, recorder.Code)
+		assert.Equal(t, "https://login.example.com", recorder.Header().Get("Location"))
+		assert.Nil(t, forwarded)
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		unreachable := newTestAuthGateway(data_models.ReverseProxyAuth{
+			ForwardAuth: &data_models.ReverseProxyAuthForward{Enabled: true, Url: "http://127.0.0.1:1", Timeout: "1s"},
+		})
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		recorder, forwarded := serveAuth(unreachable, r)
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.Nil(t, forwarded)
+	})
+}
+
+func TestAuthGatewayDevopsApiKeyRoles(t *testing.T) {
+	common.Logger = log.Get()
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+	db := data.NewJsonDatabase(ctx, filepath.Join(t.TempDir(), "test_db.json"))
+	sp := serviceprovider.NewMockProvider()
+	sp.JsonDatabase = db
+	require.NoError(t, db.Connect(ctx))
+
+	userRole, err := db.CreateRole(ctx, data_models.Role{Name: constants.USER_ROLE})
+	require.NoError(t, err)
+	superUserRole, err := db.CreateRole(ctx, data_models.Role{Name: constants.SUPER_USER_ROLE})
+	require.NoError(t, err)
+	claim, err := db.CreateClaim(ctx, data_models.Claim{Name: constants.LIST_VM_CLAIM})
+	require.NoError(t, err)
+	for _, user := range []data_models.User{
+		{ID: "user-id", Username: "user", Name: "User", Email: "user@example.com", Roles: []data_models.Role{*userRole}},
+		{ID: "admin-id", Username: "admin", Name: "Admin", Email: "admin@example.com", Roles: []data_models.Role{*superUserRole}},
+	} {
+		user.Claims = []data_models.Claim{*claim}
+		_, err := db.CreateUser(ctx, user)
+		require.NoError(t, err)
+	}
+	for _, key := range []data_models.ApiKey{
+		{ID: "user-key", Name: "user-key", Key: "USER_KEY", Secret: "secret", UserID: "user-id"},
+		{ID: "admin-key", Name: "admin-key", Key: "ADMIN_KEY", Secret: "secret", UserID: "admin-id"},
+		{ID: "no-user-key", Name: "no-user-key", Key: "NO_USER_KEY", Secret: "secret"},
+	} {
+		_, err := db.CreateApiKey(ctx, key)
+		require.NoError(t, err)
+	}
+
+	gateway := newTestAuthGateway(data_models.ReverseProxyAuth{
+		Devops: &data_models.ReverseProxyAuthDevops{Enabled: true, Roles: []string{constants.SUPER_USER_ROLE}},
+	})
+	serve := func(key string) (*httptest.ResponseRecorder, *http.Request) {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("X-Api-Key", base64.StdEncoding.EncodeToString([]byte(key+":secret")))
+		return serveAuth(gateway, request)
+	}
+
+	// the key of a user without the role of the gateway is rejected
+	recorder, forwarded := serve("USER_KEY")
+	assert.Nil(t, forwarded)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	recorder, forwarded = serve("NO_USER_KEY")
+	assert.Nil(t, forwarded)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	recorder, forwarded = serve("ADMIN_KEY")
+	require.NotNil(t, forwarded)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "admin-key", forwarded.Header.Get(constants.REVERSE_PROXY_AUTH_USER_HEADER))
+}
+
+func TestHttpRouteAuthGatewayFor(t *testing.T) {
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+	public := &data_models.ReverseProxyHostHttpRoute{TargetHost: "10.0.0.1", RegexpPattern: regexp.MustCompile("^/public")}
+	admin := &data_models.ReverseProxyHostHttpRoute{
+		TargetHost:    "10.0.0.2",
+		RegexpPattern: regexp.MustCompile("^/admin"),
+		Auth:          &data_models.ReverseProxyAuth{AllowedIps: []string{"10.0.0.0/8"}},
+	}
+	host := &data_models.ReverseProxyHost{
+		Auth:       &data_models.ReverseProxyAuth{DeniedIps: []string{"192.168.0.1"}},
+		HttpRoutes: []*data_models.ReverseProxyHostHttpRoute{public, admin},
+	}
+	routeAuth := newHttpRouteAuth(ctx, host)
+
+	assert.Same(t, routeAuth.host, routeAuth.gatewayFor(public))
+	assert.Same(t, routeAuth.routes[admin], routeAuth.gatewayFor(admin))
+	assert.Same(t, routeAuth.host, routeAuth.gatewayFor(nil))
+	assert.Same(t, admin, matchHttpRoute(host.HttpRoutes, "/admin/users"))
+	assert.Nil(t, matchHttpRoute(host.HttpRoutes, "/other"))
+}
@@ -0,0 +1,147 @@
+package certificates
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/helpers"
+)
+
+const (
+	DnsProviderExec         = "exec"
+	DnsProviderChallTestSrv = "challtestsrv"
+)
+
+// DnsProvider publishes the TXT records of the DNS-01 challenges, the fqdn
+// is the full name of the record ending with a dot
+type DnsProvider interface {
+	Present(ctx context.Context, fqdn string, value string) error
+	CleanUp(ctx context.Context, fqdn string, value string) error
+}
+
+// DnsProviderFactory creates the provider when a challenge needs it, so the
+// provider can read its configuration at that time
+type DnsProviderFactory func() (DnsProvider, error)
+
+var (
+	dnsProviders   = map[string]DnsProviderFactory{}
+	dnsProvidersMu sync.RWMutex
+)
+
+func init() {
+	RegisterDnsProvider(DnsProviderExec, newExecDnsProvider)
+	RegisterDnsProvider(DnsProviderChallTestSrv, newChallTestSrvDnsProvider)
+}
+
+// RegisterDnsProvider adds a provider the hosts can use by name in the
+// dns_provider of the acme configuration
+func RegisterDnsProvider(name string, factory DnsProviderFactory) {
+	dnsProvidersMu.Lock()
+	defer dnsProvidersMu.Unlock()
+
+	dnsProviders[strings.ToLower(name)] = factory
+}
+
+func GetDnsProvider(name string) (DnsProvider, error) {
+	dnsProvidersMu.RLock()
+	factory, ok := dnsProviders[strings.ToLower(name)]
+	dnsProvidersMu.RUnlock()
+	if !ok {
+		return nil, errors.NewWithCodef(400, "acme dns provider %v not found", name)
+	}
+
+	return factory()
+}
+
+// execDnsProvider runs a script as "<path> present|cleanup <fqdn> <value>",
+// so any DNS service can be used without adding it to the service
+type execDnsProvider struct {
+	path string
+}
+
+func newExecDnsProvider() (DnsProvider, error) {
+	path := config.Get().GetKey(constants.REVERSE_PROXY_ACME_DNS_EXEC_PATH_ENV_VAR)
+	if path == "" {
+		return nil, errors.Newf("%v is required for the %v dns provider", constants.REVERSE_PROXY_ACME_DNS_EXEC_PATH_ENV_VAR, DnsProviderExec)
+	}
+
+	return &execDnsProvider{path: path}, nil
+}
+
+func (p *execDnsProvider) Present(ctx context.Context, fqdn string, value string) error {
+	return p.run(ctx, "present", fqdn, value)
+}
+
+func (p *execDnsProvider) CleanUp(ctx context.Context, fqdn string, value string) error {
+	return p.run(ctx, "cleanup", fqdn, value)
+}
+
+func (p *execDnsProvider) run(ctx context.Context, action string, fqdn string, value string) error {
+	command := helpers.Command{
+		Command: p.path,
+		Args:    []string{action, fqdn, value},
+	}
+	if _, err := helpers.ExecuteWithNoOutput(ctx, command, 2*time.Minute); err != nil {
+		return errors.NewFromErrorf(err, "error running the dns provider %v for %v", action, fqdn)
+	}
+
+	return nil
+}
+
+// challTestSrvDnsProvider sets the records in the pebble-challtestsrv mock
+// DNS server that is used with Pebble for testing
+type challTestSrvDnsProvider struct {
+	url    string
+	client *http.Client
+}
+
+func newChallTestSrvDnsProvider() (DnsProvider, error) {
+	url := config.Get().GetKey(constants.REVERSE_PROXY_ACME_DNS_CHALLTESTSRV_URL_ENV_VAR)
+	if url == "" {
+		url = constants.REVERSE_PROXY_ACME_DEFAULT_CHALLTESTSRV_URL
+	}
+
+	return &challTestSrvDnsProvider{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (p *challTestSrvDnsProvider) Present(ctx context.Context, fqdn string, value string) error {
+	return p.post(ctx, "/set-txt", map[string]string{"host": fqdn, "value": value})
+}
+
+func (p *challTestSrvDnsProvider) CleanUp(ctx context.Context, fqdn string, value string) error {
+	return p.post(ctx, "/clear-txt", map[string]string{"host": fqdn})
+}
+
+func (p *challTestSrvDnsProvider) post(ctx context.Context, path string, body map[string]string) error {
+	content, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url+path, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return errors.NewFromErrorf(err, "error calling the challenge test server %v", p.url+path)
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return errors.Newf("challenge test server %v returned %v", p.url+path, response.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+//go:build integration
+
+package certificates
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/constants"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getEnv(name string, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
+
+// newIntegrationManager points a manager to a Pebble server that resolves
+// every domain to this machine with pebble-challtestsrv, for example:
+//
+//	pebble-challtestsrv -defaultIPv4 127.0.0.1 -defaultIPv6 ""
+//	pebble -config test/config/pebble-config.json -dnsserver 127.0.0.1:8053
+//	PEBBLE_TEST_DIRECTORY_URL=https://localhost:14000/dir \
+//	  PEBBLE_TEST_CA_CERT=test/certs/pebble.minica.pem \
+//	  go test -tags integration ./reverse_proxy/certificates/
+func newIntegrationManager(t *testing.T) *CertificateManager {
+	directoryUrl := os.Getenv("PEBBLE_TEST_DIRECTORY_URL")
+	if directoryUrl == "" {
+		t.Skip("PEBBLE_TEST_DIRECTORY_URL is not set")
+	}
+
+	manager := newTestManager()
+	manager.Options.
+		WithDirectoryUrl(directoryUrl).
+		WithEmail("prl-devops-test@example.com").
+		WithHttpPort(getEnv("PEBBLE_TEST_HTTP_PORT", "5002"))
+	if caCert := os.Getenv("PEBBLE_TEST_CA_CERT"); caCert != "" {
+		manager.Options.WithCaCert(caCert)
+	} else {
+		manager.Options.WithInsecureSkipVerify(true)
+	}
+
+	return manager
+}
+
+func newAcmeHost(id string, challenge string, domains ...string) *data_models.ReverseProxyHost {
+	return &data_models.ReverseProxyHost{
+		ID: id,
+		Tls: &data_models.ReverseProxyHostTls{
+			Enabled: true,
+			Acme: &data_models.ReverseProxyHostTlsAcme{
+				Enabled:     true,
+				Domains:     domains,
+				Challenge:   challenge,
+				DnsProvider: DnsProviderChallTestSrv,
+			},
+		},
+	}
+}
+
+func TestObtain_HttpChallenge(t *testing.T) {
+	manager := newIntegrationManager(t)
+
+	// Pebble validates the challenge on the http port of the domain
+	listener, err := net.Listen("tcp", net.JoinHostPort("", manager.Options.HttpPort))
+	require.NoError(t, err)
+	server := &http.Server{Handler: manager.HTTPHandler(nil), ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	host := newAcmeHost("http-host", constants.REVERSE_PROXY_ACME_HTTP_01, "http.prl-devops-test.example.com")
+	require.NoError(t, manager.obtain(ctx, host))
+
+	certificate, err := manager.getCertificate(host.ID, &tls.ClientHelloInfo{ServerName: "http.prl-devops-test.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http.prl-devops-test.example.com"}, certificate.Leaf.DNSNames)
+	assert.Empty(t, manager.httpTokens)
+	assert.False(t, needsRenewal(manager.hosts[host.ID], host.Tls.Acme.Domains, time.Now(), time.Hour))
+}
+
+func TestObtain_DnsChallenge(t *testing.T) {
+	manager := newIntegrationManager(t)
+	if os.Getenv(constants.REVERSE_PROXY_ACME_DNS_CHALLTESTSRV_URL_ENV_VAR) == "" {
+		t.Skip(constants.REVERSE_PROXY_ACME_DNS_CHALLTESTSRV_URL_ENV_VAR + " is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	host := newAcmeHost("dns-host", constants.REVERSE_PROXY_ACME_DNS_01, "*.dns.prl-devops-test.example.com")
+	require.NoError(t, manager.obtain(ctx, host))
+
+	assert.Equal(t, []string{"*.dns.prl-devops-test.example.com"}, manager.hosts[host.ID].domains)
+	assert.True(t, matchesAnyDomain(manager.hosts[host.ID].certificate.Leaf.DNSNames, "api.dns.prl-devops-test.example.com"))
+}
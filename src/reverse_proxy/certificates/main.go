@@ -0,0 +1,715 @@
+package certificates
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"golang.org/x/crypto/acme"
+)
+
+const httpChallengePath = "/.well-known/acme-challenge/"
+
+// CertificateManager holds the certificates of the reverse proxy hosts, the
+// ones pasted in the host and the ones requested from the ACME directory,
+// and answers the ACME challenges
+type CertificateManager struct {
+	ctx     basecontext.ApiContext
+	db      *data.JsonDatabase
+	Options *CertificateOptions
+
+	mu          sync.RWMutex
+	hosts       map[string]*hostCertificate
+	httpTokens  map[string]string
+	alpnCerts   map[string]*tls.Certificate
+	obtaining   map[string]bool
+	retries     map[string]*retryState
+	clients     map[string]*acme.Client
+	clientMutex sync.Mutex
+}
+
+// retryState is the single retry schedule of a host whose certificate could
+// not be obtained, host and ctx are the latest ones the host was ensured with
+type retryState struct {
+	ctx      context.Context
+	host     *data_models.ReverseProxyHost
+	failures int
+	timer    *time.Timer
+}
+
+type hostCertificate struct {
+	domains     []string
+	certificate *tls.Certificate
+	notAfter    time.Time
+}
+
+func New(ctx basecontext.ApiContext, db *data.JsonDatabase) *CertificateManager {
+	manager := &CertificateManager{
+		ctx:        ctx,
+		db:         db,
+		Options:    NewDefaultOptions(),
+		hosts:      make(map[string]*hostCertificate),
+		httpTokens: make(map[string]string),
+		alpnCerts:  make(map[string]*tls.Certificate),
+		obtaining:  make(map[string]bool),
+		retries:    make(map[string]*retryState),
+		clients:    make(map[string]*acme.Client),
+	}
+
+	manager.processEnvironmentVariables()
+	return manager
+}
+
+// LoadHost loads the certificate of a host with TLS, the pasted one or the
+// one issued before by the ACME directory
+func (m *CertificateManager) LoadHost(host *data_models.ReverseProxyHost) error {
+	if host.Tls == nil || !host.Tls.Enabled {
+		m.RemoveHost(host.ID)
+		return nil
+	}
+
+	if host.Tls.IsAcmeEnabled() {
+		if m.db == nil {
+			return nil
+		}
+		stored, err := m.db.GetAcmeCertificate(m.ctx, host.ID)
+		if err != nil {
+			return nil
+		}
+		certificate, err := parseCertificate(stored.Certificate, stored.Key)
+		if err != nil {
+			m.ctx.LogWarnf("[Reverse Proxy] [ACME] Ignoring the stored certificate of %s: %v", host.GetHost(), err)
+			return nil
+		}
+		m.setHostCertificate(host.ID, certificate, stored.Domains)
+		return nil
+	}
+
+	certificate, err := parseCertificate(host.Tls.Cert, host.Tls.Key)
+	if err != nil {
+		return errors.NewFromErrorf(err, "error loading the tls certificate of %v", host.GetHost())
+	}
+	m.setHostCertificate(host.ID, certificate, certificate.Leaf.DNSNames)
+	return nil
+}
+
+func (m *CertificateManager) RemoveHost(hostId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.hosts, hostId)
+	if retry, ok := m.retries[hostId]; ok {
+		retry.timer.Stop()
+		delete(m.retries, hostId)
+	}
+}
+
+// TLSConfig returns the TLS configuration of the listener of a host, it
+// also answers the TLS-ALPN-01 challenges
+func (m *CertificateManager) TLSConfig(hostId string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1", acme.ALPNProto},
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return m.getCertificate(hostId, hello)
+		},
+	}
+}
+
+// HTTPHandler answers the HTTP-01 challenges and passes everything else to
+// next, a nil next answers not found
+func (m *CertificateManager) HTTPHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, httpChallengePath) {
+			token := strings.TrimPrefix(r.URL.Path, httpChallengePath)
+			m.mu.RLock()
+			response, ok := m.httpTokens[token]
+			m.mu.RUnlock()
+			if ok {
+				w.Header().Set("Content-Type", "text/plain")
+				_, _ = w.Write([]byte(response))
+				return
+			}
+		}
+
+		if next == nil {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// EnsureCertificate requests a certificate for the host when it has none,
+// the domains changed or it is about to expire. A failed request is retried
+// with an exponential backoff while the context is not done, a host only has
+// one retry scheduled and the calls made while it waits only update the host
+// the retry uses
+func (m *CertificateManager) EnsureCertificate(ctx context.Context, host *data_models.ReverseProxyHost) {
+	if !host.Tls.IsAcmeEnabled() {
+		return
+	}
+
+	m.mu.Lock()
+	if retry, ok := m.retries[host.ID]; ok {
+		retry.ctx = ctx
+		retry.host = host
+		m.mu.Unlock()
+		return
+	}
+	m.mu.Unlock()
+
+	m.ensureCertificate(ctx, host)
+}
+
+func (m *CertificateManager) ensureCertificate(ctx context.Context, host *data_models.ReverseProxyHost) {
+	m.mu.Lock()
+	current := m.hosts[host.ID]
+	if m.obtaining[host.ID] {
+		m.mu.Unlock()
+		return
+	}
+	if !needsRenewal(current, host.Tls.Acme.Domains, time.Now(), m.Options.RenewBefore) {
+		delete(m.retries, host.ID)
+		m.mu.Unlock()
+		return
+	}
+	m.obtaining[host.ID] = true
+	m.mu.Unlock()
+
+	m.ctx.LogInfof("[Reverse Proxy] [ACME] Requesting a certificate for %s", strings.Join(host.Tls.Acme.Domains, ", "))
+	err := m.obtain(ctx, host)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.obtaining, host.ID)
+
+	if err == nil {
+		delete(m.retries, host.ID)
+		return
+	}
+
+	retry, ok := m.retries[host.ID]
+	if !ok {
+		retry = &retryState{ctx: ctx, host: host}
+		m.retries[host.ID] = retry
+	}
+	retry.failures++
+	delay := m.retryDelay(retry.failures)
+	m.ctx.LogErrorf("[Reverse Proxy] [ACME] Error requesting the certificate for %s, retrying in %s: %v", strings.Join(host.Tls.Acme.Domains, ", "), delay, err)
+	retry.timer = time.AfterFunc(delay, func() {
+		m.mu.Lock()
+		if m.retries[host.ID] != retry {
+			m.mu.Unlock()
+			return
+		}
+		retryCtx, latest := retry.ctx, retry.host
+		if retryCtx.Err() != nil {
+			delete(m.retries, host.ID)
+			m.mu.Unlock()
+			return
+		}
+		m.mu.Unlock()
+
+		m.ensureCertificate(retryCtx, latest)
+	})
+}
+
+// retryDelay doubles the retry delay for every failure after the first one
+func (m *CertificateManager) retryDelay(failures int) time.Duration {
+	delay := m.Options.RetryDelay
+	for i := 1; i < failures && delay < m.Options.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if m.Options.MaxRetryDelay > 0 && delay > m.Options.MaxRetryDelay {
+		delay = m.Options.MaxRetryDelay
+	}
+
+	return delay
+}
+
+// Run checks the certificates of the hosts every check interval until the
+// context is done
+func (m *CertificateManager) Run(ctx context.Context, hosts func() []*data_models.ReverseProxyHost) {
+	ticker := time.NewTicker(m.Options.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, host := range hosts() {
+				m.EnsureCertificate(ctx, host)
+			}
+		}
+	}
+}
+
+// UsesHttpChallenge returns true when a host needs the HTTP-01 challenges
+// to be served on the http port
+func UsesHttpChallenge(host *data_models.ReverseProxyHost) bool {
+	if !host.Tls.IsAcmeEnabled() {
+		return false
+	}
+
+	return host.Tls.Acme.Challenge == "" || host.Tls.Acme.Challenge == constants.REVERSE_PROXY_ACME_HTTP_01
+}
+
+func (m *CertificateManager) getCertificate(hostId string, hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(hello.SupportedProtos) == 1 && hello.SupportedProtos[0] == acme.ALPNProto {
+		if certificate, ok := m.alpnCerts[name]; ok {
+			return certificate, nil
+		}
+		return nil, errors.Newf("no tls-alpn-01 challenge for %v", name)
+	}
+
+	current := m.hosts[hostId]
+	if name != "" {
+		if current != nil && matchesAnyDomain(current.domains, name) {
+			return current.certificate, nil
+		}
+		for _, other := range m.hosts {
+			if matchesAnyDomain(other.domains, name) {
+				return other.certificate, nil
+			}
+		}
+	}
+	if current != nil {
+		return current.certificate, nil
+	}
+
+	return nil, errors.Newf("no certificate for %v", name)
+}
+
+func (m *CertificateManager) setHostCertificate(hostId string, certificate *tls.Certificate, domains []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hosts[hostId] = &hostCertificate{
+		domains:     domains,
+		certificate: certificate,
+		notAfter:    certificate.Leaf.NotAfter,
+	}
+}
+
+func (m *CertificateManager) obtain(ctx context.Context, host *data_models.ReverseProxyHost) error {
+	settings := host.Tls.Acme
+	email := settings.Email
+	if email == "" {
+		email = m.Options.Email
+	}
+	challengeType := settings.Challenge
+	if challengeType == "" {
+		challengeType = constants.REVERSE_PROXY_ACME_HTTP_01
+	}
+
+	client, err := m.client(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(settings.Domains...))
+	if err != nil {
+		return errors.NewFromErrorf(err, "error creating the acme order")
+	}
+	for _, authorizationUrl := range order.AuthzURLs {
+		authorization, err := client.GetAuthorization(ctx, authorizationUrl)
+		if err != nil {
+			return errors.NewFromErrorf(err, "error getting the acme authorization")
+		}
+		if authorization.Status == acme.StatusValid {
+			continue
+		}
+
+		var challenge *acme.Challenge
+		for _, c := range authorization.Challenges {
+			if c.Type == challengeType {
+				challenge = c
+				break
+			}
+		}
+		domain := authorization.Identifier.Value
+		if challenge == nil {
+			return errors.Newf("the acme directory does not offer the %v challenge for %v", challengeType, domain)
+		}
+
+		cleanUp, err := m.presentChallenge(ctx, client, challenge, domain, settings.DnsProvider)
+		if err != nil {
+			return err
+		}
+		_, err = client.Accept(ctx, challenge)
+		if err == nil {
+			_, err = client.WaitAuthorization(ctx, authorization.URI)
+		}
+		cleanUp()
+		if err != nil {
+			return errors.NewFromErrorf(err, "error validating the %v challenge for %v", challengeType, domain)
+		}
+	}
+
+	order, err = client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return errors.NewFromErrorf(err, "error waiting for the acme order")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: strings.TrimPrefix(settings.Domains[0], "*.")},
+		DNSNames: settings.Domains,
+	}, key)
+	if err != nil {
+		return err
+	}
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return errors.NewFromErrorf(err, "error finalizing the acme order")
+	}
+
+	certificatePem := make([]byte, 0)
+	for _, der := range chain {
+		certificatePem = append(certificatePem, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	keyPem, err := encodeKey(key)
+	if err != nil {
+		return err
+	}
+	certificate, err := parseCertificate(string(certificatePem), keyPem)
+	if err != nil {
+		return err
+	}
+
+	if m.db != nil {
+		if _, err := m.db.SaveAcmeCertificate(m.ctx, data_models.AcmeCertificate{
+			HostID:       host.ID,
+			Domains:      settings.Domains,
+			DirectoryUrl: m.Options.DirectoryUrl,
+			Challenge:    challengeType,
+			Certificate:  string(certificatePem),
+			Key:          keyPem,
+			NotBefore:    certificate.Leaf.NotBefore.UTC().Format(time.RFC3339),
+			NotAfter:     certificate.Leaf.NotAfter.UTC().Format(time.RFC3339),
+		}); err != nil {
+			m.ctx.LogErrorf("[Reverse Proxy] [ACME] Error saving the certificate of %s: %v", host.GetHost(), err)
+		}
+	}
+	m.setHostCertificate(host.ID, certificate, settings.Domains)
+
+	m.ctx.LogInfof("[Reverse Proxy] [ACME] Issued the certificate for %s, valid until %s", strings.Join(settings.Domains, ", "), certificate.Leaf.NotAfter.UTC().Format(time.RFC3339))
+	return nil
+}
+
+// presentChallenge sets up the answer to a challenge, the returned function
+// removes it once the challenge is validated
+func (m *CertificateManager) presentChallenge(ctx context.Context, client *acme.Client, challenge *acme.Challenge, domain string, dnsProvider string) (func(), error) {
+	switch challenge.Type {
+	case constants.REVERSE_PROXY_ACME_HTTP_01:
+		response, err := client.HTTP01ChallengeResponse(challenge.Token)
+		if err != nil {
+			return nil, err
+		}
+		m.mu.Lock()
+		m.httpTokens[challenge.Token] = response
+		m.mu.Unlock()
+
+		return func() {
+			m.mu.Lock()
+			delete(m.httpTokens, challenge.Token)
+			m.mu.Unlock()
+		}, nil
+	case constants.REVERSE_PROXY_ACME_TLS_ALPN_01:
+		certificate, err := client.TLSALPN01ChallengeCert(challenge.Token, domain)
+		if err != nil {
+			return nil, err
+		}
+		m.mu.Lock()
+		m.alpnCerts[domain] = &certificate
+		m.mu.Unlock()
+
+		return func() {
+			m.mu.Lock()
+			delete(m.alpnCerts, domain)
+			m.mu.Unlock()
+		}, nil
+	case constants.REVERSE_PROXY_ACME_DNS_01:
+		provider, err := GetDnsProvider(dnsProvider)
+		if err != nil {
+			return nil, err
+		}
+		value, err := client.DNS01ChallengeRecord(challenge.Token)
+		if err != nil {
+			return nil, err
+		}
+		fqdn := "_acme-challenge." + domain + "."
+		if err := provider.Present(ctx, fqdn, value); err != nil {
+			return nil, err
+		}
+		if m.Options.DnsPropagationWait > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(m.Options.DnsPropagationWait):
+			}
+		}
+
+		return func() {
+			if err := provider.CleanUp(context.Background(), fqdn, value); err != nil {
+				m.ctx.LogWarnf("[Reverse Proxy] [ACME] Error removing the dns record %s: %v", fqdn, err)
+			}
+		}, nil
+	}
+
+	return nil, errors.Newf("unsupported acme challenge %v", challenge.Type)
+}
+
+// client returns the ACME client of the account for the email, the account
+// is registered the first time and its key kept in the database
+func (m *CertificateManager) client(ctx context.Context, email string) (*acme.Client, error) {
+	m.clientMutex.Lock()
+	defer m.clientMutex.Unlock()
+
+	if client, ok := m.clients[email]; ok {
+		return client, nil
+	}
+
+	httpClient, err := m.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	client := &acme.Client{
+		DirectoryURL: m.Options.DirectoryUrl,
+		HTTPClient:   httpClient,
+		UserAgent:    "prl-devops-service",
+	}
+
+	if m.db != nil {
+		if account, err := m.db.GetAcmeAccount(m.ctx, m.Options.DirectoryUrl, email); err == nil {
+			key, err := decodeKey(account.Key)
+			if err != nil {
+				return nil, errors.NewFromErrorf(err, "error reading the acme account key")
+			}
+			client.Key = key
+			m.clients[email] = client
+			return client, nil
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	client.Key = key
+	account := &acme.Account{}
+	if email != "" {
+		account.Contact = []string{"mailto:" + email}
+	}
+	registered, err := client.Register(ctx, account, acme.AcceptTOS)
+	if err == acme.ErrAccountAlreadyExists {
+		registered, err = client.GetReg(ctx, "")
+	}
+	if err != nil {
+		return nil, errors.NewFromErrorf(err, "error registering the acme account in %v", m.Options.DirectoryUrl)
+	}
+
+	if m.db != nil {
+		keyPem, err := encodeKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := m.db.SaveAcmeAccount(m.ctx, data_models.AcmeAccount{
+			DirectoryUrl: m.Options.DirectoryUrl,
+			Email:        email,
+			Uri:          registered.URI,
+			Key:          keyPem,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	m.clients[email] = client
+	return client, nil
+}
+
+func (m *CertificateManager) httpClient() (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: m.Options.InsecureSkipVerify, // #nosec G402 only set for test directories
+	}
+	if m.Options.CaCert != "" {
+		content := []byte(m.Options.CaCert)
+		if !strings.Contains(m.Options.CaCert, "-----BEGIN") {
+			fileContent, err := os.ReadFile(filepath.Clean(m.Options.CaCert))
+			if err != nil {
+				return nil, errors.NewFromErrorf(err, "error reading the acme ca certificate")
+			}
+			content = fileContent
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(content) {
+			return nil, errors.New("the acme ca certificate has no valid certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport, Timeout: time.Minute}, nil
+}
+
+func (m *CertificateManager) processEnvironmentVariables() {
+	cfg := config.Get()
+	if cfg.GetKey(constants.REVERSE_PROXY_ACME_DIRECTORY_URL_ENV_VAR) != "" {
+		m.Options.WithDirectoryUrl(cfg.GetKey(constants.REVERSE_PROXY_ACME_DIRECTORY_URL_ENV_VAR))
+	}
+	if cfg.GetKey(constants.REVERSE_PROXY_ACME_EMAIL_ENV_VAR) != "" {
+		m.Options.WithEmail(cfg.GetKey(constants.REVERSE_PROXY_ACME_EMAIL_ENV_VAR))
+	}
+	if cfg.GetKey(constants.REVERSE_PROXY_ACME_CA_CERT_ENV_VAR) != "" {
+		m.Options.WithCaCert(cfg.GetKey(constants.REVERSE_PROXY_ACME_CA_CERT_ENV_VAR))
+	}
+	m.Options.WithInsecureSkipVerify(cfg.GetBoolKey(constants.REVERSE_PROXY_ACME_INSECURE_SKIP_VERIFY_ENV_VAR))
+	if cfg.GetKey(constants.REVERSE_PROXY_ACME_HTTP_PORT_ENV_VAR) != "" {
+		m.Options.WithHttpPort(cfg.GetKey(constants.REVERSE_PROXY_ACME_HTTP_PORT_ENV_VAR))
+	}
+
+	durations := map[string]func(time.Duration) *CertificateOptions{
+		constants.REVERSE_PROXY_ACME_RENEW_BEFORE_ENV_VAR:         m.Options.WithRenewBefore,
+		constants.REVERSE_PROXY_ACME_CHECK_INTERVAL_ENV_VAR:       m.Options.WithCheckInterval,
+		constants.REVERSE_PROXY_ACME_DNS_PROPAGATION_WAIT_ENV_VAR: m.Options.WithDnsPropagationWait,
+	}
+	for key, set := range durations {
+		value := cfg.GetKey(key)
+		if value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration <= 0 {
+			m.ctx.LogWarnf("[Reverse Proxy] [ACME] Invalid value for %s: %s", key, value)
+			continue
+		}
+		set(duration)
+	}
+}
+
+// needsRenewal returns true when there is no certificate, it was issued for
+// other domains or it expires within the renew window
+func needsRenewal(current *hostCertificate, domains []string, now time.Time, renewBefore time.Duration) bool {
+	if current == nil || current.certificate == nil {
+		return true
+	}
+	if !sameDomains(current.domains, domains) {
+		return true
+	}
+
+	return now.Add(renewBefore).After(current.notAfter)
+}
+
+func sameDomains(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := make([]string, len(a))
+	sortedB := make([]string, len(b))
+	for i := range a {
+		sortedA[i] = strings.ToLower(a[i])
+		sortedB[i] = strings.ToLower(b[i])
+	}
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesAnyDomain checks the server name against the domains, a wildcard
+// only covers a single label
+func matchesAnyDomain(domains []string, name string) bool {
+	for _, domain := range domains {
+		domain = strings.ToLower(domain)
+		if domain == name {
+			return true
+		}
+		if strings.HasPrefix(domain, "*.") {
+			suffix := domain[1:]
+			if strings.HasSuffix(name, suffix) && !strings.Contains(strings.TrimSuffix(name, suffix), ".") && len(name) > len(suffix) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// parseCertificate reads a certificate and key pair given as PEM content or
+// as the paths of the files
+func parseCertificate(cert string, key string) (*tls.Certificate, error) {
+	var certificate tls.Certificate
+	var err error
+	if strings.Contains(cert, "-----BEGIN") {
+		certificate, err = tls.X509KeyPair([]byte(cert), []byte(key))
+	} else {
+		certificate, err = tls.LoadX509KeyPair(filepath.Clean(cert), filepath.Clean(key))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if certificate.Leaf == nil {
+		leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+		if err != nil {
+			return nil, err
+		}
+		certificate.Leaf = leaf
+	}
+
+	return &certificate, nil
+}
+
+func encodeKey(key *ecdsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})), nil
+}
+
+func decodeKey(value string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(value))
+	if block == nil {
+		return nil, errors.New("invalid pem key")
+	}
+
+	return x509.ParseECPrivateKey(block.Bytes)
+}
@@ -0,0 +1,184 @@
+package certificates
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
+)
+
+func newTestManager() *CertificateManager {
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+	return New(ctx, nil)
+}
+
+func newTestCertificate(t *testing.T, notAfter time.Time, domains ...string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: domains[0]},
+		DNSNames:     domains,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyPem, err := encodeKey(key)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), keyPem
+}
+
+func TestHTTPHandlerAnswersChallenges(t *testing.T) {
+	manager := newTestManager()
+	manager.httpTokens["token-1"] = "token-1.thumbprint"
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	recorder := httptest.NewRecorder()
+	manager.HTTPHandler(next).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/.well-known/acme-challenge/token-1", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "token-1.thumbprint", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	manager.HTTPHandler(next).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/.well-known/acme-challenge/other", nil))
+	assert.Equal(t, http.StatusTeapot, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	manager.HTTPHandler(nil).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestGetCertificateSelectsBySni(t *testing.T) {
+	manager := newTestManager()
+	certA, keyA := newTestCertificate(t, time.Now().Add(time.Hour), "a.example.com")
+	certB, keyB := newTestCertificate(t, time.Now().Add(time.Hour), "*.b.example.com")
+	require.NoError(t, manager.LoadHost(&data_models.ReverseProxyHost{ID: "a", Tls: &data_models.ReverseProxyHostTls{Enabled: true, Cert: certA, Key: keyA}}))
+	require.NoError(t, manager.LoadHost(&data_models.ReverseProxyHost{ID: "b", Tls: &data_models.ReverseProxyHostTls{Enabled: true, Cert: certB, Key: keyB}}))
+	assert.Error(t, manager.LoadHost(&data_models.ReverseProxyHost{ID: "c", Tls: &data_models.ReverseProxyHostTls{Enabled: true, Cert: certA, Key: keyB}}))
+
+	certificate, err := manager.getCertificate("a", &tls.ClientHelloInfo{ServerName: "api.b.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*.b.example.com"}, certificate.Leaf.DNSNames)
+
+	certificate, err = manager.getCertificate("a", &tls.ClientHelloInfo{ServerName: "deep.api.b.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.example.com"}, certificate.Leaf.DNSNames)
+
+	_, err = manager.getCertificate("a", &tls.ClientHelloInfo{ServerName: "a.example.com", SupportedProtos: []string{acme.ALPNProto}})
+	assert.Error(t, err)
+	manager.alpnCerts["a.example.com"] = &tls.Certificate{}
+	certificate, err = manager.getCertificate("a", &tls.ClientHelloInfo{ServerName: "a.example.com", SupportedProtos: []string{acme.ALPNProto}})
+	require.NoError(t, err)
+	assert.Same(t, manager.alpnCerts["a.example.com"], certificate)
+
+	manager.RemoveHost("a")
+	_, err = manager.getCertificate("a", &tls.ClientHelloInfo{ServerName: "a.example.com"})
+	assert.Error(t, err)
+}
+
+func TestNeedsRenewal(t *testing.T) {
+	now := time.Now()
+	current := &hostCertificate{
+		domains:     []string{"a.example.com", "b.example.com"},
+		certificate: &tls.Certificate{},
+		notAfter:    now.Add(60 * 24 * time.Hour),
+	}
+
+	assert.True(t, needsRenewal(nil, []string{"a.example.com"}, now, time.Hour))
+	assert.False(t, needsRenewal(current, []string{"B.example.com", "a.example.com"}, now, 30*24*time.Hour))
+	assert.True(t, needsRenewal(current, []string{"a.example.com"}, now, 30*24*time.Hour))
+	assert.True(t, needsRenewal(current, []string{"a.example.com", "b.example.com"}, now, 90*24*time.Hour))
+}
+
+func TestEnsureCertificateKeepsOneRetryPerHost(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	manager := newTestManager()
+	manager.Options.WithDirectoryUrl(server.URL).WithRetryDelay(time.Hour, 4*time.Hour)
+	host := &data_models.ReverseProxyHost{ID: "host", Tls: &data_models.ReverseProxyHostTls{
+		Enabled: true,
+		Acme:    &data_models.ReverseProxyHostTlsAcme{Enabled: true, Domains: []string{"a.example.com"}},
+	}}
+
+	manager.EnsureCertificate(context.Background(), host)
+	attempts := requests.Load()
+	assert.Positive(t, attempts)
+	require.Len(t, manager.retries, 1)
+	assert.Equal(t, 1, manager.retries["host"].failures)
+
+	// the checks while the retry waits only update the host it uses
+	updated := *host
+	for i := 0; i < 5; i++ {
+		manager.EnsureCertificate(context.Background(), &updated)
+	}
+	assert.Equal(t, attempts, requests.Load())
+	require.Len(t, manager.retries, 1)
+	assert.Same(t, &updated, manager.retries["host"].host)
+
+	manager.RemoveHost("host")
+	assert.Empty(t, manager.retries)
+}
+
+func TestRetryDelay(t *testing.T) {
+	manager := newTestManager()
+	manager.Options.WithRetryDelay(15*time.Minute, 12*time.Hour)
+
+	assert.Equal(t, 15*time.Minute, manager.retryDelay(1))
+	assert.Equal(t, 30*time.Minute, manager.retryDelay(2))
+	assert.Equal(t, time.Hour, manager.retryDelay(3))
+	assert.Equal(t, 12*time.Hour, manager.retryDelay(10))
+	assert.Equal(t, 12*time.Hour, manager.retryDelay(1000))
+}
+
+func TestDnsProviders(t *testing.T) {
+	_, err := GetDnsProvider("unknown")
+	assert.Error(t, err)
+
+	requests := make([]map[string]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		body["path"] = r.URL.Path
+		requests = append(requests, body)
+	}))
+	defer server.Close()
+
+	RegisterDnsProvider("Test", func() (DnsProvider, error) {
+		return &challTestSrvDnsProvider{url: server.URL, client: server.Client()}, nil
+	})
+	provider, err := GetDnsProvider("test")
+	require.NoError(t, err)
+
+	require.NoError(t, provider.Present(context.Background(), "_acme-challenge.example.com.", "value"))
+	require.NoError(t, provider.CleanUp(context.Background(), "_acme-challenge.example.com.", "value"))
+	assert.Equal(t, []map[string]string{
+		{"path": "/set-txt", "host": "_acme-challenge.example.com.", "value": "value"},
+		{"path": "/clear-txt", "host": "_acme-challenge.example.com."},
+	}, requests)
+}
@@ -0,0 +1,86 @@
+package certificates
+
+import (
+	"time"
+
+	"github.com/Parallels/prl-devops-service/constants"
+)
+
+type CertificateOptions struct {
+	// DirectoryUrl is the ACME directory the certificates are requested from
+	DirectoryUrl string
+	// Email is the contact of the ACME account when the host does not set one
+	Email string
+	// CaCert is the PEM content or the path of the root that signs the TLS
+	// certificate of the ACME directory, like the Pebble test root
+	CaCert             string
+	InsecureSkipVerify bool
+	// HttpPort is where the HTTP-01 challenges are served
+	HttpPort string
+	// RenewBefore is how long before expiring a certificate is renewed
+	RenewBefore   time.Duration
+	CheckInterval time.Duration
+	// DnsPropagationWait is how long to wait after publishing a DNS-01 record
+	DnsPropagationWait time.Duration
+	// RetryDelay is the wait after the first failed request, it doubles
+	// after every failure up to MaxRetryDelay
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+}
+
+func NewDefaultOptions() *CertificateOptions {
+	return &CertificateOptions{
+		DirectoryUrl:  constants.REVERSE_PROXY_ACME_DEFAULT_DIRECTORY_URL,
+		HttpPort:      constants.REVERSE_PROXY_ACME_DEFAULT_HTTP_PORT,
+		RenewBefore:   30 * 24 * time.Hour,
+		CheckInterval: 12 * time.Hour,
+		RetryDelay:    15 * time.Minute,
+		MaxRetryDelay: 12 * time.Hour,
+	}
+}
+
+func (o *CertificateOptions) WithDirectoryUrl(url string) *CertificateOptions {
+	o.DirectoryUrl = url
+	return o
+}
+
+func (o *CertificateOptions) WithEmail(email string) *CertificateOptions {
+	o.Email = email
+	return o
+}
+
+func (o *CertificateOptions) WithCaCert(caCert string) *CertificateOptions {
+	o.CaCert = caCert
+	return o
+}
+
+func (o *CertificateOptions) WithInsecureSkipVerify(value bool) *CertificateOptions {
+	o.InsecureSkipVerify = value
+	return o
+}
+
+func (o *CertificateOptions) WithHttpPort(port string) *CertificateOptions {
+	o.HttpPort = port
+	return o
+}
+
+func (o *CertificateOptions) WithRenewBefore(duration time.Duration) *CertificateOptions {
+	o.RenewBefore = duration
+	return o
+}
+
+func (o *CertificateOptions) WithCheckInterval(duration time.Duration) *CertificateOptions {
+	o.CheckInterval = duration
+	return o
+}
+
+func (o *CertificateOptions) WithDnsPropagationWait(duration time.Duration) *CertificateOptions {
+	o.DnsPropagationWait = duration
+	return o
+}
+
+func (o *CertificateOptions) WithRetryDelay(duration time.Duration, maxDuration time.Duration) *CertificateOptions {
+	o.RetryDelay = duration
+	o.MaxRetryDelay = maxDuration
+	return o
+}
@@ -3,6 +3,7 @@ package reverse_proxy
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -12,6 +13,7 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -24,6 +26,7 @@ import (
 	"github.com/Parallels/prl-devops-service/helpers"
 	"github.com/Parallels/prl-devops-service/mappers"
 	global_models "github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/reverse_proxy/certificates"
 	"github.com/Parallels/prl-devops-service/reverse_proxy/models"
 	"github.com/Parallels/prl-devops-service/serviceprovider"
 	"golang.org/x/sync/errgroup"
@@ -49,6 +52,8 @@ const (
 	defaultReadTimeout      = 5 * time.Second
 	defaultWriteTimeout     = 60 * time.Second
 	defaultIdleTimeout      = 5 * time.Minute
+	defaultUdpIdleTimeout   = 1 * time.Minute
+	defaultUdpMaxSessions   = 1024
 )
 
 var (
@@ -58,6 +63,15 @@ var (
 	readTimeout      = getEnvDuration("REVERSE_PROXY_READ_TIMEOUT", defaultReadTimeout)
 	writeTimeout     = getEnvDuration("REVERSE_PROXY_WRITE_TIMEOUT", defaultWriteTimeout)
 	idleTimeout      = getEnvDuration("REVERSE_PROXY_IDLE_TIMEOUT", defaultIdleTimeout)
+	// websocketIdleTimeout closes the upgraded connections without traffic,
+	// the routes can change it in their websocket configuration
+	websocketIdleTimeout = getEnvDuration("REVERSE_PROXY_WEBSOCKET_IDLE_TIMEOUT", defaultIdleTimeout)
+	// udpIdleTimeout closes the udp sessions without traffic, the routes can
+	// change it in their idle timeout
+	udpIdleTimeout = getEnvDuration("REVERSE_PROXY_UDP_IDLE_TIMEOUT", defaultUdpIdleTimeout)
+	// udpMaxSessions is the number of clients a udp route keeps, the routes
+	// can change it in their max sessions
+	udpMaxSessions = getEnvInt("REVERSE_PROXY_UDP_MAX_SESSIONS", defaultUdpMaxSessions)
 )
 
 // Helper function to get duration from environment variable
@@ -70,6 +84,16 @@ func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	return defaultValue
 }
 
+// Helper function to get a positive number from environment variable
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if number, err := strconv.Atoi(value); err == nil && number > 0 {
+			return number
+		}
+	}
+	return defaultValue
+}
+
 type ReverseProxyService struct {
 	enabled           bool
 	host              string
@@ -81,13 +105,23 @@ type ReverseProxyService struct {
 	ctx               context.Context
 	cancelFunc        context.CancelFunc
 	tcpListeners      []net.Listener
+	udpListeners      []net.PacketConn
 	httpListeners     []*http.Server
 	wg                *sync.WaitGroup
 	activeConnections sync.WaitGroup
 
+	// hostMu guards hostCancelFuncs and the writes of forwarding_hosts, the
+	// other goroutines read the hosts with forwardingHosts
 	hostCancelFuncs map[string]context.CancelFunc
 	hostMu          sync.Mutex
 
+	certificates        *certificates.CertificateManager
+	acmeChallengeServer *http.Server
+
+	stats     *trafficStats
+	statsOnce sync.Once
+	accessLog *accessLog
+
 	opQueue   chan reverseProxyOperationRequest
 	queueOnce sync.Once
 }
@@ -147,10 +181,12 @@ func New(ctx basecontext.ApiContext) *ReverseProxyService {
 		api_ctx:         ctx,
 		db:              db,
 		tcpListeners:    []net.Listener{},
+		udpListeners:    []net.PacketConn{},
 		httpListeners:   []*http.Server{},
 		wg:              &sync.WaitGroup{},
 		State:           ReverseProxyServiceStateStopped,
 		hostCancelFuncs: make(map[string]context.CancelFunc),
+		certificates:    certificates.New(ctx, db),
 	}
 
 	return globalReverseProxyService
@@ -304,10 +340,29 @@ func (rps *ReverseProxyService) LoadFromDb() error {
 				hostCopy.TcpRoute.TargetHost = vm.InternalIpAddress
 			}
 		}
+		if hostCopy.UdpRoute != nil && hostCopy.UdpRoute.TargetVmId != "" {
+			vm, err := prl_svc.GetVm(rps.api_ctx, hostCopy.UdpRoute.TargetVmId)
+			if err != nil || vm.InternalIpAddress == "" || vm.InternalIpAddress == "-" || vm.State != "running" {
+				if err != nil {
+					rps.api_ctx.LogErrorf("Error getting vm %s for reverse proxy udp route: %s", hostCopy.UdpRoute.TargetVmId, err)
+				} else if vm == nil {
+					rps.api_ctx.LogErrorf("Error getting vm %s for reverse proxy udp route: vm could not be found", hostCopy.UdpRoute.TargetVmId)
+				} else if vm.InternalIpAddress == "" || vm.InternalIpAddress == "-" {
+					rps.api_ctx.LogErrorf("Error getting vm %s for reverse proxy udp route: vm internal ip address is empty", hostCopy.UdpRoute.TargetVmId)
+				}
+				hostCopy.UdpRoute.TargetHost = "---"
+			} else {
+				hostCopy.UdpRoute.TargetHost = vm.InternalIpAddress
+			}
+		}
 
+		rps.hostMu.Lock()
 		rps.forwarding_hosts = append(rps.forwarding_hosts, &hostCopy)
+		rps.hostMu.Unlock()
 	}
 
+	// the counters of the hosts and routes that were deleted are dropped
+	rps.trafficStats().prune(hosts)
 	return nil
 }
 
@@ -333,10 +388,15 @@ func (rps *ReverseProxyService) startInternal() error {
 	errorChan := make(chan error, 1)
 
 	// Re-initialize variables
+	rps.hostMu.Lock()
 	rps.forwarding_hosts = make([]*data_models.ReverseProxyHost, 0)
+	rps.hostMu.Unlock()
 	rps.tcpListeners = make([]net.Listener, 0)
+	rps.udpListeners = make([]net.PacketConn, 0)
 	rps.httpListeners = make([]*http.Server, 0)
 	rps.wg = &sync.WaitGroup{}
+	_ = rps.accessLog.Close()
+	rps.accessLog = newAccessLog(rps.api_ctx)
 	rps.hostMu.Lock()
 	rps.hostCancelFuncs = make(map[string]context.CancelFunc)
 	rps.hostMu.Unlock()
@@ -368,6 +428,7 @@ func (rps *ReverseProxyService) startInternal() error {
 
 	rps.api_ctx.LogInfof("[Reverse Proxy] Starting reverse proxy on %s:%s", rps.host, rps.port)
 	go rps.startServer(errorChan)
+	go rps.certificates.Run(rps.ctx, rps.forwardingHosts)
 
 	select {
 	case err := <-errorChan:
@@ -459,6 +520,18 @@ func (rps *ReverseProxyService) Stop() error {
 		})
 	}
 
+	// Close UDP listeners in parallel
+	for _, listener := range rps.udpListeners {
+		l := listener // Create local variable for closure
+		eg.Go(func() error {
+			if err := l.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+				rps.api_ctx.LogErrorf("[Reverse Proxy] Error closing UDP listener: %v", err)
+				return err
+			}
+			return nil
+		})
+	}
+
 	// Wait for all shutdowns to complete
 	if err := eg.Wait(); err != nil {
 		rps.api_ctx.LogErrorf("[Reverse Proxy] Error during shutdown: %v", err)
@@ -467,6 +540,11 @@ func (rps *ReverseProxyService) Stop() error {
 	// Clear the listeners
 	rps.httpListeners = nil
 	rps.tcpListeners = nil
+	rps.udpListeners = nil
+	rps.acmeChallengeServer = nil
+	if err := rps.accessLog.Close(); err != nil {
+		rps.api_ctx.LogErrorf("[Reverse Proxy] Error closing the access log: %v", err)
+	}
 
 	rps.State = ReverseProxyServiceStateStopped
 	rps.api_ctx.LogInfof("[Reverse Proxy] Service stopped")
@@ -511,7 +589,7 @@ func (rps *ReverseProxyService) restartInternal() error {
 		rps.api_ctx.LogErrorf("[Reverse Proxy] Failed to reload configuration: %v", err)
 		// Try to restore previous state
 		rps.State = previousState
-		rps.forwarding_hosts = previousHosts
+		rps.setForwardingHosts(previousHosts)
 		if err := rps.startInternal(); err != nil {
 			rps.api_ctx.LogErrorf("[Reverse Proxy] Failed to restore previous state: %v", err)
 		}
@@ -523,7 +601,7 @@ func (rps *ReverseProxyService) restartInternal() error {
 		rps.api_ctx.LogErrorf("[Reverse Proxy] Failed to start service during restart: %v", err)
 		// Try to restore previous state
 		rps.State = previousState
-		rps.forwarding_hosts = previousHosts
+		rps.setForwardingHosts(previousHosts)
 		if startErr := rps.startInternal(); startErr != nil {
 			rps.api_ctx.LogErrorf("[Reverse Proxy] Failed to restore previous state: %v", startErr)
 		}
@@ -534,11 +612,72 @@ func (rps *ReverseProxyService) restartInternal() error {
 	return nil
 }
 
+// forwardingHosts returns a copy of the hosts for the goroutines that do not
+// run in the operation queue
+func (rps *ReverseProxyService) forwardingHosts() []*data_models.ReverseProxyHost {
+	rps.hostMu.Lock()
+	defer rps.hostMu.Unlock()
+
+	result := make([]*data_models.ReverseProxyHost, len(rps.forwarding_hosts))
+	copy(result, rps.forwarding_hosts)
+	return result
+}
+
+func (rps *ReverseProxyService) setForwardingHosts(hosts []*data_models.ReverseProxyHost) {
+	rps.hostMu.Lock()
+	defer rps.hostMu.Unlock()
+
+	rps.forwarding_hosts = hosts
+}
+
 func (rps *ReverseProxyService) startServer(errorChan chan error) {
 	for _, host := range rps.forwarding_hosts {
 		h := host
 		rps.startHostListeners(h, errorChan)
 	}
+	rps.startAcmeChallengeListener()
+}
+
+// startAcmeChallengeListener serves the HTTP-01 challenges on the ACME http
+// port when a host needs them and no plain http host listens on that port
+func (rps *ReverseProxyService) startAcmeChallengeListener() {
+	if rps.acmeChallengeServer != nil {
+		return
+	}
+
+	needed := false
+	port := rps.certificates.Options.HttpPort
+	for _, host := range rps.forwarding_hosts {
+		if certificates.UsesHttpChallenge(host) {
+			needed = true
+		}
+		hostPort := host.Port
+		if hostPort == "" {
+			hostPort = "80"
+		}
+		if host.TcpRoute == nil && host.UdpRoute == nil && (host.Tls == nil || !host.Tls.Enabled) && hostPort == port {
+			return
+		}
+	}
+	if !needed {
+		return
+	}
+
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           rps.certificates.HTTPHandler(nil),
+		ReadHeaderTimeout: readTimeout,
+		ErrorLog:          log.New(io.Discard, "", 0),
+	}
+	rps.acmeChallengeServer = server
+	rps.httpListeners = append(rps.httpListeners, server)
+
+	rps.api_ctx.LogInfof("[Reverse Proxy] [ACME] Serving the http-01 challenges on port %s", port)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			rps.api_ctx.LogErrorf("[Reverse Proxy] [ACME] Error serving the http-01 challenges on port %s: %v", port, err)
+		}
+	}()
 }
 
 func (rps *ReverseProxyService) startHostListeners(h *data_models.ReverseProxyHost, errorChan chan error) {
@@ -558,7 +697,7 @@ func (rps *ReverseProxyService) startHostListeners(h *data_models.ReverseProxyHo
 	}
 
 	if h.TcpRoute != nil {
-		if h.TcpRoute.TargetHost == "" || h.TcpRoute.TargetPort == "---" {
+		if h.TcpRoute.Upstream == nil && (h.TcpRoute.TargetHost == "" || h.TcpRoute.TargetPort == "---") {
 			rps.api_ctx.LogErrorf("[TCP Route] target host is required for starting a tcp route, skipping host %s", h.GetHost())
 			hostCancel()
 			rps.hostMu.Lock()
@@ -574,6 +713,23 @@ func (rps *ReverseProxyService) startHostListeners(h *data_models.ReverseProxyHo
 				errorChan <- err
 			}
 		}()
+	} else if h.UdpRoute != nil {
+		if h.UdpRoute.TargetHost == "" || h.UdpRoute.TargetHost == "---" {
+			rps.api_ctx.LogErrorf("[UDP Route] target host is required for starting a udp route, skipping host %s", h.GetHost())
+			hostCancel()
+			rps.hostMu.Lock()
+			delete(rps.hostCancelFuncs, h.ID)
+			rps.hostMu.Unlock()
+			return
+		}
+
+		rps.wg.Add(1)
+		go func() {
+			defer rps.wg.Done()
+			if err := rps.listenUdpRoute(h, hostCtx, errorChan); err != nil {
+				errorChan <- err
+			}
+		}()
 	} else {
 		rps.wg.Add(1)
 		go func() {
@@ -623,10 +779,12 @@ func (rps *ReverseProxyService) Handle(ctx basecontext.ApiContext, clientID stri
 
 		if stateChange.CurrentState == "stopped" || stateChange.CurrentState == "paused" || stateChange.CurrentState == "suspended" {
 			// Find route by TargetVmId and emit route failed + host state events
-			for _, host := range rps.forwarding_hosts {
+			for _, host := range rps.forwardingHosts() {
 				failed := false
 				if host.TcpRoute != nil && host.TcpRoute.TargetVmId == stateChange.VmID {
 					failed = true
+				} else if host.UdpRoute != nil && host.UdpRoute.TargetVmId == stateChange.VmID {
+					failed = true
 				} else {
 					for _, route := range host.HttpRoutes {
 						if route.TargetVmId == stateChange.VmID {
@@ -653,10 +811,12 @@ func (rps *ReverseProxyService) Handle(ctx basecontext.ApiContext, clientID stri
 		} else if stateChange.CurrentState == "running" {
 			// Collect host IDs affected by this VM before restart clears the list
 			affectedHostIds := []string{}
-			for _, host := range rps.forwarding_hosts {
+			for _, host := range rps.forwardingHosts() {
 				matched := false
 				if host.TcpRoute != nil && host.TcpRoute.TargetVmId == stateChange.VmID {
 					matched = true
+				} else if host.UdpRoute != nil && host.UdpRoute.TargetVmId == stateChange.VmID {
+					matched = true
 				} else {
 					for _, route := range host.HttpRoutes {
 						if route.TargetVmId == stateChange.VmID {
@@ -733,6 +893,9 @@ func (rps *ReverseProxyService) checkVmIpChanged(hostID, vmID string) (bool, str
 		if host.TcpRoute != nil && host.TcpRoute.TargetVmId == vmID {
 			return host.TcpRoute.TargetHost != newIP, newIP
 		}
+		if host.UdpRoute != nil && host.UdpRoute.TargetVmId == vmID {
+			return host.UdpRoute.TargetHost != newIP, newIP
+		}
 		for _, route := range host.HttpRoutes {
 			if route.TargetVmId == vmID {
 				return route.TargetHost != newIP, newIP
@@ -770,6 +933,14 @@ func (rps *ReverseProxyService) reloadHostFromDb(hostID string) (*data_models.Re
 			hostCopy.TcpRoute.TargetHost = vm.InternalIpAddress
 		}
 	}
+	if hostCopy.UdpRoute != nil && hostCopy.UdpRoute.TargetVmId != "" {
+		vm, err := prl_svc.GetVm(rps.api_ctx, hostCopy.UdpRoute.TargetVmId)
+		if err != nil || vm == nil || vm.InternalIpAddress == "" || vm.InternalIpAddress == "-" || vm.State != "running" {
+			hostCopy.UdpRoute.TargetHost = "---"
+		} else {
+			hostCopy.UdpRoute.TargetHost = vm.InternalIpAddress
+		}
+	}
 	return &hostCopy, nil
 }
 
@@ -819,6 +990,8 @@ func (rps *ReverseProxyService) restartHostInternal(hostID string) error {
 		if h.ID == hostID {
 			if h.TcpRoute != nil {
 				oldIp = h.TcpRoute.TargetHost
+			} else if h.UdpRoute != nil {
+				oldIp = h.UdpRoute.TargetHost
 			} else if len(h.HttpRoutes) > 0 {
 				oldIp = h.HttpRoutes[0].TargetHost
 			}
@@ -827,17 +1000,21 @@ func (rps *ReverseProxyService) restartHostInternal(hostID string) error {
 	}
 	if newHost.TcpRoute != nil {
 		newIp = newHost.TcpRoute.TargetHost
+	} else if newHost.UdpRoute != nil {
+		newIp = newHost.UdpRoute.TargetHost
 	} else if len(newHost.HttpRoutes) > 0 {
 		newIp = newHost.HttpRoutes[0].TargetHost
 	}
 
 	// Update the in-memory forwarding_hosts entry.
+	rps.hostMu.Lock()
 	for i, h := range rps.forwarding_hosts {
 		if h.ID == hostID {
 			rps.forwarding_hosts[i] = newHost
 			break
 		}
 	}
+	rps.hostMu.Unlock()
 
 	if oldIp != newIp {
 		if emitter := serviceprovider.GetEventEmitter(); emitter != nil && emitter.IsRunning() {
@@ -855,16 +1032,23 @@ func (rps *ReverseProxyService) restartHostInternal(hostID string) error {
 	// goroutines do not block if nobody drains the channel.
 	errorChan := make(chan error, 1)
 	rps.startHostListeners(newHost, errorChan)
+	rps.startAcmeChallengeListener()
 
 	rps.api_ctx.LogInfof("[Reverse Proxy] Listeners restarted for host %s", hostID)
 	return nil
 }
 
 func (rps *ReverseProxyService) listenTcpRoute(host *data_models.ReverseProxyHost, hostCtx context.Context, errorChan chan error) error {
-	if host.TcpRoute.TargetPort == "" {
+	if host.TcpRoute.TargetPort == "" && host.TcpRoute.Upstream == nil {
 		return fmt.Errorf("[TCP Route] port is required for starting a tcp route")
 	}
 
+	var pool *upstreamPool
+	if host.TcpRoute.Upstream != nil {
+		pool = newUpstreamPool(rps.api_ctx, host.GetHost(), *host.TcpRoute.Upstream, host.TcpRoute.TargetPort, "", rps.listUpstreamVms)
+		go pool.run(hostCtx)
+	}
+
 	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%s", host.Host, host.Port))
 	if err != nil {
 		errorChan <- err
@@ -874,6 +1058,8 @@ func (rps *ReverseProxyService) listenTcpRoute(host *data_models.ReverseProxyHos
 	rps.tcpListeners = append(rps.tcpListeners, listener)
 	defer listener.Close()
 
+	gateway := newAuthGateway(rps.api_ctx, host.Auth)
+
 	rps.api_ctx.LogInfof("[Reverse Proxy] [TCP Route] Listening on %s:%s", host.Host, host.Port)
 	for {
 		select {
@@ -898,10 +1084,16 @@ func (rps *ReverseProxyService) listenTcpRoute(host *data_models.ReverseProxyHos
 			}
 		}
 
+		if !gateway.allowsAddress(conn.RemoteAddr().String()) {
+			rps.api_ctx.LogInfof("[Reverse Proxy] [TCP Route] Address %s is not allowed for %s:%s", conn.RemoteAddr(), host.Host, host.Port)
+			conn.Close()
+			continue
+		}
+
 		rps.activeConnections.Add(1)
 		go func() {
 			defer rps.activeConnections.Done()
-			rps.handleTcpTraffic(conn, host.ID, host.Host, fmt.Sprintf("%s:%s", host.TcpRoute.TargetHost, host.TcpRoute.TargetPort))
+			rps.handleTcpTraffic(conn, host, fmt.Sprintf("%s:%s", host.TcpRoute.TargetHost, host.TcpRoute.TargetPort), pool)
 		}()
 	}
 }
@@ -937,11 +1129,33 @@ func (rps *ReverseProxyService) listenHttpRoute(host *data_models.ReverseProxyHo
 		}
 	}
 
+	pools := make(map[*data_models.ReverseProxyHostHttpRoute]*upstreamPool)
+	for _, route := range host.HttpRoutes {
+		if route.Upstream == nil {
+			continue
+		}
+		pool := newUpstreamPool(rps.api_ctx, host.GetHost()+route.GetRoute(), *route.Upstream, route.TargetPort, route.Schema, rps.listUpstreamVms)
+		pools[route] = pool
+		go pool.run(hostCtx)
+	}
+
+	routeAuth := newHttpRouteAuth(rps.api_ctx, host)
+
 	mux := http.NewServeMux()
 	proxy := newReverseProxy(target)
+	proxy.Transport = newRouteTransport()
 	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
 		if err != nil {
 			rps.api_ctx.LogDebugf("[Reverse Proxy] [HTTP Route] Proxy error for %s: %v", r.URL.Path, err)
+			if selection, ok := r.Context().Value(upstreamSelectionKey{}).(*upstreamSelection); ok {
+				if selection.unavailable {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				if selection.member != nil {
+					selection.pool.reportFailure(selection.member)
+				}
+			}
 			if netErr, ok := err.(net.Error); ok {
 				if netErr.Timeout() {
 					w.WriteHeader(http.StatusGatewayTimeout)
@@ -967,6 +1181,15 @@ func (rps *ReverseProxyService) listenHttpRoute(host *data_models.ReverseProxyHo
 		rps.api_ctx.LogDebugf("[Reverse Proxy] [HTTP Route] Response status %d for %s",
 			resp.StatusCode, resp.Request.URL.Path)
 
+		if selection, ok := resp.Request.Context().Value(upstreamSelectionKey{}).(*upstreamSelection); ok && selection.member != nil {
+			switch resp.StatusCode {
+			case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+				selection.pool.reportFailure(selection.member)
+			default:
+				selection.pool.reportSuccess(selection.member)
+			}
+		}
+
 		if host.Cors != nil && host.Cors.Enabled {
 			rps.api_ctx.LogDebugf("[Reverse Proxy] [HTTP Route] Modifying response headers for CORS")
 			if len(host.Cors.AllowedOrigins) > 0 {
@@ -1017,7 +1240,8 @@ func (rps *ReverseProxyService) listenHttpRoute(host *data_models.ReverseProxyHo
 		if strings.EqualFold(target, req.Host) {
 			matched := false
 			for _, route := range host.HttpRoutes {
-				if route.TargetHost == "" || route.TargetHost == "---" {
+				pool := pools[route]
+				if pool == nil && (route.TargetHost == "" || route.TargetHost == "---") {
 					rps.api_ctx.LogErrorf("[HTTP Route] [%s] Target host is required for route %s, skipping",
 						requestID, route.Path)
 					continue
@@ -1032,6 +1256,26 @@ func (rps *ReverseProxyService) listenHttpRoute(host *data_models.ReverseProxyHo
 					if route.TargetPort != "" {
 						forwardTo = fmt.Sprintf("%s:%s", route.TargetHost, route.TargetPort)
 					}
+					if pool != nil {
+						selection, _ := req.Context().Value(upstreamSelectionKey{}).(*upstreamSelection)
+						member := pool.pick(req.RemoteAddr)
+						if member == nil {
+							rps.api_ctx.LogWarnf("[Reverse Proxy] [HTTP Route] [%s] No healthy target available for route %s",
+								requestID, route.GetRoute())
+							if selection != nil {
+								selection.unavailable = true
+							}
+							req.URL.Host = ""
+							break
+						}
+						if selection != nil {
+							selection.pool = pool
+							selection.member = member
+						} else {
+							member.release()
+						}
+						forwardTo = member.address
+					}
 
 					if strings.HasPrefix(forwardTo, "http") {
 						forwardTo = strings.TrimPrefix(forwardTo, "http://")
@@ -1067,7 +1311,7 @@ func (rps *ReverseProxyService) listenHttpRoute(host *data_models.ReverseProxyHo
 								TargetPort:         route.TargetPort,
 								Path:               req.URL.Path,
 								TrafficType:        "http",
-								InternalIpAddress:  route.TargetHost,
+								InternalIpAddress:  forwardTo,
 								Method:             req.Method,
 								SourceIp:           req.RemoteAddr,
 							})
@@ -1100,8 +1344,13 @@ func (rps *ReverseProxyService) listenHttpRoute(host *data_models.ReverseProxyHo
 				return
 			}
 
-			// Add request start time to context
-			ctx := context.WithValue(r.Context(), "request_start_time", time.Now())
+			// Add request start time, the matched route and the upstream selection to context
+			start := time.Now()
+			selection := &upstreamSelection{}
+			defer selection.release()
+			ctx := context.WithValue(r.Context(), "request_start_time", start)
+			ctx = context.WithValue(ctx, upstreamSelectionKey{}, selection)
+			ctx = context.WithValue(ctx, httpRouteKey{}, matchHttpRoute(host.HttpRoutes, r.URL.Path))
 			r = r.WithContext(ctx)
 
 			// Add request ID for tracing
@@ -1113,15 +1362,24 @@ func (rps *ReverseProxyService) listenHttpRoute(host *data_models.ReverseProxyHo
 			rps.api_ctx.LogDebugf("[Reverse Proxy] [HTTP Route] Starting request %s: %s",
 				requestID, r.URL.Path)
 
-			next.ServeHTTP(w, r)
+			recorder := &accessResponseWriter{ResponseWriter: w}
+			body := &countingBody{ReadCloser: r.Body}
+			r.Body = body
+			next.ServeHTTP(recorder, r)
+			rps.recordHttpAccess(host, r, recorder, body.read.Load(), start)
 		})
-	}(proxy))
+	}(routeAuth.Handler(newRouteProtocolHandler(proxy))))
+
+	if err := rps.certificates.LoadHost(host); err != nil {
+		rps.api_ctx.LogErrorf("[Reverse Proxy] [HTTP Route] %v", err)
+		return err
+	}
 
 	rps.api_ctx.LogInfof("[Reverse Proxy] [HTTP Route] Listening to %s on port %s...", host.Host, host.Port)
 	hostTarget := fmt.Sprintf("%s:%s", host.Host, host.Port)
 	server := &http.Server{
 		Addr:              hostTarget,
-		Handler:           mux,
+		Handler:           rps.certificates.HTTPHandler(mux),
 		ReadHeaderTimeout: readTimeout,
 		WriteTimeout:      writeTimeout,
 		IdleTimeout:       idleTimeout,
@@ -1143,11 +1401,26 @@ func (rps *ReverseProxyService) listenHttpRoute(host *data_models.ReverseProxyHo
 		},
 		ErrorLog: log.New(io.Discard, "", 0),
 	}
+	if host.Tls != nil && host.Tls.Enabled {
+		server.TLSConfig = rps.certificates.TLSConfig(host.ID)
+	}
+	if hostUsesHttp2(host) {
+		server.Protocols = new(http.Protocols)
+		server.Protocols.SetHTTP1(true)
+		server.Protocols.SetHTTP2(true)
+		server.Protocols.SetUnencryptedHTTP2(true)
+	}
 
 	rps.httpListeners = append(rps.httpListeners, server)
 
 	go func() {
-		if err := server.ListenAndServe(); err != nil {
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil {
 			if err != http.ErrServerClosed {
 				rps.api_ctx.LogErrorf("[Reverse Proxy] [HTTP Route] Server error for %s:%s - %v",
 					host.Host, host.Port, err)
@@ -1159,6 +1432,10 @@ func (rps *ReverseProxyService) listenHttpRoute(host *data_models.ReverseProxyHo
 		}
 	}()
 
+	if host.Tls.IsAcmeEnabled() {
+		go rps.certificates.EnsureCertificate(hostCtx, host)
+	}
+
 	<-hostCtx.Done()
 
 	ctxShutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -1169,7 +1446,7 @@ func (rps *ReverseProxyService) listenHttpRoute(host *data_models.ReverseProxyHo
 	return nil
 }
 
-func (rps *ReverseProxyService) handleTcpTraffic(src net.Conn, hostRecordId string, host string, target string) {
+func (rps *ReverseProxyService) handleTcpTraffic(src net.Conn, host *data_models.ReverseProxyHost, target string, pool *upstreamPool) {
 	rps.activeConnections.Add(1)
 	defer rps.activeConnections.Done()
 
@@ -1188,17 +1465,46 @@ func (rps *ReverseProxyService) handleTcpTraffic(src net.Conn, hostRecordId stri
 
 	defer src.Close()
 
-	dst, err := net.Dial("tcp", target)
+	stats := rps.trafficStats().route(host.ID, host.TcpRoute.ID, "", "tcp")
+	stats.active.Add(1)
+	defer stats.active.Add(-1)
+	entry := global_models.ReverseProxyAccessLogEvent{
+		ReverseProxyHostId: host.ID,
+		Host:               host.GetHost(),
+		RouteId:            host.TcpRoute.ID,
+		TrafficType:        "tcp",
+		ClientIp:           clientIp(src.RemoteAddr().String()),
+		RequestId:          connID,
+		TargetVmId:         host.TcpRoute.TargetVmId,
+	}
+
+	var dst net.Conn
+	var err error
+	if pool != nil {
+		var member *upstreamMember
+		dst, member, err = pool.dial(src.RemoteAddr().String())
+		entry.TargetVmId = ""
+		if member != nil {
+			target = member.address
+			entry.TargetVmId = member.vmId
+			defer member.release()
+		}
+	} else {
+		dst, err = net.Dial("tcp", target)
+	}
+	entry.Upstream = target
 	if err != nil {
 		rps.api_ctx.LogErrorf("[Reverse Proxy] [TCP Route] [%s] Unable to connect to target: %s",
 			connID, err)
+		entry.Error = err.Error()
+		rps.recordAccess(stats, entry, time.Since(startTime), true)
 		return
 	}
 
 	if rps.State == ReverseProxyServiceStateStarted {
 		if emitter := serviceprovider.GetEventEmitter(); emitter != nil && emitter.IsRunning() {
 			msg := global_models.NewEventMessage(constants.EventTypeReverseProxy, "TCP Traffic Forwarded", global_models.ReverseProxyForwardEvent{
-				ReverseProxyHostId: hostRecordId,
+				ReverseProxyHostId: host.ID,
 				TargetVmId:         "",
 				TargetHost:         target,
 				TargetPort:         "",
@@ -1220,11 +1526,20 @@ func (rps *ReverseProxyService) handleTcpTraffic(src net.Conn, hostRecordId stri
 	ctx, cancel := context.WithCancel(rps.ctx)
 	defer cancel()
 
-	go rps.copyWithContextAndTracking(ctx, dst, src, connID, "client->target")
-	rps.copyWithContextAndTracking(ctx, src, dst, connID, "target->client")
+	bytesIn := make(chan int64, 1)
+	go func() {
+		bytesIn <- rps.copyWithContextAndTracking(ctx, dst, src, connID, "client->target")
+	}()
+	entry.BytesOut = rps.copyWithContextAndTracking(ctx, src, dst, connID, "target->client")
+
+	// closing both ends stops the client copy if it is still reading
+	src.Close()
+	dst.Close()
+	entry.BytesIn = <-bytesIn
+	rps.recordAccess(stats, entry, time.Since(startTime), false)
 }
 
-func (rps *ReverseProxyService) copyWithContextAndTracking(ctx context.Context, dst io.Writer, src io.Reader, connID string, direction string) {
+func (rps *ReverseProxyService) copyWithContextAndTracking(ctx context.Context, dst io.Writer, src io.Reader, connID string, direction string) int64 {
 	var bytesCopied int64
 	startTime := time.Now()
 
@@ -1234,7 +1549,7 @@ func (rps *ReverseProxyService) copyWithContextAndTracking(ctx context.Context,
 		case <-ctx.Done():
 			rps.api_ctx.LogDebugf("[Reverse Proxy] [%s] [%s] Context cancelled after copying %d bytes in %v",
 				connID, direction, bytesCopied, time.Since(startTime))
-			return
+			return bytesCopied
 		default:
 			n, err := src.Read(buf)
 			if n > 0 {
@@ -1244,7 +1559,7 @@ func (rps *ReverseProxyService) copyWithContextAndTracking(ctx context.Context,
 						rps.api_ctx.LogDebugf("[Reverse Proxy] [%s] [%s] Write error: %v",
 							connID, direction, writeErr)
 					}
-					return
+					return bytesCopied
 				}
 			}
 			if err != nil {
@@ -1258,7 +1573,7 @@ func (rps *ReverseProxyService) copyWithContextAndTracking(ctx context.Context,
 					rps.api_ctx.LogDebugf("[Reverse Proxy] [%s] [%s] Read error after copying %d bytes in %v: %v",
 						connID, direction, bytesCopied, time.Since(startTime), err)
 				}
-				return
+				return bytesCopied
 			}
 		}
 	}
@@ -0,0 +1,194 @@
+package reverse_proxy
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"time"
+
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+)
+
+type httpRouteKey struct{}
+
+// matchHttpRoute returns the first route for the path, skipping the routes
+// without a target like the director does
+func matchHttpRoute(routes []*data_models.ReverseProxyHostHttpRoute, path string) *data_models.ReverseProxyHostHttpRoute {
+	for _, route := range routes {
+		if route.Upstream == nil && (route.TargetHost == "" || route.TargetHost == "---") {
+			continue
+		}
+		if route.RegexpPattern != nil && route.RegexpPattern.MatchString(path) {
+			return route
+		}
+	}
+
+	return nil
+}
+
+func httpRouteFromContext(ctx context.Context) *data_models.ReverseProxyHostHttpRoute {
+	route, _ := ctx.Value(httpRouteKey{}).(*data_models.ReverseProxyHostHttpRoute)
+	return route
+}
+
+// hostUsesHttp2 tells if the listener of the host needs to accept h2c, so
+// the grpc clients can reach the routes without tls
+func hostUsesHttp2(host *data_models.ReverseProxyHost) bool {
+	for _, route := range host.HttpRoutes {
+		if route.IsHttp2() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// routeTransport sends the requests of the http2 and grpc routes over
+// HTTP/2, using h2c for the http targets, the other routes keep the default
+// transport so their upgrades still work
+type routeTransport struct {
+	http1 http.RoundTripper
+	http2 *http.Transport
+}
+
+func newRouteTransport() *routeTransport {
+	http2 := http.DefaultTransport.(*http.Transport).Clone()
+	http2.Protocols = new(http.Protocols)
+	http2.Protocols.SetHTTP2(true)
+	http2.Protocols.SetUnencryptedHTTP2(true)
+
+	return &routeTransport{
+		http1: http.DefaultTransport,
+		http2: http2,
+	}
+}
+
+func (t *routeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if route := httpRouteFromContext(req.Context()); route != nil && route.IsHttp2() {
+		return t.http2.RoundTrip(req)
+	}
+
+	return t.http1.RoundTrip(req)
+}
+
+// routeProtocolHandler picks how a request is proxied, the streaming routes
+// flush every write and do not have the write timeout of the listener, the
+// upgraded connections are closed after the idle timeout
+type routeProtocolHandler struct {
+	proxy          *httputil.ReverseProxy
+	streamingProxy *httputil.ReverseProxy
+}
+
+func newRouteProtocolHandler(proxy *httputil.ReverseProxy) *routeProtocolHandler {
+	return &routeProtocolHandler{
+		proxy: proxy,
+		streamingProxy: &httputil.ReverseProxy{
+			Director:       proxy.Director,
+			Transport:      proxy.Transport,
+			FlushInterval:  -1,
+			ErrorHandler:   proxy.ErrorHandler,
+			ModifyResponse: proxy.ModifyResponse,
+			ErrorLog:       proxy.ErrorLog,
+		},
+	}
+}
+
+func (h *routeProtocolHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := httpRouteFromContext(r.Context())
+	controller := http.NewResponseController(w)
+
+	if isUpgradeRequest(r) {
+		timeout := websocketIdleTimeout
+		if route != nil && route.WebSocket != nil {
+			if !route.WebSocket.Enabled {
+				http.Error(w, "websockets are not enabled for this route", http.StatusBadRequest)
+				return
+			}
+			if value, err := time.ParseDuration(route.WebSocket.IdleTimeout); err == nil && value > 0 {
+				timeout = value
+			}
+		}
+
+		_ = controller.SetReadDeadline(time.Time{})
+		_ = controller.SetWriteDeadline(time.Time{})
+		h.proxy.ServeHTTP(&upgradeResponseWriter{ResponseWriter: w, idleTimeout: timeout}, r)
+		return
+	}
+
+	if route != nil && route.IsStreaming() {
+		_ = controller.SetWriteDeadline(time.Time{})
+		h.streamingProxy.ServeHTTP(w, r)
+		return
+	}
+
+	h.proxy.ServeHTTP(w, r)
+}
+
+func isUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// upgradeResponseWriter wraps the connection taken by the proxy when the
+// target switches protocols, so it can be closed once it is idle
+type upgradeResponseWriter struct {
+	http.ResponseWriter
+	idleTimeout time.Duration
+}
+
+func (w *upgradeResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *upgradeResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	idleConn := &idleTimeoutConn{Conn: conn, timeout: w.idleTimeout}
+	idleConn.extend()
+	return idleConn, rw, nil
+}
+
+// idleTimeoutConn moves the deadline of the connection on every read and
+// write, so it only expires when there is no traffic in any direction
+type idleTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *idleTimeoutConn) extend() {
+	if c.timeout > 0 {
+		_ = c.Conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+}
+
+func (c *idleTimeoutConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.extend()
+	}
+	return n, err
+}
+
+func (c *idleTimeoutConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.extend()
+	}
+	return n, err
+}
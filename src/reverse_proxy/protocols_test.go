@@ -0,0 +1,224 @@
+package reverse_proxy
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/reverse_proxy/certificates"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// startTestHttpHost runs a host listener with the routes and returns its
+// address, the listener is stopped when the test ends
+func startTestHttpHost(t *testing.T, routes ...*data_models.ReverseProxyHostHttpRoute) string {
+	_, address := startTestHttpService(t, routes...)
+	return address
+}
+
+func startTestHttpService(t *testing.T, routes ...*data_models.ReverseProxyHostHttpRoute) (*ReverseProxyService, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+	rps := &ReverseProxyService{
+		api_ctx:      ctx,
+		certificates: certificates.New(ctx, nil),
+	}
+	host := &data_models.ReverseProxyHost{
+		ID:         "test",
+		Host:       "127.0.0.1",
+		Port:       port,
+		HttpRoutes: routes,
+	}
+
+	hostCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = rps.listenHttpRoute(host, hostCtx, make(chan error, 1))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	address := net.JoinHostPort("127.0.0.1", port)
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, 5*time.Second, 20*time.Millisecond)
+
+	return rps, address
+}
+
+func testRouteTo(t *testing.T, path string, backend string) *data_models.ReverseProxyHostHttpRoute {
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(backend, "http://"))
+	require.NoError(t, err)
+	return &data_models.ReverseProxyHostHttpRoute{Path: path, TargetHost: host, TargetPort: port}
+}
+
+func TestProxyWebSocket(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, message); err != nil {
+				return
+			}
+		}
+	}))
+	defer backend.Close()
+
+	echo := testRouteTo(t, "/echo", backend.URL)
+	echo.WebSocket = &data_models.ReverseProxyHostHttpRouteWebSocket{Enabled: true, IdleTimeout: "300ms"}
+	disabled := testRouteTo(t, "/disabled", backend.URL)
+	disabled.WebSocket = &data_models.ReverseProxyHostHttpRouteWebSocket{}
+	address := startTestHttpHost(t, echo, disabled)
+
+	t.Run("echo and idle timeout", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial("ws://"+address+"/echo", nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		for _, message := range []string{"hello", "world"} {
+			require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(message)))
+			_, received, err := conn.ReadMessage()
+			require.NoError(t, err)
+			assert.Equal(t, message, string(received))
+			time.Sleep(150 * time.Millisecond)
+		}
+
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, _, err = conn.ReadMessage()
+		require.Error(t, err)
+		netErr, ok := err.(net.Error)
+		assert.False(t, ok && netErr.Timeout(), "the proxy should close the idle connection")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		_, response, err := websocket.DefaultDialer.Dial("ws://"+address+"/disabled", nil)
+		require.Error(t, err)
+		require.NotNil(t, response)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+}
+
+func TestProxyGrpc(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("vm", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Stop()
+
+	route := testRouteTo(t, "/", "http://"+listener.Addr().String())
+	route.Protocol = "grpc"
+	address := startTestHttpHost(t, route)
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	response, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "vm"})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, response.Status)
+
+	// the status of the watch stream has to arrive while the stream is open
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "vm"})
+	require.NoError(t, err)
+	update, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, update.Status)
+
+	healthServer.SetServingStatus("vm", healthpb.HealthCheckResponse_NOT_SERVING)
+	update, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, update.Status)
+}
+
+func TestProxyStreamingRoute(t *testing.T) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte("first\n"))
+		w.(http.Flusher).Flush()
+		<-release
+		_, _ = w.Write([]byte("second\n"))
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	route := testRouteTo(t, "/events", backend.URL)
+	route.Streaming = true
+	address := startTestHttpHost(t, route)
+
+	response, err := http.Get("http://" + address + "/events")
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(response.Body).ReadString('\n')
+		lines <- line
+	}()
+	select {
+	case line := <-lines:
+		assert.Equal(t, "first\n", line)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the first line was not streamed before the response ended")
+	}
+}
+
+func TestRouteTransportPicksProtocol(t *testing.T) {
+	http2Route := &data_models.ReverseProxyHostHttpRoute{Protocol: "http2"}
+	host := &data_models.ReverseProxyHost{HttpRoutes: []*data_models.ReverseProxyHostHttpRoute{{}, http2Route}}
+	assert.True(t, hostUsesHttp2(host))
+	assert.False(t, hostUsesHttp2(&data_models.ReverseProxyHost{HttpRoutes: []*data_models.ReverseProxyHostHttpRoute{{Streaming: true}}}))
+
+	assert.True(t, (&data_models.ReverseProxyHostHttpRoute{Protocol: "grpc"}).IsStreaming())
+	assert.False(t, http2Route.IsStreaming())
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Connection", "keep-alive, Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	assert.True(t, isUpgradeRequest(r))
+	r.Header.Del("Upgrade")
+	assert.False(t, isUpgradeRequest(r))
+}
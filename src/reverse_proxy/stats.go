@@ -0,0 +1,221 @@
+package reverse_proxy
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	global_models "github.com/Parallels/prl-devops-service/models"
+)
+
+// latencyBuckets are the upper bounds of the latency histograms, anything
+// slower goes to the +Inf bucket
+var latencyBuckets = [...]time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	60 * time.Second,
+}
+
+// routeStats counts the traffic of a route, the counters are updated without
+// locks as they are hit by every request
+type routeStats struct {
+	routeId     string
+	route       string
+	trafficType string
+
+	requests atomic.Int64
+	active   atomic.Int64
+	errors   atomic.Int64
+	bytesIn  atomic.Int64
+	bytesOut atomic.Int64
+	// status classes from 1xx to 5xx
+	status [5]atomic.Int64
+
+	latencyCount   atomic.Int64
+	latencySumUs   atomic.Int64
+	latencyBuckets [len(latencyBuckets) + 1]atomic.Int64
+}
+
+func (s *routeStats) record(status int, bytesIn int64, bytesOut int64, latency time.Duration, failed bool) {
+	s.requests.Add(1)
+	s.bytesIn.Add(bytesIn)
+	s.bytesOut.Add(bytesOut)
+	if status >= 100 && status < 600 {
+		s.status[status/100-1].Add(1)
+	}
+	if failed || status >= 500 {
+		s.errors.Add(1)
+	}
+
+	s.latencyCount.Add(1)
+	s.latencySumUs.Add(latency.Microseconds())
+	bucket := len(latencyBuckets)
+	for i, bound := range latencyBuckets {
+		if latency <= bound {
+			bucket = i
+			break
+		}
+	}
+	s.latencyBuckets[bucket].Add(1)
+}
+
+func (s *routeStats) snapshot() global_models.ReverseProxyRouteStats {
+	result := global_models.ReverseProxyRouteStats{
+		RouteId:     s.routeId,
+		Route:       s.route,
+		TrafficType: s.trafficType,
+		ReverseProxyTrafficStats: global_models.ReverseProxyTrafficStats{
+			Requests:          s.requests.Load(),
+			ActiveConnections: s.active.Load(),
+			Errors:            s.errors.Load(),
+			BytesIn:           s.bytesIn.Load(),
+			BytesOut:          s.bytesOut.Load(),
+		},
+	}
+
+	for i := range s.status {
+		if count := s.status[i].Load(); count > 0 {
+			if result.StatusCodes == nil {
+				result.StatusCodes = make(map[string]int64)
+			}
+			result.StatusCodes[fmt.Sprintf("%dxx", i+1)] = count
+		}
+	}
+
+	latency := &result.Latency
+	latency.Count = s.latencyCount.Load()
+	latency.SumMs = float64(s.latencySumUs.Load()) / 1000
+	if latency.Count > 0 {
+		latency.AverageMs = latency.SumMs / float64(latency.Count)
+	}
+	var cumulative int64
+	for i := range s.latencyBuckets {
+		cumulative += s.latencyBuckets[i].Load()
+		le := "+Inf"
+		if i < len(latencyBuckets) {
+			le = latencyBuckets[i].String()
+		}
+		latency.Buckets = append(latency.Buckets, global_models.ReverseProxyLatencyBucket{Le: le, Count: cumulative})
+	}
+
+	return result
+}
+
+// trafficStats keeps the route counters of every host, they live for the
+// whole service so restarting the proxy does not reset them
+type trafficStats struct {
+	mu    sync.Mutex
+	hosts map[string]map[string]*routeStats
+}
+
+func newTrafficStats() *trafficStats {
+	return &trafficStats{
+		hosts: make(map[string]map[string]*routeStats),
+	}
+}
+
+// route returns the counters of the route, routeId is empty for the requests
+// that did not match any route
+func (t *trafficStats) route(hostId string, routeId string, route string, trafficType string) *routeStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	routes, ok := t.hosts[hostId]
+	if !ok {
+		routes = make(map[string]*routeStats)
+		t.hosts[hostId] = routes
+	}
+	stats, ok := routes[routeId]
+	if !ok {
+		stats = &routeStats{routeId: routeId, trafficType: trafficType}
+		routes[routeId] = stats
+	}
+	stats.route = route
+
+	return stats
+}
+
+// prune drops the counters of the hosts and routes that are no longer
+// configured, the requests that did not match any route are kept with their
+// host
+func (t *trafficStats) prune(hosts []data_models.ReverseProxyHost) {
+	configured := make(map[string]map[string]bool, len(hosts))
+	for _, host := range hosts {
+		routes := map[string]bool{"": true}
+		for _, route := range host.HttpRoutes {
+			if route != nil {
+				routes[route.ID] = true
+			}
+		}
+		if host.TcpRoute != nil {
+			routes[host.TcpRoute.ID] = true
+		}
+		if host.UdpRoute != nil {
+			routes[host.UdpRoute.ID] = true
+		}
+		configured[host.ID] = routes
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for hostId, routes := range t.hosts {
+		configuredRoutes, ok := configured[hostId]
+		if !ok {
+			delete(t.hosts, hostId)
+			continue
+		}
+		for routeId := range routes {
+			if !configuredRoutes[routeId] {
+				delete(routes, routeId)
+			}
+		}
+	}
+}
+
+func (t *trafficStats) httpRoute(host *data_models.ReverseProxyHost, route *data_models.ReverseProxyHostHttpRoute) *routeStats {
+	if route == nil {
+		return t.route(host.ID, "", "", "http")
+	}
+
+	return t.route(host.ID, route.ID, route.GetRoute(), "http")
+}
+
+// host returns the counters of the host, the routes are sorted by their
+// route so the output is stable
+func (t *trafficStats) host(hostId string) global_models.ReverseProxyHostStats {
+	t.mu.Lock()
+	result := global_models.ReverseProxyHostStats{
+		ReverseProxyHostId: hostId,
+		Routes:             make([]global_models.ReverseProxyRouteStats, 0, len(t.hosts[hostId])),
+	}
+	for _, stats := range t.hosts[hostId] {
+		result.Routes = append(result.Routes, stats.snapshot())
+	}
+	t.mu.Unlock()
+
+	for _, route := range result.Routes {
+		result.Totals.Add(route.ReverseProxyTrafficStats)
+	}
+	sort.Slice(result.Routes, func(i, j int) bool {
+		if result.Routes[i].Route == result.Routes[j].Route {
+			return result.Routes[i].RouteId < result.Routes[j].RouteId
+		}
+		return result.Routes[i].Route < result.Routes[j].Route
+	})
+
+	return result
+}
@@ -0,0 +1,211 @@
+package reverse_proxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	global_models "github.com/Parallels/prl-devops-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteStatsRecord(t *testing.T) {
+	stats := &routeStats{routeId: "route", route: "/api", trafficType: "http"}
+	stats.record(http.StatusOK, 10, 100, 2*time.Millisecond, false)
+	stats.record(http.StatusNotFound, 5, 20, 40*time.Millisecond, false)
+	stats.record(http.StatusBadGateway, 5, 0, 2*time.Minute, false)
+	stats.record(0, 0, 0, time.Millisecond, true)
+
+	snapshot := stats.snapshot()
+	assert.Equal(t, "route", snapshot.RouteId)
+	assert.Equal(t, "/api", snapshot.Route)
+	assert.Equal(t, int64(4), snapshot.Requests)
+	assert.Equal(t, int64(2), snapshot.Errors)
+	assert.Equal(t, int64(20), snapshot.BytesIn)
+	assert.Equal(t, int64(120), snapshot.BytesOut)
+	assert.Equal(t, map[string]int64{"2xx": 1, "4xx": 1, "5xx": 1}, snapshot.StatusCodes)
+
+	latency := snapshot.Latency
+	assert.Equal(t, int64(4), latency.Count)
+	require.Len(t, latency.Buckets, len(latencyBuckets)+1)
+	assert.Equal(t, global_models.ReverseProxyLatencyBucket{Le: "5ms", Count: 2}, latency.Buckets[0])
+	assert.Equal(t, global_models.ReverseProxyLatencyBucket{Le: "50ms", Count: 3}, latency.Buckets[3])
+	assert.Equal(t, global_models.ReverseProxyLatencyBucket{Le: "1m0s", Count: 3}, latency.Buckets[len(latencyBuckets)-1])
+	assert.Equal(t, global_models.ReverseProxyLatencyBucket{Le: "+Inf", Count: 4}, latency.Buckets[len(latencyBuckets)])
+}
+
+func TestTrafficStatsHost(t *testing.T) {
+	stats := newTrafficStats()
+	stats.route("host", "b", "/b", "http").record(http.StatusOK, 1, 2, time.Millisecond, false)
+	stats.route("host", "a", "/a", "http").record(http.StatusOK, 3, 4, time.Millisecond, false)
+	stats.route("other", "c", "/c", "http").record(http.StatusOK, 5, 6, time.Millisecond, false)
+
+	result := stats.host("host")
+	assert.Equal(t, "host", result.ReverseProxyHostId)
+	require.Len(t, result.Routes, 2)
+	assert.Equal(t, "/a", result.Routes[0].Route)
+	assert.Equal(t, "/b", result.Routes[1].Route)
+	assert.Equal(t, int64(2), result.Totals.Requests)
+	assert.Equal(t, int64(4), result.Totals.BytesIn)
+	assert.Equal(t, int64(6), result.Totals.BytesOut)
+	assert.Equal(t, map[string]int64{"2xx": 2}, result.Totals.StatusCodes)
+	assert.Equal(t, int64(2), result.Totals.Latency.Buckets[0].Count)
+	assert.Equal(t, int64(2), result.Totals.Latency.Count)
+	assert.Equal(t, 2.0, result.Totals.Latency.SumMs)
+	assert.Equal(t, 1.0, result.Totals.Latency.AverageMs)
+	require.Len(t, result.Totals.Latency.Buckets, len(latencyBuckets)+1)
+	assert.Equal(t, "+Inf", result.Totals.Latency.Buckets[len(latencyBuckets)].Le)
+
+	assert.Empty(t, stats.host("missing").Routes)
+}
+
+func TestTrafficStatsPrune(t *testing.T) {
+	stats := newTrafficStats()
+	stats.route("host", "", "", "http").record(http.StatusNotFound, 1, 1, time.Millisecond, false)
+	stats.route("host", "kept", "/kept", "http").record(http.StatusOK, 1, 1, time.Millisecond, false)
+	stats.route("host", "deleted", "/deleted", "http").record(http.StatusOK, 1, 1, time.Millisecond, false)
+	stats.route("host", "udp", "", "udp").record(0, 1, 1, time.Millisecond, false)
+	stats.route("deleted", "route", "/route", "http").record(http.StatusOK, 1, 1, time.Millisecond, false)
+
+	stats.prune([]data_models.ReverseProxyHost{{
+		ID:         "host",
+		HttpRoutes: []*data_models.ReverseProxyHostHttpRoute{{ID: "kept"}},
+		UdpRoute:   &data_models.ReverseProxyHostUdpRoute{ID: "udp"},
+	}})
+
+	routes := make([]string, 0)
+	for _, route := range stats.host("host").Routes {
+		routes = append(routes, route.RouteId)
+	}
+	assert.ElementsMatch(t, []string{"", "kept", "udp"}, routes)
+	assert.NotContains(t, stats.hosts, "deleted")
+}
+
+func TestAccessLogWritesFile(t *testing.T) {
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+	path := filepath.Join(t.TempDir(), "access.log")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	require.NoError(t, err)
+
+	log := &accessLog{ctx: ctx, toFile: true, file: file}
+	log.Write(global_models.ReverseProxyAccessLogEvent{Host: "example.com", Method: http.MethodGet, Path: "/", Status: http.StatusOK})
+	log.Write(global_models.ReverseProxyAccessLogEvent{Host: "example.com", TrafficType: "tcp", Error: "refused"})
+	require.NoError(t, log.Close())
+	log.Write(global_models.ReverseProxyAccessLogEvent{Host: "closed"})
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 2)
+
+	var entry global_models.ReverseProxyAccessLogEvent
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "example.com", entry.Host)
+	assert.Equal(t, http.StatusOK, entry.Status)
+	assert.NotEmpty(t, entry.Timestamp)
+}
+
+func TestFormatAccessLogEntry(t *testing.T) {
+	line := formatAccessLogEntry(global_models.ReverseProxyAccessLogEvent{
+		Host:      "example.com",
+		ClientIp:  "10.0.0.1",
+		Method:    http.MethodPost,
+		Status:    http.StatusCreated,
+		BytesIn:   3,
+		LatencyMs: 1.5,
+	})
+
+	assert.Equal(t, `host="example.com" client="10.0.0.1" method="POST" status=201 bytes_in=3 bytes_out=0 latency_ms=1.500`, line)
+}
+
+func TestProxyHttpRouteStats(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	}))
+	defer backend.Close()
+
+	route := testRouteTo(t, "/api", backend.URL)
+	route.ID = "api"
+	rps, address := startTestHttpService(t, route)
+
+	for i := 0; i < 2; i++ {
+		resp, err := http.Post("http://"+address+"/api", "text/plain", strings.NewReader("hello"))
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	}
+
+	// the request is recorded after the response is sent to the client
+	var stats global_models.ReverseProxyHostStats
+	require.Eventually(t, func() bool {
+		stats = rps.GetHostStats(data_models.ReverseProxyHost{ID: "test", Host: "127.0.0.1"})
+		return stats.Totals.Requests == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Len(t, stats.Routes, 1)
+	assert.Equal(t, "api", stats.Routes[0].RouteId)
+	assert.Equal(t, map[string]int64{"2xx": 2}, stats.Totals.StatusCodes)
+	assert.Equal(t, int64(10), stats.Totals.BytesIn)
+	assert.Equal(t, int64(14), stats.Totals.BytesOut)
+	assert.Equal(t, int64(0), stats.Totals.ActiveConnections)
+}
+
+func TestProxyTcpTrafficStats(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer target.Close()
+	go func() {
+		conn, err := target.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 5)
+		if _, err := io.ReadFull(conn, buf); err == nil {
+			_, _ = conn.Write(buf)
+		}
+	}()
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+	rps := &ReverseProxyService{api_ctx: ctx, ctx: context.Background()}
+	host := &data_models.ReverseProxyHost{ID: "test", TcpRoute: &data_models.ReverseProxyHostTcpRoute{ID: "tcp"}}
+
+	client, src := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rps.handleTcpTraffic(src, host, target.Addr().String(), nil)
+	}()
+
+	_, err = client.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(client, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf))
+	client.Close()
+	<-done
+
+	stats := rps.GetHostStats(*host)
+	require.Len(t, stats.Routes, 1)
+	assert.Equal(t, "tcp", stats.Routes[0].TrafficType)
+	assert.Equal(t, int64(1), stats.Totals.Requests)
+	assert.Equal(t, int64(5), stats.Totals.BytesIn)
+	assert.Equal(t, int64(5), stats.Totals.BytesOut)
+	assert.Equal(t, int64(0), stats.Totals.Errors)
+}
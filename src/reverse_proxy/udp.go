@@ -0,0 +1,314 @@
+package reverse_proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/constants"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/helpers"
+	global_models "github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+)
+
+const udpMaxDatagramSize = 64 * 1024
+
+// udpSession is the traffic of one client of a udp route, the datagrams of
+// the client are sent from its own socket so the replies of the target can
+// be routed back to it
+type udpSession struct {
+	id       string
+	client   net.Addr
+	conn     net.Conn
+	lastSeen atomic.Int64
+	bytesIn  atomic.Int64
+	bytesOut atomic.Int64
+}
+
+func (s *udpSession) touch() {
+	s.lastSeen.Store(time.Now().UnixNano())
+}
+
+func (s *udpSession) idleSince() time.Time {
+	return time.Unix(0, s.lastSeen.Load())
+}
+
+// udpSessions keeps the sessions of a udp route by client address, up to
+// maxSessions clients
+type udpSessions struct {
+	mu          sync.Mutex
+	sessions    map[string]*udpSession
+	target      string
+	idleTimeout time.Duration
+	maxSessions int
+}
+
+func newUdpSessions(target string, idleTimeout time.Duration, maxSessions int) *udpSessions {
+	return &udpSessions{
+		sessions:    make(map[string]*udpSession),
+		target:      target,
+		idleTimeout: idleTimeout,
+		maxSessions: maxSessions,
+	}
+}
+
+// get returns the session of the client, dialing the target when the client
+// does not have one yet. When the route already has its maximum sessions the
+// least recently seen one is closed to make room, it is returned as evicted
+func (s *udpSessions) get(client net.Addr) (*udpSession, bool, *udpSession, error) {
+	key := client.String()
+	if session := s.existing(key); session != nil {
+		return session, false, nil, nil
+	}
+
+	// the target is dialed without the lock, a slow target does not hold the
+	// other clients of the route
+	conn, err := net.Dial("udp", s.target)
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session, ok := s.sessions[key]; ok {
+		// another datagram of the client opened the session while dialing
+		conn.Close()
+		session.touch()
+		return session, false, nil, nil
+	}
+
+	var evicted *udpSession
+	if s.maxSessions > 0 && len(s.sessions) >= s.maxSessions {
+		evicted = s.oldest()
+		delete(s.sessions, evicted.client.String())
+		// closing the socket ends the session handler of the evicted client
+		evicted.conn.Close()
+	}
+
+	session := &udpSession{
+		id:     helpers.GenerateId(),
+		client: client,
+		conn:   conn,
+	}
+	session.touch()
+	s.sessions[key] = session
+	return session, true, evicted, nil
+}
+
+func (s *udpSessions) existing(key string) *udpSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[key]
+	if !ok {
+		return nil
+	}
+	session.touch()
+	return session
+}
+
+func (s *udpSessions) oldest() *udpSession {
+	var oldest *udpSession
+	for _, session := range s.sessions {
+		if oldest == nil || session.lastSeen.Load() < oldest.lastSeen.Load() {
+			oldest = session
+		}
+	}
+
+	return oldest
+}
+
+func (s *udpSessions) remove(session *udpSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, ok := s.sessions[session.client.String()]; ok && current == session {
+		delete(s.sessions, session.client.String())
+	}
+	session.conn.Close()
+}
+
+func (s *udpSessions) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, session := range s.sessions {
+		session.conn.Close()
+		delete(s.sessions, key)
+	}
+}
+
+func (s *udpSessions) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+func udpRouteIdleTimeout(route *data_models.ReverseProxyHostUdpRoute) time.Duration {
+	if value, err := time.ParseDuration(route.IdleTimeout); err == nil && value > 0 {
+		return value
+	}
+
+	return udpIdleTimeout
+}
+
+func udpRouteMaxSessions(route *data_models.ReverseProxyHostUdpRoute) int {
+	if route.MaxSessions > 0 {
+		return route.MaxSessions
+	}
+
+	return udpMaxSessions
+}
+
+func (rps *ReverseProxyService) listenUdpRoute(host *data_models.ReverseProxyHost, hostCtx context.Context, errorChan chan error) error {
+	if host.UdpRoute.TargetPort == "" {
+		return fmt.Errorf("[UDP Route] port is required for starting a udp route")
+	}
+
+	listener, err := net.ListenPacket("udp", fmt.Sprintf("%s:%s", host.Host, host.Port))
+	if err != nil {
+		errorChan <- err
+		return err
+	}
+
+	rps.udpListeners = append(rps.udpListeners, listener)
+	defer listener.Close()
+
+	target := net.JoinHostPort(host.UdpRoute.TargetHost, host.UdpRoute.TargetPort)
+	sessions := newUdpSessions(target, udpRouteIdleTimeout(host.UdpRoute), udpRouteMaxSessions(host.UdpRoute))
+	defer sessions.closeAll()
+	gateway := newAuthGateway(rps.api_ctx, host.Auth)
+
+	// reading from the listener does not watch the context, closing it is
+	// what stops the loop when the host is restarted
+	stop := context.AfterFunc(hostCtx, func() {
+		listener.Close()
+	})
+	defer stop()
+
+	rps.api_ctx.LogInfof("[Reverse Proxy] [UDP Route] Listening on %s:%s", host.Host, host.Port)
+	buf := make([]byte, udpMaxDatagramSize)
+	for {
+		n, client, err := listener.ReadFrom(buf)
+		if err != nil {
+			if hostCtx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				rps.api_ctx.LogDebugf("[Reverse Proxy] [UDP Route] Shutting down listener for %s:%s", host.Host, host.Port)
+				return nil
+			}
+			rps.api_ctx.LogErrorf("[Reverse Proxy] [UDP Route] Error reading datagram: %s", err)
+			return err
+		}
+
+		if !gateway.allowsAddress(client.String()) {
+			rps.api_ctx.LogDebugf("[Reverse Proxy] [UDP Route] Address %s is not allowed for %s:%s", client, host.Host, host.Port)
+			continue
+		}
+
+		session, created, evicted, err := sessions.get(client)
+		if evicted != nil {
+			rps.api_ctx.LogWarnf("[Reverse Proxy] [UDP Route] [%s] Closing the session of %s, %s:%s has reached its limit of %d sessions",
+				evicted.id, evicted.client, host.Host, host.Port, sessions.maxSessions)
+		}
+		if err != nil {
+			rps.api_ctx.LogErrorf("[Reverse Proxy] [UDP Route] Unable to connect to target %s: %s", target, err)
+			rps.recordAccess(rps.udpRouteStats(host), global_models.ReverseProxyAccessLogEvent{
+				ReverseProxyHostId: host.ID,
+				Host:               host.GetHost(),
+				RouteId:            host.UdpRoute.ID,
+				TrafficType:        "udp",
+				ClientIp:           clientIp(client.String()),
+				Upstream:           target,
+				TargetVmId:         host.UdpRoute.TargetVmId,
+				BytesIn:            int64(n),
+				Error:              err.Error(),
+			}, 0, true)
+			continue
+		}
+		if created {
+			rps.activeConnections.Add(1)
+			go func() {
+				defer rps.activeConnections.Done()
+				rps.handleUdpSession(listener, sessions, session, host)
+			}()
+		}
+
+		session.bytesIn.Add(int64(n))
+		if _, err := session.conn.Write(buf[:n]); err != nil {
+			rps.api_ctx.LogDebugf("[Reverse Proxy] [UDP Route] [%s] Error writing to target: %s", session.id, err)
+		}
+	}
+}
+
+// handleUdpSession sends the replies of the target back to the client until
+// the session is idle for longer than the idle timeout
+func (rps *ReverseProxyService) handleUdpSession(listener net.PacketConn, sessions *udpSessions, session *udpSession, host *data_models.ReverseProxyHost) {
+	startTime := time.Now()
+	rps.api_ctx.LogInfof("[Reverse Proxy] [UDP Route] [%s] New session from %s to %s",
+		session.id, session.client, sessions.target)
+
+	stats := rps.udpRouteStats(host)
+	stats.active.Add(1)
+	defer func() {
+		sessions.remove(session)
+		stats.active.Add(-1)
+		rps.recordAccess(stats, global_models.ReverseProxyAccessLogEvent{
+			ReverseProxyHostId: host.ID,
+			Host:               host.GetHost(),
+			RouteId:            host.UdpRoute.ID,
+			TrafficType:        "udp",
+			ClientIp:           clientIp(session.client.String()),
+			RequestId:          session.id,
+			Upstream:           sessions.target,
+			TargetVmId:         host.UdpRoute.TargetVmId,
+			BytesIn:            session.bytesIn.Load(),
+			BytesOut:           session.bytesOut.Load(),
+		}, time.Since(startTime), false)
+		rps.api_ctx.LogInfof("[Reverse Proxy] [UDP Route] [%s] Session closed after %v",
+			session.id, time.Since(startTime))
+	}()
+
+	if rps.State == ReverseProxyServiceStateStarted {
+		if emitter := serviceprovider.GetEventEmitter(); emitter != nil && emitter.IsRunning() {
+			msg := global_models.NewEventMessage(constants.EventTypeReverseProxy, "UDP Traffic Forwarded", global_models.ReverseProxyForwardEvent{
+				ReverseProxyHostId: host.ID,
+				TargetHost:         sessions.target,
+				TrafficType:        "udp",
+				InternalIpAddress:  sessions.target,
+				SourceIp:           session.client.String(),
+			})
+			go func() { _ = emitter.Broadcast(msg) }()
+		}
+	}
+
+	buf := make([]byte, udpMaxDatagramSize)
+	for {
+		_ = session.conn.SetReadDeadline(session.idleSince().Add(sessions.idleTimeout))
+		n, err := session.conn.Read(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() && time.Since(session.idleSince()) < sessions.idleTimeout {
+				// the client sent a datagram while we were waiting
+				continue
+			}
+			return
+		}
+
+		session.touch()
+		written, err := listener.WriteTo(buf[:n], session.client)
+		session.bytesOut.Add(int64(written))
+		if err != nil {
+			rps.api_ctx.LogDebugf("[Reverse Proxy] [UDP Route] [%s] Error writing to client: %s", session.id, err)
+			return
+		}
+	}
+}
+
+func (rps *ReverseProxyService) udpRouteStats(host *data_models.ReverseProxyHost) *routeStats {
+	return rps.trafficStats().route(host.ID, host.UdpRoute.ID, "", "udp")
+}
@@ -0,0 +1,248 @@
+package reverse_proxy
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startUdpEcho runs a udp target that replies every datagram with its
+// content and returns its port
+func startUdpEcho(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, udpMaxDatagramSize)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = conn.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(conn.LocalAddr().String())
+	return port
+}
+
+// startTestUdpHost runs a udp host listener for the route and returns its
+// address, the listener is stopped when the test ends
+func startTestUdpHost(t *testing.T, route *data_models.ReverseProxyHostUdpRoute, auth *data_models.ReverseProxyAuth) string {
+	probe, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, port, _ := net.SplitHostPort(probe.LocalAddr().String())
+	probe.Close()
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+	rps := &ReverseProxyService{api_ctx: ctx}
+	host := &data_models.ReverseProxyHost{
+		ID:       "test",
+		Host:     "127.0.0.1",
+		Port:     port,
+		Auth:     auth,
+		UdpRoute: route,
+	}
+
+	hostCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = rps.listenUdpRoute(host, hostCtx, make(chan error, 1))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		rps.activeConnections.Wait()
+	})
+
+	address := net.JoinHostPort("127.0.0.1", port)
+	require.Eventually(t, func() bool {
+		// the port can only be taken again before the host listens on it
+		conn, err := net.ListenPacket("udp", address)
+		if err != nil {
+			return true
+		}
+		conn.Close()
+		return false
+	}, 5*time.Second, 20*time.Millisecond)
+
+	return address
+}
+
+func exchangeUdp(t *testing.T, conn net.Conn, message string) (string, error) {
+	_, err := conn.Write([]byte(message))
+	require.NoError(t, err)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	return string(buf[:n]), err
+}
+
+func TestProxyUdpRoute(t *testing.T) {
+	targetPort := startUdpEcho(t)
+	address := startTestUdpHost(t, &data_models.ReverseProxyHostUdpRoute{TargetHost: "127.0.0.1", TargetPort: targetPort}, nil)
+
+	first, err := net.Dial("udp", address)
+	require.NoError(t, err)
+	defer first.Close()
+	second, err := net.Dial("udp", address)
+	require.NoError(t, err)
+	defer second.Close()
+
+	for _, message := range []string{"one", "two"} {
+		reply, err := exchangeUdp(t, first, "first "+message)
+		require.NoError(t, err)
+		assert.Equal(t, "first "+message, reply)
+
+		reply, err = exchangeUdp(t, second, "second "+message)
+		require.NoError(t, err)
+		assert.Equal(t, "second "+message, reply)
+	}
+}
+
+func TestUdpSessionsIdleTimeout(t *testing.T) {
+	targetPort := startUdpEcho(t)
+	sessions := newUdpSessions(net.JoinHostPort("127.0.0.1", targetPort), 200*time.Millisecond, 0)
+	defer sessions.closeAll()
+
+	client := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 40000}
+	session, created, _, err := sessions.get(client)
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	again, created, _, err := sessions.get(client)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Same(t, session, again)
+	assert.Equal(t, 1, sessions.count())
+
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+	rps := &ReverseProxyService{api_ctx: ctx}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rps.handleUdpSession(listener, sessions, session, &data_models.ReverseProxyHost{ID: "test", UdpRoute: &data_models.ReverseProxyHostUdpRoute{}})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the idle session was not closed")
+	}
+	assert.Equal(t, 0, sessions.count())
+}
+
+func TestProxyUdpRouteDeniedAddress(t *testing.T) {
+	targetPort := startUdpEcho(t)
+	address := startTestUdpHost(t,
+		&data_models.ReverseProxyHostUdpRoute{TargetHost: "127.0.0.1", TargetPort: targetPort},
+		&data_models.ReverseProxyAuth{DeniedIps: []string{"127.0.0.1"}},
+	)
+
+	conn, err := net.Dial("udp", address)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	_ = conn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	_, err = conn.Read(make([]byte, 16))
+	require.Error(t, err)
+}
+
+func TestUdpRouteIdleTimeout(t *testing.T) {
+	assert.Equal(t, 30*time.Second, udpRouteIdleTimeout(&data_models.ReverseProxyHostUdpRoute{IdleTimeout: "30s"}))
+	assert.Equal(t, udpIdleTimeout, udpRouteIdleTimeout(&data_models.ReverseProxyHostUdpRoute{}))
+	assert.Equal(t, udpIdleTimeout, udpRouteIdleTimeout(&data_models.ReverseProxyHostUdpRoute{IdleTimeout: "invalid"}))
+}
+
+func TestUdpSessionsMaxSessions(t *testing.T) {
+	targetPort := startUdpEcho(t)
+	sessions := newUdpSessions(net.JoinHostPort("127.0.0.1", targetPort), time.Minute, 2)
+	defer sessions.closeAll()
+
+	first, _, evicted, err := sessions.get(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 40001})
+	require.NoError(t, err)
+	assert.Nil(t, evicted)
+	second, _, evicted, err := sessions.get(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 40002})
+	require.NoError(t, err)
+	assert.Nil(t, evicted)
+
+	// the first client is seen again, so the second is the least recent
+	first.lastSeen.Store(time.Now().Add(time.Second).UnixNano())
+	third, created, evicted, err := sessions.get(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 40003})
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Same(t, second, evicted)
+	assert.Equal(t, 2, sessions.count())
+
+	_, err = second.conn.Write([]byte("closed"))
+	assert.ErrorIs(t, err, net.ErrClosed)
+
+	again, created, _, err := sessions.get(third.client)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Same(t, third, again)
+}
+
+func TestUdpSessionsConcurrentGet(t *testing.T) {
+	targetPort := startUdpEcho(t)
+	sessions := newUdpSessions(net.JoinHostPort("127.0.0.1", targetPort), time.Minute, 3)
+	defer sessions.closeAll()
+
+	var wg sync.WaitGroup
+	var created atomic.Int32
+	results := make([]*udpSession, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			session, isNew, _, err := sessions.get(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 40010})
+			assert.NoError(t, err)
+			if isNew {
+				created.Add(1)
+			}
+			results[i] = session
+		}()
+	}
+	wg.Wait()
+
+	// the datagrams of a client dialing at the same time share one session
+	assert.Equal(t, int32(1), created.Load())
+	for _, session := range results {
+		assert.Same(t, results[0], session)
+	}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _, err := sessions.get(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 40020 + i})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 3, sessions.count())
+}
+
+func TestUdpRouteMaxSessions(t *testing.T) {
+	assert.Equal(t, 10, udpRouteMaxSessions(&data_models.ReverseProxyHostUdpRoute{MaxSessions: 10}))
+	assert.Equal(t, udpMaxSessions, udpRouteMaxSessions(&data_models.ReverseProxyHostUdpRoute{}))
+}
@@ -0,0 +1,445 @@
+package reverse_proxy
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	global_models "github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+)
+
+// upstreamMember is one target of a pool, it keeps its state across the
+// refreshes of the pool as long as its address does not change
+type upstreamMember struct {
+	address string
+	vmId    string
+	active  int64
+
+	mu           sync.Mutex
+	healthy      bool
+	successes    int
+	failures     int
+	fails        int
+	ejectedUntil time.Time
+}
+
+func (m *upstreamMember) release() {
+	atomic.AddInt64(&m.active, -1)
+}
+
+func (m *upstreamMember) isAvailable(now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.healthy && !now.Before(m.ejectedUntil)
+}
+
+// upstreamPool balances the traffic of a route between its targets. The
+// virtual machines of the pool are resolved again on every refresh so the
+// machines tagged while the proxy runs join the pool without a restart.
+type upstreamPool struct {
+	ctx         basecontext.ApiContext
+	name        string
+	config      data_models.ReverseProxyUpstream
+	defaultPort string
+	scheme      string
+	listVms     func() ([]global_models.ParallelsVM, error)
+
+	maxFails           int
+	failTimeout        time.Duration
+	interval           time.Duration
+	timeout            time.Duration
+	healthyThreshold   int
+	unhealthyThreshold int
+
+	mu      sync.RWMutex
+	members []*upstreamMember
+	next    uint64
+}
+
+func newUpstreamPool(ctx basecontext.ApiContext, name string, config data_models.ReverseProxyUpstream, defaultPort string, scheme string, listVms func() ([]global_models.ParallelsVM, error)) *upstreamPool {
+	pool := &upstreamPool{
+		ctx:                ctx,
+		name:               name,
+		config:             config,
+		defaultPort:        defaultPort,
+		scheme:             scheme,
+		listVms:            listVms,
+		maxFails:           config.MaxFails,
+		failTimeout:        parseUpstreamDuration(config.FailTimeout, constants.REVERSE_PROXY_UPSTREAM_DEFAULT_FAIL_TIMEOUT),
+		interval:           parseUpstreamDuration("", constants.REVERSE_PROXY_UPSTREAM_DEFAULT_CHECK_INTERVAL),
+		timeout:            parseUpstreamDuration("", constants.REVERSE_PROXY_UPSTREAM_DEFAULT_CHECK_TIMEOUT),
+		healthyThreshold:   constants.REVERSE_PROXY_UPSTREAM_DEFAULT_HEALTHY_THRESHOLD,
+		unhealthyThreshold: constants.REVERSE_PROXY_UPSTREAM_DEFAULT_UNHEALTHY_THRESHOLD,
+	}
+	if pool.maxFails == 0 {
+		pool.maxFails = constants.REVERSE_PROXY_UPSTREAM_DEFAULT_MAX_FAILS
+	}
+	if pool.scheme == "" {
+		pool.scheme = "http"
+	}
+	if check := config.HealthCheck; check != nil {
+		pool.interval = parseUpstreamDuration(check.Interval, constants.REVERSE_PROXY_UPSTREAM_DEFAULT_CHECK_INTERVAL)
+		pool.timeout = parseUpstreamDuration(check.Timeout, constants.REVERSE_PROXY_UPSTREAM_DEFAULT_CHECK_TIMEOUT)
+		if check.HealthyThreshold > 0 {
+			pool.healthyThreshold = check.HealthyThreshold
+		}
+		if check.UnhealthyThreshold > 0 {
+			pool.unhealthyThreshold = check.UnhealthyThreshold
+		}
+	}
+
+	return pool
+}
+
+func parseUpstreamDuration(value string, defaultValue string) time.Duration {
+	if value != "" {
+		if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+			return duration
+		}
+	}
+
+	duration, _ := time.ParseDuration(defaultValue)
+	return duration
+}
+
+// run keeps the pool up to date until the context is cancelled, pools of
+// fixed hosts without health checks are resolved once
+func (p *upstreamPool) run(ctx context.Context) {
+	p.refresh()
+
+	healthCheck := p.config.HealthCheck != nil && p.config.HealthCheck.Enabled
+	if !healthCheck && !p.hasVms() {
+		return
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		if healthCheck {
+			p.checkMembers(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.refresh()
+		}
+	}
+}
+
+func (p *upstreamPool) hasVms() bool {
+	if len(p.config.VmTags) > 0 {
+		return true
+	}
+	for _, target := range p.config.Targets {
+		if target.TargetVmId != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// refresh resolves the targets of the pool, virtual machines that are not
+// running or have no address are left out
+func (p *upstreamPool) refresh() {
+	type resolved struct {
+		address string
+		vmId    string
+	}
+	targets := make([]resolved, 0)
+
+	var vms []global_models.ParallelsVM
+	if p.hasVms() && p.listVms != nil {
+		result, err := p.listVms()
+		if err != nil {
+			p.ctx.LogErrorf("[Reverse Proxy] [Upstream] [%s] Error getting the virtual machines of the pool: %v", p.name, err)
+		}
+		vms = result
+	}
+
+	for _, target := range p.config.Targets {
+		port := target.TargetPort
+		if port == "" {
+			port = p.defaultPort
+		}
+		if target.TargetVmId != "" {
+			vm := findUpstreamVm(vms, target.TargetVmId)
+			if vm == nil {
+				p.ctx.LogDebugf("[Reverse Proxy] [Upstream] [%s] Virtual machine %s is not available", p.name, target.TargetVmId)
+				continue
+			}
+			targets = append(targets, resolved{address: net.JoinHostPort(vm.InternalIpAddress, port), vmId: vm.ID})
+			continue
+		}
+
+		host := strings.TrimPrefix(strings.TrimPrefix(target.TargetHost, "http://"), "https://")
+		targets = append(targets, resolved{address: net.JoinHostPort(host, port)})
+	}
+
+	for _, vm := range vms {
+		if !isUpstreamVmAvailable(vm) || !vmHasAnyTag(vm.Description, p.config.VmTags) {
+			continue
+		}
+		targets = append(targets, resolved{address: net.JoinHostPort(vm.InternalIpAddress, p.defaultPort), vmId: vm.ID})
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	existing := make(map[string]*upstreamMember, len(p.members))
+	for _, member := range p.members {
+		existing[member.address] = member
+	}
+
+	members := make([]*upstreamMember, 0, len(targets))
+	seen := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		if seen[target.address] {
+			continue
+		}
+		seen[target.address] = true
+		if member, ok := existing[target.address]; ok {
+			members = append(members, member)
+			continue
+		}
+		p.ctx.LogInfof("[Reverse Proxy] [Upstream] [%s] Target %s added to the pool", p.name, target.address)
+		members = append(members, &upstreamMember{address: target.address, vmId: target.vmId, healthy: true})
+	}
+	for address := range existing {
+		if !seen[address] {
+			p.ctx.LogInfof("[Reverse Proxy] [Upstream] [%s] Target %s removed from the pool", p.name, address)
+		}
+	}
+	p.members = members
+}
+
+func findUpstreamVm(vms []global_models.ParallelsVM, idOrName string) *global_models.ParallelsVM {
+	for i := range vms {
+		if strings.EqualFold(vms[i].ID, idOrName) || strings.EqualFold(vms[i].Name, idOrName) {
+			if !isUpstreamVmAvailable(vms[i]) {
+				return nil
+			}
+			return &vms[i]
+		}
+	}
+
+	return nil
+}
+
+func isUpstreamVmAvailable(vm global_models.ParallelsVM) bool {
+	return vm.State == "running" && vm.InternalIpAddress != "" && vm.InternalIpAddress != "-"
+}
+
+// vmHasAnyTag checks the hashtags in the description of the virtual machine,
+// a machine described as "build agent #macos #ci" has the tags macos and ci
+func vmHasAnyTag(description string, tags []string) bool {
+	if len(tags) == 0 {
+		return false
+	}
+
+	words := strings.FieldsFunc(description, func(r rune) bool {
+		return r == ' ' || r == ',' || r == ';' || r == '\n' || r == '\t'
+	})
+	for _, word := range words {
+		if !strings.HasPrefix(word, "#") {
+			continue
+		}
+		for _, tag := range tags {
+			if strings.EqualFold(strings.TrimPrefix(word, "#"), strings.TrimPrefix(tag, "#")) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// pick chooses the member for a request and counts it as active, callers
+// have to release it when the request is done. It returns nil when no member
+// is healthy.
+func (p *upstreamPool) pick(clientAddress string) *upstreamMember {
+	p.mu.RLock()
+	members := p.members
+	p.mu.RUnlock()
+
+	now := time.Now()
+	candidates := make([]*upstreamMember, 0, len(members))
+	for _, member := range members {
+		if member.isAvailable(now) {
+			candidates = append(candidates, member)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var member *upstreamMember
+	switch p.config.Strategy {
+	case constants.REVERSE_PROXY_UPSTREAM_LEAST_CONNECTIONS:
+		start := int(atomic.AddUint64(&p.next, 1) % uint64(len(candidates)))
+		for i := range candidates {
+			candidate := candidates[(start+i)%len(candidates)]
+			if member == nil || atomic.LoadInt64(&candidate.active) < atomic.LoadInt64(&member.active) {
+				member = candidate
+			}
+		}
+	case constants.REVERSE_PROXY_UPSTREAM_IP_HASH:
+		host, _, err := net.SplitHostPort(clientAddress)
+		if err != nil {
+			host = clientAddress
+		}
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(host))
+		member = candidates[hash.Sum32()%uint32(len(candidates))]
+	default:
+		member = candidates[(atomic.AddUint64(&p.next, 1)-1)%uint64(len(candidates))]
+	}
+
+	atomic.AddInt64(&member.active, 1)
+	return member
+}
+
+// reportFailure ejects the member for the fail timeout once it failed max
+// fails times in a row
+func (p *upstreamPool) reportFailure(member *upstreamMember) {
+	member.mu.Lock()
+	defer member.mu.Unlock()
+
+	member.fails++
+	if member.fails >= p.maxFails {
+		member.fails = 0
+		member.ejectedUntil = time.Now().Add(p.failTimeout)
+		p.ctx.LogWarnf("[Reverse Proxy] [Upstream] [%s] Target %s ejected for %v after %v failures", p.name, member.address, p.failTimeout, p.maxFails)
+	}
+}
+
+func (p *upstreamPool) reportSuccess(member *upstreamMember) {
+	member.mu.Lock()
+	defer member.mu.Unlock()
+	member.fails = 0
+}
+
+// dial connects to a member of the pool, trying the next one when a member
+// cannot be reached
+func (p *upstreamPool) dial(clientAddress string) (net.Conn, *upstreamMember, error) {
+	p.mu.RLock()
+	attempts := len(p.members)
+	p.mu.RUnlock()
+
+	for i := 0; i < attempts; i++ {
+		member := p.pick(clientAddress)
+		if member == nil {
+			break
+		}
+		conn, err := net.DialTimeout("tcp", member.address, p.timeout)
+		if err == nil {
+			p.reportSuccess(member)
+			return conn, member, nil
+		}
+		p.ctx.LogDebugf("[Reverse Proxy] [Upstream] [%s] Unable to connect to %s: %v", p.name, member.address, err)
+		p.reportFailure(member)
+		member.release()
+	}
+
+	return nil, nil, fmt.Errorf("no healthy target available in the pool %s", p.name)
+}
+
+func (p *upstreamPool) checkMembers(ctx context.Context) {
+	p.mu.RLock()
+	members := p.members
+	p.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, member := range members {
+		wg.Add(1)
+		go func(member *upstreamMember) {
+			defer wg.Done()
+			p.recordCheck(member, p.check(ctx, member))
+		}(member)
+	}
+	wg.Wait()
+}
+
+func (p *upstreamPool) check(ctx context.Context, member *upstreamMember) bool {
+	if p.config.HealthCheck.Path == "" {
+		conn, err := net.DialTimeout("tcp", member.address, p.timeout)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(checkCtx, http.MethodGet, fmt.Sprintf("%s://%s%s", p.scheme, member.address, p.config.HealthCheck.Path), nil)
+	if err != nil {
+		return false
+	}
+	request.Header.Set("User-Agent", constants.ExecutableName)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return false
+	}
+	_ = response.Body.Close()
+
+	return response.StatusCode < http.StatusBadRequest
+}
+
+func (p *upstreamPool) recordCheck(member *upstreamMember, ok bool) {
+	member.mu.Lock()
+	defer member.mu.Unlock()
+
+	if ok {
+		member.failures = 0
+		member.successes++
+		if !member.healthy && member.successes >= p.healthyThreshold {
+			member.healthy = true
+			p.ctx.LogInfof("[Reverse Proxy] [Upstream] [%s] Target %s is healthy", p.name, member.address)
+		}
+		return
+	}
+
+	member.successes = 0
+	member.failures++
+	if member.healthy && member.failures >= p.unhealthyThreshold {
+		member.healthy = false
+		p.ctx.LogWarnf("[Reverse Proxy] [Upstream] [%s] Target %s is unhealthy", p.name, member.address)
+	}
+}
+
+// upstreamSelection is the member picked for a request, it is shared between
+// the director, the error handler and the response of the proxy
+type upstreamSelection struct {
+	pool        *upstreamPool
+	member      *upstreamMember
+	unavailable bool
+}
+
+type upstreamSelectionKey struct{}
+
+func (s *upstreamSelection) release() {
+	if s.member != nil {
+		s.member.release()
+	}
+}
+
+func (rps *ReverseProxyService) listUpstreamVms() ([]global_models.ParallelsVM, error) {
+	provider := serviceprovider.Get()
+	if provider == nil || provider.ParallelsDesktopService == nil {
+		return nil, nil
+	}
+
+	return provider.ParallelsDesktopService.GetCachedVms(rps.api_ctx, "")
+}
@@ -0,0 +1,134 @@
+package reverse_proxy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	global_models "github.com/Parallels/prl-devops-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestUpstreamPool(config data_models.ReverseProxyUpstream, vms []global_models.ParallelsVM) *upstreamPool {
+	ctx := basecontext.NewRootBaseContext()
+	ctx.DisableLog()
+	pool := newUpstreamPool(ctx, "test", config, "8080", "", func() ([]global_models.ParallelsVM, error) {
+		return vms, nil
+	})
+	pool.refresh()
+	return pool
+}
+
+func TestUpstreamPoolResolvesTargetsAndTags(t *testing.T) {
+	vms := []global_models.ParallelsVM{
+		{ID: "vm-1", Name: "agent-1", State: "running", InternalIpAddress: "10.0.0.1", Description: "build agent #ci"},
+		{ID: "vm-2", Name: "agent-2", State: "stopped", InternalIpAddress: "10.0.0.2", Description: "#ci"},
+		{ID: "vm-3", Name: "agent-3", State: "running", InternalIpAddress: "10.0.0.3", Description: "#CI, #macos"},
+		{ID: "vm-4", Name: "web", State: "running", InternalIpAddress: "10.0.0.4", Description: "ci"},
+	}
+	pool := newTestUpstreamPool(data_models.ReverseProxyUpstream{
+		VmTags: []string{"ci"},
+		Targets: []data_models.ReverseProxyUpstreamTarget{
+			{TargetHost: "http://example.com", TargetPort: "80"},
+			{TargetVmId: "web", TargetPort: "9090"},
+			{TargetVmId: "vm-2"},
+		},
+	}, vms)
+
+	addresses := []string{}
+	for _, member := range pool.members {
+		addresses = append(addresses, member.address)
+	}
+	assert.Equal(t, []string{"example.com:80", "10.0.0.4:9090", "10.0.0.1:8080", "10.0.0.3:8080"}, addresses)
+}
+
+func TestUpstreamPoolStrategies(t *testing.T) {
+	targets := []data_models.ReverseProxyUpstreamTarget{
+		{TargetHost: "10.0.0.1"},
+		{TargetHost: "10.0.0.2"},
+		{TargetHost: "10.0.0.3"},
+	}
+
+	t.Run("round robin", func(t *testing.T) {
+		pool := newTestUpstreamPool(data_models.ReverseProxyUpstream{Targets: targets}, nil)
+		picked := []string{}
+		for i := 0; i < 4; i++ {
+			member := pool.pick("192.168.0.1:5000")
+			require.NotNil(t, member)
+			picked = append(picked, member.address)
+			member.release()
+		}
+		assert.Equal(t, []string{"10.0.0.1:8080", "10.0.0.2:8080", "10.0.0.3:8080", "10.0.0.1:8080"}, picked)
+	})
+
+	t.Run("least connections", func(t *testing.T) {
+		pool := newTestUpstreamPool(data_models.ReverseProxyUpstream{Strategy: constants.REVERSE_PROXY_UPSTREAM_LEAST_CONNECTIONS, Targets: targets}, nil)
+		first := pool.pick("")
+		second := pool.pick("")
+		third := pool.pick("")
+		assert.ElementsMatch(t, []string{"10.0.0.1:8080", "10.0.0.2:8080", "10.0.0.3:8080"}, []string{first.address, second.address, third.address})
+
+		second.release()
+		assert.Equal(t, second.address, pool.pick("").address)
+	})
+
+	t.Run("ip hash", func(t *testing.T) {
+		pool := newTestUpstreamPool(data_models.ReverseProxyUpstream{Strategy: constants.REVERSE_PROXY_UPSTREAM_IP_HASH, Targets: targets}, nil)
+		first := pool.pick("192.168.0.10:5000")
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, first.address, pool.pick("192.168.0.10:6000").address)
+		}
+	})
+}
+
+func TestUpstreamPoolEjectsFailingTargets(t *testing.T) {
+	pool := newTestUpstreamPool(data_models.ReverseProxyUpstream{
+		MaxFails: 2,
+		Targets: []data_models.ReverseProxyUpstreamTarget{
+			{TargetHost: "10.0.0.1"},
+			{TargetHost: "10.0.0.2"},
+		},
+	}, nil)
+	failing := pool.members[0]
+
+	pool.reportFailure(failing)
+	pool.reportSuccess(failing)
+	pool.reportFailure(failing)
+	assert.True(t, failing.isAvailable(time.Now()))
+
+	pool.reportFailure(failing)
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "10.0.0.2:8080", pool.pick("").address)
+	}
+
+	pool.members[1].healthy = false
+	assert.Nil(t, pool.pick(""))
+}
+
+func TestUpstreamPoolHealthThresholds(t *testing.T) {
+	pool := newTestUpstreamPool(data_models.ReverseProxyUpstream{
+		Targets:     []data_models.ReverseProxyUpstreamTarget{{TargetHost: "10.0.0.1"}},
+		HealthCheck: &data_models.ReverseProxyUpstreamHealthCheck{Enabled: true, HealthyThreshold: 2, UnhealthyThreshold: 2},
+	}, nil)
+	member := pool.members[0]
+
+	pool.recordCheck(member, false)
+	assert.True(t, member.healthy)
+	pool.recordCheck(member, false)
+	assert.False(t, member.healthy)
+
+	pool.recordCheck(member, true)
+	assert.False(t, member.healthy)
+	pool.recordCheck(member, true)
+	assert.True(t, member.healthy)
+}
+
+func TestVmHasAnyTag(t *testing.T) {
+	assert.True(t, vmHasAnyTag("build agent #ci", []string{"ci"}))
+	assert.True(t, vmHasAnyTag("#macos,#CI", []string{"#ci"}))
+	assert.False(t, vmHasAnyTag("ci agent", []string{"ci"}))
+	assert.False(t, vmHasAnyTag("#ci", nil))
+}
@@ -0,0 +1,168 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/security"
+)
+
+const LocalStoreName = "local"
+
+// LocalStore keeps the secrets in a json file, each value is encrypted with
+// the service encryption private key
+type LocalStore struct {
+	path       string
+	privateKey string
+	lock       sync.Mutex
+}
+
+func NewLocalStore(path string, privateKey string) *LocalStore {
+	return &LocalStore{
+		path:       path,
+		privateKey: privateKey,
+	}
+}
+
+func NewLocalStoreFromConfig() (*LocalStore, error) {
+	cfg := config.Get()
+	privateKey := cfg.EncryptionPrivateKey()
+	if privateKey == "" {
+		return nil, errors.New("the local secret store needs the ENCRYPTION_PRIVATE_KEY to be set")
+	}
+
+	path, err := cfg.SecretsLocalStorePath()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLocalStore(path, privateKey), nil
+}
+
+func (s *LocalStore) Name() string {
+	return LocalStoreName
+}
+
+func (s *LocalStore) Get(ctx basecontext.ApiContext, name string) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	values, err := s.load()
+	if err != nil {
+		return "", err
+	}
+
+	encrypted, ok := values[name]
+	if !ok {
+		return "", errors.NewWithCodef(404, "secret %v was not found", name)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", errors.NewFromErrorf(err, "secret %v is corrupted", name)
+	}
+
+	value, err := security.DecryptString(s.privateKey, decoded)
+	if err != nil {
+		return "", errors.NewFromErrorf(err, "error decrypting secret %v", name)
+	}
+
+	return value, nil
+}
+
+func (s *LocalStore) Set(ctx basecontext.ApiContext, name string, value string) error {
+	if name == "" {
+		return errors.New("secret name cannot be empty")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	values, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := security.EncryptString(s.privateKey, value)
+	if err != nil {
+		return errors.NewFromErrorf(err, "error encrypting secret %v", name)
+	}
+
+	values[name] = base64.StdEncoding.EncodeToString(encrypted)
+	return s.save(values)
+}
+
+func (s *LocalStore) Delete(ctx basecontext.ApiContext, name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	values, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := values[name]; !ok {
+		return errors.NewWithCodef(404, "secret %v was not found", name)
+	}
+
+	delete(values, name)
+	return s.save(values)
+}
+
+func (s *LocalStore) List(ctx basecontext.ApiContext) ([]string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	values, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0, len(values))
+	for name := range values {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	return result, nil
+}
+
+func (s *LocalStore) load() (map[string]string, error) {
+	values := make(map[string]string)
+	content, err := os.ReadFile(filepath.Clean(s.path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return values, nil
+		}
+		return nil, errors.NewFromErrorf(err, "error reading the secret store %v", s.path)
+	}
+
+	if len(content) == 0 {
+		return values, nil
+	}
+	if err := json.Unmarshal(content, &values); err != nil {
+		return nil, errors.NewFromErrorf(err, "error reading the secret store %v", s.path)
+	}
+
+	return values, nil
+}
+
+func (s *LocalStore) save(values map[string]string) error {
+	content, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Clean(s.path), content, 0o600)
+}
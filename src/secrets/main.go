@@ -0,0 +1,184 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+const (
+	SecretScheme = "secret://"
+	EnvScheme    = "env://"
+	FileScheme   = "file://"
+)
+
+// SecretStore is a backend that keeps named secrets, secret://name references
+// are resolved against the store configured in SECRETS_STORE
+type SecretStore interface {
+	Name() string
+	Get(ctx basecontext.ApiContext, name string) (string, error)
+	Set(ctx basecontext.ApiContext, name string, value string) error
+	Delete(ctx basecontext.ApiContext, name string) error
+	List(ctx basecontext.ApiContext) ([]string, error)
+}
+
+// SecretStoreFactory creates a secret store from the service configuration
+type SecretStoreFactory func(ctx basecontext.ApiContext) (SecretStore, error)
+
+var (
+	storesLock     sync.RWMutex
+	storeFactories = map[string]SecretStoreFactory{
+		LocalStoreName: func(ctx basecontext.ApiContext) (SecretStore, error) {
+			return NewLocalStoreFromConfig()
+		},
+		VaultStoreName: func(ctx basecontext.ApiContext) (SecretStore, error) {
+			return NewVaultStoreFromConfig()
+		},
+	}
+)
+
+// RegisterStore adds or replaces a secret store backend that can be selected with SECRETS_STORE
+func RegisterStore(name string, factory SecretStoreFactory) {
+	storesLock.Lock()
+	defer storesLock.Unlock()
+	storeFactories[strings.ToLower(name)] = factory
+}
+
+// GetStores returns the names of the registered secret store backends
+func GetStores() []string {
+	storesLock.RLock()
+	defer storesLock.RUnlock()
+	result := make([]string, 0, len(storeFactories))
+	for name := range storeFactories {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// GetStore returns the secret store configured in SECRETS_STORE
+func GetStore(ctx basecontext.ApiContext) (SecretStore, error) {
+	name := config.Get().SecretsStore()
+	storesLock.RLock()
+	factory, ok := storeFactories[name]
+	storesLock.RUnlock()
+	if !ok {
+		return nil, errors.Newf("secret store %v is not supported, allowed values are %v", name, strings.Join(GetStores(), ", "))
+	}
+
+	return factory(ctx)
+}
+
+// IsReference returns true if the value is a secret://, env:// or file:// reference
+func IsReference(value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	return strings.HasPrefix(value, SecretScheme) ||
+		strings.HasPrefix(value, EnvScheme) ||
+		strings.HasPrefix(value, FileScheme)
+}
+
+// ParseReference returns the scheme and the name of a reference, for file://
+// references the name is the absolute path of the file
+func ParseReference(value string) (string, string, error) {
+	value = strings.TrimSpace(value)
+	for _, scheme := range []string{SecretScheme, EnvScheme, FileScheme} {
+		if len(value) < len(scheme) || !strings.EqualFold(value[:len(scheme)], scheme) {
+			continue
+		}
+
+		name := value[len(scheme):]
+		if name == "" {
+			return "", "", errors.Newf("secret reference %v is missing a name", value)
+		}
+		if scheme == FileScheme && !filepath.IsAbs(name) {
+			return "", "", errors.Newf("secret reference %v needs an absolute path, for example file:///path/to/secret", value)
+		}
+
+		return scheme, name, nil
+	}
+
+	return "", "", errors.Newf("%v is not a secret reference", value)
+}
+
+// ValidateReference checks the syntax of a value without resolving it, values
+// that are not references are always valid
+func ValidateReference(value string) error {
+	if !IsReference(value) {
+		return nil
+	}
+
+	_, _, err := ParseReference(value)
+	return err
+}
+
+// Resolve returns the value a reference points to, values that are not
+// references are returned as they are
+func Resolve(ctx basecontext.ApiContext, value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+
+	scheme, name, err := ParseReference(value)
+	if err != nil {
+		return "", err
+	}
+
+	switch scheme {
+	case EnvScheme:
+		result, ok := os.LookupEnv(name)
+		if !ok {
+			return "", errors.Newf("environment variable %v referenced by %v is not set", name, value)
+		}
+		return result, nil
+	case FileScheme:
+		content, err := os.ReadFile(filepath.Clean(name))
+		if err != nil {
+			return "", errors.NewFromErrorf(err, "error reading secret file %v", name)
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	default:
+		store, err := GetStore(ctx)
+		if err != nil {
+			return "", err
+		}
+		result, err := store.Get(ctx, name)
+		if err != nil {
+			return "", err
+		}
+		return result, nil
+	}
+}
+
+// ResolveConnectionString resolves the references in the values of a
+// key=value;key=value connection string
+func ResolveConnectionString(ctx basecontext.ApiContext, connection string) (string, error) {
+	if !strings.Contains(strings.ToLower(connection), "://") {
+		return connection, nil
+	}
+
+	parts := strings.Split(connection, ";")
+	for i, part := range parts {
+		keyValue := strings.SplitN(part, "=", 2)
+		if len(keyValue) != 2 || !IsReference(keyValue[1]) {
+			continue
+		}
+
+		value, err := Resolve(ctx, keyValue[1])
+		if err != nil {
+			return "", err
+		}
+		if strings.Contains(value, ";") {
+			return "", errors.Newf("the value of %v cannot contain ; when used in a connection string", strings.TrimSpace(keyValue[0]))
+		}
+		parts[i] = keyValue[0] + "=" + value
+	}
+
+	return strings.Join(parts, ";"), nil
+}
@@ -0,0 +1,196 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPrivateKey(t *testing.T) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}))
+}
+
+func TestParseReference(t *testing.T) {
+	scheme, name, err := ParseReference("secret://catalog/aws#secret_key")
+	require.NoError(t, err)
+	assert.Equal(t, SecretScheme, scheme)
+	assert.Equal(t, "catalog/aws#secret_key", name)
+
+	scheme, name, err = ParseReference("FILE:///run/secrets/key")
+	require.NoError(t, err)
+	assert.Equal(t, FileScheme, scheme)
+	assert.Equal(t, "/run/secrets/key", name)
+
+	_, _, err = ParseReference("env://")
+	assert.Error(t, err)
+	_, _, err = ParseReference("file://relative/key")
+	assert.Error(t, err)
+
+	assert.False(t, IsReference("plain-password"))
+	assert.NoError(t, ValidateReference("plain-password"))
+}
+
+func TestResolve_EnvAndFile(t *testing.T) {
+	ctx := basecontext.NewBaseContext()
+	t.Setenv("SECRETS_TEST_ACCESS_KEY", "access")
+	path := filepath.Join(t.TempDir(), "secret_key")
+	require.NoError(t, os.WriteFile(path, []byte("secret\n"), 0o600))
+
+	value, err := Resolve(ctx, "env://SECRETS_TEST_ACCESS_KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "access", value)
+
+	value, err = Resolve(ctx, "file://"+path)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", value)
+
+	value, err = Resolve(ctx, "plain")
+	require.NoError(t, err)
+	assert.Equal(t, "plain", value)
+
+	_, err = Resolve(ctx, "env://SECRETS_TEST_UNSET")
+	assert.ErrorContains(t, err, "environment variable SECRETS_TEST_UNSET referenced by env://SECRETS_TEST_UNSET is not set")
+
+	connection, err := ResolveConnectionString(ctx, "provider=aws-s3;bucket=demo;access_key=env://SECRETS_TEST_ACCESS_KEY;secret_key=file://"+path)
+	require.NoError(t, err)
+	assert.Equal(t, "provider=aws-s3;bucket=demo;access_key=access;secret_key=secret", connection)
+
+	t.Setenv("SECRETS_TEST_ACCESS_KEY", "access;region=other")
+	_, err = ResolveConnectionString(ctx, "provider=aws-s3;access_key=env://SECRETS_TEST_ACCESS_KEY")
+	assert.Error(t, err)
+}
+
+func TestLocalStore(t *testing.T) {
+	ctx := basecontext.NewBaseContext()
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	store := NewLocalStore(path, newTestPrivateKey(t))
+
+	require.NoError(t, store.Set(ctx, "aws-secret-key", "secret"))
+	require.NoError(t, store.Set(ctx, "api-key", "key"))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "secret\"")
+
+	value, err := store.Get(ctx, "aws-secret-key")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", value)
+
+	names, err := store.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api-key", "aws-secret-key"}, names)
+
+	require.NoError(t, store.Delete(ctx, "api-key"))
+	_, err = store.Get(ctx, "api-key")
+	assert.Error(t, err)
+}
+
+func TestResolve_SecretStore(t *testing.T) {
+	ctx := basecontext.NewBaseContext()
+	store := NewLocalStore(filepath.Join(t.TempDir(), "secrets.json"), newTestPrivateKey(t))
+	require.NoError(t, store.Set(ctx, "password", "secret"))
+	RegisterStore("test", func(ctx basecontext.ApiContext) (SecretStore, error) {
+		return store, nil
+	})
+	t.Setenv(constants.SECRETS_STORE_ENV_VAR, "test")
+
+	value, err := Resolve(ctx, "secret://password")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", value)
+
+	t.Setenv(constants.SECRETS_STORE_ENV_VAR, "unknown")
+	_, err = Resolve(ctx, "secret://password")
+	assert.Error(t, err)
+}
+
+func TestVaultStore(t *testing.T) {
+	ctx := basecontext.NewBaseContext()
+	var lock sync.Mutex
+	values := map[string]map[string]interface{}{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Query().Get("list") == "true":
+			keys := make([]string, 0)
+			for path := range values {
+				keys = append(keys, path)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"keys": keys}})
+		case r.Method == http.MethodGet:
+			data, ok := values[strings.TrimPrefix(r.URL.Path, "/v1/kv/data/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": data}})
+		case r.Method == http.MethodPost:
+			var body struct {
+				Data map[string]interface{} `json:"data"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			values[strings.TrimPrefix(r.URL.Path, "/v1/kv/data/")] = body.Data
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodDelete:
+			delete(values, strings.TrimPrefix(r.URL.Path, "/v1/kv/metadata/"))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	store := NewVaultStore(server.URL, "token", "kv", 2)
+	require.NoError(t, store.Set(ctx, "aws#access_key", "access"))
+	require.NoError(t, store.Set(ctx, "aws#secret_key", "secret"))
+	require.NoError(t, store.Set(ctx, "api-key", "key"))
+
+	value, err := store.Get(ctx, "aws#secret_key")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", value)
+	value, err = store.Get(ctx, "aws#access_key")
+	require.NoError(t, err)
+	assert.Equal(t, "access", value)
+	value, err = store.Get(ctx, "api-key")
+	require.NoError(t, err)
+	assert.Equal(t, "key", value)
+
+	_, err = store.Get(ctx, "aws#region")
+	assert.ErrorContains(t, err, "secret aws has no field region")
+
+	names, err := store.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api-key", "aws"}, names)
+
+	require.NoError(t, store.Delete(ctx, "api-key"))
+	_, err = store.Get(ctx, "api-key")
+	assert.ErrorContains(t, err, "secret api-key was not found")
+
+	_, err = NewVaultStore(server.URL, "wrong", "kv", 2).Get(ctx, "aws")
+	assert.ErrorContains(t, err, "vault returned 403: permission denied")
+}
@@ -0,0 +1,270 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+const (
+	VaultStoreName    = "vault"
+	VaultDefaultField = "value"
+)
+
+// VaultStore reads and writes secrets in a HashiCorp Vault KV secrets engine,
+// names are paths inside the mount with an optional #field suffix, the field
+// defaults to value, for example secret://catalog/aws#secret_key
+type VaultStore struct {
+	address   string
+	token     string
+	namespace string
+	mount     string
+	version   int
+	client    *http.Client
+}
+
+func NewVaultStore(address string, token string, mount string, version int) *VaultStore {
+	if mount == "" {
+		mount = "secret"
+	}
+	if version != 1 {
+		version = 2
+	}
+
+	return &VaultStore{
+		address: strings.TrimRight(address, "/"),
+		token:   token,
+		mount:   strings.Trim(mount, "/"),
+		version: version,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func NewVaultStoreFromConfig() (*VaultStore, error) {
+	cfg := config.Get()
+	if cfg.VaultAddress() == "" {
+		return nil, errors.New("the vault secret store needs the VAULT_ADDR to be set")
+	}
+	if cfg.VaultToken() == "" {
+		return nil, errors.New("the vault secret store needs the VAULT_TOKEN to be set")
+	}
+
+	return NewVaultStore(cfg.VaultAddress(), cfg.VaultToken(), cfg.VaultKvMount(), cfg.VaultKvVersion()).
+		WithNamespace(cfg.VaultNamespace()), nil
+}
+
+func (s *VaultStore) WithNamespace(namespace string) *VaultStore {
+	s.namespace = namespace
+	return s
+}
+
+func (s *VaultStore) Name() string {
+	return VaultStoreName
+}
+
+func (s *VaultStore) Get(ctx basecontext.ApiContext, name string) (string, error) {
+	path, field := splitVaultName(name)
+	values, err := s.read(path)
+	if err != nil {
+		return "", err
+	}
+	if values == nil {
+		return "", errors.NewWithCodef(404, "secret %v was not found", path)
+	}
+
+	value, ok := values[field]
+	if !ok {
+		return "", errors.NewWithCodef(404, "secret %v has no field %v", path, field)
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	default:
+		content, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(content), nil
+	}
+}
+
+// Set writes the field of the secret keeping the other fields of the same path
+func (s *VaultStore) Set(ctx basecontext.ApiContext, name string, value string) error {
+	path, field := splitVaultName(name)
+	if path == "" {
+		return errors.New("secret name cannot be empty")
+	}
+
+	values, err := s.read(path)
+	if err != nil {
+		return err
+	}
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+	values[field] = value
+
+	var body interface{} = values
+	if s.version == 2 {
+		body = map[string]interface{}{"data": values}
+	}
+
+	_, err = s.request(http.MethodPost, s.dataUrl(path), body, nil)
+	return err
+}
+
+// Delete removes the secret path with all its fields and versions
+func (s *VaultStore) Delete(ctx basecontext.ApiContext, name string) error {
+	path, _ := splitVaultName(name)
+	url := s.dataUrl(path)
+	if s.version == 2 {
+		url = s.metadataUrl(path)
+	}
+
+	_, err := s.request(http.MethodDelete, url, nil, nil)
+	return err
+}
+
+func (s *VaultStore) List(ctx basecontext.ApiContext) ([]string, error) {
+	return s.list("")
+}
+
+func (s *VaultStore) list(prefix string) ([]string, error) {
+	var response struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+
+	url := s.dataUrl(prefix)
+	if s.version == 2 {
+		url = s.metadataUrl(prefix)
+	}
+	found, err := s.request(http.MethodGet, strings.TrimRight(url, "/")+"/?list=true", nil, &response)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	result := make([]string, 0)
+	for _, key := range response.Data.Keys {
+		if strings.HasSuffix(key, "/") {
+			children, err := s.list(prefix + key)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, children...)
+			continue
+		}
+		result = append(result, prefix+key)
+	}
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// read returns the fields of a secret path or nil if the path does not exist
+func (s *VaultStore) read(path string) (map[string]interface{}, error) {
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+
+	found, err := s.request(http.MethodGet, s.dataUrl(path), nil, &response)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	if s.version == 1 {
+		return response.Data, nil
+	}
+	values, ok := response.Data["data"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	return values, nil
+}
+
+func (s *VaultStore) dataUrl(path string) string {
+	if s.version == 1 {
+		return fmt.Sprintf("%v/v1/%v/%v", s.address, s.mount, path)
+	}
+
+	return fmt.Sprintf("%v/v1/%v/data/%v", s.address, s.mount, path)
+}
+
+func (s *VaultStore) metadataUrl(path string) string {
+	return fmt.Sprintf("%v/v1/%v/metadata/%v", s.address, s.mount, path)
+}
+
+// request calls the vault api, the shared api client is not used as it logs
+// the response bodies which would contain the secret values
+func (s *VaultStore) request(method string, url string, body interface{}, destination interface{}) (bool, error) {
+	var reader io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return false, err
+		}
+		reader = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("X-Vault-Token", s.token)
+	if s.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := s.client.Do(req)
+	if err != nil {
+		return false, errors.NewFromErrorf(err, "error connecting to vault at %v", s.address)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		var vaultError struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(response.Body).Decode(&vaultError)
+		if len(vaultError.Errors) > 0 {
+			return false, errors.NewWithCodef(response.StatusCode, "vault returned %v: %v", response.StatusCode, strings.Join(vaultError.Errors, ", "))
+		}
+		return false, errors.NewWithCodef(response.StatusCode, "vault returned %v", response.StatusCode)
+	}
+
+	if destination != nil && response.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(response.Body).Decode(destination); err != nil {
+			return false, errors.NewFromErrorf(err, "error reading the vault response")
+		}
+	}
+
+	return true, nil
+}
+
+func splitVaultName(name string) (string, string) {
+	path, field, found := strings.Cut(name, "#")
+	path = strings.Trim(path, "/")
+	if !found || field == "" {
+		field = VaultDefaultField
+	}
+
+	return path, field
+}
@@ -14,6 +14,7 @@ type ApiKeyValidationResult struct {
 	ApiKeyId   string
 	ApiKeyName string
 	UserID     string
+	Scope      *models.ApiKeyScope
 }
 
 type ApiKeyValidationError struct {
@@ -81,7 +82,7 @@ func ValidateApiKey(ctx basecontext.ApiContext, dbService interface {
 	}
 
 	passwdSvc := password.Get()
-	if err := passwdSvc.Compare(apiKeySecret, dbApiKey.ID, dbApiKey.Secret); err != nil {
+	if err := passwdSvc.Compare(apiKeySecret, dbApiKey.ID, dbApiKey.Secret); err != nil && !isValidPreviousSecret(dbApiKey, apiKeySecret) {
 		return nil, &ApiKeyValidationError{
 			Code:      401,
 			Message:   "Invalid API key secret",
@@ -93,5 +94,20 @@ func ValidateApiKey(ctx basecontext.ApiContext, dbService interface {
 		ApiKeyId:   dbApiKey.ID,
 		ApiKeyName: dbApiKey.Name,
 		UserID:     dbApiKey.UserID,
+		Scope:      dbApiKey.Scope,
 	}, nil
 }
+
+// isValidPreviousSecret returns true if the secret is the one the key had
+// before its last rotation and the overlap did not end yet
+func isValidPreviousSecret(apiKey *models.ApiKey, secret string) bool {
+	if apiKey.PreviousSecret == "" || apiKey.PreviousSecretExpiresAt == "" {
+		return false
+	}
+	expiresAt, err := time.Parse(time.RFC3339Nano, apiKey.PreviousSecretExpiresAt)
+	if err != nil || time.Now().UTC().After(expiresAt) {
+		return false
+	}
+
+	return password.Get().Compare(secret, apiKey.ID, apiKey.PreviousSecret) == nil
+}
@@ -0,0 +1,188 @@
+package apikey
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+// ScopeRequest is the request an api key scope is checked against
+type ScopeRequest struct {
+	Method   string
+	Path     string
+	SourceIp string
+	// RequiredRoles and RequiredClaims are the ones the route requires
+	RequiredRoles    []string
+	RequiredClaims   []string
+	RequireAllClaims bool
+	// CatalogId, HostId and MachineId are the resources the route addresses,
+	// empty if it does not address one
+	CatalogId string
+	HostId    string
+	MachineId string
+	// GetHostTags returns the tags of an orchestrator host
+	GetHostTags func(id string) []string
+	// GetMachineHostId returns the orchestrator host running a machine
+	GetMachineHostId func(id string) string
+}
+
+// CheckScope returns an error if the scope of the api key does not allow the
+// request, keys without a scope are not restricted
+func CheckScope(scope *models.ApiKeyScope, request ScopeRequest) error {
+	if scope == nil {
+		return nil
+	}
+
+	if len(scope.SourceCidrs) > 0 && !isAllowedSource(scope.SourceCidrs, request.SourceIp) {
+		return errors.NewWithCodef(http.StatusForbidden, "the api key cannot be used from %v", request.SourceIp)
+	}
+	if len(scope.Methods) > 0 && !containsFold(scope.Methods, request.Method) {
+		return errors.NewWithCodef(http.StatusForbidden, "the api key cannot be used for %v requests", request.Method)
+	}
+	if len(scope.Paths) > 0 && !isAllowedPath(scope.Paths, request.Path) {
+		return errors.NewWithCodef(http.StatusForbidden, "the api key cannot be used for %v", request.Path)
+	}
+	if len(scope.Claims) > 0 {
+		if err := checkClaims(scope.Claims, request); err != nil {
+			return err
+		}
+	}
+
+	return checkResources(scope.Resources, request)
+}
+
+func checkClaims(claims []string, request ScopeRequest) error {
+	// a scoped key never has the roles of its user
+	if len(request.RequiredRoles) > 0 {
+		return errors.NewWithCodef(http.StatusForbidden, "the api key scope does not allow routes that require the roles %v", request.RequiredRoles)
+	}
+	if len(request.RequiredClaims) == 0 {
+		return nil
+	}
+
+	missing := make([]string, 0)
+	for _, claim := range request.RequiredClaims {
+		if !containsFold(claims, claim) {
+			missing = append(missing, claim)
+		}
+	}
+	if len(missing) == 0 || (!request.RequireAllClaims && len(missing) < len(request.RequiredClaims)) {
+		return nil
+	}
+
+	return errors.NewWithCodef(http.StatusForbidden, "the api key scope does not contain the claims %v", missing)
+}
+
+// ScopeResources returns the catalogs and host tags the resources of a scope
+// restrict the api key to
+func ScopeResources(resources []models.ApiKeyResource) ([]string, []string) {
+	catalogs := make([]string, 0)
+	hostTags := make([]string, 0)
+	for _, resource := range resources {
+		switch resource.Type {
+		case constants.API_KEY_RESOURCE_CATALOG:
+			catalogs = append(catalogs, resource.Value)
+		case constants.API_KEY_RESOURCE_HOST_TAG:
+			hostTags = append(hostTags, resource.Value)
+		}
+	}
+
+	return catalogs, hostTags
+}
+
+// AllowsCatalog returns true if the catalog is one of the allowed ones, an
+// empty list allows every catalog
+func AllowsCatalog(catalogs []string, catalogId string) bool {
+	return len(catalogs) == 0 || containsFold(catalogs, catalogId)
+}
+
+// AllowsHostTags returns true if any of the tags of a host is one of the
+// allowed ones, an empty list allows every host
+func AllowsHostTags(hostTags []string, tags []string) bool {
+	if len(hostTags) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		if containsFold(hostTags, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func checkResources(resources []models.ApiKeyResource, request ScopeRequest) error {
+	catalogs, hostTags := ScopeResources(resources)
+
+	if request.CatalogId != "" && !AllowsCatalog(catalogs, request.CatalogId) {
+		return errors.NewWithCodef(http.StatusForbidden, "the api key cannot be used for the catalog %v", request.CatalogId)
+	}
+	if len(hostTags) > 0 && request.HostId != "" {
+		var tags []string
+		if request.GetHostTags != nil {
+			tags = request.GetHostTags(request.HostId)
+		}
+		if !AllowsHostTags(hostTags, tags) {
+			return errors.NewWithCodef(http.StatusForbidden, "the api key cannot be used for the host %v", request.HostId)
+		}
+	}
+	if len(hostTags) > 0 && request.MachineId != "" {
+		hostId := ""
+		if request.GetMachineHostId != nil {
+			hostId = request.GetMachineHostId(request.MachineId)
+		}
+		var tags []string
+		if hostId != "" && request.GetHostTags != nil {
+			tags = request.GetHostTags(hostId)
+		}
+		if !AllowsHostTags(hostTags, tags) {
+			return errors.NewWithCodef(http.StatusForbidden, "the api key cannot be used for the machine %v", request.MachineId)
+		}
+	}
+
+	return nil
+}
+
+func isAllowedSource(cidrs []string, sourceIp string) bool {
+	ip := net.ParseIP(sourceIp)
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range cidrs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isAllowedPath(paths []string, path string) bool {
+	for _, allowed := range paths {
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+			continue
+		}
+		if strings.TrimSuffix(allowed, "/") == strings.TrimSuffix(path, "/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
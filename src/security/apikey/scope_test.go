@@ -0,0 +1,99 @@
+package apikey
+
+import (
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckScope(t *testing.T) {
+	scope := &models.ApiKeyScope{
+		Claims:      []string{constants.LIST_VM_CLAIM, constants.LIST_CATALOG_MANIFEST_CLAIM},
+		Methods:     []string{"GET"},
+		Paths:       []string{"/api/v1/machines*", "/api/v1/catalog"},
+		SourceCidrs: []string{"10.0.0.0/8"},
+	}
+	request := ScopeRequest{
+		Method:           "GET",
+		Path:             "/api/v1/machines/vm-1",
+		SourceIp:         "10.1.2.3",
+		RequiredClaims:   []string{constants.LIST_VM_CLAIM},
+		RequireAllClaims: true,
+	}
+
+	assert.NoError(t, CheckScope(nil, request))
+	assert.NoError(t, CheckScope(scope, request))
+
+	denied := request
+	denied.SourceIp = "192.168.1.1"
+	assert.ErrorContains(t, CheckScope(scope, denied), "cannot be used from 192.168.1.1")
+
+	denied = request
+	denied.Method = "DELETE"
+	assert.ErrorContains(t, CheckScope(scope, denied), "cannot be used for DELETE requests")
+
+	denied = request
+	denied.Path = "/api/v1/catalog/ubuntu"
+	assert.ErrorContains(t, CheckScope(scope, denied), "cannot be used for /api/v1/catalog/ubuntu")
+
+	denied = request
+	denied.RequiredClaims = []string{constants.LIST_VM_CLAIM, constants.CREATE_VM_CLAIM}
+	assert.ErrorContains(t, CheckScope(scope, denied), "does not contain the claims [CREATE_VM]")
+
+	allowed := denied
+	allowed.RequireAllClaims = false
+	assert.NoError(t, CheckScope(scope, allowed))
+
+	denied = request
+	denied.RequiredRoles = []string{constants.SUPER_USER_ROLE}
+	assert.ErrorContains(t, CheckScope(scope, denied), "require the roles")
+}
+
+func TestCheckScope_Resources(t *testing.T) {
+	scope := &models.ApiKeyScope{
+		Resources: []models.ApiKeyResource{
+			{Type: constants.API_KEY_RESOURCE_CATALOG, Value: "ubuntu"},
+			{Type: constants.API_KEY_RESOURCE_HOST_TAG, Value: "ci"},
+		},
+	}
+	getHostTags := func(id string) []string {
+		if id == "ci-host" {
+			return []string{"CI", "arm64"}
+		}
+		return []string{"prod"}
+	}
+
+	assert.NoError(t, CheckScope(scope, ScopeRequest{Path: "/api/v1/catalog"}))
+	assert.NoError(t, CheckScope(scope, ScopeRequest{CatalogId: "Ubuntu"}))
+	assert.ErrorContains(t, CheckScope(scope, ScopeRequest{CatalogId: "windows"}), "catalog windows")
+	assert.NoError(t, CheckScope(scope, ScopeRequest{HostId: "ci-host", GetHostTags: getHostTags}))
+	assert.ErrorContains(t, CheckScope(scope, ScopeRequest{HostId: "prod-host", GetHostTags: getHostTags}), "host prod-host")
+
+	// machines are checked against the tags of the host running them
+	getMachineHostId := func(id string) string {
+		if id == "ci-vm" {
+			return "ci-host"
+		}
+		return ""
+	}
+	assert.NoError(t, CheckScope(scope, ScopeRequest{MachineId: "ci-vm", GetHostTags: getHostTags, GetMachineHostId: getMachineHostId}))
+	assert.ErrorContains(t, CheckScope(scope, ScopeRequest{MachineId: "unknown-vm", GetHostTags: getHostTags, GetMachineHostId: getMachineHostId}), "machine unknown-vm")
+}
+
+func TestScopeResources(t *testing.T) {
+	catalogs, hostTags := ScopeResources([]models.ApiKeyResource{
+		{Type: constants.API_KEY_RESOURCE_CATALOG, Value: "ubuntu"},
+		{Type: constants.API_KEY_RESOURCE_HOST_TAG, Value: "ci"},
+	})
+	assert.Equal(t, []string{"ubuntu"}, catalogs)
+	assert.Equal(t, []string{"ci"}, hostTags)
+
+	assert.True(t, AllowsCatalog(nil, "windows"))
+	assert.True(t, AllowsCatalog(catalogs, "UBUNTU"))
+	assert.False(t, AllowsCatalog(catalogs, "windows"))
+	assert.True(t, AllowsHostTags(nil, nil))
+	assert.True(t, AllowsHostTags(hostTags, []string{"prod", "CI"}))
+	assert.False(t, AllowsHostTags(hostTags, []string{"prod"}))
+}
@@ -0,0 +1,235 @@
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+// Prefix marks the values encrypted by the envelope, the full format is
+// enc:v1:<master key id>:<wrapped data key>:<ciphertext>
+const Prefix = "enc:v1:"
+
+const keySize = 32
+
+// MasterKey wraps the data keys used to encrypt each value, only the wrapped
+// data keys are stored next to the values so rotating the master key does not
+// need the values to be encrypted again
+type MasterKey struct {
+	id  string
+	key []byte
+}
+
+func NewMasterKey(key []byte) (*MasterKey, error) {
+	if len(key) != keySize {
+		return nil, errors.Newf("the master key needs to be %d bytes, found %d", keySize, len(key))
+	}
+
+	hash := sha256.Sum256(key)
+	return &MasterKey{
+		id:  hex.EncodeToString(hash[:4]),
+		key: append([]byte{}, key...),
+	}, nil
+}
+
+// ParseMasterKey reads a base64 encoded master key
+func ParseMasterKey(value string) (*MasterKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, errors.NewFromErrorf(err, "the master key is not valid base64")
+	}
+
+	return NewMasterKey(key)
+}
+
+// LoadMasterKeyFile reads a base64 encoded master key from a file
+func LoadMasterKeyFile(path string) (*MasterKey, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseMasterKey(string(content))
+}
+
+// GenerateMasterKey returns a new random base64 encoded master key
+func GenerateMasterKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ID identifies the master key in the encrypted values without revealing it
+func (k *MasterKey) ID() string {
+	return k.id
+}
+
+// IsEncrypted returns true if the value was encrypted by the envelope
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// KeyID returns the id of the master key that wrapped the value
+func KeyID(value string) string {
+	parts, err := split(value)
+	if err != nil {
+		return ""
+	}
+
+	return parts[0]
+}
+
+// Encrypt encrypts the value with a new data key and wraps the data key with
+// the master key, empty and already encrypted values are returned as they are
+func (k *MasterKey) Encrypt(value string) (string, error) {
+	if value == "" || IsEncrypted(value) {
+		return value, nil
+	}
+
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+
+	wrappedKey, err := seal(k.key, dataKey)
+	if err != nil {
+		return "", err
+	}
+	cipherText, err := seal(dataKey, []byte(value))
+	if err != nil {
+		return "", err
+	}
+
+	return Prefix + strings.Join([]string{
+		k.id,
+		base64.StdEncoding.EncodeToString(wrappedKey),
+		base64.StdEncoding.EncodeToString(cipherText),
+	}, ":"), nil
+}
+
+// Decrypt returns the plain value, values that are not encrypted are returned
+// as they are
+func (k *MasterKey) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	dataKey, cipherText, err := k.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+
+	plainText, err := open(dataKey, cipherText)
+	if err != nil {
+		return "", errors.NewFromErrorf(err, "error decrypting the value")
+	}
+
+	return string(plainText), nil
+}
+
+// Rewrap wraps the data key of the value with a new master key keeping the
+// encrypted value, values that are not encrypted are encrypted with the new key
+func (k *MasterKey) Rewrap(value string, newKey *MasterKey) (string, error) {
+	if !IsEncrypted(value) {
+		return newKey.Encrypt(value)
+	}
+
+	dataKey, cipherText, err := k.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+
+	wrappedKey, err := seal(newKey.key, dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	return Prefix + strings.Join([]string{
+		newKey.id,
+		base64.StdEncoding.EncodeToString(wrappedKey),
+		base64.StdEncoding.EncodeToString(cipherText),
+	}, ":"), nil
+}
+
+func (k *MasterKey) unwrap(value string) ([]byte, []byte, error) {
+	parts, err := split(value)
+	if err != nil {
+		return nil, nil, err
+	}
+	if parts[0] != k.id {
+		return nil, nil, errors.Newf("the value was encrypted with the master key %v but the configured key is %v", parts[0], k.id)
+	}
+
+	wrappedKey, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, errors.NewFromErrorf(err, "the encrypted value is corrupted")
+	}
+	cipherText, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, errors.NewFromErrorf(err, "the encrypted value is corrupted")
+	}
+
+	dataKey, err := open(k.key, wrappedKey)
+	if err != nil {
+		return nil, nil, errors.NewFromErrorf(err, "error unwrapping the data key")
+	}
+
+	return dataKey, cipherText, nil
+}
+
+func split(value string) ([]string, error) {
+	parts := strings.Split(strings.TrimPrefix(value, Prefix), ":")
+	if !IsEncrypted(value) || len(parts) != 3 {
+		return nil, errors.New("the encrypted value is corrupted")
+	}
+
+	return parts, nil
+}
+
+// seal encrypts with AES-GCM returning the nonce followed by the ciphertext
+func seal(key []byte, plainText []byte) ([]byte, error) {
+	gcm, err := newGcm(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plainText, nil), nil
+}
+
+func open(key []byte, cipherText []byte) ([]byte, error) {
+	gcm, err := newGcm(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(cipherText) < gcm.NonceSize() {
+		return nil, errors.New("the encrypted value is too short")
+	}
+
+	return gcm.Open(nil, cipherText[:gcm.NonceSize()], cipherText[gcm.NonceSize():], nil)
+}
+
+func newGcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,105 @@
+package envelope
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKey(t *testing.T) *MasterKey {
+	value, err := GenerateMasterKey()
+	require.NoError(t, err)
+	key, err := ParseMasterKey(value)
+	require.NoError(t, err)
+
+	return key
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := newTestKey(t)
+
+	encrypted, err := key.Encrypt("password")
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(encrypted))
+	assert.NotContains(t, encrypted, "password")
+	assert.Equal(t, key.ID(), KeyID(encrypted))
+
+	again, err := key.Encrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, encrypted, again)
+
+	decrypted, err := key.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "password", decrypted)
+
+	empty, err := key.Encrypt("")
+	require.NoError(t, err)
+	assert.Equal(t, "", empty)
+
+	plain, err := key.Decrypt("plain")
+	require.NoError(t, err)
+	assert.Equal(t, "plain", plain)
+}
+
+func TestDecrypt_WrongKey(t *testing.T) {
+	key := newTestKey(t)
+	other := newTestKey(t)
+
+	encrypted, err := key.Encrypt("password")
+	require.NoError(t, err)
+
+	_, err = other.Decrypt(encrypted)
+	assert.ErrorContains(t, err, "was encrypted with the master key "+key.ID())
+
+	_, err = key.Decrypt(Prefix + "corrupted")
+	assert.ErrorContains(t, err, "the encrypted value is corrupted")
+
+	parts := strings.Split(encrypted, ":")
+	parts[len(parts)-1] = "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+	_, err = key.Decrypt(strings.Join(parts, ":"))
+	assert.ErrorContains(t, err, "error decrypting the value")
+}
+
+func TestRewrap(t *testing.T) {
+	key := newTestKey(t)
+	newKey := newTestKey(t)
+
+	encrypted, err := key.Encrypt("password")
+	require.NoError(t, err)
+
+	rewrapped, err := key.Rewrap(encrypted, newKey)
+	require.NoError(t, err)
+	assert.Equal(t, newKey.ID(), KeyID(rewrapped))
+	// the value keeps its data key so only the wrapped key changes
+	assert.Equal(t, encrypted[strings.LastIndex(encrypted, ":"):], rewrapped[strings.LastIndex(rewrapped, ":"):])
+
+	decrypted, err := newKey.Decrypt(rewrapped)
+	require.NoError(t, err)
+	assert.Equal(t, "password", decrypted)
+
+	_, err = key.Decrypt(rewrapped)
+	assert.Error(t, err)
+
+	rewrapped, err = key.Rewrap("plain", newKey)
+	require.NoError(t, err)
+	assert.Equal(t, newKey.ID(), KeyID(rewrapped))
+}
+
+func TestParseMasterKey(t *testing.T) {
+	_, err := ParseMasterKey("not base64!")
+	assert.ErrorContains(t, err, "the master key is not valid base64")
+
+	_, err = ParseMasterKey("c2hvcnQ=")
+	assert.ErrorContains(t, err, "the master key needs to be 32 bytes, found 5")
+
+	value, err := GenerateMasterKey()
+	require.NoError(t, err)
+	first, err := ParseMasterKey(value + "\n")
+	require.NoError(t, err)
+	second, err := ParseMasterKey(value)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID(), second.ID())
+	assert.Len(t, first.ID(), 8)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/Parallels/prl-devops-service/security"
 	"github.com/go-jose/go-jose/v4"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
 var globalJwtService *JwtService
@@ -47,6 +48,11 @@ func (s *JwtService) WithTokenDuration(duration string) *JwtService {
 	return s
 }
 
+func (s *JwtService) WithRefreshTokenDuration(duration string) *JwtService {
+	s.Options.WithRefreshTokenDuration(duration)
+	return s
+}
+
 func (s *JwtService) WithSecret(secret string) *JwtService {
 	s.Options.WithSecret(secret)
 	return s
@@ -72,7 +78,8 @@ func (s *JwtService) Sign(claims map[string]interface{}) (string, error) {
 		duration = time.Minute * 15
 	}
 
-	expiresAt := time.Now().Add(duration).Unix()
+	now := time.Now()
+	expiresAt := now.Add(duration).Unix()
 	var method jwt.SigningMethod
 
 	switch s.Options.Algorithm {
@@ -99,8 +106,12 @@ func (s *JwtService) Sign(claims map[string]interface{}) (string, error) {
 		claims["claims"] = map[string]interface{}{}
 	}
 
+	// the token id is used to revoke a single token and the fractional issue
+	// time to revoke the tokens issued before a given moment
 	defaultClaims := jwt.MapClaims{
 		"exp": expiresAt,
+		"iat": float64(now.UnixMicro()) / 1e6,
+		"jti": uuid.New().String(),
 	}
 
 	for k, v := range claims {
@@ -261,6 +272,15 @@ func (s *JwtService) processEnvironmentVariables() error {
 		s.Options.WithTokenDuration(cfg.GetKey(constants.JWT_DURATION_ENV_VAR))
 	}
 
+	if cfg.GetKey(constants.JWT_REFRESH_DURATION_ENV_VAR) != "" {
+		_, err := time.ParseDuration(cfg.GetKey(constants.JWT_REFRESH_DURATION_ENV_VAR))
+		if err != nil {
+			return err
+		}
+
+		s.Options.WithRefreshTokenDuration(cfg.GetKey(constants.JWT_REFRESH_DURATION_ENV_VAR))
+	}
+
 	// generating a default secret if none is provided
 	if s.Options.Algorithm == JwtSigningAlgorithmHS256 || s.Options.Algorithm == JwtSigningAlgorithmHS384 || s.Options.Algorithm == JwtSigningAlgorithmHS512 {
 		if s.Options.Secret == "" {
@@ -1,6 +1,8 @@
 package jwt
 
 import (
+	"time"
+
 	"github.com/Parallels/prl-devops-service/basecontext"
 )
 
@@ -21,6 +23,9 @@ type JwtOptions struct {
 	Secret        string
 	PrivateKey    string
 	TokenDuration string
+	// RefreshTokenDuration is how long a session can be refreshed without
+	// logging in again
+	RefreshTokenDuration string
 }
 
 func NewDefaultOptions(ctx basecontext.ApiContext) *JwtOptions {
@@ -29,9 +34,10 @@ func NewDefaultOptions(ctx basecontext.ApiContext) *JwtOptions {
 	}
 
 	return &JwtOptions{
-		ctx:           ctx,
-		Algorithm:     JwtSigningAlgorithmHS256,
-		TokenDuration: "15m",
+		ctx:                  ctx,
+		Algorithm:            JwtSigningAlgorithmHS256,
+		TokenDuration:        "15m",
+		RefreshTokenDuration: "168h",
 	}
 }
 
@@ -54,3 +60,19 @@ func (o *JwtOptions) WithTokenDuration(duration string) *JwtOptions {
 	o.TokenDuration = duration
 	return o
 }
+
+func (o *JwtOptions) WithRefreshTokenDuration(duration string) *JwtOptions {
+	o.RefreshTokenDuration = duration
+	return o
+}
+
+// GetRefreshTokenDuration returns the lifetime of the refresh tokens, seven
+// days when the duration is not valid
+func (o *JwtOptions) GetRefreshTokenDuration() time.Duration {
+	duration, err := time.ParseDuration(o.RefreshTokenDuration)
+	if err != nil || duration <= 0 {
+		return time.Hour * 168
+	}
+
+	return duration
+}
@@ -1,6 +1,7 @@
 package jwt
 
 import (
+	"math"
 	"time"
 
 	"github.com/Parallels/prl-devops-service/errors"
@@ -87,3 +88,40 @@ func (s *JwtSystemToken) GetClaim(key string) (interface{}, error) {
 
 	return claim, nil
 }
+
+// GetTokenId returns the jti claim, tokens issued before it was added have none
+func (s *JwtSystemToken) GetTokenId() string {
+	claim, err := s.GetClaim("jti")
+	if err != nil {
+		return ""
+	}
+
+	tokenId, _ := claim.(string)
+	return tokenId
+}
+
+// GetSessionId returns the session the token was issued for, tokens exchanged
+// for api keys do not belong to a session
+func (s *JwtSystemToken) GetSessionId() string {
+	claim, err := s.GetClaim("sid")
+	if err != nil {
+		return ""
+	}
+
+	sessionId, _ := claim.(string)
+	return sessionId
+}
+
+func (s *JwtSystemToken) GetIssuedAt() (time.Time, error) {
+	claim, err := s.GetClaim("iat")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	issuedAt, ok := claim.(float64)
+	if !ok {
+		return time.Time{}, errors.New("invalid issuedAt")
+	}
+
+	return time.UnixMicro(int64(math.Round(issuedAt * 1e6))), nil
+}
@@ -186,3 +186,32 @@ func TestGetClaimWrongFormat(t *testing.T) {
 	_, err := token.GetClaim("test")
 	assert.Errorf(t, err, "invalid claim")
 }
+
+func TestGetTokenIdAndIssuedAt(t *testing.T) {
+	before := time.Now().Add(-time.Millisecond)
+	token := SetupToken(t)
+
+	assert.NotEmpty(t, token.GetTokenId())
+	assert.Empty(t, token.GetSessionId())
+
+	issuedAt, err := token.GetIssuedAt()
+	assert.NoError(t, err)
+	assert.True(t, issuedAt.After(before))
+	assert.False(t, issuedAt.After(time.Now()))
+}
+
+func TestGetSessionId(t *testing.T) {
+	ctx := basecontext.NewRootBaseContext()
+	svc := New(ctx)
+	svc.Options.WithSecret("secret")
+
+	tokenStr, err := svc.Sign(map[string]interface{}{
+		"email": "test@example.com",
+		"sid":   "session-id",
+	})
+	assert.NoError(t, err)
+
+	token, err := svc.Parse(tokenStr)
+	assert.NoError(t, err)
+	assert.Equal(t, "session-id", token.GetSessionId())
+}
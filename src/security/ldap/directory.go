@@ -0,0 +1,204 @@
+package ldap
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/errors"
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+const (
+	connectionTimeout = 10 * time.Second
+	searchTimeLimit   = 10
+)
+
+// DirectoryUser is a user entry of the directory
+type DirectoryUser struct {
+	DN       string
+	Username string
+	Email    string
+	Name     string
+	Groups   []string
+}
+
+// directory is a connection to the directory, it is bound as the service
+// account when it is opened
+type directory interface {
+	// FindUser returns the user matching the user filter, nil if no user
+	// matches it
+	FindUser(username string) (*DirectoryUser, error)
+	// Bind checks the password of the user, the connection is bound as the
+	// service account again before returning
+	Bind(dn string, password string) error
+	// GetGroups returns the names of the groups the user is a member of
+	GetGroups(user *DirectoryUser) ([]string, error)
+	Close()
+}
+
+type ldapDirectory struct {
+	conn    *goldap.Conn
+	options *LdapOptions
+}
+
+func dial(options *LdapOptions) (directory, error) {
+	tlsConfig, err := options.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := goldap.DialURL(options.Url,
+		goldap.DialWithTLSConfig(tlsConfig),
+		goldap.DialWithDialer(&net.Dialer{Timeout: connectionTimeout}))
+	if err != nil {
+		return nil, errors.NewFromErrorf(err, "error connecting to the LDAP server %v", options.Url)
+	}
+	conn.SetTimeout(connectionTimeout)
+
+	if options.StartTLS && !strings.HasPrefix(strings.ToLower(options.Url), "ldaps://") {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, errors.NewFromErrorf(err, "error starting TLS with the LDAP server %v", options.Url)
+		}
+	}
+
+	result := &ldapDirectory{conn: conn, options: options}
+	if err := result.bindServiceAccount(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (d *ldapDirectory) bindServiceAccount() error {
+	if d.options.BindDn == "" {
+		if err := d.conn.UnauthenticatedBind(""); err != nil {
+			return errors.NewFromErrorf(err, "error binding anonymously to the LDAP server")
+		}
+		return nil
+	}
+
+	if err := d.conn.Bind(d.options.BindDn, d.options.BindPassword); err != nil {
+		return errors.NewFromErrorf(err, "error binding to the LDAP server as %v", d.options.BindDn)
+	}
+
+	return nil
+}
+
+func (d *ldapDirectory) FindUser(username string) (*DirectoryUser, error) {
+	filter := strings.ReplaceAll(d.options.UserFilter, "{username}", goldap.EscapeFilter(username))
+	request := goldap.NewSearchRequest(
+		d.options.UserSearchBase,
+		goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 2, searchTimeLimit, false,
+		filter,
+		[]string{d.options.UsernameAttribute, d.options.EmailAttribute, d.options.NameAttribute, "memberOf"},
+		nil,
+	)
+
+	result, err := d.conn.Search(request)
+	if err != nil && !goldap.IsErrorWithCode(err, goldap.LDAPResultSizeLimitExceeded) {
+		return nil, errors.NewFromErrorf(err, "error searching the LDAP user %v", username)
+	}
+	if result == nil || len(result.Entries) == 0 {
+		return nil, nil
+	}
+	if len(result.Entries) > 1 {
+		return nil, errors.Newf("the LDAP user filter matches more than one entry for %v", username)
+	}
+
+	entry := result.Entries[0]
+	user := &DirectoryUser{
+		DN:       entry.DN,
+		Username: entry.GetAttributeValue(d.options.UsernameAttribute),
+		Email:    entry.GetAttributeValue(d.options.EmailAttribute),
+		Name:     entry.GetAttributeValue(d.options.NameAttribute),
+	}
+	if user.Username == "" {
+		user.Username = username
+	}
+	for _, groupDn := range entry.GetAttributeValues("memberOf") {
+		if name := commonName(groupDn); name != "" {
+			user.Groups = append(user.Groups, name)
+		}
+	}
+
+	return user, nil
+}
+
+func (d *ldapDirectory) Bind(dn string, password string) error {
+	// an empty password is an unauthenticated bind and always succeeds
+	if password == "" {
+		return errors.New("the password cannot be empty")
+	}
+
+	err := d.conn.Bind(dn, password)
+	if bindErr := d.bindServiceAccount(); bindErr != nil && err == nil {
+		err = bindErr
+	}
+
+	return err
+}
+
+func (d *ldapDirectory) GetGroups(user *DirectoryUser) ([]string, error) {
+	groups := append([]string{}, user.Groups...)
+	if d.options.GroupSearchBase == "" {
+		return groups, nil
+	}
+
+	filter := strings.NewReplacer(
+		"{dn}", goldap.EscapeFilter(user.DN),
+		"{username}", goldap.EscapeFilter(user.Username),
+	).Replace(d.options.GroupFilter)
+	request := goldap.NewSearchRequest(
+		d.options.GroupSearchBase,
+		goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 0, searchTimeLimit, false,
+		filter,
+		[]string{d.options.GroupNameAttribute},
+		nil,
+	)
+
+	result, err := d.conn.Search(request)
+	if err != nil {
+		return nil, errors.NewFromErrorf(err, "error searching the LDAP groups of %v", user.DN)
+	}
+	for _, entry := range result.Entries {
+		if name := entry.GetAttributeValue(d.options.GroupNameAttribute); name != "" && !containsGroup(groups, name) {
+			groups = append(groups, name)
+		}
+	}
+
+	return groups, nil
+}
+
+func (d *ldapDirectory) Close() {
+	d.conn.Close()
+}
+
+// commonName returns the first common name of a distinguished name
+func commonName(dn string) string {
+	parsed, err := goldap.ParseDN(dn)
+	if err != nil {
+		return ""
+	}
+	for _, rdn := range parsed.RDNs {
+		for _, attribute := range rdn.Attributes {
+			if strings.EqualFold(attribute.Type, "cn") {
+				return attribute.Value
+			}
+		}
+	}
+
+	return ""
+}
+
+func containsGroup(groups []string, name string) bool {
+	for _, group := range groups {
+		if strings.EqualFold(group, name) {
+			return true
+		}
+	}
+
+	return false
+}
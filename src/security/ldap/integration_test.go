@@ -0,0 +1,113 @@
+//go:build integration
+
+package ldap
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/security/provisioning"
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	integrationBaseDn  = "dc=example,dc=org"
+	integrationUserDn  = "uid=ldap-test,dc=example,dc=org"
+	integrationGroupDn = "cn=ldap-test-admins,dc=example,dc=org"
+)
+
+func getEnv(name string, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
+
+// newIntegrationService creates a test user and group in a real directory,
+// for example:
+//
+//	docker run --rm -p 389:389 -e LDAP_ORGANISATION=Example -e LDAP_DOMAIN=example.org \
+//	  -e LDAP_ADMIN_PASSWORD=admin osixia/openldap:1.5.0
+//	LDAP_TEST_URL=ldap://localhost:389 go test -tags integration ./security/ldap/
+func newIntegrationService(t *testing.T) (*LdapService, *goldap.Conn) {
+	url := os.Getenv("LDAP_TEST_URL")
+	if url == "" {
+		t.Skip("LDAP_TEST_URL is not set")
+	}
+
+	options := NewDefaultOptions().
+		WithUrl(url).
+		WithBind(getEnv("LDAP_TEST_BIND_DN", "cn=admin,"+integrationBaseDn), getEnv("LDAP_TEST_BIND_PASSWORD", "admin")).
+		WithUserSearchBase(integrationBaseDn).
+		WithGroupSearchBase(integrationBaseDn).
+		WithGroupRoles(provisioning.ParseGroupMapping("ldap-test-admins=" + constants.SUPER_USER_ROLE))
+	options.StartTLS = os.Getenv("LDAP_TEST_START_TLS") == "true"
+	options.InsecureSkipVerify = true
+
+	conn, err := dial(options)
+	require.NoError(t, err)
+	admin := conn.(*ldapDirectory).conn
+
+	_ = admin.Del(goldap.NewDelRequest(integrationGroupDn, nil))
+	_ = admin.Del(goldap.NewDelRequest(integrationUserDn, nil))
+	user := goldap.NewAddRequest(integrationUserDn, nil)
+	user.Attribute("objectClass", []string{"inetOrgPerson"})
+	user.Attribute("uid", []string{"ldap-test"})
+	user.Attribute("cn", []string{"LDAP Test"})
+	user.Attribute("sn", []string{"Test"})
+	user.Attribute("mail", []string{"ldap-test@example.org"})
+	user.Attribute("userPassword", []string{"Secret1!"})
+	require.NoError(t, admin.Add(user))
+	group := goldap.NewAddRequest(integrationGroupDn, nil)
+	group.Attribute("objectClass", []string{"groupOfNames"})
+	group.Attribute("cn", []string{"ldap-test-admins"})
+	group.Attribute("member", []string{integrationUserDn})
+	require.NoError(t, admin.Add(group))
+
+	t.Cleanup(func() {
+		_ = admin.Del(goldap.NewDelRequest(integrationGroupDn, nil))
+		_ = admin.Del(goldap.NewDelRequest(integrationUserDn, nil))
+		conn.Close()
+	})
+
+	return NewService(nil, options), admin
+}
+
+func TestIntegration_Authenticate(t *testing.T) {
+	svc, _ := newIntegrationService(t)
+
+	user, err := svc.Authenticate("ldap-test", "Secret1!")
+	require.NoError(t, err)
+	assert.Equal(t, integrationUserDn, user.DN)
+	assert.Equal(t, "ldap-test@example.org", user.Email)
+	assert.Contains(t, user.Groups, "ldap-test-admins")
+
+	_, err = svc.Authenticate("ldap-test@example.org", "wrong")
+	assert.Equal(t, ErrInvalidCredentials, err)
+	_, err = svc.Authenticate("ldap-test*", "Secret1!")
+	assert.Equal(t, ErrInvalidCredentials, err)
+}
+
+func TestIntegration_Sync(t *testing.T) {
+	svc, admin := newIntegrationService(t)
+	ctx, db, _, _ := newTestService(t)
+
+	user, err := svc.Login(ctx, db, "ldap-test", "Secret1!")
+	require.NoError(t, err)
+	require.Len(t, user.Roles, 1)
+	assert.Equal(t, constants.SUPER_USER_ROLE, user.Roles[0].ID)
+
+	require.NoError(t, admin.Del(goldap.NewDelRequest(integrationGroupDn, nil)))
+	require.NoError(t, admin.Del(goldap.NewDelRequest(integrationUserDn, nil)))
+	result, err := svc.Sync(ctx, db)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Disabled)
+
+	stored, err := db.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.Disabled)
+}
@@ -0,0 +1,185 @@
+package ldap
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/security/provisioning"
+)
+
+var globalLdapService *LdapService
+
+// ErrInvalidCredentials is returned for unknown users and wrong passwords, the
+// callers cannot tell them apart
+var ErrInvalidCredentials = errors.NewWithCode("invalid user or password", http.StatusUnauthorized)
+
+type LdapService struct {
+	ctx     basecontext.ApiContext
+	Options *LdapOptions
+	connect func() (directory, error)
+}
+
+func New(ctx basecontext.ApiContext) *LdapService {
+	options := NewDefaultOptions()
+	processEnvironmentVariables(ctx, options)
+	if !options.IsConfigured() {
+		globalLdapService = nil
+		return nil
+	}
+
+	globalLdapService = NewService(ctx, options)
+	ctx.LogInfof("[LDAP] Directory authentication enabled with server %v", options.Url)
+	return globalLdapService
+}
+
+func NewService(ctx basecontext.ApiContext, options *LdapOptions) *LdapService {
+	if ctx == nil {
+		ctx = basecontext.NewRootBaseContext()
+	}
+
+	return &LdapService{
+		ctx:     ctx,
+		Options: options,
+		connect: func() (directory, error) {
+			return dial(options)
+		},
+	}
+}
+
+// Get returns the LDAP service or nil if the directory is not configured
+func Get() *LdapService {
+	return globalLdapService
+}
+
+// HandlesUser returns true if the login of the user is checked against the
+// directory, these are the unknown users and the users created by the
+// directory. The root user always logs in with the local password so it can
+// still be used when the directory is not available
+func (s *LdapService) HandlesUser(user *models.User) bool {
+	if user == nil {
+		return true
+	}
+	if user.ID == constants.ROOT_USER_ID {
+		return false
+	}
+
+	return user.IdentityProvider == constants.LDAP_IDENTITY_PROVIDER
+}
+
+// Authenticate binds as the user with the password and returns the user entry
+// with its groups
+func (s *LdapService) Authenticate(username string, password string) (*DirectoryUser, error) {
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	user, err := conn.FindUser(username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err := conn.Bind(user.DN, password); err != nil {
+		s.ctx.LogDebugf("[LDAP] Bind failed for %v: %v", user.DN, err)
+		return nil, ErrInvalidCredentials
+	}
+
+	groups, err := conn.GetGroups(user)
+	if err != nil {
+		return nil, err
+	}
+	user.Groups = groups
+
+	return user, nil
+}
+
+// Login authenticates the user against the directory and returns its local
+// user, creating it on its first login
+func (s *LdapService) Login(ctx basecontext.ApiContext, db *data.JsonDatabase, username string, password string) (*models.User, error) {
+	directoryUser, err := s.Authenticate(username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// the account is back in the directory, the sync would enable it on its
+	// next run anyway
+	if user, _ := db.GetUser(ctx, directoryUser.Email); user != nil && user.Disabled && user.DisabledReason == constants.LDAP_SYNC_DISABLED_REASON {
+		if err := db.UpdateUserDisabledStatus(ctx, user.ID, false, ""); err != nil {
+			return nil, err
+		}
+		ctx.LogInfof("[LDAP] User %v enabled again", user.Email)
+	}
+
+	return provisioning.ProvisionUser(ctx, db, provisioning.ExternalUser{
+		Email:    directoryUser.Email,
+		Username: directoryUser.Username,
+		Name:     directoryUser.Name,
+		Groups:   directoryUser.Groups,
+	}, provisioning.Options{
+		Provider:       constants.LDAP_IDENTITY_PROVIDER,
+		GroupRoles:     s.Options.GroupRoles,
+		GroupClaims:    s.Options.GroupClaims,
+		AutoProvision:  s.Options.AutoProvision,
+		LinkLocalUsers: s.Options.LinkLocalUsers,
+	})
+}
+
+func processEnvironmentVariables(ctx basecontext.ApiContext, options *LdapOptions) {
+	cfg := config.Get()
+	options.WithUrl(cfg.GetKey(constants.LDAP_URL_ENV_VAR))
+	options.StartTLS = cfg.GetBoolKey(constants.LDAP_START_TLS_ENV_VAR)
+	options.InsecureSkipVerify = cfg.GetBoolKey(constants.LDAP_INSECURE_SKIP_VERIFY_ENV_VAR)
+	options.CaCertFile = cfg.GetKey(constants.LDAP_CA_CERT_FILE_ENV_VAR)
+	options.WithBind(cfg.GetKey(constants.LDAP_BIND_DN_ENV_VAR), cfg.GetKey(constants.LDAP_BIND_PASSWORD_ENV_VAR))
+	options.WithUserSearchBase(cfg.GetKey(constants.LDAP_USER_SEARCH_BASE_ENV_VAR))
+	options.WithGroupSearchBase(cfg.GetKey(constants.LDAP_GROUP_SEARCH_BASE_ENV_VAR))
+
+	if filter := cfg.GetKey(constants.LDAP_USER_FILTER_ENV_VAR); filter != "" {
+		options.UserFilter = filter
+	}
+	if attribute := cfg.GetKey(constants.LDAP_USERNAME_ATTRIBUTE_ENV_VAR); attribute != "" {
+		options.UsernameAttribute = attribute
+	}
+	if attribute := cfg.GetKey(constants.LDAP_EMAIL_ATTRIBUTE_ENV_VAR); attribute != "" {
+		options.EmailAttribute = attribute
+	}
+	if attribute := cfg.GetKey(constants.LDAP_NAME_ATTRIBUTE_ENV_VAR); attribute != "" {
+		options.NameAttribute = attribute
+	}
+	if filter := cfg.GetKey(constants.LDAP_GROUP_FILTER_ENV_VAR); filter != "" {
+		options.GroupFilter = filter
+	}
+	if attribute := cfg.GetKey(constants.LDAP_GROUP_NAME_ATTRIBUTE_ENV_VAR); attribute != "" {
+		options.GroupNameAttribute = attribute
+	}
+	options.WithGroupRoles(provisioning.ParseGroupMapping(cfg.GetKey(constants.LDAP_GROUP_ROLES_ENV_VAR)))
+	options.WithGroupClaims(provisioning.ParseGroupMapping(cfg.GetKey(constants.LDAP_GROUP_CLAIMS_ENV_VAR)))
+
+	autoProvision := strings.ToLower(cfg.GetKey(constants.LDAP_AUTO_PROVISION_ENV_VAR))
+	options.AutoProvision = autoProvision != "false" && autoProvision != "0"
+	options.LinkLocalUsers = cfg.GetBoolKey(constants.LDAP_LINK_LOCAL_USERS_ENV_VAR)
+
+	if value := cfg.GetKey(constants.LDAP_SYNC_INTERVAL_ENV_VAR); value != "" {
+		if value == "0" {
+			options.SyncInterval = 0
+		} else if interval, err := time.ParseDuration(value); err == nil {
+			options.SyncInterval = interval
+		} else {
+			ctx.LogWarnf("[LDAP] Invalid sync interval %v, using %v", value, options.SyncInterval)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package ldap
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/security/provisioning"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEntry struct {
+	user     DirectoryUser
+	password string
+}
+
+type fakeDirectory struct {
+	entries   map[string]fakeEntry
+	searchErr error
+	closed    int
+}
+
+func (d *fakeDirectory) FindUser(username string) (*DirectoryUser, error) {
+	if d.searchErr != nil {
+		return nil, d.searchErr
+	}
+	for _, entry := range d.entries {
+		if strings.EqualFold(entry.user.Username, username) || strings.EqualFold(entry.user.Email, username) {
+			user := entry.user
+			return &user, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (d *fakeDirectory) Bind(dn string, password string) error {
+	entry, ok := d.entries[dn]
+	if !ok || password == "" || entry.password != password {
+		return errors.New("invalid credentials")
+	}
+
+	return nil
+}
+
+func (d *fakeDirectory) GetGroups(user *DirectoryUser) ([]string, error) {
+	return append(user.Groups, "everyone"), nil
+}
+
+func (d *fakeDirectory) Close() {
+	d.closed++
+}
+
+const bobDn = "uid=bob,ou=people,dc=example,dc=org"
+
+func newTestService(t *testing.T) (basecontext.ApiContext, *data.JsonDatabase, *LdapService, *fakeDirectory) {
+	ctx := basecontext.NewBaseContext()
+	ctx.DisableLog()
+	db := data.NewJsonDatabase(ctx, filepath.Join(t.TempDir(), "data.json"))
+	require.NoError(t, db.Connect(ctx))
+	for _, claim := range append([]string{constants.CREATE_VM_CLAIM}, constants.DefaultClaims...) {
+		if existing, _ := db.GetClaim(ctx, claim); existing == nil {
+			_, err := db.CreateClaim(ctx, models.Claim{Name: claim})
+			require.NoError(t, err)
+		}
+	}
+	for _, role := range []string{constants.USER_ROLE, constants.SUPER_USER_ROLE} {
+		if existing, _ := db.GetRole(ctx, role); existing == nil {
+			_, err := db.CreateRole(ctx, models.Role{Name: role})
+			require.NoError(t, err)
+		}
+	}
+
+	fake := &fakeDirectory{entries: map[string]fakeEntry{
+		"uid=alice,ou=people,dc=example,dc=org": {
+			user:     DirectoryUser{DN: "uid=alice,ou=people,dc=example,dc=org", Username: "alice", Email: "alice@example.org", Name: "Alice", Groups: []string{"Admins"}},
+			password: "secret",
+		},
+		bobDn: {
+			user:     DirectoryUser{DN: bobDn, Username: "bob", Email: "bob@example.org", Name: "Bob"},
+			password: "secret",
+		},
+	}}
+
+	options := NewDefaultOptions().
+		WithUrl("ldap://localhost:389").
+		WithUserSearchBase("ou=people,dc=example,dc=org").
+		WithGroupRoles(provisioning.ParseGroupMapping("admins=" + constants.SUPER_USER_ROLE)).
+		WithGroupClaims(provisioning.ParseGroupMapping("everyone=" + constants.CREATE_VM_CLAIM))
+	svc := NewService(ctx, options)
+	svc.connect = func() (directory, error) {
+		return fake, nil
+	}
+
+	return ctx, db, svc, fake
+}
+
+func TestAuthenticate(t *testing.T) {
+	_, _, svc, directory := newTestService(t)
+
+	user, err := svc.Authenticate("alice", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.org", user.Email)
+	assert.Equal(t, []string{"Admins", "everyone"}, user.Groups)
+
+	_, err = svc.Authenticate("alice", "wrong")
+	assert.Equal(t, ErrInvalidCredentials, err)
+	_, err = svc.Authenticate("alice", "")
+	assert.Equal(t, ErrInvalidCredentials, err)
+	_, err = svc.Authenticate("nobody", "secret")
+	assert.Equal(t, ErrInvalidCredentials, err)
+	assert.Equal(t, 3, directory.closed)
+}
+
+func TestLogin_ProvisionsUser(t *testing.T) {
+	ctx, db, svc, _ := newTestService(t)
+
+	user, err := svc.Login(ctx, db, "alice", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, constants.LDAP_IDENTITY_PROVIDER, user.IdentityProvider)
+	require.Len(t, user.Roles, 1)
+	assert.Equal(t, constants.SUPER_USER_ROLE, user.Roles[0].ID)
+	require.Len(t, user.Claims, 1)
+	assert.Equal(t, constants.CREATE_VM_CLAIM, user.Claims[0].ID)
+	assert.True(t, svc.HandlesUser(user))
+}
+
+func TestLogin_LinksLocalUserOnlyWhenEnabled(t *testing.T) {
+	ctx, db, svc, directory := newTestService(t)
+	carolDn := "uid=carol,ou=people,dc=example,dc=org"
+	directory.entries[carolDn] = fakeEntry{
+		user:     DirectoryUser{DN: carolDn, Username: "carol", Email: "carol@example.org", Name: "Carol"},
+		password: "secret",
+	}
+	_, err := db.CreateUser(ctx, models.User{Username: "carol-local", Name: "Carol", Email: "carol@example.org", Password: "password"})
+	require.NoError(t, err)
+
+	// the username is unknown locally so the directory handles the login,
+	// its email belongs to the local user
+	_, err = svc.Login(ctx, db, "carol", "secret")
+	assert.ErrorContains(t, err, "is not managed by")
+
+	svc.Options.LinkLocalUsers = true
+	user, err := svc.Login(ctx, db, "carol", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "carol-local", user.Username)
+	assert.Empty(t, user.IdentityProvider)
+}
+
+func TestHandlesUser(t *testing.T) {
+	_, _, svc, _ := newTestService(t)
+
+	assert.True(t, svc.HandlesUser(nil))
+	assert.False(t, svc.HandlesUser(&models.User{ID: constants.ROOT_USER_ID, IdentityProvider: constants.LDAP_IDENTITY_PROVIDER}))
+	assert.False(t, svc.HandlesUser(&models.User{ID: "local"}))
+	assert.True(t, svc.HandlesUser(&models.User{ID: "remote", IdentityProvider: constants.LDAP_IDENTITY_PROVIDER}))
+}
+
+func TestSync(t *testing.T) {
+	ctx, db, svc, directory := newTestService(t)
+	alice, err := svc.Login(ctx, db, "alice", "secret")
+	require.NoError(t, err)
+	bob, err := svc.Login(ctx, db, "bob", "secret")
+	require.NoError(t, err)
+
+	removed := directory.entries[bobDn]
+	delete(directory.entries, bobDn)
+
+	result, err := svc.Sync(ctx, db)
+	require.NoError(t, err)
+	assert.Equal(t, &SyncResult{Checked: 2, Disabled: 1}, result)
+	stored, err := db.GetUser(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.Disabled)
+	stored, err = db.GetUser(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.Disabled)
+
+	_, err = svc.Login(ctx, db, "bob", "secret")
+	assert.Equal(t, ErrInvalidCredentials, err)
+
+	// the account is back in the directory
+	directory.entries[bobDn] = removed
+	user, err := svc.Login(ctx, db, "bob", "secret")
+	require.NoError(t, err)
+	assert.False(t, user.Disabled)
+}
+
+func TestSync_KeepsUsersWhenTheDirectoryFails(t *testing.T) {
+	ctx, db, svc, directory := newTestService(t)
+	user, err := svc.Login(ctx, db, "alice", "secret")
+	require.NoError(t, err)
+
+	directory.searchErr = errors.New("connection reset")
+	_, err = svc.Sync(ctx, db)
+	assert.ErrorContains(t, err, "connection reset")
+
+	stored, err := db.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.Disabled)
+}
+
+func TestCommonName(t *testing.T) {
+	assert.Equal(t, "Admins", commonName("cn=Admins,ou=groups,dc=example,dc=org"))
+	assert.Equal(t, "", commonName("ou=groups,dc=example,dc=org"))
+	assert.Equal(t, "", commonName("not a dn"))
+}
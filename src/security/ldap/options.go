@@ -0,0 +1,112 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+type LdapOptions struct {
+	Url                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	CaCertFile         string
+	BindDn             string
+	BindPassword       string
+	UserSearchBase     string
+	UserFilter         string
+	UsernameAttribute  string
+	EmailAttribute     string
+	NameAttribute      string
+	GroupSearchBase    string
+	GroupFilter        string
+	GroupNameAttribute string
+	GroupRoles         map[string][]string
+	GroupClaims        map[string][]string
+	AutoProvision      bool
+	LinkLocalUsers     bool
+	SyncInterval       time.Duration
+}
+
+func NewDefaultOptions() *LdapOptions {
+	syncInterval, _ := time.ParseDuration(constants.LDAP_DEFAULT_SYNC_INTERVAL)
+	return &LdapOptions{
+		UserFilter:         constants.LDAP_DEFAULT_USER_FILTER,
+		UsernameAttribute:  constants.LDAP_DEFAULT_USERNAME_ATTRIBUTE,
+		EmailAttribute:     constants.LDAP_DEFAULT_EMAIL_ATTRIBUTE,
+		NameAttribute:      constants.LDAP_DEFAULT_NAME_ATTRIBUTE,
+		GroupFilter:        constants.LDAP_DEFAULT_GROUP_FILTER,
+		GroupNameAttribute: constants.LDAP_DEFAULT_GROUP_NAME_ATTRIBUTE,
+		GroupRoles:         make(map[string][]string),
+		GroupClaims:        make(map[string][]string),
+		AutoProvision:      true,
+		SyncInterval:       syncInterval,
+	}
+}
+
+func (o *LdapOptions) WithUrl(url string) *LdapOptions {
+	o.Url = url
+	return o
+}
+
+func (o *LdapOptions) WithBind(bindDn string, bindPassword string) *LdapOptions {
+	o.BindDn = bindDn
+	o.BindPassword = bindPassword
+	return o
+}
+
+func (o *LdapOptions) WithUserSearchBase(userSearchBase string) *LdapOptions {
+	o.UserSearchBase = userSearchBase
+	return o
+}
+
+func (o *LdapOptions) WithGroupSearchBase(groupSearchBase string) *LdapOptions {
+	o.GroupSearchBase = groupSearchBase
+	return o
+}
+
+func (o *LdapOptions) WithGroupRoles(groupRoles map[string][]string) *LdapOptions {
+	o.GroupRoles = groupRoles
+	return o
+}
+
+func (o *LdapOptions) WithGroupClaims(groupClaims map[string][]string) *LdapOptions {
+	o.GroupClaims = groupClaims
+	return o
+}
+
+// IsConfigured returns true if the server and the user search base are set
+func (o *LdapOptions) IsConfigured() bool {
+	return o.Url != "" && o.UserSearchBase != ""
+}
+
+// TLSConfig returns the tls configuration used by ldaps:// urls and StartTLS
+func (o *LdapOptions) TLSConfig() (*tls.Config, error) {
+	// #nosec G402 skipping the verification is an explicit opt-in for test directories
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+	if parsed, err := url.Parse(o.Url); err == nil {
+		tlsConfig.ServerName = parsed.Hostname()
+	}
+
+	if o.CaCertFile != "" {
+		content, err := os.ReadFile(o.CaCertFile)
+		if err != nil {
+			return nil, errors.NewFromErrorf(err, "error reading the LDAP CA certificate %v", o.CaCertFile)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(content) {
+			return nil, errors.Newf("the LDAP CA certificate %v does not contain any certificate", o.CaCertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
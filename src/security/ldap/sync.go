@@ -0,0 +1,95 @@
+package ldap
+
+import (
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
+)
+
+// SyncResult is the outcome of a directory sync
+type SyncResult struct {
+	Checked  int
+	Disabled int
+	Enabled  int
+}
+
+// Sync disables the users created by the directory whose account no longer
+// exists in it and enables the ones disabled by a previous sync that are back.
+// Nothing is changed if the directory cannot be searched so an outage does not
+// lock every user out
+func (s *LdapService) Sync(ctx basecontext.ApiContext, db *data.JsonDatabase) (*SyncResult, error) {
+	users, err := db.GetUsers(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	missing := make(map[string]bool)
+	result := &SyncResult{}
+	for _, user := range users {
+		if user.IdentityProvider != constants.LDAP_IDENTITY_PROVIDER {
+			continue
+		}
+
+		result.Checked++
+		entry, err := conn.FindUser(user.Username)
+		if err == nil && entry == nil && user.Email != "" {
+			entry, err = conn.FindUser(user.Email)
+		}
+		if err != nil {
+			return nil, err
+		}
+		missing[user.ID] = entry == nil
+	}
+
+	for _, user := range users {
+		isMissing, ok := missing[user.ID]
+		if !ok {
+			continue
+		}
+
+		switch {
+		case isMissing && !user.Disabled:
+			if err := db.UpdateUserDisabledStatus(ctx, user.ID, true, constants.LDAP_SYNC_DISABLED_REASON); err != nil {
+				return nil, err
+			}
+			result.Disabled++
+			ctx.LogInfof("[LDAP] User %v disabled, the account no longer exists in the directory", user.Email)
+		case !isMissing && user.Disabled && user.DisabledReason == constants.LDAP_SYNC_DISABLED_REASON:
+			if err := db.UpdateUserDisabledStatus(ctx, user.ID, false, ""); err != nil {
+				return nil, err
+			}
+			result.Enabled++
+			ctx.LogInfof("[LDAP] User %v enabled again, the account is back in the directory", user.Email)
+		}
+	}
+
+	return result, nil
+}
+
+// StartSyncLoop syncs the users with the directory on the configured interval
+// while the service runs
+func (s *LdapService) StartSyncLoop(db *data.JsonDatabase) {
+	if s.Options.SyncInterval <= 0 {
+		return
+	}
+
+	s.ctx.LogInfof("[LDAP] Syncing the users with the directory every %v", s.Options.SyncInterval)
+	ticker := time.NewTicker(s.Options.SyncInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		result, err := s.Sync(s.ctx, db)
+		if err != nil {
+			s.ctx.LogErrorf("[LDAP] Error syncing the users with the directory: %v", err)
+			continue
+		}
+		s.ctx.LogDebugf("[LDAP] Checked %d users, %d disabled, %d enabled", result.Checked, result.Disabled, result.Enabled)
+	}
+}
@@ -0,0 +1,329 @@
+package mfa
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/security"
+	bruteforceguard "github.com/Parallels/prl-devops-service/security/brute_force_guard"
+)
+
+var globalMfaService *MfaService
+
+var (
+	ErrInvalidCode       = errors.NewWithCode("invalid multi-factor authentication code", http.StatusUnauthorized)
+	ErrInvalidChallenge  = errors.NewWithCode("multi-factor authentication token is not valid or has expired", http.StatusUnauthorized)
+	ErrRequiredByRole    = errors.NewWithCode("multi-factor authentication is required by the roles of the user", http.StatusBadRequest)
+	ErrAlreadyEnrolled   = errors.NewWithCode("multi-factor authentication is already enrolled", http.StatusBadRequest)
+	ErrEnrollmentPending = errors.NewWithCode("multi-factor authentication enrollment was not started", http.StatusBadRequest)
+)
+
+// Challenge is the second step of a login, the user proves the second factor
+// with the token of the challenge instead of the password
+type Challenge struct {
+	Token                string
+	UserID               string
+	AuthenticationMethod string
+	// EnrollmentRequired is set when the roles of the user require a second
+	// factor the user did not enroll yet
+	EnrollmentRequired bool
+	ExpiresAt          time.Time
+	attempts           int
+}
+
+type MfaService struct {
+	ctx        basecontext.ApiContext
+	Options    *MfaOptions
+	challenges map[string]*Challenge
+	mutex      sync.Mutex
+}
+
+func New(ctx basecontext.ApiContext) *MfaService {
+	globalMfaService = &MfaService{
+		ctx:        ctx,
+		Options:    NewDefaultOptions(),
+		challenges: make(map[string]*Challenge),
+	}
+
+	globalMfaService.processEnvironmentVariables()
+	return globalMfaService
+}
+
+func Get() *MfaService {
+	if globalMfaService == nil {
+		ctx := basecontext.NewRootBaseContext()
+		return New(ctx)
+	}
+
+	return globalMfaService
+}
+
+// IsRequired returns true when the user has to log in with a second factor,
+// either because the user enrolled one or because a role requires it
+func (s *MfaService) IsRequired(ctx basecontext.ApiContext, db *data.JsonDatabase, user *models.User) bool {
+	if user.Mfa != nil && user.Mfa.Enabled {
+		return true
+	}
+
+	return s.IsRequiredByRole(ctx, db, user)
+}
+
+// IsRequiredByRole reads the policy from the roles in the database, the roles
+// stored in the user are a copy that is not updated
+func (s *MfaService) IsRequiredByRole(ctx basecontext.ApiContext, db *data.JsonDatabase, user *models.User) bool {
+	for _, userRole := range user.Roles {
+		role, err := db.GetRole(ctx, userRole.ID)
+		if err != nil || role == nil {
+			continue
+		}
+		if role.MfaRequired {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewChallenge starts the second step of the login of the user
+func (s *MfaService) NewChallenge(user *models.User, authenticationMethod string) (*Challenge, error) {
+	token, err := security.GenerateCryptoRandomString(64)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &Challenge{
+		Token:                token,
+		UserID:               user.ID,
+		AuthenticationMethod: authenticationMethod,
+		EnrollmentRequired:   user.Mfa == nil || !user.Mfa.Enabled,
+		ExpiresAt:            time.Now().Add(s.Options.ChallengeDuration),
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, existing := range s.challenges {
+		if time.Now().After(existing.ExpiresAt) {
+			delete(s.challenges, key)
+		}
+	}
+	s.challenges[token] = challenge
+
+	return challenge, nil
+}
+
+// GetChallenge returns the challenge of the token if it did not expire
+func (s *MfaService) GetChallenge(token string) (*Challenge, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	challenge, ok := s.challenges[token]
+	if !ok {
+		return nil, ErrInvalidChallenge
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		delete(s.challenges, token)
+		return nil, ErrInvalidChallenge
+	}
+
+	return challenge, nil
+}
+
+// CompleteChallenge verifies the second factor of a login. Users that did not
+// enroll yet complete the enrollment with the code, the recovery codes are
+// returned in that case
+func (s *MfaService) CompleteChallenge(ctx basecontext.ApiContext, db *data.JsonDatabase, token string, code string, recoveryCode string) (*models.User, []string, error) {
+	challenge, err := s.GetChallenge(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// a challenge can only be tried a few times, the brute force guard also
+	// slows down the user
+	s.mutex.Lock()
+	challenge.attempts++
+	if challenge.attempts > s.Options.MaxAttempts {
+		delete(s.challenges, token)
+		s.mutex.Unlock()
+		return nil, nil, ErrInvalidChallenge
+	}
+	s.mutex.Unlock()
+
+	user, err := db.GetUser(ctx, challenge.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidChallenge
+	}
+
+	var recoveryCodes []string
+	if user.Mfa != nil && user.Mfa.Enabled {
+		err = s.Verify(ctx, db, user, code, recoveryCode)
+	} else {
+		recoveryCodes, err = s.CompleteEnrollment(ctx, db, user, code)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.mutex.Lock()
+	delete(s.challenges, token)
+	s.mutex.Unlock()
+
+	if diag := bruteforceguard.Get().Process(user.ID, true, "Success"); diag.HasErrors() {
+		ctx.LogErrorf("[Mfa] Error processing brute force guard: %v", diag)
+	}
+
+	return user, recoveryCodes, nil
+}
+
+// StartEnrollment generates a new secret for the user, it replaces any
+// enrollment that was not completed
+func (s *MfaService) StartEnrollment(ctx basecontext.ApiContext, db *data.JsonDatabase, user *models.User) (string, string, error) {
+	if user.Mfa != nil && user.Mfa.Enabled {
+		return "", "", ErrAlreadyEnrolled
+	}
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := db.SetUserMfaPendingSecret(ctx, user.ID, secret); err != nil {
+		return "", "", err
+	}
+
+	return secret, ProvisioningUri(s.Options.Issuer, user.Email, secret), nil
+}
+
+// CompleteEnrollment enables the second factor once the user proves the app
+// generates valid codes, the recovery codes are only returned here
+func (s *MfaService) CompleteEnrollment(ctx basecontext.ApiContext, db *data.JsonDatabase, user *models.User, code string) ([]string, error) {
+	if user.Mfa != nil && user.Mfa.Enabled {
+		return nil, ErrAlreadyEnrolled
+	}
+	if user.Mfa == nil || user.Mfa.PendingSecret == "" {
+		return nil, ErrEnrollmentPending
+	}
+
+	step, valid := ValidateCode(user.Mfa.PendingSecret, code, time.Now(), 0)
+	if !valid {
+		s.processFailure(ctx, user)
+		return nil, ErrInvalidCode
+	}
+
+	recoveryCodes, hashes, err := s.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := db.EnableUserMfa(ctx, user.ID, hashes, step); err != nil {
+		return nil, err
+	}
+
+	ctx.LogInfof("[Mfa] User %v enrolled multi-factor authentication", user.Email)
+	return recoveryCodes, nil
+}
+
+// Verify checks a code of the app or a recovery code of the user, recovery
+// codes can only be used once
+func (s *MfaService) Verify(ctx basecontext.ApiContext, db *data.JsonDatabase, user *models.User, code string, recoveryCode string) error {
+	if user.Mfa == nil || !user.Mfa.Enabled {
+		return data.ErrUserMfaNotEnrolled
+	}
+
+	if recoveryCode != "" {
+		used, err := db.UseUserMfaRecoveryCode(ctx, user.ID, hashRecoveryCode(recoveryCode))
+		if err != nil {
+			return err
+		}
+		if !used {
+			s.processFailure(ctx, user)
+			return ErrInvalidCode
+		}
+
+		ctx.LogInfof("[Mfa] User %v used a recovery code", user.Email)
+		return nil
+	}
+
+	step, valid := ValidateCode(user.Mfa.Secret, code, time.Now(), user.Mfa.LastUsedStep)
+	if !valid {
+		s.processFailure(ctx, user)
+		return ErrInvalidCode
+	}
+
+	return db.UpdateUserMfaLastUsedStep(ctx, user.ID, step)
+}
+
+// Disable removes the second factor of the user after checking a code, users
+// whose roles require a second factor cannot remove it
+func (s *MfaService) Disable(ctx basecontext.ApiContext, db *data.JsonDatabase, user *models.User, code string, recoveryCode string) error {
+	if s.IsRequiredByRole(ctx, db, user) {
+		return ErrRequiredByRole
+	}
+	if err := s.Verify(ctx, db, user, code, recoveryCode); err != nil {
+		return err
+	}
+
+	return db.DisableUserMfa(ctx, user.ID)
+}
+
+func (s *MfaService) processFailure(ctx basecontext.ApiContext, user *models.User) {
+	if diag := bruteforceguard.Get().Process(user.ID, false, "Invalid MFA code"); diag.HasErrors() {
+		ctx.LogErrorf("[Mfa] Error processing brute force guard: %v", diag)
+	}
+}
+
+// generateRecoveryCodes returns the recovery codes and the hashes we store
+func (s *MfaService) generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, s.Options.RecoveryCodeCount)
+	hashes := make([]string, 0, s.Options.RecoveryCodeCount)
+	for i := 0; i < s.Options.RecoveryCodeCount; i++ {
+		value, err := security.GenerateCryptoRandomString(10)
+		if err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(value[:5] + "-" + value[5:])
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	hash := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(hash[:])
+}
+
+func (s *MfaService) processEnvironmentVariables() {
+	cfg := config.Get()
+	if cfg.GetKey(constants.MFA_ISSUER_ENV_VAR) != "" {
+		s.Options.WithIssuer(cfg.GetKey(constants.MFA_ISSUER_ENV_VAR))
+	}
+
+	if cfg.GetKey(constants.MFA_CHALLENGE_DURATION_ENV_VAR) != "" {
+		duration, err := time.ParseDuration(cfg.GetKey(constants.MFA_CHALLENGE_DURATION_ENV_VAR))
+		if err != nil || duration <= 0 {
+			s.ctx.LogWarnf("[Mfa] Invalid value for %s: %s", constants.MFA_CHALLENGE_DURATION_ENV_VAR, cfg.GetKey(constants.MFA_CHALLENGE_DURATION_ENV_VAR))
+		} else {
+			s.Options.WithChallengeDuration(duration)
+		}
+	}
+
+	if cfg.GetKey(constants.MFA_MAX_ATTEMPTS_ENV_VAR) != "" {
+		attempts, err := strconv.Atoi(cfg.GetKey(constants.MFA_MAX_ATTEMPTS_ENV_VAR))
+		if err != nil {
+			s.ctx.LogWarnf("[Mfa] Invalid value for %s: %s", constants.MFA_MAX_ATTEMPTS_ENV_VAR, err.Error())
+		} else {
+			s.Options.WithMaxAttempts(attempts)
+		}
+	}
+}
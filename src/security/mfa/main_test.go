@@ -0,0 +1,144 @@
+package mfa
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupMfaTest creates a user per test, the database is shared between the
+// tests of the package
+func setupMfaTest(t *testing.T, name string) (basecontext.ApiContext, *data.JsonDatabase, *models.User) {
+	ctx := basecontext.NewBaseContext()
+	ctx.DisableLog()
+	sp := serviceprovider.NewMockProvider()
+	db := data.NewJsonDatabase(ctx, filepath.Join(t.TempDir(), "data.json"))
+	sp.JsonDatabase = db
+	require.NoError(t, db.Connect(ctx))
+
+	for _, role := range constants.DefaultRoles {
+		_, _ = db.CreateRole(ctx, models.Role{ID: role, Name: role})
+	}
+	for _, claim := range constants.DefaultClaims {
+		_, _ = db.CreateClaim(ctx, models.Claim{ID: claim, Name: claim})
+	}
+	user, err := db.CreateUser(ctx, models.User{
+		ID:       name + "-id",
+		Email:    name + "@example.com",
+		Username: name,
+		Name:     name,
+		Password: "password",
+	})
+	require.NoError(t, err)
+
+	return ctx, db, user
+}
+
+func TestMfaService_Enrollment(t *testing.T) {
+	ctx, db, user := setupMfaTest(t, "mfa-enrollment")
+	svc := New(ctx)
+
+	assert.False(t, svc.IsRequired(ctx, db, user))
+
+	secret, uri, err := svc.StartEnrollment(ctx, db, user)
+	require.NoError(t, err)
+	assert.Contains(t, uri, secret)
+
+	user, err = db.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	_, err = svc.CompleteEnrollment(ctx, db, user, "000000")
+	assert.ErrorIs(t, err, ErrInvalidCode)
+
+	code, err := GenerateCode(secret, time.Now())
+	require.NoError(t, err)
+	recoveryCodes, err := svc.CompleteEnrollment(ctx, db, user, code)
+	require.NoError(t, err)
+	assert.Len(t, recoveryCodes, svc.Options.RecoveryCodeCount)
+
+	user, err = db.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, svc.IsRequired(ctx, db, user))
+	assert.NotContains(t, user.Mfa.RecoveryCodes, recoveryCodes[0])
+
+	// the code used for the enrollment cannot be used again
+	assert.ErrorIs(t, svc.Verify(ctx, db, user, code, ""), ErrInvalidCode)
+
+	// recovery codes can only be used once and ignore dashes and case
+	assert.NoError(t, svc.Verify(ctx, db, user, "", " "+recoveryCodes[0]+" "))
+	assert.ErrorIs(t, svc.Verify(ctx, db, user, "", recoveryCodes[0]), ErrInvalidCode)
+
+	require.NoError(t, svc.Disable(ctx, db, user, "", recoveryCodes[1]))
+	user, err = db.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.False(t, svc.IsRequired(ctx, db, user))
+}
+
+func TestMfaService_RolePolicy(t *testing.T) {
+	ctx, db, user := setupMfaTest(t, "mfa-policy")
+	svc := New(ctx)
+
+	_, err := db.UpdateRoleMfaRequired(ctx, constants.USER_ROLE, true)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = db.UpdateRoleMfaRequired(ctx, constants.USER_ROLE, false)
+	})
+	assert.True(t, svc.IsRequired(ctx, db, user))
+
+	challenge, err := svc.NewChallenge(user, constants.SESSION_AUTHENTICATION_PASSWORD)
+	require.NoError(t, err)
+	assert.True(t, challenge.EnrollmentRequired)
+
+	secret, _, err := svc.StartEnrollment(ctx, db, user)
+	require.NoError(t, err)
+	code, err := GenerateCode(secret, time.Now())
+	require.NoError(t, err)
+
+	loggedUser, recoveryCodes, err := svc.CompleteChallenge(ctx, db, challenge.Token, code, "")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, loggedUser.ID)
+	assert.NotEmpty(t, recoveryCodes)
+
+	// the challenge can only be completed once
+	_, _, err = svc.CompleteChallenge(ctx, db, challenge.Token, code, "")
+	assert.ErrorIs(t, err, ErrInvalidChallenge)
+
+	// the role does not allow removing the second factor
+	user, err = db.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.ErrorIs(t, svc.Disable(ctx, db, user, "", recoveryCodes[0]), ErrRequiredByRole)
+}
+
+func TestMfaService_ChallengeAttempts(t *testing.T) {
+	ctx, db, user := setupMfaTest(t, "mfa-attempts")
+	svc := New(ctx)
+	svc.Options.WithMaxAttempts(2)
+
+	secret, _, err := svc.StartEnrollment(ctx, db, user)
+	require.NoError(t, err)
+	user, err = db.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+
+	challenge, err := svc.NewChallenge(user, constants.SESSION_AUTHENTICATION_PASSWORD)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, _, err = svc.CompleteChallenge(ctx, db, challenge.Token, "000000", "")
+		assert.ErrorIs(t, err, ErrInvalidCode)
+	}
+
+	code, err := GenerateCode(secret, time.Now())
+	require.NoError(t, err)
+	_, _, err = svc.CompleteChallenge(ctx, db, challenge.Token, code, "")
+	assert.ErrorIs(t, err, ErrInvalidChallenge)
+
+	_, err = svc.GetChallenge("unknown")
+	assert.ErrorIs(t, err, ErrInvalidChallenge)
+}
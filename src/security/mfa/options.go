@@ -0,0 +1,44 @@
+package mfa
+
+import (
+	"time"
+
+	"github.com/Parallels/prl-devops-service/constants"
+)
+
+type MfaOptions struct {
+	// Issuer is the name authenticator apps show next to the codes
+	Issuer string
+	// ChallengeDuration is how long the second step of a login can take
+	ChallengeDuration time.Duration
+	// MaxAttempts is the number of codes that can be tried in a login
+	MaxAttempts       int
+	RecoveryCodeCount int
+}
+
+func NewDefaultOptions() *MfaOptions {
+	return &MfaOptions{
+		Issuer:            constants.MFA_DEFAULT_ISSUER,
+		ChallengeDuration: 5 * time.Minute,
+		MaxAttempts:       5,
+		RecoveryCodeCount: 10,
+	}
+}
+
+func (o *MfaOptions) WithIssuer(issuer string) *MfaOptions {
+	o.Issuer = issuer
+	return o
+}
+
+func (o *MfaOptions) WithChallengeDuration(duration time.Duration) *MfaOptions {
+	o.ChallengeDuration = duration
+	return o
+}
+
+func (o *MfaOptions) WithMaxAttempts(attempts int) *MfaOptions {
+	if attempts < 1 {
+		attempts = 1
+	}
+	o.MaxAttempts = attempts
+	return o
+}
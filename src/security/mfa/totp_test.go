@@ -0,0 +1,77 @@
+package mfa
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the base32 encoding of the SHA1 seed of the RFC 6238 test
+// vectors
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateCode(t *testing.T) {
+	tests := []struct {
+		at       int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := GenerateCode(rfcSecret, time.Unix(tt.at, 0))
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, code)
+	}
+
+	_, err := GenerateCode("not base32!", time.Now())
+	assert.Error(t, err)
+}
+
+func TestValidateCode(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	code, err := GenerateCode(secret, now)
+	require.NoError(t, err)
+
+	step, valid := ValidateCode(secret, code, now, 0)
+	assert.True(t, valid)
+	assert.Equal(t, now.Unix()/totpPeriod, step)
+
+	// codes of the previous period are accepted for clock drift
+	step, valid = ValidateCode(secret, code, now.Add(totpPeriod*time.Second), 0)
+	assert.True(t, valid)
+	assert.Equal(t, now.Unix()/totpPeriod, step)
+
+	_, valid = ValidateCode(secret, code, now.Add(3*totpPeriod*time.Second), 0)
+	assert.False(t, valid)
+
+	// a code cannot be used twice
+	_, valid = ValidateCode(secret, code, now, step)
+	assert.False(t, valid)
+
+	_, valid = ValidateCode(secret, "12345", now, 0)
+	assert.False(t, valid)
+}
+
+func TestProvisioningUri(t *testing.T) {
+	uri := ProvisioningUri("Parallels DevOps", "user@example.com", rfcSecret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/"))
+
+	parsed, err := url.Parse(uri)
+	require.NoError(t, err)
+	assert.Equal(t, "/Parallels DevOps:user@example.com", parsed.Path)
+	assert.Equal(t, rfcSecret, parsed.Query().Get("secret"))
+	assert.Equal(t, "Parallels DevOps", parsed.Query().Get("issuer"))
+	assert.Equal(t, "6", parsed.Query().Get("digits"))
+}
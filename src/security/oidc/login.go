@@ -0,0 +1,162 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+// loginRequest is an authorization code flow waiting for the callback of the
+// identity provider
+type loginRequest struct {
+	codeVerifier string
+	nonce        string
+	expiresAt    time.Time
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IdToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+}
+
+// NewLoginUrl starts an authorization code flow with PKCE and returns the url
+// of the identity provider the user needs to be redirected to
+func (s *OidcService) NewLoginUrl() (string, error) {
+	if s.Options.RedirectUrl == "" {
+		return "", errors.NewWithCode("the OIDC redirect url is not configured", http.StatusBadRequest)
+	}
+
+	discovery, err := s.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+	if discovery.AuthorizationEndpoint == "" {
+		return "", errors.New("the OIDC discovery document does not contain the authorization_endpoint")
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	codeVerifier, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	s.lock.Lock()
+	now := time.Now()
+	for key, login := range s.logins {
+		if now.After(login.expiresAt) {
+			delete(s.logins, key)
+		}
+	}
+	s.logins[state] = loginRequest{
+		codeVerifier: codeVerifier,
+		nonce:        nonce,
+		expiresAt:    now.Add(constants.OIDC_LOGIN_REQUEST_EXPIRES_IN * time.Second),
+	}
+	s.lock.Unlock()
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", s.Options.ClientId)
+	query.Set("redirect_uri", s.Options.RedirectUrl)
+	query.Set("scope", strings.Join(s.Options.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge(codeVerifier))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// CompleteLogin exchanges the authorization code of the callback and returns
+// the identity of the id token, each state can only be used once
+func (s *OidcService) CompleteLogin(code string, state string) (*Identity, error) {
+	if code == "" || state == "" {
+		return nil, errors.NewWithCode("the code and state are required", http.StatusBadRequest)
+	}
+
+	s.lock.Lock()
+	login, ok := s.logins[state]
+	delete(s.logins, state)
+	s.lock.Unlock()
+	if !ok || time.Now().After(login.expiresAt) {
+		return nil, errors.NewWithCode("the login request is invalid or has expired", http.StatusUnauthorized)
+	}
+
+	discovery, err := s.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", s.Options.RedirectUrl)
+	form.Set("client_id", s.Options.ClientId)
+	form.Set("code_verifier", login.codeVerifier)
+	if s.Options.ClientSecret != "" {
+		form.Set("client_secret", s.Options.ClientSecret)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var response tokenResponse
+	if err := s.getJson(request, &response); err != nil {
+		return nil, errors.NewWithCodef(http.StatusUnauthorized, "error exchanging the authorization code: %v", err)
+	}
+	if response.IdToken == "" {
+		return nil, errors.NewWithCode("the identity provider did not return an id token", http.StatusUnauthorized)
+	}
+
+	claims, err := s.parse(response.IdToken, []string{s.Options.ClientId})
+	if err != nil {
+		return nil, err
+	}
+	if nonce, _ := claims["nonce"].(string); nonce != login.nonce {
+		return nil, errors.NewWithCode("invalid id token nonce", http.StatusUnauthorized)
+	}
+	if _, ok := claims["email"]; !ok && response.AccessToken != "" {
+		if err := s.addUserInfo(response.AccessToken, claims); err != nil {
+			s.ctx.LogWarnf("[OIDC] Error getting the user info: %v", err)
+		}
+	}
+
+	return s.newIdentity(claims)
+}
+
+func randomString() (string, error) {
+	value := make([]byte, 32)
+	if _, err := rand.Read(value); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(value), nil
+}
+
+func codeChallenge(codeVerifier string) string {
+	hash := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
@@ -0,0 +1,434 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/security/provisioning"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	httpTimeout = 10 * time.Second
+	// keysRefreshInterval limits how often an unknown key id fetches the
+	// signing keys again
+	keysRefreshInterval = time.Minute
+)
+
+var validSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+var globalOidcService *OidcService
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+// Identity is the user asserted by a token of the identity provider
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	Name          string
+	Groups        []string
+	ExpiresAt     time.Time
+}
+
+type OidcService struct {
+	ctx           basecontext.ApiContext
+	Options       *OidcOptions
+	client        *http.Client
+	discovery     *discoveryDocument
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+	identities    map[string]Identity
+	logins        map[string]loginRequest
+	lock          sync.Mutex
+}
+
+// New creates the OIDC service from the configuration, it returns nil when
+// the issuer or the client id are not set
+func New(ctx basecontext.ApiContext) *OidcService {
+	options := NewDefaultOptions()
+	processEnvironmentVariables(options)
+	if !options.IsConfigured() {
+		globalOidcService = nil
+		return nil
+	}
+
+	globalOidcService = NewService(ctx, options)
+	ctx.LogInfof("[OIDC] Single sign-on enabled with issuer %v", options.IssuerUrl)
+	return globalOidcService
+}
+
+func NewService(ctx basecontext.ApiContext, options *OidcOptions) *OidcService {
+	if ctx == nil {
+		ctx = basecontext.NewRootBaseContext()
+	}
+
+	return &OidcService{
+		ctx:        ctx,
+		Options:    options,
+		client:     &http.Client{Timeout: httpTimeout},
+		keys:       make(map[string]interface{}),
+		identities: make(map[string]Identity),
+		logins:     make(map[string]loginRequest),
+	}
+}
+
+// Get returns the OIDC service or nil if single sign-on is not configured
+func Get() *OidcService {
+	return globalOidcService
+}
+
+// IsIssuedBy returns true if the unverified issuer of the token is the
+// configured identity provider
+func (s *OidcService) IsIssuedBy(token string) bool {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return false
+	}
+
+	issuer, _ := claims["iss"].(string)
+	return issuer != "" && strings.TrimRight(issuer, "/") == s.Options.IssuerUrl
+}
+
+// ValidateToken validates a bearer token issued by the identity provider and
+// returns its identity, the identities are cached until the token expires
+func (s *OidcService) ValidateToken(token string) (*Identity, error) {
+	cacheKey := hashToken(token)
+	s.lock.Lock()
+	if identity, ok := s.identities[cacheKey]; ok && time.Now().Before(identity.ExpiresAt) {
+		s.lock.Unlock()
+		return &identity, nil
+	}
+	s.lock.Unlock()
+
+	claims, err := s.parse(token, s.Options.GetAudiences())
+	if err != nil {
+		return nil, err
+	}
+
+	// access tokens do not always carry the profile of the user
+	if _, ok := claims["email"]; !ok {
+		if err := s.addUserInfo(token, claims); err != nil {
+			s.ctx.LogWarnf("[OIDC] Error getting the user info: %v", err)
+		}
+	}
+
+	identity, err := s.newIdentity(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	now := time.Now()
+	for key, cached := range s.identities {
+		if now.After(cached.ExpiresAt) {
+			delete(s.identities, key)
+		}
+	}
+	s.identities[cacheKey] = *identity
+	s.lock.Unlock()
+
+	return identity, nil
+}
+
+// parse verifies the signature, issuer, audience and lifetime of a token
+func (s *OidcService) parse(token string, audiences []string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods(validSigningMethods))
+	tokenObj, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return s.getKey(kid)
+	})
+	if err != nil {
+		return nil, errors.NewWithCodef(401, "invalid token: %v", err)
+	}
+	if !tokenObj.Valid {
+		return nil, errors.NewWithCode("invalid token", 401)
+	}
+
+	issuer, _ := claims["iss"].(string)
+	if strings.TrimRight(issuer, "/") != s.Options.IssuerUrl {
+		return nil, errors.NewWithCodef(401, "invalid token issuer %v", issuer)
+	}
+
+	validAudience := false
+	for _, audience := range audiences {
+		if claims.VerifyAudience(audience, true) {
+			validAudience = true
+			break
+		}
+	}
+	if !validAudience {
+		return nil, errors.NewWithCode("invalid token audience", 401)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.NewWithCode("token does not expire", 401)
+	}
+
+	return claims, nil
+}
+
+func (s *OidcService) newIdentity(claims jwt.MapClaims) (*Identity, error) {
+	identity := &Identity{
+		Subject: getStringClaim(claims, "sub"),
+		Email:   getStringClaim(claims, "email"),
+		Name:    getStringClaim(claims, "name"),
+		Groups:  getListClaim(claims, s.Options.GroupsClaim),
+	}
+
+	// the email is only trusted when the provider says it verified it, a
+	// missing claim is not verified
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = strings.EqualFold(verified, "true")
+	}
+
+	for _, name := range []string{s.Options.UsernameClaim, "preferred_username", "upn"} {
+		if identity.Username = getStringClaim(claims, name); identity.Username != "" {
+			break
+		}
+	}
+	if identity.Email == "" {
+		return nil, errors.NewWithCode("the token does not contain the email of the user", 401)
+	}
+	if identity.Username == "" {
+		identity.Username = identity.Email
+	}
+	if identity.Name == "" {
+		identity.Name = identity.Username
+	}
+
+	if exp, ok := claims["exp"].(float64); ok {
+		identity.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+
+	return identity, nil
+}
+
+func (s *OidcService) addUserInfo(token string, claims jwt.MapClaims) error {
+	discovery, err := s.getDiscovery()
+	if err != nil {
+		return err
+	}
+	if discovery.UserInfoEndpoint == "" {
+		return nil
+	}
+
+	request, err := http.NewRequest(http.MethodGet, discovery.UserInfoEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	var userInfo map[string]interface{}
+	if err := s.getJson(request, &userInfo); err != nil {
+		return err
+	}
+
+	// the user info cannot be used to impersonate another subject
+	if sub, _ := userInfo["sub"].(string); sub != "" && sub != getStringClaim(claims, "sub") {
+		return errors.New("the user info subject does not match the token")
+	}
+	for key, value := range userInfo {
+		if _, ok := claims[key]; !ok {
+			claims[key] = value
+		}
+	}
+
+	return nil
+}
+
+func (s *OidcService) getDiscovery() (*discoveryDocument, error) {
+	s.lock.Lock()
+	discovery := s.discovery
+	s.lock.Unlock()
+	if discovery != nil {
+		return discovery, nil
+	}
+
+	request, err := http.NewRequest(http.MethodGet, s.Options.IssuerUrl+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var document discoveryDocument
+	if err := s.getJson(request, &document); err != nil {
+		return nil, errors.NewFromErrorf(err, "error getting the OIDC discovery document")
+	}
+	if strings.TrimRight(document.Issuer, "/") != s.Options.IssuerUrl {
+		return nil, errors.Newf("the OIDC discovery issuer %v does not match %v", document.Issuer, s.Options.IssuerUrl)
+	}
+	if document.JwksUri == "" {
+		return nil, errors.New("the OIDC discovery document does not contain the jwks_uri")
+	}
+
+	s.lock.Lock()
+	s.discovery = &document
+	s.lock.Unlock()
+	return &document, nil
+}
+
+// getKey returns the public key of the provider with the key id, the keys are
+// fetched again when the provider rotates them
+func (s *OidcService) getKey(kid string) (interface{}, error) {
+	s.lock.Lock()
+	key := s.findKey(kid)
+	canRefresh := time.Since(s.keysFetchedAt) > keysRefreshInterval
+	s.lock.Unlock()
+	if key != nil {
+		return key, nil
+	}
+	if !canRefresh {
+		return nil, errors.Newf("unknown signing key %v", kid)
+	}
+
+	keys, err := s.fetchKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.keys = keys
+	s.keysFetchedAt = time.Now()
+	if key = s.findKey(kid); key == nil {
+		return nil, errors.Newf("unknown signing key %v", kid)
+	}
+
+	return key, nil
+}
+
+func (s *OidcService) findKey(kid string) interface{} {
+	if key, ok := s.keys[kid]; ok {
+		return key
+	}
+	// tokens without a key id can only use a provider with a single key
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key
+		}
+	}
+
+	return nil
+}
+
+func (s *OidcService) fetchKeys() (map[string]interface{}, error) {
+	discovery, err := s.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest(http.MethodGet, discovery.JwksUri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var keySet jose.JSONWebKeySet
+	if err := s.getJson(request, &keySet); err != nil {
+		return nil, errors.NewFromErrorf(err, "error getting the OIDC signing keys")
+	}
+
+	keys := make(map[string]interface{})
+	for _, key := range keySet.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		keys[key.KeyID] = key.Public().Key
+	}
+
+	return keys, nil
+}
+
+func (s *OidcService) getJson(request *http.Request, target interface{}) error {
+	request.Header.Set("Accept", "application/json")
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1024*1024))
+	if err != nil {
+		return err
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return errors.Newf("%v returned %v: %v", request.URL.String(), response.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return json.Unmarshal(body, target)
+}
+
+func processEnvironmentVariables(options *OidcOptions) {
+	cfg := config.Get()
+	options.WithIssuerUrl(cfg.GetKey(constants.OIDC_ISSUER_URL_ENV_VAR))
+	options.WithClient(cfg.GetKey(constants.OIDC_CLIENT_ID_ENV_VAR), cfg.GetKey(constants.OIDC_CLIENT_SECRET_ENV_VAR))
+	options.WithRedirectUrl(cfg.GetKey(constants.OIDC_REDIRECT_URL_ENV_VAR))
+	options.PostLoginRedirectUrl = cfg.GetKey(constants.OIDC_POST_LOGIN_REDIRECT_URL_ENV_VAR)
+
+	if scopes := splitList(cfg.GetKey(constants.OIDC_SCOPES_ENV_VAR)); len(scopes) > 0 {
+		options.Scopes = scopes
+	}
+	if audiences := splitList(cfg.GetKey(constants.OIDC_AUDIENCE_ENV_VAR)); len(audiences) > 0 {
+		options.Audiences = audiences
+	}
+	if claim := cfg.GetKey(constants.OIDC_USERNAME_CLAIM_ENV_VAR); claim != "" {
+		options.UsernameClaim = claim
+	}
+	if claim := cfg.GetKey(constants.OIDC_GROUPS_CLAIM_ENV_VAR); claim != "" {
+		options.GroupsClaim = claim
+	}
+	options.WithGroupRoles(provisioning.ParseGroupMapping(cfg.GetKey(constants.OIDC_GROUP_ROLES_ENV_VAR)))
+	options.WithGroupClaims(provisioning.ParseGroupMapping(cfg.GetKey(constants.OIDC_GROUP_CLAIMS_ENV_VAR)))
+
+	autoProvision := strings.ToLower(cfg.GetKey(constants.OIDC_AUTO_PROVISION_ENV_VAR))
+	options.AutoProvision = autoProvision != "false" && autoProvision != "0"
+	options.LinkLocalUsers = cfg.GetBoolKey(constants.OIDC_LINK_LOCAL_USERS_ENV_VAR)
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func getStringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return strings.TrimSpace(value)
+}
+
+func getListClaim(claims jwt.MapClaims, name string) []string {
+	result := make([]string, 0)
+	switch value := claims[name].(type) {
+	case string:
+		if value != "" {
+			result = append(result, value)
+		}
+	case []interface{}:
+		for _, item := range value {
+			if text, ok := item.(string); ok && text != "" {
+				result = append(result, text)
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,283 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testProvider struct {
+	server        *httptest.Server
+	key           *rsa.PrivateKey
+	kid           string
+	userInfo      map[string]interface{}
+	idTokenClaims jwt.MapClaims
+	codeVerifier  string
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	provider := &testProvider{key: key, kid: "key-1"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 provider.server.URL,
+			"authorization_endpoint": provider.server.URL + "/authorize",
+			"token_endpoint":         provider.server.URL + "/token",
+			"userinfo_endpoint":      provider.server.URL + "/userinfo",
+			"jwks_uri":               provider.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &provider.key.PublicKey, KeyID: provider.kid, Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if provider.userInfo == nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(provider.userInfo)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		provider.codeVerifier = r.PostForm.Get("code_verifier")
+		if r.PostForm.Get("code") != "good-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "access",
+			"id_token":     provider.sign(t, provider.idTokenClaims),
+			"token_type":   "Bearer",
+		})
+	})
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+
+	return provider
+}
+
+func (p *testProvider) claims(overrides jwt.MapClaims) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"iss":            p.server.URL,
+		"aud":            "client",
+		"sub":            "user-1",
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"name":           "Alice",
+		"groups":         []string{"Developers"},
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	}
+	for key, value := range overrides {
+		if value == nil {
+			delete(claims, key)
+			continue
+		}
+		claims[key] = value
+	}
+
+	return claims
+}
+
+func (p *testProvider) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.kid
+	result, err := token.SignedString(p.key)
+	require.NoError(t, err)
+
+	return result
+}
+
+func newTestService(provider *testProvider) *OidcService {
+	ctx := basecontext.NewBaseContext()
+	ctx.DisableLog()
+	options := NewDefaultOptions().
+		WithIssuerUrl(provider.server.URL).
+		WithClient("client", "").
+		WithRedirectUrl("https://devops.local/api/v1/auth/oidc/callback")
+
+	return NewService(ctx, options)
+}
+
+func TestIsIssuedBy(t *testing.T) {
+	provider := newTestProvider(t)
+	svc := newTestService(provider)
+
+	assert.True(t, svc.IsIssuedBy(provider.sign(t, provider.claims(nil))))
+	assert.False(t, svc.IsIssuedBy(provider.sign(t, provider.claims(jwt.MapClaims{"iss": "https://other"}))))
+	assert.False(t, svc.IsIssuedBy("not a token"))
+}
+
+func TestValidateToken(t *testing.T) {
+	provider := newTestProvider(t)
+	svc := newTestService(provider)
+
+	identity, err := svc.ValidateToken(provider.sign(t, provider.claims(jwt.MapClaims{"preferred_username": "alice"})))
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", identity.Email)
+	assert.Equal(t, "alice", identity.Username)
+	assert.Equal(t, "Alice", identity.Name)
+	assert.Equal(t, []string{"Developers"}, identity.Groups)
+	assert.True(t, identity.EmailVerified)
+}
+
+func TestValidateToken_EmailVerified(t *testing.T) {
+	provider := newTestProvider(t)
+	svc := newTestService(provider)
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		verified bool
+	}{
+		{"missing", nil, false},
+		{"false", false, false},
+		{"true string", "true", true},
+		{"other string", "yes", false},
+		{"true", true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			identity, err := svc.ValidateToken(provider.sign(t, provider.claims(jwt.MapClaims{"email_verified": test.value})))
+			require.NoError(t, err)
+			assert.Equal(t, test.verified, identity.EmailVerified)
+		})
+	}
+}
+
+func TestValidateToken_EmailNotFromUsername(t *testing.T) {
+	provider := newTestProvider(t)
+	svc := newTestService(provider)
+
+	_, err := svc.ValidateToken(provider.sign(t, provider.claims(jwt.MapClaims{"email": nil, "upn": "admin@example.com"})))
+	assert.ErrorContains(t, err, "does not contain the email")
+}
+
+func TestValidateToken_Invalid(t *testing.T) {
+	provider := newTestProvider(t)
+	svc := newTestService(provider)
+
+	_, err := svc.ValidateToken(provider.sign(t, provider.claims(jwt.MapClaims{"aud": "other"})))
+	assert.ErrorContains(t, err, "invalid token audience")
+
+	_, err = svc.ValidateToken(provider.sign(t, provider.claims(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})))
+	assert.ErrorContains(t, err, "expired")
+
+	_, err = svc.ValidateToken(provider.sign(t, provider.claims(jwt.MapClaims{"exp": nil})))
+	assert.ErrorContains(t, err, "token does not expire")
+
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, provider.claims(nil)).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = svc.ValidateToken(hmacToken)
+	assert.ErrorContains(t, err, "signing method HS256 is invalid")
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, provider.claims(nil))
+	forged.Header["kid"] = provider.kid
+	forgedToken, err := forged.SignedString(otherKey)
+	require.NoError(t, err)
+	_, err = svc.ValidateToken(forgedToken)
+	assert.ErrorContains(t, err, "invalid token")
+}
+
+func TestValidateToken_UserInfo(t *testing.T) {
+	provider := newTestProvider(t)
+	provider.userInfo = map[string]interface{}{"sub": "user-1", "email": "bob@example.com", "name": "Bob"}
+	svc := newTestService(provider)
+
+	identity, err := svc.ValidateToken(provider.sign(t, provider.claims(jwt.MapClaims{"email": nil, "name": nil})))
+	require.NoError(t, err)
+	assert.Equal(t, "bob@example.com", identity.Email)
+	assert.Equal(t, "Bob", identity.Name)
+
+	provider.userInfo = map[string]interface{}{"sub": "someone-else", "email": "eve@example.com"}
+	_, err = svc.ValidateToken(provider.sign(t, provider.claims(jwt.MapClaims{"email": nil, "sub": "user-2"})))
+	assert.ErrorContains(t, err, "does not contain the email")
+}
+
+func TestValidateToken_KeyRotation(t *testing.T) {
+	provider := newTestProvider(t)
+	svc := newTestService(provider)
+
+	_, err := svc.ValidateToken(provider.sign(t, provider.claims(nil)))
+	require.NoError(t, err)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	provider.key = key
+	provider.kid = "key-2"
+	svc.keysFetchedAt = time.Now().Add(-2 * keysRefreshInterval)
+
+	_, err = svc.ValidateToken(provider.sign(t, provider.claims(jwt.MapClaims{"sub": "user-2"})))
+	require.NoError(t, err)
+}
+
+func TestLoginFlow(t *testing.T) {
+	provider := newTestProvider(t)
+	svc := newTestService(provider)
+
+	loginUrl, err := svc.NewLoginUrl()
+	require.NoError(t, err)
+	parsed, err := url.Parse(loginUrl)
+	require.NoError(t, err)
+	query := parsed.Query()
+	assert.Equal(t, provider.server.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, "client", query.Get("client_id"))
+	assert.Equal(t, "openid profile email", query.Get("scope"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+
+	provider.idTokenClaims = provider.claims(jwt.MapClaims{"nonce": query.Get("nonce")})
+	identity, err := svc.CompleteLogin("good-code", query.Get("state"))
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", identity.Email)
+
+	hash := sha256.Sum256([]byte(provider.codeVerifier))
+	assert.Equal(t, query.Get("code_challenge"), base64.RawURLEncoding.EncodeToString(hash[:]))
+
+	// the state can only be used once
+	_, err = svc.CompleteLogin("good-code", query.Get("state"))
+	assert.ErrorContains(t, err, "invalid or has expired")
+}
+
+func TestLoginFlow_InvalidNonce(t *testing.T) {
+	provider := newTestProvider(t)
+	svc := newTestService(provider)
+
+	loginUrl, err := svc.NewLoginUrl()
+	require.NoError(t, err)
+	parsed, err := url.Parse(loginUrl)
+	require.NoError(t, err)
+
+	provider.idTokenClaims = provider.claims(jwt.MapClaims{"nonce": "other"})
+	_, err = svc.CompleteLogin("good-code", parsed.Query().Get("state"))
+	assert.ErrorContains(t, err, "invalid id token nonce")
+}
+
+func TestNewLoginUrl_RequiresRedirectUrl(t *testing.T) {
+	provider := newTestProvider(t)
+	svc := newTestService(provider)
+	svc.Options.RedirectUrl = ""
+
+	_, err := svc.NewLoginUrl()
+	assert.ErrorContains(t, err, "redirect url is not configured")
+}
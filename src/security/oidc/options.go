@@ -0,0 +1,81 @@
+package oidc
+
+import (
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/constants"
+)
+
+type OidcOptions struct {
+	IssuerUrl            string
+	ClientId             string
+	ClientSecret         string
+	RedirectUrl          string
+	PostLoginRedirectUrl string
+	Scopes               []string
+	Audiences            []string
+	UsernameClaim        string
+	GroupsClaim          string
+	GroupRoles           map[string][]string
+	GroupClaims          map[string][]string
+	AutoProvision        bool
+	LinkLocalUsers       bool
+}
+
+func NewDefaultOptions() *OidcOptions {
+	return &OidcOptions{
+		Scopes:        strings.Fields(constants.OIDC_DEFAULT_SCOPES),
+		UsernameClaim: constants.OIDC_DEFAULT_USERNAME_CLAIM,
+		GroupsClaim:   constants.OIDC_DEFAULT_GROUPS_CLAIM,
+		GroupRoles:    make(map[string][]string),
+		GroupClaims:   make(map[string][]string),
+		AutoProvision: true,
+	}
+}
+
+func (o *OidcOptions) WithIssuerUrl(issuerUrl string) *OidcOptions {
+	o.IssuerUrl = strings.TrimRight(issuerUrl, "/")
+	return o
+}
+
+func (o *OidcOptions) WithClient(clientId string, clientSecret string) *OidcOptions {
+	o.ClientId = clientId
+	o.ClientSecret = clientSecret
+	return o
+}
+
+func (o *OidcOptions) WithRedirectUrl(redirectUrl string) *OidcOptions {
+	o.RedirectUrl = redirectUrl
+	return o
+}
+
+func (o *OidcOptions) WithGroupRoles(groupRoles map[string][]string) *OidcOptions {
+	o.GroupRoles = groupRoles
+	return o
+}
+
+func (o *OidcOptions) WithGroupClaims(groupClaims map[string][]string) *OidcOptions {
+	o.GroupClaims = groupClaims
+	return o
+}
+
+// IsConfigured returns true if the issuer and the client are set
+func (o *OidcOptions) IsConfigured() bool {
+	return o.IssuerUrl != "" && o.ClientId != ""
+}
+
+// GetAudiences returns the accepted audiences of the tokens, the client id if
+// none was set
+func (o *OidcOptions) GetAudiences() []string {
+	if len(o.Audiences) > 0 {
+		return o.Audiences
+	}
+
+	return []string{o.ClientId}
+}
+
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
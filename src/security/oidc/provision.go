@@ -0,0 +1,36 @@
+package oidc
+
+import (
+	"net/http"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/security/provisioning"
+)
+
+// ProvisionUser returns the local user of the identity, only identities with a
+// verified email are accepted
+func (s *OidcService) ProvisionUser(ctx basecontext.ApiContext, db *data.JsonDatabase, identity *Identity) (*models.User, error) {
+	if identity == nil {
+		return nil, errors.NewWithCode("invalid identity", http.StatusUnauthorized)
+	}
+	if !identity.EmailVerified {
+		return nil, errors.NewWithCodef(http.StatusUnauthorized, "the email %v is not verified by the identity provider", identity.Email)
+	}
+
+	return provisioning.ProvisionUser(ctx, db, provisioning.ExternalUser{
+		Email:    identity.Email,
+		Username: identity.Username,
+		Name:     identity.Name,
+		Groups:   identity.Groups,
+	}, provisioning.Options{
+		Provider:       constants.OIDC_IDENTITY_PROVIDER,
+		GroupRoles:     s.Options.GroupRoles,
+		GroupClaims:    s.Options.GroupClaims,
+		AutoProvision:  s.Options.AutoProvision,
+		LinkLocalUsers: s.Options.LinkLocalUsers,
+	})
+}
@@ -0,0 +1,68 @@
+package oidc
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newProvisionTestDatabase(t *testing.T) (basecontext.ApiContext, *data.JsonDatabase) {
+	ctx := basecontext.NewBaseContext()
+	ctx.DisableLog()
+	db := data.NewJsonDatabase(ctx, filepath.Join(t.TempDir(), "data.json"))
+	require.NoError(t, db.Connect(ctx))
+
+	for _, claim := range constants.DefaultClaims {
+		if existing, _ := db.GetClaim(ctx, claim); existing == nil {
+			_, err := db.CreateClaim(ctx, models.Claim{Name: claim})
+			require.NoError(t, err)
+		}
+	}
+	for _, role := range constants.DefaultRoles {
+		if existing, _ := db.GetRole(ctx, role); existing == nil {
+			_, err := db.CreateRole(ctx, models.Role{Name: role})
+			require.NoError(t, err)
+		}
+	}
+
+	return ctx, db
+}
+
+func newProvisionTestService() *OidcService {
+	options := NewDefaultOptions().
+		WithIssuerUrl("https://idp.example.com").
+		WithClient("client", "")
+
+	return NewService(nil, options)
+}
+
+func TestProvisionUser_CreatesUser(t *testing.T) {
+	ctx, db := newProvisionTestDatabase(t)
+	svc := newProvisionTestService()
+
+	user, err := svc.ProvisionUser(ctx, db, &Identity{Email: "alice@example.com", EmailVerified: true, Username: "alice", Name: "Alice"})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, constants.OIDC_IDENTITY_PROVIDER, user.IdentityProvider)
+}
+
+func TestProvisionUser_Rejected(t *testing.T) {
+	ctx, db := newProvisionTestDatabase(t)
+	svc := newProvisionTestService()
+
+	_, err := svc.ProvisionUser(ctx, db, nil)
+	assert.ErrorContains(t, err, "invalid identity")
+
+	_, err = svc.ProvisionUser(ctx, db, &Identity{Email: "dave@example.com", EmailVerified: false, Username: "dave", Name: "Dave"})
+	assert.ErrorContains(t, err, "is not verified")
+
+	svc.Options.AutoProvision = false
+	_, err = svc.ProvisionUser(ctx, db, &Identity{Email: "dave@example.com", EmailVerified: true, Username: "dave", Name: "Dave"})
+	assert.ErrorContains(t, err, "does not exist")
+}
@@ -0,0 +1,749 @@
+package policy
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+// Expression is a compiled condition. The syntax is a subset of CEL: string,
+// number, boolean, null and list literals, field access, the ==, !=, <, <=,
+// >, >=, in, &&, || and ! operators, the size() function and the
+// startsWith, endsWith, contains, matches and size methods. Strings are
+// compared ignoring the case, like the roles and claims are.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile parses a condition, the expression has to return a boolean
+func Compile(source string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, errors.Newf("unexpected %v at position %v", p.peek().value, p.peek().position)
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate runs the expression with the variables, missing fields are an
+// error so typos in conditions do not go unnoticed
+func (e *Expression) Evaluate(variables map[string]interface{}) (bool, error) {
+	value, err := e.root.eval(variables)
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := value.(bool)
+	if !ok {
+		return false, errors.Newf("the condition returned %v instead of a boolean", typeName(value))
+	}
+
+	return result, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind     tokenKind
+	value    string
+	position int
+}
+
+func tokenize(source string) ([]token, error) {
+	tokens := make([]token, 0)
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isIdentStart(c):
+			start := i
+			for i < len(source) && (isIdentStart(source[i]) || isDigit(source[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, value: source[start:i], position: start})
+		case isDigit(c):
+			start := i
+			for i < len(source) && (isDigit(source[i]) || (source[i] == '.' && i+1 < len(source) && isDigit(source[i+1]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: source[start:i], position: start})
+		case c == '"' || c == '\'':
+			start := i
+			value := strings.Builder{}
+			i++
+			for {
+				if i >= len(source) {
+					return nil, errors.Newf("unterminated string at position %v", start)
+				}
+				if source[i] == c {
+					i++
+					break
+				}
+				if source[i] == '\\' && i+1 < len(source) {
+					i++
+					switch source[i] {
+					case 'n':
+						value.WriteByte('\n')
+					case 't':
+						value.WriteByte('\t')
+					default:
+						value.WriteByte(source[i])
+					}
+					i++
+					continue
+				}
+				value.WriteByte(source[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenString, value: value.String(), position: start})
+		default:
+			operator := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ",", ".", "-"} {
+				if strings.HasPrefix(source[i:], candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				return nil, errors.Newf("unexpected character %q at position %v", c, i)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, value: operator, position: i})
+			i += len(operator)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, value: "end of the expression", position: len(source)}), nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+type parser struct {
+	tokens   []token
+	position int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.position]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.position]
+	if t.kind != tokenEOF {
+		p.position++
+	}
+	return t
+}
+
+func (p *parser) isOperator(value string) bool {
+	t := p.peek()
+	return t.kind == tokenOperator && t.value == value
+}
+
+func (p *parser) expect(value string) error {
+	if !p.isOperator(value) {
+		return errors.Newf("expected %v at position %v, found %v", value, p.peek().position, p.peek().value)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{operator: "||", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseRelation()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("&&") {
+		p.next()
+		right, err := p.parseRelation()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{operator: "&&", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseRelation() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	isRelation := t.kind == tokenOperator && (t.value == "==" || t.value == "!=" || t.value == "<" || t.value == "<=" || t.value == ">" || t.value == ">=")
+	if !isRelation && !(t.kind == tokenIdent && t.value == "in") {
+		return left, nil
+	}
+
+	p.next()
+	right, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &relationNode{operator: t.value, left: left, right: right}, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOperator("!") || p.isOperator("-") {
+		operator := p.next().value
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{operator: operator, operand: operand}, nil
+	}
+
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	result, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.isOperator("."):
+			p.next()
+			name := p.next()
+			if name.kind != tokenIdent {
+				return nil, errors.Newf("expected a field name at position %v", name.position)
+			}
+			if p.isOperator("(") {
+				args, err := p.parseArguments()
+				if err != nil {
+					return nil, err
+				}
+				result = &callNode{name: name.value, target: result, args: args}
+			} else {
+				result = &fieldNode{target: result, name: name.value}
+			}
+		case p.isOperator("["):
+			p.next()
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			result = &indexNode{target: result, index: index}
+		default:
+			return result, nil
+		}
+	}
+}
+
+func (p *parser) parseArguments() ([]node, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	args := make([]node, 0)
+	if p.isOperator(")") {
+		p.next()
+		return args, nil
+	}
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.isOperator(",") {
+			p.next()
+			continue
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return args, nil
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, errors.Newf("invalid number %v at position %v", t.value, t.position)
+		}
+		return &literalNode{value: value}, nil
+	case tokenString:
+		return &literalNode{value: t.value}, nil
+	case tokenIdent:
+		switch t.value {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		case "in":
+			return nil, errors.Newf("unexpected in at position %v", t.position)
+		}
+		if p.isOperator("(") {
+			args, err := p.parseArguments()
+			if err != nil {
+				return nil, err
+			}
+			return &callNode{name: t.value, args: args}, nil
+		}
+		return &variableNode{name: t.value}, nil
+	case tokenOperator:
+		switch t.value {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		case "[":
+			items := make([]node, 0)
+			if p.isOperator("]") {
+				p.next()
+				return &listNode{items: items}, nil
+			}
+			for {
+				item, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+				if p.isOperator(",") {
+					p.next()
+					continue
+				}
+				if err := p.expect("]"); err != nil {
+					return nil, err
+				}
+				return &listNode{items: items}, nil
+			}
+		}
+	}
+
+	return nil, errors.Newf("unexpected %v at position %v", t.value, t.position)
+}
+
+type node interface {
+	eval(variables map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(variables map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) eval(variables map[string]interface{}) (interface{}, error) {
+	result := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		value, err := item.eval(variables)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+
+	return result, nil
+}
+
+type variableNode struct {
+	name string
+}
+
+func (n *variableNode) eval(variables map[string]interface{}) (interface{}, error) {
+	value, ok := variables[n.name]
+	if !ok {
+		return nil, errors.Newf("undeclared reference to %v", n.name)
+	}
+
+	return value, nil
+}
+
+type fieldNode struct {
+	target node
+	name   string
+}
+
+func (n *fieldNode) eval(variables map[string]interface{}) (interface{}, error) {
+	target, err := n.target.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := target.(map[string]interface{})
+	if !ok {
+		return nil, errors.Newf("cannot read the field %v of a %v", n.name, typeName(target))
+	}
+	value, ok := fields[n.name]
+	if !ok {
+		return nil, errors.Newf("no such field %v", n.name)
+	}
+
+	return value, nil
+}
+
+type indexNode struct {
+	target node
+	index  node
+}
+
+func (n *indexNode) eval(variables map[string]interface{}) (interface{}, error) {
+	target, err := n.target.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := target.(type) {
+	case []interface{}:
+		position, ok := index.(float64)
+		if !ok || position != float64(int(position)) {
+			return nil, errors.Newf("list index must be an integer, got %v", typeName(index))
+		}
+		if int(position) < 0 || int(position) >= len(value) {
+			return nil, errors.Newf("list index %v out of range", int(position))
+		}
+		return value[int(position)], nil
+	case map[string]interface{}:
+		key, ok := index.(string)
+		if !ok {
+			return nil, errors.Newf("map key must be a string, got %v", typeName(index))
+		}
+		result, ok := value[key]
+		if !ok {
+			return nil, errors.Newf("no such key %v", key)
+		}
+		return result, nil
+	}
+
+	return nil, errors.Newf("cannot index a %v", typeName(target))
+}
+
+type unaryNode struct {
+	operator string
+	operand  node
+}
+
+func (n *unaryNode) eval(variables map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.operator == "!" {
+		result, ok := value.(bool)
+		if !ok {
+			return nil, errors.Newf("cannot negate a %v", typeName(value))
+		}
+		return !result, nil
+	}
+
+	number, ok := value.(float64)
+	if !ok {
+		return nil, errors.Newf("cannot negate a %v", typeName(value))
+	}
+	return -number, nil
+}
+
+type logicalNode struct {
+	operator string
+	left     node
+	right    node
+}
+
+func (n *logicalNode) eval(variables map[string]interface{}) (interface{}, error) {
+	left, err := evalBool(n.left, variables, n.operator)
+	if err != nil {
+		return nil, err
+	}
+	if n.operator == "&&" && !left {
+		return false, nil
+	}
+	if n.operator == "||" && left {
+		return true, nil
+	}
+
+	return evalBool(n.right, variables, n.operator)
+}
+
+func evalBool(n node, variables map[string]interface{}, operator string) (bool, error) {
+	value, err := n.eval(variables)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, errors.Newf("the operands of %v must be booleans, got %v", operator, typeName(value))
+	}
+
+	return result, nil
+}
+
+type relationNode struct {
+	operator string
+	left     node
+	right    node
+}
+
+func (n *relationNode) eval(variables map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.operator {
+	case "==":
+		return equals(left, right), nil
+	case "!=":
+		return !equals(left, right), nil
+	case "in":
+		switch container := right.(type) {
+		case []interface{}:
+			for _, item := range container {
+				if equals(left, item) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]interface{}:
+			key, ok := left.(string)
+			if !ok {
+				return false, nil
+			}
+			_, ok = container[key]
+			return ok, nil
+		}
+		return nil, errors.Newf("the right operand of in must be a list or a map, got %v", typeName(right))
+	}
+
+	comparison, err := compare(left, right)
+	if err != nil {
+		return nil, err
+	}
+	switch n.operator {
+	case "<":
+		return comparison < 0, nil
+	case "<=":
+		return comparison <= 0, nil
+	case ">":
+		return comparison > 0, nil
+	default:
+		return comparison >= 0, nil
+	}
+}
+
+type callNode struct {
+	name   string
+	target node
+	args   []node
+}
+
+func (n *callNode) eval(variables map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, 0, len(n.args)+1)
+	if n.target != nil {
+		target, err := n.target.eval(variables)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, target)
+	}
+	for _, arg := range n.args {
+		value, err := arg.eval(variables)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+
+	switch n.name {
+	case "size":
+		if len(args) != 1 {
+			return nil, errors.New("size expects one argument")
+		}
+		switch value := args[0].(type) {
+		case string:
+			return float64(len([]rune(value))), nil
+		case []interface{}:
+			return float64(len(value)), nil
+		case map[string]interface{}:
+			return float64(len(value)), nil
+		}
+		return nil, errors.Newf("size is not defined for a %v", typeName(args[0]))
+	case "startsWith", "endsWith", "contains", "matches":
+		if n.target == nil || len(args) != 2 {
+			return nil, errors.Newf("%v must be called on a string with one argument", n.name)
+		}
+		value, ok := args[0].(string)
+		if !ok {
+			return nil, errors.Newf("%v is not defined for a %v", n.name, typeName(args[0]))
+		}
+		argument, ok := args[1].(string)
+		if !ok {
+			return nil, errors.Newf("the argument of %v must be a string, got %v", n.name, typeName(args[1]))
+		}
+		switch n.name {
+		case "startsWith":
+			return strings.HasPrefix(strings.ToLower(value), strings.ToLower(argument)), nil
+		case "endsWith":
+			return strings.HasSuffix(strings.ToLower(value), strings.ToLower(argument)), nil
+		case "contains":
+			return strings.Contains(strings.ToLower(value), strings.ToLower(argument)), nil
+		default:
+			pattern, err := compileRegex(argument)
+			if err != nil {
+				return nil, err
+			}
+			return pattern.MatchString(value), nil
+		}
+	}
+
+	return nil, errors.Newf("unknown function %v", n.name)
+}
+
+var regexCache sync.Map
+
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := regexCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.NewFromErrorf(err, "invalid regular expression %v", pattern)
+	}
+	regexCache.Store(pattern, compiled)
+
+	return compiled, nil
+}
+
+func equals(left interface{}, right interface{}) bool {
+	switch l := left.(type) {
+	case string:
+		r, ok := right.(string)
+		return ok && strings.EqualFold(l, r)
+	case []interface{}:
+		r, ok := right.([]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for i := range l {
+			if !equals(l[i], r[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return reflect.DeepEqual(left, right)
+}
+
+func compare(left interface{}, right interface{}) (int, error) {
+	switch l := left.(type) {
+	case float64:
+		if r, ok := right.(float64); ok {
+			switch {
+			case l < r:
+				return -1, nil
+			case l > r:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return strings.Compare(strings.ToLower(l), strings.ToLower(r)), nil
+		}
+	}
+
+	return 0, errors.Newf("cannot compare a %v with a %v", typeName(left), typeName(right))
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
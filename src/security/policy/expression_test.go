@@ -0,0 +1,225 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testVariables() map[string]interface{} {
+	return map[string]interface{}{
+		"subject": map[string]interface{}{
+			"id":    "user-1",
+			"roles": []interface{}{"TEAM-IOS", "USER"},
+		},
+		"resource": map[string]interface{}{
+			"owner":     "user-1",
+			"name":      "ios-runner-01",
+			"host_tags": []interface{}{"ios", "ci"},
+			"cpus":      float64(4),
+		},
+		"action": "start",
+	}
+}
+
+func TestExpressionEvaluate(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{`resource.owner == subject.id`, true},
+		{`"team-ios" in subject.roles`, true},
+		{`"ios" in resource.host_tags && action in ["start", "stop"]`, true},
+		{`action == "delete" || "admin" in subject.roles`, false},
+		{`!("macos" in resource.host_tags)`, true},
+		{`resource.name.startsWith("IOS-") && resource.name.endsWith("01")`, true},
+		{`resource.name.contains("runner")`, true},
+		{`resource.name.matches("^ios-runner-[0-9]+$")`, true},
+		{`size(resource.host_tags) == 2 && resource.host_tags.size() >= 1`, true},
+		{`resource.cpus > 2 && resource.cpus <= 4.5 && -resource.cpus < 0`, true},
+		{`resource.host_tags[0] == "ios"`, true},
+		{`"owner" in resource`, true},
+		{`true && (false || !false)`, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expression, err := Compile(test.expression)
+			require.NoError(t, err)
+			result, err := expression.Evaluate(testVariables())
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestExpressionShortCircuits(t *testing.T) {
+	expression, err := Compile(`action == "stop" && resource.missing == "x"`)
+	require.NoError(t, err)
+
+	result, err := expression.Evaluate(testVariables())
+	require.NoError(t, err)
+	assert.False(t, result)
+}
+
+func TestExpressionPrecedence(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{`true || false && false`, true},
+		{`false && true || true`, true},
+		{`(true || false) && false`, false},
+		{`!true || true`, true},
+		{`!(true || true)`, false},
+		{`!false && false`, false},
+		{`!!true`, true},
+		{`action == "start" || action == "stop" && false`, true},
+		{`(action == "start" || action == "stop") && false`, false},
+		{`-2 < -1 && -resource.cpus == -4`, true},
+		{`("ios" in resource.host_tags) == false`, false},
+		{`!("ios" in resource.host_tags) || resource.owner == subject.id`, true},
+		{`resource.name.startsWith("ios") && !resource.name.contains("mac")`, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expression, err := Compile(test.expression)
+			require.NoError(t, err)
+			result, err := expression.Evaluate(testVariables())
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestExpressionListsAndIn(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{`action in ["start", "stop"]`, true},
+		{`action in ["delete"]`, false},
+		{`action in []`, false},
+		{`"IOS" in resource.host_tags`, true},
+		{`4 in [1, 2, resource.cpus]`, true},
+		{`4 in resource.host_tags`, false},
+		{`"ci" in ["ios", resource.host_tags[1]]`, true},
+		{`["ios", "ci"] == resource.host_tags`, true},
+		{`["a"] in [["A"], ["b"]]`, true},
+		{`[1, "a", true][2]`, true},
+		{`"owner" in resource`, true},
+		{`"missing" in resource`, false},
+		{`1 in resource`, false},
+		{`size([]) == 0 && size(["a", "b"]) == 2`, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expression, err := Compile(test.expression)
+			require.NoError(t, err)
+			result, err := expression.Evaluate(testVariables())
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestExpressionMissingAttributes(t *testing.T) {
+	tests := []struct {
+		expression string
+		err        string
+	}{
+		{`resource.missing == "x"`, "no such field missing"},
+		{`subject.missing`, "no such field missing"},
+		{`null == resource.missing`, "no such field missing"},
+		{`resource["missing"] == 1`, "no such key missing"},
+		{`resource.owner.name == "x"`, "cannot read the field name of a string"},
+		{`unknown == "x"`, "undeclared reference to unknown"},
+		{`resource.host_tags[2] == "x"`, "list index 2 out of range"},
+		{`resource.host_tags[-1] == "x"`, "list index -1 out of range"},
+		{`resource.host_tags[1.5] == "x"`, "list index must be an integer"},
+		{`resource[1] == "x"`, "map key must be a string"},
+		{`"ios" in resource.owner`, "the right operand of in must be a list or a map"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expression, err := Compile(test.expression)
+			require.NoError(t, err)
+			_, err = expression.Evaluate(testVariables())
+			assert.ErrorContains(t, err, test.err)
+		})
+	}
+
+	// the missing attributes are not read when the result is already known
+	for _, source := range []string{`false && resource.missing`, `true || resource.missing`} {
+		expression, err := Compile(source)
+		require.NoError(t, err)
+		_, err = expression.Evaluate(testVariables())
+		assert.NoError(t, err, source)
+	}
+}
+
+func TestExpressionParseErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		err        string
+	}{
+		{`resource.owner ==`, "unexpected end of the expression at position 17"},
+		{`(action == "start"`, "expected ) at position 18"},
+		{`action = "start"`, "unexpected character '=' at position 7"},
+		{`action # 1`, "unexpected character '#' at position 7"},
+		{`"unterminated`, "unterminated string at position 0"},
+		{`action == "start" action`, "unexpected action at position 18"},
+		{`[1, 2`, "expected ] at position 5"},
+		{`1 < 2 == true`, "unexpected == at position 6"},
+		{`resource.`, "expected a field name at position 9"},
+		{`resource[`, "unexpected end of the expression at position 9"},
+		{`action in`, "unexpected end of the expression at position 9"},
+		{`in resource`, "unexpected in at position 0"},
+		{`size(`, "unexpected end of the expression at position 5"},
+		{`a && && b`, "unexpected && at position 5"},
+		{`1.2.3 == 1`, "invalid number 1.2.3 at position 0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := Compile(test.expression)
+			assert.ErrorContains(t, err, test.err)
+		})
+	}
+}
+
+func TestExpressionCompileErrors(t *testing.T) {
+	for _, source := range []string{
+		`resource.owner ==`,
+		`(action == "start"`,
+		`action = "start"`,
+		`"unterminated`,
+		`action == "start" action`,
+		`[1, 2`,
+	} {
+		_, err := Compile(source)
+		assert.Error(t, err, source)
+	}
+}
+
+func TestExpressionEvaluateErrors(t *testing.T) {
+	for _, source := range []string{
+		`resource.missing == "x"`,
+		`unknown == "x"`,
+		`resource.owner`,
+		`resource.owner && true`,
+		`resource.cpus > "a"`,
+		`resource.name.matches("[")`,
+		`resource.host_tags[5] == "x"`,
+		`lower(action) == "start"`,
+	} {
+		expression, err := Compile(source)
+		require.NoError(t, err, source)
+		_, err = expression.Evaluate(testVariables())
+		assert.Error(t, err, source)
+	}
+}
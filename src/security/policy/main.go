@@ -0,0 +1,216 @@
+package policy
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+var expressions sync.Map
+
+// Subject is who is calling, ApiKey is set instead of the user fields for
+// requests authorized with an api key
+type Subject struct {
+	ID          string
+	Username    string
+	Email       string
+	ApiKey      string
+	Roles       []string
+	Claims      []string
+	IsSuperUser bool
+}
+
+// Resource is the machine or host the action is done on, the fields that do
+// not apply to the type of resource are empty
+type Resource struct {
+	Type     string
+	ID       string
+	Name     string
+	Owner    string
+	HostID   string
+	Host     string
+	HostTags []string
+	Tags     []string
+	State    string
+	OS       string
+}
+
+// Decision is the result of the policies, Policy is the name of the policy
+// that decided it when there was one
+type Decision struct {
+	Allowed bool
+	Policy  string
+	Reason  string
+}
+
+// Evaluate decides if the subject can do the action on the resource. Super
+// users can always do it. A deny policy that matches wins, then if any allow
+// policy applies to the subject, the type of resource and the action one of
+// them has to match, otherwise the subject is not restricted by the policies
+// and only the roles and claims of the route apply. An allow policy for
+// start and stop does not restrict read or delete.
+func Evaluate(policies []models.AccessPolicy, subject Subject, resource Resource, action string) Decision {
+	if subject.IsSuperUser {
+		return Decision{Allowed: true, Reason: "super users are not restricted by the access policies"}
+	}
+
+	variables := map[string]interface{}{
+		"subject":  subject.variables(),
+		"resource": resource.variables(),
+		"action":   action,
+	}
+
+	restricted := false
+	var allowedBy *models.AccessPolicy
+	for i := range policies {
+		item := &policies[i]
+		if !item.Enabled || !containsFold(item.Resources, resource.Type) || !appliesTo(item, subject) {
+			continue
+		}
+		if !containsFold(item.Actions, action) && !containsFold(item.Actions, constants.ACCESS_POLICY_ACTION_ALL) {
+			continue
+		}
+		isDeny := strings.EqualFold(item.Effect, constants.ACCESS_POLICY_EFFECT_DENY)
+		if !isDeny {
+			restricted = true
+		}
+
+		matches, err := matchesCondition(item.Condition, variables)
+		if err != nil {
+			// a deny that cannot be evaluated still denies, an allow that
+			// cannot be evaluated does not allow
+			if isDeny {
+				return Decision{Allowed: false, Policy: item.Name, Reason: "the condition of access policy " + item.Name + " failed: " + err.Error()}
+			}
+			continue
+		}
+		if !matches {
+			continue
+		}
+		if isDeny {
+			return Decision{Allowed: false, Policy: item.Name, Reason: "denied by access policy " + item.Name}
+		}
+		if allowedBy == nil {
+			allowedBy = item
+		}
+	}
+
+	if allowedBy != nil {
+		return Decision{Allowed: true, Policy: allowedBy.Name, Reason: "allowed by access policy " + allowedBy.Name}
+	}
+	if restricted {
+		return Decision{Allowed: false, Reason: "no access policy allows " + action + " on this " + strings.ReplaceAll(resource.Type, "_", " ")}
+	}
+
+	return Decision{Allowed: true}
+}
+
+// Validate checks the condition compiles and can be evaluated, it is run
+// with empty values so typos in the field names are found when the policy is
+// saved
+func Validate(condition string) error {
+	if strings.TrimSpace(condition) == "" {
+		return nil
+	}
+
+	variables := map[string]interface{}{
+		"subject":  Subject{}.variables(),
+		"resource": Resource{}.variables(),
+		"action":   "",
+	}
+	if _, err := matchesCondition(condition, variables); err != nil {
+		return errors.NewFromErrorf(err, "invalid condition")
+	}
+
+	return nil
+}
+
+func matchesCondition(condition string, variables map[string]interface{}) (bool, error) {
+	if strings.TrimSpace(condition) == "" {
+		return true, nil
+	}
+
+	var expression *Expression
+	if cached, ok := expressions.Load(condition); ok {
+		expression = cached.(*Expression)
+	} else {
+		compiled, err := Compile(condition)
+		if err != nil {
+			return false, err
+		}
+		expressions.Store(condition, compiled)
+		expression = compiled
+	}
+
+	return expression.Evaluate(variables)
+}
+
+func appliesTo(item *models.AccessPolicy, subject Subject) bool {
+	if len(item.Roles) == 0 && len(item.Users) == 0 {
+		return true
+	}
+
+	for _, user := range item.Users {
+		if user == "" {
+			continue
+		}
+		if strings.EqualFold(user, subject.ID) || strings.EqualFold(user, subject.Username) || strings.EqualFold(user, subject.Email) || strings.EqualFold(user, subject.ApiKey) {
+			return true
+		}
+	}
+	for _, role := range item.Roles {
+		if containsFold(subject.Roles, role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s Subject) variables() map[string]interface{} {
+	return map[string]interface{}{
+		"id":       s.ID,
+		"username": s.Username,
+		"email":    s.Email,
+		"api_key":  s.ApiKey,
+		"roles":    toList(s.Roles),
+		"claims":   toList(s.Claims),
+	}
+}
+
+func (r Resource) variables() map[string]interface{} {
+	return map[string]interface{}{
+		"type":      r.Type,
+		"id":        r.ID,
+		"name":      r.Name,
+		"owner":     r.Owner,
+		"host_id":   r.HostID,
+		"host":      r.Host,
+		"host_tags": toList(r.HostTags),
+		"tags":      toList(r.Tags),
+		"state":     r.State,
+		"os":        r.OS,
+	}
+}
+
+func toList(values []string) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		result = append(result, value)
+	}
+
+	return result
+}
+
+func containsFold(values []string, value string) bool {
+	for _, item := range values {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,165 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateWithoutPoliciesAllows(t *testing.T) {
+	decision := Evaluate(nil, Subject{ID: "user-1"}, Resource{Type: constants.ACCESS_POLICY_RESOURCE_MACHINE}, constants.ACCESS_POLICY_ACTION_DELETE)
+	assert.True(t, decision.Allowed)
+}
+
+func TestEvaluateTeamCanStartAndStopButNotDelete(t *testing.T) {
+	policies := []models.AccessPolicy{
+		{
+			Name:      "team-ios-operate",
+			Effect:    constants.ACCESS_POLICY_EFFECT_ALLOW,
+			Roles:     []string{"TEAM-IOS"},
+			Resources: []string{constants.ACCESS_POLICY_RESOURCE_MACHINE},
+			Actions:   []string{"read", "start", "stop"},
+			Condition: `"ios" in resource.host_tags`,
+			Enabled:   true,
+		},
+	}
+	subject := Subject{ID: "user-1", Roles: []string{"team-ios"}}
+	iosMachine := Resource{Type: constants.ACCESS_POLICY_RESOURCE_MACHINE, ID: "vm-1", HostTags: []string{"ios"}}
+	otherMachine := Resource{Type: constants.ACCESS_POLICY_RESOURCE_MACHINE, ID: "vm-2", HostTags: []string{"windows"}}
+
+	decision := Evaluate(policies, subject, iosMachine, constants.ACCESS_POLICY_ACTION_START)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, "team-ios-operate", decision.Policy)
+	assert.False(t, Evaluate(policies, subject, otherMachine, constants.ACCESS_POLICY_ACTION_STOP).Allowed)
+	assert.False(t, Evaluate(policies, subject, otherMachine, constants.ACCESS_POLICY_ACTION_READ).Allowed)
+
+	// the actions the policy does not cover need a deny policy
+	assert.True(t, Evaluate(policies, subject, iosMachine, constants.ACCESS_POLICY_ACTION_DELETE).Allowed)
+	policies = append(policies, models.AccessPolicy{
+		Name:      "team-ios-no-delete",
+		Effect:    constants.ACCESS_POLICY_EFFECT_DENY,
+		Roles:     []string{"TEAM-IOS"},
+		Resources: []string{constants.ACCESS_POLICY_RESOURCE_MACHINE},
+		Actions:   []string{constants.ACCESS_POLICY_ACTION_DELETE},
+		Enabled:   true,
+	})
+	assert.False(t, Evaluate(policies, subject, iosMachine, constants.ACCESS_POLICY_ACTION_DELETE).Allowed)
+
+	// the policy does not apply to other users or resources
+	assert.True(t, Evaluate(policies, Subject{ID: "user-2", Roles: []string{"USER"}}, iosMachine, constants.ACCESS_POLICY_ACTION_DELETE).Allowed)
+	assert.True(t, Evaluate(policies, subject, Resource{Type: constants.ACCESS_POLICY_RESOURCE_ORCHESTRATOR_HOST}, constants.ACCESS_POLICY_ACTION_DELETE).Allowed)
+}
+
+func TestEvaluateOwnMachinesOnly(t *testing.T) {
+	policies := []models.AccessPolicy{
+		{
+			Name:      "own-machines",
+			Effect:    constants.ACCESS_POLICY_EFFECT_ALLOW,
+			Users:     []string{"jane"},
+			Resources: []string{constants.ACCESS_POLICY_RESOURCE_MACHINE},
+			Actions:   []string{constants.ACCESS_POLICY_ACTION_ALL},
+			Condition: `resource.owner == subject.id`,
+			Enabled:   true,
+		},
+	}
+	subject := Subject{ID: "user-1", Username: "jane"}
+
+	assert.True(t, Evaluate(policies, subject, Resource{Type: constants.ACCESS_POLICY_RESOURCE_MACHINE, Owner: "user-1"}, constants.ACCESS_POLICY_ACTION_READ).Allowed)
+	assert.False(t, Evaluate(policies, subject, Resource{Type: constants.ACCESS_POLICY_RESOURCE_MACHINE, Owner: "user-2"}, constants.ACCESS_POLICY_ACTION_READ).Allowed)
+	assert.False(t, Evaluate(policies, subject, Resource{Type: constants.ACCESS_POLICY_RESOURCE_MACHINE}, constants.ACCESS_POLICY_ACTION_READ).Allowed)
+}
+
+func TestEvaluateAllowOnlyRestrictsItsActions(t *testing.T) {
+	policies := []models.AccessPolicy{
+		{
+			Name:      "operate-ci-machines",
+			Effect:    constants.ACCESS_POLICY_EFFECT_ALLOW,
+			Resources: []string{constants.ACCESS_POLICY_RESOURCE_MACHINE},
+			Actions:   []string{constants.ACCESS_POLICY_ACTION_START, constants.ACCESS_POLICY_ACTION_STOP},
+			Condition: `"ci" in resource.host_tags`,
+			Enabled:   true,
+		},
+	}
+	prodMachine := Resource{Type: constants.ACCESS_POLICY_RESOURCE_MACHINE, HostTags: []string{"production"}}
+
+	assert.False(t, Evaluate(policies, Subject{ID: "user-1"}, prodMachine, constants.ACCESS_POLICY_ACTION_START).Allowed)
+	assert.True(t, Evaluate(policies, Subject{ID: "user-1"}, prodMachine, constants.ACCESS_POLICY_ACTION_READ).Allowed)
+}
+
+func TestEvaluateDenyWins(t *testing.T) {
+	policies := []models.AccessPolicy{
+		{
+			Name:      "allow-all",
+			Effect:    constants.ACCESS_POLICY_EFFECT_ALLOW,
+			Resources: []string{constants.ACCESS_POLICY_RESOURCE_MACHINE},
+			Actions:   []string{constants.ACCESS_POLICY_ACTION_ALL},
+			Enabled:   true,
+		},
+		{
+			Name:      "no-delete-on-production",
+			Effect:    constants.ACCESS_POLICY_EFFECT_DENY,
+			Resources: []string{constants.ACCESS_POLICY_RESOURCE_MACHINE},
+			Actions:   []string{constants.ACCESS_POLICY_ACTION_DELETE},
+			Condition: `"production" in resource.host_tags`,
+			Enabled:   true,
+		},
+	}
+	production := Resource{Type: constants.ACCESS_POLICY_RESOURCE_MACHINE, HostTags: []string{"production"}}
+
+	decision := Evaluate(policies, Subject{ID: "user-1"}, production, constants.ACCESS_POLICY_ACTION_DELETE)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "no-delete-on-production", decision.Policy)
+	assert.True(t, Evaluate(policies, Subject{ID: "user-1"}, production, constants.ACCESS_POLICY_ACTION_STOP).Allowed)
+	assert.True(t, Evaluate(policies, Subject{ID: "user-1", IsSuperUser: true}, production, constants.ACCESS_POLICY_ACTION_DELETE).Allowed)
+}
+
+func TestEvaluateDenyOnlyPoliciesDoNotRestrict(t *testing.T) {
+	policies := []models.AccessPolicy{
+		{
+			Name:      "no-delete",
+			Effect:    constants.ACCESS_POLICY_EFFECT_DENY,
+			Resources: []string{constants.ACCESS_POLICY_RESOURCE_REVERSE_PROXY_HOST},
+			Actions:   []string{constants.ACCESS_POLICY_ACTION_DELETE},
+			Enabled:   true,
+		},
+		{
+			Name:      "disabled",
+			Effect:    constants.ACCESS_POLICY_EFFECT_ALLOW,
+			Resources: []string{constants.ACCESS_POLICY_RESOURCE_REVERSE_PROXY_HOST},
+			Actions:   []string{constants.ACCESS_POLICY_ACTION_READ},
+			Enabled:   false,
+		},
+	}
+	resource := Resource{Type: constants.ACCESS_POLICY_RESOURCE_REVERSE_PROXY_HOST}
+
+	assert.False(t, Evaluate(policies, Subject{ApiKey: "key-1"}, resource, constants.ACCESS_POLICY_ACTION_DELETE).Allowed)
+	assert.True(t, Evaluate(policies, Subject{ApiKey: "key-1"}, resource, constants.ACCESS_POLICY_ACTION_UPDATE).Allowed)
+}
+
+func TestEvaluateConditionErrors(t *testing.T) {
+	policies := []models.AccessPolicy{
+		{
+			Name:      "broken-allow",
+			Effect:    constants.ACCESS_POLICY_EFFECT_ALLOW,
+			Resources: []string{constants.ACCESS_POLICY_RESOURCE_MACHINE},
+			Actions:   []string{constants.ACCESS_POLICY_ACTION_ALL},
+			Condition: `resource.owner > 1`,
+			Enabled:   true,
+		},
+	}
+	resource := Resource{Type: constants.ACCESS_POLICY_RESOURCE_MACHINE}
+	assert.False(t, Evaluate(policies, Subject{ID: "user-1"}, resource, constants.ACCESS_POLICY_ACTION_READ).Allowed)
+
+	policies[0].Effect = constants.ACCESS_POLICY_EFFECT_DENY
+	assert.False(t, Evaluate(policies, Subject{ID: "user-1"}, resource, constants.ACCESS_POLICY_ACTION_READ).Allowed)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(""))
+	assert.NoError(t, Validate(`resource.owner == subject.id && "ios" in resource.host_tags`))
+	assert.Error(t, Validate(`resource.ownr == subject.id`))
+	assert.Error(t, Validate(`resource.owner ==`))
+	assert.Error(t, Validate(`resource.owner`))
+}
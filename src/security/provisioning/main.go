@@ -0,0 +1,246 @@
+package provisioning
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/errors"
+	"github.com/Parallels/prl-devops-service/security"
+)
+
+// ExternalUser is a user authenticated by an external identity provider
+type ExternalUser struct {
+	Email    string
+	Username string
+	Name     string
+	Groups   []string
+}
+
+// Options configures how the users of an identity provider are provisioned
+type Options struct {
+	// Provider is stored in the users created by the identity provider
+	Provider      string
+	GroupRoles    map[string][]string
+	GroupClaims   map[string][]string
+	AutoProvision bool
+	// LinkLocalUsers lets an identity log in as the local user with the same
+	// email, otherwise only the users created by the provider can log in
+	LinkLocalUsers bool
+}
+
+// HasGroupMapping returns true if any group is mapped to roles or claims
+func (o Options) HasGroupMapping() bool {
+	return len(o.GroupRoles) > 0 || len(o.GroupClaims) > 0
+}
+
+// ProvisionUser returns the local user of an external user. Users are created
+// on their first login and the users created by the identity provider get the
+// roles and claims of their groups on every login. Local users with the same
+// email are only linked when LinkLocalUsers is set and keep the roles and
+// claims they have
+func ProvisionUser(ctx basecontext.ApiContext, db *data.JsonDatabase, externalUser ExternalUser, options Options) (*models.User, error) {
+	if externalUser.Email == "" {
+		return nil, errors.NewWithCode("the identity provider did not return the email of the user", http.StatusUnauthorized)
+	}
+	if externalUser.Username == "" {
+		externalUser.Username = externalUser.Email
+	}
+	if externalUser.Name == "" {
+		externalUser.Name = externalUser.Username
+	}
+
+	roles, claims := mapGroups(ctx, db, externalUser.Groups, options)
+	user, _ := db.GetUser(ctx, externalUser.Email)
+	if user == nil {
+		if !options.AutoProvision {
+			return nil, errors.NewWithCodef(http.StatusUnauthorized, "user %v does not exist", externalUser.Email)
+		}
+
+		// the password is never shared, the user can only log in with the
+		// identity provider
+		password, err := security.GenerateCryptoRandomString(32)
+		if err != nil {
+			return nil, err
+		}
+		newUser := models.User{
+			Username:         externalUser.Username,
+			Name:             externalUser.Name,
+			Email:            externalUser.Email,
+			Password:         password,
+			IdentityProvider: options.Provider,
+			Roles:            roles,
+			Claims:           claims,
+		}
+		if existing, _ := db.GetUser(ctx, newUser.Username); existing != nil {
+			newUser.Username = externalUser.Email
+		}
+
+		user, err = db.CreateUser(ctx, newUser)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx.LogInfof("[Provisioning] User %v provisioned by %v", user.Email, options.Provider)
+		return user, nil
+	}
+
+	if user.ID == constants.ROOT_USER_ID {
+		return nil, errors.NewWithCode("the root user cannot log in with an identity provider", http.StatusUnauthorized)
+	}
+	if user.Disabled {
+		return nil, errors.NewWithCodef(http.StatusUnauthorized, "user %v is disabled", user.Email)
+	}
+	if user.Blocked {
+		return nil, errors.NewWithCodef(http.StatusUnauthorized, "user %v is blocked", user.Email)
+	}
+	if user.IdentityProvider != options.Provider {
+		// anyone able to use the same email with the provider would get the
+		// roles of the local user
+		if user.IdentityProvider != "" || !options.LinkLocalUsers {
+			return nil, errors.NewWithCodef(http.StatusUnauthorized, "user %v is not managed by %v", user.Email, options.Provider)
+		}
+		ctx.LogInfof("[Provisioning] User %v logged in with %v", user.Email, options.Provider)
+		return user, nil
+	}
+	if !options.HasGroupMapping() {
+		return user, nil
+	}
+
+	if len(roles) == 0 {
+		roles = getRoles(ctx, db, constants.DefaultRoles)
+	}
+	if len(claims) == 0 {
+		claims = getClaims(ctx, db, constants.DefaultClaims)
+	}
+	if sameRoles(user.Roles, roles) && sameClaims(user.Claims, claims) && user.Name == externalUser.Name {
+		return user, nil
+	}
+
+	if err := db.UpdateUser(ctx, models.User{ID: user.ID, Name: externalUser.Name, Roles: roles, Claims: claims}); err != nil {
+		return nil, err
+	}
+	ctx.LogInfof("[Provisioning] User %v roles and claims updated from the groups %v", user.Email, externalUser.Groups)
+
+	return db.GetUser(ctx, user.ID)
+}
+
+// ParseGroupMapping reads a group mapping in the group=VALUE1,VALUE2;group2=VALUE3
+// format, the group names are case insensitive
+func ParseGroupMapping(value string) map[string][]string {
+	result := make(map[string][]string)
+	for _, item := range strings.Split(value, ";") {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		group := strings.ToLower(strings.TrimSpace(parts[0]))
+		if group == "" {
+			continue
+		}
+		for _, name := range strings.Split(parts[1], ",") {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				result[group] = append(result[group], name)
+			}
+		}
+	}
+
+	return result
+}
+
+// mapGroups returns the roles and claims of the groups, the roles and claims
+// that do not exist are ignored
+func mapGroups(ctx basecontext.ApiContext, db *data.JsonDatabase, groups []string, options Options) ([]models.Role, []models.Claim) {
+	roleNames := make([]string, 0)
+	claimNames := make([]string, 0)
+	for _, group := range groups {
+		group = strings.ToLower(group)
+		roleNames = append(roleNames, options.GroupRoles[group]...)
+		claimNames = append(claimNames, options.GroupClaims[group]...)
+	}
+
+	return getRoles(ctx, db, roleNames), getClaims(ctx, db, claimNames)
+}
+
+func getRoles(ctx basecontext.ApiContext, db *data.JsonDatabase, names []string) []models.Role {
+	result := make([]models.Role, 0)
+	for _, name := range names {
+		role, err := db.GetRole(ctx, name)
+		if err != nil || role == nil {
+			ctx.LogWarnf("[Provisioning] Role %v does not exist", name)
+			continue
+		}
+		if !containsRole(result, role.ID) {
+			result = append(result, *role)
+		}
+	}
+
+	return result
+}
+
+func getClaims(ctx basecontext.ApiContext, db *data.JsonDatabase, names []string) []models.Claim {
+	result := make([]models.Claim, 0)
+	for _, name := range names {
+		claim, err := db.GetClaim(ctx, name)
+		if err != nil || claim == nil {
+			ctx.LogWarnf("[Provisioning] Claim %v does not exist", name)
+			continue
+		}
+		if !containsClaim(result, claim.ID) {
+			result = append(result, *claim)
+		}
+	}
+
+	return result
+}
+
+func containsRole(roles []models.Role, id string) bool {
+	for _, role := range roles {
+		if strings.EqualFold(role.ID, id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsClaim(claims []models.Claim, id string) bool {
+	for _, claim := range claims {
+		if strings.EqualFold(claim.ID, id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sameRoles(current []models.Role, expected []models.Role) bool {
+	if len(current) != len(expected) {
+		return false
+	}
+	for _, role := range expected {
+		if !containsRole(current, role.ID) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sameClaims(current []models.Claim, expected []models.Claim) bool {
+	if len(current) != len(expected) {
+		return false
+	}
+	for _, claim := range expected {
+		if !containsClaim(current, claim.ID) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,136 @@
+package provisioning
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/data"
+	"github.com/Parallels/prl-devops-service/data/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProvider = "test"
+
+func newTestDatabase(t *testing.T) (basecontext.ApiContext, *data.JsonDatabase) {
+	ctx := basecontext.NewBaseContext()
+	ctx.DisableLog()
+	db := data.NewJsonDatabase(ctx, filepath.Join(t.TempDir(), "data.json"))
+	require.NoError(t, db.Connect(ctx))
+
+	for _, claim := range append([]string{constants.LIST_VM_CLAIM, constants.CREATE_VM_CLAIM}, constants.DefaultClaims...) {
+		if existing, _ := db.GetClaim(ctx, claim); existing == nil {
+			_, err := db.CreateClaim(ctx, models.Claim{Name: claim})
+			require.NoError(t, err)
+		}
+	}
+	for _, role := range []string{constants.USER_ROLE, constants.SUPER_USER_ROLE} {
+		if existing, _ := db.GetRole(ctx, role); existing == nil {
+			_, err := db.CreateRole(ctx, models.Role{Name: role})
+			require.NoError(t, err)
+		}
+	}
+
+	return ctx, db
+}
+
+func newTestOptions() Options {
+	return Options{
+		Provider:      testProvider,
+		GroupRoles:    ParseGroupMapping("admins=" + constants.SUPER_USER_ROLE + ",MISSING"),
+		GroupClaims:   ParseGroupMapping("developers=" + constants.CREATE_VM_CLAIM),
+		AutoProvision: true,
+	}
+}
+
+func TestParseGroupMapping(t *testing.T) {
+	mapping := ParseGroupMapping("Admins=SUPER_USER; developers = USER, CATALOG ;invalid;=ROLE")
+
+	assert.Equal(t, map[string][]string{
+		"admins":     {"SUPER_USER"},
+		"developers": {"USER", "CATALOG"},
+	}, mapping)
+	assert.Empty(t, ParseGroupMapping(""))
+}
+
+func TestProvisionUser_CreatesUser(t *testing.T) {
+	ctx, db := newTestDatabase(t)
+
+	user, err := ProvisionUser(ctx, db, ExternalUser{Email: "alice@example.com", Username: "alice", Name: "Alice", Groups: []string{"Admins"}}, newTestOptions())
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, testProvider, user.IdentityProvider)
+	require.Len(t, user.Roles, 1)
+	assert.Equal(t, constants.SUPER_USER_ROLE, user.Roles[0].ID)
+
+	stored, err := db.GetUser(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, stored.ID)
+}
+
+func TestProvisionUser_SyncsGroups(t *testing.T) {
+	ctx, db := newTestDatabase(t)
+	externalUser := ExternalUser{Email: "bob@example.com", Username: "bob", Name: "Bob", Groups: []string{"admins"}}
+
+	_, err := ProvisionUser(ctx, db, externalUser, newTestOptions())
+	require.NoError(t, err)
+
+	externalUser.Groups = []string{"developers"}
+	user, err := ProvisionUser(ctx, db, externalUser, newTestOptions())
+	require.NoError(t, err)
+	require.Len(t, user.Roles, 1)
+	assert.Equal(t, constants.USER_ROLE, user.Roles[0].ID)
+	require.Len(t, user.Claims, 1)
+	assert.Equal(t, constants.CREATE_VM_CLAIM, user.Claims[0].ID)
+}
+
+func TestProvisionUser_LinksLocalUserOnlyWhenEnabled(t *testing.T) {
+	ctx, db := newTestDatabase(t)
+	superUser, err := db.GetRole(ctx, constants.SUPER_USER_ROLE)
+	require.NoError(t, err)
+	_, err = db.CreateUser(ctx, models.User{Username: "carol", Name: "Carol", Email: "carol@example.com", Password: "password", Roles: []models.Role{*superUser}})
+	require.NoError(t, err)
+
+	options := newTestOptions()
+	_, err = ProvisionUser(ctx, db, ExternalUser{Email: "carol@example.com", Username: "carol", Name: "Carol"}, options)
+	assert.ErrorContains(t, err, "is not managed by")
+
+	options.LinkLocalUsers = true
+	user, err := ProvisionUser(ctx, db, ExternalUser{Email: "carol@example.com", Username: "carol", Name: "Carol"}, options)
+	require.NoError(t, err)
+	require.Len(t, user.Roles, 1)
+	assert.Equal(t, constants.SUPER_USER_ROLE, user.Roles[0].ID)
+	assert.Empty(t, user.IdentityProvider)
+}
+
+func TestProvisionUser_Rejected(t *testing.T) {
+	ctx, db := newTestDatabase(t)
+	options := newTestOptions()
+
+	_, err := ProvisionUser(ctx, db, ExternalUser{Username: "dave"}, options)
+	assert.ErrorContains(t, err, "did not return the email")
+
+	options.AutoProvision = false
+	_, err = ProvisionUser(ctx, db, ExternalUser{Email: "dave@example.com", Username: "dave"}, options)
+	assert.ErrorContains(t, err, "does not exist")
+
+	options.AutoProvision = true
+	user, err := ProvisionUser(ctx, db, ExternalUser{Email: "dave@example.com", Username: "dave"}, options)
+	require.NoError(t, err)
+	require.NoError(t, db.UpdateUserDisabledStatus(ctx, user.ID, true, "removed"))
+	_, err = ProvisionUser(ctx, db, ExternalUser{Email: "dave@example.com", Username: "dave"}, options)
+	assert.ErrorContains(t, err, "is disabled")
+}
+
+func TestProvisionUser_OtherProviderUserNeverLinked(t *testing.T) {
+	ctx, db := newTestDatabase(t)
+	_, err := db.CreateUser(ctx, models.User{Username: "erin", Name: "Erin", Email: "erin@example.com", Password: "password", IdentityProvider: "other"})
+	require.NoError(t, err)
+
+	options := newTestOptions()
+	options.LinkLocalUsers = true
+	_, err = ProvisionUser(ctx, db, ExternalUser{Email: "erin@example.com", Username: "erin"}, options)
+	assert.ErrorContains(t, err, "is not managed by")
+}
@@ -0,0 +1,268 @@
+package ratelimit
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/constants"
+	sql_database "github.com/Parallels/prl-devops-service/sql"
+)
+
+var globalRateLimiter *RateLimiter
+
+// Request is who is calling and what, the identity fields are empty for
+// requests that are not authenticated
+type Request struct {
+	UserID   string
+	Username string
+	Email    string
+	ApiKeyID string
+	Ip       string
+	Path     string
+	// ApiKeyLimit is the limit of the scope of the api key, it replaces the
+	// limit of each api key
+	ApiKeyLimit Limit
+}
+
+// Decision is the result of a request, Scope is the limit that was exceeded
+type Decision struct {
+	Allowed    bool
+	RetryAfter time.Duration
+	Scope      string
+	Limit      Limit
+}
+
+type RateLimiter struct {
+	ctx     basecontext.ApiContext
+	Options *RateLimitOptions
+	store   Store
+	mutex   sync.RWMutex
+}
+
+func New(ctx basecontext.ApiContext) *RateLimiter {
+	if globalRateLimiter != nil && globalRateLimiter.store != nil {
+		_ = globalRateLimiter.store.Close()
+	}
+
+	globalRateLimiter = &RateLimiter{
+		ctx:     ctx,
+		Options: NewDefaultOptions(),
+		store:   newMemoryStore(),
+	}
+
+	globalRateLimiter.processEnvironmentVariables()
+	if globalRateLimiter.Options.Enabled && globalRateLimiter.Options.Store == constants.RATE_LIMIT_MYSQL_STORE {
+		store, err := newMySQLStore(&sql_database.MySQLService{})
+		if err != nil {
+			ctx.LogErrorf("[RateLimit] Error opening the %v store, using the memory store: %v", constants.RATE_LIMIT_MYSQL_STORE, err)
+		} else {
+			globalRateLimiter.store = store
+		}
+	}
+	if globalRateLimiter.Options.Enabled {
+		ctx.LogInfof("[RateLimit] Rate limiting enabled with the %v store", globalRateLimiter.store.Name())
+	}
+
+	return globalRateLimiter
+}
+
+// Get returns the rate limiter, it is nil when New was not called
+func Get() *RateLimiter {
+	return globalRateLimiter
+}
+
+// WithStore replaces where the buckets are kept
+func (l *RateLimiter) WithStore(store Store) *RateLimiter {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.store = store
+	return l
+}
+
+// IsEnabled returns true when the configured limits apply to the requests
+func (l *RateLimiter) IsEnabled() bool {
+	return l != nil && l.Options.Enabled
+}
+
+// Allow takes a token from every bucket that applies to the request, the
+// route groups first, then the identity, the address and the global bucket.
+// No token is taken when any of the buckets is empty. The limit of the scope
+// of an api key applies even when the configured limits are disabled or the
+// caller is exempt from them. Errors of the store let the request through
+func (l *RateLimiter) Allow(request Request) Decision {
+	if l == nil {
+		return Decision{Allowed: true}
+	}
+
+	checks := make([]check, 0)
+	if l.Options.Enabled && !l.IsExempt(request) {
+		checks = l.getChecks(request)
+	} else if request.ApiKeyID != "" && !request.ApiKeyLimit.IsZero() {
+		checks = append(checks, check{scope: "api key", key: "api_key:" + request.ApiKeyID, limit: request.ApiKeyLimit})
+	}
+
+	buckets := make([]BucketLimit, 0, len(checks))
+	scopes := make([]check, 0, len(checks))
+	for _, c := range checks {
+		if c.limit.IsZero() {
+			continue
+		}
+		buckets = append(buckets, BucketLimit{Key: c.key, Limit: c.limit})
+		scopes = append(scopes, c)
+	}
+	if len(buckets) == 0 {
+		return Decision{Allowed: true}
+	}
+
+	l.mutex.RLock()
+	store := l.store
+	l.mutex.RUnlock()
+
+	denied, retryAfter, err := store.Take(l.ctx, buckets, time.Now())
+	if err != nil {
+		l.ctx.LogWarnf("[RateLimit] Error checking the limits, the request is allowed: %v", err)
+		return Decision{Allowed: true}
+	}
+	if denied >= 0 {
+		return Decision{Allowed: false, RetryAfter: retryAfter, Scope: scopes[denied].scope, Limit: scopes[denied].limit}
+	}
+
+	return Decision{Allowed: true}
+}
+
+// getChecks returns the buckets of the configured limits that apply to the
+// request, in the order they are taken
+func (l *RateLimiter) getChecks(request Request) []check {
+	identity := ""
+	identityLimit := Limit{}
+	switch {
+	case request.ApiKeyID != "":
+		identity = "api_key:" + request.ApiKeyID
+		identityLimit = l.Options.PerApiKey
+		if !request.ApiKeyLimit.IsZero() {
+			identityLimit = request.ApiKeyLimit
+		}
+	case request.UserID != "":
+		identity = "user:" + request.UserID
+		identityLimit = l.Options.PerUser
+	case request.Ip != "":
+		identity = "ip:" + request.Ip
+	}
+
+	checks := make([]check, 0)
+	for _, group := range l.Options.RouteGroups {
+		if identity != "" && group.Matches(request.Path) {
+			checks = append(checks, check{scope: group.Name + " route group", key: "route:" + group.Name + ":" + identity, limit: group.Limit})
+		}
+	}
+	if request.ApiKeyID != "" {
+		checks = append(checks, check{scope: "api key", key: identity, limit: identityLimit})
+	} else if request.UserID != "" {
+		checks = append(checks, check{scope: "user", key: identity, limit: identityLimit})
+	}
+	if request.Ip != "" {
+		checks = append(checks, check{scope: "ip", key: "ip:" + request.Ip, limit: l.Options.PerIp})
+	}
+	checks = append(checks, check{scope: "global", key: "global", limit: l.Options.Global})
+
+	return checks
+}
+
+// IsExempt returns true if the caller or the route is in the exemption list
+func (l *RateLimiter) IsExempt(request Request) bool {
+	exemptions := l.Options.Exemptions
+	for _, route := range exemptions.Routes {
+		if matchesPathPrefix(request.Path, "/"+strings.Trim(route, "/")) {
+			return true
+		}
+	}
+	if request.ApiKeyID != "" {
+		for _, apiKey := range exemptions.ApiKeys {
+			if strings.EqualFold(apiKey, request.ApiKeyID) {
+				return true
+			}
+		}
+	}
+	if request.UserID != "" {
+		for _, user := range exemptions.Users {
+			if strings.EqualFold(user, request.UserID) || strings.EqualFold(user, request.Username) || strings.EqualFold(user, request.Email) {
+				return true
+			}
+		}
+	}
+	if ip := net.ParseIP(request.Ip); ip != nil {
+		for _, network := range exemptions.Networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type check struct {
+	scope string
+	key   string
+	limit Limit
+}
+
+func (l *RateLimiter) processEnvironmentVariables() {
+	cfg := config.Get()
+	if cfg.GetKey(constants.RATE_LIMIT_ENABLED_ENV_VAR) != "" {
+		l.Options.WithEnabled(cfg.GetBoolKey(constants.RATE_LIMIT_ENABLED_ENV_VAR))
+	}
+	if cfg.GetKey(constants.RATE_LIMIT_STORE_ENV_VAR) != "" {
+		store := strings.ToLower(cfg.GetKey(constants.RATE_LIMIT_STORE_ENV_VAR))
+		if store != constants.RATE_LIMIT_MEMORY_STORE && store != constants.RATE_LIMIT_MYSQL_STORE {
+			l.ctx.LogWarnf("[RateLimit] Invalid value for %s: %s, allowed values are %s and %s", constants.RATE_LIMIT_STORE_ENV_VAR, store, constants.RATE_LIMIT_MEMORY_STORE, constants.RATE_LIMIT_MYSQL_STORE)
+		} else {
+			l.Options.WithStore(store)
+		}
+	}
+
+	limits := []struct {
+		envVar string
+		set    func(Limit) *RateLimitOptions
+	}{
+		{constants.RATE_LIMIT_GLOBAL_ENV_VAR, l.Options.WithGlobal},
+		{constants.RATE_LIMIT_PER_USER_ENV_VAR, l.Options.WithPerUser},
+		{constants.RATE_LIMIT_PER_API_KEY_ENV_VAR, l.Options.WithPerApiKey},
+		{constants.RATE_LIMIT_PER_IP_ENV_VAR, l.Options.WithPerIp},
+	}
+	for _, item := range limits {
+		if cfg.GetKey(item.envVar) == "" {
+			continue
+		}
+		limit, err := ParseLimit(cfg.GetKey(item.envVar))
+		if err != nil {
+			l.ctx.LogWarnf("[RateLimit] Invalid value for %s: %s", item.envVar, err.Error())
+			continue
+		}
+		item.set(limit)
+	}
+
+	if cfg.GetKey(constants.RATE_LIMIT_ROUTE_GROUPS_ENV_VAR) != "" {
+		groups, err := ParseRouteGroups(cfg.GetKey(constants.RATE_LIMIT_ROUTE_GROUPS_ENV_VAR), cfg.GetKey(constants.RATE_LIMIT_ROUTE_GROUP_LIMITS_ENV_VAR))
+		if err != nil {
+			l.ctx.LogWarnf("[RateLimit] Invalid value for %s: %s", constants.RATE_LIMIT_ROUTE_GROUPS_ENV_VAR, err.Error())
+		} else {
+			for _, group := range groups {
+				l.Options.WithRouteGroup(group)
+			}
+		}
+	}
+
+	if cfg.GetKey(constants.RATE_LIMIT_EXEMPT_ENV_VAR) != "" {
+		exemptions, err := ParseExemptions(cfg.GetKey(constants.RATE_LIMIT_EXEMPT_ENV_VAR))
+		if err != nil {
+			l.ctx.LogWarnf("[RateLimit] Invalid value for %s: %s", constants.RATE_LIMIT_EXEMPT_ENV_VAR, err.Error())
+		} else {
+			l.Options.WithExemptions(exemptions)
+		}
+	}
+}
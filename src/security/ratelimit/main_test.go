@@ -0,0 +1,160 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("60/1m")
+	require.NoError(t, err)
+	assert.Equal(t, Limit{Requests: 60, Period: time.Minute}, limit)
+
+	limit, err = ParseLimit("")
+	require.NoError(t, err)
+	assert.True(t, limit.IsZero())
+
+	for _, value := range []string{"60", "a/1m", "60/x", "60/0s"} {
+		_, err = ParseLimit(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestParseRouteGroups(t *testing.T) {
+	groups, err := ParseRouteGroups("machines=/machines,orchestrator/machines/;catalog=/catalog", "machines=60/1m;catalog=10/1s")
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, []string{"/machines", "/orchestrator/machines"}, groups[0].Paths)
+	assert.True(t, groups[0].Matches("/orchestrator/machines/123/status"))
+	assert.False(t, groups[0].Matches("/machines-templates"))
+	assert.Equal(t, 10, groups[1].Limit.Requests)
+
+	_, err = ParseRouteGroups("machines=/machines", "")
+	assert.Error(t, err)
+}
+
+func TestMemoryStore_Take(t *testing.T) {
+	ctx := basecontext.NewRootBaseContext()
+	store := newMemoryStore()
+	limit := Limit{Requests: 2, Period: 2 * time.Second}
+	key := []BucketLimit{{Key: "key", Limit: limit}}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		denied, _, err := store.Take(ctx, key, now)
+		require.NoError(t, err)
+		assert.Equal(t, -1, denied)
+	}
+
+	denied, retryAfter, err := store.Take(ctx, key, now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, denied)
+	assert.Equal(t, time.Second, retryAfter)
+
+	// the bucket refills one token per second
+	denied, _, _ = store.Take(ctx, key, now.Add(time.Second))
+	assert.Equal(t, -1, denied)
+	denied, _, _ = store.Take(ctx, []BucketLimit{{Key: "other", Limit: limit}}, now)
+	assert.Equal(t, -1, denied)
+}
+
+func TestMemoryStore_TakeKeepsTokensWhenDenied(t *testing.T) {
+	ctx := basecontext.NewRootBaseContext()
+	store := newMemoryStore()
+	now := time.Now()
+	group := BucketLimit{Key: "group", Limit: Limit{Requests: 2, Period: time.Minute}}
+	user := BucketLimit{Key: "user", Limit: Limit{Requests: 1, Period: time.Minute}}
+
+	denied, _, err := store.Take(ctx, []BucketLimit{group, user}, now)
+	require.NoError(t, err)
+	assert.Equal(t, -1, denied)
+
+	// the empty user bucket does not spend the token of the group
+	denied, _, err = store.Take(ctx, []BucketLimit{group, user}, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, denied)
+	denied, _, err = store.Take(ctx, []BucketLimit{group}, now)
+	require.NoError(t, err)
+	assert.Equal(t, -1, denied)
+}
+
+func TestRateLimiter_Allow(t *testing.T) {
+	ctx := basecontext.NewRootBaseContext()
+	limiter := New(ctx)
+	exemptions, err := ParseExemptions("ip:10.0.0.0/8,user:ci-bot,api_key:monitoring,route:/health")
+	require.NoError(t, err)
+	limiter.Options.
+		WithEnabled(true).
+		WithPerUser(Limit{Requests: 2, Period: time.Minute}).
+		WithPerIp(Limit{Requests: 3, Period: time.Minute}).
+		WithRouteGroup(RouteGroup{Name: "machines", Paths: []string{"/machines"}, Limit: Limit{Requests: 1, Period: time.Minute}}).
+		WithExemptions(exemptions)
+
+	user := Request{UserID: "user-id", Ip: "192.168.1.10", Path: "/machines"}
+	assert.True(t, limiter.Allow(user).Allowed)
+
+	decision := limiter.Allow(user)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "machines route group", decision.Scope)
+	assert.Greater(t, decision.RetryAfter, time.Duration(0))
+
+	// the other routes use the limit of the user
+	user.Path = "/catalog"
+	assert.True(t, limiter.Allow(user).Allowed)
+	decision = limiter.Allow(user)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "user", decision.Scope)
+
+	// the address was used by every request of the user
+	anonymous := Request{Ip: "192.168.1.10", Path: "/catalog"}
+	assert.True(t, limiter.Allow(anonymous).Allowed)
+	decision = limiter.Allow(anonymous)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "ip", decision.Scope)
+
+	for _, request := range []Request{
+		{UserID: "other", Username: "ci-bot", Ip: "192.168.1.10", Path: "/machines"},
+		{ApiKeyID: "MONITORING", Ip: "192.168.1.10", Path: "/machines"},
+		{Ip: "10.1.2.3", Path: "/machines"},
+		{Ip: "192.168.1.10", Path: "/health/probe"},
+	} {
+		assert.True(t, limiter.IsExempt(request), request)
+		assert.True(t, limiter.Allow(request).Allowed, request)
+	}
+
+	limiter.Options.WithEnabled(false)
+	assert.True(t, limiter.Allow(user).Allowed)
+}
+
+func TestRateLimiter_AllowApiKeyLimit(t *testing.T) {
+	ctx := basecontext.NewRootBaseContext()
+	limiter := New(ctx)
+	exemptions, err := ParseExemptions("api_key:exempt")
+	require.NoError(t, err)
+	limiter.Options.
+		WithEnabled(false).
+		WithPerApiKey(Limit{Requests: 10, Period: time.Minute}).
+		WithExemptions(exemptions)
+
+	// the limit of the scope applies while the configured limits are disabled
+	request := Request{ApiKeyID: "key", Ip: "192.168.1.10", Path: "/catalog", ApiKeyLimit: Limit{Requests: 1, Period: time.Minute}}
+	assert.True(t, limiter.Allow(request).Allowed)
+	decision := limiter.Allow(request)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "api key", decision.Scope)
+	assert.True(t, limiter.Allow(Request{ApiKeyID: "other", Path: "/catalog"}).Allowed)
+
+	// and replaces the limit of each api key when they are enabled
+	limiter.Options.WithEnabled(true).WithPerIp(Limit{})
+	request.ApiKeyID = "enabled"
+	assert.True(t, limiter.Allow(request).Allowed)
+	assert.False(t, limiter.Allow(request).Allowed)
+
+	request.ApiKeyID = "exempt"
+	assert.True(t, limiter.Allow(request).Allowed)
+	assert.False(t, limiter.Allow(request).Allowed)
+}
@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/errors"
+	sql_database "github.com/Parallels/prl-devops-service/sql"
+)
+
+const mysqlCreateBucketsTable = `CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+	bucket_key VARCHAR(255) NOT NULL PRIMARY KEY,
+	tokens DOUBLE NOT NULL,
+	updated_at BIGINT NOT NULL,
+	expires_at BIGINT NOT NULL
+)`
+
+// mysqlStore keeps the buckets in the MySQL database configured with the
+// MYSQL_* variables so every instance of an orchestrator shares them
+type mysqlStore struct {
+	db           *sql.DB
+	cleanupMutex sync.Mutex
+	lastCleanup  time.Time
+}
+
+func newMySQLStore(service sql_database.DatabaseService) (*mysqlStore, error) {
+	db, err := service.Connect()
+	if err != nil {
+		return nil, errors.NewFromErrorf(err, "error connecting to the rate limit database")
+	}
+	if _, err := db.Exec(mysqlCreateBucketsTable); err != nil {
+		_ = db.Close()
+		return nil, errors.NewFromErrorf(err, "error creating the rate limit table")
+	}
+
+	return &mysqlStore{db: db}, nil
+}
+
+func (s *mysqlStore) Name() string {
+	return "mysql"
+}
+
+// Take locks the rows of the buckets so the instances cannot take the same
+// token, the rows are always locked in the order of the checks of the limiter
+func (s *mysqlStore) Take(ctx basecontext.ApiContext, buckets []BucketLimit, now time.Time) (int, time.Duration, error) {
+	s.cleanup(ctx, now)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return -1, 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	updated := make([]bucket, len(buckets))
+	for i, item := range buckets {
+		var updatedAt int64
+		err = tx.QueryRow("SELECT tokens, updated_at FROM rate_limit_buckets WHERE bucket_key = ? FOR UPDATE", item.Key).Scan(&updated[i].tokens, &updatedAt)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return -1, 0, err
+		default:
+			updated[i].updatedAt = time.UnixMicro(updatedAt)
+		}
+
+		if allowed, retryAfter := updated[i].take(item.Limit, now); !allowed {
+			return i, retryAfter, nil
+		}
+	}
+
+	for i, item := range buckets {
+		if _, err := tx.Exec(
+			"INSERT INTO rate_limit_buckets (bucket_key, tokens, updated_at, expires_at) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE tokens = VALUES(tokens), updated_at = VALUES(updated_at), expires_at = VALUES(expires_at)",
+			item.Key, updated[i].tokens, updated[i].updatedAt.UnixMicro(), now.Add(item.Limit.Period).UnixMicro(),
+		); err != nil {
+			return -1, 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return -1, 0, err
+	}
+
+	return -1, 0, nil
+}
+
+func (s *mysqlStore) cleanup(ctx basecontext.ApiContext, now time.Time) {
+	s.cleanupMutex.Lock()
+	if now.Sub(s.lastCleanup) < 10*time.Minute {
+		s.cleanupMutex.Unlock()
+		return
+	}
+	s.lastCleanup = now
+	s.cleanupMutex.Unlock()
+
+	if _, err := s.db.Exec("DELETE FROM rate_limit_buckets WHERE expires_at < ?", now.UnixMicro()); err != nil {
+		ctx.LogWarnf("[RateLimit] Error deleting the expired buckets: %v", err)
+	}
+}
+
+func (s *mysqlStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,253 @@
+package ratelimit
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/errors"
+)
+
+// Limit is the size of a token bucket, Requests can be made at once and the
+// bucket refills at Requests per Period
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// IsZero returns true when the limit is not configured
+func (l Limit) IsZero() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+func (l Limit) String() string {
+	if l.IsZero() {
+		return ""
+	}
+
+	return strconv.Itoa(l.Requests) + "/" + l.Period.String()
+}
+
+// ParseLimit reads a limit in the requests/duration format, for example
+// 600/1m, an empty value disables the limit
+func ParseLimit(value string) (Limit, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "0" {
+		return Limit{}, nil
+	}
+
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, errors.Newf("invalid rate limit %v, the format is requests/duration, for example 600/1m", value)
+	}
+	requests, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || requests < 0 {
+		return Limit{}, errors.Newf("invalid number of requests in rate limit %v", value)
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || period <= 0 {
+		return Limit{}, errors.Newf("invalid duration in rate limit %v", value)
+	}
+
+	return Limit{Requests: requests, Period: period}, nil
+}
+
+// RouteGroup limits the requests of each identity to a set of routes, the
+// paths are matched without the api prefix and version
+type RouteGroup struct {
+	Name  string
+	Paths []string
+	Limit Limit
+}
+
+// Matches returns true if the path starts with one of the paths of the group
+func (g RouteGroup) Matches(path string) bool {
+	for _, prefix := range g.Paths {
+		if matchesPathPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Exemptions are the callers and routes that are never rate limited
+type Exemptions struct {
+	Networks []*net.IPNet
+	Users    []string
+	ApiKeys  []string
+	Routes   []string
+}
+
+// ParseExemptions reads a comma separated list of ip:, user:, api_key: and
+// route: entries
+func ParseExemptions(value string) (Exemptions, error) {
+	result := Exemptions{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(item, constants.RATE_LIMIT_EXEMPT_IP_PREFIX):
+			network, err := parseNetwork(strings.TrimPrefix(item, constants.RATE_LIMIT_EXEMPT_IP_PREFIX))
+			if err != nil {
+				return Exemptions{}, err
+			}
+			result.Networks = append(result.Networks, network)
+		case strings.HasPrefix(item, constants.RATE_LIMIT_EXEMPT_USER_PREFIX):
+			result.Users = append(result.Users, strings.TrimPrefix(item, constants.RATE_LIMIT_EXEMPT_USER_PREFIX))
+		case strings.HasPrefix(item, constants.RATE_LIMIT_EXEMPT_API_KEY_PREFIX):
+			result.ApiKeys = append(result.ApiKeys, strings.TrimPrefix(item, constants.RATE_LIMIT_EXEMPT_API_KEY_PREFIX))
+		case strings.HasPrefix(item, constants.RATE_LIMIT_EXEMPT_ROUTE_PREFIX):
+			result.Routes = append(result.Routes, strings.TrimPrefix(item, constants.RATE_LIMIT_EXEMPT_ROUTE_PREFIX))
+		default:
+			return Exemptions{}, errors.Newf("invalid rate limit exemption %v, it must start with ip:, user:, api_key: or route:", item)
+		}
+	}
+
+	return result, nil
+}
+
+// ParseRouteGroups reads the paths of the groups in the
+// group=/path1,/path2;group2=/path3 format and their limits in the
+// group=requests/duration;group2=requests/duration format
+func ParseRouteGroups(paths string, limits string) ([]RouteGroup, error) {
+	groupLimits := make(map[string]Limit)
+	for _, item := range strings.Split(limits, ";") {
+		name, value, ok := strings.Cut(item, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || name == "" {
+			continue
+		}
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, errors.NewFromErrorf(err, "invalid limit of route group %v", name)
+		}
+		groupLimits[name] = limit
+	}
+
+	result := make([]RouteGroup, 0)
+	for _, item := range strings.Split(paths, ";") {
+		name, value, ok := strings.Cut(item, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || name == "" {
+			continue
+		}
+		limit, ok := groupLimits[name]
+		if !ok || limit.IsZero() {
+			return nil, errors.Newf("route group %v has no limit", name)
+		}
+
+		group := RouteGroup{Name: name, Limit: limit}
+		for _, path := range strings.Split(value, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				group.Paths = append(group.Paths, "/"+strings.Trim(path, "/"))
+			}
+		}
+		result = append(result, group)
+	}
+
+	return result, nil
+}
+
+type RateLimitOptions struct {
+	Enabled bool
+	// Store is where the buckets are kept, the mysql store shares them
+	// between the instances of an orchestrator
+	Store       string
+	Global      Limit
+	PerUser     Limit
+	PerApiKey   Limit
+	PerIp       Limit
+	RouteGroups []RouteGroup
+	Exemptions  Exemptions
+}
+
+func NewDefaultOptions() *RateLimitOptions {
+	perUser, _ := ParseLimit(constants.RATE_LIMIT_DEFAULT_PER_USER)
+	perApiKey, _ := ParseLimit(constants.RATE_LIMIT_DEFAULT_PER_API_KEY)
+	perIp, _ := ParseLimit(constants.RATE_LIMIT_DEFAULT_PER_IP)
+
+	return &RateLimitOptions{
+		Store:       constants.RATE_LIMIT_MEMORY_STORE,
+		PerUser:     perUser,
+		PerApiKey:   perApiKey,
+		PerIp:       perIp,
+		RouteGroups: make([]RouteGroup, 0),
+	}
+}
+
+func (o *RateLimitOptions) WithEnabled(enabled bool) *RateLimitOptions {
+	o.Enabled = enabled
+	return o
+}
+
+func (o *RateLimitOptions) WithStore(store string) *RateLimitOptions {
+	o.Store = strings.ToLower(store)
+	return o
+}
+
+func (o *RateLimitOptions) WithGlobal(limit Limit) *RateLimitOptions {
+	o.Global = limit
+	return o
+}
+
+func (o *RateLimitOptions) WithPerUser(limit Limit) *RateLimitOptions {
+	o.PerUser = limit
+	return o
+}
+
+func (o *RateLimitOptions) WithPerApiKey(limit Limit) *RateLimitOptions {
+	o.PerApiKey = limit
+	return o
+}
+
+func (o *RateLimitOptions) WithPerIp(limit Limit) *RateLimitOptions {
+	o.PerIp = limit
+	return o
+}
+
+func (o *RateLimitOptions) WithRouteGroup(group RouteGroup) *RateLimitOptions {
+	o.RouteGroups = append(o.RouteGroups, group)
+	return o
+}
+
+func (o *RateLimitOptions) WithExemptions(exemptions Exemptions) *RateLimitOptions {
+	o.Exemptions = exemptions
+	return o
+}
+
+func parseNetwork(value string) (*net.IPNet, error) {
+	value = strings.TrimSpace(value)
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, errors.Newf("invalid rate limit exemption address %v", value)
+		}
+		if ip.To4() != nil {
+			value += "/32"
+		} else {
+			value += "/128"
+		}
+	}
+
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, errors.NewFromErrorf(err, "invalid rate limit exemption network %v", value)
+	}
+
+	return network, nil
+}
+
+func matchesPathPrefix(path string, prefix string) bool {
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix == "" {
+		return true
+	}
+
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
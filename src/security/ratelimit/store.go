@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+)
+
+// Store keeps the token buckets. Take removes a token from every bucket only
+// when none of them is empty, otherwise it returns the index of the first
+// empty bucket and how long to wait for it, or -1 when the tokens were taken
+type Store interface {
+	Name() string
+	Take(ctx basecontext.ApiContext, buckets []BucketLimit, now time.Time) (int, time.Duration, error)
+	Close() error
+}
+
+// BucketLimit is the key of a bucket and the limit it is filled with
+type BucketLimit struct {
+	Key   string
+	Limit Limit
+}
+
+// bucket is a token bucket, it is full when it is first used
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// take refills the bucket for the time since it was last used and removes a
+// token from it
+func (b *bucket) take(limit Limit, now time.Time) (bool, time.Duration) {
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Period.Seconds()
+	if b.updatedAt.IsZero() {
+		b.tokens = capacity
+	} else if elapsed := now.Sub(b.updatedAt).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*rate)
+	}
+	if now.After(b.updatedAt) {
+		b.updatedAt = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+// memoryStore keeps the buckets of this instance
+type memoryStore struct {
+	mutex       sync.Mutex
+	buckets     map[string]*memoryBucket
+	lastCleanup time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	expiresAt time.Time
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{buckets: make(map[string]*memoryBucket)}
+}
+
+func (s *memoryStore) Name() string {
+	return "memory"
+}
+
+func (s *memoryStore) Take(ctx basecontext.ApiContext, buckets []BucketLimit, now time.Time) (int, time.Duration, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// buckets that were not used for a whole period are full again, we can
+	// forget them
+	if now.Sub(s.lastCleanup) > time.Minute {
+		for bucketKey, existing := range s.buckets {
+			if now.After(existing.expiresAt) {
+				delete(s.buckets, bucketKey)
+			}
+		}
+		s.lastCleanup = now
+	}
+
+	// the buckets are only updated when every one of them has a token
+	updated := make([]bucket, len(buckets))
+	for i, item := range buckets {
+		if entry, ok := s.buckets[item.Key]; ok {
+			updated[i] = entry.bucket
+		}
+		if allowed, retryAfter := updated[i].take(item.Limit, now); !allowed {
+			return i, retryAfter, nil
+		}
+	}
+	for i, item := range buckets {
+		s.buckets[item.Key] = &memoryBucket{bucket: updated[i], expiresAt: now.Add(item.Limit.Period)}
+	}
+
+	return -1, 0, nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
@@ -1,8 +1,19 @@
 package serviceprovider
 
 import (
+	"path/filepath"
+
 	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/config"
+	"github.com/Parallels/prl-devops-service/constants"
 	"github.com/Parallels/prl-devops-service/data"
+	"github.com/Parallels/prl-devops-service/serviceprovider/system"
+)
+
+const (
+	catalogDatabaseDirectory = "/etc/parallels-devops"
+	userDatabaseDirectory    = ".parallels-devops"
+	databaseFilename         = "data.json"
 )
 
 func GetDatabaseService(ctx basecontext.ApiContext) (*data.JsonDatabase, error) {
@@ -23,3 +34,48 @@ func GetDatabaseService(ctx basecontext.ApiContext) (*data.JsonDatabase, error)
 
 	return dbService, nil
 }
+
+// getDatabaseFolder returns the folder of the data.json file, the configured
+// database folder wins over rootFolder for root and ~/.parallels-devops for
+// any other user
+func getDatabaseFolder(ctx basecontext.ApiContext, srv *system.SystemService, runningUser string, rootFolder string) (string, error) {
+	cfg := config.Get()
+	if cfg.DatabaseFolder() != "" {
+		return cfg.DatabaseFolder(), nil
+	}
+
+	if runningUser == "root" {
+		return rootFolder, nil
+	}
+
+	userHome, err := srv.GetUserHome(ctx, runningUser)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(userHome, userDatabaseDirectory), nil
+}
+
+// GetDatabaseFilename returns the data.json file the service opens on this
+// host, the catalog services on linux always run as root
+func GetDatabaseFilename(ctx basecontext.ApiContext) (string, error) {
+	srv := system.Get()
+	if srv.GetOperatingSystem() != "macos" {
+		folder, err := getDatabaseFolder(ctx, srv, "root", catalogDatabaseDirectory)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(folder, databaseFilename), nil
+	}
+
+	currentUser, err := srv.GetCurrentUser(ctx)
+	if err != nil {
+		return "", err
+	}
+	folder, err := getDatabaseFolder(ctx, srv, currentUser, constants.ServiceDefaultDirectory)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(folder, databaseFilename), nil
+}
@@ -49,6 +49,7 @@ func (s *DownloadService) DownloadFile(url string, headers map[string]string, de
 		pw.SetCurrentAction(progressReporter.Action)
 		pw.SetPrefix(progressReporter.Prefix)
 		pw.SetFilename(progressReporter.Filename)
+		pw.SetRateLimiter(progressReporter.RateLimiter)
 		progressWriter = pw
 	} else {
 		progressWriter = file
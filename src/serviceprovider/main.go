@@ -52,7 +52,6 @@ type ServiceProvider struct {
 var globalProvider *ServiceProvider
 
 func InitCatalogServices(ctx basecontext.ApiContext) {
-	cfg := config.Get()
 	globalProvider = &ServiceProvider{
 		Logger: common.Logger,
 	}
@@ -65,42 +64,17 @@ func InitCatalogServices(ctx basecontext.ApiContext) {
 	globalProvider.CurrentSystemUser = currentUser
 	globalProvider.RunningUser = currentUser
 
-	if globalProvider.RunningUser == "root" {
-		dbLocation := "/etc/parallels-devops"
-		err := helpers.CreateDirIfNotExist(dbLocation)
-		if err != nil {
-			panic(err)
-		}
-
-		if cfg.DatabaseFolder() != "" {
-			globalProvider.JsonDatabase = data.NewJsonDatabase(ctx, filepath.Join(cfg.DatabaseFolder(), "/data.json"))
-			dbLocation = cfg.DatabaseFolder()
-		} else {
-			globalProvider.JsonDatabase = data.NewJsonDatabase(ctx, filepath.Join(dbLocation, "/data.json"))
-		}
-
-		_ = globalProvider.JsonDatabase.Connect(ctx)
-		ctx.LogInfof("Running as %s, using %s/data.json file", globalProvider.RunningUser, dbLocation)
-	} else {
-		userHome, err := globalProvider.System.GetUserHome(ctx, currentUser)
-		if err != nil {
-			panic(err)
-		}
-		dbLocation := userHome + "/.parallels-devops"
-		err = helpers.CreateDirIfNotExist(dbLocation)
-		if err != nil {
-			panic(err)
-		}
-
-		if cfg.DatabaseFolder() != "" {
-			dbLocation = cfg.DatabaseFolder()
-			globalProvider.JsonDatabase = data.NewJsonDatabase(ctx, filepath.Join(cfg.DatabaseFolder(), "/data.json"))
-		} else {
-			globalProvider.JsonDatabase = data.NewJsonDatabase(ctx, filepath.Join(dbLocation, "/data.json"))
-		}
-		_ = globalProvider.JsonDatabase.Connect(ctx)
-		ctx.LogInfof("Running as %s, using %s/data.json file", globalProvider.RunningUser, dbLocation)
+	dbLocation, err := getDatabaseFolder(ctx, globalProvider.System, globalProvider.RunningUser, catalogDatabaseDirectory)
+	if err != nil {
+		panic(err)
 	}
+	if err := helpers.CreateDirIfNotExist(dbLocation); err != nil {
+		panic(err)
+	}
+
+	globalProvider.JsonDatabase = data.NewJsonDatabase(ctx, filepath.Join(dbLocation, databaseFilename))
+	_ = globalProvider.JsonDatabase.Connect(ctx)
+	ctx.LogInfof("Running as %s, using %s/data.json file", globalProvider.RunningUser, dbLocation)
 
 	key := "00000000-0000-0000-0000-000000000000"
 	hid := "XXX00000000000000000000000000000000"
@@ -200,45 +174,18 @@ func InitServices(ctx basecontext.ApiContext) {
 	globalProvider.CurrentSystemUser = currentUser
 	globalProvider.RunningUser = currentUser
 
-	if globalProvider.RunningUser == "root" {
-		dbLocation := constants.ServiceDefaultDirectory
-		err := helpers.CreateDirIfNotExist(dbLocation)
-		if err != nil {
-			panic(err)
-		}
-
-		if cfg.DatabaseFolder() != "" {
-			dbLocation = cfg.DatabaseFolder()
-			globalProvider.JsonDatabase = data.NewJsonDatabase(ctx, filepath.Join(cfg.DatabaseFolder(), "/data.json"))
-		} else {
-			globalProvider.JsonDatabase = data.NewJsonDatabase(ctx, filepath.Join(dbLocation, "/data.json"))
-		}
-
-		_ = globalProvider.JsonDatabase.Connect(ctx)
-		globalProvider.ParallelsDesktopService.SetDatabaseService(globalProvider.JsonDatabase)
-		ctx.LogInfof("Running as %s, using %s/data.json file", globalProvider.RunningUser, dbLocation)
-	} else {
-		userHome, err := globalProvider.System.GetUserHome(ctx, currentUser)
-		if err != nil {
-			panic(err)
-		}
-		dbLocation := userHome + "/.parallels-devops"
-		err = helpers.CreateDirIfNotExist(dbLocation)
-		if err != nil {
-			panic(err)
-		}
-
-		if cfg.DatabaseFolder() != "" {
-			dbLocation = cfg.DatabaseFolder()
-			globalProvider.JsonDatabase = data.NewJsonDatabase(ctx, filepath.Join(cfg.DatabaseFolder(), "/data.json"))
-		} else {
-			globalProvider.JsonDatabase = data.NewJsonDatabase(ctx, filepath.Join(dbLocation, "/data.json"))
-		}
-
-		_ = globalProvider.JsonDatabase.Connect(ctx)
-		globalProvider.ParallelsDesktopService.SetDatabaseService(globalProvider.JsonDatabase)
-		ctx.LogInfof("Running as %s, using %s/data.json file", globalProvider.RunningUser, dbLocation)
+	dbLocation, err := getDatabaseFolder(ctx, globalProvider.System, globalProvider.RunningUser, constants.ServiceDefaultDirectory)
+	if err != nil {
+		panic(err)
 	}
+	if err := helpers.CreateDirIfNotExist(dbLocation); err != nil {
+		panic(err)
+	}
+
+	globalProvider.JsonDatabase = data.NewJsonDatabase(ctx, filepath.Join(dbLocation, databaseFilename))
+	_ = globalProvider.JsonDatabase.Connect(ctx)
+	globalProvider.ParallelsDesktopService.SetDatabaseService(globalProvider.JsonDatabase)
+	ctx.LogInfof("Running as %s, using %s/data.json file", globalProvider.RunningUser, dbLocation)
 
 	key := "00000000-0000-0000-0000-000000000000"
 	hid := "XXX00000000000000000000000000000000"
@@ -4,7 +4,9 @@ import (
 	"encoding/base64"
 	"time"
 
+	"github.com/Parallels/prl-devops-service/audit"
 	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/catalog"
 	"github.com/Parallels/prl-devops-service/config"
 	"github.com/Parallels/prl-devops-service/constants"
 	"github.com/Parallels/prl-devops-service/data/models"
@@ -19,7 +21,11 @@ import (
 	"github.com/Parallels/prl-devops-service/reverse_proxy"
 	bruteforceguard "github.com/Parallels/prl-devops-service/security/brute_force_guard"
 	"github.com/Parallels/prl-devops-service/security/jwt"
+	"github.com/Parallels/prl-devops-service/security/ldap"
+	"github.com/Parallels/prl-devops-service/security/mfa"
+	"github.com/Parallels/prl-devops-service/security/oidc"
 	"github.com/Parallels/prl-devops-service/security/password"
+	"github.com/Parallels/prl-devops-service/security/ratelimit"
 	"github.com/Parallels/prl-devops-service/serviceprovider"
 	diskspace "github.com/Parallels/prl-devops-service/serviceprovider/diskSpace"
 	eventemitter "github.com/Parallels/prl-devops-service/serviceprovider/eventEmitter"
@@ -46,7 +52,11 @@ func Init(ctx basecontext.ApiContext) {
 
 	password.New(ctx)
 	jwt.New(ctx)
+	oidc.New(ctx)
+	ldap.New(ctx)
 	bruteforceguard.New(ctx)
+	mfa.New(ctx)
+	ratelimit.New(ctx)
 }
 
 func Start(ctx basecontext.ApiContext) {
@@ -125,11 +135,26 @@ func Start(ctx basecontext.ApiContext) {
 		panic(err)
 	}
 
-	// Clean up expired/used enrollment tokens at startup
+	// recording the changes made through the api in the audit log
+	if auditLog := audit.New(ctx); auditLog != nil {
+		go auditLog.StartRetentionLoop()
+	}
+
+	// disabling the users removed from the directory
+	if ldapSvc := ldap.Get(); ldapSvc != nil {
+		if dbService, err := serviceprovider.GetDatabaseService(ctx); err == nil {
+			go ldapSvc.StartSyncLoop(dbService)
+		}
+	}
+
+	// Clean up expired/used enrollment tokens and expired sessions at startup
 	if dbService, err := serviceprovider.GetDatabaseService(ctx); err == nil {
 		if err := dbService.DeleteExpiredEnrollmentTokens(ctx); err != nil {
 			ctx.LogWarnf("Could not purge expired enrollment tokens: %v", err)
 		}
+		if err := dbService.DeleteExpiredUserSessions(ctx); err != nil {
+			ctx.LogWarnf("Could not purge expired sessions: %v", err)
+		}
 	}
 
 	ctx.LogInfof("Applying migrations")
@@ -325,6 +350,9 @@ func Start(ctx basecontext.ApiContext) {
 		}
 	}()
 
+	// verifying the catalog cache integrity in the background if configured
+	go catalog.StartCacheScrubLoop(ctx)
+
 	// loading snapshots from parallels desktop if the host module is enabled
 	// and parallels desktop is available, we will be doing this in a go routine
 	// so that the api can start faster and the snapshots will be loaded in
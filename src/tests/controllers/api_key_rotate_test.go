@@ -0,0 +1,72 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Parallels/prl-devops-service/basecontext"
+	"github.com/Parallels/prl-devops-service/constants"
+	"github.com/Parallels/prl-devops-service/controllers"
+	data_models "github.com/Parallels/prl-devops-service/data/models"
+	"github.com/Parallels/prl-devops-service/models"
+	"github.com/Parallels/prl-devops-service/serviceprovider"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestApiKey(t *testing.T, apiKey data_models.ApiKey) {
+	t.Helper()
+	ctx := basecontext.NewBaseContext()
+	ctx.DisableLog()
+	_, err := serviceprovider.Get().JsonDatabase.CreateApiKey(ctx, apiKey)
+	require.NoError(t, err)
+}
+
+// rotateApiKey calls the rotate handler as the test user with the claims
+func rotateApiKey(t *testing.T, id string, claims ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := authRequest(t, http.MethodPut, "/api/v1/auth/api_keys/"+id+"/rotate", nil)
+	authCtx := req.Context().Value(constants.AUTHORIZATION_CONTEXT_KEY).(*basecontext.AuthorizationContext)
+	for _, claim := range claims {
+		authCtx.User.EffectiveClaims = append(authCtx.User.EffectiveClaims, models.UserClaimResponse{Name: claim})
+	}
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+
+	rr := httptest.NewRecorder()
+	controllers.RotateApiKeyHandler()(rr, req)
+	return rr
+}
+
+func TestRotateApiKeyHandler_OwnKey(t *testing.T) {
+	cleanup := setupDB(t)
+	defer cleanup()
+	createTestApiKey(t, data_models.ApiKey{ID: "own", Name: "own", Key: "own", Secret: "secret", UserID: "test-user-id"})
+
+	rr := rotateApiKey(t, "own", constants.UPDATE_OWN_API_KEY_CLAIM)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+}
+
+func TestRotateApiKeyHandler_OtherUserKeyRejected(t *testing.T) {
+	cleanup := setupDB(t)
+	defer cleanup()
+	createTestApiKey(t, data_models.ApiKey{ID: "other", Name: "other", Key: "other", Secret: "secret", UserID: "other-user-id"})
+
+	rr := rotateApiKey(t, "other", constants.UPDATE_OWN_API_KEY_CLAIM)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "encoded")
+
+	rr = rotateApiKey(t, "other", constants.UPDATE_API_KEY_CLAIM)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+}
+
+func TestRotateApiKeyHandler_InternalKeyRejected(t *testing.T) {
+	cleanup := setupDB(t)
+	defer cleanup()
+	createTestApiKey(t, data_models.ApiKey{ID: "internal", Name: "internal", Key: "internal", Secret: "secret", UserID: "test-user-id", Type: "internal"})
+
+	rr := rotateApiKey(t, "internal", constants.UPDATE_API_KEY_CLAIM)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "encoded")
+}
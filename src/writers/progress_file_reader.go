@@ -19,6 +19,7 @@ type ProgressFileReader struct {
 	prefix        string
 	jobId         string
 	currentAction string
+	limiter       *RateLimiter
 	mu            sync.Mutex // Added mu
 }
 
@@ -51,6 +52,12 @@ func (pr *ProgressFileReader) SetCurrentAction(action string) {
 	pr.currentAction = action
 }
 
+// SetRateLimiter throttles the reads to the limiter bandwidth, a nil limiter
+// disables the throttling.
+func (pr *ProgressFileReader) SetRateLimiter(limiter *RateLimiter) {
+	pr.limiter = limiter
+}
+
 func (pr *ProgressFileReader) Size() int64 {
 	return pr.size
 }
@@ -58,6 +65,7 @@ func (pr *ProgressFileReader) Size() int64 {
 func (pr *ProgressFileReader) Read(p []byte) (int, error) {
 	n, err := pr.file.Read(p)
 	if n > 0 {
+		pr.limiter.Wait(n)
 		newRead := atomic.AddInt64(&pr.read, int64(n))
 		if pr.size > 0 {
 			percentage := float64(newRead) * 100 / float64(pr.size)
@@ -85,6 +93,7 @@ func (pr *ProgressFileReader) Read(p []byte) (int, error) {
 
 func (pr *ProgressFileReader) ReadAt(p []byte, off int64) (int, error) {
 	n, err := pr.file.ReadAt(p, off)
+	pr.limiter.Wait(n)
 	if err != nil {
 		return n, err
 	}
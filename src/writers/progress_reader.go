@@ -21,6 +21,7 @@ type ProgressReader struct {
 	prefix        string
 	jobId         string
 	currentAction string
+	limiter       *RateLimiter
 	mu            sync.Mutex
 }
 
@@ -58,6 +59,12 @@ func (pr *ProgressReader) SetCurrentAction(action string) {
 	pr.currentAction = action
 }
 
+// SetRateLimiter throttles the reads to the limiter bandwidth, a nil limiter
+// disables the throttling.
+func (pr *ProgressReader) SetRateLimiter(limiter *RateLimiter) {
+	pr.limiter = limiter
+}
+
 func (pr *ProgressReader) Size() int64 {
 	return pr.size
 }
@@ -73,6 +80,7 @@ func (pr *ProgressReader) GetReaderAt() io.ReaderAt {
 func (pr *ProgressReader) Read(p []byte) (int, error) {
 	n, err := pr.reader.Read(p)
 	if n > 0 {
+		pr.limiter.Wait(n)
 		newRead := atomic.AddInt64(&pr.read, int64(n))
 		if pr.size > 0 {
 			percentage := float64(newRead) * 100 / float64(pr.size)
@@ -103,6 +111,7 @@ func (pr *ProgressReader) ReadAt(p []byte, off int64) (int, error) {
 		return 0, fmt.Errorf("underlying reader does not support ReadAt")
 	}
 	n, err := ra.ReadAt(p, off)
+	pr.limiter.Wait(n)
 	if err != nil {
 		return n, err
 	}
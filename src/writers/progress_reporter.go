@@ -37,6 +37,7 @@ type ProgressReporter struct {
 	Action               string
 	Prefix               string
 	Filename             string
+	RateLimiter          *RateLimiter
 	ActionUpdateCallback func(jobId, prefix string, current int64, percent int, total int64)
 }
 
@@ -63,6 +64,10 @@ func (pr *ProgressReporter) SetFilename(filename string) {
 	pr.Filename = filename
 }
 
+func (pr *ProgressReporter) SetRateLimiter(limiter *RateLimiter) {
+	pr.RateLimiter = limiter
+}
+
 func (pr *ProgressReporter) SetActionUpdateCallback(callback func(jobId, prefix string, current int64, percent int, total int64)) {
 	pr.ActionUpdateCallback = callback
 }
@@ -20,6 +20,7 @@ type ProgressWriter struct {
 	prefix         string
 	jobId          string
 	currentAction  string
+	limiter        *RateLimiter
 	mu             sync.Mutex
 }
 
@@ -53,6 +54,12 @@ func (pr *ProgressWriter) SetCurrentAction(action string) {
 	pr.currentAction = action
 }
 
+// SetRateLimiter throttles the writes to the limiter bandwidth, a nil limiter
+// disables the throttling.
+func (pr *ProgressWriter) SetRateLimiter(limiter *RateLimiter) {
+	pr.limiter = limiter
+}
+
 func (pr *ProgressWriter) CorrelationId() string {
 	return pr.correlationId
 }
@@ -70,6 +77,8 @@ func (pr *ProgressWriter) GetWriterAt() io.WriterAt {
 }
 
 func (pw *ProgressWriter) WriteAt(p []byte, off int64) (n int, err error) {
+	// Throttling before taking the lock so we do not block progress updates
+	pw.limiter.Wait(len(p))
 	pw.mu.Lock()
 	defer pw.mu.Unlock()
 	if _, ok := pw.writer.(io.WriterAt); !ok {
@@ -102,6 +111,7 @@ func (pw *ProgressWriter) WriteAt(p []byte, off int64) (n int, err error) {
 }
 
 func (pw *ProgressWriter) Write(p []byte) (int, error) {
+	pw.limiter.Wait(len(p))
 	pw.mu.Lock()
 	defer pw.mu.Unlock()
 
@@ -0,0 +1,174 @@
+package writers
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Parallels/prl-devops-service/config"
+)
+
+var (
+	globalRateLimiter     atomic.Pointer[RateLimiter]
+	globalRateLimiterOnce sync.Once
+)
+
+// RateLimiter is a simple token bucket used to cap the bandwidth of catalog
+// transfers. A limiter can be shared between several readers and writers, for
+// example all the chunk workers of the same pull, so the limit applies to the
+// combined throughput.
+type RateLimiter struct {
+	bytesPerSecond int64
+	burst          int64
+	tokens         float64
+	lastRefill     time.Time
+	parent         *RateLimiter
+	mu             sync.Mutex
+}
+
+// NewRateLimiter creates a new limiter for the given bytes per second, it will
+// return nil if the limit is zero or negative so callers can pass the result
+// around without checking if throttling is enabled.
+func NewRateLimiter(bytesPerSecond int64) *RateLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+
+	return &RateLimiter{
+		bytesPerSecond: bytesPerSecond,
+		burst:          bytesPerSecond,
+		tokens:         float64(bytesPerSecond),
+		lastRefill:     time.Now(),
+	}
+}
+
+// NewTransferRateLimiter creates the limiter for a single transfer request, the
+// request limit is chained to the global limit so a request can never go above
+// the service wide configuration.
+func NewTransferRateLimiter(bytesPerSecond int64) *RateLimiter {
+	global := GlobalRateLimiter()
+	limiter := NewRateLimiter(bytesPerSecond)
+	if limiter == nil {
+		return global
+	}
+
+	limiter.parent = global
+	return limiter
+}
+
+// GlobalRateLimiter returns the service wide limiter configured with
+// CATALOG_MAX_TRANSFER_RATE_KB, or nil if no global limit is set.
+func GlobalRateLimiter() *RateLimiter {
+	globalRateLimiterOnce.Do(func() {
+		globalRateLimiter.Store(NewRateLimiter(config.Get().CatalogMaxTransferRate()))
+	})
+
+	return globalRateLimiter.Load()
+}
+
+func (l *RateLimiter) BytesPerSecond() int64 {
+	if l == nil {
+		return 0
+	}
+
+	return l.bytesPerSecond
+}
+
+// Wait blocks until n bytes can be transferred.
+func (l *RateLimiter) Wait(n int) {
+	_ = l.WaitContext(context.Background(), n)
+}
+
+// WaitContext blocks until n bytes can be transferred or the context is done.
+func (l *RateLimiter) WaitContext(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+
+	remaining := int64(n)
+	for remaining > 0 {
+		// We take at most a burst worth of tokens at a time, otherwise a large
+		// buffer would never fit in the bucket
+		take := remaining
+		if take > l.burst {
+			take = l.burst
+		}
+
+		delay := l.reserve(take)
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		remaining -= take
+	}
+
+	if l.parent != nil {
+		return l.parent.WaitContext(ctx, n)
+	}
+
+	return nil
+}
+
+// reserve takes the tokens from the bucket and returns how long the caller
+// needs to wait before the reservation is honored.
+func (l *RateLimiter) reserve(n int64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(l.lastRefill).Seconds()
+	l.lastRefill = now
+	l.tokens += elapsed * float64(l.bytesPerSecond)
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / float64(l.bytesPerSecond) * float64(time.Second))
+}
+
+// ThrottledReader wraps a reader and waits on the limiter for every read.
+type ThrottledReader struct {
+	reader  io.Reader
+	limiter *RateLimiter
+}
+
+func NewThrottledReader(reader io.Reader, limiter *RateLimiter) *ThrottledReader {
+	return &ThrottledReader{
+		reader:  reader,
+		limiter: limiter,
+	}
+}
+
+func (r *ThrottledReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.limiter.Wait(n)
+	}
+
+	return n, err
+}
+
+// ProgressThrottle returns a progress callback that blocks for the bytes
+// transferred since the last call, used by SDKs that only expose a cumulative
+// progress callback instead of the reader or writer.
+func (l *RateLimiter) ProgressThrottle() func(bytesTransferred int64) {
+	var last int64
+	return func(bytesTransferred int64) {
+		previous := atomic.SwapInt64(&last, bytesTransferred)
+		if delta := bytesTransferred - previous; delta > 0 {
+			l.Wait(int(delta))
+		}
+	}
+}
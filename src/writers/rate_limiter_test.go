@@ -0,0 +1,93 @@
+package writers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRateLimiter_DisabledReturnsNil(t *testing.T) {
+	assert.Nil(t, NewRateLimiter(0))
+	assert.Nil(t, NewRateLimiter(-1))
+}
+
+func TestRateLimiter_NilIsNoop(t *testing.T) {
+	var limiter *RateLimiter
+	assert.Equal(t, int64(0), limiter.BytesPerSecond())
+	assert.NoError(t, limiter.WaitContext(context.Background(), 1024))
+}
+
+func TestRateLimiter_BurstDoesNotWait(t *testing.T) {
+	limiter := NewRateLimiter(1000)
+	require.NotNil(t, limiter)
+
+	start := time.Now()
+	require.NoError(t, limiter.WaitContext(context.Background(), 1000))
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestRateLimiter_WaitsWhenBucketIsEmpty(t *testing.T) {
+	limiter := NewRateLimiter(1000)
+	require.NoError(t, limiter.WaitContext(context.Background(), 1000))
+
+	start := time.Now()
+	require.NoError(t, limiter.WaitContext(context.Background(), 200))
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestRateLimiter_LargerThanBurst(t *testing.T) {
+	limiter := NewRateLimiter(1000)
+
+	// The first burst is free, the remaining 500 bytes need half a second
+	start := time.Now()
+	require.NoError(t, limiter.WaitContext(context.Background(), 1500))
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+}
+
+func TestRateLimiter_ContextCancelled(t *testing.T) {
+	limiter := NewRateLimiter(10)
+	require.NoError(t, limiter.WaitContext(context.Background(), 10))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := limiter.WaitContext(ctx, 10)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRateLimiter_ParentLimitApplies(t *testing.T) {
+	parent := NewRateLimiter(1000)
+	child := NewRateLimiter(1000000)
+	child.parent = parent
+	require.NoError(t, parent.WaitContext(context.Background(), 1000))
+
+	start := time.Now()
+	require.NoError(t, child.WaitContext(context.Background(), 200))
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestThrottledReader_ReadsEverything(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), 4096)
+	reader := NewThrottledReader(bytes.NewReader(data), NewRateLimiter(1024*1024))
+
+	result, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, data, result)
+}
+
+func TestProgressThrottle_WaitsOnDelta(t *testing.T) {
+	limiter := NewRateLimiter(1000)
+	throttle := limiter.ProgressThrottle()
+
+	throttle(1000)
+	start := time.Now()
+	// Only the 200 new bytes are charged, not the cumulative 1200
+	throttle(1200)
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, elapsed, 150*time.Millisecond)
+	assert.Less(t, elapsed, 800*time.Millisecond)
+}